@@ -0,0 +1,68 @@
+// Command reencrypt encrypts personal data columns again under the active key
+// (PII_ACTIVE_KEY_ID): values stored in plain text before PII_KEY_PROVIDER was
+// set, and values under keys being retired. Run it after enabling encryption
+// or adding a new active key, and drop the old key from PII_LOCAL_KEYS only
+// once it reports nothing left to rewrite. It is safe to run while the service
+// is serving.
+//
+//	reencrypt -batch 500
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"order-service/config"
+	"order-service/internal/core"
+	"order-service/internal/store"
+	"order-service/internal/util"
+)
+
+func main() {
+	batch := flag.Int("batch", 500, "rows read per query")
+	flag.Parse()
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+	if err := util.InitLogger(cfg.Server.Env); err != nil {
+		log.Fatalf("Failed to initialize logger: %v", err)
+	}
+	defer util.SyncLogger()
+
+	cipher, err := core.PIICipher(cfg)
+	if err != nil {
+		log.Fatalf("Invalid personal data encryption configuration: %v", err)
+	}
+	if cipher == nil {
+		log.Fatalf("PII_KEY_PROVIDER is not set; there is nothing to encrypt with")
+	}
+
+	db, err := store.NewStore(cfg.Database.URL)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer db.Close()
+	db.SetMaxAttempts(cfg.Database.MaxRetryAttempts)
+	db.SetPIICipher(cipher)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	rewritten, err := db.ReencryptPII(ctx, *batch)
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if encodeErr := encoder.Encode(map[string]interface{}{"rewritten": rewritten}); encodeErr != nil {
+		log.Printf("Failed to write report: %v", encodeErr)
+	}
+	if err != nil {
+		log.Printf("Re-encryption failed: %v", err)
+		os.Exit(1)
+	}
+}
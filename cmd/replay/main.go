@@ -0,0 +1,143 @@
+// Command replay reads historical events from the order events topic and
+// re-delivers them through the saga handlers, to recover orders a consumer
+// bug mishandled. It reports what would be handled unless -apply is given.
+//
+//	replay -since 2024-05-01T10:00:00Z -until 2024-05-01T12:00:00Z -types PAYMENT_SUCCESS
+//	replay -partition 3 -from-offset 18200 -to-offset 18450 -order-id 42 -apply
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"order-service/config"
+	"order-service/internal/broker"
+	"order-service/internal/core"
+	"order-service/internal/redisclient"
+	"order-service/internal/service"
+	"order-service/internal/store"
+	"order-service/internal/util"
+	"order-service/internal/worker"
+
+	"github.com/segmentio/kafka-go"
+)
+
+func main() {
+	partition := flag.Int("partition", -1, "partition to read, -1 for every partition")
+	fromOffset := flag.Int64("from-offset", -1, "first offset to read in each partition")
+	toOffset := flag.Int64("to-offset", -1, "last offset to read in each partition")
+	since := flag.String("since", "", "read events produced at or after this RFC 3339 time")
+	until := flag.String("until", "", "read events produced up to this RFC 3339 time")
+	orderID := flag.Int64("order-id", 0, "only replay events of this order")
+	types := flag.String("types", "", "comma-separated event types to replay")
+	apply := flag.Bool("apply", false, "deliver the events; without it only report what would be delivered")
+	reprocess := flag.Bool("reprocess", false, "deliver events the saga already processed again")
+	limit := flag.Int("limit", 1000, "maximum number of events to replay")
+	flag.Parse()
+
+	rng := broker.HistoryRange{
+		Partition:  *partition,
+		FromOffset: *fromOffset,
+		ToOffset:   *toOffset,
+		Since:      parseTime("since", *since),
+		Until:      parseTime("until", *until),
+	}
+
+	req := service.ReplayRequest{
+		Range:     rng,
+		OrderID:   *orderID,
+		Mode:      service.ReplayModeDryRun,
+		Reprocess: *reprocess,
+		Limit:     *limit,
+		Actor:     "cli:" + os.Getenv("USER"),
+	}
+	if *types != "" {
+		req.EventTypes = strings.Split(*types, ",")
+	}
+	if *apply {
+		req.Mode = service.ReplayModeApply
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+	if err := util.InitLogger(cfg.Server.Env); err != nil {
+		log.Fatalf("Failed to initialize logger: %v", err)
+	}
+	defer util.SyncLogger()
+
+	db, err := store.NewStore(cfg.Database.URL)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer db.Close()
+	db.SetMaxAttempts(cfg.Database.MaxRetryAttempts)
+	piiCipher, err := core.PIICipher(cfg)
+	if err != nil {
+		log.Fatalf("Invalid personal data encryption configuration: %v", err)
+	}
+	db.SetPIICipher(piiCipher)
+
+	redisClient, err := redisclient.NewClientWithOptions(redisclient.Options{
+		Mode:             cfg.Redis.Mode,
+		Addrs:            cfg.Redis.Addrs,
+		Password:         cfg.Redis.Password,
+		DB:               cfg.Redis.DB,
+		MasterName:       cfg.Redis.MasterName,
+		SentinelPassword: cfg.Redis.SentinelPassword,
+		MaxRetries:       cfg.Redis.MaxRetries,
+	})
+	if err != nil {
+		log.Fatalf("Failed to connect to Redis: %v", err)
+	}
+	defer redisClient.Close()
+
+	orderCore, err := core.New(cfg, db, redisClient)
+	if err != nil {
+		log.Fatalf("Failed to initialize order core: %v", err)
+	}
+	defer orderCore.Producer.Close()
+
+	replayer := service.NewEventReplayer(func(ctx context.Context, rng broker.HistoryRange, fn func(kafka.Message) (bool, error)) error {
+		return broker.ReadHistory(ctx, orderCore.Kafka, cfg.Kafka.TopicOrder, rng, fn)
+	}, worker.SagaHandler(orderCore.Saga), db)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	report, err := replayer.Replay(ctx, req)
+	if report != nil {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			log.Printf("Failed to write report: %v", err)
+		}
+	}
+	if err != nil {
+		log.Printf("Replay failed: %v", err)
+		os.Exit(1)
+	}
+	if report.Results[service.ReplayFailed] > 0 || report.Results[service.ReplayInvalid] > 0 {
+		os.Exit(1)
+	}
+}
+
+// parseTime parses the RFC 3339 value of a time flag, zero when unset
+func parseTime(name, raw string) time.Time {
+	if raw == "" {
+		return time.Time{}
+	}
+	t, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		log.Fatalf("Invalid -%s: %v", name, err)
+	}
+	return t
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"order-service/config"
+	"order-service/internal/broker"
+	"order-service/internal/core"
+	"order-service/internal/doctor"
+	"order-service/internal/redisclient"
+	"order-service/internal/store"
+)
+
+// runDoctor validates the environment the server would run in and prints a
+// pass/fail report. It returns the exit code: 0 when every check passed.
+//
+//	server doctor
+//	server doctor -format json -max-clock-skew 500ms
+func runDoctor(args []string) int {
+	flags := flag.NewFlagSet("doctor", flag.ExitOnError)
+	format := flags.String("format", "text", "report format: text or json")
+	timeout := flags.Duration("timeout", 5*time.Second, "timeout of each check")
+	maxSkew := flags.Duration("max-clock-skew", time.Second, "largest clock skew to Postgres and Redis allowed")
+	flags.Parse(args)
+
+	var (
+		cfg   *config.Config
+		db    *store.Store
+		redis *redisclient.Client
+		kafka *broker.Cluster
+	)
+	defer func() {
+		if db != nil {
+			db.Close()
+		}
+		if redis != nil {
+			redis.Close()
+		}
+	}()
+
+	checks := []doctor.Check{
+		{Name: "config", Run: func(ctx context.Context) (string, error) {
+			var err error
+			if cfg, err = config.Load(); err != nil {
+				return "", err
+			}
+			return "env=" + cfg.Server.Env, nil
+		}},
+		{Name: "postgres", Needs: "config", Run: func(ctx context.Context) (string, error) {
+			var err error
+			db, err = store.NewStore(cfg.Database.URL)
+			return "", err
+		}},
+		{Name: "schema", Needs: "postgres", Run: func(ctx context.Context) (string, error) {
+			applied, err := db.AppliedSchemaVersion(ctx)
+			if err != nil {
+				return "", fmt.Errorf("failed to read schema version: %w", err)
+			}
+			detail := fmt.Sprintf("version %d, build needs %d", applied, store.SchemaVersion)
+			if applied < store.SchemaVersion {
+				return detail, fmt.Errorf("migrations %d to %d are not applied", applied+1, store.SchemaVersion)
+			}
+			return detail, nil
+		}},
+		{Name: "postgres-clock", Needs: "postgres", Run: func(ctx context.Context) (string, error) {
+			return doctor.ClockSkew(db.DatabaseTime, *maxSkew)(ctx)
+		}},
+		{Name: "redis", Needs: "config", Run: func(ctx context.Context) (string, error) {
+			var err error
+			redis, err = redisclient.NewClientWithOptions(redisclient.Options{
+				Mode:             cfg.Redis.Mode,
+				Addrs:            cfg.Redis.Addrs,
+				Password:         cfg.Redis.Password,
+				DB:               cfg.Redis.DB,
+				MasterName:       cfg.Redis.MasterName,
+				SentinelPassword: cfg.Redis.SentinelPassword,
+				MaxRetries:       cfg.Redis.MaxRetries,
+			})
+			return "mode=" + cfg.Redis.Mode, err
+		}},
+		{Name: "redis-scripts", Needs: "redis", Run: func(ctx context.Context) (string, error) {
+			return "", redis.LoadScripts(ctx)
+		}},
+		{Name: "redis-clock", Needs: "redis", Run: func(ctx context.Context) (string, error) {
+			return doctor.ClockSkew(redis.Time, *maxSkew)(ctx)
+		}},
+		{Name: "kafka", Needs: "config", Run: func(ctx context.Context) (string, error) {
+			var err error
+			if kafka, err = core.KafkaCluster(cfg); err != nil {
+				return "", err
+			}
+			return strings.Join(cfg.Kafka.Brokers, ","), broker.Ping(ctx, kafka)
+		}},
+		{Name: "kafka-topics", Needs: "kafka", Run: func(ctx context.Context) (string, error) {
+			topics := []string{cfg.Kafka.TopicOrder, cfg.Kafka.TopicIdentity}
+			if cfg.Kafka.TopicOrderExpress != "" {
+				topics = append(topics, cfg.Kafka.TopicOrderExpress)
+			}
+			if cfg.Flash.AsyncOrders {
+				topics = append(topics, cfg.Kafka.TopicFlashOrders)
+			}
+			missing, err := broker.MissingTopics(ctx, kafka, topics...)
+			if err != nil {
+				return "", err
+			}
+			if len(missing) > 0 {
+				return "", fmt.Errorf("missing topics %s", strings.Join(missing, ", "))
+			}
+			return strings.Join(topics, ","), nil
+		}},
+		{Name: "jaeger", Needs: "config", Run: func(ctx context.Context) (string, error) {
+			return checkReachable(ctx, cfg.Observ.JaegerEndpoint)
+		}},
+	}
+
+	report := doctor.Run(context.Background(), checks, *timeout)
+
+	var err error
+	if *format == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(report)
+	} else {
+		err = report.WriteText(os.Stdout)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to write report: %v\n", err)
+		return 1
+	}
+	if !report.Passed {
+		return 1
+	}
+	return 0
+}
+
+// checkReachable checks that an HTTP endpoint answers. Any response counts,
+// as the Jaeger collector answers GET with 405.
+func checkReachable(ctx context.Context, endpoint string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+	return fmt.Sprintf("%s answered %d", endpoint, resp.StatusCode), nil
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"order-service/config"
+	"order-service/internal/reports"
+	"order-service/internal/service"
+	"order-service/internal/store"
+)
+
+// newInvoiceService builds the invoice service storing invoices in the
+// configured bucket, with the configured template if any
+func newInvoiceService(cfg config.InvoiceConfig, currency string, db *store.Store) (*service.InvoiceService, error) {
+	storage, err := reports.NewS3Publisher(cfg.S3Endpoint, cfg.S3Region, cfg.S3Bucket,
+		cfg.S3AccessKey, cfg.S3SecretKey, cfg.S3UseSSL)
+	if err != nil {
+		return nil, err
+	}
+
+	var template string
+	if cfg.TemplateFile != "" {
+		text, err := os.ReadFile(cfg.TemplateFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read INVOICE_TEMPLATE_FILE: %w", err)
+		}
+		template = string(text)
+	}
+
+	return service.NewInvoiceService(db, db, db, storage, service.InvoiceOptions{
+		Template: template,
+		Issuer:   cfg.Issuer,
+		Currency: currency,
+		Prefix:   cfg.S3Prefix,
+		LinkTTL:  time.Duration(cfg.LinkTTLSeconds) * time.Second,
+	})
+}
@@ -12,19 +12,36 @@ import (
 
 	"order-service/config"
 	"order-service/internal/api"
+	"order-service/internal/api/chirouter"
+	"order-service/internal/api/ginrouter"
 	"order-service/internal/broker"
+	"order-service/internal/core"
+	"order-service/internal/graph"
+	"order-service/internal/health"
+	"order-service/internal/models"
+	"order-service/internal/realtime"
 	"order-service/internal/redisclient"
+	"order-service/internal/reports"
+	"order-service/internal/resilience/retry"
 	"order-service/internal/service"
+	"order-service/internal/shutdown"
 	"order-service/internal/store"
 	"order-service/internal/util"
 	"order-service/internal/worker"
 
-	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/segmentio/kafka-go"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "doctor" {
+		os.Exit(runDoctor(os.Args[2:]))
+	}
 
-	cfg := config.Load()
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
 
 	if err := util.InitLogger(cfg.Server.Env); err != nil {
 		log.Fatalf("Failed to initialize logger: %v", err)
@@ -33,6 +50,7 @@ func main() {
 
 	logger := util.GetLogger()
 	logger.Info("Starting order service")
+	log.Printf("Effective configuration:\n%s", cfg.Report())
 
 	tp, err := util.InitTracer("order-service", cfg.Observ.JaegerEndpoint)
 	if err != nil {
@@ -46,70 +64,628 @@ func main() {
 		}
 	}()
 
-	db, err := store.NewStore(cfg.Database.URL)
+	db, err := store.NewStoreWithReplicas(cfg.Database.URL, cfg.Database.ReplicaURLs)
 	if err != nil {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
-	defer db.Close()
-	log.Println("Database connected")
+	db.SetMaxAttempts(cfg.Database.MaxRetryAttempts)
+	db.SetFailoverPolicy(cfg.Database.ReadRetryAttempts, cfg.Database.PoolResetThreshold)
+	db.SetQueryOptions(cfg.Database.PreparedStatements, time.Duration(cfg.Database.SlowQueryMs)*time.Millisecond)
+	piiCipher, err := core.PIICipher(cfg)
+	if err != nil {
+		log.Fatalf("Invalid personal data encryption configuration: %v", err)
+	}
+	db.SetPIICipher(piiCipher)
+	if err := db.RegisterPoolMetrics(prometheus.DefaultRegisterer); err != nil {
+		log.Printf("Failed to register database pool metrics: %v", err)
+	}
+	log.Printf("Database connected (%d read replicas)", len(cfg.Database.ReplicaURLs))
 
-	redisClient, err := redisclient.NewClient(cfg.Redis.Addr, cfg.Redis.Password, cfg.Redis.DB)
+	redisClient, err := redisclient.NewClientWithOptions(redisclient.Options{
+		Mode:             cfg.Redis.Mode,
+		Addrs:            cfg.Redis.Addrs,
+		Password:         cfg.Redis.Password,
+		DB:               cfg.Redis.DB,
+		MasterName:       cfg.Redis.MasterName,
+		SentinelPassword: cfg.Redis.SentinelPassword,
+		MaxRetries:       cfg.Redis.MaxRetries,
+	})
 	if err != nil {
 		log.Fatalf("Failed to connect to Redis: %v", err)
 	}
-	defer redisClient.Close()
-	log.Println("Redis connected")
+	log.Printf("Redis connected: mode=%s", cfg.Redis.Mode)
 
-	producer := broker.NewProducer(cfg.Kafka.Brokers, cfg.Kafka.TopicOrder)
-	defer producer.Close()
+	orderCore, err := core.New(cfg, db, redisClient)
+	if err != nil {
+		log.Fatalf("Failed to initialize order core: %v", err)
+	}
 	log.Println("Kafka producer initialized")
 
-	eventPublisher := broker.NewEventPublisher(producer)
-
-	inventoryClient := service.NewInventoryClient(db, redisClient)
-	paymentService := service.NewPaymentService(db, eventPublisher)
-	orderService := service.NewOrderService(db, redisClient, eventPublisher, inventoryClient)
-	sagaOrchestrator := service.NewSagaOrchestrator(db, inventoryClient, paymentService, eventPublisher)
+	availabilityFeed := service.NewAvailabilityFeed(redisClient, orderCore.Inventory, cfg.Flash.HotProducts,
+		cfg.Flash.StreamMaxConnections, cfg.Flash.StreamMaxConnectionsPerClient)
 
 	ctx := context.Background()
-	if err := inventoryClient.SyncInventoryToRedis(ctx); err != nil {
+	if err := orderCore.Inventory.SyncInventoryToRedis(ctx); err != nil {
 		log.Printf("Failed to sync inventory to Redis: %v", err)
 	}
+	if len(cfg.Flash.MetricsSKUs) > 0 {
+		productMetrics := service.NewProductMetrics(db, cfg.Flash.MetricsMaxProducts)
+		productMetrics.Watch(ctx, cfg.Flash.MetricsSKUs)
+		orderCore.Inventory.SetProductMetrics(productMetrics)
+	}
+
+	checkTimeout := time.Duration(cfg.Observ.HealthCheckTimeoutMs) * time.Millisecond
+	healthChecker := health.NewChecker()
+	healthChecker.Register("postgres", checkTimeout, db.Ping)
+	healthChecker.Register("redis", checkTimeout, redisClient.Ping)
+	healthChecker.Register("kafka", checkTimeout, func(ctx context.Context) error {
+		return broker.Ping(ctx, orderCore.Kafka)
+	})
 
 	workerCtx, workerCancel := context.WithCancel(context.Background())
 	defer workerCancel()
 
-	orderConsumer := broker.NewConsumer(cfg.Kafka.Brokers, cfg.Kafka.TopicOrder, cfg.Kafka.ConsumerGroup)
-	orderWorker := worker.NewOrderWorker(orderConsumer, sagaOrchestrator)
 	go func() {
-		if err := orderWorker.Start(workerCtx); err != nil {
+		if err := orderCore.OrderCache.Run(workerCtx); err != nil && err != context.Canceled {
+			log.Printf("Order cache invalidation listener error: %v", err)
+		}
+	}()
+
+	go func() {
+		if err := orderCore.ProductCache.Run(workerCtx); err != nil && err != context.Canceled {
+			log.Printf("Product cache invalidation listener error: %v", err)
+		}
+	}()
+
+	go func() {
+		if err := availabilityFeed.Run(workerCtx); err != nil && err != context.Canceled {
+			log.Printf("Availability feed error: %v", err)
+		}
+	}()
+
+	go func() {
+		if err := orderCore.StatusFeed.Run(workerCtx); err != nil && err != context.Canceled {
+			log.Printf("Order status feed error: %v", err)
+		}
+	}()
+
+	go func() {
+		if err := orderCore.Inventory.RunLeaseExpiry(workerCtx); err != nil && err != context.Canceled {
+			log.Printf("Quota lease expiry error: %v", err)
+		}
+	}()
+
+	if orderCore.FairReservations != nil {
+		go func() {
+			if err := orderCore.FairReservations.Run(workerCtx); err != nil && err != context.Canceled {
+				log.Printf("Fair reservation queue error: %v", err)
+			}
+		}()
+	}
+
+	deadLetterQueue := service.NewDeadLetterQueue(db)
+	republisher := broker.NewRepublisher(orderCore.Kafka)
+	deadLetterQueue.SetRedrive(republisher.Republish, cfg.Kafka.DeadLetterMaxRedrives)
+
+	orderConsumer := broker.NewConsumer(orderCore.Kafka, cfg.Kafka.TopicOrder, cfg.Kafka.ConsumerGroup)
+	orderConsumer.SetDeadLetter(deadLetterQueue.Handler(cfg.Kafka.ConsumerGroup))
+	orderConsumer.SetObserver(orderCore.Timeline.Observer(service.TimelineConsumed))
+	orderWorker := worker.NewOrderWorker(orderConsumer, orderCore.Saga, healthChecker)
+	go func() {
+		if err := healthChecker.RunWorker("order-worker", func() error { return orderWorker.Start(workerCtx) }); err != nil {
 			log.Printf("Order worker error: %v", err)
 		}
 	}()
 
-	paymentConsumer := broker.NewConsumer(cfg.Kafka.Brokers, cfg.Kafka.TopicOrder, "payment-service-group")
-	paymentWorker := worker.NewPaymentWorker(paymentConsumer, paymentService)
+	paymentConsumer := broker.NewConsumer(orderCore.Kafka, cfg.Kafka.TopicOrder, "payment-service-group")
+	paymentConsumer.SetDeadLetter(deadLetterQueue.Handler("payment-service-group"))
+	paymentConsumer.SetObserver(orderCore.Timeline.Observer(service.TimelineConsumed))
+	paymentWorker := worker.NewPaymentWorker(paymentConsumer, orderCore.Payments, healthChecker)
 	go func() {
-		if err := paymentWorker.Start(workerCtx); err != nil {
+		if err := healthChecker.RunWorker("payment-worker", func() error { return paymentWorker.Start(workerCtx) }); err != nil {
 			log.Printf("Payment worker error: %v", err)
 		}
 	}()
 
-	if cfg.Server.Env == "production" {
-		gin.SetMode(gin.ReleaseMode)
+	// Express orders have their own lane: their events are copied to the
+	// express topic, consumed by their own saga and payment workers, and the
+	// standard lane's workers leave them alone
+	var (
+		expressOrderConsumer   *broker.Consumer
+		expressPaymentConsumer *broker.Consumer
+		expressOrderWorker     *worker.OrderWorker
+		expressPaymentWorker   *worker.PaymentWorker
+	)
+	if cfg.Kafka.TopicOrderExpress != "" {
+		orderWorker.SetLane(models.PriorityStandard)
+		paymentWorker.SetLane(models.PriorityStandard)
+
+		expressOrderConsumer = broker.NewConsumer(orderCore.Kafka, cfg.Kafka.TopicOrderExpress, cfg.Kafka.ConsumerGroup)
+		expressOrderConsumer.SetDeadLetter(deadLetterQueue.Handler(cfg.Kafka.ConsumerGroup))
+		expressOrderWorker = worker.NewOrderWorker(expressOrderConsumer, orderCore.Saga, healthChecker)
+		expressOrderWorker.SetLane(models.PriorityExpress)
+		go func() {
+			if err := healthChecker.RunWorker("order-worker-express", func() error { return expressOrderWorker.Start(workerCtx) }); err != nil {
+				log.Printf("Express order worker error: %v", err)
+			}
+		}()
+
+		expressPaymentConsumer = broker.NewConsumer(orderCore.Kafka, cfg.Kafka.TopicOrderExpress, "payment-service-group")
+		expressPaymentConsumer.SetDeadLetter(deadLetterQueue.Handler("payment-service-group"))
+		expressPaymentWorker = worker.NewPaymentWorker(expressPaymentConsumer, orderCore.Payments, healthChecker)
+		expressPaymentWorker.SetLane(models.PriorityExpress)
+		go func() {
+			if err := healthChecker.RunWorker("payment-worker-express", func() error { return expressPaymentWorker.Start(workerCtx) }); err != nil {
+				log.Printf("Express payment worker error: %v", err)
+			}
+		}()
 	}
 
-	router := gin.Default()
-	handler := api.NewHandler(orderService)
-	handler.SetupRoutes(router)
+	anonymizationService := service.NewAnonymizationService(db, orderCore.Events,
+		time.Duration(cfg.Jobs.AnonymizationRetentionDays)*24*time.Hour, cfg.Jobs.AnonymizationBatchSize)
+	identityConsumer := broker.NewConsumer(orderCore.Kafka, cfg.Kafka.TopicIdentity, cfg.Kafka.ConsumerGroup)
+	identityConsumer.SetDeadLetter(deadLetterQueue.Handler(cfg.Kafka.ConsumerGroup))
+	identityWorker := worker.NewIdentityWorker(identityConsumer, orderCore.Customers, anonymizationService, healthChecker)
+	go func() {
+		if err := healthChecker.RunWorker("identity-worker", func() error { return identityWorker.Start(workerCtx) }); err != nil {
+			log.Printf("Identity worker error: %v", err)
+		}
+	}()
+
+	var (
+		flashOrderConsumer *broker.Consumer
+		flashOrderWorker   *worker.FlashOrderWorker
+	)
+	if orderCore.FlashOrders != nil {
+		flashOrderConsumer = broker.NewConsumer(orderCore.Kafka, cfg.Kafka.TopicFlashOrders, cfg.Kafka.ConsumerGroup)
+		flashOrderConsumer.SetDeadLetter(deadLetterQueue.Handler(cfg.Kafka.ConsumerGroup))
+		flashOrderWorker = worker.NewFlashOrderWorker(flashOrderConsumer, orderCore.FlashOrders, healthChecker)
+		go func() {
+			if err := healthChecker.RunWorker("flash-order-worker", func() error { return flashOrderWorker.Start(workerCtx) }); err != nil {
+				log.Printf("Flash order worker error: %v", err)
+			}
+		}()
+	}
+
+	notifications, err := newNotificationService(cfg.Notify, db)
+	if err != nil {
+		log.Fatalf("Failed to set up notifications: %v", err)
+	}
+	var (
+		notificationConsumer *broker.Consumer
+		notificationWorker   *worker.NotificationWorker
+	)
+	if notifications.Enabled() {
+		notificationConsumer = broker.NewConsumer(orderCore.Kafka, cfg.Kafka.TopicOrder, "notification-service-group")
+		notificationConsumer.SetDeadLetter(deadLetterQueue.Handler("notification-service-group"))
+		notificationWorker = worker.NewNotificationWorker(notificationConsumer, notifications, healthChecker)
+		go func() {
+			if err := healthChecker.RunWorker("notification-worker", func() error { return notificationWorker.Start(workerCtx) }); err != nil {
+				log.Printf("Notification worker error: %v", err)
+			}
+		}()
+
+		notificationDispatcher := worker.NewNotificationDispatchWorker(notifications,
+			time.Duration(cfg.Jobs.NotificationPollIntervalSeconds)*time.Second)
+		go func() {
+			if err := healthChecker.RunWorker("notification-dispatcher", func() error { return notificationDispatcher.Start(workerCtx) }); err != nil && err != context.Canceled {
+				log.Printf("Notification dispatch worker error: %v", err)
+			}
+		}()
+	}
+
+	var (
+		invoices        *service.InvoiceService
+		invoiceConsumer *broker.Consumer
+		invoiceWorker   *worker.InvoiceWorker
+	)
+	if cfg.Invoices.S3Endpoint != "" {
+		invoices, err = newInvoiceService(cfg.Invoices, cfg.Business.Currency, db)
+		if err != nil {
+			log.Fatalf("Failed to set up invoices: %v", err)
+		}
+		invoiceConsumer = broker.NewConsumer(orderCore.Kafka, cfg.Kafka.TopicOrder, "invoice-service-group")
+		invoiceConsumer.SetDeadLetter(deadLetterQueue.Handler("invoice-service-group"))
+		invoiceWorker = worker.NewInvoiceWorker(invoiceConsumer, invoices, healthChecker)
+		go func() {
+			if err := healthChecker.RunWorker("invoice-worker", func() error { return invoiceWorker.Start(workerCtx) }); err != nil {
+				log.Printf("Invoice worker error: %v", err)
+			}
+		}()
+	}
+
+	var (
+		webhooks        *service.WebhookService
+		webhookConsumer *broker.Consumer
+		webhookWorker   *worker.WebhookWorker
+	)
+	if cfg.Webhooks.Enabled {
+		webhooks = service.NewWebhookService(db, service.WebhookPolicy{
+			Policy: retry.Policy{
+				MaxAttempts: cfg.Webhooks.MaxAttempts,
+				BaseDelay:   time.Duration(cfg.Webhooks.RetryBackoffSeconds) * time.Second,
+				MaxDelay:    time.Duration(cfg.Webhooks.RetryMaxBackoffSeconds) * time.Second,
+				Jitter:      0.2,
+			},
+			DisableAfterFailures:  cfg.Webhooks.DisableAfterFailures,
+			AllowInsecureURLs:     cfg.Webhooks.AllowInsecureURLs,
+			AllowPrivateAddresses: cfg.Webhooks.AllowPrivateAddresses,
+		})
+		webhookConsumer = broker.NewConsumer(orderCore.Kafka, cfg.Kafka.TopicOrder, "webhook-service-group")
+		webhookConsumer.SetDeadLetter(deadLetterQueue.Handler("webhook-service-group"))
+		webhookWorker = worker.NewWebhookWorker(webhookConsumer, webhooks, healthChecker)
+		go func() {
+			if err := healthChecker.RunWorker("webhook-worker", func() error { return webhookWorker.Start(workerCtx) }); err != nil {
+				log.Printf("Webhook worker error: %v", err)
+			}
+		}()
 
-	srv := &http.Server{
-		Addr:    fmt.Sprintf(":%s", cfg.Server.Port),
-		Handler: router,
+		webhookDispatcher := worker.NewWebhookDispatchWorker(webhooks,
+			time.Duration(cfg.Jobs.WebhookDeliveryPollIntervalSeconds)*time.Second)
+		go func() {
+			if err := healthChecker.RunWorker("webhook-dispatcher", func() error { return webhookDispatcher.Start(workerCtx) }); err != nil && err != context.Canceled {
+				log.Printf("Webhook dispatch worker error: %v", err)
+			}
+		}()
 	}
 
+	// The admin order list and search read the summaries this worker projects
+	orderSummaryConsumer := broker.NewConsumer(orderCore.Kafka, cfg.Kafka.TopicOrder, "order-summary-group")
+	orderSummaryConsumer.SetDeadLetter(deadLetterQueue.Handler("order-summary-group"))
+	orderSummaryWorker := worker.NewOrderSummaryWorker(orderSummaryConsumer, service.NewOrderSummaryProjector(db), healthChecker)
 	go func() {
-		log.Printf("Starting HTTP server on port %s", cfg.Server.Port)
+		if err := healthChecker.RunWorker("order-summary-worker", func() error { return orderSummaryWorker.Start(workerCtx) }); err != nil {
+			log.Printf("Order summary worker error: %v", err)
+		}
+	}()
+
+	scalingMonitor := service.NewScalingMonitor(db)
+	scalingMonitor.Watch("order-worker", orderConsumer)
+	scalingMonitor.Watch("payment-worker", paymentConsumer)
+	scalingMonitor.Watch("identity-worker", identityConsumer)
+	scalingMonitor.Watch("order-summary-worker", orderSummaryConsumer)
+	if expressOrderConsumer != nil {
+		scalingMonitor.Watch("order-worker-express", expressOrderConsumer)
+		scalingMonitor.Watch("payment-worker-express", expressPaymentConsumer)
+	}
+	if flashOrderConsumer != nil {
+		scalingMonitor.Watch("flash-order-worker", flashOrderConsumer)
+	}
+	if notificationConsumer != nil {
+		scalingMonitor.Watch("notification-worker", notificationConsumer)
+	}
+	if invoiceConsumer != nil {
+		scalingMonitor.Watch("invoice-worker", invoiceConsumer)
+	}
+	if webhookConsumer != nil {
+		scalingMonitor.Watch("webhook-worker", webhookConsumer)
+	}
+
+	hostname, _ := os.Hostname()
+	heartbeats := service.NewWorkerHeartbeats(redisClient, healthChecker, hostname, service.WorkerHeartbeatPolicy{
+		Timeout:        time.Duration(cfg.Observ.WorkerHeartbeatTimeoutSeconds) * time.Second,
+		StuckThreshold: time.Duration(cfg.Observ.WorkerStuckThresholdSeconds) * time.Second,
+		Critical:       cfg.Observ.CriticalWorkers,
+	})
+	heartbeats.Watch("order-worker", orderConsumer)
+	heartbeats.Watch("payment-worker", paymentConsumer)
+	heartbeats.Watch("identity-worker", identityConsumer)
+	heartbeats.Watch("order-summary-worker", orderSummaryConsumer)
+	if expressOrderConsumer != nil {
+		heartbeats.Watch("order-worker-express", expressOrderConsumer)
+		heartbeats.Watch("payment-worker-express", expressPaymentConsumer)
+	}
+	if flashOrderConsumer != nil {
+		heartbeats.Watch("flash-order-worker", flashOrderConsumer)
+	}
+	if notificationConsumer != nil {
+		heartbeats.Watch("notification-worker", notificationConsumer)
+	}
+	if invoiceConsumer != nil {
+		heartbeats.Watch("invoice-worker", invoiceConsumer)
+	}
+	if webhookConsumer != nil {
+		heartbeats.Watch("webhook-worker", webhookConsumer)
+	}
+	healthChecker.Register("workers", checkTimeout, heartbeats.Check)
+	heartbeatWorker := worker.NewHeartbeatWorker(heartbeats, time.Duration(cfg.Observ.WorkerHeartbeatIntervalSeconds)*time.Second)
+	go func() {
+		if err := heartbeatWorker.Start(workerCtx); err != nil && err != context.Canceled {
+			log.Printf("Heartbeat worker error: %v", err)
+		}
+	}()
+
+	if cfg.Jobs.InventoryReconcileIntervalSeconds > 0 {
+		reconciler := worker.NewInventoryReconciler(orderCore.Inventory,
+			time.Duration(cfg.Jobs.InventoryReconcileIntervalSeconds)*time.Second,
+			cfg.Jobs.InventoryReconcileStrategy)
+		go func() {
+			if err := healthChecker.RunWorker("inventory-reconciler", func() error { return reconciler.Start(workerCtx) }); err != nil && err != context.Canceled {
+				log.Printf("Inventory reconciler error: %v", err)
+			}
+		}()
+	}
+
+	if cfg.Jobs.OrderTimeoutReapIntervalSeconds > 0 {
+		reaper := worker.NewOrderTimeoutReaper(orderCore.Saga,
+			time.Duration(cfg.Jobs.OrderTimeoutReapIntervalSeconds)*time.Second)
+		go func() {
+			if err := healthChecker.RunWorker("order-timeout-reaper", func() error { return reaper.Start(workerCtx) }); err != nil && err != context.Canceled {
+				log.Printf("Order timeout reaper error: %v", err)
+			}
+		}()
+	}
+
+	scheduledEvents := worker.NewScheduledEventWorker(
+		service.NewScheduledEventDispatcher(redisClient, orderCore.Producer, db),
+		time.Duration(cfg.Jobs.ScheduledEventsPollIntervalMs)*time.Millisecond)
+	go func() {
+		if err := healthChecker.RunWorker("scheduled-events", func() error { return scheduledEvents.Start(workerCtx) }); err != nil && err != context.Canceled {
+			log.Printf("Scheduled event worker error: %v", err)
+		}
+	}()
+
+	if cfg.Business.PaymentMaxRetries > 0 {
+		paymentRetrier := worker.NewPaymentRetryWorker(orderCore.Payments,
+			time.Duration(cfg.Jobs.PaymentRetryPollIntervalSeconds)*time.Second)
+		go func() {
+			if err := healthChecker.RunWorker("payment-retrier", func() error { return paymentRetrier.Start(workerCtx) }); err != nil && err != context.Canceled {
+				log.Printf("Payment retry worker error: %v", err)
+			}
+		}()
+	}
+
+	compensationRetrier := worker.NewCompensationRetryWorker(orderCore.Saga,
+		time.Duration(cfg.Jobs.CompensationPollIntervalSeconds)*time.Second)
+	go func() {
+		if err := healthChecker.RunWorker("compensation-retrier", func() error { return compensationRetrier.Start(workerCtx) }); err != nil && err != context.Canceled {
+			log.Printf("Compensation retry worker error: %v", err)
+		}
+	}()
+
+	if cfg.Business.BackordersEnabled {
+		backorders := worker.NewBackorderWorker(
+			service.NewBackorderFulfiller(db, redisClient, orderCore.Inventory, orderCore.Events, orderCore.OrderCache),
+			time.Duration(cfg.Jobs.BackorderFulfillIntervalSeconds)*time.Second)
+		go func() {
+			if err := healthChecker.RunWorker("backorders", func() error { return backorders.Start(workerCtx) }); err != nil && err != context.Canceled {
+				log.Printf("Backorder worker error: %v", err)
+			}
+		}()
+	}
+
+	drops := service.NewDropService(db, orderCore.Orders, time.Duration(cfg.Jobs.DropClaimTTLSeconds)*time.Second)
+	dropWorker := worker.NewDropWorker(drops, time.Duration(cfg.Jobs.DropIntervalSeconds)*time.Second)
+	go func() {
+		if err := healthChecker.RunWorker("drops", func() error { return dropWorker.Start(workerCtx) }); err != nil && err != context.Canceled {
+			log.Printf("Drop worker error: %v", err)
+		}
+	}()
+
+	carts := service.NewCartService(db, db, orderCore.Orders, redisClient, service.CartPolicy{
+		TTL:         time.Duration(cfg.Business.CartTTLHours) * time.Hour,
+		MaxItems:    cfg.Business.CartMaxItems,
+		MaxQuantity: cfg.Business.CartMaxItemQuantity,
+	})
+	if cfg.Business.CartHoldSeconds > 0 {
+		carts.SetHolds(orderCore.Inventory, time.Duration(cfg.Business.CartHoldSeconds)*time.Second)
+	}
+	cartPurger := worker.NewCartPurgeWorker(carts, time.Duration(cfg.Jobs.CartPurgeIntervalSeconds)*time.Second)
+	go func() {
+		if err := healthChecker.RunWorker("cart-purger", func() error { return cartPurger.Start(workerCtx) }); err != nil && err != context.Canceled {
+			log.Printf("Cart purge worker error: %v", err)
+		}
+	}()
+
+	if cfg.Jobs.StatusNotifyBridgeEnabled {
+		statusBridge := worker.NewStatusBridgeWorker(
+			service.NewStatusChangeBridge(db, orderCore.Events, cfg.Jobs.StatusNotifyBridgeBatchSize),
+			time.Duration(cfg.Jobs.StatusNotifyBridgePollSeconds)*time.Second)
+		go func() {
+			if err := healthChecker.RunWorker("status-notify-bridge", func() error { return statusBridge.Start(workerCtx) }); err != nil && err != context.Canceled {
+				log.Printf("Status notify bridge error: %v", err)
+			}
+		}()
+	}
+
+	recovery := worker.NewSagaRecovery(orderCore.Saga, service.RecoveryPolicy{
+		StallThreshold: time.Duration(cfg.Jobs.SagaRecoveryStallSeconds) * time.Second,
+		MaxAttempts:    cfg.Jobs.SagaRecoveryMaxAttempts,
+	}, time.Duration(cfg.Jobs.SagaRecoveryIntervalSeconds)*time.Second)
+	go func() {
+		if err := healthChecker.RunWorker("saga-recovery", func() error { return recovery.Start(workerCtx) }); err != nil && err != context.Canceled {
+			log.Printf("Saga recovery error: %v", err)
+		}
+	}()
+
+	if cfg.Jobs.EventRedispatchLookbackMinutes > 0 {
+		orderCore.Saga.SetTimeline(db)
+		go func() {
+			redispatched, err := orderCore.Saga.RedispatchMissingEvents(workerCtx, service.RedispatchPolicy{
+				Lookback: time.Duration(cfg.Jobs.EventRedispatchLookbackMinutes) * time.Minute,
+				Grace:    time.Duration(cfg.Jobs.EventRedispatchGraceSeconds) * time.Second,
+			})
+			if err != nil {
+				log.Printf("Event re-dispatch failed: %v", err)
+				return
+			}
+			log.Printf("Event re-dispatch re-published %d missing order events", redispatched)
+		}()
+	}
+
+	if cfg.Jobs.SyntheticProbeIntervalSeconds > 0 {
+		probe := service.NewSyntheticProbe(db, orderCore.Orders, orderCore.Inventory,
+			cfg.Jobs.SyntheticProbeSKU, cfg.Jobs.SyntheticProbeUserID,
+			time.Duration(cfg.Jobs.SyntheticProbeSLOSeconds)*time.Second)
+		prober := worker.NewSyntheticProber(probe, time.Duration(cfg.Jobs.SyntheticProbeIntervalSeconds)*time.Second)
+		go func() {
+			if err := healthChecker.RunWorker("synthetic-prober", func() error { return prober.Start(workerCtx) }); err != nil && err != context.Canceled {
+				log.Printf("Synthetic prober error: %v", err)
+			}
+		}()
+	}
+
+	adminTokens, err := api.ParseAdminTokens(cfg.Server.AdminTokens, cfg.Server.AdminToken)
+	if err != nil {
+		log.Fatalf("Invalid admin token configuration: %v", err)
+	}
+	tenantAPIKeys, err := api.ParseTenantAPIKeys(cfg.Server.TenantAPIKeys)
+	if err != nil {
+		log.Fatalf("Invalid tenant API key configuration: %v", err)
+	}
+
+	handler := api.NewHandler(orderCore.Orders, availabilityFeed, healthChecker, redisClient, orderCore.Schemas, api.HandlerConfig{
+		IdempotencyTTL:        time.Duration(cfg.Cache.IdempotencyTTLHours) * time.Hour,
+		ReplayRejectThreshold: cfg.Cache.ReplayRejectThreshold,
+		StuckThreshold:        time.Duration(cfg.Observ.WorkerStuckThresholdSeconds) * time.Second,
+		ValidateRequests:      cfg.Server.ValidateRequestSchemas,
+		AdminTokens:           adminTokens,
+		TenantAPIKeys:         tenantAPIKeys,
+		TenantJWTSecret:       []byte(cfg.Server.TenantJWTSecret),
+		TenantJWTClaim:        cfg.Server.TenantJWTClaim,
+		CustomerJWTClaim:      cfg.Server.CustomerJWTClaim,
+		Currency:              cfg.Business.Currency,
+		OrderRules: api.OrderRules{
+			MaxItems:        cfg.Business.OrderMaxItems,
+			MaxItemQuantity: cfg.Business.OrderMaxItemQuantity,
+			PaymentMethods:  cfg.Business.PaymentMethods,
+			Currencies:      cfg.Business.OrderCurrencies,
+		},
+	})
+	handler.SetAdminServices(api.AdminServices{
+		Saga:        orderCore.Saga,
+		Inventory:   orderCore.Inventory,
+		DeadLetters: deadLetterQueue,
+		Plans: service.NewAdminPlanner(redisClient, orderCore.Saga, orderCore.Inventory,
+			time.Duration(cfg.Server.AdminPlanTTLSeconds)*time.Second),
+		KillSwitches: orderCore.KillSwitches,
+		Importer: service.NewInventoryImporter(db, orderCore.Inventory, orderCore.ProductCache,
+			cfg.Server.InventoryImportBatchSize),
+		AmountAudit:   service.NewAmountAuditor(db, db),
+		OrderDiff:     service.NewOrderDiffer(db, db),
+		Wallets:       orderCore.Wallets,
+		Compensations: orderCore.Compensations,
+		Notifications: notifications,
+		Workers:       heartbeats,
+		LowStock:      orderCore.LowStock,
+		Audit:         service.NewAuditLog(db),
+		Replay: service.NewEventReplayer(func(ctx context.Context, rng broker.HistoryRange, fn func(kafka.Message) (bool, error)) error {
+			return broker.ReadHistory(ctx, orderCore.Kafka, cfg.Kafka.TopicOrder, rng, fn)
+		}, worker.SagaHandler(orderCore.Saga), db),
+	})
+	handler.SetEventIngestion(api.EventIngestion{
+		Handler:        broker.Observed(orderWorker.Handler(), orderCore.Timeline.Observer(service.TimelineConsumed)),
+		AllowedTypes:   cfg.Server.EventIngestAllowedTypes,
+		AllowedSources: cfg.Server.EventIngestAllowedSources,
+	})
+	handler.SetScalingMonitor(scalingMonitor)
+	handler.SetOrderTimeline(orderCore.Timeline)
+	handler.SetOrderStatusFeed(orderCore.StatusFeed)
+	handler.SetDrops(drops)
+	handler.SetCarts(carts)
+	handler.SetIncomingStock(orderCore.IncomingStock)
+	handler.SetWebhooks(webhooks)
+	handler.SetOrderCancellation(orderCore.Saga)
+	handler.SetOrderAmendment(orderCore.Saga)
+	handler.SetFlashOrders(orderCore.FlashOrders)
+	handler.SetPayments(orderCore.Payments)
+	handler.SetInvoices(invoices)
+	orderReports := reports.NewService(db, cfg.Reports.MaxDays)
+	handler.SetReports(orderReports)
+	if cfg.Reports.S3Endpoint != "" {
+		schedule, err := reports.ParseSchedule(cfg.Reports.Schedule)
+		if err != nil {
+			log.Fatalf("Invalid REPORT_SCHEDULE: %v", err)
+		}
+		publisher, err := reports.NewS3Publisher(cfg.Reports.S3Endpoint, cfg.Reports.S3Region, cfg.Reports.S3Bucket,
+			cfg.Reports.S3AccessKey, cfg.Reports.S3SecretKey, cfg.Reports.S3UseSSL)
+		if err != nil {
+			log.Fatalf("Failed to set up report storage: %v", err)
+		}
+		reportScheduler := worker.NewReportSchedulerWorker(
+			reports.NewScheduler(orderReports, publisher, redisClient, schedule, cfg.Reports.Formats, cfg.Reports.S3Prefix))
+		go func() {
+			if err := healthChecker.RunWorker("report-scheduler", func() error { return reportScheduler.Start(workerCtx) }); err != nil && err != context.Canceled {
+				log.Printf("Report scheduler error: %v", err)
+			}
+		}()
+	}
+	if cfg.Server.GraphQLEnabled {
+		handler.SetGraphQL(graph.NewHandler(db, db, db, cfg.Server.GraphQLComplexityLimit))
+	}
+
+	if cfg.Flash.WaitingRoomMaxConcurrent > 0 {
+		waitingRoom := service.NewWaitingRoom(orderCore.Orders, redisClient, service.WaitingRoomPolicy{
+			MaxConcurrent:  cfg.Flash.WaitingRoomMaxConcurrent,
+			AdmitPerSecond: cfg.Flash.WaitingRoomAdmitPerSecond,
+			TicketTTL:      time.Duration(cfg.Flash.WaitingRoomTicketTTLSeconds) * time.Second,
+		})
+		waitingRoomDispatcher := worker.NewWaitingRoomDispatcher(waitingRoom, time.Second)
+		go func() {
+			if err := healthChecker.RunWorker("waiting-room-dispatcher", func() error { return waitingRoomDispatcher.Start(workerCtx) }); err != nil && err != context.Canceled {
+				log.Printf("Waiting room dispatcher error: %v", err)
+			}
+		}()
+		handler.SetWaitingRoom(waitingRoom)
+	}
+
+	var realtimeHub *realtime.Hub
+	var realtimeConsumer *broker.Consumer
+	if cfg.Server.RealtimeEnabled {
+		realtimeHub = realtime.NewHub(db, realtime.Limits{
+			MaxConnections:        cfg.Server.RealtimeMaxConnections,
+			MaxConnectionsPerUser: cfg.Server.RealtimeMaxConnectionsPerUser,
+			SendBuffer:            cfg.Server.RealtimeSendBuffer,
+		})
+		// Every instance needs every event for the sockets it holds, so each reads
+		// the topic in its own group, starting from the newest events
+		realtimeConsumer = broker.NewLiveConsumer(orderCore.Kafka, cfg.Kafka.TopicOrder,
+			cfg.Kafka.ConsumerGroup+"-realtime-"+hostname)
+		go func() {
+			if err := realtimeConsumer.StartConsuming(workerCtx, realtimeHub.HandleMessage); err != nil && err != context.Canceled {
+				log.Printf("Realtime consumer error: %v", err)
+			}
+		}()
+		handler.SetRealtimeHub(realtimeHub)
+	}
+
+	var router http.Handler
+	switch cfg.Server.HTTPRouter {
+	case "chi":
+		router = chirouter.New(handler.Routes())
+	default:
+		router = ginrouter.New(handler.Routes(), cfg.Server.Env == "production")
+	}
+
+	configManager := config.NewManager(cfg)
+	configManager.Subscribe(func(t config.Tunables) {
+		orderCore.Payments.SetSuccessRate(t.PaymentSuccessRate)
+		orderCore.TimeoutPolicy.SetOrderTimeout(time.Duration(t.OrderTimeoutSeconds) * time.Second)
+		handler.SetReplayRejectThreshold(t.ReplayRejectThreshold)
+		availabilityFeed.SetLimits(t.StreamMaxConnections, t.StreamMaxConnectionsPerClient)
+	})
+	go configManager.WatchSignals(workerCtx)
+
+	srv, err := api.NewServer(router, api.ServerOptions{
+		Addr:              fmt.Sprintf(":%s", cfg.Server.Port),
+		ReadHeaderTimeout: time.Duration(cfg.Server.HTTPReadHeaderTimeoutSeconds) * time.Second,
+		ReadTimeout:       time.Duration(cfg.Server.HTTPReadTimeoutSeconds) * time.Second,
+		WriteTimeout:      time.Duration(cfg.Server.HTTPWriteTimeoutSeconds) * time.Second,
+		IdleTimeout:       time.Duration(cfg.Server.HTTPIdleTimeoutSeconds) * time.Second,
+		MaxHeaderBytes:    cfg.Server.HTTPMaxHeaderBytes,
+		TLSCertFile:       cfg.Server.TLSCertFile,
+		TLSKeyFile:        cfg.Server.TLSKeyFile,
+		AutocertDomains:   cfg.Server.TLSAutocertDomains,
+		AutocertCacheDir:  cfg.Server.TLSAutocertCacheDir,
+		AutocertEmail:     cfg.Server.TLSAutocertEmail,
+		H2C:               cfg.Server.HTTPH2CEnabled,
+	})
+	if err != nil {
+		log.Fatalf("Invalid HTTP server configuration: %v", err)
+	}
+
+	go func() {
+		log.Printf("Starting HTTP server on port %s (TLS: %t)", cfg.Server.Port, srv.TLS())
 		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 			log.Fatalf("Failed to start server: %v", err)
 		}
@@ -121,16 +697,83 @@ func main() {
 
 	log.Println("Shutting down server...")
 
-	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer shutdownCancel()
-
-	if err := srv.Shutdown(shutdownCtx); err != nil {
-		log.Printf("Server forced to shutdown: %v", err)
+	coordinator := shutdown.NewCoordinator(time.Duration(cfg.Server.ShutdownDrainTimeoutSeconds) * time.Second)
+	if realtimeHub != nil {
+		// Hijacked WebSocket connections are not closed by srv.Shutdown
+		coordinator.OnDrain("websockets", realtimeHub.Close)
+	}
+	coordinator.OnDrain("http", srv.Shutdown)
+	coordinator.OnDrain("consumers", func(ctx context.Context) error {
+		// Stop fetching and let the handlers already running finish their saga step
+		workerCancel()
+		drainers := []interface{ Drain(context.Context) error }{orderWorker, paymentWorker, identityWorker, orderSummaryWorker}
+		if expressOrderWorker != nil {
+			drainers = append(drainers, expressOrderWorker, expressPaymentWorker)
+		}
+		if flashOrderWorker != nil {
+			drainers = append(drainers, flashOrderWorker)
+		}
+		if notificationWorker != nil {
+			drainers = append(drainers, notificationWorker)
+		}
+		if invoiceWorker != nil {
+			drainers = append(drainers, invoiceWorker)
+		}
+		if webhookWorker != nil {
+			drainers = append(drainers, webhookWorker)
+		}
+		for _, w := range drainers {
+			if err := w.Drain(ctx); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	// Drained handlers may have just published; the writer flushes on close
+	coordinator.OnDrain("quota-leases", orderCore.Inventory.ReturnLeases)
+	coordinator.OnDrain("producer", func(context.Context) error { return orderCore.Producer.Close() })
+	if orderCore.FlashProducer != nil {
+		coordinator.OnDrain("flash-producer", func(context.Context) error { return orderCore.FlashProducer.Close() })
+	}
+	if orderCore.ExpressProducer != nil {
+		coordinator.OnDrain("express-producer", func(context.Context) error { return orderCore.ExpressProducer.Close() })
 	}
+	if orderCore.AnalyticsProducer != nil {
+		coordinator.OnDrain("analytics-producer", func(context.Context) error { return orderCore.AnalyticsProducer.Close() })
+	}
+	coordinator.OnDrain("republisher", func(context.Context) error { return republisher.Close() })
+	coordinator.OnClose("kafka", func(context.Context) error {
+		orderWorker.Stop()
+		paymentWorker.Stop()
+		identityWorker.Stop()
+		orderSummaryWorker.Stop()
+		if expressOrderWorker != nil {
+			expressOrderWorker.Stop()
+			expressPaymentWorker.Stop()
+		}
+		if flashOrderWorker != nil {
+			flashOrderWorker.Stop()
+		}
+		if notificationWorker != nil {
+			notificationWorker.Stop()
+		}
+		if invoiceWorker != nil {
+			invoiceWorker.Stop()
+		}
+		if webhookWorker != nil {
+			webhookWorker.Stop()
+		}
+		if realtimeConsumer != nil {
+			realtimeConsumer.Close()
+		}
+		return nil
+	})
+	coordinator.OnClose("redis", func(context.Context) error { return redisClient.Close() })
+	coordinator.OnClose("postgres", func(context.Context) error { return db.Close() })
 
-	workerCancel()
-	orderWorker.Stop()
-	paymentWorker.Stop()
+	if !coordinator.Shutdown() {
+		log.Println("Shutdown drain did not complete cleanly")
+	}
 
 	log.Println("Server exited")
 }
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"time"
+
+	"order-service/config"
+	"order-service/internal/models"
+	"order-service/internal/notification"
+	"order-service/internal/resilience/retry"
+	"order-service/internal/store"
+)
+
+// newNotificationService builds the customer notification service with a
+// notifier per configured channel
+func newNotificationService(cfg config.NotificationConfig, db *store.Store) (*notification.Service, error) {
+	notifications, err := notification.NewService(db, db, db, retry.Policy{
+		MaxAttempts: cfg.MaxAttempts,
+		BaseDelay:   time.Duration(cfg.RetryBackoffSeconds) * time.Second,
+		MaxDelay:    time.Duration(cfg.RetryMaxBackoffSeconds) * time.Second,
+		Jitter:      0.2,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, channel := range cfg.Channels {
+		switch channel {
+		case models.NotificationEmail:
+			notifications.Register(channel, notification.NewSMTPNotifier(cfg.SMTPAddr, cfg.SMTPUsername, cfg.SMTPPassword, cfg.EmailFrom))
+		case models.NotificationSMS:
+			notifications.Register(channel, notification.NewSMSNotifier(notification.NewHTTPSMSGateway(cfg.SMSGatewayURL, cfg.SMSGatewayToken)))
+		case models.NotificationWebhook:
+			notifications.Register(channel, notification.NewWebhookNotifier(cfg.WebhookSecret))
+		}
+	}
+	return notifications, nil
+}
@@ -0,0 +1,222 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Where an effective setting came from; environment variables override the config file
+const (
+	SourceDefault = "default"
+	SourceFile    = "file"
+	SourceEnv     = "env"
+)
+
+// Setting is one effective configuration value and its source
+type Setting struct {
+	Key    string
+	Value  string
+	Source string
+}
+
+// loader reads typed settings from the environment and an optional config
+// file, recording each one and collecting parse errors instead of dropping them
+type loader struct {
+	file     map[string]string
+	settings map[string]Setting
+	errs     []string
+}
+
+func newLoader(file map[string]string) *loader {
+	return &loader{file: file, settings: make(map[string]Setting)}
+}
+
+func (l *loader) errorf(format string, args ...interface{}) {
+	l.errs = append(l.errs, fmt.Sprintf(format, args...))
+}
+
+// lookup returns the raw value of key, falling back from env to file to def
+func (l *loader) lookup(key, def string) string {
+	value, source := def, SourceDefault
+	if v := l.file[key]; v != "" {
+		value, source = v, SourceFile
+	}
+	if v := os.Getenv(key); v != "" {
+		value, source = v, SourceEnv
+	}
+
+	if _, seen := l.settings[key]; !seen {
+		l.settings[key] = Setting{Key: key, Value: value, Source: source}
+	}
+	return value
+}
+
+func (l *loader) getString(key, def string) string {
+	return l.lookup(key, def)
+}
+
+func (l *loader) getInt(key string, def int) int {
+	raw := l.lookup(key, strconv.Itoa(def))
+	v, err := strconv.Atoi(strings.TrimSpace(raw))
+	if err != nil {
+		l.errorf("%s: %q is not an integer", key, raw)
+		return def
+	}
+	return v
+}
+
+func (l *loader) getInt64(key string, def int64) int64 {
+	raw := l.lookup(key, strconv.FormatInt(def, 10))
+	v, err := strconv.ParseInt(strings.TrimSpace(raw), 10, 64)
+	if err != nil {
+		l.errorf("%s: %q is not an integer", key, raw)
+		return def
+	}
+	return v
+}
+
+func (l *loader) getFloat(key string, def float64) float64 {
+	raw := l.lookup(key, strconv.FormatFloat(def, 'f', -1, 64))
+	v, err := strconv.ParseFloat(strings.TrimSpace(raw), 64)
+	if err != nil {
+		l.errorf("%s: %q is not a number", key, raw)
+		return def
+	}
+	return v
+}
+
+func (l *loader) getBool(key string, def bool) bool {
+	raw := l.lookup(key, strconv.FormatBool(def))
+	v, err := strconv.ParseBool(strings.TrimSpace(raw))
+	if err != nil {
+		l.errorf("%s: %q is not a boolean", key, raw)
+		return def
+	}
+	return v
+}
+
+func (l *loader) getList(key string) []string {
+	var values []string
+	for _, raw := range strings.Split(l.lookup(key, ""), ",") {
+		if raw = strings.TrimSpace(raw); raw != "" {
+			values = append(values, raw)
+		}
+	}
+	return values
+}
+
+func (l *loader) getInt64List(key string) []int64 {
+	var values []int64
+	for _, raw := range l.getList(key) {
+		v, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			l.errorf("%s: %q is not an integer", key, raw)
+			continue
+		}
+		values = append(values, v)
+	}
+	return values
+}
+
+// readConfigFile loads a flat map of setting names to values from a YAML or
+// JSON file. Keys use the environment variable names; lists may be arrays.
+func readConfigFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	raw := make(map[string]interface{})
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		err = json.Unmarshal(data, &raw)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &raw)
+	default:
+		return nil, fmt.Errorf("config file %s must be .yaml, .yml or .json", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	values := make(map[string]string, len(raw))
+	for key, value := range raw {
+		values[strings.ToUpper(key)] = fileValue(value)
+	}
+	return values, nil
+}
+
+// fileValue renders a config file value the way it would be written in an env var
+func fileValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case []interface{}:
+		parts := make([]string, 0, len(v))
+		for _, item := range v {
+			parts = append(parts, fileValue(item))
+		}
+		return strings.Join(parts, ",")
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// secretMarkers flag settings whose values are never logged
+var secretMarkers = []string{"PASSWORD", "TOKEN", "SECRET", "API_KEY", "LOCAL_KEYS"}
+
+// redact hides secrets and the password part of connection URLs
+func redact(key, value string) string {
+	if value == "" {
+		return value
+	}
+	for _, marker := range secretMarkers {
+		if strings.Contains(key, marker) {
+			return "[redacted]"
+		}
+	}
+	if strings.HasSuffix(key, "_URLS") {
+		urls := strings.Split(value, ",")
+		for i, raw := range urls {
+			urls[i] = redact(strings.TrimSuffix(key, "S"), raw)
+		}
+		return strings.Join(urls, ",")
+	}
+	if strings.HasSuffix(key, "_URL") {
+		if u, err := url.Parse(strings.TrimSpace(value)); err == nil && u.User != nil {
+			return u.Redacted()
+		}
+	}
+	return value
+}
+
+// Report renders the effective configuration one setting per line, sorted by
+// key, with its source and secrets redacted
+func (c *Config) Report() string {
+	keys := make([]string, 0, len(c.settings))
+	for key := range c.settings {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	for _, key := range keys {
+		s := c.settings[key]
+		fmt.Fprintf(&b, "  %s=%s (%s)\n", s.Key, redact(s.Key, s.Value), s.Source)
+	}
+	return b.String()
+}
+
+// isSet reports whether key was given explicitly rather than defaulted
+func (c *Config) isSet(key string) bool {
+	s, ok := c.settings[key]
+	return ok && s.Source != SourceDefault
+}
@@ -0,0 +1,105 @@
+package config
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+)
+
+// Tunables are the settings that can change without a restart. Everything
+// else is structural (connections, topics, schedules) and needs one.
+type Tunables struct {
+	PaymentSuccessRate            float64
+	OrderTimeoutSeconds           int
+	ReplayRejectThreshold         int
+	StreamMaxConnections          int
+	StreamMaxConnectionsPerClient int
+}
+
+// Tunables returns the reloadable part of the configuration
+func (c *Config) Tunables() Tunables {
+	return Tunables{
+		PaymentSuccessRate:            c.Business.PaymentSuccessRate,
+		OrderTimeoutSeconds:           c.Business.OrderTimeoutSeconds,
+		ReplayRejectThreshold:         c.Cache.ReplayRejectThreshold,
+		StreamMaxConnections:          c.Flash.StreamMaxConnections,
+		StreamMaxConnectionsPerClient: c.Flash.StreamMaxConnectionsPerClient,
+	}
+}
+
+// Manager holds the current tunables and notifies subscribers when a reload
+// changes them
+type Manager struct {
+	load func() (*Config, error)
+
+	mu          sync.Mutex
+	current     Tunables
+	subscribers []func(Tunables)
+}
+
+// NewManager creates a manager starting from an already loaded config
+func NewManager(cfg *Config) *Manager {
+	return &Manager{load: Load, current: cfg.Tunables()}
+}
+
+// Tunables returns the current tunables
+func (m *Manager) Tunables() Tunables {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.current
+}
+
+// Subscribe registers fn to be called with the new tunables after each
+// reload that changes them
+func (m *Manager) Subscribe(fn func(Tunables)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.subscribers = append(m.subscribers, fn)
+}
+
+// Reload loads the configuration again and applies changed tunables. An
+// invalid configuration is rejected as a whole and the current one kept.
+func (m *Manager) Reload() error {
+	cfg, err := m.load()
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	next := cfg.Tunables()
+	if next == m.current {
+		m.mu.Unlock()
+		log.Println("Config reloaded: no tunables changed")
+		return nil
+	}
+	m.current = next
+	subscribers := append([]func(Tunables){}, m.subscribers...)
+	m.mu.Unlock()
+
+	log.Printf("Config reloaded: %+v", next)
+	for _, fn := range subscribers {
+		fn(next)
+	}
+	return nil
+}
+
+// WatchSignals reloads on SIGHUP until ctx is done
+func (m *Manager) WatchSignals(ctx context.Context) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			if err := m.Reload(); err != nil {
+				log.Printf("Config reload rejected: %v", err)
+			}
+		}
+	}
+}
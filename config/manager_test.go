@@ -0,0 +1,41 @@
+package config
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReloadNotifiesSubscribersOfChangedTunables(t *testing.T) {
+	cfg, err := Load()
+	require.NoError(t, err)
+	m := NewManager(cfg)
+
+	var got []Tunables
+	m.Subscribe(func(t Tunables) { got = append(got, t) })
+
+	require.NoError(t, m.Reload())
+	assert.Empty(t, got, "unchanged tunables must not notify")
+
+	t.Setenv("PAYMENT_SUCCESS_RATE", "0.5")
+	require.NoError(t, m.Reload())
+	require.Len(t, got, 1)
+	assert.Equal(t, 0.5, got[0].PaymentSuccessRate)
+	assert.Equal(t, 0.5, m.Tunables().PaymentSuccessRate)
+}
+
+func TestReloadKeepsCurrentTunablesOnInvalidConfig(t *testing.T) {
+	cfg, err := Load()
+	require.NoError(t, err)
+	m := NewManager(cfg)
+	m.load = func() (*Config, error) { return nil, errors.New("invalid configuration") }
+
+	called := false
+	m.Subscribe(func(Tunables) { called = true })
+
+	assert.Error(t, m.Reload())
+	assert.False(t, called)
+	assert.Equal(t, cfg.Tunables(), m.Tunables())
+}
@@ -0,0 +1,207 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// requiredInProduction must be set explicitly when ENV=production, so a
+// deployment never silently talks to the localhost defaults
+var requiredInProduction = []string{"DATABASE_URL", "KAFKA_BROKERS"}
+
+// Validate checks ranges, enumerations and cross-field requirements
+func (c *Config) Validate() error {
+	var errs []string
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			errs = append(errs, fmt.Sprintf(format, args...))
+		}
+	}
+	oneOf := func(key, value string, allowed ...string) {
+		for _, a := range allowed {
+			if value == a {
+				return
+			}
+		}
+		errs = append(errs, fmt.Sprintf("%s: %q must be one of %s", key, value, strings.Join(allowed, ", ")))
+	}
+
+	port, err := strconv.Atoi(c.Server.Port)
+	check(err == nil && port > 0 && port < 65536, "PORT: %q is not a valid port", c.Server.Port)
+	check(c.Server.ShutdownDrainTimeoutSeconds > 0, "SHUTDOWN_DRAIN_TIMEOUT_SECONDS must be positive")
+	check(c.Server.AdminPlanTTLSeconds > 0, "ADMIN_PLAN_TTL_SECONDS must be positive")
+	check(c.Server.InventoryImportBatchSize > 0, "INVENTORY_IMPORT_BATCH_SIZE must be positive")
+	check(c.Server.TenantJWTSecret == "" || c.Server.TenantJWTClaim != "", "TENANT_JWT_CLAIM is required with TENANT_JWT_SECRET")
+	oneOf("HTTP_ROUTER", c.Server.HTTPRouter, "gin", "chi")
+	check(c.Server.HTTPReadHeaderTimeoutSeconds > 0 && c.Server.HTTPReadTimeoutSeconds > 0 &&
+		c.Server.HTTPWriteTimeoutSeconds > 0 && c.Server.HTTPIdleTimeoutSeconds > 0, "HTTP_*_TIMEOUT_SECONDS must be positive")
+	check(c.Server.HTTPReadHeaderTimeoutSeconds <= c.Server.HTTPReadTimeoutSeconds,
+		"HTTP_READ_HEADER_TIMEOUT_SECONDS must be at most HTTP_READ_TIMEOUT_SECONDS")
+	check(c.Server.HTTPMaxHeaderBytes >= 4096, "HTTP_MAX_HEADER_BYTES must be at least 4096")
+	check((c.Server.TLSCertFile == "") == (c.Server.TLSKeyFile == ""), "TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	check(c.Server.TLSCertFile == "" || len(c.Server.TLSAutocertDomains) == 0, "TLS_CERT_FILE and TLS_AUTOCERT_DOMAINS are mutually exclusive")
+	check(len(c.Server.TLSAutocertDomains) == 0 || c.Server.TLSAutocertCacheDir != "", "TLS_AUTOCERT_CACHE_DIR is required with TLS_AUTOCERT_DOMAINS")
+	check(!c.Server.HTTPH2CEnabled || (c.Server.TLSCertFile == "" && len(c.Server.TLSAutocertDomains) == 0),
+		"HTTP_H2C_ENABLED only applies without TLS, which offers HTTP/2 anyway")
+	check(c.Server.OrderTrackingMaxConnections > 0, "ORDER_TRACKING_MAX_CONNECTIONS must be positive")
+	check(c.Server.RealtimeMaxConnections > 0, "REALTIME_MAX_CONNECTIONS must be positive")
+	check(c.Server.RealtimeMaxConnectionsPerUser > 0, "REALTIME_MAX_CONNECTIONS_PER_USER must be positive")
+	check(c.Server.RealtimeSendBuffer > 0, "REALTIME_SEND_BUFFER must be positive")
+	check(c.Server.GraphQLComplexityLimit > 0, "GRAPHQL_COMPLEXITY_LIMIT must be positive")
+
+	check(c.Database.MaxRetryAttempts > 0, "DB_MAX_RETRY_ATTEMPTS must be positive")
+	check(c.Database.ReadRetryAttempts > 0, "DB_READ_RETRY_ATTEMPTS must be positive")
+	check(c.Database.PoolResetThreshold > 0, "DB_POOL_RESET_THRESHOLD must be positive")
+	check(c.Database.SlowQueryMs >= 0, "DB_SLOW_QUERY_MS must not be negative")
+	oneOf("PII_KEY_PROVIDER", c.Database.PIIKeyProvider, "", "local", "vault")
+	check(c.Database.PIIKeyProvider != "local" || (c.Database.PIILocalKeys != "" && c.Database.PIIActiveKeyID != ""),
+		"PII_LOCAL_KEYS and PII_ACTIVE_KEY_ID are required with PII_KEY_PROVIDER=local")
+	check(c.Database.PIIKeyProvider != "vault" || (c.Database.PIIVaultAddr != "" && c.Database.PIIVaultKey != ""),
+		"PII_VAULT_ADDR and PII_VAULT_KEY are required with PII_KEY_PROVIDER=vault")
+
+	oneOf("REDIS_MODE", c.Redis.Mode, "standalone", "cluster", "sentinel")
+	check(c.Redis.Mode != "sentinel" || c.Redis.MasterName != "", "REDIS_SENTINEL_MASTER is required in sentinel mode")
+	check(c.Redis.DB >= 0, "REDIS_DB must not be negative")
+	check(c.Redis.MaxRetries >= 0, "REDIS_MAX_RETRIES must not be negative")
+
+	check(c.Kafka.TLSEnabled || (c.Kafka.TLSCAFile == "" && c.Kafka.TLSCertFile == ""),
+		"KAFKA_TLS_CA_FILE and KAFKA_TLS_CERT_FILE need KAFKA_TLS_ENABLED")
+	check((c.Kafka.TLSCertFile == "") == (c.Kafka.TLSKeyFile == ""), "KAFKA_TLS_CERT_FILE and KAFKA_TLS_KEY_FILE must be set together")
+	oneOf("KAFKA_SASL_MECHANISM", c.Kafka.SASLMechanism, "", "plain", "scram-sha-256", "scram-sha-512")
+	check(c.Kafka.SASLMechanism == "" || (c.Kafka.SASLUsername != "" && c.Kafka.SASLPassword != ""),
+		"KAFKA_SASL_USERNAME and KAFKA_SASL_PASSWORD are required with KAFKA_SASL_MECHANISM")
+	check(c.Kafka.SASLMechanism != "plain" || c.Kafka.TLSEnabled, "KAFKA_SASL_MECHANISM=plain sends the password in clear text without KAFKA_TLS_ENABLED")
+	check(c.Kafka.TopicOrderExpress != c.Kafka.TopicOrder, "KAFKA_TOPIC_ORDER_EXPRESS_EVENTS must differ from KAFKA_TOPIC_ORDER_EVENTS")
+	oneOf("EVENT_FIELD_NAMING", c.Kafka.EventFieldNaming, "camelCase", "snake_case")
+	check(c.Kafka.EventPublishRetryDelaySeconds >= 0, "EVENT_PUBLISH_RETRY_DELAY_SECONDS must not be negative")
+	check(c.Kafka.DeadLetterMaxRedrives > 0, "DLQ_MAX_REDRIVES must be positive")
+
+	check(c.Observ.HealthCheckTimeoutMs > 0, "HEALTH_CHECK_TIMEOUT_MS must be positive")
+	check(c.Observ.WorkerStuckThresholdSeconds > 0, "WORKER_STUCK_THRESHOLD_SECONDS must be positive")
+	check(c.Observ.WorkerHeartbeatIntervalSeconds > 0, "WORKER_HEARTBEAT_INTERVAL_SECONDS must be positive")
+	check(c.Observ.WorkerHeartbeatTimeoutSeconds > c.Observ.WorkerHeartbeatIntervalSeconds,
+		"WORKER_HEARTBEAT_TIMEOUT_SECONDS must be greater than WORKER_HEARTBEAT_INTERVAL_SECONDS")
+
+	check(c.Business.OrderTimeoutSeconds > 0, "ORDER_TIMEOUT_SECONDS must be positive")
+	check(c.Business.PaymentTimeoutSeconds > 0, "PAYMENT_TIMEOUT_SECONDS must be positive")
+	check(c.Business.PaymentSuccessRate >= 0 && c.Business.PaymentSuccessRate <= 1, "PAYMENT_SUCCESS_RATE must be between 0 and 1")
+	check(c.Business.PaymentMaxRetries >= 0, "PAYMENT_MAX_RETRIES must not be negative")
+	check(c.Business.AvailabilityMaxLag >= 0, "AVAILABILITY_MAX_LAG must not be negative")
+	check(c.Business.CartTTLHours > 0, "CART_TTL_HOURS must be positive")
+	check(c.Business.CartMaxItems > 0 && c.Business.CartMaxItemQuantity > 0,
+		"CART_MAX_ITEMS and CART_MAX_ITEM_QUANTITY must be positive")
+	check(c.Business.CartHoldSeconds >= 0, "CART_HOLD_SECONDS must not be negative")
+	check(c.Business.OrderMaxItems > 0 && c.Business.OrderMaxItems <= 100, "ORDER_MAX_ITEMS must be between 1 and 100")
+	check(c.Business.OrderMaxItemQuantity > 0, "ORDER_MAX_ITEM_QUANTITY must be positive")
+	for _, currency := range c.Business.OrderCurrencies {
+		check(len(currency) == 3 && strings.ToUpper(currency) == currency, "ORDER_CURRENCIES: %q is not an ISO 4217 code", currency)
+	}
+	check(c.Business.PaymentRetryBackoffSeconds > 0 && c.Business.PaymentRetryMaxBackoffSeconds >= c.Business.PaymentRetryBackoffSeconds,
+		"PAYMENT_RETRY_BACKOFF_SECONDS must be positive and at most PAYMENT_RETRY_MAX_BACKOFF_SECONDS")
+	oneOf("STOCK_COMMIT_FAILURE_POLICY", c.Business.StockCommitFailurePolicy, "void", "hold")
+	check(len(c.Business.Currency) == 3 && strings.ToUpper(c.Business.Currency) == c.Business.Currency,
+		"BUSINESS_CURRENCY: %q is not an ISO 4217 code", c.Business.Currency)
+	oneOf("WAREHOUSE_ALLOCATION", c.Business.WarehouseAllocation, "nearest", "most-stock", "round-robin")
+	check(c.Business.StockCommitMaxAttempts > 0, "STOCK_COMMIT_MAX_ATTEMPTS must be positive")
+	check(c.Business.StockCommitBackoffMs >= 0, "STOCK_COMMIT_BACKOFF_MS must not be negative")
+	check(c.Business.CompensationMaxAttempts > 0, "COMPENSATION_MAX_ATTEMPTS must be positive")
+	check(c.Business.CompensationRetryBackoffSeconds > 0 && c.Business.CompensationRetryMaxBackoffSeconds >= c.Business.CompensationRetryBackoffSeconds,
+		"COMPENSATION_RETRY_BACKOFF_SECONDS must be positive and at most COMPENSATION_RETRY_MAX_BACKOFF_SECONDS")
+	check(c.Business.SLAReservationSeconds >= 0 && c.Business.SLAPaymentSeconds >= 0 && c.Business.SLAConfirmationSeconds >= 0,
+		"SLA_*_SECONDS must not be negative")
+	check(c.Business.PaymentReminderAfterSeconds >= 0, "PAYMENT_REMINDER_AFTER_SECONDS must not be negative")
+	check(c.Business.ReservationExpiryWarningSeconds >= 0, "RESERVATION_EXPIRY_WARNING_SECONDS must not be negative")
+	check(c.Business.RiskLargeOrderAmount >= 0, "RISK_LARGE_ORDER_AMOUNT must not be negative")
+	check(c.Business.RiskBulkUnits >= 0, "RISK_BULK_UNITS must not be negative")
+	check(0 < c.Business.RiskBandMediumScore && c.Business.RiskBandMediumScore < c.Business.RiskBandHighScore && c.Business.RiskBandHighScore <= 100,
+		"RISK_BAND_MEDIUM_SCORE and RISK_BAND_HIGH_SCORE must satisfy 0 < medium < high <= 100")
+
+	check(c.Cache.OrderTTLSeconds >= 0 && c.Cache.OrderLocalTTLSeconds >= 0 && c.Cache.ProductTTLSeconds >= 0 &&
+		c.Cache.ProductLocalTTLSeconds >= 0, "cache TTLs must not be negative")
+	check(c.Cache.ProductLocalSize >= 0, "PRODUCT_CACHE_LOCAL_SIZE must not be negative")
+	check(c.Cache.IdempotencyTTLHours > 0, "IDEMPOTENCY_TTL_HOURS must be positive")
+	check(c.Cache.ReplayRejectThreshold >= 0, "IDEMPOTENCY_REPLAY_REJECT_THRESHOLD must not be negative")
+
+	check(c.Jobs.InventoryReconcileIntervalSeconds >= 0, "INVENTORY_RECONCILE_INTERVAL_SECONDS must not be negative")
+	oneOf("INVENTORY_RECONCILE_STRATEGY", c.Jobs.InventoryReconcileStrategy, "db-wins", "redis-wins", "alert-only")
+	check(c.Jobs.OrderTimeoutReapIntervalSeconds >= 0, "ORDER_TIMEOUT_REAP_INTERVAL_SECONDS must not be negative")
+	check(c.Jobs.SyntheticProbeIntervalSeconds >= 0, "SYNTHETIC_PROBE_INTERVAL_SECONDS must not be negative")
+	check(c.Jobs.SyntheticProbeIntervalSeconds == 0 || c.Jobs.SyntheticProbeUserID > 0,
+		"SYNTHETIC_PROBE_USER_ID is required when the synthetic probe is enabled")
+	check(c.Jobs.AnonymizationRetentionDays > 0, "ANONYMIZATION_RETENTION_DAYS must be positive")
+	check(c.Jobs.AnonymizationBatchSize > 0, "ANONYMIZATION_BATCH_SIZE must be positive")
+	check(c.Jobs.SagaRecoveryIntervalSeconds >= 0, "SAGA_RECOVERY_INTERVAL_SECONDS must not be negative")
+	check(c.Jobs.SagaRecoveryStallSeconds > 0, "SAGA_RECOVERY_STALL_SECONDS must be positive")
+	check(c.Jobs.SagaRecoveryMaxAttempts > 0, "SAGA_RECOVERY_MAX_ATTEMPTS must be positive")
+	check(c.Jobs.EventRedispatchLookbackMinutes >= 0, "EVENT_REDISPATCH_LOOKBACK_MINUTES must not be negative")
+	check(c.Jobs.EventRedispatchGraceSeconds >= 0, "EVENT_REDISPATCH_GRACE_SECONDS must not be negative")
+	check(c.Jobs.ScheduledEventsPollIntervalMs > 0, "SCHEDULED_EVENTS_POLL_INTERVAL_MS must be positive")
+	check(c.Jobs.PaymentRetryPollIntervalSeconds > 0, "PAYMENT_RETRY_POLL_INTERVAL_SECONDS must be positive")
+	check(c.Jobs.CompensationPollIntervalSeconds > 0, "COMPENSATION_POLL_INTERVAL_SECONDS must be positive")
+	check(c.Jobs.NotificationPollIntervalSeconds > 0, "NOTIFICATION_POLL_INTERVAL_SECONDS must be positive")
+	check(c.Jobs.WebhookDeliveryPollIntervalSeconds > 0, "WEBHOOK_DELIVERY_POLL_INTERVAL_SECONDS must be positive")
+	check(c.Jobs.BackorderFulfillIntervalSeconds > 0, "BACKORDER_FULFILL_INTERVAL_SECONDS must be positive")
+	check(c.Jobs.DropIntervalSeconds > 0, "DROP_INTERVAL_SECONDS must be positive")
+	check(c.Jobs.DropClaimTTLSeconds > 0, "DROP_CLAIM_TTL_SECONDS must be positive")
+	check(c.Jobs.CartPurgeIntervalSeconds > 0, "CART_PURGE_INTERVAL_SECONDS must be positive")
+	check(c.Jobs.StatusNotifyBridgePollSeconds > 0, "STATUS_NOTIFY_BRIDGE_POLL_SECONDS must be positive")
+	check(c.Jobs.StatusNotifyBridgeBatchSize > 0, "STATUS_NOTIFY_BRIDGE_BATCH_SIZE must be positive")
+
+	check(c.Flash.StreamMaxConnections > 0 && c.Flash.StreamMaxConnectionsPerClient > 0,
+		"AVAILABILITY_STREAM_MAX_CONNECTIONS* must be positive")
+	check(c.Flash.QuotaLeaseSize > 0, "QUOTA_LEASE_SIZE must be positive")
+	check(c.Flash.QuotaLeaseTTLSeconds > 0, "QUOTA_LEASE_TTL_SECONDS must be positive")
+	check(c.Flash.MetricsMaxProducts > 0, "PRODUCT_METRICS_MAX_PRODUCTS must be positive")
+	check(!c.Flash.AsyncOrders || c.Kafka.TopicFlashOrders != "", "KAFKA_TOPIC_FLASH_ORDERS is required with FLASH_SALE_ASYNC_ORDERS")
+	check(c.Flash.WaitingRoomMaxConcurrent >= 0, "WAITING_ROOM_MAX_CONCURRENT must not be negative")
+	check(c.Flash.WaitingRoomAdmitPerSecond > 0, "WAITING_ROOM_ADMIT_PER_SECOND must be positive")
+	check(c.Flash.WaitingRoomTicketTTLSeconds > 0, "WAITING_ROOM_TICKET_TTL_SECONDS must be positive")
+	check(c.Flash.FairReservationWaitMs > 0, "FAIR_RESERVATION_WAIT_MS must be positive")
+
+	for _, channel := range c.Notify.Channels {
+		oneOf("NOTIFICATION_CHANNELS", channel, "email", "sms", "webhook")
+		switch channel {
+		case "email":
+			check(c.Notify.SMTPAddr != "" && c.Notify.EmailFrom != "", "SMTP_ADDR and NOTIFICATION_EMAIL_FROM are required for email notifications")
+		case "sms":
+			check(c.Notify.SMSGatewayURL != "", "SMS_GATEWAY_URL is required for sms notifications")
+		}
+	}
+	check(c.Notify.MaxAttempts > 0, "NOTIFICATION_MAX_ATTEMPTS must be positive")
+	check(c.Notify.RetryBackoffSeconds > 0 && c.Notify.RetryMaxBackoffSeconds >= c.Notify.RetryBackoffSeconds,
+		"NOTIFICATION_RETRY_BACKOFF_SECONDS must be positive and at most NOTIFICATION_RETRY_MAX_BACKOFF_SECONDS")
+
+	check(c.Webhooks.MaxAttempts > 0, "WEBHOOK_DELIVERY_MAX_ATTEMPTS must be positive")
+	check(c.Webhooks.RetryBackoffSeconds > 0 && c.Webhooks.RetryMaxBackoffSeconds >= c.Webhooks.RetryBackoffSeconds,
+		"WEBHOOK_RETRY_BACKOFF_SECONDS must be positive and at most WEBHOOK_RETRY_MAX_BACKOFF_SECONDS")
+	check(c.Webhooks.DisableAfterFailures > 0, "WEBHOOK_DISABLE_AFTER_FAILURES must be positive")
+
+	check(c.Reports.MaxDays > 0, "REPORT_MAX_DAYS must be positive")
+	for _, format := range c.Reports.Formats {
+		oneOf("REPORT_FORMATS", format, "csv", "json", "parquet")
+	}
+	if c.Reports.S3Endpoint != "" {
+		check(c.Reports.S3Bucket != "", "REPORT_S3_BUCKET is required with REPORT_S3_ENDPOINT")
+		check(len(strings.Fields(c.Reports.Schedule)) == 5, "REPORT_SCHEDULE: %q must have 5 fields: minute hour day month weekday", c.Reports.Schedule)
+	}
+
+	// S3 signs links for at most 7 days
+	check(c.Invoices.LinkTTLSeconds > 0 && c.Invoices.LinkTTLSeconds <= 7*24*3600,
+		"INVOICE_LINK_TTL_SECONDS must be positive and at most 604800 (7 days)")
+	if c.Invoices.S3Endpoint != "" {
+		check(c.Invoices.S3Bucket != "", "INVOICE_S3_BUCKET is required with INVOICE_S3_ENDPOINT")
+	}
+
+	if c.Server.Env == "production" {
+		for _, key := range requiredInProduction {
+			check(c.isSet(key), "%s is required in production", key)
+		}
+		check(c.isSet("REDIS_ADDR") || c.isSet("REDIS_ADDRS"), "REDIS_ADDR or REDIS_ADDRS is required in production")
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid configuration: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
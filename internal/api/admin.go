@@ -0,0 +1,968 @@
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"order-service/internal/apperrors"
+	"order-service/internal/broker"
+	"order-service/internal/fraud"
+	"order-service/internal/models"
+	"order-service/internal/notification"
+	"order-service/internal/rbac"
+	"order-service/internal/service"
+	"order-service/internal/tenant"
+)
+
+// roleOperator is the role of admin tokens from before rbac roles; it has
+// every permission
+const roleOperator = "operator"
+
+// adminRoleKey is the request context key holding the caller's admin role
+type adminRoleKey struct{}
+
+// adminTokenKey is the request context key holding a fingerprint of the
+// caller's admin token, telling apart callers sharing a role
+type adminTokenKey struct{}
+
+// AdminServices backs the mutating admin endpoints
+type AdminServices struct {
+	Saga          *service.SagaOrchestrator
+	Inventory     *service.InventoryClient
+	DeadLetters   *service.DeadLetterQueue
+	Plans         *service.AdminPlanner
+	KillSwitches  *service.KillSwitches
+	Importer      *service.InventoryImporter
+	AmountAudit   *service.AmountAuditor
+	OrderDiff     *service.OrderDiffer
+	Replay        *service.EventReplayer
+	Workers       *service.WorkerHeartbeats
+	Wallets       *service.WalletService
+	Compensations *service.CompensationQueue
+	Notifications *notification.Service
+	LowStock      *service.LowStockMonitor
+	Audit         *service.AuditLog
+}
+
+// SetAdminServices enables the admin operations endpoints
+func (h *Handler) SetAdminServices(services AdminServices) {
+	h.admin = services
+}
+
+// ParseAdminTokens parses comma-separated role:token pairs into a token to
+// role map. operator tokens and legacyToken, if set, are admin tokens.
+func ParseAdminTokens(raw, legacyToken string) (map[string]string, error) {
+	tokens := make(map[string]string)
+	for _, pair := range strings.Split(raw, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		role, token, ok := strings.Cut(pair, ":")
+		if !ok || token == "" {
+			return nil, fmt.Errorf("admin token entry %q is not role:token", pair)
+		}
+		if role == roleOperator {
+			role = rbac.RoleAdmin
+		}
+		if !rbac.IsStaffRole(role) {
+			return nil, fmt.Errorf("unknown admin role %q", role)
+		}
+		tokens[token] = role
+	}
+
+	if legacyToken != "" {
+		tokens[legacyToken] = rbac.RoleAdmin
+	}
+	return tokens, nil
+}
+
+// permit guards staff routes: the caller needs an admin token whose role may
+// perform action on resource
+func (h *Handler) permit(action, resource string) middleware {
+	return func(next http.Handler) http.Handler {
+		return h.adminAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if err := authorize(r, action, resource); err != nil {
+				writeProblem(w, r, err)
+				return
+			}
+			next.ServeHTTP(w, r)
+		}))
+	}
+}
+
+// authorize returns a forbidden error unless the caller's role may perform
+// action on resource, for handlers whose permission depends on the request
+func authorize(r *http.Request, action, resource string) error {
+	role := requestRole(r)
+	if !rbac.Can(role, action, resource) {
+		return apperrors.New(apperrors.ErrForbidden, "%s role may not %s %s", role, action, resource)
+	}
+	return nil
+}
+
+// requestRole returns the role of the caller: its admin token's, else
+// customer. Routes open to customers check they are authenticated with
+// authorizeOrders.
+func requestRole(r *http.Request) string {
+	if role, ok := r.Context().Value(adminRoleKey{}).(string); ok {
+		return role
+	}
+	return rbac.RoleCustomer
+}
+
+// adminAuth authenticates staff routes with role-scoped bearer tokens. With
+// no tokens configured the admin API is disabled.
+func (h *Handler) adminAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(h.cfg.AdminTokens) == 0 {
+			writeProblem(w, r, apperrors.New(apperrors.ErrNotFound, "admin API is disabled"))
+			return
+		}
+
+		role := h.staffRole(r)
+		if role == "" {
+			writeProblem(w, r, apperrors.New(apperrors.ErrUnauthorized, "invalid admin token"))
+			return
+		}
+		staff, err := asStaff(r, role)
+		if err != nil {
+			writeProblem(w, r, err)
+			return
+		}
+		next.ServeHTTP(w, staff)
+	})
+}
+
+// staffRole returns the role of the admin token r carries, empty if it
+// carries none
+func (h *Handler) staffRole(r *http.Request) string {
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	role := ""
+	for candidate, candidateRole := range h.cfg.AdminTokens {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(candidate)) == 1 {
+			role = candidateRole
+		}
+	}
+	return role
+}
+
+// asStaff returns r acting for staff of role. Staff run the whole service:
+// they act for the tenant they name, or for every tenant.
+func asStaff(r *http.Request, role string) (*http.Request, error) {
+	ctx := tenant.Detach(context.WithValue(r.Context(), adminRoleKey{}, role))
+	fingerprint := sha256.Sum256([]byte(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")))
+	ctx = context.WithValue(ctx, adminTokenKey{}, hex.EncodeToString(fingerprint[:4]))
+	if id := r.Header.Get("X-Tenant-ID"); id != "" {
+		if !tenant.Valid(id) {
+			return nil, apperrors.New(apperrors.ErrInvalidRequest, "X-Tenant-ID: invalid tenant ID %q", id)
+		}
+		ctx = tenant.WithID(ctx, id)
+	}
+	return r.WithContext(ctx), nil
+}
+
+// adminActor names the caller in the order status history
+func adminActor(r *http.Request) string {
+	role, _ := r.Context().Value(adminRoleKey{}).(string)
+	return models.ActorAdmin + ":" + role
+}
+
+// adminOrderID parses the {id} path parameter, writing a problem if it is invalid
+func adminOrderID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	idStr := r.PathValue("id")
+	orderID, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		writeProblem(w, r, apperrors.New(apperrors.ErrInvalidRequest, "invalid order ID %q", idStr))
+		return 0, false
+	}
+	return orderID, true
+}
+
+// dryRun reports whether the request only previews a destructive operation,
+// writing a problem if the dry_run flag is invalid
+func dryRun(w http.ResponseWriter, r *http.Request) (bool, bool) {
+	raw := queryDefault(r, "dry_run", "false")
+	v, err := strconv.ParseBool(raw)
+	if err != nil {
+		writeProblem(w, r, apperrors.New(apperrors.ErrInvalidRequest, "dry_run: %q is not a boolean", raw))
+		return false, false
+	}
+	return v, true
+}
+
+// ForceTransitionRequest is the body of a forced order status change
+type ForceTransitionRequest struct {
+	Status string `json:"status" binding:"required"`
+	Reason string `json:"reason" binding:"required"`
+}
+
+// forceOrderTransition sets an order's status without running the saga
+func (h *Handler) forceOrderTransition(w http.ResponseWriter, r *http.Request) {
+	orderID, ok := adminOrderID(w, r)
+	if !ok {
+		return
+	}
+
+	preview, ok := dryRun(w, r)
+	if !ok {
+		return
+	}
+	if err := h.checkIfMatch(r, orderID); err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	var req ForceTransitionRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeProblem(w, r, apperrors.New(apperrors.ErrInvalidRequest, "%v", err))
+		return
+	}
+
+	if preview {
+		plan, err := h.admin.Plans.PlanForceTransition(r.Context(), orderID, req.Status, req.Reason, adminActor(r))
+		if err != nil {
+			writeProblem(w, r, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, H{"dry_run": true, "plan": plan})
+		return
+	}
+
+	if err := h.admin.Saga.ForceTransition(r.Context(), orderID, req.Status, req.Reason, adminActor(r)); err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, H{"order_id": orderID, "status": req.Status})
+}
+
+// retriggerPayment runs payment again for a reserved order
+func (h *Handler) retriggerPayment(w http.ResponseWriter, r *http.Request) {
+	orderID, ok := adminOrderID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.admin.Saga.RetriggerPayment(r.Context(), orderID); err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusAccepted, H{"order_id": orderID, "message": "payment retriggered"})
+}
+
+// getOrderAmountAudit recomputes an order's total and flags where it
+// disagrees with the persisted total or the payments
+func (h *Handler) getOrderAmountAudit(w http.ResponseWriter, r *http.Request) {
+	orderID, ok := adminOrderID(w, r)
+	if !ok {
+		return
+	}
+
+	audit, err := h.admin.AmountAudit.Audit(r.Context(), orderID)
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, audit)
+}
+
+// getOrderDiff returns what changed on an order between two points in time
+func (h *Handler) getOrderDiff(w http.ResponseWriter, r *http.Request) {
+	orderID, ok := adminOrderID(w, r)
+	if !ok {
+		return
+	}
+
+	var from, to time.Time
+	for name, dest := range map[string]*time.Time{"from": &from, "to": &to} {
+		raw := queryDefault(r, name, "")
+		if raw == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			writeProblem(w, r, apperrors.New(apperrors.ErrInvalidRequest, "%s: %q is not an RFC 3339 time", name, raw))
+			return
+		}
+		*dest = t
+	}
+	if from.IsZero() {
+		writeProblem(w, r, apperrors.New(apperrors.ErrInvalidRequest, "from is required"))
+		return
+	}
+	if to.IsZero() {
+		to = time.Now()
+	}
+
+	diff, err := h.admin.OrderDiff.Diff(r.Context(), orderID, from, to)
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, diff)
+}
+
+// getOrderNotifications lists the notifications sent to the customer of an
+// order with their delivery status
+func (h *Handler) getOrderNotifications(w http.ResponseWriter, r *http.Request) {
+	orderID, ok := adminOrderID(w, r)
+	if !ok {
+		return
+	}
+
+	notifications, err := h.admin.Notifications.OrderNotifications(r.Context(), orderID)
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, H{"notifications": notifications})
+}
+
+// ReplaySagaStepRequest names the saga step to re-run
+type ReplaySagaStepRequest struct {
+	Step string `json:"step" binding:"required"`
+}
+
+// replaySagaStep re-runs a saga step for an order stuck between steps
+func (h *Handler) replaySagaStep(w http.ResponseWriter, r *http.Request) {
+	orderID, ok := adminOrderID(w, r)
+	if !ok {
+		return
+	}
+
+	var req ReplaySagaStepRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeProblem(w, r, apperrors.New(apperrors.ErrInvalidRequest, "%v", err))
+		return
+	}
+
+	if err := h.admin.Saga.ReplayStep(r.Context(), orderID, req.Step, adminActor(r)); err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, H{"order_id": orderID, "step": req.Step})
+}
+
+// listDeadLetters lists the parked messages matching the query filters,
+// newest first
+func (h *Handler) listDeadLetters(w http.ResponseWriter, r *http.Request) {
+	filter := models.DeadLetterFilter{
+		ConsumerGroup: queryDefault(r, "consumer_group", ""),
+		Topic:         queryDefault(r, "topic", ""),
+		Error:         queryDefault(r, "error", ""),
+	}
+
+	limit, err := strconv.Atoi(queryDefault(r, "limit", "50"))
+	if err != nil || limit <= 0 || limit > 500 {
+		writeProblem(w, r, apperrors.New(apperrors.ErrInvalidRequest, "limit must be between 1 and 500"))
+		return
+	}
+	filter.Limit = limit
+
+	pending, err := strconv.ParseBool(queryDefault(r, "pending", "false"))
+	if err != nil {
+		writeProblem(w, r, apperrors.New(apperrors.ErrInvalidRequest, "pending must be a boolean"))
+		return
+	}
+	filter.Pending = pending
+
+	for name, dest := range map[string]*time.Time{"since": &filter.Since, "until": &filter.Until} {
+		raw := queryDefault(r, name, "")
+		if raw == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			writeProblem(w, r, apperrors.New(apperrors.ErrInvalidRequest, "%s: %q is not an RFC 3339 time", name, raw))
+			return
+		}
+		*dest = t
+	}
+
+	letters, err := h.admin.DeadLetters.List(r.Context(), filter)
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, H{"dead_letters": letters})
+}
+
+// getDeadLetterHistory lists the re-drives of a parked message and of the
+// messages it was re-driven from
+func (h *Handler) getDeadLetterHistory(w http.ResponseWriter, r *http.Request) {
+	idStr := r.PathValue("id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		writeProblem(w, r, apperrors.New(apperrors.ErrInvalidRequest, "invalid dead letter ID %q", idStr))
+		return
+	}
+
+	redrives, err := h.admin.DeadLetters.History(r.Context(), id)
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, H{"dead_letter_id": id, "redrives": redrives})
+}
+
+// RedriveDeadLettersRequest selects parked messages to publish again
+type RedriveDeadLettersRequest struct {
+	IDs           []int64    `json:"ids,omitempty"`
+	ConsumerGroup string     `json:"consumer_group,omitempty"`
+	Topic         string     `json:"topic,omitempty"`
+	Error         string     `json:"error,omitempty"`
+	Since         *time.Time `json:"since,omitempty"`
+	Until         *time.Time `json:"until,omitempty"`
+	Limit         int        `json:"limit,omitempty"`
+
+	// MergePatch is a JSON merge patch applied to each payload before it is published
+	MergePatch json.RawMessage `json:"merge_patch,omitempty"`
+}
+
+// redriveDeadLetters publishes the selected parked messages again to the
+// topic they were consumed from
+func (h *Handler) redriveDeadLetters(w http.ResponseWriter, r *http.Request) {
+	var req RedriveDeadLettersRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeProblem(w, r, apperrors.New(apperrors.ErrInvalidRequest, "%v", err))
+		return
+	}
+
+	if len(req.IDs) == 0 && req.ConsumerGroup == "" && req.Topic == "" && req.Error == "" && req.Since == nil {
+		writeProblem(w, r, apperrors.New(apperrors.ErrInvalidRequest,
+			"select dead letters by ids, consumer_group, topic, error or since"))
+		return
+	}
+	if req.Limit == 0 {
+		req.Limit = 100
+	}
+	if req.Limit < 0 || req.Limit > 500 {
+		writeProblem(w, r, apperrors.New(apperrors.ErrInvalidRequest, "limit must be between 1 and 500"))
+		return
+	}
+
+	filter := models.DeadLetterFilter{
+		IDs:           req.IDs,
+		ConsumerGroup: req.ConsumerGroup,
+		Topic:         req.Topic,
+		Error:         req.Error,
+		Limit:         req.Limit,
+	}
+	if req.Since != nil {
+		filter.Since = *req.Since
+	}
+	if req.Until != nil {
+		filter.Until = *req.Until
+	}
+
+	result, err := h.admin.DeadLetters.Redrive(r.Context(), service.RedriveRequest{
+		Filter:     filter,
+		MergePatch: req.MergePatch,
+		Actor:      adminActor(r),
+	})
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+// ReplayEventsRequest selects historical order events to deliver again to the
+// saga handlers. Offsets apply to each partition read.
+type ReplayEventsRequest struct {
+	Partition  *int       `json:"partition,omitempty"`
+	FromOffset *int64     `json:"from_offset,omitempty"`
+	ToOffset   *int64     `json:"to_offset,omitempty"`
+	Since      *time.Time `json:"since,omitempty"`
+	Until      *time.Time `json:"until,omitempty"`
+	OrderID    int64      `json:"order_id,omitempty"`
+	EventTypes []string   `json:"event_types,omitempty"`
+	// Mode is dry-run, the default, or apply
+	Mode      string `json:"mode,omitempty"`
+	Reprocess bool   `json:"reprocess,omitempty"`
+	Limit     int    `json:"limit,omitempty"`
+}
+
+// replayEvents reads events from the order events topic and re-delivers the
+// matching ones through the saga handlers, or reports what would be delivered
+func (h *Handler) replayEvents(w http.ResponseWriter, r *http.Request) {
+	var req ReplayEventsRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeProblem(w, r, apperrors.New(apperrors.ErrInvalidRequest, "%v", err))
+		return
+	}
+
+	rng := broker.HistoryRange{Partition: -1, FromOffset: -1, ToOffset: -1}
+	if req.Partition != nil {
+		rng.Partition = *req.Partition
+	}
+	if req.FromOffset != nil {
+		rng.FromOffset = *req.FromOffset
+	}
+	if req.ToOffset != nil {
+		rng.ToOffset = *req.ToOffset
+	}
+	if req.Since != nil {
+		rng.Since = *req.Since
+	}
+	if req.Until != nil {
+		rng.Until = *req.Until
+	}
+	if rng.Partition < -1 || rng.FromOffset < -1 || rng.ToOffset < -1 {
+		writeProblem(w, r, apperrors.New(apperrors.ErrInvalidRequest, "partition and offsets must not be negative"))
+		return
+	}
+
+	report, err := h.admin.Replay.Replay(r.Context(), service.ReplayRequest{
+		Range:      rng,
+		OrderID:    req.OrderID,
+		EventTypes: req.EventTypes,
+		Mode:       req.Mode,
+		Reprocess:  req.Reprocess,
+		Limit:      req.Limit,
+		Actor:      adminActor(r),
+	})
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, report)
+}
+
+// listOrders lists the orders created in a time window, riskiest first,
+// optionally in one risk band; the window defaults to the last 24 hours
+func (h *Handler) listOrders(w http.ResponseWriter, r *http.Request) {
+	filter := models.OrderFilter{RiskBand: queryDefault(r, "risk_band", "")}
+	if filter.RiskBand != "" && !fraud.IsBand(filter.RiskBand) {
+		writeProblem(w, r, apperrors.New(apperrors.ErrInvalidRequest, "risk_band must be low, medium or high"))
+		return
+	}
+
+	limit, err := strconv.Atoi(queryDefault(r, "limit", "100"))
+	if err != nil || limit <= 0 || limit > 500 {
+		writeProblem(w, r, apperrors.New(apperrors.ErrInvalidRequest, "limit must be between 1 and 500"))
+		return
+	}
+	filter.Limit = limit
+
+	filter.Since = time.Now().Add(-24 * time.Hour)
+	for name, dest := range map[string]*time.Time{"since": &filter.Since, "until": &filter.Until} {
+		raw := queryDefault(r, name, "")
+		if raw == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			writeProblem(w, r, apperrors.New(apperrors.ErrInvalidRequest, "%s: %q is not an RFC 3339 time", name, raw))
+			return
+		}
+		*dest = t
+	}
+
+	orders, err := h.orderService.ListOrders(r.Context(), filter)
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, H{"orders": orders})
+}
+
+// searchOrders finds orders by idempotency key, provider transaction ID,
+// product or variant, or order or user ID
+func (h *Handler) searchOrders(w http.ResponseWriter, r *http.Request) {
+	limit, err := strconv.Atoi(queryDefault(r, "limit", "20"))
+	if err != nil || limit <= 0 || limit > 100 {
+		writeProblem(w, r, apperrors.New(apperrors.ErrInvalidRequest, "limit must be between 1 and 100"))
+		return
+	}
+
+	results, err := h.orderService.SearchOrders(r.Context(), queryDefault(r, "q", ""), limit)
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, H{"orders": results})
+}
+
+// resyncInventory reconciles Redis inventory against the database
+func (h *Handler) resyncInventory(w http.ResponseWriter, r *http.Request) {
+	strategy := queryDefault(r, "strategy", service.ReconcileDBWins)
+	switch strategy {
+	case service.ReconcileDBWins, service.ReconcileRedisWins, service.ReconcileAlertOnly:
+	default:
+		writeProblem(w, r, apperrors.New(apperrors.ErrInvalidRequest, "unknown reconcile strategy %q", strategy))
+		return
+	}
+
+	preview, ok := dryRun(w, r)
+	if !ok {
+		return
+	}
+	if preview {
+		plan, err := h.admin.Plans.PlanInventoryResync(r.Context(), strategy, adminActor(r))
+		if err != nil {
+			writeProblem(w, r, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, H{"dry_run": true, "plan": plan})
+		return
+	}
+
+	drifted, err := h.admin.Inventory.ReconcileInventory(r.Context(), strategy)
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, H{"strategy": strategy, "drifted": drifted})
+}
+
+// importFormats maps the content types accepted by inventory imports to their format
+var importFormats = map[string]string{
+	"text/csv":             service.ImportFormatCSV,
+	"application/x-ndjson": service.ImportFormatNDJSON,
+	"application/ndjson":   service.ImportFormatNDJSON,
+}
+
+// importInventory streams a CSV or NDJSON catalog into products and
+// inventory, reporting the rows that failed
+func (h *Handler) importInventory(w http.ResponseWriter, r *http.Request) {
+	format := queryDefault(r, "format", "")
+	if format == "" {
+		mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		format = importFormats[mediaType]
+	}
+	if format == "" {
+		writeProblem(w, r, apperrors.New(apperrors.ErrInvalidRequest, "Content-Type must be text/csv or application/x-ndjson"))
+		return
+	}
+
+	clearDeadlines(w)
+	report, err := h.admin.Importer.Import(r.Context(), format, r.Body)
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, report)
+}
+
+// listLowStock lists the variants at or below their product's reorder threshold
+func (h *Handler) listLowStock(w http.ResponseWriter, r *http.Request) {
+	items, err := h.admin.LowStock.LowStock(r.Context())
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, H{"items": items})
+}
+
+// setReorderThreshold sets the reorder threshold of a product; null stops
+// its low-stock alerts
+func (h *Handler) setReorderThreshold(w http.ResponseWriter, r *http.Request) {
+	idStr := r.PathValue("id")
+	productID, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		writeProblem(w, r, apperrors.New(apperrors.ErrInvalidRequest, "invalid product ID %q", idStr))
+		return
+	}
+
+	var req struct {
+		ReorderThreshold *int `json:"reorder_threshold" binding:"omitempty,min=0"`
+	}
+	if err := decodeJSON(r, &req); err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	if err := h.admin.LowStock.SetReorderThreshold(r.Context(), productID, req.ReorderThreshold); err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, H{"product_id": productID, "reorder_threshold": req.ReorderThreshold})
+}
+
+// listWarehouses lists the warehouses stock is kept in
+func (h *Handler) listWarehouses(w http.ResponseWriter, r *http.Request) {
+	warehouses, err := h.admin.Inventory.ListWarehouses(r.Context())
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, H{"warehouses": warehouses})
+}
+
+// saveWarehouse adds or updates the warehouse with the code in the path
+func (h *Handler) saveWarehouse(w http.ResponseWriter, r *http.Request) {
+	var req service.SaveWarehouseRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	warehouse, err := h.admin.Inventory.SaveWarehouse(r.Context(), r.PathValue("code"), req)
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, warehouse)
+}
+
+// applyPlan executes a plan previewed with dry_run=true
+func (h *Handler) applyPlan(w http.ResponseWriter, r *http.Request) {
+	plan, err := h.admin.Plans.Apply(r.Context(), r.PathValue("token"), adminActor(r), func(operation string) error {
+		resource := rbac.Operations
+		switch operation {
+		case service.PlanForceTransition:
+			resource = rbac.Orders
+		case service.PlanInventoryResync:
+			resource = rbac.Inventory
+		}
+		return authorize(r, rbac.Write, resource)
+	})
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, H{"applied": true, "plan": plan})
+}
+
+// listKillSwitches lists the engaged kill switches
+func (h *Handler) listKillSwitches(w http.ResponseWriter, r *http.Request) {
+	switches, err := h.admin.KillSwitches.List(r.Context())
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, H{"kill_switches": switches})
+}
+
+// listWorkers lists the latest heartbeat of each worker on each instance
+func (h *Handler) listWorkers(w http.ResponseWriter, r *http.Request) {
+	workers, err := h.admin.Workers.List(r.Context())
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, H{"workers": workers})
+}
+
+// walletUserID parses the {user_id} path parameter, writing a problem if it is invalid
+func walletUserID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	userStr := r.PathValue("user_id")
+	userID, err := strconv.ParseInt(userStr, 10, 64)
+	if err != nil {
+		writeProblem(w, r, apperrors.New(apperrors.ErrInvalidRequest, "invalid user ID %q", userStr))
+		return 0, false
+	}
+	return userID, true
+}
+
+// getWallet returns the balance of a user's wallet and its latest transactions
+func (h *Handler) getWallet(w http.ResponseWriter, r *http.Request) {
+	userID, ok := walletUserID(w, r)
+	if !ok {
+		return
+	}
+	limit, err := strconv.Atoi(queryDefault(r, "limit", "50"))
+	if err != nil || limit <= 0 || limit > 500 {
+		writeProblem(w, r, apperrors.New(apperrors.ErrInvalidRequest, "limit must be between 1 and 500"))
+		return
+	}
+
+	wallet, err := h.admin.Wallets.Balance(r.Context(), userID)
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+	txns, err := h.admin.Wallets.Transactions(r.Context(), userID, limit)
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, H{"wallet": wallet, "transactions": txns})
+}
+
+// CreditWalletRequest adds store credit to a wallet, e.g. as a goodwill gesture
+type CreditWalletRequest struct {
+	Amount int64  `json:"amount" binding:"required"`
+	Reason string `json:"reason" binding:"required"`
+}
+
+// creditWallet adds store credit to a user's wallet
+func (h *Handler) creditWallet(w http.ResponseWriter, r *http.Request) {
+	userID, ok := walletUserID(w, r)
+	if !ok {
+		return
+	}
+
+	var req CreditWalletRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeProblem(w, r, apperrors.New(apperrors.ErrInvalidRequest, "%v", err))
+		return
+	}
+
+	txn, err := h.admin.Wallets.Credit(r.Context(), userID, req.Amount, service.WalletEntry{
+		Reason: req.Reason,
+		Actor:  adminActor(r),
+	})
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, txn)
+}
+
+// compensationID parses the {id} path parameter, writing a problem if it is invalid
+func compensationID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	idStr := r.PathValue("id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		writeProblem(w, r, apperrors.New(apperrors.ErrInvalidRequest, "invalid compensation ID %q", idStr))
+		return 0, false
+	}
+	return id, true
+}
+
+// listCompensations lists queued compensations, most urgent first; the
+// escalated ones await manual resolution
+func (h *Handler) listCompensations(w http.ResponseWriter, r *http.Request) {
+	filter := models.CompensationFilter{Status: queryDefault(r, "status", "")}
+	switch filter.Status {
+	case "", models.CompensationPending, models.CompensationDone, models.CompensationEscalated, models.CompensationResolved:
+	default:
+		writeProblem(w, r, apperrors.New(apperrors.ErrInvalidRequest, "unknown compensation status %q", filter.Status))
+		return
+	}
+
+	if raw := queryDefault(r, "order_id", ""); raw != "" {
+		orderID, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			writeProblem(w, r, apperrors.New(apperrors.ErrInvalidRequest, "invalid order ID %q", raw))
+			return
+		}
+		filter.OrderID = orderID
+	}
+
+	limit, err := strconv.Atoi(queryDefault(r, "limit", "50"))
+	if err != nil || limit <= 0 || limit > 500 {
+		writeProblem(w, r, apperrors.New(apperrors.ErrInvalidRequest, "limit must be between 1 and 500"))
+		return
+	}
+	filter.Limit = limit
+
+	compensations, err := h.admin.Compensations.List(r.Context(), filter)
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, H{"compensations": compensations})
+}
+
+// ResolveCompensationRequest records how a compensation was put right by hand
+type ResolveCompensationRequest struct {
+	Resolution string `json:"resolution" binding:"required"`
+}
+
+// resolveCompensation closes a compensation an operator settled by hand
+func (h *Handler) resolveCompensation(w http.ResponseWriter, r *http.Request) {
+	id, ok := compensationID(w, r)
+	if !ok {
+		return
+	}
+
+	var req ResolveCompensationRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeProblem(w, r, apperrors.New(apperrors.ErrInvalidRequest, "%v", err))
+		return
+	}
+
+	compensation, err := h.admin.Compensations.Resolve(r.Context(), id, req.Resolution, adminActor(r))
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, compensation)
+}
+
+// retryCompensation hands an escalated compensation back to the retrier
+func (h *Handler) retryCompensation(w http.ResponseWriter, r *http.Request) {
+	id, ok := compensationID(w, r)
+	if !ok {
+		return
+	}
+
+	compensation, err := h.admin.Compensations.Retry(r.Context(), id, adminActor(r))
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, compensation)
+}
+
+// EngageKillSwitchRequest records why a kill switch is engaged
+type EngageKillSwitchRequest struct {
+	Reason string `json:"reason" binding:"required"`
+}
+
+// engageKillSwitch blocks ordering a SKU or paying with a payment method
+func (h *Handler) engageKillSwitch(w http.ResponseWriter, r *http.Request) {
+	var req EngageKillSwitchRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeProblem(w, r, apperrors.New(apperrors.ErrInvalidRequest, "%v", err))
+		return
+	}
+
+	killSwitch, err := h.admin.KillSwitches.Engage(r.Context(), r.PathValue("kind"), r.PathValue("value"), req.Reason, adminActor(r))
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, killSwitch)
+}
+
+// releaseKillSwitch lifts a kill switch
+func (h *Handler) releaseKillSwitch(w http.ResponseWriter, r *http.Request) {
+	kind, value := r.PathValue("kind"), r.PathValue("value")
+	if err := h.admin.KillSwitches.Release(r.Context(), kind, value, adminActor(r)); err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, H{"kind": kind, "value": value, "released": true})
+}
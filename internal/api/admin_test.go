@@ -0,0 +1,72 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"order-service/internal/rbac"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAdminTokens(t *testing.T) {
+	tokens, err := ParseAdminTokens("viewer:v1, support:s1, ops:p1, operator:o1", "legacy")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"v1": rbac.RoleViewer, "s1": rbac.RoleSupport, "p1": rbac.RoleOps,
+		"o1": rbac.RoleAdmin, "legacy": rbac.RoleAdmin,
+	}, tokens)
+
+	_, err = ParseAdminTokens("root:x", "")
+	assert.Error(t, err)
+	_, err = ParseAdminTokens("customer:x", "")
+	assert.Error(t, err)
+	_, err = ParseAdminTokens("viewer", "")
+	assert.Error(t, err)
+}
+
+func TestPermitEnforcesRoles(t *testing.T) {
+	h := &Handler{cfg: HandlerConfig{AdminTokens: map[string]string{
+		"v1": rbac.RoleViewer, "s1": rbac.RoleSupport, "p1": rbac.RoleOps, "a1": rbac.RoleAdmin,
+	}}}
+
+	router := chain(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(adminActor(r)))
+	}, h.permit(rbac.Write, rbac.Refunds))
+
+	cases := []struct {
+		token string
+		want  int
+	}{
+		{"", http.StatusUnauthorized},
+		{"nope", http.StatusUnauthorized},
+		{"v1", http.StatusForbidden},
+		{"p1", http.StatusForbidden},
+		{"s1", http.StatusOK},
+		{"a1", http.StatusOK},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodPost, "/op", nil)
+		req.Header.Set("Authorization", "Bearer "+tc.token)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		assert.Equal(t, tc.want, rec.Code, "token %q", tc.token)
+		if tc.want == http.StatusOK {
+			assert.Equal(t, "admin:"+h.cfg.AdminTokens[tc.token], rec.Body.String())
+		}
+	}
+}
+
+func TestAdminAuthDisabledWithoutTokens(t *testing.T) {
+	h := &Handler{}
+
+	router := chain(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}, h.permit(rbac.Read, rbac.Orders))
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/view", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
@@ -0,0 +1,55 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"order-service/internal/apperrors"
+	"order-service/internal/rbac"
+	"order-service/internal/service"
+)
+
+// SetOrderAmendment enables PATCH /orders/{id}/items
+func (h *Handler) SetOrderAmendment(saga *service.SagaOrchestrator) {
+	h.amendment = saga
+}
+
+// amendOrder changes the quantities of an order's items, or removes them, for
+// its customer while the order awaits payment
+func (h *Handler) amendOrder(w http.ResponseWriter, r *http.Request) {
+	if h.amendment == nil {
+		writeProblem(w, r, apperrors.New(apperrors.ErrNotFound, "order editing is disabled"))
+		return
+	}
+
+	idStr := r.PathValue("id")
+	orderID, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		writeProblem(w, r, apperrors.New(apperrors.ErrInvalidRequest, "invalid order ID %q", idStr))
+		return
+	}
+	userID, err := h.orderCustomer(r, rbac.Write, orderID)
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+	if err := h.checkIfMatch(r, orderID); err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	var req service.AmendOrderRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeProblem(w, r, apperrors.New(apperrors.ErrInvalidRequest, "%v", err))
+		return
+	}
+	req.UserID = userID
+
+	amendment, err := h.amendment.AmendOrder(r.Context(), orderID, req)
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, amendment)
+}
@@ -0,0 +1,184 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"order-service/internal/apperrors"
+	"order-service/internal/models"
+)
+
+// maxAuditResponse is the largest response recorded as the after state of a
+// mutation; larger ones are left out
+const maxAuditResponse = 64 << 10
+
+// auditTarget names what an admin mutation acts on: kind followed by the
+// values of the path parameters params, e.g. order:42. load, if set, returns
+// the target as it is, so its state before and after is recorded; otherwise
+// the response is recorded as the after state.
+type auditTarget struct {
+	kind   string
+	params []string
+	load   func(r *http.Request) (interface{}, error)
+}
+
+// name returns the target of r, e.g. kill_switch:sku/TEE-XL
+func (t auditTarget) name(r *http.Request) string {
+	values := make([]string, len(t.params))
+	for i, param := range t.params {
+		values[i] = r.PathValue(param)
+	}
+	if len(values) == 0 {
+		return t.kind
+	}
+	return t.kind + ":" + strings.Join(values, "/")
+}
+
+// audit records each request of a mutating admin route its caller's role may
+// make as action in the audit log, failed ones included. Dry runs are not
+// recorded.
+func (h *Handler) audit(action string, target auditTarget) middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if h.admin.Audit == nil || isDryRun(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			entry := &models.AuditEntry{
+				Actor:     auditActor(r),
+				Action:    action,
+				Target:    target.name(r),
+				RequestID: requestIDFrom(r),
+			}
+			if target.load != nil {
+				entry.Before = auditSnapshot(r, target.load)
+			}
+
+			recorder := &auditRecorder{statusRecorder: statusRecorder{ResponseWriter: w}}
+			next.ServeHTTP(recorder, r)
+
+			entry.Status = recorder.Status()
+			if target.load != nil {
+				entry.After = auditSnapshot(r, target.load)
+			} else if entry.Status < 300 && !recorder.truncated && isJSON(recorder.Header().Get("Content-Type")) {
+				body := json.RawMessage(bytes.TrimSpace(recorder.body.Bytes()))
+				if json.Valid(body) {
+					entry.After = &body
+				}
+			}
+
+			ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), 5*time.Second)
+			defer cancel()
+			h.admin.Audit.Record(ctx, entry)
+		})
+	}
+}
+
+// auditActor names the caller in the audit log: its role and a fingerprint of
+// its token, e.g. admin:support:1a2b3c4d
+func auditActor(r *http.Request) string {
+	fingerprint, _ := r.Context().Value(adminTokenKey{}).(string)
+	return adminActor(r) + ":" + fingerprint
+}
+
+// auditSnapshot returns the target of r as JSON, or nil if it cannot be loaded
+func auditSnapshot(r *http.Request, load func(r *http.Request) (interface{}, error)) *json.RawMessage {
+	value, err := load(r)
+	if err != nil || value == nil {
+		return nil
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil
+	}
+	raw := json.RawMessage(data)
+	return &raw
+}
+
+// orderAuditTarget is the order of the {id} path parameter, which renders
+// without the customer's personal data
+func (h *Handler) orderAuditTarget() auditTarget {
+	return auditTarget{kind: "order", params: []string{"id"}, load: func(r *http.Request) (interface{}, error) {
+		orderID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		view, err := h.orderService.GetOrderAdminView(r.Context(), orderID)
+		if err != nil {
+			return nil, err
+		}
+		return view.Order, nil
+	}}
+}
+
+// isDryRun reports whether r only previews its mutation
+func isDryRun(r *http.Request) bool {
+	v, _ := strconv.ParseBool(r.URL.Query().Get("dry_run"))
+	return v
+}
+
+func isJSON(contentType string) bool {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	return mediaType == "application/json"
+}
+
+// auditRecorder keeps a copy of the response, up to maxAuditResponse bytes
+type auditRecorder struct {
+	statusRecorder
+	body      bytes.Buffer
+	truncated bool
+}
+
+func (r *auditRecorder) Write(data []byte) (int, error) {
+	if r.body.Len()+len(data) > maxAuditResponse {
+		r.truncated = true
+	} else if !r.truncated {
+		r.body.Write(data)
+	}
+	return r.statusRecorder.Write(data)
+}
+
+// listAuditEntries lists the audit log, newest first
+func (h *Handler) listAuditEntries(w http.ResponseWriter, r *http.Request) {
+	filter := models.AuditFilter{
+		Actor:  queryDefault(r, "actor", ""),
+		Action: queryDefault(r, "action", ""),
+	}
+	for param, dest := range map[string]*time.Time{"from": &filter.From, "until": &filter.Until} {
+		raw := queryDefault(r, param, "")
+		if raw == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			writeProblem(w, r, apperrors.New(apperrors.ErrInvalidRequest, "%s: %q is not an RFC 3339 time", param, raw))
+			return
+		}
+		*dest = t
+	}
+	if filter.From.IsZero() {
+		filter.From = time.Now().Add(-24 * time.Hour)
+	}
+
+	limit, err := strconv.Atoi(queryDefault(r, "limit", "100"))
+	if err != nil || limit <= 0 || limit > 1000 {
+		writeProblem(w, r, apperrors.New(apperrors.ErrInvalidRequest, "limit must be between 1 and 1000"))
+		return
+	}
+	filter.Limit = limit
+
+	entries, err := h.admin.Audit.List(r.Context(), filter)
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, H{"entries": entries})
+}
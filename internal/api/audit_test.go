@@ -0,0 +1,61 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"order-service/internal/models"
+	"order-service/internal/rbac"
+	"order-service/internal/service"
+	"order-service/internal/store/mocks"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuditRecordsAdminMutations(t *testing.T) {
+	entries := mocks.NewAuditRepository(t)
+	var recorded *models.AuditEntry
+	entries.On("CreateAuditEntry", mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) { recorded = args.Get(1).(*models.AuditEntry) }).
+		Return(nil).Once()
+
+	h := &Handler{
+		cfg:   HandlerConfig{AdminTokens: map[string]string{"o1": rbac.RoleOps}},
+		admin: AdminServices{Audit: service.NewAuditLog(entries)},
+	}
+	router := requestID(chain(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, H{"kind": r.PathValue("kind"), "value": r.PathValue("value"), "engaged": true})
+	}, h.permit(rbac.Write, rbac.Operations),
+		h.audit("kill_switch.engage", auditTarget{kind: "kill_switch", params: []string{"kind", "value"}})))
+	mux := http.NewServeMux()
+	mux.Handle("PUT /kill-switches/{kind}/{value}", router)
+
+	req := httptest.NewRequest(http.MethodPut, "/kill-switches/sku/TEE-XL", nil)
+	req.Header.Set("Authorization", "Bearer o1")
+	req.Header.Set(requestIDHeader, "req-1")
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "req-1", rec.Header().Get(requestIDHeader))
+	require.NotNil(t, recorded)
+	assert.Regexp(t, `^admin:ops:[0-9a-f]{8}$`, recorded.Actor)
+	assert.Equal(t, "kill_switch.engage", recorded.Action)
+	assert.Equal(t, "kill_switch:sku/TEE-XL", recorded.Target)
+	assert.Equal(t, "req-1", recorded.RequestID)
+	assert.Equal(t, http.StatusOK, recorded.Status)
+	assert.Nil(t, recorded.Before)
+	require.NotNil(t, recorded.After)
+	assert.JSONEq(t, `{"kind": "sku", "value": "TEE-XL", "engaged": true}`, string(*recorded.After))
+
+	// dry runs change nothing and are not recorded
+	req = httptest.NewRequest(http.MethodPut, "/kill-switches/sku/TEE-XL?dry_run=true", nil)
+	req.Header.Set("Authorization", "Bearer o1")
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NotEmpty(t, rec.Header().Get(requestIDHeader), "requests without an ID get one")
+}
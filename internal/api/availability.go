@@ -0,0 +1,65 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"order-service/internal/apperrors"
+	"order-service/internal/service"
+)
+
+// availabilityHeartbeat keeps idle SSE connections alive through proxies
+const availabilityHeartbeat = 15 * time.Second
+
+// streamAvailability streams availability deltas of hot products as Server-Sent Events
+func (h *Handler) streamAvailability(w http.ResponseWriter, r *http.Request) {
+	updates, cancel, err := h.availabilityFeed.Subscribe(clientIP(r))
+	if err != nil {
+		if errors.Is(err, service.ErrTooManyClientConnections) {
+			writeProblem(w, r, apperrors.New(apperrors.ErrRateLimited, "%v", err))
+			return
+		}
+		writeProblem(w, r, apperrors.New(apperrors.ErrUnavailable, "%v", err))
+		return
+	}
+	defer cancel()
+
+	clearDeadlines(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	flusher := http.NewResponseController(w)
+	for _, snapshot := range h.availabilityFeed.Snapshot(r.Context()) {
+		writeSSE(w, "availability", snapshot)
+	}
+	_ = flusher.Flush()
+
+	heartbeat := time.NewTicker(availabilityHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case update := <-updates:
+			writeSSE(w, "availability", update)
+		case <-heartbeat.C:
+			writeSSE(w, "heartbeat", time.Now().Unix())
+		}
+		_ = flusher.Flush()
+	}
+}
+
+// writeSSE writes one Server-Sent Event with a JSON payload
+func writeSSE(w http.ResponseWriter, event string, data interface{}) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(w, "event:%s\ndata:%s\n\n", event, payload)
+}
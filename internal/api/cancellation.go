@@ -0,0 +1,63 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"order-service/internal/apperrors"
+	"order-service/internal/rbac"
+	"order-service/internal/service"
+)
+
+// SetOrderCancellation enables POST /orders/{id}/cancel
+func (h *Handler) SetOrderCancellation(saga *service.SagaOrchestrator) {
+	h.cancellation = saga
+}
+
+// CancelOrderRequest gives the reason of a cancellation. The customer is the
+// one the request is authenticated as.
+type CancelOrderRequest struct {
+	Reason string `json:"reason,omitempty"`
+}
+
+// cancelOrder cancels an order for its customer under their cancellation
+// policy and returns the refund
+func (h *Handler) cancelOrder(w http.ResponseWriter, r *http.Request) {
+	if h.cancellation == nil {
+		writeProblem(w, r, apperrors.New(apperrors.ErrNotFound, "order cancellation is disabled"))
+		return
+	}
+
+	idStr := r.PathValue("id")
+	orderID, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		writeProblem(w, r, apperrors.New(apperrors.ErrInvalidRequest, "invalid order ID %q", idStr))
+		return
+	}
+	userID, err := h.orderCustomer(r, rbac.Write, orderID)
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+	if err := h.checkIfMatch(r, orderID); err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	var req CancelOrderRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeProblem(w, r, apperrors.New(apperrors.ErrInvalidRequest, "%v", err))
+		return
+	}
+	if req.Reason == "" {
+		req.Reason = "customer_request"
+	}
+
+	quote, err := h.cancellation.CancelOrder(r.Context(), orderID, userID, req.Reason)
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, quote)
+}
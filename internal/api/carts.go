@@ -0,0 +1,220 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"order-service/internal/apperrors"
+	"order-service/internal/models"
+	"order-service/internal/rbac"
+	"order-service/internal/service"
+)
+
+// SetCarts enables the shopping cart endpoints
+func (h *Handler) SetCarts(carts *service.CartService) {
+	h.carts = carts
+}
+
+// cartsEnabled writes a problem unless carts are enabled
+func (h *Handler) cartsEnabled(w http.ResponseWriter, r *http.Request) bool {
+	if h.carts == nil {
+		writeProblem(w, r, apperrors.New(apperrors.ErrNotFound, "carts are disabled"))
+		return false
+	}
+	return true
+}
+
+// cartVariantID parses the {variant_id} path parameter, writing a problem if it is invalid
+func cartVariantID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	idStr := r.PathValue("variant_id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		writeProblem(w, r, apperrors.New(apperrors.ErrInvalidRequest, "invalid variant ID %q", idStr))
+		return 0, false
+	}
+	return id, true
+}
+
+// authorizeCart returns a cart unless the caller may not perform action on
+// it: staff whose role may, or the customer who opened it. Other customers
+// get a not found error, so cart IDs cannot be probed.
+func (h *Handler) authorizeCart(r *http.Request, action, cartID string) (*models.Cart, error) {
+	if err := authorizeOrders(r, action); err != nil {
+		return nil, err
+	}
+	cart, err := h.carts.Get(r.Context(), cartID)
+	if err != nil {
+		return nil, err
+	}
+	if !isStaff(r) && !rbac.OwnsOrder(r.Context(), cart.UserID) {
+		return nil, apperrors.New(apperrors.ErrNotFound, "cart %s not found", cartID)
+	}
+	return cart, nil
+}
+
+// cartAccess writes a problem unless carts are enabled and the caller may
+// perform action on the cart of the request
+func (h *Handler) cartAccess(w http.ResponseWriter, r *http.Request, action string) (*models.Cart, bool) {
+	if !h.cartsEnabled(w, r) {
+		return nil, false
+	}
+	cart, err := h.authorizeCart(r, action, r.PathValue("id"))
+	if err != nil {
+		writeProblem(w, r, err)
+		return nil, false
+	}
+	return cart, true
+}
+
+// createCart opens an empty cart for the authenticated customer, or for staff
+// for the user the request names
+func (h *Handler) createCart(w http.ResponseWriter, r *http.Request) {
+	if !h.cartsEnabled(w, r) {
+		return
+	}
+
+	var req service.CreateCartRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeProblem(w, r, apperrors.New(apperrors.ErrInvalidRequest, "%v", err))
+		return
+	}
+	userID, err := actingCustomer(r, rbac.Write, req.UserID)
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+	req.UserID = userID
+
+	cart, err := h.carts.Create(r.Context(), req)
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, cart)
+}
+
+// getCart returns a cart with its items
+func (h *Handler) getCart(w http.ResponseWriter, r *http.Request) {
+	cart, ok := h.cartAccess(w, r, rbac.Read)
+	if !ok {
+		return
+	}
+
+	writeJSON(w, http.StatusOK, cart)
+}
+
+// addCartItem adds a quantity of a variant to a cart
+func (h *Handler) addCartItem(w http.ResponseWriter, r *http.Request) {
+	if _, ok := h.cartAccess(w, r, rbac.Write); !ok {
+		return
+	}
+
+	var req service.CartItemRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeProblem(w, r, apperrors.New(apperrors.ErrInvalidRequest, "%v", err))
+		return
+	}
+
+	cart, err := h.carts.AddItem(r.Context(), r.PathValue("id"), req)
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, cart)
+}
+
+// UpdateCartItemRequest sets the quantity of a variant in a cart
+type UpdateCartItemRequest struct {
+	Quantity *int `json:"quantity" binding:"required"`
+}
+
+// updateCartItem sets the quantity of a variant in a cart; zero removes it
+func (h *Handler) updateCartItem(w http.ResponseWriter, r *http.Request) {
+	if _, ok := h.cartAccess(w, r, rbac.Write); !ok {
+		return
+	}
+	variantID, ok := cartVariantID(w, r)
+	if !ok {
+		return
+	}
+
+	var req UpdateCartItemRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeProblem(w, r, apperrors.New(apperrors.ErrInvalidRequest, "%v", err))
+		return
+	}
+
+	cart, err := h.carts.UpdateItem(r.Context(), r.PathValue("id"), variantID, *req.Quantity)
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, cart)
+}
+
+// removeCartItem removes a variant from a cart
+func (h *Handler) removeCartItem(w http.ResponseWriter, r *http.Request) {
+	if _, ok := h.cartAccess(w, r, rbac.Write); !ok {
+		return
+	}
+	variantID, ok := cartVariantID(w, r)
+	if !ok {
+		return
+	}
+
+	cart, err := h.carts.UpdateItem(r.Context(), r.PathValue("id"), variantID, 0)
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, cart)
+}
+
+// startCartCheckout soft-holds the stock of a cart's items while the user
+// completes checkout
+func (h *Handler) startCartCheckout(w http.ResponseWriter, r *http.Request) {
+	if _, ok := h.cartAccess(w, r, rbac.Write); !ok {
+		return
+	}
+
+	hold, err := h.carts.StartCheckout(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, hold)
+}
+
+// checkoutCart converts a cart into an order. Checking out a cart again
+// returns the order it was converted into.
+func (h *Handler) checkoutCart(w http.ResponseWriter, r *http.Request) {
+	cart, ok := h.cartAccess(w, r, rbac.Write)
+	if !ok {
+		return
+	}
+
+	var req service.CheckoutRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeProblem(w, r, apperrors.New(apperrors.ErrInvalidRequest, "%v", err))
+		return
+	}
+	if paysFromWallet(req.WalletAmount, req.PaymentMethod) {
+		if err := authorizeWallet(r, cart.UserID); err != nil {
+			writeProblem(w, r, err)
+			return
+		}
+	}
+
+	resp, err := h.carts.Checkout(r.Context(), r.PathValue("id"), req)
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, resp)
+}
@@ -0,0 +1,26 @@
+// Package chirouter serves the API routes with chi on plain net/http
+package chirouter
+
+import (
+	"order-service/internal/api"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// New returns a chi router serving routes
+func New(routes []api.Route) chi.Router {
+	router := chi.NewRouter()
+	router.Use(middleware.Recoverer)
+	router.Use(middleware.Logger)
+
+	Mount(router, routes)
+	return router
+}
+
+// Mount registers routes on an existing chi router, e.g. under a sub-router
+func Mount(router chi.Router, routes []api.Route) {
+	for _, route := range routes {
+		router.Method(route.Method, route.Pattern, route.Handler)
+	}
+}
@@ -0,0 +1,130 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"order-service/internal/apperrors"
+	"order-service/internal/rbac"
+	"order-service/internal/service"
+)
+
+// SetDrops enables product drop registration and the drop admin endpoints
+func (h *Handler) SetDrops(drops *service.DropService) {
+	h.drops = drops
+}
+
+// dropID parses the drop ID path parameter; ok is false once a problem was written
+func (h *Handler) dropID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	if h.drops == nil {
+		writeProblem(w, r, apperrors.New(apperrors.ErrNotFound, "product drops are disabled"))
+		return 0, false
+	}
+
+	idStr := r.PathValue("id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		writeProblem(w, r, apperrors.New(apperrors.ErrInvalidRequest, "invalid drop ID %q", idStr))
+		return 0, false
+	}
+	return id, true
+}
+
+// registerForDrop registers the intent of the authenticated customer, or for
+// staff of the user the request names, to order a drop before it starts
+func (h *Handler) registerForDrop(w http.ResponseWriter, r *http.Request) {
+	dropID, ok := h.dropID(w, r)
+	if !ok {
+		return
+	}
+
+	var req service.DropRegistrationRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeProblem(w, r, apperrors.New(apperrors.ErrInvalidRequest, "%v", err))
+		return
+	}
+	userID, err := actingCustomer(r, rbac.Write, req.UserID)
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+	req.UserID = userID
+
+	reg, err := h.drops.Register(r.Context(), dropID, req)
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, reg)
+}
+
+// getDropRegistration returns a user's registration for a drop, with its
+// order once the drop started. Customers only see their own.
+func (h *Handler) getDropRegistration(w http.ResponseWriter, r *http.Request) {
+	dropID, ok := h.dropID(w, r)
+	if !ok {
+		return
+	}
+
+	userStr := r.PathValue("user_id")
+	userID, err := strconv.ParseInt(userStr, 10, 64)
+	if err != nil {
+		writeProblem(w, r, apperrors.New(apperrors.ErrInvalidRequest, "invalid user ID %q", userStr))
+		return
+	}
+	if err := authorizeOrders(r, rbac.Read); err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+	if !isStaff(r) && !rbac.OwnsOrder(r.Context(), userID) {
+		writeProblem(w, r, apperrors.New(apperrors.ErrNotFound, "user %d is not registered for drop %d", userID, dropID))
+		return
+	}
+
+	reg, err := h.drops.GetRegistration(r.Context(), dropID, userID)
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, reg)
+}
+
+// createDrop schedules a product drop
+func (h *Handler) createDrop(w http.ResponseWriter, r *http.Request) {
+	if h.drops == nil {
+		writeProblem(w, r, apperrors.New(apperrors.ErrNotFound, "product drops are disabled"))
+		return
+	}
+
+	var req service.CreateDropRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeProblem(w, r, apperrors.New(apperrors.ErrInvalidRequest, "%v", err))
+		return
+	}
+
+	drop, err := h.drops.CreateDrop(r.Context(), req)
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, drop)
+}
+
+// getDrop returns a drop with its registrations counted by status
+func (h *Handler) getDrop(w http.ResponseWriter, r *http.Request) {
+	dropID, ok := h.dropID(w, r)
+	if !ok {
+		return
+	}
+
+	drop, err := h.drops.GetDrop(r.Context(), dropID)
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, drop)
+}
@@ -0,0 +1,67 @@
+package api
+
+import (
+	"hash/fnv"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"order-service/internal/apperrors"
+	"order-service/internal/models"
+)
+
+// orderRevision identifies a revision of an order. Every change clients can
+// see bumps the order's updated_at, which is stored to the microsecond.
+func orderRevision(o *models.Order) string {
+	return strconv.FormatInt(o.ID, 10) + "." + strconv.FormatInt(o.UpdatedAt.UnixMicro(), 36)
+}
+
+// orderETag is the strong ETag of the order representation r asks for: the
+// order's revision, then a hash of the path and query since versions,
+// ?fields= and ?expand= render a revision differently
+func orderETag(r *http.Request, o *models.Order) string {
+	variant := fnv.New32a()
+	variant.Write([]byte(r.URL.Path + "?" + r.URL.RawQuery))
+	return `"` + orderRevision(o) + "-" + strconv.FormatUint(uint64(variant.Sum32()), 36) + `"`
+}
+
+// etagRevision returns the order revision of an ETag orderETag made
+func etagRevision(etag string) string {
+	revision, _, _ := strings.Cut(strings.Trim(etag, `"`), "-")
+	return revision
+}
+
+// notModified reports whether the If-None-Match of r matches etag, comparing
+// weakly as RFC 9110 asks
+func notModified(r *http.Request, etag string) bool {
+	for _, tag := range splitList(r.Header.Get("If-None-Match")) {
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// checkIfMatch enforces the If-Match precondition of a change to an order:
+// unless the order is still at a revision one of the ETags was made for, the
+// change is refused with 412 so a client acting on a stale copy reloads it
+// instead of overwriting a newer change. Requests without If-Match pass.
+func (h *Handler) checkIfMatch(r *http.Request, orderID int64) error {
+	tags := splitList(r.Header.Get("If-Match"))
+	if len(tags) == 0 {
+		return nil
+	}
+
+	order, err := h.orderService.GetOrderUncached(r.Context(), orderID)
+	if err != nil {
+		return err
+	}
+	revision := orderRevision(order)
+	for _, tag := range tags {
+		// If-Match compares strongly, so weak ETags never match
+		if tag == "*" || (!strings.HasPrefix(tag, "W/") && etagRevision(tag) == revision) {
+			return nil
+		}
+	}
+	return apperrors.New(apperrors.ErrPreconditionFailed, "order %d changed since it was read", orderID)
+}
@@ -0,0 +1,44 @@
+package api
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"order-service/internal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOrderETagChangesWithRevisionAndRepresentation(t *testing.T) {
+	updated := time.Date(2024, 1, 15, 10, 30, 0, 123456000, time.UTC)
+	order := &models.Order{ID: 7, UpdatedAt: updated}
+
+	etag := orderETag(httptest.NewRequest("GET", "/api/v1/orders/7", nil), order)
+	assert.Equal(t, etag, orderETag(httptest.NewRequest("GET", "/api/v1/orders/7", nil), order))
+	assert.NotEqual(t, etag, orderETag(httptest.NewRequest("GET", "/api/v2/orders/7", nil), order))
+	assert.NotEqual(t, etag, orderETag(httptest.NewRequest("GET", "/api/v1/orders/7?fields=id", nil), order))
+	assert.NotEqual(t, etag, orderETag(httptest.NewRequest("GET", "/api/v1/orders/7", nil),
+		&models.Order{ID: 7, UpdatedAt: updated.Add(time.Microsecond)}))
+
+	// Representations of a revision all satisfy If-Match on it
+	assert.Equal(t, orderRevision(order), etagRevision(etag))
+	assert.Equal(t, orderRevision(order), etagRevision(orderETag(httptest.NewRequest("GET", "/api/v2/orders/7?expand=", nil), order)))
+}
+
+func TestNotModifiedComparesWeakly(t *testing.T) {
+	etag := `"7.abc-xyz"`
+	cases := map[string]bool{
+		"":                 false,
+		`"7.abc-other"`:    false,
+		etag:               true,
+		"W/" + etag:        true,
+		`"1.a-b", ` + etag: true,
+		"*":                true,
+	}
+	for header, want := range cases {
+		r := httptest.NewRequest("GET", "/api/v1/orders/7", nil)
+		r.Header.Set("If-None-Match", header)
+		assert.Equal(t, want, notModified(r, etag), header)
+	}
+}
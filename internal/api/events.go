@@ -0,0 +1,95 @@
+package api
+
+import (
+	"io"
+	"net/http"
+	"time"
+
+	"order-service/internal/apperrors"
+	"order-service/internal/broker"
+	"order-service/internal/util"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// maxIngestBodyBytes caps the size of a CloudEvent received over HTTP
+const maxIngestBodyBytes = 1 << 20
+
+// EventIngestion routes CloudEvents received over HTTP into the same handler
+// pipeline as Kafka messages
+type EventIngestion struct {
+	Handler        broker.MessageHandler
+	AllowedTypes   []string
+	AllowedSources []string
+}
+
+// SetEventIngestion enables POST /api/v1/events
+func (h *Handler) SetEventIngestion(ingestion EventIngestion) {
+	h.ingestion = ingestion
+}
+
+// ingestEvent accepts a CloudEvent in binary or structured mode from a partner
+// system and handles it like the equivalent Kafka message
+func (h *Handler) ingestEvent(w http.ResponseWriter, r *http.Request) {
+	if h.ingestion.Handler == nil || len(h.ingestion.AllowedTypes) == 0 || len(h.ingestion.AllowedSources) == 0 {
+		writeProblem(w, r, apperrors.New(apperrors.ErrNotFound, "event ingestion is disabled"))
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxIngestBodyBytes))
+	if err != nil {
+		util.EventsIngestedTotal.WithLabelValues("invalid").Inc()
+		writeProblem(w, r, apperrors.New(apperrors.ErrInvalidRequest, "failed to read event: %v", err))
+		return
+	}
+
+	event, payload, err := broker.DecodeHTTP(r.Header, body)
+	if err != nil {
+		util.EventsIngestedTotal.WithLabelValues("invalid").Inc()
+		writeProblem(w, r, apperrors.New(apperrors.ErrInvalidRequest, "%v", err))
+		return
+	}
+
+	if !contains(h.ingestion.AllowedTypes, event.Type) || !contains(h.ingestion.AllowedSources, event.Source) {
+		util.EventsIngestedTotal.WithLabelValues("rejected").Inc()
+		writeProblem(w, r, apperrors.New(apperrors.ErrForbidden,
+			"event type %q from source %q is not accepted", event.Type, event.Source))
+		return
+	}
+
+	if h.cfg.ValidateRequests {
+		if err := h.schemas.ValidateEvent(payload); err != nil {
+			util.EventsIngestedTotal.WithLabelValues("invalid").Inc()
+			writeProblem(w, r, apperrors.New(apperrors.ErrInvalidRequest, "%v", err))
+			return
+		}
+	}
+
+	msg := kafka.Message{
+		Key:   []byte(event.Subject),
+		Value: payload,
+		Time:  time.Now(),
+	}
+	for _, key := range []string{"traceparent", "tracestate"} {
+		if v := r.Header.Get(key); v != "" {
+			msg.Headers = append(msg.Headers, kafka.Header{Key: key, Value: []byte(v)})
+		}
+	}
+	if err := h.ingestion.Handler(r.Context(), msg); err != nil {
+		util.EventsIngestedTotal.WithLabelValues("failed").Inc()
+		writeProblem(w, r, err)
+		return
+	}
+
+	util.EventsIngestedTotal.WithLabelValues("accepted").Inc()
+	writeJSON(w, http.StatusAccepted, H{"id": event.ID, "type": event.Type})
+}
+
+func contains(values []string, v string) bool {
+	for _, candidate := range values {
+		if candidate == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,54 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIngestEventEnforcesAllowList(t *testing.T) {
+	var handled []string
+	h := &Handler{}
+	h.SetEventIngestion(EventIngestion{
+		Handler: func(ctx context.Context, msg kafka.Message) error {
+			handled = append(handled, string(msg.Value))
+			return nil
+		},
+		AllowedTypes:   []string{"PAYMENT_SUCCESS"},
+		AllowedSources: []string{"/psp"},
+	})
+
+	router := http.HandlerFunc(h.ingestEvent)
+
+	send := func(eventType, source string) int {
+		req := httptest.NewRequest(http.MethodPost, "/events", strings.NewReader(`{"order_id":1}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("ce-specversion", "1.0")
+		req.Header.Set("ce-id", "p-1")
+		req.Header.Set("ce-type", eventType)
+		req.Header.Set("ce-source", source)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	assert.Equal(t, http.StatusForbidden, send("PAYMENT_SUCCESS", "/elsewhere"))
+	assert.Equal(t, http.StatusForbidden, send("USER_DELETED", "/psp"))
+	assert.Empty(t, handled)
+
+	assert.Equal(t, http.StatusAccepted, send("PAYMENT_SUCCESS", "/psp"))
+	assert.Len(t, handled, 1)
+}
+
+func TestIngestEventDisabledWithoutAllowList(t *testing.T) {
+	router := http.HandlerFunc((&Handler{}).ingestEvent)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/events", strings.NewReader(`{}`)))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
@@ -0,0 +1,46 @@
+// Package ginrouter serves the API routes with gin
+package ginrouter
+
+import (
+	"net/http"
+	"regexp"
+
+	"order-service/internal/api"
+
+	"github.com/gin-gonic/gin"
+)
+
+// placeholder matches a {name} path parameter
+var placeholder = regexp.MustCompile(`\{([^}]+)\}`)
+
+// New returns a gin engine serving routes; release turns off gin's debug output
+func New(routes []api.Route, release bool) *gin.Engine {
+	if release {
+		gin.SetMode(gin.ReleaseMode)
+	}
+
+	engine := gin.New()
+	engine.Use(gin.Recovery())
+	engine.Use(gin.Logger())
+
+	for _, route := range routes {
+		engine.Handle(route.Method, Pattern(route.Pattern), Wrap(route.Handler))
+	}
+	return engine
+}
+
+// Pattern converts a {name} route pattern to gin's :name syntax
+func Pattern(pattern string) string {
+	return placeholder.ReplaceAllString(pattern, ":$1")
+}
+
+// Wrap adapts a net/http handler to gin, exposing gin's path parameters
+// through http.Request.PathValue
+func Wrap(handler http.Handler) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		for _, param := range c.Params {
+			c.Request.SetPathValue(param.Key, param.Value)
+		}
+		handler.ServeHTTP(c.Writer, c.Request)
+	}
+}
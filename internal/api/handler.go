@@ -3,135 +3,428 @@ package api
 import (
 	"net/http"
 	"strconv"
+	"strings"
+	"sync/atomic"
 	"time"
 
+	"order-service/internal/apperrors"
+	"order-service/internal/health"
+	"order-service/internal/rbac"
+	"order-service/internal/realtime"
+	"order-service/internal/redisclient"
+	"order-service/internal/reports"
+	"order-service/internal/schema"
 	"order-service/internal/service"
-	"order-service/internal/util"
-
-	"github.com/gin-gonic/gin"
-	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 // Handler contains HTTP handlers
 type Handler struct {
-	orderService *service.OrderService
+	orderService     *service.OrderService
+	availabilityFeed *service.AvailabilityFeed
+	health           *health.Checker
+	redis            *redisclient.Client
+	schemas          *schema.Registry
+	admin            AdminServices
+	ingestion        EventIngestion
+	scaling          *service.ScalingMonitor
+	timeline         *service.OrderTimeline
+	statusFeed       *service.OrderStatusFeed
+	realtime         *realtime.Hub
+	drops            *service.DropService
+	incomingStock    *service.IncomingStockService
+	webhooks         *service.WebhookService
+	carts            *service.CartService
+	cancellation     *service.SagaOrchestrator
+	amendment        *service.SagaOrchestrator
+	flashOrders      *service.FlashOrders
+	waitingRoom      *service.WaitingRoom
+	graphQL          http.Handler
+	payments         *service.PaymentService
+	reports          *reports.Service
+	invoices         *service.InvoiceService
+	cfg              HandlerConfig
+	orderValidators  []OrderValidator
+
+	// replayRejectThreshold starts at cfg.ReplayRejectThreshold and can be reloaded
+	replayRejectThreshold atomic.Int64
+}
+
+// HandlerConfig holds HTTP layer tunables
+type HandlerConfig struct {
+	IdempotencyTTL        time.Duration
+	ReplayRejectThreshold int
+	StuckThreshold        time.Duration
+	ValidateRequests      bool
+	// AdminTokens maps admin bearer tokens to their role
+	AdminTokens map[string]string
+	// TenantAPIKeys maps X-API-Key values to their tenant
+	TenantAPIKeys map[string]string
+	// TenantJWTSecret verifies bearer JWTs naming the tenant in TenantJWTClaim
+	TenantJWTSecret []byte
+	TenantJWTClaim  string
+	// CustomerJWTClaim of such a JWT is the user ID of the customer it
+	// authenticates, for the customer order routes
+	CustomerJWTClaim string
+	// Currency is the ISO 4217 code of amounts, shown with them from v2 on
+	Currency string
+	// OrderRules limit the orders clients may create
+	OrderRules OrderRules
 }
 
 // NewHandler creates a new HTTP handler
-func NewHandler(orderService *service.OrderService) *Handler {
-	return &Handler{
-		orderService: orderService,
+func NewHandler(
+	orderService *service.OrderService,
+	availabilityFeed *service.AvailabilityFeed,
+	health *health.Checker,
+	redis *redisclient.Client,
+	schemas *schema.Registry,
+	cfg HandlerConfig,
+) *Handler {
+	h := &Handler{
+		orderService:     orderService,
+		availabilityFeed: availabilityFeed,
+		health:           health,
+		redis:            redis,
+		schemas:          schemas,
+		cfg:              cfg,
 	}
+	h.replayRejectThreshold.Store(int64(cfg.ReplayRejectThreshold))
+	return h
 }
 
-// SetupRoutes sets up HTTP routes
-func (h *Handler) SetupRoutes(router *gin.Engine) {
-	router.Use(gin.Recovery())
-	router.Use(prometheusMiddleware())
-	router.Use(gin.Logger())
-
-	router.GET("/health", h.healthCheck)
-	router.GET("/ready", h.readinessCheck)
-
-	router.GET("/metrics", gin.WrapH(promhttp.Handler()))
+// SetReplayRejectThreshold changes how many idempotent replays per hour a client may make
+func (h *Handler) SetReplayRejectThreshold(threshold int) {
+	h.replayRejectThreshold.Store(int64(threshold))
+}
 
-	v1 := router.Group("/api/v1")
-	{
-		v1.POST("/orders", h.createOrder)
-		v1.GET("/orders/:id", h.getOrder)
+// healthCheck handles liveness requests; fails when a worker is wedged on a message
+func (h *Handler) healthCheck(w http.ResponseWriter, r *http.Request) {
+	if stuck := h.health.StuckWorkers(h.cfg.StuckThreshold); len(stuck) > 0 {
+		writeJSON(w, http.StatusServiceUnavailable, H{
+			"status":        "unhealthy",
+			"stuck_workers": stuck,
+			"time":          time.Now().Unix(),
+		})
+		return
 	}
-}
 
-// healthCheck handles health check requests
-func (h *Handler) healthCheck(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{
+	writeJSON(w, http.StatusOK, H{
 		"status": "healthy",
 		"time":   time.Now().Unix(),
 	})
 }
 
-// readinessCheck handles readiness check requests
-func (h *Handler) readinessCheck(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{
-		"status": "ready",
-		"time":   time.Now().Unix(),
+// readinessCheck probes Postgres, Redis and Kafka and reports per-component status
+func (h *Handler) readinessCheck(w http.ResponseWriter, r *http.Request) {
+	report := h.health.CheckReadiness(r.Context())
+
+	status, code := "ready", http.StatusOK
+	if !report.Healthy {
+		status, code = "not_ready", http.StatusServiceUnavailable
+	}
+
+	writeJSON(w, code, H{
+		"status":     status,
+		"components": report.Components,
+		"time":       time.Now().Unix(),
 	})
 }
 
+// SetScalingMonitor enables /metrics/scaling
+func (h *Handler) SetScalingMonitor(monitor *service.ScalingMonitor) {
+	h.scaling = monitor
+}
+
+// SetOrderTimeline enables GET /orders/{id}?include=timeline
+func (h *Handler) SetOrderTimeline(timeline *service.OrderTimeline) {
+	h.timeline = timeline
+}
+
+// SetFlashOrders enables GET /api/v1/pending-orders/{token}
+func (h *Handler) SetFlashOrders(flashOrders *service.FlashOrders) {
+	h.flashOrders = flashOrders
+}
+
+// SetPayments embeds the order's payment in v2 order resources
+func (h *Handler) SetPayments(payments *service.PaymentService) {
+	h.payments = payments
+}
+
+// SetReports enables GET /api/v1/admin/reports/orders
+func (h *Handler) SetReports(reports *reports.Service) {
+	h.reports = reports
+}
+
+// SetGraphQL enables /graphql
+func (h *Handler) SetGraphQL(graphQL http.Handler) {
+	h.graphQL = graphQL
+}
+
+// SetWaitingRoom queues order creation past the room's concurrency threshold
+// and enables GET /api/v1/queue/{token}
+func (h *Handler) SetWaitingRoom(room *service.WaitingRoom) {
+	h.waitingRoom = room
+}
+
+// serveGraphQL serves order and catalog queries
+func (h *Handler) serveGraphQL(w http.ResponseWriter, r *http.Request) {
+	if h.graphQL == nil {
+		writeProblem(w, r, apperrors.New(apperrors.ErrNotFound, "GraphQL is disabled"))
+		return
+	}
+	// Resolvers leave out the orders of other customers
+	if err := authorizeOrders(r, rbac.Read); err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+	h.graphQL.ServeHTTP(w, r)
+}
+
+// scalingMetrics reports pipeline backlog as flat JSON for the KEDA metrics-api scaler
+func (h *Handler) scalingMetrics(w http.ResponseWriter, r *http.Request) {
+	if h.scaling == nil {
+		writeProblem(w, r, apperrors.New(apperrors.ErrNotFound, "scaling metrics are disabled"))
+		return
+	}
+
+	snapshot, err := h.scaling.Snapshot(r.Context())
+	if err != nil {
+		writeProblem(w, r, apperrors.Wrap(apperrors.ErrUnavailable, err, "scaling metrics unavailable"))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, snapshot)
+}
+
 // createOrder handles order creation
-func (h *Handler) createOrder(c *gin.Context) {
+func (h *Handler) createOrder(w http.ResponseWriter, r *http.Request) {
 	var req service.CreateOrderRequest
 
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   "Invalid request body",
-			"details": err.Error(),
-		})
+	if err := decodeJSON(r, &req); err != nil {
+		writeProblem(w, r, apperrors.New(apperrors.ErrInvalidRequest, "%v", err))
 		return
 	}
 
 	if req.IdempotencyKey == "" {
-		req.IdempotencyKey = c.GetHeader("Idempotency-Key")
+		req.IdempotencyKey = r.Header.Get("Idempotency-Key")
+	}
+	if err := h.validateOrder(r.Context(), &req); err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+	if paysFromWallet(req.WalletAmount, req.PaymentMethod) {
+		if err := authorizeWallet(r, req.UserID); err != nil {
+			writeProblem(w, r, err)
+			return
+		}
 	}
 
-	resp, err := h.orderService.CreateOrder(c.Request.Context(), &req)
+	if h.waitingRoom != nil {
+		if !h.waitingRoom.Enter(r.Context()) {
+			ticket, err := h.waitingRoom.Enqueue(r.Context(), &req)
+			if err != nil {
+				writeProblem(w, r, err)
+				return
+			}
+			writeJSON(w, http.StatusAccepted, ticket)
+			return
+		}
+		defer h.waitingRoom.Leave()
+	}
+
+	resp, err := h.orderService.CreateOrder(r.Context(), &req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Failed to create order",
-			"details": err.Error(),
-		})
+		writeProblem(w, r, err)
+		return
+	}
+
+	if resp.OrderToken != "" {
+		// Accepted in flash-sale mode; the order is persisted asynchronously
+		writeJSON(w, http.StatusAccepted, resp)
+		return
+	}
+	writeJSON(w, http.StatusCreated, resp)
+}
+
+// getPendingOrder reports whether an order accepted in flash-sale mode was placed
+func (h *Handler) getPendingOrder(w http.ResponseWriter, r *http.Request) {
+	if h.flashOrders == nil {
+		writeProblem(w, r, apperrors.New(apperrors.ErrNotFound, "flash-sale mode is disabled"))
 		return
 	}
 
-	c.JSON(http.StatusCreated, resp)
+	status, err := h.flashOrders.Status(r.Context(), r.PathValue("token"))
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, status)
+}
+
+// getQueueTicket reports the position of an order request waiting in line, and
+// its order once admitted
+func (h *Handler) getQueueTicket(w http.ResponseWriter, r *http.Request) {
+	if h.waitingRoom == nil {
+		writeProblem(w, r, apperrors.New(apperrors.ErrNotFound, "waiting room is disabled"))
+		return
+	}
+
+	ticket, err := h.waitingRoom.Ticket(r.Context(), r.PathValue("token"))
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, ticket)
+}
+
+// getOrder handles get order by ID, rendered as version v
+func (h *Handler) getOrder(v apiVersion) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		p, err := parseProjection(r, v.order, orderExpandable, v.orderExpands)
+		if err != nil {
+			writeProblem(w, r, err)
+			return
+		}
+		// ?include=timeline predates ?expand=
+		for _, include := range strings.Split(r.URL.Query().Get("include"), ",") {
+			switch strings.TrimSpace(include) {
+			case "":
+			case "timeline":
+				p.expand["timeline"] = true
+			default:
+				writeProblem(w, r, apperrors.New(apperrors.ErrInvalidRequest, "unknown include %q", include))
+				return
+			}
+		}
+		if p.expands("timeline") && h.timeline == nil {
+			writeProblem(w, r, apperrors.New(apperrors.ErrInvalidRequest, "order timeline is not enabled"))
+			return
+		}
+
+		order, err := h.loadOrder(r, p)
+		if err != nil {
+			writeProblem(w, r, err)
+			return
+		}
+		etag := orderETag(r, order.order)
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Cache-Control", "private, no-cache")
+		if notModified(r, etag) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		resp, err := v.serializeOrder(h, order, p)
+		if err != nil {
+			writeProblem(w, r, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, resp)
+	}
 }
 
-// getOrder handles get order by ID
-func (h *Handler) getOrder(c *gin.Context) {
-	idStr := c.Param("id")
+// bulkGetOrders returns up to service.MaxBulkGetOrders orders with their items
+func (h *Handler) bulkGetOrders(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		OrderIDs []int64 `json:"order_ids"`
+	}
+	if err := decodeJSON(r, &req); err != nil {
+		writeProblem(w, r, apperrors.New(apperrors.ErrInvalidRequest, "%v", err))
+		return
+	}
+
+	resp, err := h.orderService.BulkGetOrders(r.Context(), req.OrderIDs)
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// getOrderHistory returns the status audit log of an order
+func (h *Handler) getOrderHistory(w http.ResponseWriter, r *http.Request) {
+	idStr := r.PathValue("id")
 	orderID, err := strconv.ParseInt(idStr, 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Invalid order ID",
-		})
+		writeProblem(w, r, apperrors.New(apperrors.ErrInvalidRequest, "invalid order ID %q", idStr))
+		return
+	}
+	if err := h.authorizeOrder(r, rbac.Read, orderID); err != nil {
+		writeProblem(w, r, err)
 		return
 	}
 
-	order, items, err := h.orderService.GetOrder(c.Request.Context(), orderID)
+	history, err := h.orderService.GetOrderHistory(r.Context(), orderID)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"error":   "Order not found",
-			"details": err.Error(),
-		})
+		writeProblem(w, r, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"order": order,
-		"items": items,
+	writeJSON(w, http.StatusOK, H{
+		"order_id": orderID,
+		"history":  history,
 	})
 }
 
-// prometheusMiddleware collects HTTP metrics
-func prometheusMiddleware() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		start := time.Now()
+// getOrderReservations returns the stock reservations of an order
+func (h *Handler) getOrderReservations(w http.ResponseWriter, r *http.Request) {
+	idStr := r.PathValue("id")
+	orderID, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		writeProblem(w, r, apperrors.New(apperrors.ErrInvalidRequest, "invalid order ID %q", idStr))
+		return
+	}
+	if err := h.authorizeOrder(r, rbac.Read, orderID); err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	reservations, err := h.orderService.GetOrderReservations(r.Context(), orderID)
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, H{
+		"order_id":     orderID,
+		"reservations": reservations,
+	})
+}
+
+// getProductVariants returns the variants of a product
+func (h *Handler) getProductVariants(w http.ResponseWriter, r *http.Request) {
+	idStr := r.PathValue("id")
+	productID, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		writeProblem(w, r, apperrors.New(apperrors.ErrInvalidRequest, "invalid product ID %q", idStr))
+		return
+	}
 
-		c.Next()
+	variants, err := h.orderService.GetProductVariants(r.Context(), productID)
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
 
-		duration := time.Since(start).Seconds()
-		status := strconv.Itoa(c.Writer.Status())
+	writeJSON(w, http.StatusOK, H{
+		"product_id": productID,
+		"variants":   variants,
+	})
+}
 
-		util.HTTPRequestDuration.WithLabelValues(
-			c.Request.Method,
-			c.FullPath(),
-			status,
-		).Observe(duration)
+// getOrderAdminView returns an order with its lifecycle SLA timings
+func (h *Handler) getOrderAdminView(w http.ResponseWriter, r *http.Request) {
+	idStr := r.PathValue("id")
+	orderID, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		writeProblem(w, r, apperrors.New(apperrors.ErrInvalidRequest, "invalid order ID %q", idStr))
+		return
+	}
 
-		util.HTTPRequestsTotal.WithLabelValues(
-			c.Request.Method,
-			c.FullPath(),
-			status,
-		).Inc()
+	view, err := h.orderService.GetOrderAdminView(r.Context(), orderID)
+	if err != nil {
+		writeProblem(w, r, err)
+		return
 	}
+
+	writeJSON(w, http.StatusOK, view)
 }
@@ -0,0 +1,205 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"order-service/internal/apperrors"
+	"order-service/internal/redisclient"
+	"order-service/internal/util"
+
+	"go.uber.org/zap"
+)
+
+const (
+	idempotencyStateInFlight  = "in_flight"
+	idempotencyStateCompleted = "completed"
+)
+
+// idempotencyLockTTL is how long the claim of a request in flight holds its
+// key. It outlasts the default write timeout, so a key is only released
+// early for requests the server gave up on, and a crashed instance blocks
+// retries for no longer than this.
+const idempotencyLockTTL = 90 * time.Second
+
+// idempotencyRecord is the stored state of a request keyed by Idempotency-Key
+type idempotencyRecord struct {
+	State       string `json:"state"`
+	Fingerprint string `json:"fingerprint"`
+	StatusCode  int    `json:"status_code,omitempty"`
+	ContentType string `json:"content_type,omitempty"`
+	Body        []byte `json:"body,omitempty"`
+}
+
+// responseRecorder captures the response status and body so they can be cached
+type responseRecorder struct {
+	statusRecorder
+	body bytes.Buffer
+}
+
+func (r *responseRecorder) Write(data []byte) (int, error) {
+	r.body.Write(data)
+	return r.statusRecorder.Write(data)
+}
+
+// idempotency caches responses by Idempotency-Key. Replays return the stored
+// status and body, concurrent duplicates get 409, and reusing a key with a
+// different payload gets 422. Clients replaying more than the configured
+// threshold per hour are rejected with 429. pattern is the route the key is
+// scoped to.
+//
+// A request holds its key for idempotencyLockTTL while in flight; the response
+// is then kept for the idempotency TTL. Server errors and panics release the
+// key so the client can retry. Both are done even if the client disconnects.
+func (h *Handler) idempotency(pattern string) middleware {
+	logger := util.GetLogger()
+	redis, ttl := h.redis, h.cfg.IdempotencyTTL
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get("Idempotency-Key")
+			if key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				writeProblem(w, r, apperrors.New(apperrors.ErrInvalidRequest, "failed to read request body"))
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			sum := sha256.Sum256(append([]byte(r.Method+" "+pattern+"\n"), body...))
+			fingerprint := hex.EncodeToString(sum[:])
+
+			ctx := r.Context()
+			claim, _ := json.Marshal(idempotencyRecord{State: idempotencyStateInFlight, Fingerprint: fingerprint})
+
+			claimed, err := redis.ClaimIdempotencyKey(ctx, key, claim, idempotencyLockTTL)
+			if err != nil {
+				logger.Warn("Idempotency store unavailable, processing without response cache", zap.Error(err))
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if !claimed {
+				if h.replayLimitExceeded(r) {
+					util.IdempotencyRequestsTotal.WithLabelValues("rejected").Inc()
+					writeProblem(w, r, apperrors.New(apperrors.ErrRateLimited, "too many idempotent replays from this client"))
+					return
+				}
+				replayIdempotentResponse(w, r, redis, key, fingerprint)
+				return
+			}
+			util.IdempotencyRequestsTotal.WithLabelValues("new").Inc()
+
+			recorder := &responseRecorder{statusRecorder: statusRecorder{ResponseWriter: w}}
+			served := false
+			defer func() {
+				// The request's context ends with the client's connection
+				ctx := context.WithoutCancel(ctx)
+				status := recorder.Status()
+				if !served || status >= http.StatusInternalServerError {
+					// Let the client retry server errors and panics with the same key
+					if err := redis.DeleteIdempotencyKey(ctx, key); err != nil {
+						logger.Error("Failed to release idempotency key", zap.String("key", key), zap.Error(err))
+					}
+					return
+				}
+
+				record, _ := json.Marshal(idempotencyRecord{
+					State:       idempotencyStateCompleted,
+					Fingerprint: fingerprint,
+					StatusCode:  status,
+					ContentType: recorder.Header().Get("Content-Type"),
+					Body:        recorder.body.Bytes(),
+				})
+				if err := redis.SetIdempotencyKey(ctx, key, record, ttl); err != nil {
+					logger.Error("Failed to store idempotent response", zap.String("key", key), zap.Error(err))
+				}
+			}()
+			next.ServeHTTP(recorder, r)
+			served = true
+		})
+	}
+}
+
+// replayIdempotentResponse answers a request whose key has already been claimed
+func replayIdempotentResponse(w http.ResponseWriter, r *http.Request, redis *redisclient.Client, key, fingerprint string) {
+	data, err := redis.GetIdempotencyKey(r.Context(), key)
+	if err != nil || data == nil {
+		writeProblem(w, r, apperrors.New(apperrors.ErrRequestInProgress, "a request with this Idempotency-Key is in progress"))
+		return
+	}
+
+	var record idempotencyRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		writeProblem(w, r, apperrors.New(apperrors.ErrRequestInProgress, "a request with this Idempotency-Key is in progress"))
+		return
+	}
+
+	if record.Fingerprint != fingerprint {
+		util.IdempotencyRequestsTotal.WithLabelValues("mismatch").Inc()
+		writeProblem(w, r, apperrors.New(apperrors.ErrIdempotencyMismatch, "Idempotency-Key was already used with a different request"))
+		return
+	}
+
+	if record.State == idempotencyStateInFlight {
+		util.IdempotencyRequestsTotal.WithLabelValues("in_flight").Inc()
+		writeProblem(w, r, apperrors.New(apperrors.ErrRequestInProgress, "a request with this Idempotency-Key is in progress"))
+		return
+	}
+
+	util.IdempotencyRequestsTotal.WithLabelValues("replayed").Inc()
+	w.Header().Set("Idempotent-Replayed", "true")
+	writeData(w, record.StatusCode, record.ContentType, record.Body)
+}
+
+// clientIdentity identifies the caller for replay accounting: API key if sent, else client IP
+func clientIdentity(r *http.Request) string {
+	if apiKey := r.Header.Get("X-API-Key"); apiKey != "" {
+		sum := sha256.Sum256([]byte(apiKey))
+		return "key:" + hex.EncodeToString(sum[:8])
+	}
+	return "ip:" + clientIP(r)
+}
+
+// replayLimitExceeded records a replay for the caller and reports whether it crossed the threshold
+func (h *Handler) replayLimitExceeded(r *http.Request) bool {
+	count, err := h.redis.RecordIdempotencyReplay(r.Context(), clientIdentity(r))
+	if err != nil {
+		util.GetLogger().Warn("Failed to record idempotency replay", zap.Error(err))
+		return false
+	}
+	threshold := h.replayRejectThreshold.Load()
+	return threshold > 0 && count > threshold
+}
+
+// topIdempotencyReplayers lists the clients replaying the most idempotency keys this hour
+func (h *Handler) topIdempotencyReplayers(w http.ResponseWriter, r *http.Request) {
+	limit, err := strconv.Atoi(queryDefault(r, "limit", "10"))
+	if err != nil || limit <= 0 || limit > 100 {
+		writeProblem(w, r, apperrors.New(apperrors.ErrInvalidRequest, "limit must be between 1 and 100"))
+		return
+	}
+
+	offenders, err := h.redis.TopIdempotencyReplayers(r.Context(), limit)
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, H{
+		"window":    "current_hour",
+		"threshold": h.replayRejectThreshold.Load(),
+		"clients":   offenders,
+	})
+}
@@ -0,0 +1,121 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"order-service/internal/redisclient"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newIdempotentRoute serves handler behind the idempotency middleware over
+// an in-memory Redis
+func newIdempotentRoute(t *testing.T, handler http.HandlerFunc) (http.Handler, *miniredis.Miniredis) {
+	t.Helper()
+	server := miniredis.RunT(t)
+	client, err := redisclient.NewClient(server.Addr(), "", 0)
+	require.NoError(t, err)
+	t.Cleanup(func() { client.Close() })
+
+	h := &Handler{redis: client, cfg: HandlerConfig{IdempotencyTTL: 24 * time.Hour}}
+	return chain(handler, h.idempotency("/api/v1/orders")), server
+}
+
+func postWithKey(router http.Handler, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/orders", strings.NewReader(body))
+	req.Header.Set("Idempotency-Key", "key-1")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestIdempotencyReplaysTheStoredResponse(t *testing.T) {
+	calls := 0
+	router, server := newIdempotentRoute(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		writeJSON(w, http.StatusCreated, H{"id": calls})
+	})
+
+	first := postWithKey(router, `{"a":1}`)
+	require.Equal(t, http.StatusCreated, first.Code)
+	// The response is kept for the idempotency TTL, not the lock's
+	assert.Greater(t, server.TTL("idempotency:key-1"), idempotencyLockTTL)
+
+	replay := postWithKey(router, `{"a":1}`)
+	assert.Equal(t, http.StatusCreated, replay.Code)
+	assert.Equal(t, first.Body.String(), replay.Body.String())
+	assert.Equal(t, "true", replay.Header().Get("Idempotent-Replayed"))
+	assert.Equal(t, 1, calls)
+}
+
+func TestIdempotencyRejectsConcurrentDuplicates(t *testing.T) {
+	var router http.Handler
+	var server *miniredis.Miniredis
+	var duplicate *httptest.ResponseRecorder
+	var lockTTL time.Duration
+	router, server = newIdempotentRoute(t, func(w http.ResponseWriter, r *http.Request) {
+		if duplicate == nil {
+			lockTTL = server.TTL("idempotency:key-1")
+			duplicate = postWithKey(router, `{"a":1}`)
+		}
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	require.Equal(t, http.StatusCreated, postWithKey(router, `{"a":1}`).Code)
+	assert.Equal(t, http.StatusConflict, duplicate.Code)
+	assert.Equal(t, idempotencyLockTTL, lockTTL, "requests in flight hold their key briefly")
+}
+
+func TestIdempotencyRejectsReusedKeys(t *testing.T) {
+	router, _ := newIdempotentRoute(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	require.Equal(t, http.StatusCreated, postWithKey(router, `{"a":1}`).Code)
+	assert.Equal(t, http.StatusUnprocessableEntity, postWithKey(router, `{"a":2}`).Code)
+}
+
+func TestIdempotencyReleasesKeysOfServerErrors(t *testing.T) {
+	calls := 0
+	router, _ := newIdempotentRoute(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		switch calls {
+		case 1:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case 2:
+			panic("boom")
+		default:
+			w.WriteHeader(http.StatusCreated)
+		}
+	})
+
+	assert.Equal(t, http.StatusServiceUnavailable, postWithKey(router, `{"a":1}`).Code)
+	assert.Panics(t, func() { postWithKey(router, `{"a":1}`) })
+	assert.Equal(t, http.StatusCreated, postWithKey(router, `{"a":1}`).Code)
+	assert.Equal(t, 3, calls)
+}
+
+func TestIdempotencyStoresResponsesOfDisconnectedClients(t *testing.T) {
+	ctx, disconnect := context.WithCancel(context.Background())
+	calls := 0
+	router, _ := newIdempotentRoute(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		disconnect()
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/orders", strings.NewReader(`{"a":1}`)).WithContext(ctx)
+	req.Header.Set("Idempotency-Key", "key-1")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	replay := postWithKey(router, `{"a":1}`)
+	assert.Equal(t, http.StatusCreated, replay.Code)
+	assert.Equal(t, 1, calls)
+}
@@ -0,0 +1,131 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"order-service/internal/apperrors"
+	"order-service/internal/service"
+)
+
+// SetIncomingStock enables availability by date and the incoming stock admin endpoints
+func (h *Handler) SetIncomingStock(incoming *service.IncomingStockService) {
+	h.incomingStock = incoming
+}
+
+// incomingStockParam parses an ID from the path parameter or query parameter
+// name; ok is false once a problem was written
+func (h *Handler) incomingStockParam(w http.ResponseWriter, r *http.Request, name, what string) (int64, bool) {
+	if h.incomingStock == nil {
+		writeProblem(w, r, apperrors.New(apperrors.ErrNotFound, "incoming stock is disabled"))
+		return 0, false
+	}
+
+	raw := r.PathValue(name)
+	if raw == "" {
+		raw = r.URL.Query().Get(name)
+	}
+	id, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil {
+		writeProblem(w, r, apperrors.New(apperrors.ErrInvalidRequest, "invalid %s %q", what, raw))
+		return 0, false
+	}
+	return id, true
+}
+
+// getVariantAvailability returns the stock of a variant now and as each
+// incoming arrival comes in
+func (h *Handler) getVariantAvailability(w http.ResponseWriter, r *http.Request) {
+	variantID, ok := h.incomingStockParam(w, r, "id", "variant ID")
+	if !ok {
+		return
+	}
+
+	availability, err := h.incomingStock.Availability(r.Context(), variantID)
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, availability)
+}
+
+// listIncomingStock returns the open incoming stock of a variant
+func (h *Handler) listIncomingStock(w http.ResponseWriter, r *http.Request) {
+	variantID, ok := h.incomingStockParam(w, r, "variant_id", "variant ID")
+	if !ok {
+		return
+	}
+
+	incoming, err := h.incomingStock.ListOpen(r.Context(), variantID)
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, H{
+		"variant_id":     variantID,
+		"incoming_stock": incoming,
+	})
+}
+
+// createIncomingStock records stock expected from a supplier
+func (h *Handler) createIncomingStock(w http.ResponseWriter, r *http.Request) {
+	if h.incomingStock == nil {
+		writeProblem(w, r, apperrors.New(apperrors.ErrNotFound, "incoming stock is disabled"))
+		return
+	}
+
+	var req service.CreateIncomingStockRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeProblem(w, r, apperrors.New(apperrors.ErrInvalidRequest, "%v", err))
+		return
+	}
+
+	incoming, err := h.incomingStock.Create(r.Context(), req)
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, incoming)
+}
+
+// updateIncomingStock changes the quantity, ETA or status of incoming stock
+// and replans the backorders waiting for it
+func (h *Handler) updateIncomingStock(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.incomingStockParam(w, r, "id", "incoming stock ID")
+	if !ok {
+		return
+	}
+
+	var req service.UpdateIncomingStockRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeProblem(w, r, apperrors.New(apperrors.ErrInvalidRequest, "%v", err))
+		return
+	}
+
+	incoming, err := h.incomingStock.Update(r.Context(), id, req)
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, incoming)
+}
+
+// receiveIncomingStock adds arrived incoming stock to available stock
+func (h *Handler) receiveIncomingStock(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.incomingStockParam(w, r, "id", "incoming stock ID")
+	if !ok {
+		return
+	}
+
+	incoming, err := h.incomingStock.Receive(r.Context(), id)
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, incoming)
+}
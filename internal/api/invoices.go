@@ -0,0 +1,42 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"order-service/internal/apperrors"
+	"order-service/internal/rbac"
+	"order-service/internal/service"
+)
+
+// SetInvoices enables GET /orders/{id}/invoice
+func (h *Handler) SetInvoices(invoices *service.InvoiceService) {
+	h.invoices = invoices
+}
+
+// getOrderInvoice returns a signed link downloading the PDF invoice of an
+// order, once it is confirmed and invoiced
+func (h *Handler) getOrderInvoice(w http.ResponseWriter, r *http.Request) {
+	if h.invoices == nil {
+		writeProblem(w, r, apperrors.New(apperrors.ErrNotFound, "invoices are disabled"))
+		return
+	}
+
+	idStr := r.PathValue("id")
+	orderID, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		writeProblem(w, r, apperrors.New(apperrors.ErrInvalidRequest, "invalid order ID %q", idStr))
+		return
+	}
+	if err := h.authorizeOrder(r, rbac.Read, orderID); err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	link, err := h.invoices.Link(r.Context(), orderID)
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, link)
+}
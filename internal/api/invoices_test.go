@@ -0,0 +1,70 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"order-service/internal/models"
+	"order-service/internal/service"
+	"order-service/internal/store/mocks"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// signingStorage signs links of invoices without storing any
+type signingStorage struct{}
+
+func (signingStorage) Publish(context.Context, string, string, []byte) error { return nil }
+
+func (signingStorage) URL(key string) string { return "s3://invoices/" + key }
+
+func (signingStorage) SignedURL(_ context.Context, key string, _ time.Duration) (string, error) {
+	return "https://storage.example.com/" + key + "?signed", nil
+}
+
+func TestGetOrderInvoiceRejectsOtherCustomers(t *testing.T) {
+	secret := []byte("s3cret")
+	url := "s3://invoices/acme/1.pdf"
+	orders := mocks.NewOrderRepository(t)
+	orders.On("GetOrderByID", mock.Anything, int64(1)).Return(&models.Order{ID: 1, UserID: 42, InvoiceURL: &url}, nil)
+	invoices, err := service.NewInvoiceService(orders, mocks.NewInventoryRepository(t), mocks.NewInvoiceRepository(t),
+		signingStorage{}, service.InvoiceOptions{LinkTTL: time.Minute})
+	require.NoError(t, err)
+
+	h := &Handler{
+		cfg: HandlerConfig{
+			TenantJWTSecret:  secret,
+			TenantJWTClaim:   "tenant_id",
+			CustomerJWTClaim: "sub",
+		},
+		orderService: service.NewOrderService(orders, nil, nil, nil, nil, nil, nil, nil),
+	}
+	h.SetInvoices(invoices)
+	router := h.tenancy(chain(h.getOrderInvoice, h.orderAuth))
+
+	get := func(authorization string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/orders/1/invoice", nil)
+		req.SetPathValue("id", "1")
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := get(customerToken(t, secret, 7))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.NotContains(t, rec.Body.String(), "signed", "no link is signed for another user's order")
+
+	assert.Equal(t, http.StatusUnauthorized, get("").Code)
+
+	rec = get(customerToken(t, secret, 42))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "https://storage.example.com/acme/1.pdf?signed")
+}
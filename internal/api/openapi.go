@@ -0,0 +1,38 @@
+package api
+
+import (
+	_ "embed"
+	"net/http"
+)
+
+//go:embed openapi.json
+var openAPISpec []byte
+
+// swaggerUIPage renders Swagger UI against the embedded spec
+const swaggerUIPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8" />
+  <title>Order Service API</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css" />
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js" crossorigin></script>
+  <script>
+    window.onload = () => {
+      window.ui = SwaggerUIBundle({ url: "/openapi.json", dom_id: "#swagger-ui" });
+    };
+  </script>
+</body>
+</html>`
+
+// openAPIDocument serves the OpenAPI 3 specification
+func (h *Handler) openAPIDocument(w http.ResponseWriter, r *http.Request) {
+	writeData(w, http.StatusOK, "application/json", openAPISpec)
+}
+
+// swaggerUI serves the interactive API documentation
+func (h *Handler) swaggerUI(w http.ResponseWriter, r *http.Request) {
+	writeData(w, http.StatusOK, "text/html; charset=utf-8", []byte(swaggerUIPage))
+}
@@ -0,0 +1,17 @@
+package api
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenAPISpecIsValidJSON(t *testing.T) {
+	var spec map[string]interface{}
+	require.NoError(t, json.Unmarshal(openAPISpec, &spec))
+
+	assert.Equal(t, "3.0.3", spec["openapi"])
+	assert.Contains(t, spec["paths"], "/api/v1/orders")
+}
@@ -0,0 +1,123 @@
+package api
+
+import (
+	"net/http"
+
+	"order-service/internal/apperrors"
+	"order-service/internal/models"
+	"order-service/internal/rbac"
+)
+
+// orderAuth authenticates the callers of the customer order routes: staff by
+// their admin token, customers by the customer claim of their bearer JWT
+// (see tenancy). Anonymous requests pass, for handlers to refuse with
+// authorizeOrders once the request is otherwise valid.
+func (h *Handler) orderAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if role := h.staffRole(r); role != "" {
+			staff, err := asStaff(r, role)
+			if err != nil {
+				writeProblem(w, r, err)
+				return
+			}
+			r = staff
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// isStaff reports whether the caller authenticated with an admin token
+func isStaff(r *http.Request) bool {
+	_, ok := r.Context().Value(adminRoleKey{}).(string)
+	return ok
+}
+
+// authorizeOrders returns an error unless the caller is staff whose role may
+// perform action on orders, or an authenticated customer. What customers may
+// do is further limited to their own orders, see authorizeOrder.
+func authorizeOrders(r *http.Request, action string) error {
+	if _, ok := rbac.Customer(r.Context()); !ok && !isStaff(r) {
+		return apperrors.New(apperrors.ErrUnauthorized, "a customer bearer token or an admin token is required")
+	}
+	return authorize(r, action, rbac.Orders)
+}
+
+// ownsOrder returns a not found error, so order IDs cannot be probed, unless
+// the caller may see order: staff, or the customer who placed it
+func ownsOrder(r *http.Request, order *models.Order) error {
+	if !isStaff(r) && !rbac.OwnsOrder(r.Context(), order.UserID) {
+		return apperrors.New(apperrors.ErrOrderNotFound, "order %d not found", order.ID)
+	}
+	return nil
+}
+
+// authorizeOrder returns an error unless the caller may perform action on an
+// order: staff whose role may, or the customer who placed it
+func (h *Handler) authorizeOrder(r *http.Request, action string, orderID int64) error {
+	if err := authorizeOrders(r, action); err != nil {
+		return err
+	}
+	if isStaff(r) {
+		return nil
+	}
+	order, err := h.orderService.GetOrderUncached(r.Context(), orderID)
+	if err != nil {
+		return err
+	}
+	return ownsOrder(r, order)
+}
+
+// orderCustomer returns the user the caller acts as on an order: the
+// authenticated customer, whose ownership the services check, or for staff
+// whose role may perform action, the customer who placed it
+func (h *Handler) orderCustomer(r *http.Request, action string, orderID int64) (int64, error) {
+	if err := authorizeOrders(r, action); err != nil {
+		return 0, err
+	}
+	if customer, ok := rbac.Customer(r.Context()); ok && !isStaff(r) {
+		return customer, nil
+	}
+	order, err := h.orderService.GetOrderUncached(r.Context(), orderID)
+	if err != nil {
+		return 0, err
+	}
+	return order.UserID, nil
+}
+
+// actingCustomer returns the user the caller acts as when it names userID, 0
+// for none, in a request: the authenticated customer, who may only name
+// themselves, or for staff whose role may perform action on orders, userID
+func actingCustomer(r *http.Request, action string, userID int64) (int64, error) {
+	if err := authorizeOrders(r, action); err != nil {
+		return 0, err
+	}
+	if customer, ok := rbac.Customer(r.Context()); ok && !isStaff(r) {
+		if userID != 0 && userID != customer {
+			return 0, apperrors.New(apperrors.ErrForbidden, "customer %d may not act for user %d", customer, userID)
+		}
+		return customer, nil
+	}
+	if userID <= 0 {
+		return 0, apperrors.New(apperrors.ErrInvalidRequest, "user_id is required")
+	}
+	return userID, nil
+}
+
+// paysFromWallet reports whether an order is paid, in part or in full, from
+// the wallet of its customer
+func paysFromWallet(walletAmount int64, paymentMethod string) bool {
+	return walletAmount != 0 || paymentMethod == models.PaymentMethodWallet
+}
+
+// authorizeWallet returns an error unless the caller is the customer userID,
+// the only one who may spend their wallet
+func authorizeWallet(r *http.Request, userID int64) error {
+	customer, ok := rbac.Customer(r.Context())
+	switch {
+	case !ok:
+		return apperrors.New(apperrors.ErrUnauthorized, "paying from a wallet needs the customer's bearer token")
+	case customer != userID:
+		return apperrors.New(apperrors.ErrForbidden, "only user %d may pay from their wallet", userID)
+	}
+	return nil
+}
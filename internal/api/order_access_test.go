@@ -0,0 +1,206 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"order-service/internal/apperrors"
+	"order-service/internal/models"
+	"order-service/internal/rbac"
+	"order-service/internal/redisclient"
+	"order-service/internal/service"
+	"order-service/internal/store/mocks"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// customerToken signs a bearer JWT authenticating the customer userID
+func customerToken(t *testing.T, secret []byte, userID int64) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"tenant_id": "acme", "sub": userID}).SignedString(secret)
+	require.NoError(t, err)
+	return "Bearer " + token
+}
+
+func TestCustomersOnlyReachTheirOwnOrders(t *testing.T) {
+	secret := []byte("s3cret")
+	orders := mocks.NewOrderRepository(t)
+	orders.On("GetOrderByID", mock.Anything, int64(1)).Return(&models.Order{ID: 1, UserID: 42}, nil)
+	orders.On("GetOrderStatusHistory", mock.Anything, int64(1)).Return([]models.OrderStatusHistory{}, nil)
+	h := &Handler{
+		cfg: HandlerConfig{
+			AdminTokens:      map[string]string{"v1": rbac.RoleViewer},
+			TenantJWTSecret:  secret,
+			TenantJWTClaim:   "tenant_id",
+			CustomerJWTClaim: "sub",
+		},
+		orderService: service.NewOrderService(orders, nil, nil, nil, nil, nil, nil, nil),
+	}
+	router := h.tenancy(chain(h.getOrderHistory, h.orderAuth))
+
+	cases := []struct {
+		name          string
+		authorization string
+		want          int
+	}{
+		{"anonymous", "", http.StatusUnauthorized},
+		{"owner", customerToken(t, secret, 42), http.StatusOK},
+		{"other customer", customerToken(t, secret, 7), http.StatusNotFound},
+		{"staff", "Bearer v1", http.StatusOK},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/orders/1/history", nil)
+		req.SetPathValue("id", "1")
+		if tc.authorization != "" {
+			req.Header.Set("Authorization", tc.authorization)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		assert.Equal(t, tc.want, rec.Code, tc.name)
+	}
+}
+
+func TestWalletPaymentsNeedTheirCustomer(t *testing.T) {
+	secret := []byte("s3cret")
+	h := &Handler{cfg: HandlerConfig{
+		OrderRules:       testOrderRules,
+		TenantJWTSecret:  secret,
+		TenantJWTClaim:   "tenant_id",
+		CustomerJWTClaim: "sub",
+	}}
+	router := h.tenancy(http.HandlerFunc(h.createOrder))
+
+	cases := []struct {
+		name          string
+		authorization string
+		want          int
+	}{
+		{"anonymous", "", http.StatusUnauthorized},
+		{"other customer", customerToken(t, secret, 7), http.StatusForbidden},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/orders",
+			strings.NewReader(`{"user_id": 42, "items": [{"product_id": 1, "quantity": 1}], "payment_method": "mock", "wallet_amount": 500}`))
+		if tc.authorization != "" {
+			req.Header.Set("Authorization", tc.authorization)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		assert.Equal(t, tc.want, rec.Code, tc.name)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/orders", nil)
+	assert.NoError(t, authorizeWallet(req.WithContext(rbac.WithCustomer(req.Context(), 42)), 42))
+}
+
+func TestCustomersOnlyReachTheirOwnCarts(t *testing.T) {
+	secret := []byte("s3cret")
+	server := miniredis.RunT(t)
+	redis, err := redisclient.NewClient(server.Addr(), "", 0)
+	require.NoError(t, err)
+	t.Cleanup(func() { redis.Close() })
+
+	const cartID = "0b7c3c52-0d4e-4d8c-9a55-3f0e6a1b2c3d"
+	carts := mocks.NewCartRepository(t)
+	carts.On("GetCart", mock.Anything, cartID).
+		Return(&models.Cart{ID: cartID, UserID: 42, ExpiresAt: time.Now().Add(time.Hour)}, nil).Maybe()
+	h := &Handler{
+		cfg: HandlerConfig{
+			AdminTokens:      map[string]string{"v1": rbac.RoleViewer},
+			TenantJWTSecret:  secret,
+			TenantJWTClaim:   "tenant_id",
+			CustomerJWTClaim: "sub",
+		},
+		carts: service.NewCartService(carts, nil, nil, redis, service.CartPolicy{TTL: time.Hour}),
+	}
+	router := h.tenancy(chain(h.getCart, h.orderAuth))
+
+	cases := []struct {
+		name          string
+		authorization string
+		want          int
+	}{
+		{"anonymous", "", http.StatusUnauthorized},
+		{"owner", customerToken(t, secret, 42), http.StatusOK},
+		{"other customer", customerToken(t, secret, 7), http.StatusNotFound},
+		{"staff", "Bearer v1", http.StatusOK},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/carts/"+cartID, nil)
+		req.SetPathValue("id", cartID)
+		if tc.authorization != "" {
+			req.Header.Set("Authorization", tc.authorization)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		assert.Equal(t, tc.want, rec.Code, tc.name)
+	}
+}
+
+func TestActingCustomer(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/carts", nil)
+	customer := req.WithContext(rbac.WithCustomer(req.Context(), 42))
+	staff, err := asStaff(req, rbac.RoleSupport)
+	require.NoError(t, err)
+
+	userID, err := actingCustomer(customer, rbac.Write, 0)
+	require.NoError(t, err)
+	assert.Equal(t, int64(42), userID, "customers act as themselves")
+
+	_, err = actingCustomer(customer, rbac.Write, 7)
+	assert.ErrorIs(t, err, apperrors.ErrForbidden)
+
+	_, err = actingCustomer(req, rbac.Write, 42)
+	assert.ErrorIs(t, err, apperrors.ErrUnauthorized)
+
+	userID, err = actingCustomer(staff, rbac.Write, 7)
+	require.NoError(t, err)
+	assert.Equal(t, int64(7), userID)
+
+	_, err = actingCustomer(staff, rbac.Write, 0)
+	assert.ErrorIs(t, err, apperrors.ErrInvalidRequest)
+}
+
+func TestCustomersOnlySeeTheirOwnDropRegistrations(t *testing.T) {
+	secret := []byte("s3cret")
+	drops := mocks.NewDropRepository(t)
+	drops.On("GetDropRegistration", mock.Anything, int64(1), int64(42)).
+		Return(&models.DropRegistration{DropID: 1, UserID: 42}, nil).Once()
+	h := &Handler{
+		cfg: HandlerConfig{
+			TenantJWTSecret:  secret,
+			TenantJWTClaim:   "tenant_id",
+			CustomerJWTClaim: "sub",
+		},
+		drops: service.NewDropService(drops, nil, time.Minute),
+	}
+	router := h.tenancy(chain(h.getDropRegistration, h.orderAuth))
+
+	cases := []struct {
+		name          string
+		authorization string
+		want          int
+	}{
+		{"anonymous", "", http.StatusUnauthorized},
+		{"other customer", customerToken(t, secret, 7), http.StatusNotFound},
+		{"owner", customerToken(t, secret, 42), http.StatusOK},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/drops/1/registrations/42", nil)
+		req.SetPathValue("id", "1")
+		req.SetPathValue("user_id", "42")
+		if tc.authorization != "" {
+			req.Header.Set("Authorization", tc.authorization)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		assert.Equal(t, tc.want, rec.Code, tc.name)
+	}
+}
@@ -0,0 +1,61 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"order-service/internal/apperrors"
+	"order-service/internal/rbac"
+	"order-service/internal/realtime"
+	"order-service/internal/tenant"
+	"order-service/internal/util"
+)
+
+// SetRealtimeHub enables GET /ws/orders
+func (h *Handler) SetRealtimeHub(hub *realtime.Hub) {
+	h.realtime = hub
+}
+
+// orderSocket upgrades to a WebSocket pushing the status changes of the
+// authenticated customer's orders
+func (h *Handler) orderSocket(w http.ResponseWriter, r *http.Request) {
+	if h.realtime == nil {
+		writeProblem(w, r, apperrors.New(apperrors.ErrNotFound, "order notifications are disabled"))
+		return
+	}
+
+	tenantID, userID, err := h.socketCustomer(r)
+	if err == nil && userID == 0 {
+		err = apperrors.New(apperrors.ErrUnauthorized, "a customer bearer token is required")
+	}
+	if err != nil {
+		util.RealtimeRejectedTotal.WithLabelValues("unauthorized").Inc()
+		writeProblem(w, r, err)
+		return
+	}
+
+	if err := h.realtime.Serve(w, r, tenantID, userID); err != nil {
+		if errors.Is(err, realtime.ErrAtCapacity) {
+			writeProblem(w, r, apperrors.New(apperrors.ErrUnavailable, "%v", err))
+			return
+		}
+		writeProblem(w, r, err)
+	}
+}
+
+// socketCustomer returns the tenant and customer a socket request
+// authenticates, from its bearer JWT (see tenancy) or, for browsers that
+// cannot set headers on WebSockets, the same JWT in its token query parameter.
+// The customer is 0 if there is none.
+func (h *Handler) socketCustomer(r *http.Request) (string, int64, error) {
+	if userID, ok := rbac.Customer(r.Context()); ok {
+		return tenant.Owner(r.Context()), userID, nil
+	}
+	token := r.URL.Query().Get("token")
+	if token == "" || r.Header.Get("Authorization") != "" {
+		return "", 0, nil
+	}
+	r = r.Clone(r.Context())
+	r.Header.Set("Authorization", "Bearer "+token)
+	return h.resolveTenant(r)
+}
@@ -0,0 +1,62 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"order-service/internal/models"
+	"order-service/internal/realtime"
+	"order-service/internal/tenant"
+
+	"github.com/gorilla/websocket"
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type socketOrders map[int64]*models.Order
+
+func (o socketOrders) GetOrderByID(_ context.Context, id int64) (*models.Order, error) {
+	return o[id], nil
+}
+
+func TestOrderSocketAuthenticatesTheCustomerJWT(t *testing.T) {
+	secret := []byte("s3cret")
+	hub := realtime.NewHub(socketOrders{}, realtime.Limits{MaxConnections: 10, MaxConnectionsPerUser: 2, SendBuffer: 4})
+	h := &Handler{cfg: HandlerConfig{TenantJWTSecret: secret, TenantJWTClaim: "tenant_id", CustomerJWTClaim: "sub"}}
+	h.SetRealtimeHub(hub)
+	server := httptest.NewServer(h.tenancy(http.HandlerFunc(h.orderSocket)))
+	defer server.Close()
+	url := "ws" + strings.TrimPrefix(server.URL, "http")
+
+	_, resp, err := websocket.DefaultDialer.Dial(url, nil)
+	require.Error(t, err)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	_, resp, err = websocket.DefaultDialer.Dial(url+"?token=forged.jwt.token", nil)
+	require.Error(t, err)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	token := strings.TrimPrefix(customerToken(t, secret, 42), "Bearer ")
+	ws, _, err := websocket.DefaultDialer.Dial(url+"?token="+token, nil)
+	require.NoError(t, err)
+	defer ws.Close()
+
+	// The socket is registered before it is upgraded. Customer 42 of another
+	// tenant has an order paid, then ours does.
+	require.NoError(t, hub.HandleMessage(tenant.WithID(context.Background(), "globex"), kafka.Message{
+		Value: []byte(`{"event_type":"ORDER_PAID","order_id":8,"user_id":42}`),
+	}))
+	require.NoError(t, hub.HandleMessage(tenant.WithID(context.Background(), "acme"), kafka.Message{
+		Value: []byte(`{"event_type":"ORDER_PAID","order_id":7,"user_id":42}`),
+	}))
+
+	var n realtime.Notification
+	_ = ws.SetReadDeadline(time.Now().Add(time.Second))
+	require.NoError(t, ws.ReadJSON(&n))
+	assert.Equal(t, int64(7), n.OrderID)
+}
@@ -0,0 +1,86 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"order-service/internal/apperrors"
+	"order-service/internal/rbac"
+	"order-service/internal/redisclient"
+	"order-service/internal/service"
+)
+
+// SetOrderStatusFeed enables GET /api/v1/orders/{id}/events
+func (h *Handler) SetOrderStatusFeed(feed *service.OrderStatusFeed) {
+	h.statusFeed = feed
+}
+
+// trackOrder streams an order's status changes as Server-Sent Events, starting
+// with its current status, until the order reaches a final status
+func (h *Handler) trackOrder(w http.ResponseWriter, r *http.Request) {
+	if h.statusFeed == nil {
+		writeProblem(w, r, apperrors.New(apperrors.ErrNotFound, "order tracking is disabled"))
+		return
+	}
+
+	idStr := r.PathValue("id")
+	orderID, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		writeProblem(w, r, apperrors.New(apperrors.ErrInvalidRequest, "invalid order ID %q", idStr))
+		return
+	}
+	if err := h.authorizeOrder(r, rbac.Read, orderID); err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	// Subscribe before reading the current status so no transition falls in between
+	updates, cancel, err := h.statusFeed.Subscribe(orderID)
+	if err != nil {
+		if errors.Is(err, service.ErrStatusFeedAtCapacity) {
+			writeProblem(w, r, apperrors.New(apperrors.ErrUnavailable, "%v", err))
+			return
+		}
+		writeProblem(w, r, err)
+		return
+	}
+	defer cancel()
+
+	order, _, err := h.orderService.GetOrder(r.Context(), orderID)
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	clearDeadlines(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	flusher := http.NewResponseController(w)
+	status := order.Status
+	writeSSE(w, "status", redisclient.OrderStatusChange{OrderID: orderID, Status: status, Timestamp: order.UpdatedAt})
+	_ = flusher.Flush()
+
+	heartbeat := time.NewTicker(availabilityHeartbeat)
+	defer heartbeat.Stop()
+
+	for !service.IsFinalStatus(status) {
+		select {
+		case <-r.Context().Done():
+			return
+		case change := <-updates:
+			if change.Status == status {
+				continue
+			}
+			status = change.Status
+			writeSSE(w, "status", change)
+		case <-heartbeat.C:
+			writeSSE(w, "heartbeat", time.Now().Unix())
+		}
+		_ = flusher.Flush()
+	}
+}
@@ -0,0 +1,107 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"order-service/internal/apperrors"
+	"order-service/internal/models"
+	"order-service/internal/service"
+	"order-service/internal/util"
+)
+
+// idempotencyKeyFormat accepts UUIDs and opaque tokens, not free text that
+// could carry personal data into Redis keys and logs
+var idempotencyKeyFormat = regexp.MustCompile(`^[A-Za-z0-9_.:-]{8,255}$`)
+
+// OrderRules are the business limits CreateOrder requests are validated
+// against, whether or not schema validation is on
+type OrderRules struct {
+	MaxItems        int
+	MaxItemQuantity int
+	// PaymentMethods and Currencies are the values accepted; empty accepts any
+	PaymentMethods []string
+	Currencies     []string
+}
+
+// OrderValidator checks a CreateOrder request beyond the built-in rules and
+// returns the fields it breaks
+type OrderValidator func(ctx context.Context, req *service.CreateOrderRequest) []apperrors.FieldError
+
+// AddOrderValidator adds a check CreateOrder requests must pass after the
+// built-in rules
+func (h *Handler) AddOrderValidator(v OrderValidator) {
+	h.orderValidators = append(h.orderValidators, v)
+}
+
+// validateOrder returns an invalid_request error listing every field of req
+// that breaks a rule, or nil
+func (h *Handler) validateOrder(ctx context.Context, req *service.CreateOrderRequest) error {
+	fields := h.cfg.OrderRules.check(req)
+	for _, validate := range h.orderValidators {
+		fields = append(fields, validate(ctx, req)...)
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+	for _, field := range fields {
+		util.OrderValidationFailuresTotal.WithLabelValues(field.Rule).Inc()
+	}
+	return apperrors.Invalid(fields)
+}
+
+// check applies the rules to req
+func (rules OrderRules) check(req *service.CreateOrderRequest) []apperrors.FieldError {
+	var fields []apperrors.FieldError
+	invalid := func(field, rule, format string, args ...interface{}) {
+		fields = append(fields, apperrors.FieldError{Field: field, Rule: rule, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if req.UserID <= 0 {
+		invalid("user_id", "required", "is required")
+	}
+
+	switch {
+	case len(req.Items) == 0:
+		invalid("items", "required", "must hold at least one item")
+	case rules.MaxItems > 0 && len(req.Items) > rules.MaxItems:
+		invalid("items", "max_items", "must hold at most %d items, not %d", rules.MaxItems, len(req.Items))
+	}
+	for i, item := range req.Items {
+		if item.ProductID <= 0 {
+			invalid(fmt.Sprintf("items[%d].product_id", i), "required", "is required")
+		}
+		switch {
+		case item.Quantity < 1:
+			invalid(fmt.Sprintf("items[%d].quantity", i), "min_quantity", "must be at least 1")
+		case rules.MaxItemQuantity > 0 && item.Quantity > rules.MaxItemQuantity:
+			invalid(fmt.Sprintf("items[%d].quantity", i), "max_quantity", "must be at most %d", rules.MaxItemQuantity)
+		}
+	}
+
+	switch {
+	case req.PaymentMethod == "":
+		invalid("payment_method", "required", "is required")
+	case len(rules.PaymentMethods) > 0 && !slices.Contains(rules.PaymentMethods, req.PaymentMethod):
+		invalid("payment_method", "payment_method", "must be one of %s", strings.Join(rules.PaymentMethods, ", "))
+	}
+
+	if req.Currency != "" && len(rules.Currencies) > 0 && !slices.Contains(rules.Currencies, req.Currency) {
+		invalid("currency", "currency", "must be one of %s", strings.Join(rules.Currencies, ", "))
+	}
+
+	switch req.Priority {
+	case "", models.PriorityStandard, models.PriorityExpress:
+	default:
+		invalid("priority", "priority", "must be %s or %s", models.PriorityStandard, models.PriorityExpress)
+	}
+
+	if req.IdempotencyKey != "" && !idempotencyKeyFormat.MatchString(req.IdempotencyKey) {
+		invalid("idempotency_key", "idempotency_key_format", "must be 8 to 255 letters, digits or _.:-")
+	}
+
+	return fields
+}
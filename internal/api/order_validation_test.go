@@ -0,0 +1,88 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"order-service/internal/apperrors"
+	"order-service/internal/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testOrderRules = OrderRules{
+	MaxItems:        2,
+	MaxItemQuantity: 5,
+	PaymentMethods:  []string{"mock", "card"},
+	Currencies:      []string{"IDR"},
+}
+
+func TestOrderRulesAcceptValidOrder(t *testing.T) {
+	assert.Empty(t, testOrderRules.check(&service.CreateOrderRequest{
+		UserID:         1,
+		Items:          []service.OrderItemRequest{{ProductID: 1, Quantity: 5}},
+		PaymentMethod:  "card",
+		Currency:       "IDR",
+		Priority:       "express",
+		IdempotencyKey: "3f1c2a9e-7b1d-4c55-9a4e-0c2b7d8e9f10",
+	}))
+}
+
+func TestOrderRulesListEveryInvalidField(t *testing.T) {
+	fields := testOrderRules.check(&service.CreateOrderRequest{
+		UserID:         1,
+		Items:          []service.OrderItemRequest{{ProductID: 1, Quantity: 6}, {Quantity: 0}, {ProductID: 3, Quantity: 1}},
+		PaymentMethod:  "gift_card",
+		Currency:       "USD",
+		Priority:       "urgent",
+		IdempotencyKey: "my key",
+	})
+
+	rules := make(map[string]string)
+	for _, field := range fields {
+		rules[field.Field] = field.Rule
+	}
+	assert.Equal(t, map[string]string{
+		"items":               "max_items",
+		"items[0].quantity":   "max_quantity",
+		"items[1].product_id": "required",
+		"items[1].quantity":   "min_quantity",
+		"payment_method":      "payment_method",
+		"currency":            "currency",
+		"priority":            "priority",
+		"idempotency_key":     "idempotency_key_format",
+	}, rules)
+}
+
+func TestCreateOrderReturnsFieldErrors(t *testing.T) {
+	h := &Handler{cfg: HandlerConfig{OrderRules: testOrderRules}}
+	h.AddOrderValidator(func(ctx context.Context, req *service.CreateOrderRequest) []apperrors.FieldError {
+		if req.UserID == 13 {
+			return []apperrors.FieldError{{Field: "user_id", Rule: "blocked_user", Message: "may not order"}}
+		}
+		return nil
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/orders",
+		strings.NewReader(`{"user_id": 13, "items": [{"product_id": 1, "quantity": 9}], "payment_method": "mock"}`))
+	req.Header.Set("Idempotency-Key", "short")
+	rec := httptest.NewRecorder()
+	h.createOrder(rec, req)
+
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, problemContentType, rec.Header().Get("Content-Type"))
+	var problem Problem
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &problem))
+	assert.Equal(t, "invalid_request", problem.Code)
+	assert.Equal(t, "3 fields are invalid", problem.Detail)
+	assert.Equal(t, []apperrors.FieldError{
+		{Field: "items[0].quantity", Rule: "max_quantity", Message: "must be at most 5"},
+		{Field: "idempotency_key", Rule: "idempotency_key_format", Message: "must be 8 to 255 letters, digits or _.:-"},
+		{Field: "user_id", Rule: "blocked_user", Message: "may not order"},
+	}, problem.Errors)
+}
@@ -0,0 +1,49 @@
+package api
+
+import (
+	"net/http"
+
+	"order-service/internal/apperrors"
+	"order-service/internal/util"
+
+	"go.uber.org/zap"
+)
+
+// problemContentType is the RFC 7807 media type
+const problemContentType = "application/problem+json"
+
+// Problem is an RFC 7807 problem details document
+type Problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+	Code     string `json:"code"`
+	// Errors lists the request fields that break validation rules
+	Errors []apperrors.FieldError `json:"errors,omitempty"`
+}
+
+// writeProblem maps err to its domain error and writes a problem+json response
+func writeProblem(w http.ResponseWriter, r *http.Request, err error) {
+	appErr := apperrors.From(err)
+
+	detail := appErr.Detail
+	if appErr == apperrors.ErrInternal {
+		// Never leak internal error strings to clients
+		util.GetLogger().Error("Request failed",
+			zap.String("path", r.URL.Path),
+			zap.Error(err))
+		detail = ""
+	}
+
+	writeJSONAs(w, appErr.Status, problemContentType, Problem{
+		Type:     "/problems/" + appErr.Code,
+		Title:    appErr.Title,
+		Status:   appErr.Status,
+		Detail:   detail,
+		Instance: r.URL.Path,
+		Code:     appErr.Code,
+		Errors:   appErr.Fields,
+	})
+}
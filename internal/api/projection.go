@@ -0,0 +1,108 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"order-service/internal/apperrors"
+)
+
+// projection selects which fields of a resource a response carries
+// (?fields=id,status) and which related resources are expanded into it
+// (?expand=items,payment), so clients fetch no more than they show
+type projection struct {
+	// fields selected, nil for every field
+	fields map[string]bool
+	expand map[string]bool
+}
+
+// parseProjection reads ?fields= and ?expand= for a resource. Fields must be
+// JSON fields of resource; expansions must be expandable, and without
+// ?expand= the defaults are expanded.
+func parseProjection(r *http.Request, resource interface{}, expandable, defaults []string) (*projection, error) {
+	p := &projection{expand: make(map[string]bool)}
+
+	query := r.URL.Query()
+	if query.Has("fields") {
+		known := jsonFields(reflect.TypeOf(resource))
+		p.fields = make(map[string]bool)
+		for _, field := range splitList(query.Get("fields")) {
+			if !known[field] {
+				return nil, apperrors.New(apperrors.ErrInvalidRequest, "unknown field %q", field)
+			}
+			p.fields[field] = true
+		}
+	}
+
+	expand := defaults
+	if query.Has("expand") {
+		expand = splitList(query.Get("expand"))
+	}
+	for _, name := range expand {
+		if !contains(expandable, name) {
+			return nil, apperrors.New(apperrors.ErrInvalidRequest, "unknown expand %q, expected one of %s", name, strings.Join(expandable, ", "))
+		}
+		p.expand[name] = true
+	}
+	return p, nil
+}
+
+// expands reports whether the related resource name is expanded
+func (p *projection) expands(name string) bool {
+	return p.expand[name]
+}
+
+// apply renders resource as a JSON object of the selected fields, and of the
+// keep fields whatever is selected
+func (p *projection) apply(resource interface{}, keep ...string) (map[string]json.RawMessage, error) {
+	data, err := json.Marshal(resource)
+	if err != nil {
+		return nil, err
+	}
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal(data, &object); err != nil {
+		return nil, err
+	}
+
+	if p.fields != nil {
+		for field := range object {
+			if !p.fields[field] && !contains(keep, field) {
+				delete(object, field)
+			}
+		}
+	}
+	return object, nil
+}
+
+// jsonFields returns the names a struct type is encoded to JSON with
+func jsonFields(t reflect.Type) map[string]bool {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	fields := make(map[string]bool)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if !f.IsExported() || name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields[name] = true
+	}
+	return fields
+}
+
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,63 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"order-service/internal/apperrors"
+	"order-service/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testProjection parses the projection of an order request to target
+func testProjection(t *testing.T, target string, v apiVersion) *projection {
+	t.Helper()
+	p, err := parseProjection(httptest.NewRequest("GET", target, nil), v.order, orderExpandable, v.orderExpands)
+	require.NoError(t, err)
+	return p
+}
+
+func TestProjectionSelectsFieldsAndExpansions(t *testing.T) {
+	h := &Handler{cfg: HandlerConfig{Currency: "USD"}}
+	o := &orderResource{
+		order:   &models.Order{ID: 7, Status: models.OrderStatusPaid, TotalAmount: 3000, CreatedAt: time.Now()},
+		items:   []models.OrderItem{{ID: 1, ProductID: 10, Quantity: 2, UnitPrice: 1500}},
+		payment: &models.Payment{ID: 3, Status: models.PaymentStatusSuccess},
+	}
+	decode := func(resp interface{}) map[string]interface{} {
+		data, err := json.Marshal(resp)
+		require.NoError(t, err)
+		var got map[string]interface{}
+		require.NoError(t, json.Unmarshal(data, &got))
+		return got
+	}
+
+	resp, err := v1.serializeOrder(h, o, testProjection(t, "/api/v1/orders/7?fields=id,status,total_amount&expand=payment", v1))
+	require.NoError(t, err)
+	got := decode(resp)
+	assert.Equal(t, map[string]interface{}{"id": float64(7), "status": models.OrderStatusPaid, "total_amount": float64(3000)}, got["order"])
+	assert.NotContains(t, got, "items", "an explicit ?expand= replaces the default expansions")
+	assert.Equal(t, float64(3), got["payment"].(map[string]interface{})["id"])
+
+	resp, err = v2.serializeOrder(h, o, testProjection(t, "/api/v2/orders/7?fields=id,status&expand=", v2))
+	require.NoError(t, err)
+	got = decode(resp)
+	assert.ElementsMatch(t, []string{"id", "status", "_links"}, keys(got), "links are kept whatever fields are selected")
+
+	for _, target := range []string{"/api/v1/orders/7?fields=id,nope", "/api/v1/orders/7?expand=reviews"} {
+		_, err := parseProjection(httptest.NewRequest("GET", target, nil), v1.order, orderExpandable, v1.orderExpands)
+		assert.ErrorIs(t, err, apperrors.ErrInvalidRequest, target)
+	}
+}
+
+func keys(m map[string]interface{}) []string {
+	var names []string
+	for name := range m {
+		names = append(names, name)
+	}
+	return names
+}
@@ -0,0 +1,54 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"order-service/internal/apperrors"
+	"order-service/internal/reports"
+)
+
+// exportOrderReport streams the order report of the days from..to, both
+// inclusive and defaulting to the last 7 days, as csv, json or parquet
+func (h *Handler) exportOrderReport(w http.ResponseWriter, r *http.Request) {
+	if h.reports == nil {
+		writeProblem(w, r, apperrors.New(apperrors.ErrNotFound, "reports are disabled"))
+		return
+	}
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	to, err := queryDate(r, "to", today)
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+	from, err := queryDate(r, "from", to.AddDate(0, 0, -6))
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+	format := queryDefault(r, "format", reports.FormatCSV)
+
+	w.Header().Set("Content-Type", reports.ContentType(format))
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="orders_%s_%s.%s"`,
+		from.Format("2006-01-02"), to.Format("2006-01-02"), format))
+	if err := h.reports.WriteOrderReport(r.Context(), w, format, from, to.AddDate(0, 0, 1)); err != nil {
+		// Nothing was written unless encoding failed midway
+		w.Header().Del("Content-Disposition")
+		writeProblem(w, r, err)
+	}
+}
+
+// queryDate parses a YYYY-MM-DD query parameter, in UTC
+func queryDate(r *http.Request, name string, def time.Time) (time.Time, error) {
+	raw := queryDefault(r, name, "")
+	if raw == "" {
+		return def, nil
+	}
+	t, err := time.Parse("2006-01-02", raw)
+	if err != nil {
+		return time.Time{}, apperrors.New(apperrors.ErrInvalidRequest, "%s: %q is not a YYYY-MM-DD date", name, raw)
+	}
+	return t, nil
+}
@@ -0,0 +1,131 @@
+package api
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"order-service/internal/util"
+
+	"github.com/go-playground/validator/v10"
+	"go.uber.org/zap"
+)
+
+// H is a shortcut for JSON response objects
+type H map[string]interface{}
+
+// validate checks the binding tags of decoded request bodies
+var validate = func() *validator.Validate {
+	v := validator.New()
+	v.SetTagName("binding")
+	return v
+}()
+
+// writeJSON writes v as a JSON response
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	writeJSONAs(w, status, "application/json; charset=utf-8", v)
+}
+
+// writeJSONAs writes v as a JSON response with the given media type
+func writeJSONAs(w http.ResponseWriter, status int, contentType string, v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		util.GetLogger().Error("Failed to encode response", zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	writeData(w, status, contentType, data)
+}
+
+// writeData writes a raw response body
+func writeData(w http.ResponseWriter, status int, contentType string, data []byte) {
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(status)
+	_, _ = w.Write(data)
+}
+
+// decodeJSON decodes the request body into v and checks its binding tags
+func decodeJSON(r *http.Request, v interface{}) error {
+	if r.Body == nil {
+		return fmt.Errorf("invalid request")
+	}
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		return err
+	}
+	return validate.Struct(v)
+}
+
+// queryDefault returns a query parameter, or fallback when it is absent
+func queryDefault(r *http.Request, name, fallback string) string {
+	if values, ok := r.URL.Query()[name]; ok {
+		return values[0]
+	}
+	return fallback
+}
+
+// clientIP returns the originating client address, trusting forwarding headers
+// set by the proxies in front of the service
+func clientIP(r *http.Request) string {
+	if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+		first, _, _ := strings.Cut(forwarded, ",")
+		if ip := strings.TrimSpace(first); ip != "" {
+			return ip
+		}
+	}
+	if ip := strings.TrimSpace(r.Header.Get("X-Real-IP")); ip != "" {
+		return ip
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// statusRecorder remembers the status code written to a response
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(code int) {
+	if r.status == 0 {
+		r.status = code
+	}
+	r.ResponseWriter.WriteHeader(code)
+}
+
+func (r *statusRecorder) Write(data []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	return r.ResponseWriter.Write(data)
+}
+
+// Status returns the written status code, 200 if none was written explicitly
+func (r *statusRecorder) Status() int {
+	if r.status == 0 {
+		return http.StatusOK
+	}
+	return r.status
+}
+
+// Hijack lets WebSocket upgrades take over the connection
+func (r *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := r.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("response writer does not support hijacking")
+	}
+	if r.status == 0 {
+		r.status = http.StatusSwitchingProtocols
+	}
+	return hijacker.Hijack()
+}
+
+// Unwrap exposes the wrapped writer to http.ResponseController
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
@@ -0,0 +1,195 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"order-service/internal/rbac"
+	"order-service/internal/schema"
+	"order-service/internal/store"
+	"order-service/internal/util"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Route is one API endpoint, independent of the HTTP framework serving it.
+// Handlers read path parameters with http.Request.PathValue, so any router
+// that sets them (net/http.ServeMux, chi, or the gin adapter) can mount them.
+type Route struct {
+	Method string
+	// Pattern is the path with {name} placeholders, e.g. /api/v1/orders/{id}
+	Pattern string
+	Handler http.Handler
+}
+
+// middleware wraps a handler, e.g. to authenticate or validate the request
+type middleware func(http.Handler) http.Handler
+
+// Routes returns every endpoint of the service with its middleware applied
+func (h *Handler) Routes() []Route {
+	orderTarget := h.orderAuditTarget()
+	routes := []Route{
+		{http.MethodGet, "/health", http.HandlerFunc(h.healthCheck)},
+		{http.MethodGet, "/ready", http.HandlerFunc(h.readinessCheck)},
+
+		{http.MethodGet, "/metrics", promhttp.Handler()},
+		{http.MethodGet, "/metrics/scaling", http.HandlerFunc(h.scalingMetrics)},
+
+		{http.MethodGet, "/openapi.json", http.HandlerFunc(h.openAPIDocument)},
+		{http.MethodGet, "/docs", http.HandlerFunc(h.swaggerUI)},
+		{http.MethodGet, "/schemas", http.HandlerFunc(h.listSchemas)},
+		{http.MethodGet, "/schemas/{name}", http.HandlerFunc(h.getSchema)},
+
+		{http.MethodPost, "/api/v1/orders", chain(h.createOrder,
+			h.schemaValidation(schema.CreateOrderRequest),
+			h.idempotency("/api/v1/orders"))},
+		{http.MethodGet, "/api/v1/orders/search", chain(h.searchOrders, h.permit(rbac.Read, rbac.Orders))},
+		// A read, so it goes to the replica like the GET routes
+		{http.MethodPost, "/api/v1/orders/bulk-get", replicaReads(chain(h.bulkGetOrders, h.permit(rbac.Read, rbac.Orders)))},
+		{http.MethodGet, "/api/v1/orders/{id}", h.orderAuth(h.getOrder(v1))},
+		{http.MethodGet, "/api/v1/orders/{id}/history", chain(h.getOrderHistory, h.orderAuth)},
+		{http.MethodGet, "/api/v1/orders/{id}/reservations", chain(h.getOrderReservations, h.orderAuth)},
+		{http.MethodGet, "/api/v1/orders/{id}/invoice", chain(h.getOrderInvoice, h.orderAuth)},
+		{http.MethodGet, "/api/v1/orders/{id}/events", chain(h.trackOrder, h.orderAuth)},
+		{http.MethodPost, "/api/v1/orders/{id}/cancel", chain(h.cancelOrder, h.orderAuth)},
+		{http.MethodPatch, "/api/v1/orders/{id}/items", chain(h.amendOrder, h.orderAuth)},
+		{http.MethodGet, "/api/v1/pending-orders/{token}", http.HandlerFunc(h.getPendingOrder)},
+		{http.MethodGet, "/api/v1/queue/{token}", http.HandlerFunc(h.getQueueTicket)},
+		{http.MethodGet, "/api/v1/products/availability/stream", http.HandlerFunc(h.streamAvailability)},
+		{http.MethodGet, "/api/v1/products/{id}/variants", http.HandlerFunc(h.getProductVariants)},
+		{http.MethodGet, "/api/v1/variants/{id}/availability", http.HandlerFunc(h.getVariantAvailability)},
+		{http.MethodPost, "/api/v1/drops/{id}/registrations", chain(h.registerForDrop, h.orderAuth)},
+		{http.MethodGet, "/api/v1/drops/{id}/registrations/{user_id}", chain(h.getDropRegistration, h.orderAuth)},
+		{http.MethodPost, "/api/v1/carts", chain(h.createCart, h.orderAuth)},
+		{http.MethodGet, "/api/v1/carts/{id}", chain(h.getCart, h.orderAuth)},
+		{http.MethodPost, "/api/v1/carts/{id}/items", chain(h.addCartItem, h.orderAuth)},
+		{http.MethodPut, "/api/v1/carts/{id}/items/{variant_id}", chain(h.updateCartItem, h.orderAuth)},
+		{http.MethodDelete, "/api/v1/carts/{id}/items/{variant_id}", chain(h.removeCartItem, h.orderAuth)},
+		{http.MethodPost, "/api/v1/carts/{id}/checkout/start", chain(h.startCartCheckout, h.orderAuth)},
+		{http.MethodPost, "/api/v1/carts/{id}/checkout", chain(h.checkoutCart, h.orderAuth)},
+		{http.MethodPost, "/api/v1/events", http.HandlerFunc(h.ingestEvent)},
+		{http.MethodGet, "/ws/orders", http.HandlerFunc(h.orderSocket)},
+
+		{http.MethodGet, "/api/v2/orders/{id}", h.orderAuth(h.getOrder(v2))},
+		{http.MethodGet, "/graphql", chain(h.serveGraphQL, h.orderAuth)},
+		// The schema has no mutations, so POSTed queries go to the replica too
+		{http.MethodPost, "/graphql", replicaReads(chain(h.serveGraphQL, h.orderAuth))},
+
+		{http.MethodGet, "/api/v1/webhooks/subscriptions", chain(h.listWebhookSubscriptions, h.permit(rbac.Read, rbac.Webhooks))},
+		{http.MethodGet, "/api/v1/webhooks/subscriptions/{id}/deliveries", chain(h.listWebhookDeliveries, h.permit(rbac.Read, rbac.Webhooks))},
+		{http.MethodPost, "/api/v1/webhooks/subscriptions", chain(h.createWebhookSubscription, h.permit(rbac.Write, rbac.Webhooks),
+			h.audit("webhook_subscription.create", auditTarget{kind: "webhook_subscription"}))},
+		{http.MethodPost, "/api/v1/webhooks/subscriptions/{id}/enable", chain(h.enableWebhookSubscription, h.permit(rbac.Write, rbac.Webhooks),
+			h.audit("webhook_subscription.enable", auditTarget{kind: "webhook_subscription", params: []string{"id"}}))},
+		{http.MethodDelete, "/api/v1/webhooks/subscriptions/{id}", chain(h.deleteWebhookSubscription, h.permit(rbac.Write, rbac.Webhooks),
+			h.audit("webhook_subscription.delete", auditTarget{kind: "webhook_subscription", params: []string{"id"}}))},
+
+		{http.MethodGet, "/api/v1/admin/idempotency/top-offenders", chain(h.topIdempotencyReplayers, h.permit(rbac.Read, rbac.Operations))},
+		{http.MethodGet, "/api/v1/admin/orders", chain(h.listOrders, h.permit(rbac.Read, rbac.Orders))},
+		{http.MethodGet, "/api/v1/admin/orders/{id}", chain(h.getOrderAdminView, h.permit(rbac.Read, rbac.Orders))},
+		{http.MethodGet, "/api/v1/admin/reports/orders", replicaReads(chain(h.exportOrderReport, h.permit(rbac.Read, rbac.Reports)))},
+		{http.MethodGet, "/api/v1/admin/orders/{id}/amount-audit", chain(h.getOrderAmountAudit, h.permit(rbac.Read, rbac.Orders))},
+		{http.MethodGet, "/api/v1/admin/orders/{id}/diff", chain(h.getOrderDiff, h.permit(rbac.Read, rbac.Orders))},
+		{http.MethodGet, "/api/v1/admin/orders/{id}/notifications", chain(h.getOrderNotifications, h.permit(rbac.Read, rbac.Orders))},
+		{http.MethodGet, "/api/v1/admin/dlq", chain(h.listDeadLetters, h.permit(rbac.Read, rbac.Operations))},
+		{http.MethodGet, "/api/v1/admin/dlq/{id}/redrives", chain(h.getDeadLetterHistory, h.permit(rbac.Read, rbac.Operations))},
+		{http.MethodGet, "/api/v1/admin/kill-switches", chain(h.listKillSwitches, h.permit(rbac.Read, rbac.Operations))},
+		{http.MethodGet, "/api/v1/admin/workers", chain(h.listWorkers, h.permit(rbac.Read, rbac.Operations))},
+		{http.MethodGet, "/api/v1/admin/wallets/{user_id}", chain(h.getWallet, h.permit(rbac.Read, rbac.Refunds))},
+		{http.MethodGet, "/api/v1/admin/compensations", chain(h.listCompensations, h.permit(rbac.Read, rbac.Operations))},
+		{http.MethodGet, "/api/v1/admin/drops/{id}", chain(h.getDrop, h.permit(rbac.Read, rbac.Inventory))},
+		{http.MethodGet, "/api/v1/admin/incoming-stock", chain(h.listIncomingStock, h.permit(rbac.Read, rbac.Inventory))},
+		{http.MethodGet, "/api/v1/admin/warehouses", chain(h.listWarehouses, h.permit(rbac.Read, rbac.Inventory))},
+		{http.MethodGet, "/api/v1/admin/audit", chain(h.listAuditEntries, h.permit(rbac.Read, rbac.Audit))},
+		{http.MethodGet, "/api/v1/admin/inventory/low", chain(h.listLowStock, h.permit(rbac.Read, rbac.Inventory))},
+		{http.MethodPost, "/api/v1/admin/orders/{id}/transition", chain(h.forceOrderTransition, h.permit(rbac.Write, rbac.Orders),
+			h.audit("order.transition", orderTarget))},
+		{http.MethodPost, "/api/v1/admin/orders/{id}/payment/retry", chain(h.retriggerPayment, h.permit(rbac.Write, rbac.Orders),
+			h.audit("order.payment_retry", orderTarget))},
+		{http.MethodPost, "/api/v1/admin/orders/{id}/saga/replay", chain(h.replaySagaStep, h.permit(rbac.Write, rbac.Operations),
+			h.audit("order.saga_replay", orderTarget))},
+		{http.MethodPost, "/api/v1/admin/inventory/resync", chain(h.resyncInventory, h.permit(rbac.Write, rbac.Inventory),
+			h.audit("inventory.resync", auditTarget{kind: "inventory"}))},
+		{http.MethodPost, "/api/v1/admin/inventory/import", chain(h.importInventory, h.permit(rbac.Write, rbac.Inventory),
+			h.audit("inventory.import", auditTarget{kind: "inventory"}))},
+		{http.MethodPost, "/api/v1/admin/dlq/redrive", chain(h.redriveDeadLetters, h.permit(rbac.Write, rbac.Operations),
+			h.audit("dead_letter.redrive", auditTarget{kind: "dead_letter"}))},
+		{http.MethodPost, "/api/v1/admin/events/replay", chain(h.replayEvents, h.permit(rbac.Write, rbac.Operations),
+			h.audit("event.replay", auditTarget{kind: "event"}))},
+		{http.MethodPost, "/api/v1/admin/wallets/{user_id}/credit", chain(h.creditWallet, h.permit(rbac.Write, rbac.Refunds),
+			h.audit("wallet.credit", auditTarget{kind: "wallet", params: []string{"user_id"}}))},
+		{http.MethodPost, "/api/v1/admin/compensations/{id}/resolve", chain(h.resolveCompensation, h.permit(rbac.Write, rbac.Operations),
+			h.audit("compensation.resolve", auditTarget{kind: "compensation", params: []string{"id"}}))},
+		{http.MethodPost, "/api/v1/admin/compensations/{id}/retry", chain(h.retryCompensation, h.permit(rbac.Write, rbac.Operations),
+			h.audit("compensation.retry", auditTarget{kind: "compensation", params: []string{"id"}}))},
+		{http.MethodPost, "/api/v1/admin/drops", chain(h.createDrop, h.permit(rbac.Write, rbac.Inventory),
+			h.audit("drop.create", auditTarget{kind: "drop"}))},
+		{http.MethodPost, "/api/v1/admin/incoming-stock", chain(h.createIncomingStock, h.permit(rbac.Write, rbac.Inventory),
+			h.audit("incoming_stock.create", auditTarget{kind: "incoming_stock"}))},
+		{http.MethodPost, "/api/v1/admin/incoming-stock/{id}/receive", chain(h.receiveIncomingStock, h.permit(rbac.Write, rbac.Inventory),
+			h.audit("incoming_stock.receive", auditTarget{kind: "incoming_stock", params: []string{"id"}}))},
+		// applyPlan checks the permission of the plan's operation
+		{http.MethodPost, "/api/v1/admin/plans/{token}/apply", chain(h.applyPlan, h.adminAuth,
+			h.audit("plan.apply", auditTarget{kind: "plan", params: []string{"token"}}))},
+		{http.MethodPut, "/api/v1/admin/kill-switches/{kind}/{value}", chain(h.engageKillSwitch, h.permit(rbac.Write, rbac.Operations),
+			h.audit("kill_switch.engage", auditTarget{kind: "kill_switch", params: []string{"kind", "value"}}))},
+		{http.MethodPut, "/api/v1/admin/warehouses/{code}", chain(h.saveWarehouse, h.permit(rbac.Write, rbac.Inventory),
+			h.audit("warehouse.save", auditTarget{kind: "warehouse", params: []string{"code"}}))},
+		{http.MethodPut, "/api/v1/admin/products/{id}/reorder-threshold", chain(h.setReorderThreshold, h.permit(rbac.Write, rbac.Inventory),
+			h.audit("product.reorder_threshold", auditTarget{kind: "product", params: []string{"id"}}))},
+		{http.MethodPatch, "/api/v1/admin/incoming-stock/{id}", chain(h.updateIncomingStock, h.permit(rbac.Write, rbac.Inventory),
+			h.audit("incoming_stock.update", auditTarget{kind: "incoming_stock", params: []string{"id"}}))},
+		{http.MethodDelete, "/api/v1/admin/kill-switches/{kind}/{value}", chain(h.releaseKillSwitch, h.permit(rbac.Write, rbac.Operations),
+			h.audit("kill_switch.release", auditTarget{kind: "kill_switch", params: []string{"kind", "value"}}))},
+	}
+
+	for i := range routes {
+		if routes[i].Method == http.MethodGet {
+			routes[i].Handler = replicaReads(routes[i].Handler)
+		}
+		routes[i].Handler = h.tenancy(routes[i].Handler)
+		routes[i].Handler = requestID(routes[i].Handler)
+		routes[i].Handler = instrument(routes[i].Method, routes[i].Pattern, routes[i].Handler)
+	}
+	return routes
+}
+
+// chain applies middleware to handler, outermost first
+func chain(handler http.HandlerFunc, middlewares ...middleware) http.Handler {
+	var next http.Handler = handler
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		next = middlewares[i](next)
+	}
+	return next
+}
+
+// replicaReads lets the store serve a read-only request from a read replica
+func replicaReads(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(store.WithReplicaReads(r.Context())))
+	})
+}
+
+// instrument collects HTTP metrics for a route
+func instrument(method, pattern string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		recorder := &statusRecorder{ResponseWriter: w}
+
+		next.ServeHTTP(recorder, r)
+
+		duration := time.Since(start).Seconds()
+		status := strconv.Itoa(recorder.Status())
+
+		util.HTTPRequestDuration.WithLabelValues(method, pattern, status).Observe(duration)
+		util.HTTPRequestsTotal.WithLabelValues(method, pattern, status).Inc()
+	})
+}
+
+// Mount registers routes on a net/http ServeMux
+func Mount(mux *http.ServeMux, routes []Route) {
+	for _, route := range routes {
+		mux.Handle(route.Method+" "+route.Pattern, route.Handler)
+	}
+}
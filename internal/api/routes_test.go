@@ -0,0 +1,81 @@
+package api_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"order-service/internal/api"
+	"order-service/internal/api/chirouter"
+	"order-service/internal/api/ginrouter"
+	"order-service/internal/schema"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRoutesServeTheSameOnEveryRouter(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	schemas, err := schema.NewRegistry()
+	require.NoError(t, err)
+	routes := api.NewHandler(nil, nil, nil, nil, schemas, api.HandlerConfig{}).Routes()
+
+	mux := http.NewServeMux()
+	api.Mount(mux, routes)
+	routers := map[string]http.Handler{
+		"gin":      ginrouter.New(routes, false),
+		"chi":      chirouter.New(routes),
+		"net/http": mux,
+	}
+
+	cases := []struct {
+		method, path string
+		want         int
+	}{
+		{http.MethodGet, "/schemas/" + schema.CreateOrderRequest, http.StatusOK},
+		{http.MethodGet, "/schemas/unknown", http.StatusNotFound},
+		{http.MethodGet, "/api/v1/orders/abc", http.StatusBadRequest},
+		{http.MethodGet, "/api/v1/orders/search?q=TEE", http.StatusNotFound},
+		{http.MethodPost, "/api/v1/orders/bulk-get", http.StatusNotFound},
+		{http.MethodPost, "/graphql", http.StatusNotFound},
+		{http.MethodGet, "/api/v1/products/abc/variants", http.StatusBadRequest},
+		{http.MethodGet, "/api/v1/admin/dlq", http.StatusNotFound},
+		{http.MethodGet, "/api/v1/admin/orders/1/amount-audit", http.StatusNotFound},
+		{http.MethodGet, "/api/v1/admin/orders/1/diff", http.StatusNotFound},
+		{http.MethodGet, "/api/v1/admin/orders/1/notifications", http.StatusNotFound},
+		{http.MethodPost, "/api/v1/admin/events/replay", http.StatusNotFound},
+		{http.MethodGet, "/api/v1/admin/workers", http.StatusNotFound},
+		{http.MethodPost, "/api/v1/admin/wallets/7/credit", http.StatusNotFound},
+		{http.MethodPost, "/api/v1/admin/compensations/3/retry", http.StatusNotFound},
+		{http.MethodDelete, "/api/v1/admin/kill-switches/sku/TEE-XL", http.StatusNotFound},
+		{http.MethodPut, "/api/v1/admin/warehouses/east", http.StatusNotFound},
+		{http.MethodGet, "/api/v1/admin/inventory/low", http.StatusNotFound},
+		{http.MethodGet, "/api/v1/admin/reports/orders?format=csv", http.StatusNotFound},
+		{http.MethodGet, "/api/v1/drops/1/registrations/2", http.StatusNotFound},
+		{http.MethodGet, "/api/v1/variants/1/availability", http.StatusNotFound},
+		{http.MethodPatch, "/api/v1/admin/incoming-stock/1", http.StatusNotFound},
+		{http.MethodPost, "/api/v1/orders/1/cancel", http.StatusNotFound},
+		{http.MethodGet, "/api/v1/orders/1/invoice", http.StatusNotFound},
+		{http.MethodPatch, "/api/v1/orders/1/items", http.StatusNotFound},
+		{http.MethodGet, "/api/v1/pending-orders/3f0e6a1b", http.StatusNotFound},
+		{http.MethodGet, "/api/v1/queue/3f0e6a1b", http.StatusNotFound},
+		{http.MethodGet, "/api/v1/webhooks/subscriptions", http.StatusNotFound},
+		{http.MethodGet, "/api/v1/carts/0b7c3c52-0d4e-4d8c-9a55-3f0e6a1b2c3d", http.StatusNotFound},
+		{http.MethodPut, "/api/v1/carts/0b7c3c52-0d4e-4d8c-9a55-3f0e6a1b2c3d/items/abc", http.StatusNotFound},
+		{http.MethodPost, "/api/v1/carts/0b7c3c52-0d4e-4d8c-9a55-3f0e6a1b2c3d/checkout/start", http.StatusNotFound},
+		{http.MethodDelete, "/api/v1/webhooks/subscriptions/1", http.StatusNotFound},
+	}
+	for name, router := range routers {
+		for _, tc := range cases {
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(tc.method, tc.path, nil))
+			assert.Equal(t, tc.want, rec.Code, "%s %s %s", name, tc.method, tc.path)
+		}
+	}
+}
+
+func TestGinPatternUsesColonParams(t *testing.T) {
+	assert.Equal(t, "/api/v1/orders/:id/history", ginrouter.Pattern("/api/v1/orders/{id}/history"))
+	assert.Equal(t, "/health", ginrouter.Pattern("/health"))
+}
@@ -0,0 +1,52 @@
+package api
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+
+	"order-service/internal/apperrors"
+)
+
+// listSchemas lists the published JSON Schemas
+func (h *Handler) listSchemas(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, H{
+		"schemas": h.schemas.Names(),
+	})
+}
+
+// getSchema serves a single JSON Schema document
+func (h *Handler) getSchema(w http.ResponseWriter, r *http.Request) {
+	data, ok := h.schemas.Raw(r.PathValue("name"))
+	if !ok {
+		writeProblem(w, r, apperrors.New(apperrors.ErrNotFound, "schema %q not found", r.PathValue("name")))
+		return
+	}
+	writeData(w, http.StatusOK, "application/schema+json", data)
+}
+
+// schemaValidation rejects request bodies that do not match the named schema
+func (h *Handler) schemaValidation(name string) middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !h.cfg.ValidateRequests {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				writeProblem(w, r, apperrors.New(apperrors.ErrInvalidRequest, "failed to read request body"))
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			if err := h.schemas.Validate(name, body); err != nil {
+				writeProblem(w, r, apperrors.New(apperrors.ErrInvalidRequest, "%v", err))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package api
+
+import (
+	"crypto/tls"
+	"errors"
+	"net/http"
+	"time"
+
+	"golang.org/x/crypto/acme/autocert"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+// ServerOptions configures the HTTP server the API is served by
+type ServerOptions struct {
+	Addr string
+
+	// ReadHeaderTimeout bounds reading request headers and ReadTimeout the
+	// whole request; WriteTimeout bounds writing the response and IdleTimeout
+	// how long a keep-alive connection waits for its next request. Streams
+	// and uploads that outlive them clear their deadlines.
+	ReadHeaderTimeout time.Duration
+	ReadTimeout       time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	MaxHeaderBytes    int
+
+	// TLSCertFile and TLSKeyFile terminate TLS with a fixed certificate.
+	// Otherwise AutocertDomains obtains certificates from Let's Encrypt,
+	// cached in AutocertCacheDir; the server must then be reachable on 443
+	// for TLS-ALPN challenges. Without either the server speaks plain HTTP.
+	TLSCertFile      string
+	TLSKeyFile       string
+	AutocertDomains  []string
+	AutocertCacheDir string
+	AutocertEmail    string
+
+	// H2C serves HTTP/2 without TLS, for load balancers that speak it to
+	// their backends; over TLS HTTP/2 is always negotiated
+	H2C bool
+}
+
+// Server is the HTTP server of the API
+type Server struct {
+	*http.Server
+	certFile string
+	keyFile  string
+}
+
+// NewServer returns a server of handler with opts
+func NewServer(handler http.Handler, opts ServerOptions) (*Server, error) {
+	srv := &http.Server{
+		Addr:              opts.Addr,
+		Handler:           handler,
+		ReadHeaderTimeout: opts.ReadHeaderTimeout,
+		ReadTimeout:       opts.ReadTimeout,
+		WriteTimeout:      opts.WriteTimeout,
+		IdleTimeout:       opts.IdleTimeout,
+		MaxHeaderBytes:    opts.MaxHeaderBytes,
+	}
+
+	switch {
+	case opts.TLSCertFile != "" || opts.TLSKeyFile != "":
+		if opts.TLSCertFile == "" || opts.TLSKeyFile == "" {
+			return nil, errors.New("TLS needs both a certificate and a key file")
+		}
+		srv.TLSConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+	case len(opts.AutocertDomains) > 0:
+		manager := &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			HostPolicy: autocert.HostWhitelist(opts.AutocertDomains...),
+			Cache:      autocert.DirCache(opts.AutocertCacheDir),
+			Email:      opts.AutocertEmail,
+		}
+		srv.TLSConfig = manager.TLSConfig()
+		srv.TLSConfig.MinVersion = tls.VersionTLS12
+	case opts.H2C:
+		srv.Handler = h2c.NewHandler(handler, &http2.Server{IdleTimeout: opts.IdleTimeout})
+	}
+
+	return &Server{Server: srv, certFile: opts.TLSCertFile, keyFile: opts.TLSKeyFile}, nil
+}
+
+// TLS reports whether the server terminates TLS
+func (s *Server) TLS() bool {
+	return s.TLSConfig != nil
+}
+
+// ListenAndServe serves until the server is shut down, over TLS if configured
+func (s *Server) ListenAndServe() error {
+	if s.TLS() {
+		return s.Server.ListenAndServeTLS(s.certFile, s.keyFile)
+	}
+	return s.Server.ListenAndServe()
+}
+
+// clearDeadlines lifts the server's read and write timeouts from a request
+// that legitimately runs longer, like an event stream or a bulk upload
+func clearDeadlines(w http.ResponseWriter) {
+	rc := http.NewResponseController(w)
+	_ = rc.SetReadDeadline(time.Time{})
+	_ = rc.SetWriteDeadline(time.Time{})
+}
@@ -0,0 +1,85 @@
+package api
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/http2"
+)
+
+func TestNewServerSetsTimeouts(t *testing.T) {
+	srv, err := NewServer(http.NotFoundHandler(), ServerOptions{
+		Addr:              ":8080",
+		ReadHeaderTimeout: 5 * time.Second,
+		ReadTimeout:       30 * time.Second,
+		WriteTimeout:      time.Minute,
+		IdleTimeout:       2 * time.Minute,
+		MaxHeaderBytes:    1 << 20,
+	})
+	require.NoError(t, err)
+	assert.False(t, srv.TLS())
+	assert.Equal(t, 5*time.Second, srv.ReadHeaderTimeout)
+	assert.Equal(t, 30*time.Second, srv.ReadTimeout)
+	assert.Equal(t, time.Minute, srv.WriteTimeout)
+	assert.Equal(t, 2*time.Minute, srv.IdleTimeout)
+	assert.Equal(t, 1<<20, srv.MaxHeaderBytes)
+
+	srv, err = NewServer(http.NotFoundHandler(), ServerOptions{TLSCertFile: "server.crt", TLSKeyFile: "server.key"})
+	require.NoError(t, err)
+	assert.True(t, srv.TLS())
+	assert.Equal(t, uint16(tls.VersionTLS12), srv.TLSConfig.MinVersion)
+
+	_, err = NewServer(http.NotFoundHandler(), ServerOptions{TLSCertFile: "server.crt"})
+	assert.Error(t, err)
+}
+
+func TestServerSpeaksH2C(t *testing.T) {
+	srv, err := NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, r.Proto)
+	}), ServerOptions{H2C: true})
+	require.NoError(t, err)
+	ts := httptest.NewServer(srv.Handler)
+	t.Cleanup(ts.Close)
+
+	client := &http.Client{Transport: &http2.Transport{
+		AllowHTTP: true,
+		DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, network, addr)
+		},
+	}}
+	resp, err := client.Get(ts.URL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, "HTTP/2.0", string(body))
+}
+
+func TestClearDeadlinesOutlivesWriteTimeout(t *testing.T) {
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/stream" {
+			clearDeadlines(&statusRecorder{ResponseWriter: w})
+		}
+		time.Sleep(100 * time.Millisecond)
+		io.WriteString(w, "done")
+	}))
+	ts.Config.WriteTimeout = 20 * time.Millisecond
+	ts.Start()
+	t.Cleanup(ts.Close)
+
+	resp, err := ts.Client().Get(ts.URL + "/stream")
+	require.NoError(t, err)
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.Equal(t, "done", string(body))
+
+	_, err = ts.Client().Get(ts.URL + "/short")
+	assert.Error(t, err, "the write timeout still applies to other requests")
+}
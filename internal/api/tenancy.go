@@ -0,0 +1,104 @@
+package api
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"order-service/internal/apperrors"
+	"order-service/internal/rbac"
+	"order-service/internal/tenant"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ParseTenantAPIKeys parses comma-separated tenant:key pairs into a key to
+// tenant map
+func ParseTenantAPIKeys(raw string) (map[string]string, error) {
+	keys := make(map[string]string)
+	for _, pair := range strings.Split(raw, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		id, key, ok := strings.Cut(pair, ":")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("tenant API key entry %q is not tenant:key", pair)
+		}
+		if !tenant.Valid(id) {
+			return nil, fmt.Errorf("invalid tenant ID %q: use lowercase letters, digits, - and _", id)
+		}
+		keys[key] = id
+	}
+	return keys, nil
+}
+
+// tenancy makes a request act for the tenant its credentials name: the
+// tenant of its X-API-Key, else the tenant claim of its bearer JWT, else the
+// default tenant. A JWT that also names a user in its customer claim
+// authenticates the request as that customer. Credentials that do not check
+// out are refused rather than falling back, so a storefront never sees the
+// default tenant's data by mistake. Admin tokens are opaque, so bearer tokens
+// that are not JWTs are left to adminAuth.
+func (h *Handler) tenancy(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, customer, err := h.resolveTenant(r)
+		if err != nil {
+			writeProblem(w, r, err)
+			return
+		}
+		ctx := tenant.WithID(r.Context(), id)
+		if customer > 0 {
+			ctx = rbac.WithCustomer(ctx, customer)
+		}
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// resolveTenant returns the tenant r's credentials name, and the user ID of
+// the customer they authenticate, 0 if none
+func (h *Handler) resolveTenant(r *http.Request) (string, int64, error) {
+	if key := r.Header.Get("X-API-Key"); key != "" && len(h.cfg.TenantAPIKeys) > 0 {
+		id := ""
+		for candidate, candidateID := range h.cfg.TenantAPIKeys {
+			if subtle.ConstantTimeCompare([]byte(key), []byte(candidate)) == 1 {
+				id = candidateID
+			}
+		}
+		if id == "" {
+			return "", 0, apperrors.New(apperrors.ErrUnauthorized, "invalid API key")
+		}
+		return id, 0, nil
+	}
+
+	token, bearer := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if bearer && len(h.cfg.TenantJWTSecret) > 0 && strings.Count(token, ".") == 2 {
+		claims := jwt.MapClaims{}
+		_, err := jwt.ParseWithClaims(token, claims, func(*jwt.Token) (interface{}, error) {
+			return h.cfg.TenantJWTSecret, nil
+		}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithJSONNumber())
+		if err != nil {
+			return "", 0, apperrors.New(apperrors.ErrUnauthorized, "invalid bearer token: %v", err)
+		}
+		id, _ := claims[h.cfg.TenantJWTClaim].(string)
+		if !tenant.Valid(id) {
+			return "", 0, apperrors.New(apperrors.ErrUnauthorized, "bearer token names no valid tenant in its %q claim", h.cfg.TenantJWTClaim)
+		}
+
+		claim, ok := claims[h.cfg.CustomerJWTClaim]
+		if !ok || h.cfg.CustomerJWTClaim == "" {
+			return id, 0, nil
+		}
+		// Numeric claims decode as json.Number, subjects as strings
+		customer, err := strconv.ParseInt(fmt.Sprint(claim), 10, 64)
+		if err != nil || customer <= 0 {
+			return "", 0, apperrors.New(apperrors.ErrUnauthorized, "bearer token names no valid user ID in its %q claim", h.cfg.CustomerJWTClaim)
+		}
+		return id, customer, nil
+	}
+
+	return tenant.Default, 0, nil
+}
@@ -0,0 +1,138 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"order-service/internal/rbac"
+	"order-service/internal/tenant"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTenantAPIKeys(t *testing.T) {
+	keys, err := ParseTenantAPIKeys("acme:k1, globex:k2")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"k1": "acme", "k2": "globex"}, keys)
+
+	_, err = ParseTenantAPIKeys("Acme:k1")
+	assert.Error(t, err)
+	_, err = ParseTenantAPIKeys("acme")
+	assert.Error(t, err)
+}
+
+func TestTenancyResolvesCredentials(t *testing.T) {
+	secret := []byte("s3cret")
+	h := &Handler{cfg: HandlerConfig{
+		TenantAPIKeys:   map[string]string{"k1": "acme"},
+		TenantJWTSecret: secret,
+		TenantJWTClaim:  "tenant_id",
+	}}
+	router := h.tenancy(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, _ := tenant.ID(r.Context())
+		_, _ = w.Write([]byte(id))
+	}))
+
+	sign := func(claims jwt.MapClaims, key []byte) string {
+		token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(key)
+		require.NoError(t, err)
+		return token
+	}
+
+	cases := []struct {
+		name   string
+		header string
+		value  string
+		want   int
+		tenant string
+	}{
+		{"no credentials", "", "", http.StatusOK, tenant.Default},
+		{"api key", "X-API-Key", "k1", http.StatusOK, "acme"},
+		{"unknown api key", "X-API-Key", "nope", http.StatusUnauthorized, ""},
+		{"jwt", "Authorization", "Bearer " + sign(jwt.MapClaims{"tenant_id": "globex"}, secret), http.StatusOK, "globex"},
+		{"jwt with wrong key", "Authorization", "Bearer " + sign(jwt.MapClaims{"tenant_id": "globex"}, []byte("other")), http.StatusUnauthorized, ""},
+		{"jwt without claim", "Authorization", "Bearer " + sign(jwt.MapClaims{"sub": "u1"}, secret), http.StatusUnauthorized, ""},
+		{"admin token", "Authorization", "Bearer o1", http.StatusOK, tenant.Default},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/orders/1", nil)
+		if tc.header != "" {
+			req.Header.Set(tc.header, tc.value)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		assert.Equal(t, tc.want, rec.Code, tc.name)
+		if tc.want == http.StatusOK {
+			assert.Equal(t, tc.tenant, rec.Body.String(), tc.name)
+		}
+	}
+}
+
+func TestTenancyAuthenticatesCustomers(t *testing.T) {
+	secret := []byte("s3cret")
+	h := &Handler{cfg: HandlerConfig{TenantJWTSecret: secret, TenantJWTClaim: "tenant_id", CustomerJWTClaim: "sub"}}
+	router := h.tenancy(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		customer, _ := rbac.Customer(r.Context())
+		_, _ = fmt.Fprint(w, customer)
+	}))
+
+	cases := []struct {
+		name     string
+		claims   jwt.MapClaims
+		want     int
+		customer string
+	}{
+		{"numeric subject", jwt.MapClaims{"tenant_id": "acme", "sub": 42}, http.StatusOK, "42"},
+		{"string subject", jwt.MapClaims{"tenant_id": "acme", "sub": "42"}, http.StatusOK, "42"},
+		{"no subject", jwt.MapClaims{"tenant_id": "acme"}, http.StatusOK, "0"},
+		{"non-numeric subject", jwt.MapClaims{"tenant_id": "acme", "sub": "u1"}, http.StatusUnauthorized, ""},
+		{"negative subject", jwt.MapClaims{"tenant_id": "acme", "sub": -1}, http.StatusUnauthorized, ""},
+	}
+	for _, tc := range cases {
+		token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, tc.claims).SignedString(secret)
+		require.NoError(t, err)
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/orders/1", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		assert.Equal(t, tc.want, rec.Code, tc.name)
+		if tc.want == http.StatusOK {
+			assert.Equal(t, tc.customer, rec.Body.String(), tc.name)
+		}
+	}
+}
+
+func TestAdminAuthActsForNamedTenant(t *testing.T) {
+	h := &Handler{cfg: HandlerConfig{AdminTokens: map[string]string{"v1": rbac.RoleViewer}}}
+	router := h.tenancy(chain(func(w http.ResponseWriter, r *http.Request) {
+		id, _ := tenant.ID(r.Context())
+		_, _ = w.Write([]byte(id))
+	}, h.permit(rbac.Read, rbac.Orders)))
+
+	cases := []struct {
+		tenant string
+		want   int
+		body   string
+	}{
+		{"", http.StatusOK, ""},
+		{"acme", http.StatusOK, "acme"},
+		{"Acme!", http.StatusBadRequest, ""},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodGet, "/view", nil)
+		req.Header.Set("Authorization", "Bearer v1")
+		if tc.tenant != "" {
+			req.Header.Set("X-Tenant-ID", tc.tenant)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		assert.Equal(t, tc.want, rec.Code, "tenant %q", tc.tenant)
+		if tc.want == http.StatusOK {
+			assert.Equal(t, tc.body, rec.Body.String())
+		}
+	}
+}
@@ -0,0 +1,270 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"order-service/internal/apperrors"
+	"order-service/internal/models"
+	"order-service/internal/rbac"
+)
+
+// apiVersion is a major version of the REST API. Handlers load a resource
+// once and leave rendering it to the version's serializer, so a version can
+// redesign a resource without touching the handler logic or older versions.
+type apiVersion struct {
+	// order is the order resource as rendered, whose fields ?fields= selects
+	order interface{}
+	// orderExpands are expanded into orders without ?expand=
+	orderExpands []string
+	// serializeOrder renders an order resource as p projects it
+	serializeOrder func(h *Handler, o *orderResource, p *projection) (interface{}, error)
+}
+
+// orderExpandable are the related resources ?expand= embeds in an order
+var orderExpandable = []string{"items", "payment", "timeline"}
+
+// orderResource is what handlers load for an order, whatever the version
+type orderResource struct {
+	order    *models.Order
+	items    []models.OrderItem
+	timeline []models.OrderTimelineEntry
+	// payment is the latest payment, nil if none was made or it is not expanded
+	payment *models.Payment
+}
+
+// Paths every route of a version starts with
+const (
+	v1Prefix = "/api/v1"
+	v2Prefix = "/api/v2"
+)
+
+var (
+	// v1 is stable: its resources only ever gain fields
+	v1 = apiVersion{
+		order:          models.Order{},
+		orderExpands:   []string{"items"},
+		serializeOrder: serializeOrderV1,
+	}
+	// v2 shows money as {amount, currency}, timestamps as UTC ISO 8601, links
+	// to related resources (HAL) and embeds the order's payment
+	v2 = apiVersion{
+		order:          orderV2{},
+		orderExpands:   []string{"items", "payment"},
+		serializeOrder: serializeOrderV2,
+	}
+)
+
+func serializeOrderV1(_ *Handler, o *orderResource, p *projection) (interface{}, error) {
+	order, err := p.apply(o.order)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := H{"order": order}
+	if p.expands("items") {
+		resp["items"] = o.items
+	}
+	if p.expands("payment") {
+		resp["payment"] = o.payment
+	}
+	if p.expands("timeline") {
+		resp["timeline"] = o.timeline
+	}
+	return resp, nil
+}
+
+// moneyV2 is an amount in minor units of currency
+type moneyV2 struct {
+	Amount   int64  `json:"amount"`
+	Currency string `json:"currency"`
+}
+
+// halLink is a link to a related resource
+type halLink struct {
+	Href string `json:"href"`
+}
+
+type orderV2 struct {
+	ID            int64              `json:"id"`
+	UserID        int64              `json:"user_id"`
+	Status        string             `json:"status"`
+	Total         moneyV2            `json:"total"`
+	WalletCredit  moneyV2            `json:"wallet_credit"`
+	PaymentMethod string             `json:"payment_method"`
+	CreatedAt     string             `json:"created_at"`
+	UpdatedAt     string             `json:"updated_at"`
+	ExpiresAt     *string            `json:"expires_at,omitempty"`
+	ReservedAt    *string            `json:"reserved_at,omitempty"`
+	PaidAt        *string            `json:"paid_at,omitempty"`
+	ConfirmedAt   *string            `json:"confirmed_at,omitempty"`
+	Items         []orderItemV2      `json:"items,omitempty"`
+	Timeline      []timelineEntryV2  `json:"timeline,omitempty"`
+	Links         map[string]halLink `json:"_links"`
+	Embedded      *orderEmbeddedV2   `json:"_embedded,omitempty"`
+}
+
+type orderItemV2 struct {
+	ID                int64   `json:"id"`
+	ProductID         int64   `json:"product_id"`
+	VariantID         int64   `json:"variant_id"`
+	Quantity          int     `json:"quantity"`
+	UnitPrice         moneyV2 `json:"unit_price"`
+	Subtotal          moneyV2 `json:"subtotal"`
+	FulfillmentStatus string  `json:"fulfillment_status"`
+	WarehouseID       *int64  `json:"warehouse_id,omitempty"`
+	ExpectedShipDate  *string `json:"expected_ship_date,omitempty"`
+}
+
+type timelineEntryV2 struct {
+	EventID    string `json:"event_id"`
+	EventType  string `json:"event_type"`
+	Direction  string `json:"direction"`
+	Summary    string `json:"summary"`
+	OccurredAt string `json:"occurred_at"`
+}
+
+type orderEmbeddedV2 struct {
+	// Payment is null until the order's payment is requested
+	Payment *paymentV2 `json:"payment"`
+}
+
+type paymentV2 struct {
+	ID        int64   `json:"id"`
+	Status    string  `json:"status"`
+	Amount    moneyV2 `json:"amount"`
+	Refunded  moneyV2 `json:"refunded"`
+	Wallet    moneyV2 `json:"wallet"`
+	CreatedAt string  `json:"created_at"`
+	UpdatedAt string  `json:"updated_at"`
+}
+
+func serializeOrderV2(h *Handler, o *orderResource, p *projection) (interface{}, error) {
+	money := func(amount int64) moneyV2 {
+		return moneyV2{Amount: amount, Currency: h.cfg.Currency}
+	}
+
+	order := o.order
+	id := strconv.FormatInt(order.ID, 10)
+	resp := orderV2{
+		ID:            order.ID,
+		UserID:        order.UserID,
+		Status:        order.Status,
+		Total:         money(order.TotalAmount),
+		WalletCredit:  money(order.WalletAmount),
+		PaymentMethod: order.PaymentMethod,
+		CreatedAt:     iso8601(order.CreatedAt),
+		UpdatedAt:     iso8601(order.UpdatedAt),
+		ExpiresAt:     iso8601Ptr(order.ExpiresAt),
+		ReservedAt:    iso8601Ptr(order.ReservedAt),
+		PaidAt:        iso8601Ptr(order.PaidAt),
+		ConfirmedAt:   iso8601Ptr(order.ConfirmedAt),
+		Links: map[string]halLink{
+			"self":         {Href: v2Prefix + "/orders/" + id},
+			"history":      {Href: v1Prefix + "/orders/" + id + "/history"},
+			"reservations": {Href: v1Prefix + "/orders/" + id + "/reservations"},
+			"events":       {Href: v1Prefix + "/orders/" + id + "/events"},
+		},
+	}
+	switch order.Status {
+	case models.OrderStatusCreated, models.OrderStatusReserved, models.OrderStatusPaid,
+		models.OrderStatusOnHold, models.OrderStatusConfirmed:
+		// The customer may still cancel, unless the payment has settled
+		resp.Links["cancel"] = halLink{Href: v1Prefix + "/orders/" + id + "/cancel"}
+	}
+
+	for _, item := range o.items {
+		if !p.expands("items") {
+			break
+		}
+		resp.Items = append(resp.Items, orderItemV2{
+			ID:                item.ID,
+			ProductID:         item.ProductID,
+			VariantID:         item.VariantID,
+			Quantity:          item.Quantity,
+			UnitPrice:         money(item.UnitPrice),
+			Subtotal:          money(item.UnitPrice * int64(item.Quantity)),
+			FulfillmentStatus: item.FulfillmentStatus,
+			WarehouseID:       item.WarehouseID,
+			ExpectedShipDate:  iso8601Ptr(item.ExpectedShipDate),
+		})
+	}
+	for _, entry := range o.timeline {
+		resp.Timeline = append(resp.Timeline, timelineEntryV2{
+			EventID:    entry.EventID,
+			EventType:  entry.EventType,
+			Direction:  entry.Direction,
+			Summary:    entry.Summary,
+			OccurredAt: iso8601(entry.OccurredAt),
+		})
+	}
+	if p.expands("payment") {
+		resp.Embedded = &orderEmbeddedV2{}
+		if payment := o.payment; payment != nil {
+			resp.Embedded.Payment = &paymentV2{
+				ID:        payment.ID,
+				Status:    payment.Status,
+				Amount:    money(payment.Amount),
+				Refunded:  money(payment.RefundedAmount),
+				Wallet:    money(payment.WalletAmount),
+				CreatedAt: iso8601(payment.CreatedAt),
+				UpdatedAt: iso8601(payment.UpdatedAt),
+			}
+		}
+	}
+
+	// Links and expanded resources are kept whatever fields are selected
+	return p.apply(resp, "_links", "_embedded", "items", "timeline")
+}
+
+// iso8601 formats t in UTC to the second, e.g. 2024-01-15T10:30:00Z
+func iso8601(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+func iso8601Ptr(t *time.Time) *string {
+	if t == nil {
+		return nil
+	}
+	s := iso8601(*t)
+	return &s
+}
+
+// loadOrder loads the order a request is for and the related resources p expands
+func (h *Handler) loadOrder(r *http.Request, p *projection) (*orderResource, error) {
+	idStr := r.PathValue("id")
+	orderID, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		return nil, apperrors.New(apperrors.ErrInvalidRequest, "invalid order ID %q", idStr)
+	}
+	if err := authorizeOrders(r, rbac.Read); err != nil {
+		return nil, err
+	}
+
+	order, items, err := h.orderService.GetOrder(r.Context(), orderID)
+	if err != nil {
+		return nil, err
+	}
+	if err := ownsOrder(r, order); err != nil {
+		return nil, err
+	}
+	o := &orderResource{order: order, items: items}
+
+	if p.expands("timeline") {
+		if o.timeline, err = h.timeline.Get(r.Context(), orderID); err != nil {
+			return nil, err
+		}
+	}
+	if p.expands("payment") && h.payments != nil {
+		o.payment, err = h.payments.GetPayment(r.Context(), orderID)
+		if errors.Is(err, apperrors.ErrNotFound) {
+			o.payment, err = nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return o, nil
+}
@@ -0,0 +1,65 @@
+package api
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"order-service/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOrderV2ShowsMoneyTimestampsLinksAndPayment(t *testing.T) {
+	h := &Handler{cfg: HandlerConfig{Currency: "IDR"}}
+	created := time.Date(2024, 1, 15, 17, 30, 0, 123456789, time.FixedZone("WIB", 7*3600))
+	o := &orderResource{
+		order: &models.Order{ID: 7, Status: models.OrderStatusPaid, TotalAmount: 3000, CreatedAt: created, UpdatedAt: created},
+		items: []models.OrderItem{{ID: 1, ProductID: 10, Quantity: 2, UnitPrice: 1500}},
+		payment: &models.Payment{ID: 3, Status: models.PaymentStatusSuccess, Amount: 3000,
+			CreatedAt: created, UpdatedAt: created},
+	}
+
+	resp, err := v2.serializeOrder(h, o, testProjection(t, "/api/v2/orders/7", v2))
+	require.NoError(t, err)
+	data, err := json.Marshal(resp)
+	require.NoError(t, err)
+	var got map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &got))
+
+	assert.Equal(t, map[string]interface{}{"amount": float64(3000), "currency": "IDR"}, got["total"])
+	assert.Equal(t, "2024-01-15T10:30:00Z", got["created_at"])
+	item := got["items"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, float64(3000), item["subtotal"].(map[string]interface{})["amount"])
+
+	links := got["_links"].(map[string]interface{})
+	assert.Equal(t, map[string]interface{}{"href": "/api/v2/orders/7"}, links["self"])
+	assert.Equal(t, map[string]interface{}{"href": "/api/v1/orders/7/cancel"}, links["cancel"])
+	payment := got["_embedded"].(map[string]interface{})["payment"].(map[string]interface{})
+	assert.Equal(t, models.PaymentStatusSuccess, payment["status"])
+
+	// v1 is unchanged
+	resp, err = v1.serializeOrder(h, o, testProjection(t, "/api/v1/orders/7", v1))
+	require.NoError(t, err)
+	data, err = json.Marshal(resp)
+	require.NoError(t, err)
+	got = nil
+	require.NoError(t, json.Unmarshal(data, &got))
+	assert.Equal(t, float64(3000), got["order"].(map[string]interface{})["total_amount"])
+	assert.NotContains(t, got, "_links")
+	assert.NotContains(t, got, "payment", "v1 expands only items by default")
+}
+
+func TestOrderV1LeavesOutPersonalData(t *testing.T) {
+	email, name, taxID := "ada@example.com", "Ada Lovelace", "DE123456789"
+	o := &orderResource{order: &models.Order{ID: 7, CustomerEmail: &email, CustomerName: &name, TaxID: &taxID}}
+
+	resp, err := v1.serializeOrder(&Handler{}, o, testProjection(t, "/api/v1/orders/7", v1))
+	require.NoError(t, err)
+	data, err := json.Marshal(resp)
+	require.NoError(t, err)
+	for _, value := range []string{email, name, taxID} {
+		assert.NotContains(t, string(data), value)
+	}
+}
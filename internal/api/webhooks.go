@@ -0,0 +1,138 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"order-service/internal/apperrors"
+	"order-service/internal/models"
+	"order-service/internal/service"
+)
+
+// SetWebhooks enables the webhook subscription endpoints
+func (h *Handler) SetWebhooks(webhooks *service.WebhookService) {
+	h.webhooks = webhooks
+}
+
+// webhookSubscriptionID parses the {id} path parameter; ok is false once a
+// problem was written
+func (h *Handler) webhookSubscriptionID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	if !h.webhooksEnabled(w, r) {
+		return 0, false
+	}
+	idStr := r.PathValue("id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		writeProblem(w, r, apperrors.New(apperrors.ErrInvalidRequest, "invalid subscription ID %q", idStr))
+		return 0, false
+	}
+	return id, true
+}
+
+// webhooksEnabled writes a problem unless webhooks are enabled
+func (h *Handler) webhooksEnabled(w http.ResponseWriter, r *http.Request) bool {
+	if h.webhooks == nil {
+		writeProblem(w, r, apperrors.New(apperrors.ErrNotFound, "webhooks are disabled"))
+		return false
+	}
+	return true
+}
+
+// createWebhookSubscription registers a third-party endpoint for order event
+// types. The response carries the signing secret, which is not shown again.
+func (h *Handler) createWebhookSubscription(w http.ResponseWriter, r *http.Request) {
+	if !h.webhooksEnabled(w, r) {
+		return
+	}
+
+	var req service.WebhookSubscriptionRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeProblem(w, r, apperrors.New(apperrors.ErrInvalidRequest, "%v", err))
+		return
+	}
+
+	sub, err := h.webhooks.Subscribe(r.Context(), req, adminActor(r))
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, sub)
+}
+
+// listWebhookSubscriptions lists the subscriptions, optionally by status
+func (h *Handler) listWebhookSubscriptions(w http.ResponseWriter, r *http.Request) {
+	if !h.webhooksEnabled(w, r) {
+		return
+	}
+
+	status := queryDefault(r, "status", "")
+	switch status {
+	case "", models.WebhookActive, models.WebhookDisabled:
+	default:
+		writeProblem(w, r, apperrors.New(apperrors.ErrInvalidRequest, "unknown subscription status %q", status))
+		return
+	}
+
+	subs, err := h.webhooks.List(r.Context(), status)
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, H{"subscriptions": subs})
+}
+
+// listWebhookDeliveries lists the latest deliveries of a subscription with
+// their attempts, newest first
+func (h *Handler) listWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.webhookSubscriptionID(w, r)
+	if !ok {
+		return
+	}
+
+	limit, err := strconv.Atoi(queryDefault(r, "limit", "50"))
+	if err != nil || limit <= 0 || limit > 500 {
+		writeProblem(w, r, apperrors.New(apperrors.ErrInvalidRequest, "limit must be between 1 and 500"))
+		return
+	}
+
+	deliveries, err := h.webhooks.Deliveries(r.Context(), id, limit)
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, H{"subscription_id": id, "deliveries": deliveries})
+}
+
+// enableWebhookSubscription re-enables a subscription disabled after repeated
+// failures; its pending deliveries resume
+func (h *Handler) enableWebhookSubscription(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.webhookSubscriptionID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.webhooks.Enable(r.Context(), id, adminActor(r)); err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, H{"id": id, "status": models.WebhookActive})
+}
+
+// deleteWebhookSubscription removes a subscription with its deliveries
+func (h *Handler) deleteWebhookSubscription(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.webhookSubscriptionID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.webhooks.Delete(r.Context(), id, adminActor(r)); err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, H{"id": id, "deleted": true})
+}
@@ -0,0 +1,124 @@
+package apperrors
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Error is a domain error with a stable code clients can branch on
+type Error struct {
+	Code   string
+	Status int
+	Title  string
+	Detail string
+	Err    error
+	// Fields lists the request fields that break validation rules
+	Fields []FieldError
+}
+
+// FieldError is a validation rule a request field breaks
+type FieldError struct {
+	// Field is the JSON path of the field, e.g. items[1].quantity
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// Domain errors
+var (
+	ErrInvalidRequest        = newError("invalid_request", http.StatusBadRequest, "Invalid request")
+	ErrUnauthorized          = newError("unauthorized", http.StatusUnauthorized, "Unauthorized")
+	ErrForbidden             = newError("forbidden", http.StatusForbidden, "Forbidden")
+	ErrCustomerNotFound      = newError("customer_not_found", http.StatusUnprocessableEntity, "Customer not found")
+	ErrCustomerInactive      = newError("customer_inactive", http.StatusForbidden, "Customer inactive")
+	ErrProductNotFound       = newError("product_not_found", http.StatusUnprocessableEntity, "Product not found")
+	ErrMixedPricing          = newError("mixed_pricing", http.StatusUnprocessableEntity, "Mixed contract and retail pricing")
+	ErrInsufficientStock     = newError("insufficient_stock", http.StatusConflict, "Insufficient stock")
+	ErrStaleAvailability     = newError("refresh_availability", http.StatusConflict, "Availability is stale, refresh it")
+	ErrNotFound              = newError("not_found", http.StatusNotFound, "Not found")
+	ErrOrderNotFound         = newError("order_not_found", http.StatusNotFound, "Order not found")
+	ErrDuplicateOrder        = newError("duplicate_order", http.StatusConflict, "Duplicate order")
+	ErrInvalidOrderState     = newError("invalid_order_state", http.StatusConflict, "Invalid order state")
+	ErrOrderAmended          = newError("order_amended", http.StatusConflict, "Order amended")
+	ErrPaymentDeclined       = newError("payment_declined", http.StatusPaymentRequired, "Payment declined")
+	ErrInsufficientBalance   = newError("insufficient_balance", http.StatusConflict, "Insufficient wallet balance")
+	ErrSKUBlocked            = newError("sku_blocked", http.StatusUnprocessableEntity, "SKU blocked")
+	ErrPaymentMethodDisabled = newError("payment_method_disabled", http.StatusUnprocessableEntity, "Payment method disabled")
+	ErrRequestInProgress     = newError("request_in_progress", http.StatusConflict, "Request in progress")
+	ErrStalePlan             = newError("stale_plan", http.StatusConflict, "Stale plan")
+	ErrDropClosed            = newError("drop_closed", http.StatusConflict, "Drop closed")
+	ErrIncomingStockClosed   = newError("incoming_stock_closed", http.StatusConflict, "Incoming stock closed")
+	ErrCompensationClosed    = newError("compensation_closed", http.StatusConflict, "Compensation closed")
+	ErrCartClosed            = newError("cart_closed", http.StatusConflict, "Cart closed")
+	ErrIdempotencyMismatch   = newError("idempotency_key_reused", http.StatusUnprocessableEntity, "Idempotency key reused")
+	ErrPreconditionFailed    = newError("precondition_failed", http.StatusPreconditionFailed, "Precondition failed")
+	ErrRateLimited           = newError("rate_limited", http.StatusTooManyRequests, "Too many requests")
+	ErrUnavailable           = newError("service_unavailable", http.StatusServiceUnavailable, "Service unavailable")
+	ErrInternal              = newError("internal_error", http.StatusInternalServerError, "Internal server error")
+)
+
+func newError(code string, status int, title string) *Error {
+	return &Error{Code: code, Status: status, Title: title}
+}
+
+// Error implements the error interface
+func (e *Error) Error() string {
+	switch {
+	case e.Detail != "" && e.Err != nil:
+		return fmt.Sprintf("%s: %s: %v", e.Code, e.Detail, e.Err)
+	case e.Detail != "":
+		return fmt.Sprintf("%s: %s", e.Code, e.Detail)
+	case e.Err != nil:
+		return fmt.Sprintf("%s: %v", e.Code, e.Err)
+	}
+	return e.Code
+}
+
+// Unwrap returns the underlying cause
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is matches errors by code so wrapped copies still match the sentinel
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// New returns a copy of base with a human-readable detail
+func New(base *Error, format string, args ...interface{}) error {
+	e := *base
+	e.Detail = fmt.Sprintf(format, args...)
+	return &e
+}
+
+// Wrap returns a copy of base carrying cause as the underlying error
+func Wrap(base *Error, cause error, format string, args ...interface{}) error {
+	e := *base
+	e.Detail = fmt.Sprintf(format, args...)
+	e.Err = cause
+	return &e
+}
+
+// Invalid returns a copy of ErrInvalidRequest listing the fields that break
+// their rules
+func Invalid(fields []FieldError) error {
+	e := *ErrInvalidRequest
+	e.Fields = fields
+	if len(fields) == 1 {
+		e.Detail = fields[0].Field + ": " + fields[0].Message
+	} else {
+		e.Detail = fmt.Sprintf("%d fields are invalid", len(fields))
+	}
+	return &e
+}
+
+// From extracts the domain error from err, falling back to ErrInternal
+func From(err error) *Error {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr
+	}
+	return ErrInternal
+}
@@ -0,0 +1,34 @@
+package apperrors
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWrappedErrorsMatchSentinel(t *testing.T) {
+	err := fmt.Errorf("reserve failed: %w", New(ErrInsufficientStock, "product %d", 7))
+
+	assert.True(t, errors.Is(err, ErrInsufficientStock))
+	assert.False(t, errors.Is(err, ErrOrderNotFound))
+	assert.Equal(t, "product 7", From(err).Detail)
+	assert.Equal(t, http.StatusConflict, From(err).Status)
+}
+
+func TestInvalidListsFields(t *testing.T) {
+	err := Invalid([]FieldError{{Field: "items", Rule: "max_items", Message: "at most 50 items"}})
+	assert.True(t, errors.Is(err, ErrInvalidRequest))
+	assert.Equal(t, "items: at most 50 items", From(err).Detail)
+	assert.Empty(t, ErrInvalidRequest.Fields, "the sentinel is not modified")
+
+	err = Invalid([]FieldError{{Field: "items", Rule: "max_items"}, {Field: "currency", Rule: "currency"}})
+	assert.Equal(t, "2 fields are invalid", From(err).Detail)
+	assert.Len(t, From(err).Fields, 2)
+}
+
+func TestFromFallsBackToInternal(t *testing.T) {
+	assert.Equal(t, ErrInternal, From(errors.New("boom")))
+}
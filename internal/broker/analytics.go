@@ -0,0 +1,57 @@
+package broker
+
+import (
+	"regexp"
+
+	"order-service/internal/models"
+)
+
+// analyticsSchemaVersion is bumped whenever a field of models.AnalyticsEvent
+// changes meaning or is removed; added fields keep the version
+const analyticsSchemaVersion = 1
+
+// reasonCode matches decline reasons that are provider codes. Anything else
+// may quote the provider's message, which can name the cardholder.
+var reasonCode = regexp.MustCompile(`^[a-z0-9_]{1,64}$`)
+
+// AnalyticsEvent serializes an internal event for the analytics topic, or
+// returns nil for events analytics does not receive
+func AnalyticsEvent(event interface{}) *models.AnalyticsEvent {
+	switch e := event.(type) {
+	case *models.OrderCreatedEvent:
+		a := newAnalyticsEvent(e.BaseEvent, models.AnalyticsOrderCreated, e.OrderID)
+		a.Amount = e.TotalAmount
+		a.ItemCount = len(e.Items)
+		for _, item := range e.Items {
+			a.Units += item.Quantity
+		}
+		a.RiskBand = e.RiskBand
+		a.Synthetic = e.Synthetic
+		return a
+	case *models.PaymentSuccessEvent:
+		a := newAnalyticsEvent(e.BaseEvent, models.AnalyticsOrderValue, e.OrderID)
+		a.Amount = e.Amount
+		return a
+	case *models.PaymentFailedEvent:
+		a := newAnalyticsEvent(e.BaseEvent, models.AnalyticsDeclineReason, e.OrderID)
+		a.Reason = "other"
+		if reasonCode.MatchString(e.Reason) {
+			a.Reason = e.Reason
+		}
+		a.DeclineClass = e.DeclineClass
+		a.Attempts = e.Attempts
+		return a
+	default:
+		return nil
+	}
+}
+
+func newAnalyticsEvent(base models.BaseEvent, name string, orderID int64) *models.AnalyticsEvent {
+	return &models.AnalyticsEvent{
+		SchemaVersion: analyticsSchemaVersion,
+		EventID:       base.EventID,
+		Event:         name,
+		OccurredAt:    base.Timestamp.UTC(),
+		OrderID:       orderID,
+	}
+}
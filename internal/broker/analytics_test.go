@@ -0,0 +1,63 @@
+package broker
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"order-service/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnalyticsEventLeavesOutIdentifiers(t *testing.T) {
+	at := time.Date(2026, 10, 14, 12, 0, 0, 0, time.FixedZone("WIB", 7*3600))
+	event := AnalyticsEvent(&models.OrderCreatedEvent{
+		BaseEvent:   models.BaseEvent{EventID: "evt-1", EventType: models.EventTypeOrderCreated, Timestamp: at},
+		OrderID:     7,
+		UserID:      42,
+		TotalAmount: 5000,
+		Items:       []models.OrderItemData{{ProductID: 1, Quantity: 2}, {ProductID: 2, Quantity: 1}},
+		RiskBand:    "low",
+		Billing:     &models.BillingDetails{Company: "Acme", TaxID: "NPWP-1"},
+	})
+	require.NotNil(t, event)
+
+	payload, err := json.Marshal(event)
+	require.NoError(t, err)
+	var fields map[string]interface{}
+	require.NoError(t, json.Unmarshal(payload, &fields))
+	assert.Equal(t, map[string]interface{}{
+		"schema_version": float64(1),
+		"event_id":       "evt-1",
+		"event":          "order_created",
+		"occurred_at":    "2026-10-14T05:00:00Z",
+		"order_id":       float64(7),
+		"amount":         float64(5000),
+		"item_count":     float64(2),
+		"units":          float64(3),
+		"risk_band":      "low",
+	}, fields)
+}
+
+func TestAnalyticsEventScrubsDeclineReasons(t *testing.T) {
+	for reason, want := range map[string]string{
+		"insufficient_funds":              "insufficient_funds",
+		"Card 4111 declined for J. Smith": "other",
+		"":                                "other",
+	} {
+		event := AnalyticsEvent(&models.PaymentFailedEvent{
+			OrderID: 7, PaymentID: 3, Reason: reason, DeclineClass: models.DeclineClassHard, Attempts: 1,
+		})
+		require.NotNil(t, event)
+		assert.Equal(t, models.AnalyticsDeclineReason, event.Event)
+		assert.Equal(t, want, event.Reason, reason)
+	}
+}
+
+func TestAnalyticsEventSkipsOtherEvents(t *testing.T) {
+	assert.Nil(t, AnalyticsEvent(&models.OrderReservedEvent{OrderID: 7}))
+	assert.Equal(t, models.AnalyticsOrderValue,
+		AnalyticsEvent(&models.PaymentSuccessEvent{OrderID: 7, Amount: 5000, TxID: "TXN-1"}).Event)
+}
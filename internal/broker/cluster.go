@@ -0,0 +1,122 @@
+package broker
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/sasl"
+	"github.com/segmentio/kafka-go/sasl/plain"
+	"github.com/segmentio/kafka-go/sasl/scram"
+)
+
+// SASL mechanisms a Cluster can authenticate with
+const (
+	SASLPlain       = "plain"
+	SASLScramSHA256 = "scram-sha-256"
+	SASLScramSHA512 = "scram-sha-512"
+)
+
+// Security configures how connections to the brokers are encrypted and
+// authenticated. The zero value connects in plain text, as to a local broker.
+type Security struct {
+	// TLS encrypts connections, verifying brokers against TLSCAFile or, if
+	// empty, the system roots
+	TLS       bool
+	TLSCAFile string
+	// TLSCertFile and TLSKeyFile present a client certificate (mTLS)
+	TLSCertFile string
+	TLSKeyFile  string
+
+	// SASLMechanism is one of SASLPlain, SASLScramSHA256 and SASLScramSHA512;
+	// empty disables SASL
+	SASLMechanism string
+	SASLUsername  string
+	SASLPassword  string
+}
+
+// Cluster is the Kafka cluster the service talks to: its brokers and the
+// dialer and transport every producer, consumer and admin connection shares
+type Cluster struct {
+	brokers   []string
+	dialer    *kafka.Dialer
+	transport *kafka.Transport
+}
+
+// NewCluster returns the cluster of brokers connected to with sec
+func NewCluster(brokers []string, sec Security) (*Cluster, error) {
+	tlsConfig, err := sec.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+	mechanism, err := sec.saslMechanism()
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Cluster{
+		brokers: brokers,
+		dialer: &kafka.Dialer{
+			Timeout:       10 * time.Second,
+			DualStack:     true,
+			TLS:           tlsConfig,
+			SASLMechanism: mechanism,
+		},
+	}
+	// Writers without a transport share kafka.DefaultTransport, which
+	// connects in plain text
+	if tlsConfig != nil || mechanism != nil {
+		c.transport = &kafka.Transport{TLS: tlsConfig, SASL: mechanism}
+	}
+	return c, nil
+}
+
+// Brokers returns the bootstrap brokers of the cluster
+func (c *Cluster) Brokers() []string {
+	return c.brokers
+}
+
+func (s Security) tlsConfig() (*tls.Config, error) {
+	if !s.TLS {
+		return nil, nil
+	}
+
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+	if s.TLSCAFile != "" {
+		pem, err := os.ReadFile(s.TLSCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read Kafka CA file: %w", err)
+		}
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in Kafka CA file %s", s.TLSCAFile)
+		}
+	}
+	if s.TLSCertFile != "" || s.TLSKeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(s.TLSCertFile, s.TLSKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load Kafka client certificate: %w", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return config, nil
+}
+
+func (s Security) saslMechanism() (sasl.Mechanism, error) {
+	switch s.SASLMechanism {
+	case "":
+		return nil, nil
+	case SASLPlain:
+		return plain.Mechanism{Username: s.SASLUsername, Password: s.SASLPassword}, nil
+	case SASLScramSHA256:
+		return scram.Mechanism(scram.SHA256, s.SASLUsername, s.SASLPassword)
+	case SASLScramSHA512:
+		return scram.Mechanism(scram.SHA512, s.SASLUsername, s.SASLPassword)
+	default:
+		return nil, errors.New("unknown Kafka SASL mechanism " + s.SASLMechanism)
+	}
+}
@@ -0,0 +1,96 @@
+package broker
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func localCluster(t *testing.T) *Cluster {
+	t.Helper()
+	cluster, err := NewCluster([]string{"localhost:9092"}, Security{})
+	require.NoError(t, err)
+	return cluster
+}
+
+func TestPlainTextClusterUsesDefaultTransport(t *testing.T) {
+	cluster := localCluster(t)
+	assert.Nil(t, cluster.transport)
+	assert.Nil(t, cluster.dialer.TLS)
+	assert.Nil(t, cluster.dialer.SASLMechanism)
+	assert.Nil(t, NewProducer(cluster, "orders").writer.Transport)
+}
+
+func TestClusterSASL(t *testing.T) {
+	for mechanism, name := range map[string]string{
+		SASLPlain:       "PLAIN",
+		SASLScramSHA256: "SCRAM-SHA-256",
+		SASLScramSHA512: "SCRAM-SHA-512",
+	} {
+		cluster, err := NewCluster([]string{"b-1:9096"}, Security{
+			TLS: true, SASLMechanism: mechanism, SASLUsername: "svc", SASLPassword: "secret",
+		})
+		require.NoError(t, err, mechanism)
+		assert.Equal(t, name, cluster.dialer.SASLMechanism.Name())
+		require.NotNil(t, cluster.transport)
+		assert.Equal(t, name, cluster.transport.SASL.Name())
+		assert.Same(t, cluster.transport.TLS, cluster.dialer.TLS)
+	}
+
+	_, err := NewCluster([]string{"b-1:9096"}, Security{SASLMechanism: "gssapi"})
+	assert.Error(t, err)
+}
+
+func TestClusterMutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writeCertificate(t, dir)
+
+	cluster, err := NewCluster([]string{"b-1:9094"}, Security{
+		TLS: true, TLSCAFile: certFile, TLSCertFile: certFile, TLSKeyFile: keyFile,
+	})
+	require.NoError(t, err)
+	require.NotNil(t, cluster.dialer.TLS)
+	assert.NotNil(t, cluster.dialer.TLS.RootCAs)
+	assert.Len(t, cluster.dialer.TLS.Certificates, 1)
+
+	_, err = NewCluster([]string{"b-1:9094"}, Security{TLS: true, TLSCAFile: keyFile})
+	assert.ErrorContains(t, err, "no certificates found")
+	_, err = NewCluster([]string{"b-1:9094"}, Security{TLS: true, TLSCertFile: certFile})
+	assert.ErrorContains(t, err, "client certificate")
+}
+
+// writeCertificate writes a self-signed certificate and its key as PEM files
+func writeCertificate(t *testing.T, dir string) (certFile, keyFile string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "order-service"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	certFile = filepath.Join(dir, "client.crt")
+	keyFile = filepath.Join(dir, "client.key")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+	return certFile, keyFile
+}
@@ -0,0 +1,217 @@
+package broker
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+	"unicode"
+)
+
+// Field naming styles for event payloads
+const (
+	NamingSnakeCase = "snake_case"
+	NamingCamelCase = "camelCase"
+)
+
+// CloudEvents 1.0 structured-mode constants
+const (
+	cloudEventsSpecVersion = "1.0"
+	cloudEventsContentType = "application/cloudevents+json"
+)
+
+// Codec converts the service's internal snake_case events to their wire
+// format: CloudEvents 1.0 structured mode with configurable field naming, or
+// the legacy bare JSON payload during migration.
+type Codec struct {
+	legacy bool
+	naming string
+	source string
+}
+
+// NewCodec creates an event codec. With legacy set, events are written
+// unchanged and naming and source are ignored.
+func NewCodec(legacy bool, naming, source string) (*Codec, error) {
+	if naming != NamingSnakeCase && naming != NamingCamelCase {
+		return nil, fmt.Errorf("unknown event field naming %q", naming)
+	}
+	return &Codec{legacy: legacy, naming: naming, source: source}, nil
+}
+
+// ContentType returns the Kafka content-type header value for encoded events
+func (c *Codec) ContentType() string {
+	if c.legacy {
+		return "application/json"
+	}
+	return cloudEventsContentType
+}
+
+// Encode converts a snake_case event payload to the configured wire format
+func (c *Codec) Encode(payload []byte, subject string) ([]byte, error) {
+	if c.legacy {
+		return payload, nil
+	}
+
+	fields, err := unmarshalObject(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	envelope := map[string]interface{}{
+		"specversion":     cloudEventsSpecVersion,
+		"id":              fields["event_id"],
+		"type":            fields["event_type"],
+		"source":          c.source,
+		"time":            fields["timestamp"],
+		"subject":         subject,
+		"datacontenttype": "application/json",
+	}
+	delete(fields, "event_id")
+	delete(fields, "event_type")
+	delete(fields, "timestamp")
+
+	if c.naming == NamingCamelCase {
+		envelope["data"] = renameKeys(fields, snakeToCamel)
+	} else {
+		envelope["data"] = fields
+	}
+
+	return json.Marshal(envelope)
+}
+
+// Decode normalizes an incoming event, legacy or CloudEvents structured mode in
+// either field naming, to the internal snake_case payload handlers expect
+func Decode(payload []byte) ([]byte, error) {
+	fields, err := unmarshalObject(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := fields["specversion"]; ok {
+		data, _ := fields["data"].(map[string]interface{})
+		if data == nil {
+			data = make(map[string]interface{})
+		}
+		data["event_id"] = fields["id"]
+		data["event_type"] = fields["type"]
+		data["timestamp"] = fields["time"]
+		fields = data
+	}
+
+	return json.Marshal(renameKeys(fields, camelToSnake))
+}
+
+// CloudEvent holds the context attributes of an event received over HTTP
+type CloudEvent struct {
+	ID      string
+	Type    string
+	Source  string
+	Subject string
+}
+
+// DecodeHTTP reads a CloudEvent sent over HTTP in structured mode
+// (application/cloudevents+json body) or binary mode (ce-* headers with the
+// data as body) and returns its attributes and the payload normalized like Decode
+func DecodeHTTP(header http.Header, body []byte) (*CloudEvent, []byte, error) {
+	mediaType, _, _ := mime.ParseMediaType(header.Get("Content-Type"))
+
+	var envelope map[string]interface{}
+	if mediaType == cloudEventsContentType {
+		fields, err := unmarshalObject(body)
+		if err != nil {
+			return nil, nil, err
+		}
+		envelope = fields
+	} else {
+		data, err := unmarshalObject(body)
+		if err != nil {
+			return nil, nil, err
+		}
+		envelope = map[string]interface{}{"data": data}
+		for _, attr := range []string{"specversion", "id", "type", "source", "subject", "time"} {
+			if v := header.Get("ce-" + attr); v != "" {
+				envelope[attr] = v
+			}
+		}
+	}
+
+	attr := func(name string) string {
+		s, _ := envelope[name].(string)
+		return s
+	}
+	if v := attr("specversion"); v != cloudEventsSpecVersion {
+		return nil, nil, fmt.Errorf("unsupported CloudEvents specversion %q", v)
+	}
+	event := &CloudEvent{ID: attr("id"), Type: attr("type"), Source: attr("source"), Subject: attr("subject")}
+	if event.ID == "" || event.Type == "" || event.Source == "" {
+		return nil, nil, fmt.Errorf("CloudEvent requires id, type and source")
+	}
+
+	raw, err := json.Marshal(envelope)
+	if err != nil {
+		return nil, nil, err
+	}
+	payload, err := Decode(raw)
+	if err != nil {
+		return nil, nil, err
+	}
+	return event, payload, nil
+}
+
+// unmarshalObject decodes a JSON object keeping numbers exact, so int64 IDs survive re-encoding
+func unmarshalObject(payload []byte) (map[string]interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(payload))
+	dec.UseNumber()
+
+	var fields map[string]interface{}
+	if err := dec.Decode(&fields); err != nil {
+		return nil, fmt.Errorf("event is not a JSON object: %w", err)
+	}
+	return fields, nil
+}
+
+// renameKeys applies rename to every object key, recursively
+func renameKeys(v interface{}, rename func(string) string) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(val))
+		for k, child := range val {
+			out[rename(k)] = renameKeys(child, rename)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(val))
+		for i, child := range val {
+			out[i] = renameKeys(child, rename)
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+func snakeToCamel(s string) string {
+	parts := strings.Split(s, "_")
+	for i := 1; i < len(parts); i++ {
+		if parts[i] != "" {
+			parts[i] = strings.ToUpper(parts[i][:1]) + parts[i][1:]
+		}
+	}
+	return strings.Join(parts, "")
+}
+
+func camelToSnake(s string) string {
+	var b strings.Builder
+	for i, r := range s {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
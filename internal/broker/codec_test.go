@@ -0,0 +1,103 @@
+package broker
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const legacyEvent = `{"event_id":"e-1","event_type":"ORDER_CREATED","timestamp":"2024-01-01T00:00:00Z","order_id":7,"items":[{"product_id":1,"unit_price":100}]}`
+
+func TestCodecEncodesCloudEventsWithCamelCaseData(t *testing.T) {
+	codec, err := NewCodec(false, NamingCamelCase, "/order-service")
+	require.NoError(t, err)
+
+	out, err := codec.Encode([]byte(legacyEvent), "order-7")
+	require.NoError(t, err)
+
+	var envelope map[string]interface{}
+	require.NoError(t, json.Unmarshal(out, &envelope))
+	assert.Equal(t, "1.0", envelope["specversion"])
+	assert.Equal(t, "e-1", envelope["id"])
+	assert.Equal(t, "ORDER_CREATED", envelope["type"])
+	assert.Equal(t, "/order-service", envelope["source"])
+	assert.Equal(t, "order-7", envelope["subject"])
+
+	data := envelope["data"].(map[string]interface{})
+	assert.Equal(t, float64(7), data["orderId"])
+	assert.NotContains(t, data, "event_id")
+	item := data["items"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, float64(100), item["unitPrice"])
+}
+
+func TestCodecLegacyPassesThrough(t *testing.T) {
+	codec, err := NewCodec(true, NamingCamelCase, "/order-service")
+	require.NoError(t, err)
+
+	out, err := codec.Encode([]byte(legacyEvent), "order-7")
+	require.NoError(t, err)
+	assert.JSONEq(t, legacyEvent, string(out))
+}
+
+func TestDecodeRoundTripsEveryFormat(t *testing.T) {
+	for _, naming := range []string{NamingSnakeCase, NamingCamelCase} {
+		codec, err := NewCodec(false, naming, "/order-service")
+		require.NoError(t, err)
+
+		encoded, err := codec.Encode([]byte(legacyEvent), "order-7")
+		require.NoError(t, err)
+
+		decoded, err := Decode(encoded)
+		require.NoError(t, err)
+		assert.JSONEq(t, legacyEvent, string(decoded), naming)
+	}
+
+	decoded, err := Decode([]byte(legacyEvent))
+	require.NoError(t, err)
+	assert.JSONEq(t, legacyEvent, string(decoded))
+}
+
+func TestNewCodecRejectsUnknownNaming(t *testing.T) {
+	_, err := NewCodec(false, "kebab-case", "/order-service")
+	assert.Error(t, err)
+}
+
+func TestDecodeHTTPStructuredMode(t *testing.T) {
+	header := http.Header{"Content-Type": {"application/cloudevents+json; charset=utf-8"}}
+	body := `{"specversion":"1.0","id":"p-1","type":"PAYMENT_SUCCESS","source":"/psp","subject":"order-7","data":{"orderId":7,"txId":"T1"}}`
+
+	event, payload, err := DecodeHTTP(header, []byte(body))
+	require.NoError(t, err)
+	assert.Equal(t, &CloudEvent{ID: "p-1", Type: "PAYMENT_SUCCESS", Source: "/psp", Subject: "order-7"}, event)
+	assert.JSONEq(t, `{"event_id":"p-1","event_type":"PAYMENT_SUCCESS","timestamp":null,"order_id":7,"tx_id":"T1"}`, string(payload))
+}
+
+func TestDecodeHTTPBinaryMode(t *testing.T) {
+	header := http.Header{}
+	header.Set("Content-Type", "application/json")
+	header.Set("ce-specversion", "1.0")
+	header.Set("ce-id", "p-2")
+	header.Set("ce-type", "PAYMENT_FAILED")
+	header.Set("ce-source", "/psp")
+	header.Set("ce-time", "2024-01-01T00:00:00Z")
+
+	event, payload, err := DecodeHTTP(header, []byte(`{"order_id":7,"reason":"declined"}`))
+	require.NoError(t, err)
+	assert.Equal(t, "PAYMENT_FAILED", event.Type)
+	assert.JSONEq(t, `{"event_id":"p-2","event_type":"PAYMENT_FAILED","timestamp":"2024-01-01T00:00:00Z","order_id":7,"reason":"declined"}`, string(payload))
+}
+
+func TestDecodeHTTPRequiresAttributes(t *testing.T) {
+	header := http.Header{}
+	header.Set("ce-specversion", "1.0")
+	header.Set("ce-type", "PAYMENT_FAILED")
+
+	_, _, err := DecodeHTTP(header, []byte(`{"order_id":7}`))
+	assert.Error(t, err)
+
+	_, _, err = DecodeHTTP(http.Header{}, []byte(`{"order_id":7}`))
+	assert.Error(t, err)
+}
@@ -3,17 +3,34 @@ package broker
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"time"
 
 	"order-service/internal/models"
+	"order-service/internal/util"
 
+	"github.com/google/uuid"
 	"github.com/segmentio/kafka-go"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// ErrSchedulingDisabled is returned by PublishAt when no scheduler is set
+var ErrSchedulingDisabled = errors.New("event scheduling is not configured")
+
+// EventScheduler persists events to be published at a later time
+type EventScheduler interface {
+	ScheduleEvent(ctx context.Context, id string, at time.Time, key string, payload []byte) error
+}
+
 // EventPublisher handles publishing domain events
 type EventPublisher struct {
-	producer *Producer
+	producer   *Producer
+	scheduler  EventScheduler
+	retryDelay time.Duration
+	analytics  *Producer
+	express    *Producer
 }
 
 // NewEventPublisher creates a new event publisher
@@ -21,40 +38,209 @@ func NewEventPublisher(producer *Producer) *EventPublisher {
 	return &EventPublisher{producer: producer}
 }
 
+// SetScheduler enables PublishAt. With a positive retryDelay, events that fail
+// to publish are scheduled again retryDelay later instead of returning the error.
+func (ep *EventPublisher) SetScheduler(scheduler EventScheduler, retryDelay time.Duration) {
+	ep.scheduler = scheduler
+	ep.retryDelay = retryDelay
+}
+
+// SetAnalytics fans the events AnalyticsEvent knows out to producer's topic,
+// serialized for analytics, once they are published or scheduled for retry
+func (ep *EventPublisher) SetAnalytics(producer *Producer) {
+	ep.analytics = producer
+}
+
+// SetExpress copies the events of express orders to producer's topic, the
+// express lane the saga and payment workers consume apart from the backlog
+// of standard orders. The order topic keeps every event for its other
+// consumers.
+func (ep *EventPublisher) SetExpress(producer *Producer) {
+	ep.express = producer
+}
+
+// publishExpress copies an event of an express order to the express lane. It
+// returns ctx handling a standard order if the lane cannot take the event,
+// so the standard lane's workers handle it instead.
+func (ep *EventPublisher) publishExpress(ctx context.Context, key string, event interface{}) context.Context {
+	if ep.express == nil || Priority(ctx) != models.PriorityExpress {
+		return ctx
+	}
+	if err := ep.express.PublishEvent(ctx, key, event); err != nil {
+		util.ExpressLaneFallbacksTotal.Inc()
+		log.Printf("Failed to publish to the express lane, falling back to the standard lane: key=%s: %v", key, err)
+		return WithPriority(ctx, models.PriorityStandard)
+	}
+	return ctx
+}
+
+// PublishAt persists event to be published under key once at is reached. The
+// event ID identifies the scheduled event, so scheduling it again replaces it.
+func (ep *EventPublisher) PublishAt(ctx context.Context, at time.Time, key string, event interface{}) error {
+	if ep.scheduler == nil {
+		return ErrSchedulingDisabled
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+	var base models.BaseEvent
+	if err := json.Unmarshal(payload, &base); err != nil || base.EventID == "" {
+		base.EventID = uuid.New().String()
+	}
+
+	if err := ep.scheduler.ScheduleEvent(ctx, base.EventID, at, key, payload); err != nil {
+		return fmt.Errorf("failed to schedule event: %w", err)
+	}
+	util.EventsScheduledTotal.WithLabelValues(eventType(payload)).Inc()
+	return nil
+}
+
+// publish publishes an event now, scheduling a retry if Kafka cannot take it
+func (ep *EventPublisher) publish(ctx context.Context, key string, event interface{}) error {
+	ctx = ep.publishExpress(ctx, key, event)
+	err := ep.producer.PublishEvent(ctx, key, event)
+	if err == nil {
+		ep.publishAnalytics(ctx, key, event)
+	}
+	if err == nil || ep.scheduler == nil || ep.retryDelay <= 0 || errors.Is(err, ErrInvalidEvent) {
+		return err
+	}
+
+	if schedErr := ep.PublishAt(ctx, time.Now().Add(ep.retryDelay), key, event); schedErr != nil {
+		log.Printf("Failed to schedule event retry: key=%s: %v", key, schedErr)
+		return err
+	}
+	log.Printf("Publish failed, retrying in %s: key=%s: %v", ep.retryDelay, key, err)
+	ep.publishAnalytics(ctx, key, event)
+	return nil
+}
+
+// publishAnalytics publishes the analytics event of event, if any. Analytics
+// is best effort: a failure is counted and logged, never returned.
+func (ep *EventPublisher) publishAnalytics(ctx context.Context, key string, event interface{}) {
+	if ep.analytics == nil {
+		return
+	}
+	analytics := AnalyticsEvent(event)
+	if analytics == nil {
+		return
+	}
+	if err := ep.analytics.PublishEvent(ctx, key, analytics); err != nil {
+		util.AnalyticsEventsTotal.WithLabelValues(analytics.Event, "failed").Inc()
+		log.Printf("Failed to publish analytics event: key=%s, event=%s: %v", key, analytics.Event, err)
+		return
+	}
+	util.AnalyticsEventsTotal.WithLabelValues(analytics.Event, "published").Inc()
+}
+
+// SchedulePaymentReminder schedules a PaymentReminder event for at
+func (ep *EventPublisher) SchedulePaymentReminder(ctx context.Context, at time.Time, event *models.PaymentReminderEvent) error {
+	key := fmt.Sprintf("order-%d", event.OrderID)
+	return ep.PublishAt(ctx, at, key, event)
+}
+
+// ScheduleReservationExpiring schedules a ReservationExpiring event for at
+func (ep *EventPublisher) ScheduleReservationExpiring(ctx context.Context, at time.Time, event *models.ReservationExpiringEvent) error {
+	key := fmt.Sprintf("order-%d", event.OrderID)
+	return ep.PublishAt(ctx, at, key, event)
+}
+
+// PublishOrderBackordered publishes OrderBackordered event
+func (ep *EventPublisher) PublishOrderBackordered(ctx context.Context, event *models.OrderBackorderedEvent) error {
+	key := fmt.Sprintf("order-%d", event.OrderID)
+	return ep.publish(ctx, key, event)
+}
+
+// PublishBackorderRescheduled publishes BackorderRescheduled event
+func (ep *EventPublisher) PublishBackorderRescheduled(ctx context.Context, event *models.BackorderRescheduledEvent) error {
+	key := fmt.Sprintf("order-%d", event.OrderID)
+	return ep.publish(ctx, key, event)
+}
+
+// PublishBackorderFulfilled publishes BackorderFulfilled event
+func (ep *EventPublisher) PublishBackorderFulfilled(ctx context.Context, event *models.BackorderFulfilledEvent) error {
+	key := fmt.Sprintf("order-%d", event.OrderID)
+	return ep.publish(ctx, key, event)
+}
+
+// PublishOrderAmended publishes OrderAmended event
+func (ep *EventPublisher) PublishOrderAmended(ctx context.Context, event *models.OrderAmendedEvent) error {
+	key := fmt.Sprintf("order-%d", event.OrderID)
+	return ep.publish(ctx, key, event)
+}
+
+// PublishInventoryLow publishes InventoryLow event
+func (ep *EventPublisher) PublishInventoryLow(ctx context.Context, event *models.InventoryLowEvent) error {
+	key := fmt.Sprintf("variant-%d", event.VariantID)
+	return ep.publish(ctx, key, event)
+}
+
+// PublishOrderStatusChanged publishes OrderStatusChanged event
+func (ep *EventPublisher) PublishOrderStatusChanged(ctx context.Context, event *models.OrderStatusChangedEvent) error {
+	key := fmt.Sprintf("order-%d", event.OrderID)
+	return ep.publish(ctx, key, event)
+}
+
 // PublishOrderCreated publishes OrderCreated event
 func (ep *EventPublisher) PublishOrderCreated(ctx context.Context, event *models.OrderCreatedEvent) error {
 	key := fmt.Sprintf("order-%d", event.OrderID)
-	return ep.producer.PublishEvent(ctx, key, event)
+	return ep.publish(ctx, key, event)
 }
 
 // PublishOrderReserved publishes OrderReserved event
 func (ep *EventPublisher) PublishOrderReserved(ctx context.Context, event *models.OrderReservedEvent) error {
 	key := fmt.Sprintf("order-%d", event.OrderID)
-	return ep.producer.PublishEvent(ctx, key, event)
+	return ep.publish(ctx, key, event)
 }
 
 // PublishOrderPaid publishes OrderPaid event
 func (ep *EventPublisher) PublishOrderPaid(ctx context.Context, event *models.OrderPaidEvent) error {
 	key := fmt.Sprintf("order-%d", event.OrderID)
-	return ep.producer.PublishEvent(ctx, key, event)
+	return ep.publish(ctx, key, event)
 }
 
 // PublishOrderCancelled publishes OrderCancelled event
 func (ep *EventPublisher) PublishOrderCancelled(ctx context.Context, event *models.OrderCancelledEvent) error {
 	key := fmt.Sprintf("order-%d", event.OrderID)
-	return ep.producer.PublishEvent(ctx, key, event)
+	return ep.publish(ctx, key, event)
 }
 
 // PublishPaymentSuccess publishes PaymentSuccess event
 func (ep *EventPublisher) PublishPaymentSuccess(ctx context.Context, event *models.PaymentSuccessEvent) error {
 	key := fmt.Sprintf("order-%d", event.OrderID)
-	return ep.producer.PublishEvent(ctx, key, event)
+	return ep.publish(ctx, key, event)
 }
 
 // PublishPaymentFailed publishes PaymentFailed event
 func (ep *EventPublisher) PublishPaymentFailed(ctx context.Context, event *models.PaymentFailedEvent) error {
 	key := fmt.Sprintf("order-%d", event.OrderID)
-	return ep.producer.PublishEvent(ctx, key, event)
+	return ep.publish(ctx, key, event)
+}
+
+// PublishPaymentVoided publishes PaymentVoided event
+func (ep *EventPublisher) PublishPaymentVoided(ctx context.Context, event *models.PaymentVoidedEvent) error {
+	key := fmt.Sprintf("order-%d", event.OrderID)
+	return ep.publish(ctx, key, event)
+}
+
+// PublishPaymentRefunded publishes PaymentRefunded event
+func (ep *EventPublisher) PublishPaymentRefunded(ctx context.Context, event *models.PaymentRefundedEvent) error {
+	key := fmt.Sprintf("order-%d", event.OrderID)
+	return ep.publish(ctx, key, event)
+}
+
+// PublishOrderOnHold publishes OrderOnHold event
+func (ep *EventPublisher) PublishOrderOnHold(ctx context.Context, event *models.OrderOnHoldEvent) error {
+	key := fmt.Sprintf("order-%d", event.OrderID)
+	return ep.publish(ctx, key, event)
+}
+
+// PublishUserAnonymization publishes a UserAnonymizationProgress or Completed event
+func (ep *EventPublisher) PublishUserAnonymization(ctx context.Context, event *models.UserAnonymizationEvent) error {
+	key := fmt.Sprintf("user-%d", event.UserID)
+	return ep.publish(ctx, key, event)
 }
 
 // EventHandler handles incoming events
@@ -87,6 +273,14 @@ func (eh *EventHandler) HandleMessage(ctx context.Context, msg kafka.Message) er
 
 	log.Printf("Handling event: type=%s, id=%s", baseEvent.EventType, baseEvent.EventID)
 
+	// Messages handed over outside a Consumer, e.g. over HTTP, carry their
+	// trace context in the message headers only
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		ctx = tracePropagator.Extract(ctx, headerCarrier{headers: &msg.Headers})
+	}
+	ctx, span := util.StartSpan(ctx, "EventHandler."+baseEvent.EventType)
+	defer span.End()
+
 	switch baseEvent.EventType {
 	case models.EventTypePaymentSuccess:
 		if eh.onPaymentSuccess != nil {
@@ -0,0 +1,48 @@
+package broker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"order-service/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type scheduledCall struct {
+	id, key string
+	at      time.Time
+	payload []byte
+}
+
+type fakeScheduler struct {
+	calls []scheduledCall
+}
+
+func (f *fakeScheduler) ScheduleEvent(_ context.Context, id string, at time.Time, key string, payload []byte) error {
+	f.calls = append(f.calls, scheduledCall{id: id, key: key, at: at, payload: payload})
+	return nil
+}
+
+func TestPublishAtSchedulesUnderEventID(t *testing.T) {
+	ep := NewEventPublisher(nil)
+	event := &models.PaymentReminderEvent{
+		BaseEvent: models.BaseEvent{EventID: "evt-1", EventType: models.EventTypePaymentReminder},
+		OrderID:   7,
+	}
+	at := time.Now().Add(time.Minute)
+
+	assert.ErrorIs(t, ep.SchedulePaymentReminder(context.Background(), at, event), ErrSchedulingDisabled)
+
+	scheduler := &fakeScheduler{}
+	ep.SetScheduler(scheduler, 0)
+	require.NoError(t, ep.SchedulePaymentReminder(context.Background(), at, event))
+
+	require.Len(t, scheduler.calls, 1)
+	assert.Equal(t, "evt-1", scheduler.calls[0].id)
+	assert.Equal(t, "order-7", scheduler.calls[0].key)
+	assert.True(t, at.Equal(scheduler.calls[0].at))
+	assert.Contains(t, string(scheduler.calls[0].payload), `"event_type":"PAYMENT_REMINDER"`)
+}
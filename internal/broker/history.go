@@ -0,0 +1,127 @@
+package broker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// HistoryRange selects the messages of a topic to read again. Offsets apply
+// to each partition read; a negative offset and a zero time are unbounded.
+type HistoryRange struct {
+	// Partition is the partition read, -1 for every partition
+	Partition  int
+	FromOffset int64
+	ToOffset   int64
+	Since      time.Time
+	Until      time.Time
+}
+
+// ReadHistory reads the messages of topic in rng, partition by partition and
+// oldest first, up to the end each partition had when reading started, and
+// calls fn with each until it returns false or an error. Messages are passed
+// as stored, without decoding. No consumer group is joined or committed.
+func ReadHistory(ctx context.Context, cluster *Cluster, topic string, rng HistoryRange, fn func(kafka.Message) (bool, error)) error {
+	if len(cluster.brokers) == 0 {
+		return errors.New("no Kafka brokers configured")
+	}
+
+	conn, err := cluster.dialer.DialContext(ctx, "tcp", cluster.brokers[0])
+	if err != nil {
+		return fmt.Errorf("failed to dial Kafka: %w", err)
+	}
+	partitions, err := conn.ReadPartitions(topic)
+	conn.Close()
+	if err != nil {
+		return fmt.Errorf("failed to read partitions of %s: %w", topic, err)
+	}
+
+	for _, partition := range partitions {
+		if rng.Partition >= 0 && partition.ID != rng.Partition {
+			continue
+		}
+		more, err := readPartitionHistory(ctx, cluster, topic, partition.ID, rng, fn)
+		if err != nil {
+			return fmt.Errorf("partition %d: %w", partition.ID, err)
+		}
+		if !more {
+			return nil
+		}
+	}
+	return nil
+}
+
+// readPartitionHistory reads the messages of one partition in rng. Returns
+// false once fn stopped the read.
+func readPartitionHistory(ctx context.Context, cluster *Cluster, topic string, partition int, rng HistoryRange, fn func(kafka.Message) (bool, error)) (bool, error) {
+	start, end, err := historyBounds(ctx, cluster, topic, partition, rng)
+	if err != nil || start >= end {
+		return true, err
+	}
+
+	reader := kafka.NewReader(kafka.ReaderConfig{
+		Brokers:   cluster.brokers,
+		Dialer:    cluster.dialer,
+		Topic:     topic,
+		Partition: partition,
+		MinBytes:  1,
+		MaxBytes:  10e6,
+	})
+	defer reader.Close()
+	if err := reader.SetOffset(start); err != nil {
+		return true, err
+	}
+
+	for {
+		msg, err := reader.FetchMessage(ctx)
+		if err != nil {
+			return true, err
+		}
+		if !rng.Until.IsZero() && msg.Time.After(rng.Until) {
+			return true, nil
+		}
+		more, err := fn(msg)
+		if err != nil || !more {
+			return more, err
+		}
+		if msg.Offset+1 >= end {
+			return true, nil
+		}
+	}
+}
+
+// historyBounds returns the offsets of a partition to read from, inclusive,
+// and to, exclusive
+func historyBounds(ctx context.Context, cluster *Cluster, topic string, partition int, rng HistoryRange) (int64, int64, error) {
+	conn, err := cluster.dialer.DialLeader(ctx, "tcp", cluster.brokers[0], topic, partition)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to dial partition leader: %w", err)
+	}
+	defer conn.Close()
+
+	start, end, err := conn.ReadOffsets()
+	if err != nil {
+		return 0, 0, err
+	}
+	if !rng.Since.IsZero() {
+		since, err := conn.ReadOffset(rng.Since)
+		if err != nil {
+			return 0, 0, err
+		}
+		// -1 when no message is as recent as Since
+		if since < 0 {
+			since = end
+		}
+		start = max(start, since)
+	}
+	if rng.FromOffset >= 0 {
+		start = max(start, rng.FromOffset)
+	}
+	if rng.ToOffset >= 0 {
+		end = min(end, rng.ToOffset+1)
+	}
+	return start, end, nil
+}
@@ -3,25 +3,73 @@ package broker
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
+	"net"
+	"strconv"
 	"time"
 
+	"order-service/internal/resilience/retry"
+	"order-service/internal/util"
+
 	"github.com/segmentio/kafka-go"
 )
 
+// publishRetryPolicy retries transient broker errors when publishing events
+var publishRetryPolicy = retry.Policy{
+	MaxAttempts: 3,
+	BaseDelay:   100 * time.Millisecond,
+	MaxDelay:    time.Second,
+	Jitter:      0.2,
+	Retryable:   isTransientKafkaError,
+	OnRetry: func(attempt int, err error) {
+		log.Printf("Retrying event publish (attempt %d): %v", attempt, err)
+	},
+}
+
+// isTransientKafkaError reports whether a write failed for a reason worth retrying
+func isTransientKafkaError(err error) bool {
+	var writeErrs kafka.WriteErrors
+	if errors.As(err, &writeErrs) {
+		for _, e := range writeErrs {
+			if e != nil && !isTransientKafkaError(e) {
+				return false
+			}
+		}
+		return true
+	}
+
+	var kafkaErr kafka.Error
+	if errors.As(err, &kafkaErr) {
+		return kafkaErr.Temporary()
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// ErrInvalidEvent is returned for events that violate their schema; publishing
+// them again cannot succeed
+var ErrInvalidEvent = errors.New("refusing to publish invalid event")
+
 type Producer struct {
-	writer *kafka.Writer
+	writer   *kafka.Writer
+	validate func(payload []byte) error
+	codec    *Codec
+	observe  EventObserver
 }
 
 // NewProducer creates a new Kafka producer
-func NewProducer(brokers []string, topic string) *Producer {
+func NewProducer(cluster *Cluster, topic string) *Producer {
 	writer := &kafka.Writer{
-		Addr:         kafka.TCP(brokers...),
+		Addr:         kafka.TCP(cluster.brokers...),
+		Transport:    cluster.transport,
 		Topic:        topic,
-		Balancer:     &kafka.LeastBytes{},
+		Balancer:     &ConsistentHashBalancer{},
 		RequiredAcks: kafka.RequireAll,
-		MaxAttempts:  3,
+		MaxAttempts:  1, // retried by PublishEvent with jittered backoff
 		WriteTimeout: 10 * time.Second,
 		ReadTimeout:  10 * time.Second,
 	}
@@ -29,6 +77,21 @@ func NewProducer(brokers []string, topic string) *Producer {
 	return &Producer{writer: writer}
 }
 
+// SetValidator installs a check run on every serialized event before it is written
+func (p *Producer) SetValidator(validate func(payload []byte) error) {
+	p.validate = validate
+}
+
+// SetCodec sets the wire format of published events; without one events are written as legacy JSON
+func (p *Producer) SetCodec(codec *Codec) {
+	p.codec = codec
+}
+
+// SetObserver sets a function told about every event once it is published
+func (p *Producer) SetObserver(observe EventObserver) {
+	p.observe = observe
+}
+
 // PublishEvent publishes an event to Kafka
 func (p *Producer) PublishEvent(ctx context.Context, key string, event interface{}) error {
 	eventBytes, err := json.Marshal(event)
@@ -36,16 +99,44 @@ func (p *Producer) PublishEvent(ctx context.Context, key string, event interface
 		return fmt.Errorf("failed to marshal event: %w", err)
 	}
 
+	if p.validate != nil {
+		if err := p.validate(eventBytes); err != nil {
+			util.EventSchemaViolationsTotal.Inc()
+			return fmt.Errorf("%w: %v", ErrInvalidEvent, err)
+		}
+	}
+
 	msg := kafka.Message{
 		Key:   []byte(key),
 		Value: eventBytes,
 		Time:  time.Now(),
 	}
 
-	err = p.writer.WriteMessages(ctx, msg)
+	if p.codec != nil {
+		msg.Value, err = p.codec.Encode(eventBytes, key)
+		if err != nil {
+			return fmt.Errorf("failed to encode event: %w", err)
+		}
+		msg.Headers = []kafka.Header{{Key: "content-type", Value: []byte(p.codec.ContentType())}}
+	}
+	setTenantHeader(ctx, &msg)
+	setPriorityHeader(ctx, &msg)
+
+	ctx, span := startPublishSpan(ctx, &msg, p.writer.Topic)
+	defer span.End()
+
+	start := time.Now()
+	err = retry.Do(ctx, publishRetryPolicy, func(ctx context.Context) error {
+		return p.writer.WriteMessages(ctx, msg)
+	})
+	util.KafkaPublishDuration.WithLabelValues(p.writer.Topic, resultLabel(err)).Observe(time.Since(start).Seconds())
 	if err != nil {
+		span.RecordError(err)
 		return fmt.Errorf("failed to write message to kafka: %w", err)
 	}
+	if p.observe != nil {
+		p.observe(ctx, eventBytes)
+	}
 
 	log.Printf("Published event: key=%s, type=%T", key, event)
 	return nil
@@ -56,24 +147,68 @@ func (p *Producer) Close() error {
 	return p.writer.Close()
 }
 
+// Ping checks that at least one broker accepts connections and returns metadata
+func Ping(ctx context.Context, cluster *Cluster) error {
+	var lastErr error
+	for _, addr := range cluster.brokers {
+		conn, err := cluster.dialer.DialContext(ctx, "tcp", addr)
+		if err != nil {
+			util.KafkaBrokerUp.WithLabelValues(addr).Set(0)
+			lastErr = err
+			continue
+		}
+		_, err = conn.Brokers()
+		conn.Close()
+		if err == nil {
+			util.KafkaBrokerUp.WithLabelValues(addr).Set(1)
+			return nil
+		}
+		util.KafkaBrokerUp.WithLabelValues(addr).Set(0)
+		lastErr = err
+	}
+	return fmt.Errorf("no kafka broker reachable: %w", lastErr)
+}
+
 // Consumer represents a Kafka consumer
 type Consumer struct {
-	reader *kafka.Reader
+	reader     *kafka.Reader
+	deadLetter DeadLetterFunc
+	observe    EventObserver
+	stopped    chan struct{}
 }
 
+// DeadLetterFunc parks a message whose handler failed
+type DeadLetterFunc func(ctx context.Context, msg kafka.Message, handlerErr error) error
+
+// EventObserver is told about an event's decoded JSON payload. It must not
+// fail the message, so it reports nothing back.
+type EventObserver func(ctx context.Context, payload []byte)
+
 // NewConsumer creates a new Kafka consumer
-func NewConsumer(brokers []string, topic, groupID string) *Consumer {
+func NewConsumer(cluster *Cluster, topic, groupID string) *Consumer {
+	return newConsumer(cluster, topic, groupID, kafka.FirstOffset)
+}
+
+// NewLiveConsumer creates a consumer that starts at the end of the topic when
+// its group has no committed offset, for consumers that only care about events
+// published while they run
+func NewLiveConsumer(cluster *Cluster, topic, groupID string) *Consumer {
+	return newConsumer(cluster, topic, groupID, kafka.LastOffset)
+}
+
+func newConsumer(cluster *Cluster, topic, groupID string, startOffset int64) *Consumer {
 	reader := kafka.NewReader(kafka.ReaderConfig{
-		Brokers:        brokers,
+		Brokers:        cluster.brokers,
+		Dialer:         cluster.dialer,
 		Topic:          topic,
 		GroupID:        groupID,
 		MinBytes:       1,
 		MaxBytes:       10e6,
 		CommitInterval: time.Second,
-		StartOffset:    kafka.FirstOffset,
+		StartOffset:    startOffset,
 	})
 
-	return &Consumer{reader: reader}
+	return &Consumer{reader: reader, stopped: make(chan struct{})}
 }
 
 // ConsumeBatch reads a batch of messages
@@ -104,6 +239,31 @@ func (c *Consumer) CommitMessage(ctx context.Context, msg kafka.Message) error {
 	return c.reader.CommitMessages(ctx, msg)
 }
 
+// Lag returns how many messages the consumer is behind the end of its partitions
+func (c *Consumer) Lag() int64 {
+	lag := c.reader.Stats().Lag
+	if lag < 0 {
+		return 0
+	}
+	return lag
+}
+
+// Topic returns the topic the consumer reads
+func (c *Consumer) Topic() string {
+	return c.reader.Config().Topic
+}
+
+// SetDeadLetter parks messages that fail to decode or handle and commits past
+// them; without one such messages are left uncommitted
+func (c *Consumer) SetDeadLetter(fn DeadLetterFunc) {
+	c.deadLetter = fn
+}
+
+// SetObserver sets a function told about every message handled successfully
+func (c *Consumer) SetObserver(observe EventObserver) {
+	c.observe = observe
+}
+
 // Close closes the consumer
 func (c *Consumer) Close() error {
 	return c.reader.Close()
@@ -112,9 +272,23 @@ func (c *Consumer) Close() error {
 // MessageHandler is a function type for handling messages
 type MessageHandler func(ctx context.Context, msg kafka.Message) error
 
-// StartConsuming starts consuming messages with a handler
+// Observed wraps handler so observe is told about each message it handles
+// successfully, for messages that don't come through a Consumer
+func Observed(handler MessageHandler, observe EventObserver) MessageHandler {
+	return func(ctx context.Context, msg kafka.Message) error {
+		if err := handler(ctx, msg); err != nil {
+			return err
+		}
+		observe(ctx, msg.Value)
+		return nil
+	}
+}
+
+// StartConsuming starts consuming messages with a handler. Cancelling ctx
+// stops fetching; a message already being handled is finished and committed.
 func (c *Consumer) StartConsuming(ctx context.Context, handler MessageHandler) error {
 	log.Printf("Starting Kafka consumer for topic: %s", c.reader.Config().Topic)
+	defer close(c.stopped)
 
 	for {
 		select {
@@ -124,19 +298,141 @@ func (c *Consumer) StartConsuming(ctx context.Context, handler MessageHandler) e
 		default:
 			msg, err := c.reader.FetchMessage(ctx)
 			if err != nil {
+				if ctx.Err() != nil {
+					continue
+				}
+				util.KafkaFetchErrorsTotal.WithLabelValues(c.Topic()).Inc()
 				log.Printf("Error fetching message: %v", err)
 				time.Sleep(time.Second)
 				continue
 			}
 
-			if err := handler(ctx, msg); err != nil {
-				log.Printf("Error handling message: %v", err)
-				continue
-			}
+			c.process(context.WithoutCancel(ctx), msg, handler)
+		}
+	}
+}
 
-			if err := c.reader.CommitMessages(ctx, msg); err != nil {
-				log.Printf("Error committing message: %v", err)
-			}
+// Drain waits until StartConsuming has returned, i.e. the in-flight handler
+// finished after the consumer context was cancelled, or until ctx is done
+func (c *Consumer) Drain(ctx context.Context) error {
+	select {
+	case <-c.stopped:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("consumer for topic %s still handling a message: %w", c.Topic(), ctx.Err())
+	}
+}
+
+// process decodes, handles and commits one message
+func (c *Consumer) process(ctx context.Context, msg kafka.Message, handler MessageHandler) {
+	c.recordLag(msg)
+
+	ctx, span := startConsumeSpan(ctx, msg)
+	defer span.End()
+	ctx = withMessageTenant(ctx, msg)
+	ctx = withMessagePriority(ctx, msg)
+
+	value, err := Decode(msg.Value)
+	if err != nil {
+		log.Printf("Error decoding message: %v", err)
+		c.park(ctx, msg, err)
+		return
+	}
+	msg.Value = value
+
+	start := time.Now()
+	err = handler(ctx, msg)
+	util.KafkaMessageHandleDuration.WithLabelValues(msg.Topic, eventType(msg.Value), resultLabel(err)).
+		Observe(time.Since(start).Seconds())
+	if err != nil {
+		span.RecordError(err)
+		log.Printf("Error handling message: %v", err)
+		c.park(ctx, msg, err)
+		return
+	}
+	if c.observe != nil {
+		c.observe(ctx, msg.Value)
+	}
+
+	c.commit(ctx, msg)
+}
+
+// commit commits a handled message, counting failures
+func (c *Consumer) commit(ctx context.Context, msg kafka.Message) {
+	if err := c.reader.CommitMessages(ctx, msg); err != nil {
+		util.KafkaCommitErrorsTotal.WithLabelValues(msg.Topic).Inc()
+		log.Printf("Error committing message: %v", err)
+	}
+}
+
+// recordLag sets the lag of the message's partition from its high water mark
+func (c *Consumer) recordLag(msg kafka.Message) {
+	lag := msg.HighWaterMark - msg.Offset - 1
+	if lag < 0 {
+		lag = 0
+	}
+	util.KafkaConsumerLag.WithLabelValues(c.reader.Config().GroupID, msg.Topic, strconv.Itoa(msg.Partition)).Set(float64(lag))
+}
+
+// eventType reads the event type of a decoded message for metric labels
+func eventType(value []byte) string {
+	var base struct {
+		EventType string `json:"event_type"`
+	}
+	if err := json.Unmarshal(value, &base); err != nil || base.EventType == "" {
+		return "unknown"
+	}
+	return base.EventType
+}
+
+// resultLabel turns an error into the result label of a metric
+func resultLabel(err error) string {
+	if err != nil {
+		return "error"
+	}
+	return "ok"
+}
+
+// park hands a failed message to the dead letter queue and commits it once parked
+func (c *Consumer) park(ctx context.Context, msg kafka.Message, handlerErr error) {
+	if c.deadLetter == nil {
+		return
+	}
+
+	if err := c.deadLetter(ctx, msg, handlerErr); err != nil {
+		log.Printf("Error dead-lettering message at offset %d: %v", msg.Offset, err)
+		return
+	}
+
+	c.commit(ctx, msg)
+}
+
+// MissingTopics returns the topics that do not exist on the cluster
+func MissingTopics(ctx context.Context, cluster *Cluster, topics ...string) ([]string, error) {
+	if len(cluster.brokers) == 0 {
+		return nil, errors.New("no Kafka brokers configured")
+	}
+
+	conn, err := cluster.dialer.DialContext(ctx, "tcp", cluster.brokers[0])
+	if err != nil {
+		return nil, fmt.Errorf("failed to dial Kafka: %w", err)
+	}
+	defer conn.Close()
+
+	partitions, err := conn.ReadPartitions()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read topics: %w", err)
+	}
+	existing := make(map[string]bool)
+	for _, partition := range partitions {
+		existing[partition.Topic] = true
+	}
+
+	var missing []string
+	for _, topic := range topics {
+		if !existing[topic] {
+			missing = append(missing, topic)
 		}
 	}
+	return missing, nil
 }
@@ -0,0 +1,28 @@
+package broker
+
+import (
+	"testing"
+
+	"order-service/internal/util"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecordLagUsesHighWaterMark(t *testing.T) {
+	c := NewConsumer(localCluster(t), "orders", "lag-test")
+	t.Cleanup(func() { c.Close() })
+
+	c.recordLag(kafka.Message{Topic: "orders", Partition: 2, Offset: 10, HighWaterMark: 15})
+	assert.Equal(t, 4.0, testutil.ToFloat64(util.KafkaConsumerLag.WithLabelValues("lag-test", "orders", "2")))
+
+	c.recordLag(kafka.Message{Topic: "orders", Partition: 2, Offset: 14, HighWaterMark: 15})
+	assert.Equal(t, 0.0, testutil.ToFloat64(util.KafkaConsumerLag.WithLabelValues("lag-test", "orders", "2")))
+}
+
+func TestEventTypeLabel(t *testing.T) {
+	assert.Equal(t, "ORDER_CREATED", eventType([]byte(`{"event_type":"ORDER_CREATED"}`)))
+	assert.Equal(t, "unknown", eventType([]byte(`{}`)))
+	assert.Equal(t, "unknown", eventType([]byte(`not json`)))
+}
@@ -0,0 +1,40 @@
+package broker
+
+import (
+	"hash/fnv"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// ConsistentHashBalancer assigns each message to a partition by a consistent
+// hash of its key. Events are keyed by order ID (order-<id>), so every event
+// of an order lands on the same partition and is consumed in the order it was
+// published, whichever path wrote it. Adding partitions moves only the share
+// of keys the new partitions take over (jump consistent hashing). Messages
+// without a key are spread round-robin.
+type ConsistentHashBalancer struct {
+	keyless kafka.RoundRobin
+}
+
+// Balance implements kafka.Balancer
+func (b *ConsistentHashBalancer) Balance(msg kafka.Message, partitions ...int) int {
+	if len(msg.Key) == 0 {
+		return b.keyless.Balance(msg, partitions...)
+	}
+
+	h := fnv.New64a()
+	h.Write(msg.Key)
+	return partitions[jumpHash(h.Sum64(), len(partitions))]
+}
+
+// jumpHash maps key to one of n buckets (Lamping and Veach, "A Fast, Minimal
+// Memory, Consistent Hash Algorithm")
+func jumpHash(key uint64, n int) int {
+	var b, j int64 = -1, 0
+	for j < int64(n) {
+		b = j
+		key = key*2862933555777941757 + 1
+		j = int64(float64(b+1) * (float64(int64(1)<<31) / float64((key>>33)+1)))
+	}
+	return int(b)
+}
@@ -0,0 +1,79 @@
+package broker
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func partitionsUpTo(n int) []int {
+	partitions := make([]int, n)
+	for i := range partitions {
+		partitions[i] = i
+	}
+	return partitions
+}
+
+func TestConsistentHashBalancerKeepsAnOrderOnOnePartition(t *testing.T) {
+	b := &ConsistentHashBalancer{}
+	partitions := partitionsUpTo(12)
+
+	for id := 1; id <= 100; id++ {
+		msg := kafka.Message{Key: []byte(fmt.Sprintf("order-%d", id))}
+		first := b.Balance(msg, partitions...)
+		for i := 0; i < 5; i++ {
+			require.Equal(t, first, b.Balance(msg, partitions...), "order %d moved partition", id)
+		}
+		// A fresh balancer, e.g. on another instance, agrees
+		require.Equal(t, first, (&ConsistentHashBalancer{}).Balance(msg, partitions...))
+	}
+}
+
+func TestConsistentHashBalancerSpreadsOrdersEvenly(t *testing.T) {
+	b := &ConsistentHashBalancer{}
+	partitions := partitionsUpTo(8)
+
+	counts := make(map[int]int)
+	for id := 1; id <= 8000; id++ {
+		counts[b.Balance(kafka.Message{Key: []byte(fmt.Sprintf("order-%d", id))}, partitions...)]++
+	}
+	require.Len(t, counts, 8)
+	for partition, count := range counts {
+		assert.InDelta(t, 1000, count, 150, "partition %d", partition)
+	}
+}
+
+func TestConsistentHashBalancerMovesFewKeysWhenPartitionsAreAdded(t *testing.T) {
+	b := &ConsistentHashBalancer{}
+
+	moved := 0
+	for id := 1; id <= 9000; id++ {
+		msg := kafka.Message{Key: []byte(fmt.Sprintf("order-%d", id))}
+		before, after := b.Balance(msg, partitionsUpTo(8)...), b.Balance(msg, partitionsUpTo(9)...)
+		if before != after {
+			moved++
+			assert.Equal(t, 8, after, "keys only move to the new partition")
+		}
+	}
+	// Ideally 1/9 of the keys
+	assert.InDelta(t, 1000, moved, 150)
+}
+
+func TestConsistentHashBalancerRoundRobinsKeylessMessages(t *testing.T) {
+	b := &ConsistentHashBalancer{}
+	partitions := partitionsUpTo(3)
+
+	var got []int
+	for i := 0; i < 6; i++ {
+		got = append(got, b.Balance(kafka.Message{}, partitions...))
+	}
+	assert.ElementsMatch(t, []int{0, 0, 1, 1, 2, 2}, got)
+}
+
+func TestWritersHashOnTheMessageKey(t *testing.T) {
+	assert.IsType(t, &ConsistentHashBalancer{}, NewProducer(localCluster(t), "orders").writer.Balancer)
+	assert.IsType(t, &ConsistentHashBalancer{}, NewRepublisher(localCluster(t)).writer.Balancer)
+}
@@ -0,0 +1,58 @@
+package broker
+
+import (
+	"context"
+
+	"order-service/internal/models"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// PriorityHeader is the Kafka message header carrying the priority of the
+// order an event is about
+const PriorityHeader = "order-priority"
+
+type priorityKey struct{}
+
+// WithPriority returns ctx handling an order of priority, so the events
+// published from it carry the priority and take its lane
+func WithPriority(ctx context.Context, priority string) context.Context {
+	return context.WithValue(ctx, priorityKey{}, priority)
+}
+
+// Priority returns the priority of the order ctx handles, standard if unset
+func Priority(ctx context.Context) string {
+	if priority, ok := ctx.Value(priorityKey{}).(string); ok && priority != "" {
+		return priority
+	}
+	return models.PriorityStandard
+}
+
+// SkipExpress wraps the handler of the standard lane to leave the messages
+// of express orders to the express lane's handler
+func SkipExpress(handler MessageHandler) MessageHandler {
+	return func(ctx context.Context, msg kafka.Message) error {
+		if Priority(ctx) == models.PriorityExpress {
+			return nil
+		}
+		return handler(ctx, msg)
+	}
+}
+
+// setPriorityHeader tags msg with the priority of the order ctx handles.
+// Standard priority is left implicit.
+func setPriorityHeader(ctx context.Context, msg *kafka.Message) {
+	if priority := Priority(ctx); priority != models.PriorityStandard {
+		headerCarrier{headers: &msg.Headers}.Set(PriorityHeader, priority)
+	}
+}
+
+// withMessagePriority returns ctx handling an order of the priority msg is
+// tagged with, so the events its handler publishes stay in the same lane.
+// Untagged messages are about standard orders.
+func withMessagePriority(ctx context.Context, msg kafka.Message) context.Context {
+	if priority := (headerCarrier{headers: &msg.Headers}).Get(PriorityHeader); priority == models.PriorityExpress {
+		return WithPriority(ctx, priority)
+	}
+	return ctx
+}
@@ -0,0 +1,37 @@
+package broker
+
+import (
+	"context"
+	"testing"
+
+	"order-service/internal/models"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPriorityCrossesKafkaHeaders(t *testing.T) {
+	var msg kafka.Message
+	setPriorityHeader(context.Background(), &msg)
+	assert.Empty(t, msg.Headers, "standard orders publish untagged messages")
+	assert.Equal(t, models.PriorityStandard, Priority(withMessagePriority(context.Background(), msg)))
+
+	setPriorityHeader(WithPriority(context.Background(), models.PriorityExpress), &msg)
+	assert.Equal(t, models.PriorityExpress, Priority(withMessagePriority(context.Background(), msg)))
+
+	unknown := kafka.Message{Headers: []kafka.Header{{Key: PriorityHeader, Value: []byte("urgent")}}}
+	assert.Equal(t, models.PriorityStandard, Priority(withMessagePriority(context.Background(), unknown)))
+}
+
+func TestSkipExpressLeavesExpressOrdersToTheExpressLane(t *testing.T) {
+	handled := 0
+	handler := SkipExpress(func(context.Context, kafka.Message) error {
+		handled++
+		return nil
+	})
+
+	require.NoError(t, handler(context.Background(), kafka.Message{}))
+	require.NoError(t, handler(WithPriority(context.Background(), models.PriorityExpress), kafka.Message{}))
+	assert.Equal(t, 1, handled)
+}
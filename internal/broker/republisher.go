@@ -0,0 +1,72 @@
+package broker
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"order-service/internal/resilience/retry"
+	"order-service/internal/util"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// Headers of a message re-driven from the dead letter queue
+const (
+	HeaderDeadLetterID   = "x-dead-letter-id"
+	HeaderRedriveAttempt = "x-redrive-attempt"
+)
+
+// Republisher writes messages to the topic each one names, e.g. to re-drive
+// dead letters to the topic they were consumed from
+type Republisher struct {
+	writer *kafka.Writer
+}
+
+// NewRepublisher creates a new republisher
+func NewRepublisher(cluster *Cluster) *Republisher {
+	return &Republisher{writer: &kafka.Writer{
+		Addr:         kafka.TCP(cluster.brokers...),
+		Transport:    cluster.transport,
+		Balancer:     &ConsistentHashBalancer{},
+		RequiredAcks: kafka.RequireAll,
+		MaxAttempts:  1, // retried by Republish with jittered backoff
+		WriteTimeout: 10 * time.Second,
+		ReadTimeout:  10 * time.Second,
+	}}
+}
+
+// Republish writes msg to msg.Topic
+func (r *Republisher) Republish(ctx context.Context, msg kafka.Message) error {
+	msg.Time = time.Now()
+
+	start := time.Now()
+	err := retry.Do(ctx, publishRetryPolicy, func(ctx context.Context) error {
+		return r.writer.WriteMessages(ctx, msg)
+	})
+	util.KafkaPublishDuration.WithLabelValues(msg.Topic, resultLabel(err)).Observe(time.Since(start).Seconds())
+	if err != nil {
+		return fmt.Errorf("failed to republish message to %s: %w", msg.Topic, err)
+	}
+	return nil
+}
+
+// Close closes the republisher
+func (r *Republisher) Close() error {
+	return r.writer.Close()
+}
+
+// RedriveHeaders reads the dead letter a message was re-driven from and its
+// re-drive attempt; ok is false for messages that were never re-driven
+func RedriveHeaders(msg kafka.Message) (deadLetterID int64, attempt int, ok bool) {
+	for _, header := range msg.Headers {
+		switch header.Key {
+		case HeaderDeadLetterID:
+			deadLetterID, _ = strconv.ParseInt(string(header.Value), 10, 64)
+		case HeaderRedriveAttempt:
+			attempt, _ = strconv.Atoi(string(header.Value))
+		}
+	}
+	return deadLetterID, attempt, deadLetterID > 0
+}
@@ -0,0 +1,26 @@
+package broker
+
+import (
+	"context"
+
+	"order-service/internal/tenant"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// setTenantHeader tags msg with the tenant ctx acts for, if any
+func setTenantHeader(ctx context.Context, msg *kafka.Message) {
+	if id, ok := tenant.ID(ctx); ok {
+		headerCarrier{headers: &msg.Headers}.Set(tenant.Header, id)
+	}
+}
+
+// withMessageTenant returns ctx acting for the tenant msg is tagged with.
+// Untagged messages, published for no tenant or before tenancy, are handled
+// for no tenant.
+func withMessageTenant(ctx context.Context, msg kafka.Message) context.Context {
+	if id := (headerCarrier{headers: &msg.Headers}).Get(tenant.Header); tenant.Valid(id) {
+		return tenant.WithID(ctx, id)
+	}
+	return ctx
+}
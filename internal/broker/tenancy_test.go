@@ -0,0 +1,27 @@
+package broker
+
+import (
+	"context"
+	"testing"
+
+	"order-service/internal/tenant"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTenantCrossesKafkaHeaders(t *testing.T) {
+	var msg kafka.Message
+	setTenantHeader(context.Background(), &msg)
+	assert.Empty(t, msg.Headers, "work for no tenant publishes untagged messages")
+	_, ok := tenant.ID(withMessageTenant(context.Background(), msg))
+	assert.False(t, ok)
+
+	setTenantHeader(tenant.WithID(context.Background(), "acme"), &msg)
+	id, _ := tenant.ID(withMessageTenant(context.Background(), msg))
+	assert.Equal(t, "acme", id)
+
+	forged := kafka.Message{Headers: []kafka.Header{{Key: tenant.Header, Value: []byte("acme:*")}}}
+	_, ok = tenant.ID(withMessageTenant(context.Background(), forged))
+	assert.False(t, ok, "invalid tenant IDs are ignored")
+}
@@ -0,0 +1,77 @@
+package broker
+
+import (
+	"context"
+
+	"order-service/internal/util"
+
+	"github.com/segmentio/kafka-go"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracePropagator carries W3C trace context (traceparent, tracestate) in
+// message headers so a saga shows as one trace across publish and consume
+var tracePropagator = propagation.TraceContext{}
+
+// headerCarrier adapts Kafka message headers to the propagation carrier interface
+type headerCarrier struct {
+	headers *[]kafka.Header
+}
+
+// Get returns the value of the first header named key
+func (c headerCarrier) Get(key string) string {
+	for _, h := range *c.headers {
+		if h.Key == key {
+			return string(h.Value)
+		}
+	}
+	return ""
+}
+
+// Set replaces any header named key
+func (c headerCarrier) Set(key, value string) {
+	for i, h := range *c.headers {
+		if h.Key == key {
+			(*c.headers)[i].Value = []byte(value)
+			return
+		}
+	}
+	*c.headers = append(*c.headers, kafka.Header{Key: key, Value: []byte(value)})
+}
+
+// Keys returns the header names
+func (c headerCarrier) Keys() []string {
+	keys := make([]string, 0, len(*c.headers))
+	for _, h := range *c.headers {
+		keys = append(keys, h.Key)
+	}
+	return keys
+}
+
+// startPublishSpan starts a producer span and writes its context into msg's headers
+func startPublishSpan(ctx context.Context, msg *kafka.Message, topic string) (context.Context, trace.Span) {
+	ctx, span := util.GetTracer().Start(ctx, "kafka.publish "+topic,
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			attribute.String("messaging.system", "kafka"),
+			attribute.String("messaging.destination.name", topic),
+			attribute.String("messaging.kafka.message.key", string(msg.Key)),
+		))
+	tracePropagator.Inject(ctx, headerCarrier{headers: &msg.Headers})
+	return ctx, span
+}
+
+// startConsumeSpan continues the trace carried in msg's headers with a consumer span
+func startConsumeSpan(ctx context.Context, msg kafka.Message) (context.Context, trace.Span) {
+	ctx = tracePropagator.Extract(ctx, headerCarrier{headers: &msg.Headers})
+	return util.GetTracer().Start(ctx, "kafka.consume "+msg.Topic,
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			attribute.String("messaging.system", "kafka"),
+			attribute.String("messaging.source.name", msg.Topic),
+			attribute.Int("messaging.kafka.partition", msg.Partition),
+			attribute.Int64("messaging.kafka.message.offset", msg.Offset),
+		))
+}
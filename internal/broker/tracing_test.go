@@ -0,0 +1,37 @@
+package broker
+
+import (
+	"context"
+	"testing"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTraceContextCrossesKafkaHeaders(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	msg := kafka.Message{
+		Topic:   "orders",
+		Key:     []byte("order-1"),
+		Headers: []kafka.Header{{Key: "content-type", Value: []byte("application/json")}},
+	}
+	_, publish := startPublishSpan(context.Background(), &msg, "orders")
+	publish.End()
+
+	require.NotEmpty(t, headerCarrier{headers: &msg.Headers}.Get("traceparent"))
+	assert.Equal(t, "application/json", headerCarrier{headers: &msg.Headers}.Get("content-type"))
+
+	_, consume := startConsumeSpan(context.Background(), msg)
+	consume.End()
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	assert.Equal(t, spans[0].SpanContext().TraceID(), spans[1].SpanContext().TraceID())
+	assert.Equal(t, spans[0].SpanContext().SpanID(), spans[1].Parent().SpanID())
+}
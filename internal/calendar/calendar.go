@@ -0,0 +1,95 @@
+package calendar
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const dateLayout = "2006-01-02"
+
+var weekdayNames = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// Calendar describes which days are business days in a given location
+type Calendar struct {
+	loc      *time.Location
+	workdays map[time.Weekday]bool
+	holidays map[string]bool
+}
+
+// New creates a calendar from a timezone name, a comma-separated list of
+// working weekdays (e.g. "Mon,Tue,Wed,Thu,Fri") and holiday dates (YYYY-MM-DD)
+func New(timezone, workdays string, holidays []string) (*Calendar, error) {
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid calendar timezone %q: %w", timezone, err)
+	}
+
+	c := &Calendar{
+		loc:      loc,
+		workdays: make(map[time.Weekday]bool),
+		holidays: make(map[string]bool),
+	}
+
+	for _, name := range strings.Split(workdays, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		day, ok := weekdayNames[name[:min(3, len(name))]]
+		if !ok {
+			return nil, fmt.Errorf("invalid calendar weekday %q", name)
+		}
+		c.workdays[day] = true
+	}
+	if len(c.workdays) == 0 {
+		return nil, fmt.Errorf("calendar must have at least one working weekday")
+	}
+
+	for _, date := range holidays {
+		date = strings.TrimSpace(date)
+		if date == "" {
+			continue
+		}
+		if _, err := time.ParseInLocation(dateLayout, date, loc); err != nil {
+			return nil, fmt.Errorf("invalid calendar holiday %q: %w", date, err)
+		}
+		c.holidays[date] = true
+	}
+
+	return c, nil
+}
+
+// IsBusinessDay reports whether t falls on a working weekday that is not a holiday
+func (c *Calendar) IsBusinessDay(t time.Time) bool {
+	t = t.In(c.loc)
+	return c.workdays[t.Weekday()] && !c.holidays[t.Format(dateLayout)]
+}
+
+// EndOfNextBusinessDay returns the last instant of the first business day strictly after t's date
+func (c *Calendar) EndOfNextBusinessDay(t time.Time) time.Time {
+	return c.AddBusinessDays(t, 1)
+}
+
+// AddBusinessDays returns the end of the n-th business day after t's date
+func (c *Calendar) AddBusinessDays(t time.Time, n int) time.Time {
+	t = t.In(c.loc)
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, c.loc)
+
+	for n > 0 {
+		day = day.AddDate(0, 0, 1)
+		if c.IsBusinessDay(day) {
+			n--
+		}
+	}
+
+	return day.AddDate(0, 0, 1).Add(-time.Nanosecond)
+}
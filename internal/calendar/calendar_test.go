@@ -0,0 +1,33 @@
+package calendar
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEndOfNextBusinessDaySkipsWeekendAndHolidays(t *testing.T) {
+	cal, err := New("UTC", "Mon,Tue,Wed,Thu,Fri", []string{"2026-12-28"})
+	require.NoError(t, err)
+
+	// Friday afternoon -> weekend skipped, Monday is a holiday -> Tuesday
+	friday := time.Date(2026, 12, 25, 15, 0, 0, 0, time.UTC)
+	deadline := cal.EndOfNextBusinessDay(friday)
+
+	assert.Equal(t, time.Date(2026, 12, 29, 23, 59, 59, 999999999, time.UTC), deadline)
+}
+
+func TestIsBusinessDay(t *testing.T) {
+	cal, err := New("UTC", "Mon,Tue,Wed,Thu,Fri", nil)
+	require.NoError(t, err)
+
+	assert.True(t, cal.IsBusinessDay(time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)))
+	assert.False(t, cal.IsBusinessDay(time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)))
+}
+
+func TestNewRejectsInvalidWeekday(t *testing.T) {
+	_, err := New("UTC", "Mon,Funday", nil)
+	assert.Error(t, err)
+}
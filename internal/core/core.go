@@ -0,0 +1,241 @@
+package core
+
+import (
+	"fmt"
+	"time"
+
+	"order-service/config"
+	"order-service/internal/broker"
+	"order-service/internal/calendar"
+	"order-service/internal/fraud"
+	"order-service/internal/redisclient"
+	"order-service/internal/resilience/retry"
+	"order-service/internal/schema"
+	"order-service/internal/service"
+	"order-service/internal/store"
+)
+
+// Core is the order-taking core of the service: the domain services, the saga
+// and the event producer, without the HTTP server, consumers or background jobs.
+// The server and embedded callers build it the same way.
+type Core struct {
+	Store    *store.Store
+	Redis    *redisclient.Client
+	Kafka    *broker.Cluster
+	Schemas  *schema.Registry
+	Producer *broker.Producer
+	Events   *broker.EventPublisher
+	Timeline *service.OrderTimeline
+
+	OrderCache    *service.OrderCache
+	ProductCache  *service.ProductCache
+	TimeoutPolicy *service.TimeoutPolicy
+	Pricing       *service.PricingService
+	Inventory     *service.InventoryClient
+	Payments      *service.PaymentService
+	Wallets       *service.WalletService
+	Customers     *service.CustomerService
+	Orders        *service.OrderService
+	Saga          *service.SagaOrchestrator
+	Compensations *service.CompensationQueue
+	SLA           *service.SLATracker
+	StatusFeed    *service.OrderStatusFeed
+	KillSwitches  *service.KillSwitches
+	IncomingStock *service.IncomingStockService
+	LowStock      *service.LowStockMonitor
+
+	// FlashOrders and the producer queueing them are nil unless flash-sale
+	// mode accepts orders asynchronously
+	FlashOrders   *service.FlashOrders
+	FlashProducer *broker.Producer
+
+	// FairReservations is nil unless products reserve through a FIFO queue;
+	// the server drains the queues with its Run
+	FairReservations *service.FairReservations
+
+	// ExpressProducer is nil unless express orders have their own lane
+	ExpressProducer *broker.Producer
+	// AnalyticsProducer is nil unless analytics events are published
+	AnalyticsProducer *broker.Producer
+}
+
+// New wires the core on top of an open database and Redis connection
+func New(cfg *config.Config, db *store.Store, redis *redisclient.Client) (*Core, error) {
+	schemas, err := schema.NewRegistry()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load JSON schemas: %w", err)
+	}
+
+	kafka, err := KafkaCluster(cfg)
+	if err != nil {
+		return nil, err
+	}
+	producer := broker.NewProducer(kafka, cfg.Kafka.TopicOrder)
+	if cfg.Server.ValidateEventSchemas {
+		producer.SetValidator(schemas.ValidateEvent)
+	}
+	codec, err := broker.NewCodec(cfg.Kafka.EventLegacyFormat, cfg.Kafka.EventFieldNaming, cfg.Kafka.EventSource)
+	if err != nil {
+		return nil, fmt.Errorf("invalid event format configuration: %w", err)
+	}
+	producer.SetCodec(codec)
+	timeline := service.NewOrderTimeline(db)
+	producer.SetObserver(timeline.Observer(service.TimelinePublished))
+
+	events := broker.NewEventPublisher(producer)
+	events.SetScheduler(redis, time.Duration(cfg.Kafka.EventPublishRetryDelaySeconds)*time.Second)
+	var expressProducer *broker.Producer
+	if cfg.Kafka.TopicOrderExpress != "" {
+		expressProducer = broker.NewProducer(kafka, cfg.Kafka.TopicOrderExpress)
+		if cfg.Server.ValidateEventSchemas {
+			expressProducer.SetValidator(schemas.ValidateEvent)
+		}
+		expressProducer.SetCodec(codec)
+		events.SetExpress(expressProducer)
+	}
+	var analyticsProducer *broker.Producer
+	if cfg.Kafka.TopicAnalytics != "" {
+		analyticsProducer = broker.NewProducer(kafka, cfg.Kafka.TopicAnalytics)
+		events.SetAnalytics(analyticsProducer)
+	}
+
+	orderCache := service.NewOrderCache(redis,
+		time.Duration(cfg.Cache.OrderTTLSeconds)*time.Second,
+		time.Duration(cfg.Cache.OrderLocalTTLSeconds)*time.Second)
+	productCache := service.NewProductCache(db, redis,
+		time.Duration(cfg.Cache.ProductTTLSeconds)*time.Second)
+	productCache.SetLocalCache(cfg.Cache.ProductLocalSize,
+		time.Duration(cfg.Cache.ProductLocalTTLSeconds)*time.Second)
+
+	businessCalendar, err := calendar.New(cfg.Business.CalendarTimezone, cfg.Business.CalendarWorkdays, cfg.Business.CalendarHolidays)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load business calendar: %w", err)
+	}
+	timeoutPolicy, err := service.NewTimeoutPolicy(businessCalendar,
+		time.Duration(cfg.Business.OrderTimeoutSeconds)*time.Second,
+		cfg.Business.PaymentTimeoutRules)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load payment timeout rules: %w", err)
+	}
+
+	pricing := service.NewPricingService(db)
+	inventory := service.NewInventoryClient(db, redis)
+	inventory.SetHotProducts(cfg.Flash.HotProducts)
+	inventory.SetQuotaLease(cfg.Flash.QuotaLeaseSize, time.Duration(cfg.Flash.QuotaLeaseTTLSeconds)*time.Second)
+	allocator, err := service.NewWarehouseAllocator(cfg.Business.WarehouseAllocation)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load warehouse allocation: %w", err)
+	}
+	inventory.SetWarehouseAllocator(allocator)
+	lowStock := service.NewLowStockMonitor(db, productCache, redis, events)
+	inventory.SetLowStockMonitor(lowStock)
+	var fairReservations *service.FairReservations
+	if len(cfg.Flash.FairReservationProducts) > 0 {
+		fairReservations = service.NewFairReservations(redis, cfg.Flash.FairReservationProducts,
+			time.Duration(cfg.Flash.FairReservationWaitMs)*time.Millisecond)
+		inventory.SetFairReservations(fairReservations)
+	}
+	payments := service.NewPaymentService(db, events)
+	payments.SetSuccessRate(cfg.Business.PaymentSuccessRate)
+	payments.SetRetryPolicy(service.PaymentRetryPolicy{Policy: retry.Policy{
+		MaxAttempts: cfg.Business.PaymentMaxRetries + 1,
+		BaseDelay:   time.Duration(cfg.Business.PaymentRetryBackoffSeconds) * time.Second,
+		MaxDelay:    time.Duration(cfg.Business.PaymentRetryMaxBackoffSeconds) * time.Second,
+		Jitter:      0.2,
+	}})
+	wallets := service.NewWalletService(db)
+	payments.SetWallets(wallets)
+	orders := service.NewOrderService(db, redis, events, inventory, orderCache, productCache, pricing, timeoutPolicy)
+	orders.SetWallets(wallets)
+	customers := service.NewCustomerService(db, cfg.Business.CustomerRequired)
+	orders.SetCustomers(customers)
+	orders.SetReminders(service.ReminderPolicy{
+		PaymentReminderAfter: time.Duration(cfg.Business.PaymentReminderAfterSeconds) * time.Second,
+		ExpiryWarningBefore:  time.Duration(cfg.Business.ReservationExpiryWarningSeconds) * time.Second,
+	})
+	orders.SetBackorders(cfg.Business.BackordersEnabled)
+	orders.SetAvailabilityMaxLag(cfg.Business.AvailabilityMaxLag)
+	incomingStock := service.NewIncomingStockService(db, db, inventory, events, orderCache)
+	orders.SetIncomingStock(incomingStock)
+	orders.SetRiskScorer(
+		fraud.NewRuleScorer(cfg.Business.RiskLargeOrderAmount, cfg.Business.RiskBulkUnits, cfg.Business.RiskPaymentMethods),
+		fraud.Bands{Medium: cfg.Business.RiskBandMediumScore, High: cfg.Business.RiskBandHighScore})
+	killSwitches := service.NewKillSwitches(redis)
+	orders.SetKillSwitches(killSwitches)
+	saga := service.NewSagaOrchestrator(db, redis, inventory, payments, events, orderCache, service.CommitFailurePolicy{
+		Action: cfg.Business.StockCommitFailurePolicy,
+		Policy: retry.Policy{
+			MaxAttempts: cfg.Business.StockCommitMaxAttempts,
+			BaseDelay:   time.Duration(cfg.Business.StockCommitBackoffMs) * time.Millisecond,
+			Jitter:      0.2,
+		},
+	})
+
+	sla := service.NewSLATracker(db, service.SLATargets{
+		Reservation:  time.Duration(cfg.Business.SLAReservationSeconds) * time.Second,
+		Payment:      time.Duration(cfg.Business.SLAPaymentSeconds) * time.Second,
+		Confirmation: time.Duration(cfg.Business.SLAConfirmationSeconds) * time.Second,
+	})
+	orders.SetSLATracker(sla)
+	saga.SetSLATracker(sla)
+
+	statusFeed := service.NewOrderStatusFeed(redis, cfg.Server.OrderTrackingMaxConnections)
+	orders.SetStatusFeed(statusFeed)
+	saga.SetStatusFeed(statusFeed)
+
+	cancellations, err := service.NewCancellationPolicies(db, cfg.Business.CancellationPolicies)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load cancellation policies: %w", err)
+	}
+	saga.SetCancellationPolicies(cancellations)
+
+	// Jittered so the retries of compensations failed in one outage spread out
+	compensations := service.NewCompensationQueue(db, retry.Policy{
+		MaxAttempts: cfg.Business.CompensationMaxAttempts,
+		BaseDelay:   time.Duration(cfg.Business.CompensationRetryBackoffSeconds) * time.Second,
+		MaxDelay:    time.Duration(cfg.Business.CompensationRetryMaxBackoffSeconds) * time.Second,
+		Jitter:      0.2,
+	})
+	saga.SetCompensations(compensations)
+	payments.SetCompensations(compensations)
+
+	var flashOrders *service.FlashOrders
+	var flashProducer *broker.Producer
+	if cfg.Flash.AsyncOrders {
+		flashProducer = broker.NewProducer(kafka, cfg.Kafka.TopicFlashOrders)
+		flashOrders = service.NewFlashOrders(orders, inventory, redis, flashProducer)
+		orders.SetFlashOrders(flashOrders)
+	}
+
+	return &Core{
+		Store:         db,
+		Redis:         redis,
+		Kafka:         kafka,
+		Schemas:       schemas,
+		Producer:      producer,
+		Events:        events,
+		Timeline:      timeline,
+		OrderCache:    orderCache,
+		ProductCache:  productCache,
+		TimeoutPolicy: timeoutPolicy,
+		Pricing:       pricing,
+		Inventory:     inventory,
+		Payments:      payments,
+		Wallets:       wallets,
+		Customers:     customers,
+		Orders:        orders,
+		Saga:          saga,
+		Compensations: compensations,
+		SLA:           sla,
+		StatusFeed:    statusFeed,
+		KillSwitches:  killSwitches,
+		IncomingStock: incomingStock,
+		LowStock:      lowStock,
+		FlashOrders:   flashOrders,
+		FlashProducer: flashProducer,
+
+		FairReservations:  fairReservations,
+		ExpressProducer:   expressProducer,
+		AnalyticsProducer: analyticsProducer,
+	}, nil
+}
@@ -0,0 +1,26 @@
+package core
+
+import (
+	"fmt"
+
+	"order-service/config"
+	"order-service/internal/broker"
+)
+
+// KafkaCluster returns the Kafka cluster the service connects to, with the
+// TLS and SASL settings of cfg
+func KafkaCluster(cfg *config.Config) (*broker.Cluster, error) {
+	cluster, err := broker.NewCluster(cfg.Kafka.Brokers, broker.Security{
+		TLS:           cfg.Kafka.TLSEnabled,
+		TLSCAFile:     cfg.Kafka.TLSCAFile,
+		TLSCertFile:   cfg.Kafka.TLSCertFile,
+		TLSKeyFile:    cfg.Kafka.TLSKeyFile,
+		SASLMechanism: cfg.Kafka.SASLMechanism,
+		SASLUsername:  cfg.Kafka.SASLUsername,
+		SASLPassword:  cfg.Kafka.SASLPassword,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid Kafka security configuration: %w", err)
+	}
+	return cluster, nil
+}
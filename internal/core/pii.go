@@ -0,0 +1,25 @@
+package core
+
+import (
+	"fmt"
+
+	"order-service/config"
+	"order-service/internal/pii"
+)
+
+// PIICipher returns the cipher encrypting personal data columns, or nil if
+// they are stored in plain text
+func PIICipher(cfg *config.Config) (*pii.Cipher, error) {
+	switch cfg.Database.PIIKeyProvider {
+	case "local":
+		keys, err := pii.ParseLocalKeys(cfg.Database.PIILocalKeys, cfg.Database.PIIActiveKeyID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid PII_LOCAL_KEYS: %w", err)
+		}
+		return pii.NewCipher(keys), nil
+	case "vault":
+		return pii.NewCipher(pii.NewVaultTransit(cfg.Database.PIIVaultAddr, cfg.Database.PIIVaultToken, cfg.Database.PIIVaultKey)), nil
+	default:
+		return nil, nil
+	}
+}
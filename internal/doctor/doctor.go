@@ -0,0 +1,125 @@
+// Package doctor runs the self-test of the environment an instance is about to
+// run in, e.g. before a rollout or in CI: that the database schema is current,
+// the topics exist, the Redis scripts load, tracing is reachable and the clocks
+// agree.
+package doctor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Check statuses
+const (
+	StatusPass = "pass"
+	StatusFail = "fail"
+	StatusSkip = "skip"
+)
+
+// CheckFunc probes one part of the environment, returning a short detail of
+// what it found
+type CheckFunc func(ctx context.Context) (string, error)
+
+// Check is one named probe of the environment
+type Check struct {
+	Name string
+	Run  CheckFunc
+	// Needs names a check that must pass for this one to run, e.g. the
+	// database connection for the schema version
+	Needs string
+}
+
+// Result is the outcome of one check
+type Result struct {
+	Name       string `json:"name"`
+	Status     string `json:"status"`
+	Detail     string `json:"detail,omitempty"`
+	Error      string `json:"error,omitempty"`
+	DurationMs int64  `json:"duration_ms"`
+}
+
+// Report is the outcome of every check, in the order they ran
+type Report struct {
+	Passed  bool      `json:"passed"`
+	Checks  []Result  `json:"checks"`
+	Started time.Time `json:"started"`
+}
+
+// Run runs the checks in order, each with its own timeout. A check whose
+// prerequisite did not pass is skipped and fails the report.
+func Run(ctx context.Context, checks []Check, timeout time.Duration) Report {
+	report := Report{Passed: true, Checks: make([]Result, 0, len(checks)), Started: time.Now().UTC()}
+	status := make(map[string]string, len(checks))
+
+	for _, check := range checks {
+		result := Result{Name: check.Name}
+		if check.Needs != "" && status[check.Needs] != StatusPass {
+			result.Status = StatusSkip
+			result.Error = fmt.Sprintf("needs %s", check.Needs)
+		} else {
+			checkCtx, cancel := context.WithTimeout(ctx, timeout)
+			start := time.Now()
+			detail, err := check.Run(checkCtx)
+			cancel()
+
+			result.DurationMs = time.Since(start).Milliseconds()
+			result.Status, result.Detail = StatusPass, detail
+			if err != nil {
+				result.Status, result.Error = StatusFail, err.Error()
+			}
+		}
+
+		if result.Status != StatusPass {
+			report.Passed = false
+		}
+		status[check.Name] = result.Status
+		report.Checks = append(report.Checks, result)
+	}
+	return report
+}
+
+// WriteText writes the report one check per line, for operators
+func (r Report) WriteText(w io.Writer) error {
+	for _, result := range r.Checks {
+		line := fmt.Sprintf("[%s] %-14s", result.Status, result.Name)
+		if result.Detail != "" {
+			line += " " + result.Detail
+		}
+		if result.Error != "" {
+			line += " error: " + result.Error
+		}
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+
+	verdict := "PASSED"
+	if !r.Passed {
+		verdict = "FAILED"
+	}
+	_, err := fmt.Fprintf(w, "doctor %s\n", verdict)
+	return err
+}
+
+// ClockSkew checks that a remote clock, read by now, is within max of the
+// local one. The read is timed and the remote time compared to the middle of
+// it, so the round trip does not count as skew.
+func ClockSkew(now func(ctx context.Context) (time.Time, error), max time.Duration) CheckFunc {
+	return func(ctx context.Context) (string, error) {
+		before := time.Now()
+		remote, err := now(ctx)
+		if err != nil {
+			return "", err
+		}
+		rtt := time.Since(before)
+
+		skew := remote.Sub(before.Add(rtt / 2))
+		detail := fmt.Sprintf("skew %s", skew.Round(time.Millisecond))
+		if skew > max || skew < -max {
+			return detail, fmt.Errorf("clock skew %s exceeds %s", skew.Round(time.Millisecond), max)
+		}
+		return detail, nil
+	}
+}
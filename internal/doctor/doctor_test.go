@@ -0,0 +1,44 @@
+package doctor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunSkipsChecksWhosePrerequisiteFailed(t *testing.T) {
+	ran := false
+	report := Run(context.Background(), []Check{
+		{Name: "config", Run: func(context.Context) (string, error) { return "env=test", nil }},
+		{Name: "postgres", Needs: "config", Run: func(context.Context) (string, error) { return "", errors.New("connection refused") }},
+		{Name: "schema", Needs: "postgres", Run: func(context.Context) (string, error) { ran = true; return "", nil }},
+	}, time.Second)
+
+	assert.False(t, report.Passed)
+	assert.False(t, ran)
+	require.Len(t, report.Checks, 3)
+	assert.Equal(t, StatusPass, report.Checks[0].Status)
+	assert.Equal(t, StatusFail, report.Checks[1].Status)
+	assert.Equal(t, "connection refused", report.Checks[1].Error)
+	assert.Equal(t, StatusSkip, report.Checks[2].Status)
+
+	var out bytes.Buffer
+	require.NoError(t, report.WriteText(&out))
+	assert.Contains(t, out.String(), "[skip] schema")
+	assert.Contains(t, out.String(), "doctor FAILED")
+}
+
+func TestClockSkewFailsBeyondMax(t *testing.T) {
+	ahead := func(context.Context) (time.Time, error) { return time.Now().Add(3 * time.Second), nil }
+	inSync := func(context.Context) (time.Time, error) { return time.Now(), nil }
+
+	_, err := ClockSkew(ahead, time.Second)(context.Background())
+	assert.Error(t, err)
+	_, err = ClockSkew(inSync, time.Second)(context.Background())
+	assert.NoError(t, err)
+}
@@ -0,0 +1,101 @@
+package fraud
+
+import "context"
+
+// Risk bands orders are grouped into for review
+const (
+	BandLow    = "low"
+	BandMedium = "medium"
+	BandHigh   = "high"
+)
+
+// IsBand reports whether band is a known risk band
+func IsBand(band string) bool {
+	switch band {
+	case BandLow, BandMedium, BandHigh:
+		return true
+	}
+	return false
+}
+
+// Order is what an order is scored on
+type Order struct {
+	UserID        int64
+	TotalAmount   int64
+	PaymentMethod string
+	// Units is the number of units across all items
+	Units int
+}
+
+// Scorer scores the fraud risk of an order from 0 (no risk) to 100
+type Scorer interface {
+	Score(ctx context.Context, order Order) (int, error)
+}
+
+// Bands are the lowest scores of the medium and high risk bands
+type Bands struct {
+	Medium int
+	High   int
+}
+
+// Band returns the risk band of a score
+func (b Bands) Band(score int) string {
+	switch {
+	case score >= b.High:
+		return BandHigh
+	case score >= b.Medium:
+		return BandMedium
+	default:
+		return BandLow
+	}
+}
+
+// RuleScorer scores orders with fixed rules on their amount, size and payment
+// method. Each rule that matches adds its weight; the score is capped at 100.
+type RuleScorer struct {
+	// LargeAmount is the total from which an order counts as large; twice
+	// that counts as very large
+	LargeAmount int64
+	// BulkUnits is the unit count from which an order counts as a bulk buy
+	BulkUnits int
+	// RiskyPaymentMethods are payment methods that are hard to claw back
+	RiskyPaymentMethods map[string]bool
+}
+
+// Rule weights
+const (
+	weightLargeAmount     = 30
+	weightVeryLargeAmount = 30
+	weightBulkUnits       = 25
+	weightRiskyPayment    = 25
+)
+
+// NewRuleScorer creates a rule scorer
+func NewRuleScorer(largeAmount int64, bulkUnits int, riskyPaymentMethods []string) *RuleScorer {
+	risky := make(map[string]bool, len(riskyPaymentMethods))
+	for _, method := range riskyPaymentMethods {
+		risky[method] = true
+	}
+	return &RuleScorer{LargeAmount: largeAmount, BulkUnits: bulkUnits, RiskyPaymentMethods: risky}
+}
+
+// Score scores an order
+func (s *RuleScorer) Score(_ context.Context, order Order) (int, error) {
+	score := 0
+	if s.LargeAmount > 0 && order.TotalAmount >= s.LargeAmount {
+		score += weightLargeAmount
+		if order.TotalAmount >= 2*s.LargeAmount {
+			score += weightVeryLargeAmount
+		}
+	}
+	if s.BulkUnits > 0 && order.Units >= s.BulkUnits {
+		score += weightBulkUnits
+	}
+	if s.RiskyPaymentMethods[order.PaymentMethod] {
+		score += weightRiskyPayment
+	}
+	if score > 100 {
+		score = 100
+	}
+	return score, nil
+}
@@ -0,0 +1,31 @@
+package fraud
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRuleScorerBands(t *testing.T) {
+	scorer := NewRuleScorer(1000, 10, []string{"gift_card"})
+	bands := Bands{Medium: 30, High: 70}
+
+	cases := []struct {
+		order Order
+		score int
+		band  string
+	}{
+		{Order{TotalAmount: 500, Units: 1, PaymentMethod: "card"}, 0, BandLow},
+		{Order{TotalAmount: 1500, Units: 1, PaymentMethod: "card"}, 30, BandMedium},
+		{Order{TotalAmount: 2000, Units: 12, PaymentMethod: "card"}, 85, BandHigh},
+		{Order{TotalAmount: 5000, Units: 50, PaymentMethod: "gift_card"}, 100, BandHigh},
+	}
+	for _, tc := range cases {
+		score, err := scorer.Score(context.Background(), tc.order)
+		require.NoError(t, err)
+		assert.Equal(t, tc.score, score, "%+v", tc.order)
+		assert.Equal(t, tc.band, bands.Band(score), "%+v", tc.order)
+	}
+}
@@ -0,0 +1,92 @@
+package graph
+
+import (
+	"context"
+	"time"
+
+	"order-service/internal/models"
+	"order-service/internal/store"
+
+	"github.com/vikstrous/dataloadgen"
+)
+
+// loaderWait is how long a loader collects keys before fetching them in one query
+const loaderWait = 2 * time.Millisecond
+
+// loadersKey carries a request's loaders in its context
+type loadersKey struct{}
+
+// loaders batch the lookups made while resolving one request, so a list of
+// orders loads its items, payments and products with one query each
+type loaders struct {
+	orders   *dataloadgen.Loader[int64, *models.Order]
+	items    *dataloadgen.Loader[int64, []*models.OrderItem]
+	payments *dataloadgen.Loader[int64, []*models.Payment]
+	products *dataloadgen.Loader[int64, *models.Product]
+}
+
+func newLoaders(orders store.OrderRepository, payments store.PaymentRepository, inventory store.InventoryRepository) *loaders {
+	return &loaders{
+		orders: dataloadgen.NewLoader(func(ctx context.Context, ids []int64) ([]*models.Order, []error) {
+			found, err := orders.GetOrdersByIDs(ctx, ids)
+			if err != nil {
+				return nil, []error{err}
+			}
+			byID := make(map[int64]*models.Order, len(found))
+			for i := range found {
+				byID[found[i].ID] = &found[i]
+			}
+			return inKeyOrder(ids, byID), nil
+		}, dataloadgen.WithWait(loaderWait)),
+
+		items: dataloadgen.NewLoader(func(ctx context.Context, orderIDs []int64) ([][]*models.OrderItem, []error) {
+			found, err := orders.GetOrderItemsByOrderIDs(ctx, orderIDs)
+			if err != nil {
+				return nil, []error{err}
+			}
+			byOrder := make(map[int64][]*models.OrderItem, len(orderIDs))
+			for i := range found {
+				byOrder[found[i].OrderID] = append(byOrder[found[i].OrderID], &found[i])
+			}
+			return inKeyOrder(orderIDs, byOrder), nil
+		}, dataloadgen.WithWait(loaderWait)),
+
+		payments: dataloadgen.NewLoader(func(ctx context.Context, orderIDs []int64) ([][]*models.Payment, []error) {
+			found, err := payments.GetPaymentsByOrderIDs(ctx, orderIDs)
+			if err != nil {
+				return nil, []error{err}
+			}
+			byOrder := make(map[int64][]*models.Payment, len(orderIDs))
+			for i := range found {
+				byOrder[found[i].OrderID] = append(byOrder[found[i].OrderID], &found[i])
+			}
+			return inKeyOrder(orderIDs, byOrder), nil
+		}, dataloadgen.WithWait(loaderWait)),
+
+		products: dataloadgen.NewLoader(func(ctx context.Context, ids []int64) ([]*models.Product, []error) {
+			found, err := inventory.GetProductsByIDs(ctx, ids)
+			if err != nil {
+				return nil, []error{err}
+			}
+			byID := make(map[int64]*models.Product, len(found))
+			for i := range found {
+				byID[found[i].ID] = &found[i]
+			}
+			return inKeyOrder(ids, byID), nil
+		}, dataloadgen.WithWait(loaderWait)),
+	}
+}
+
+// inKeyOrder lines fetched values up with the keys asked for, leaving the zero
+// value for keys that were not found
+func inKeyOrder[V any](keys []int64, byKey map[int64]V) []V {
+	values := make([]V, len(keys))
+	for i, key := range keys {
+		values[i] = byKey[key]
+	}
+	return values
+}
+
+func loadersFrom(ctx context.Context) *loaders {
+	return ctx.Value(loadersKey{}).(*loaders)
+}
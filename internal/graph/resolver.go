@@ -0,0 +1,28 @@
+// Package graph serves order and catalog queries over GraphQL, resolving
+// nested fields with per-request loaders batched against the store.
+package graph
+
+//go:generate gqlgen generate
+
+import (
+	"slices"
+
+	"order-service/internal/store"
+)
+
+// maxIDs is how many orders or products can be queried by ID at once
+const maxIDs = 500
+
+// Resolver resolves the queries in schema.graphqls
+type Resolver struct {
+	orders    store.OrderRepository
+	payments  store.PaymentRepository
+	inventory store.InventoryRepository
+}
+
+// uniqueSorted returns ids in ascending order without repeats
+func uniqueSorted(ids []int64) []int64 {
+	sorted := slices.Clone(ids)
+	slices.Sort(sorted)
+	return slices.Compact(sorted)
+}
@@ -0,0 +1,85 @@
+package graph
+
+// This file will be automatically regenerated based on the schema, any resolver implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+// Code generated by github.com/99designs/gqlgen version v0.17.49
+
+import (
+	"context"
+	"order-service/internal/apperrors"
+	"order-service/internal/models"
+	"order-service/internal/rbac"
+	"slices"
+)
+
+// Items is the resolver for the items field.
+func (r *orderResolver) Items(ctx context.Context, obj *models.Order) ([]*models.OrderItem, error) {
+	items, err := loadersFrom(ctx).items.Load(ctx, obj.ID)
+	if items == nil && err == nil {
+		items = []*models.OrderItem{}
+	}
+	return items, err
+}
+
+// Payments is the resolver for the payments field.
+func (r *orderResolver) Payments(ctx context.Context, obj *models.Order) ([]*models.Payment, error) {
+	payments, err := loadersFrom(ctx).payments.Load(ctx, obj.ID)
+	if payments == nil && err == nil {
+		payments = []*models.Payment{}
+	}
+	return payments, err
+}
+
+// Product is the resolver for the product field.
+func (r *orderItemResolver) Product(ctx context.Context, obj *models.OrderItem) (*models.Product, error) {
+	return loadersFrom(ctx).products.Load(ctx, obj.ProductID)
+}
+
+// Order is the resolver for the order field.
+func (r *queryResolver) Order(ctx context.Context, id int64) (*models.Order, error) {
+	order, err := loadersFrom(ctx).orders.Load(ctx, id)
+	if order != nil && !rbac.OwnsOrder(ctx, order.UserID) {
+		return nil, err
+	}
+	return order, err
+}
+
+// Orders is the resolver for the orders field.
+func (r *queryResolver) Orders(ctx context.Context, ids []int64) ([]*models.Order, error) {
+	if len(ids) > maxIDs {
+		return nil, apperrors.New(apperrors.ErrInvalidRequest, "at most %d orders can be queried at once", maxIDs)
+	}
+
+	orders, err := loadersFrom(ctx).orders.LoadAll(ctx, uniqueSorted(ids))
+	return slices.DeleteFunc(orders, func(order *models.Order) bool {
+		return order == nil || !rbac.OwnsOrder(ctx, order.UserID)
+	}), err
+}
+
+// Product is the resolver for the product field.
+func (r *queryResolver) Product(ctx context.Context, id int64) (*models.Product, error) {
+	return loadersFrom(ctx).products.Load(ctx, id)
+}
+
+// Products is the resolver for the products field.
+func (r *queryResolver) Products(ctx context.Context, ids []int64) ([]*models.Product, error) {
+	if len(ids) > maxIDs {
+		return nil, apperrors.New(apperrors.ErrInvalidRequest, "at most %d products can be queried at once", maxIDs)
+	}
+
+	products, err := loadersFrom(ctx).products.LoadAll(ctx, uniqueSorted(ids))
+	return slices.DeleteFunc(products, func(product *models.Product) bool { return product == nil }), err
+}
+
+// Order returns OrderResolver implementation.
+func (r *Resolver) Order() OrderResolver { return &orderResolver{r} }
+
+// OrderItem returns OrderItemResolver implementation.
+func (r *Resolver) OrderItem() OrderItemResolver { return &orderItemResolver{r} }
+
+// Query returns QueryResolver implementation.
+func (r *Resolver) Query() QueryResolver { return &queryResolver{r} }
+
+type orderResolver struct{ *Resolver }
+type orderItemResolver struct{ *Resolver }
+type queryResolver struct{ *Resolver }
@@ -0,0 +1,64 @@
+package graph
+
+import (
+	"context"
+	"net/http"
+
+	"order-service/internal/apperrors"
+	"order-service/internal/store"
+	"order-service/internal/util"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/99designs/gqlgen/graphql/handler/extension"
+	"github.com/99designs/gqlgen/graphql/handler/lru"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+	"go.uber.org/zap"
+)
+
+// NewHandler serves the schema over GET and POST. Queries whose complexity,
+// with lists weighted by the number of IDs asked for, exceeds complexityLimit
+// are rejected before they run.
+func NewHandler(orders store.OrderRepository, payments store.PaymentRepository, inventory store.InventoryRepository, complexityLimit int) http.Handler {
+	cfg := Config{Resolvers: &Resolver{orders: orders, payments: payments, inventory: inventory}}
+	cfg.Complexity.Query.Orders = func(childComplexity int, ids []int64) int {
+		return childComplexity * len(ids)
+	}
+	cfg.Complexity.Query.Products = func(childComplexity int, ids []int64) int {
+		return childComplexity * len(ids)
+	}
+
+	srv := handler.New(NewExecutableSchema(cfg))
+	srv.AddTransport(transport.GET{})
+	srv.AddTransport(transport.POST{})
+	srv.SetQueryCache(lru.New(1000))
+	srv.Use(extension.Introspection{})
+	srv.Use(extension.FixedComplexityLimit(complexityLimit))
+	srv.SetErrorPresenter(presentError)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), loadersKey{}, newLoaders(orders, payments, inventory))
+		srv.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// presentError reports resolver errors by their apperrors code, and never
+// leaks internal error strings to clients
+func presentError(ctx context.Context, err error) *gqlerror.Error {
+	gqlErr := graphql.DefaultErrorPresenter(ctx, err)
+	if gqlErr.Err == nil {
+		// A parse or validation error
+		return gqlErr
+	}
+
+	appErr := apperrors.From(gqlErr.Err)
+	gqlErr.Message = appErr.Title
+	if appErr == apperrors.ErrInternal {
+		util.GetLogger().Error("GraphQL resolver failed", zap.Error(err))
+	} else if appErr.Detail != "" {
+		gqlErr.Message = appErr.Detail
+	}
+	gqlErr.Extensions = map[string]interface{}{"code": appErr.Code}
+	return gqlErr
+}
@@ -0,0 +1,110 @@
+package graph
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"order-service/internal/models"
+	"order-service/internal/rbac"
+	"order-service/internal/store/mocks"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func query(t *testing.T, h http.Handler, q string) map[string]interface{} {
+	t.Helper()
+	body, err := json.Marshal(map[string]string{"query": q})
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(string(body)))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	var resp map[string]interface{}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	return resp
+}
+
+func TestNestedFieldsAreBatchedAcrossOrders(t *testing.T) {
+	orders := mocks.NewOrderRepository(t)
+	payments := mocks.NewPaymentRepository(t)
+	inventory := mocks.NewInventoryRepository(t)
+
+	orders.On("GetOrdersByIDs", mock.Anything, []int64{1, 2, 3}).
+		Return([]models.Order{{ID: 1, Status: models.OrderStatusPaid}, {ID: 2, Status: models.OrderStatusReserved}}, nil).Once()
+	orders.On("GetOrderItemsByOrderIDs", mock.Anything, mock.MatchedBy(func(ids []int64) bool { return len(ids) == 2 })).
+		Return([]models.OrderItem{
+			{OrderID: 1, ProductID: 10, Quantity: 1},
+			{OrderID: 2, ProductID: 10, Quantity: 2},
+			{OrderID: 2, ProductID: 20, Quantity: 1},
+		}, nil).Once()
+	payments.On("GetPaymentsByOrderIDs", mock.Anything, mock.MatchedBy(func(ids []int64) bool { return len(ids) == 2 })).
+		Return([]models.Payment{{OrderID: 1, Status: "SUCCESS", Amount: 1000}}, nil).Once()
+	inventory.On("GetProductsByIDs", mock.Anything, mock.MatchedBy(func(ids []int64) bool { return len(ids) == 2 })).
+		Return([]models.Product{{ID: 10, Name: "Tee"}, {ID: 20, Name: "Cap"}}, nil).Once()
+
+	resp := query(t, NewHandler(orders, payments, inventory, 1000),
+		`{ orders(ids: [3, 2, 1, 2]) { id status items { quantity product { name } } payments { amount } } }`)
+	require.Nil(t, resp["errors"])
+
+	got := resp["data"].(map[string]interface{})["orders"].([]interface{})
+	require.Len(t, got, 2, "unknown and repeated IDs are left out")
+	first, second := got[0].(map[string]interface{}), got[1].(map[string]interface{})
+	assert.Equal(t, float64(1), first["id"])
+	assert.Len(t, first["payments"], 1)
+	assert.Empty(t, second["payments"])
+	items := second["items"].([]interface{})
+	require.Len(t, items, 2)
+	assert.Equal(t, "Cap", items[1].(map[string]interface{})["product"].(map[string]interface{})["name"])
+}
+
+func TestQueriesOverTheComplexityLimitAreRejected(t *testing.T) {
+	h := NewHandler(mocks.NewOrderRepository(t), mocks.NewPaymentRepository(t), mocks.NewInventoryRepository(t), 10)
+
+	resp := query(t, h, `{ orders(ids: [1, 2, 3, 4, 5, 6]) { id status } }`)
+	errs := resp["errors"].([]interface{})
+	require.Len(t, errs, 1)
+	assert.Contains(t, errs[0].(map[string]interface{})["message"], "complexity")
+}
+
+func TestStoreErrorsAreNotLeaked(t *testing.T) {
+	orders := mocks.NewOrderRepository(t)
+	orders.On("GetOrdersByIDs", mock.Anything, []int64{1}).Return(nil, assert.AnError).Once()
+
+	resp := query(t, NewHandler(orders, mocks.NewPaymentRepository(t), mocks.NewInventoryRepository(t), 1000), `{ order(id: 1) { id } }`)
+	errs := resp["errors"].([]interface{})
+	require.Len(t, errs, 1)
+	assert.Equal(t, "Internal server error", errs[0].(map[string]interface{})["message"])
+	assert.Equal(t, "internal_error", errs[0].(map[string]interface{})["extensions"].(map[string]interface{})["code"])
+}
+
+func TestCustomersOnlySeeTheirOwnOrders(t *testing.T) {
+	orders := mocks.NewOrderRepository(t)
+	orders.On("GetOrdersByIDs", mock.Anything, mock.Anything).
+		Return([]models.Order{{ID: 1, UserID: 42}, {ID: 2, UserID: 7}}, nil)
+	h := NewHandler(orders, mocks.NewPaymentRepository(t), mocks.NewInventoryRepository(t), 1000)
+	customer := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.ServeHTTP(w, r.WithContext(rbac.WithCustomer(r.Context(), 42)))
+	})
+
+	resp := query(t, customer, `{ orders(ids: [1, 2]) { id } }`)
+	require.Nil(t, resp["errors"])
+	got := resp["data"].(map[string]interface{})["orders"].([]interface{})
+	require.Len(t, got, 1)
+	assert.Equal(t, float64(1), got[0].(map[string]interface{})["id"])
+
+	resp = query(t, customer, `{ order(id: 2) { id } }`)
+	require.Nil(t, resp["errors"])
+	assert.Nil(t, resp["data"].(map[string]interface{})["order"])
+
+	// Staff see every order
+	resp = query(t, h, `{ orders(ids: [1, 2]) { id } }`)
+	assert.Len(t, resp["data"].(map[string]interface{})["orders"], 2)
+}
@@ -0,0 +1,198 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Component statuses
+const (
+	StatusUp   = "up"
+	StatusDown = "down"
+)
+
+// CheckFunc probes a single dependency
+type CheckFunc func(ctx context.Context) error
+
+type check struct {
+	name    string
+	timeout time.Duration
+	fn      CheckFunc
+}
+
+// ComponentStatus is the result of one dependency check
+type ComponentStatus struct {
+	Status    string `json:"status"`
+	Error     string `json:"error,omitempty"`
+	LatencyMs int64  `json:"latency_ms"`
+}
+
+// Report aggregates dependency check results
+type Report struct {
+	Healthy    bool                       `json:"-"`
+	Components map[string]ComponentStatus `json:"components"`
+}
+
+// WorkerStatus is what a worker of this instance last did
+type WorkerStatus struct {
+	Running   bool      `json:"running"`
+	StartedAt time.Time `json:"started_at"`
+	// InFlightSince is when the message being handled was picked up
+	InFlightSince   *time.Time `json:"in_flight_since,omitempty"`
+	LastProcessedAt *time.Time `json:"last_processed_at,omitempty"`
+	LastPartition   int        `json:"last_partition"`
+	LastOffset      int64      `json:"last_offset"`
+	LastError       string     `json:"last_error,omitempty"`
+	LastErrorAt     *time.Time `json:"last_error_at,omitempty"`
+}
+
+// Checker runs dependency checks and tracks in-flight worker handlers
+type Checker struct {
+	checks []check
+
+	mu       sync.Mutex
+	inFlight map[string]time.Time
+	workers  map[string]*WorkerStatus
+}
+
+// NewChecker creates a new health checker
+func NewChecker() *Checker {
+	return &Checker{
+		inFlight: make(map[string]time.Time),
+		workers:  make(map[string]*WorkerStatus),
+	}
+}
+
+// Register adds a dependency check with its own timeout
+func (c *Checker) Register(name string, timeout time.Duration, fn CheckFunc) {
+	c.checks = append(c.checks, check{name: name, timeout: timeout, fn: fn})
+}
+
+// CheckReadiness runs all dependency checks concurrently
+func (c *Checker) CheckReadiness(ctx context.Context) Report {
+	report := Report{
+		Healthy:    true,
+		Components: make(map[string]ComponentStatus, len(c.checks)),
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, chk := range c.checks {
+		wg.Add(1)
+		go func(chk check) {
+			defer wg.Done()
+
+			checkCtx, cancel := context.WithTimeout(ctx, chk.timeout)
+			defer cancel()
+
+			start := time.Now()
+			err := chk.fn(checkCtx)
+			status := ComponentStatus{Status: StatusUp, LatencyMs: time.Since(start).Milliseconds()}
+			if err != nil {
+				status.Status = StatusDown
+				status.Error = err.Error()
+			}
+
+			mu.Lock()
+			report.Components[chk.name] = status
+			if err != nil {
+				report.Healthy = false
+			}
+			mu.Unlock()
+		}(chk)
+	}
+	wg.Wait()
+
+	return report
+}
+
+// BeginWork records that a worker started handling a message
+func (c *Checker) BeginWork(worker string) {
+	c.mu.Lock()
+	c.inFlight[worker] = time.Now()
+	c.mu.Unlock()
+}
+
+// EndWork records that a worker finished handling a message
+func (c *Checker) EndWork(worker string) {
+	c.mu.Lock()
+	delete(c.inFlight, worker)
+	c.mu.Unlock()
+}
+
+// StuckWorkers returns workers whose current message has been in flight longer than threshold
+func (c *Checker) StuckWorkers(threshold time.Duration) []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var stuck []string
+	for worker, since := range c.inFlight {
+		if time.Since(since) > threshold {
+			stuck = append(stuck, worker)
+		}
+	}
+	sort.Strings(stuck)
+	return stuck
+}
+
+// RunWorker runs a worker loop, recording it as running until run returns.
+// A context cancellation is not recorded as an error.
+func (c *Checker) RunWorker(worker string, run func() error) error {
+	c.mu.Lock()
+	status := c.worker(worker)
+	status.Running, status.StartedAt = true, time.Now()
+	c.mu.Unlock()
+
+	err := run()
+
+	c.mu.Lock()
+	status.Running = false
+	if err != nil && !errors.Is(err, context.Canceled) {
+		now := time.Now()
+		status.LastError, status.LastErrorAt = err.Error(), &now
+	}
+	c.mu.Unlock()
+	return err
+}
+
+// RecordWork records the outcome of a message handled by a worker
+func (c *Checker) RecordWork(worker string, partition int, offset int64, err error) {
+	now := time.Now()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	status := c.worker(worker)
+	status.LastProcessedAt, status.LastPartition, status.LastOffset = &now, partition, offset
+	if err != nil {
+		status.LastError, status.LastErrorAt = err.Error(), &now
+	}
+}
+
+// Workers returns the status of every worker run or recorded so far
+func (c *Checker) Workers() map[string]WorkerStatus {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	workers := make(map[string]WorkerStatus, len(c.workers))
+	for name, status := range c.workers {
+		snapshot := *status
+		if since, ok := c.inFlight[name]; ok {
+			snapshot.InFlightSince = &since
+		}
+		workers[name] = snapshot
+	}
+	return workers
+}
+
+// worker returns the status of a worker, adding it if unknown; c.mu must be held
+func (c *Checker) worker(name string) *WorkerStatus {
+	status, ok := c.workers[name]
+	if !ok {
+		status = &WorkerStatus{}
+		c.workers[name] = status
+	}
+	return status
+}
@@ -0,0 +1,76 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckReadinessReportsFailedComponents(t *testing.T) {
+	c := NewChecker()
+	c.Register("postgres", time.Second, func(ctx context.Context) error { return nil })
+	c.Register("redis", time.Second, func(ctx context.Context) error { return errors.New("connection refused") })
+
+	report := c.CheckReadiness(context.Background())
+
+	assert.False(t, report.Healthy)
+	assert.Equal(t, StatusUp, report.Components["postgres"].Status)
+	assert.Equal(t, StatusDown, report.Components["redis"].Status)
+	assert.Equal(t, "connection refused", report.Components["redis"].Error)
+}
+
+func TestCheckReadinessAppliesTimeout(t *testing.T) {
+	c := NewChecker()
+	c.Register("kafka", 10*time.Millisecond, func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	report := c.CheckReadiness(context.Background())
+
+	assert.False(t, report.Healthy)
+	assert.Equal(t, StatusDown, report.Components["kafka"].Status)
+}
+
+func TestStuckWorkers(t *testing.T) {
+	c := NewChecker()
+	c.BeginWork("order-worker")
+	c.BeginWork("payment-worker")
+	c.EndWork("payment-worker")
+
+	time.Sleep(5 * time.Millisecond)
+
+	assert.Equal(t, []string{"order-worker"}, c.StuckWorkers(time.Millisecond))
+	assert.Empty(t, c.StuckWorkers(time.Hour))
+}
+
+func TestWorkersReportRunsAndLastMessage(t *testing.T) {
+	c := NewChecker()
+	stop := make(chan struct{})
+	done := make(chan error)
+	go func() {
+		done <- c.RunWorker("order-worker", func() error {
+			<-stop
+			return errors.New("reader closed")
+		})
+	}()
+
+	assert.Eventually(t, func() bool { return c.Workers()["order-worker"].Running }, time.Second, time.Millisecond)
+	c.RecordWork("order-worker", 2, 41, nil)
+	c.BeginWork("order-worker")
+	c.RecordWork("order-worker", 2, 42, errors.New("saga failed"))
+
+	status := c.Workers()["order-worker"]
+	assert.Equal(t, int64(42), status.LastOffset)
+	assert.Equal(t, "saga failed", status.LastError)
+	assert.NotNil(t, status.InFlightSince)
+
+	close(stop)
+	assert.EqualError(t, <-done, "reader closed")
+	status = c.Workers()["order-worker"]
+	assert.False(t, status.Running)
+	assert.Equal(t, "reader closed", status.LastError)
+}
@@ -12,6 +12,36 @@ const (
 	EventTypeOrderFailed    = "ORDER_FAILED"
 	EventTypePaymentSuccess = "PAYMENT_SUCCESS"
 	EventTypePaymentFailed  = "PAYMENT_FAILED"
+	EventTypePaymentVoided  = "PAYMENT_VOIDED"
+	EventTypeOrderOnHold    = "ORDER_ON_HOLD"
+
+	// Published when a customer cancels a paid order after its free-cancel window
+	EventTypePaymentRefunded = "PAYMENT_REFUNDED"
+
+	// Published by the notify bridge for every committed status change
+	EventTypeOrderStatusChanged = "ORDER_STATUS_CHANGED"
+
+	// Scheduled reminders, published when due only if the order is still awaiting payment
+	EventTypePaymentReminder     = "PAYMENT_REMINDER"
+	EventTypeReservationExpiring = "RESERVATION_EXPIRING"
+
+	// Items ordered while out of stock, their new ship date when incoming stock
+	// changes, and their reservation once restocked
+	EventTypeOrderBackordered     = "ORDER_BACKORDERED"
+	EventTypeBackorderRescheduled = "BACKORDER_RESCHEDULED"
+	EventTypeBackorderFulfilled   = "BACKORDER_FULFILLED"
+
+	// Published when a customer changes the items of an order before paying it
+	EventTypeOrderAmended = "ORDER_AMENDED"
+
+	// Published when a commit leaves a variant at or below its reorder threshold
+	EventTypeInventoryLow = "INVENTORY_LOW"
+
+	// Identity events consumed for account closures, and the progress reported back
+	EventTypeUserUpdated                = "USER_UPDATED"
+	EventTypeUserDeleted                = "USER_DELETED"
+	EventTypeUserAnonymizationProgress  = "USER_ANONYMIZATION_PROGRESS"
+	EventTypeUserAnonymizationCompleted = "USER_ANONYMIZATION_COMPLETED"
 )
 
 // BaseEvent contains common fields for all events
@@ -28,15 +58,23 @@ type OrderCreatedEvent struct {
 	UserID      int64           `json:"user_id"`
 	TotalAmount int64           `json:"total_amount"`
 	Items       []OrderItemData `json:"items"`
+	Synthetic   bool            `json:"synthetic,omitempty"`
+	RiskScore   *int            `json:"risk_score,omitempty"`
+	RiskBand    string          `json:"risk_band,omitempty"`
+	// Billing is set for B2B orders, to be invoiced to the company
+	Billing *BillingDetails `json:"billing,omitempty"`
 }
 
 // OrderReservedEvent published when inventory is reserved
 type OrderReservedEvent struct {
 	BaseEvent
-	OrderID     int64           `json:"order_id"`
-	UserID      int64           `json:"user_id"`
-	TotalAmount int64           `json:"total_amount"`
-	Items       []OrderItemData `json:"items"`
+	OrderID     int64 `json:"order_id"`
+	UserID      int64 `json:"user_id"`
+	TotalAmount int64 `json:"total_amount"`
+	// WalletAmount of TotalAmount is paid from the user's wallet
+	WalletAmount int64           `json:"wallet_amount,omitempty"`
+	Items        []OrderItemData `json:"items"`
+	Synthetic    bool            `json:"synthetic,omitempty"`
 }
 
 // OrderPaidEvent published when payment succeeds
@@ -77,11 +115,196 @@ type PaymentFailedEvent struct {
 	OrderID   int64  `json:"order_id"`
 	PaymentID int64  `json:"payment_id"`
 	Reason    string `json:"reason"`
+	// DeclineClass is SOFT or HARD, and Attempts the payments declined
+	// including retries
+	DeclineClass string `json:"decline_class,omitempty"`
+	Attempts     int    `json:"attempts,omitempty"`
+}
+
+// PaymentVoidedEvent published when a captured payment is voided (compensation)
+type PaymentVoidedEvent struct {
+	BaseEvent
+	OrderID   int64  `json:"order_id"`
+	PaymentID int64  `json:"payment_id"`
+	Amount    int64  `json:"amount"`
+	TxID      string `json:"tx_id"`
+	Reason    string `json:"reason"`
+}
+
+// PaymentRefundedEvent published when a captured payment is refunded less a
+// late-cancel fee
+type PaymentRefundedEvent struct {
+	BaseEvent
+	OrderID   int64 `json:"order_id"`
+	PaymentID int64 `json:"payment_id"`
+	// Amount is what was given back, the captured amount less Fee
+	Amount int64  `json:"amount"`
+	Fee    int64  `json:"fee"`
+	Policy string `json:"policy"`
+	TxID   string `json:"tx_id"`
+	Reason string `json:"reason"`
+}
+
+// OrderOnHoldEvent published when a paid order needs manual intervention
+type OrderOnHoldEvent struct {
+	BaseEvent
+	OrderID int64  `json:"order_id"`
+	Reason  string `json:"reason"`
+	Error   string `json:"error,omitempty"`
+}
+
+// PaymentReminderEvent published a while after reservation if the order is still unpaid
+type PaymentReminderEvent struct {
+	BaseEvent
+	OrderID       int64     `json:"order_id"`
+	UserID        int64     `json:"user_id"`
+	PaymentMethod string    `json:"payment_method"`
+	ExpiresAt     time.Time `json:"expires_at"`
+}
+
+// ReservationExpiringEvent published shortly before an unpaid order's reservation expires
+type ReservationExpiringEvent struct {
+	BaseEvent
+	OrderID   int64     `json:"order_id"`
+	UserID    int64     `json:"user_id"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// OrderBackorderedEvent published when items of a new order are backordered
+type OrderBackorderedEvent struct {
+	BaseEvent
+	OrderID int64           `json:"order_id"`
+	UserID  int64           `json:"user_id"`
+	Items   []OrderItemData `json:"items"`
+	// ExpectedShipDate is when incoming stock covers every backordered item
+	// of the order, unset if it does not
+	ExpectedShipDate *time.Time `json:"expected_ship_date,omitempty"`
+}
+
+// OrderStatusChangedEvent published by the notify bridge when an order status
+// change commits. Its event ID is derived from HistoryID, so a change
+// forwarded twice has the same ID.
+type OrderStatusChangedEvent struct {
+	BaseEvent
+	OrderID   int64     `json:"order_id"`
+	HistoryID int64     `json:"history_id"`
+	OldStatus *string   `json:"old_status,omitempty"`
+	NewStatus string    `json:"new_status"`
+	Reason    string    `json:"reason"`
+	Actor     string    `json:"actor"`
+	ChangedAt time.Time `json:"changed_at"`
+}
+
+// BackorderRescheduledEvent published when changes to incoming stock move the
+// expected ship date of an order's backordered items
+type BackorderRescheduledEvent struct {
+	BaseEvent
+	OrderID int64 `json:"order_id"`
+	UserID  int64 `json:"user_id"`
+	// Items are the backordered items whose plan changed
+	Items            []OrderItemData `json:"items"`
+	ExpectedShipDate *time.Time      `json:"expected_ship_date,omitempty"`
+}
+
+// BackorderFulfilledEvent published when stock is reserved for backordered items
+type BackorderFulfilledEvent struct {
+	BaseEvent
+	OrderID int64           `json:"order_id"`
+	UserID  int64           `json:"user_id"`
+	Items   []OrderItemData `json:"items"`
+}
+
+// OrderAmendedEvent published when the items of an order awaiting payment are
+// changed. Items are the order's items after the change; Changes are the
+// quantities changed, zero for removed items.
+type OrderAmendedEvent struct {
+	BaseEvent
+	OrderID             int64           `json:"order_id"`
+	UserID              int64           `json:"user_id"`
+	PreviousTotalAmount int64           `json:"previous_total_amount"`
+	TotalAmount         int64           `json:"total_amount"`
+	WalletAmount        int64           `json:"wallet_amount,omitempty"`
+	Items               []OrderItemData `json:"items"`
+	Changes             []OrderItemData `json:"changes"`
+}
+
+// InventoryLowEvent published when committing an order leaves the available
+// stock of a variant, across warehouses, at or below its product's reorder
+// threshold. It is published once until the stock recovers above it.
+type InventoryLowEvent struct {
+	BaseEvent
+	ProductID        int64  `json:"product_id"`
+	VariantID        int64  `json:"variant_id"`
+	SKU              string `json:"sku,omitempty"`
+	Available        int    `json:"available"`
+	ReorderThreshold int    `json:"reorder_threshold"`
+	// OrderID is the order whose commit crossed the threshold
+	OrderID int64 `json:"order_id"`
+}
+
+// UserUpdatedEvent published by the identity service when an account is
+// created or its details or status change
+type UserUpdatedEvent struct {
+	BaseEvent
+	UserID int64  `json:"user_id"`
+	Email  string `json:"email"`
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	// Phone and WebhookURL are optional contacts for order notifications
+	Phone      string `json:"phone,omitempty"`
+	WebhookURL string `json:"webhook_url,omitempty"`
+}
+
+// UserDeletedEvent published by the identity service when an account is closed
+type UserDeletedEvent struct {
+	BaseEvent
+	UserID int64 `json:"user_id"`
+}
+
+// UserAnonymizationEvent reports progress or completion of a user's anonymization
+type UserAnonymizationEvent struct {
+	BaseEvent
+	UserID           int64 `json:"user_id"`
+	JobID            int64 `json:"job_id"`
+	OrdersTotal      int   `json:"orders_total"`
+	OrdersAnonymized int   `json:"orders_anonymized"`
+	OrdersDeleted    int   `json:"orders_deleted"`
 }
 
 // OrderItemData represents item data in events
 type OrderItemData struct {
 	ProductID int64 `json:"product_id"`
+	VariantID int64 `json:"variant_id,omitempty"`
 	Quantity  int   `json:"quantity"`
 	UnitPrice int64 `json:"unit_price"`
 }
+
+// Analytics event names, published to the analytics topic in place of the
+// internal events they are made from
+const (
+	AnalyticsOrderCreated  = "order_created"
+	AnalyticsOrderValue    = "order_value"
+	AnalyticsDeclineReason = "decline_reason"
+)
+
+// AnalyticsEvent is the slim event published for the data team: no user or
+// payment identifiers, only what order and decline analysis needs. Amounts are
+// in cents.
+type AnalyticsEvent struct {
+	SchemaVersion int       `json:"schema_version"`
+	EventID       string    `json:"event_id"`
+	Event         string    `json:"event"`
+	OccurredAt    time.Time `json:"occurred_at"`
+	OrderID       int64     `json:"order_id"`
+	// order_created and order_value
+	Amount int64 `json:"amount,omitempty"`
+	// order_created
+	ItemCount int    `json:"item_count,omitempty"`
+	Units     int    `json:"units,omitempty"`
+	RiskBand  string `json:"risk_band,omitempty"`
+	Synthetic bool   `json:"synthetic,omitempty"`
+	// decline_reason
+	Reason       string `json:"reason,omitempty"`
+	DeclineClass string `json:"decline_class,omitempty"`
+	Attempts     int    `json:"attempts,omitempty"`
+}
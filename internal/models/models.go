@@ -1,6 +1,9 @@
 package models
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 // Product represents a product in the catalog
 type Product struct {
@@ -9,25 +12,208 @@ type Product struct {
 	Name      string    `db:"name" json:"name"`
 	Price     int64     `db:"price" json:"price"`
 	CreatedAt time.Time `db:"created_at" json:"created_at"`
+
+	// ReservationStrategy selects how InventoryClient reserves the product's stock
+	ReservationStrategy string `db:"reservation_strategy" json:"reservation_strategy"`
+
+	// ReorderThreshold is the available units of a variant at or below which
+	// procurement is alerted to reorder; nil disables the alerts
+	ReorderThreshold *int `db:"reorder_threshold" json:"reorder_threshold,omitempty"`
+
+	// TenantID is the storefront selling the product; its variants and stock
+	// belong to the same one
+	TenantID string `db:"tenant_id" json:"-"`
+}
+
+// ProductVariant is a sellable version of a product, e.g. one size and color,
+// with its own SKU and stock
+type ProductVariant struct {
+	ID         int64           `db:"id" json:"id"`
+	ProductID  int64           `db:"product_id" json:"product_id"`
+	SKU        string          `db:"sku" json:"sku"`
+	Attributes json.RawMessage `db:"attributes" json:"attributes"`
+	IsDefault  bool            `db:"is_default" json:"is_default"`
+	CreatedAt  time.Time       `db:"created_at" json:"created_at"`
+}
+
+// DefaultWarehouseID is the warehouse stock is kept in unless placed elsewhere
+const DefaultWarehouseID = 1
+
+// Warehouse is a site stock is kept in and order items ship from
+type Warehouse struct {
+	ID        int64     `db:"id" json:"id"`
+	Code      string    `db:"code" json:"code"`
+	Name      string    `db:"name" json:"name"`
+	Latitude  float64   `db:"latitude" json:"latitude"`
+	Longitude float64   `db:"longitude" json:"longitude"`
+	Active    bool      `db:"active" json:"active"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
 }
 
-// Inventory represents product stock
+// GeoPoint is a location in decimal degrees
+type GeoPoint struct {
+	Latitude  float64 `json:"latitude" binding:"min=-90,max=90"`
+	Longitude float64 `json:"longitude" binding:"min=-180,max=180"`
+}
+
+// Inventory represents the stock of a product variant in a warehouse. Totals
+// across warehouses leave WarehouseID zero.
 type Inventory struct {
-	ProductID int64     `db:"product_id" json:"product_id"`
-	Available int       `db:"available" json:"available"`
-	Reserved  int       `db:"reserved" json:"reserved"`
-	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
+	ProductID   int64     `db:"product_id" json:"product_id"`
+	VariantID   int64     `db:"variant_id" json:"variant_id"`
+	WarehouseID int64     `db:"warehouse_id" json:"warehouse_id,omitempty"`
+	Available   int       `db:"available" json:"available"`
+	Reserved    int       `db:"reserved" json:"reserved"`
+	UpdatedAt   time.Time `db:"updated_at" json:"updated_at"`
+}
+
+// LowStockItem is a product variant whose available stock across warehouses
+// is at or below its product's reorder threshold
+type LowStockItem struct {
+	ProductID        int64  `db:"product_id" json:"product_id"`
+	VariantID        int64  `db:"variant_id" json:"variant_id"`
+	Name             string `db:"name" json:"name"`
+	SKU              string `db:"sku" json:"sku"`
+	Available        int    `db:"available" json:"available"`
+	Reserved         int    `db:"reserved" json:"reserved"`
+	ReorderThreshold int    `db:"reorder_threshold" json:"reorder_threshold"`
+}
+
+// InventoryImportRow is one product variant and its available stock in a
+// bulk inventory import. Without a VariantSKU the row sets the stock of the
+// product's default variant, and without a Warehouse code its stock in the
+// default warehouse.
+type InventoryImportRow struct {
+	// Line is the row's position in the import, for error reports
+	Line       int             `json:"-"`
+	SKU        string          `json:"sku"`
+	Name       string          `json:"name"`
+	Price      int64           `json:"price"`
+	VariantSKU string          `json:"variant_sku,omitempty"`
+	Attributes json.RawMessage `json:"attributes,omitempty"`
+	Warehouse  string          `json:"warehouse,omitempty"`
+	Available  int             `json:"available"`
 }
 
 // Order represents a customer order
 type Order struct {
-	ID             int64     `db:"id" json:"id"`
-	UserID         int64     `db:"user_id" json:"user_id"`
-	TotalAmount    int64     `db:"total_amount" json:"total_amount"`
-	Status         string    `db:"status" json:"status"`
-	IdempotencyKey string    `db:"idempotency_key" json:"idempotency_key,omitempty"`
-	CreatedAt      time.Time `db:"created_at" json:"created_at"`
-	UpdatedAt      time.Time `db:"updated_at" json:"updated_at"`
+	ID                 int64      `db:"id" json:"id"`
+	UserID             int64      `db:"user_id" json:"user_id"`
+	TotalAmount        int64      `db:"total_amount" json:"total_amount"`
+	Status             string     `db:"status" json:"status"`
+	IdempotencyKey     string     `db:"idempotency_key" json:"idempotency_key,omitempty"`
+	FenceToken         int64      `db:"fence_token" json:"-"`
+	PaymentMethod      string     `db:"payment_method" json:"payment_method"`
+	ExpiresAt          *time.Time `db:"expires_at" json:"expires_at,omitempty"`
+	Synthetic          bool       `db:"synthetic" json:"synthetic,omitempty"`
+	PriceListID        *int64     `db:"price_list_id" json:"price_list_id,omitempty"`
+	RiskScore          *int       `db:"risk_score" json:"risk_score,omitempty"`
+	RiskBand           *string    `db:"risk_band" json:"risk_band,omitempty"`
+	ReservedAt         *time.Time `db:"reserved_at" json:"reserved_at,omitempty"`
+	PaidAt             *time.Time `db:"paid_at" json:"paid_at,omitempty"`
+	ConfirmedAt        *time.Time `db:"confirmed_at" json:"confirmed_at,omitempty"`
+	SLABreached        bool       `db:"sla_breached" json:"-"`
+	AnonymizedAt       *time.Time `db:"anonymized_at" json:"anonymized_at,omitempty"`
+	RecoveryAttempts   int        `db:"recovery_attempts" json:"-"`
+	LastRecoveryAt     *time.Time `db:"last_recovery_at" json:"-"`
+	CancellationPolicy *string    `db:"cancellation_policy" json:"cancellation_policy,omitempty"`
+	CancellationFee    *int64     `db:"cancellation_fee" json:"cancellation_fee,omitempty"`
+	BillingCompany     *string    `db:"billing_company" json:"billing_company,omitempty"`
+	BillingCountry     *string    `db:"billing_country" json:"billing_country,omitempty"`
+	TaxID              *string    `db:"tax_id" json:"-"`
+	WalletAmount       int64      `db:"wallet_amount" json:"wallet_amount,omitempty"`
+	CustomerEmail      *string    `db:"customer_email" json:"-"`
+	CustomerName       *string    `db:"customer_name" json:"-"`
+	Priority           string     `db:"priority" json:"priority"`
+	InvoiceURL         *string    `db:"invoice_url" json:"-"`
+	InvoiceNumber      *string    `db:"invoice_number" json:"invoice_number,omitempty"`
+	InvoicedAt         *time.Time `db:"invoiced_at" json:"invoiced_at,omitempty"`
+	TenantID           string     `db:"tenant_id" json:"-"`
+	CreatedAt          time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt          time.Time  `db:"updated_at" json:"updated_at"`
+}
+
+// BillingDetails identify the company a B2B order is invoiced to. Country is
+// an ISO 3166-1 alpha-2 code and TaxID the company's tax identifier there,
+// e.g. its VAT number or NPWP.
+type BillingDetails struct {
+	Company string `json:"company" binding:"required"`
+	Country string `json:"country" binding:"required"`
+	TaxID   string `json:"tax_id" binding:"required"`
+}
+
+// Billing returns the billing details of a B2B order, or nil
+func (o *Order) Billing() *BillingDetails {
+	if o.TaxID == nil {
+		return nil
+	}
+	billing := &BillingDetails{TaxID: *o.TaxID}
+	if o.BillingCompany != nil {
+		billing.Company = *o.BillingCompany
+	}
+	if o.BillingCountry != nil {
+		billing.Country = *o.BillingCountry
+	}
+	return billing
+}
+
+// OrderFilter selects orders for the admin order list
+type OrderFilter struct {
+	RiskBand string
+	Since    time.Time
+	Until    time.Time
+	Limit    int
+}
+
+// OrderSummary is the read model of an order the list and search endpoints
+// serve, projected from the order and payment events
+type OrderSummary struct {
+	ID            int64  `db:"order_id" json:"id"`
+	UserID        int64  `db:"user_id" json:"user_id"`
+	Status        string `db:"status" json:"status"`
+	ItemCount     int    `db:"item_count" json:"item_count"`
+	TotalQuantity int    `db:"total_quantity" json:"total_quantity"`
+	TotalAmount   int64  `db:"total_amount" json:"total_amount"`
+	WalletAmount  int64  `db:"wallet_amount" json:"wallet_amount,omitempty"`
+	PaymentMethod string `db:"payment_method" json:"payment_method"`
+	// PaymentStatus is the status of the latest payment, nil before one is made
+	PaymentStatus  *string `db:"payment_status" json:"payment_status,omitempty"`
+	RefundedAmount int64   `db:"refunded_amount" json:"refunded_amount,omitempty"`
+	RiskScore      *int    `db:"risk_score" json:"risk_score,omitempty"`
+	RiskBand       *string `db:"risk_band" json:"risk_band,omitempty"`
+	// LastEventType is the newest event projected, nil for backfilled summaries
+	LastEventType *string   `db:"last_event_type" json:"last_event_type,omitempty"`
+	LastEventAt   time.Time `db:"last_event_at" json:"last_event_at"`
+	TenantID      string    `db:"tenant_id" json:"-"`
+	CreatedAt     time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt     time.Time `db:"updated_at" json:"updated_at"`
+}
+
+// OrderSearchResult is an order found by a support search
+type OrderSearchResult struct {
+	OrderSummary
+	// Score ranks results by relevance, 1 for an exact match
+	Score float64 `json:"score"`
+	// MatchedOn lists the fields the query matched, e.g. idempotency_key or product
+	MatchedOn []string `json:"matched_on"`
+}
+
+// PriceList is a customer-group specific set of contract prices
+type PriceList struct {
+	ID            int64      `db:"id" json:"id"`
+	Name          string     `db:"name" json:"name"`
+	CustomerGroup string     `db:"customer_group" json:"customer_group"`
+	Active        bool       `db:"active" json:"active"`
+	ValidFrom     *time.Time `db:"valid_from" json:"valid_from,omitempty"`
+	ValidTo       *time.Time `db:"valid_to" json:"valid_to,omitempty"`
+	CreatedAt     time.Time  `db:"created_at" json:"created_at"`
+}
+
+// PriceListItem is the contract price of a product in a price list
+type PriceListItem struct {
+	PriceListID int64 `db:"price_list_id" json:"price_list_id"`
+	ProductID   int64 `db:"product_id" json:"product_id"`
+	Price       int64 `db:"price" json:"price"`
 }
 
 // OrderItem represents items in an order
@@ -35,19 +221,451 @@ type OrderItem struct {
 	ID        int64 `db:"id" json:"id"`
 	OrderID   int64 `db:"order_id" json:"order_id"`
 	ProductID int64 `db:"product_id" json:"product_id"`
+	VariantID int64 `db:"variant_id" json:"variant_id"`
 	Quantity  int   `db:"quantity" json:"quantity"`
 	UnitPrice int64 `db:"unit_price" json:"unit_price"`
+
+	// FulfillmentStatus is BACKORDERED while no stock is reserved for the item
+	FulfillmentStatus string `db:"fulfillment_status" json:"fulfillment_status"`
+
+	// WarehouseID is the warehouse the item's stock was allocated from, which
+	// it ships from
+	WarehouseID *int64 `db:"warehouse_id" json:"warehouse_id,omitempty"`
+
+	// StockCommittedAt marks that this item's reserved stock has been deducted
+	StockCommittedAt *time.Time `db:"stock_committed_at" json:"-"`
+
+	// IncomingStockID is the incoming stock a backordered item is planned
+	// against, which ships it on ExpectedShipDate
+	IncomingStockID  *int64     `db:"incoming_stock_id" json:"incoming_stock_id,omitempty"`
+	ExpectedShipDate *time.Time `db:"expected_ship_date" json:"expected_ship_date,omitempty"`
+}
+
+// OrderWithItems is an order with its items, as fetched in bulk
+type OrderWithItems struct {
+	Order Order       `json:"order"`
+	Items []OrderItem `json:"items"`
+}
+
+// SkippedOrderItem is an item left out of an order created with allow_partial
+type SkippedOrderItem struct {
+	ID        int64     `db:"id" json:"-"`
+	OrderID   int64     `db:"order_id" json:"-"`
+	ProductID int64     `db:"product_id" json:"product_id"`
+	VariantID int64     `db:"variant_id" json:"variant_id"`
+	Quantity  int       `db:"quantity" json:"quantity"`
+	Reason    string    `db:"reason" json:"reason"`
+	CreatedAt time.Time `db:"created_at" json:"-"`
 }
 
-// Payment represents a payment transaction
+// Reservation is the stock held for one product variant of an order. Its status makes
+// reserving, releasing and committing the stock idempotent.
+type Reservation struct {
+	OrderID   int64     `db:"order_id" json:"order_id"`
+	ProductID int64     `db:"product_id" json:"product_id"`
+	VariantID int64     `db:"variant_id" json:"variant_id"`
+	Quantity  int       `db:"quantity" json:"quantity"`
+	Status    string    `db:"status" json:"status"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
+
+	// WarehouseID is the warehouse the units are held in, nil until allocated
+	WarehouseID *int64 `db:"warehouse_id" json:"warehouse_id,omitempty"`
+}
+
+// Payment represents a payment transaction. WalletAmount is the part of Amount
+// settled from the user's wallet; the provider charges the rest.
 type Payment struct {
+	ID             int64     `db:"id" json:"id"`
+	OrderID        int64     `db:"order_id" json:"order_id"`
+	Status         string    `db:"status" json:"status"`
+	ProviderTxID   string    `db:"provider_tx_id" json:"provider_tx_id,omitempty"`
+	Amount         int64     `db:"amount" json:"amount"`
+	RefundedAmount int64     `db:"refunded_amount" json:"refunded_amount"`
+	WalletAmount   int64     `db:"wallet_amount" json:"wallet_amount"`
+	CreatedAt      time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt      time.Time `db:"updated_at" json:"updated_at"`
+}
+
+// Wallet is the store credit of a user. Version increments with every
+// balance change and guards concurrent updates.
+type Wallet struct {
+	UserID    int64     `db:"user_id" json:"user_id"`
+	Balance   int64     `db:"balance" json:"balance"`
+	Version   int64     `db:"version" json:"version"`
+	TenantID  string    `db:"tenant_id" json:"-"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
+}
+
+// Customer is the order service's projection of an identity service user
+type Customer struct {
+	UserID int64  `db:"user_id" json:"user_id"`
+	Email  string `db:"email" json:"email"`
+	Name   string `db:"name" json:"name"`
+	Status string `db:"status" json:"status"`
+	// Phone and WebhookURL are where order notifications go besides the
+	// email, empty if the customer has none
+	Phone      string `db:"phone" json:"phone,omitempty"`
+	WebhookURL string `db:"webhook_url" json:"webhook_url,omitempty"`
+	// SourceUpdatedAt is the timestamp of the event the customer was last
+	// updated from
+	SourceUpdatedAt time.Time `db:"source_updated_at" json:"source_updated_at"`
+	TenantID        string    `db:"tenant_id" json:"-"`
+	CreatedAt       time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt       time.Time `db:"updated_at" json:"updated_at"`
+}
+
+// WalletTransaction is a debit or credit of a wallet
+type WalletTransaction struct {
+	ID           int64  `db:"id" json:"id"`
+	UserID       int64  `db:"user_id" json:"user_id"`
+	Kind         string `db:"kind" json:"kind"`
+	Amount       int64  `db:"amount" json:"amount"`
+	BalanceAfter int64  `db:"balance_after" json:"balance_after"`
+	// PaymentID is the payment settled or refunded, unset for manual credits
+	PaymentID *int64    `db:"payment_id" json:"payment_id,omitempty"`
+	Reason    string    `db:"reason" json:"reason"`
+	Actor     string    `db:"actor" json:"actor"`
+	TenantID  string    `db:"tenant_id" json:"-"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+}
+
+// PaymentAttempt is a declined payment of an order and whether it is retried
+type PaymentAttempt struct {
+	ID            int64  `db:"id" json:"id"`
+	OrderID       int64  `db:"order_id" json:"order_id"`
+	PaymentID     int64  `db:"payment_id" json:"payment_id"`
+	Attempt       int    `db:"attempt" json:"attempt"`
+	Amount        int64  `db:"amount" json:"amount"`
+	DeclineReason string `db:"decline_reason" json:"decline_reason"`
+	DeclineClass  string `db:"decline_class" json:"decline_class"`
+	Status        string `db:"status" json:"status"`
+	// UserID and WalletAmount of the order, set on claimed retries
+	UserID       int64 `db:"user_id" json:"-"`
+	WalletAmount int64 `db:"wallet_amount" json:"-"`
+	// RetryAt is when a scheduled retry falls due
+	RetryAt   *time.Time `db:"retry_at" json:"retry_at,omitempty"`
+	CreatedAt time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt time.Time  `db:"updated_at" json:"updated_at"`
+}
+
+// Compensation is a saga compensation step that failed and is retried in the
+// background until it succeeds or is escalated for manual resolution
+type Compensation struct {
+	ID      int64  `db:"id" json:"id"`
+	OrderID int64  `db:"order_id" json:"order_id"`
+	Kind    string `db:"kind" json:"kind"`
+	// VariantID is set on stock compensations, PaymentID and Amount on refunds
+	VariantID     int64     `db:"variant_id" json:"variant_id,omitempty"`
+	PaymentID     int64     `db:"payment_id" json:"payment_id,omitempty"`
+	Amount        int64     `db:"amount" json:"amount,omitempty"`
+	Reason        string    `db:"reason" json:"reason"`
+	Priority      int       `db:"priority" json:"priority"`
+	Status        string    `db:"status" json:"status"`
+	Attempts      int       `db:"attempts" json:"attempts"`
+	NextAttemptAt time.Time `db:"next_attempt_at" json:"next_attempt_at"`
+	LastError     string    `db:"last_error" json:"last_error,omitempty"`
+	ResolvedBy    *string   `db:"resolved_by" json:"resolved_by,omitempty"`
+	Resolution    *string   `db:"resolution" json:"resolution,omitempty"`
+	CreatedAt     time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt     time.Time `db:"updated_at" json:"updated_at"`
+}
+
+// AuditEntry records one admin mutation
+type AuditEntry struct {
+	ID int64 `db:"id" json:"id"`
+	// Actor is the caller's role and a fingerprint of its token, e.g.
+	// admin:support:1a2b3c4d
+	Actor  string `db:"actor" json:"actor"`
+	Action string `db:"action" json:"action"`
+	// Target names what was acted on, e.g. order:42, empty for actions on
+	// nothing in particular
+	Target    string `db:"target" json:"target,omitempty"`
+	RequestID string `db:"request_id" json:"request_id,omitempty"`
+	// Status is the HTTP status the mutation was answered with
+	Status int `db:"status" json:"status"`
+	// Before and After are the target as it was and became, where it can be
+	// loaded, else After is the response. Diff maps each changed top-level
+	// field to its from and to values.
+	Before    *json.RawMessage `db:"before" json:"before,omitempty"`
+	After     *json.RawMessage `db:"after" json:"after,omitempty"`
+	Diff      *json.RawMessage `db:"diff" json:"diff,omitempty"`
+	CreatedAt time.Time        `db:"created_at" json:"created_at"`
+}
+
+// AuditFilter selects audit log entries, newest first
+type AuditFilter struct {
+	Actor  string
+	Action string
+	From   time.Time
+	Until  time.Time
+	Limit  int
+}
+
+// Notification is a message about an order sent to its customer on one
+// channel, retried until it is delivered or runs out of attempts
+type Notification struct {
+	ID      int64  `db:"id" json:"id"`
+	OrderID int64  `db:"order_id" json:"order_id"`
+	EventID string `db:"event_id" json:"event_id"`
+	// Kind is the type of the order event notified, e.g. ORDER_CONFIRMED
+	Kind          string     `db:"kind" json:"kind"`
+	Channel       string     `db:"channel" json:"channel"`
+	Recipient     string     `db:"recipient" json:"recipient"`
+	Subject       string     `db:"subject" json:"subject,omitempty"`
+	Body          string     `db:"body" json:"body"`
+	Status        string     `db:"status" json:"status"`
+	Attempts      int        `db:"attempts" json:"attempts"`
+	NextAttemptAt time.Time  `db:"next_attempt_at" json:"next_attempt_at"`
+	LastError     string     `db:"last_error" json:"last_error,omitempty"`
+	SentAt        *time.Time `db:"sent_at" json:"sent_at,omitempty"`
+	CreatedAt     time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt     time.Time  `db:"updated_at" json:"updated_at"`
+}
+
+// WebhookSubscription is a third-party endpoint subscribed to order events
+type WebhookSubscription struct {
+	ID         int64    `db:"id" json:"id"`
+	URL        string   `db:"url" json:"url"`
+	EventTypes []string `db:"-" json:"event_types"`
+	// Secret signs the payloads; it is only returned when the subscription is
+	// created
+	Secret      string `db:"secret" json:"secret,omitempty"`
+	Description string `db:"description" json:"description,omitempty"`
+	Status      string `db:"status" json:"status"`
+	// ConsecutiveFailures counts the failed delivery attempts since the last
+	// successful one; the subscription is disabled when it reaches the limit
+	ConsecutiveFailures int        `db:"consecutive_failures" json:"consecutive_failures"`
+	DisabledReason      *string    `db:"disabled_reason" json:"disabled_reason,omitempty"`
+	DisabledAt          *time.Time `db:"disabled_at" json:"disabled_at,omitempty"`
+	CreatedBy           string     `db:"created_by" json:"created_by"`
+	TenantID            string     `db:"tenant_id" json:"-"`
+	CreatedAt           time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt           time.Time  `db:"updated_at" json:"updated_at"`
+}
+
+// Subscribes reports whether the subscription wants events of a type
+func (s *WebhookSubscription) Subscribes(eventType string) bool {
+	for _, t := range s.EventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookDelivery is one event posted to one webhook subscription, retried
+// until it is delivered or runs out of attempts
+type WebhookDelivery struct {
+	ID             int64      `db:"id" json:"id"`
+	SubscriptionID int64      `db:"subscription_id" json:"subscription_id"`
+	EventID        string     `db:"event_id" json:"event_id"`
+	EventType      string     `db:"event_type" json:"event_type"`
+	Payload        string     `db:"payload" json:"-"`
+	Status         string     `db:"status" json:"status"`
+	Attempts       int        `db:"attempts" json:"attempts"`
+	NextAttemptAt  time.Time  `db:"next_attempt_at" json:"next_attempt_at"`
+	LastStatusCode *int       `db:"last_status_code" json:"last_status_code,omitempty"`
+	LastError      string     `db:"last_error" json:"last_error,omitempty"`
+	LastAttemptAt  *time.Time `db:"last_attempt_at" json:"last_attempt_at,omitempty"`
+	DeliveredAt    *time.Time `db:"delivered_at" json:"delivered_at,omitempty"`
+	CreatedAt      time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt      time.Time  `db:"updated_at" json:"updated_at"`
+}
+
+// Cart is a shopping cart a user fills before checking it out into an order
+type Cart struct {
+	ID     string `db:"id" json:"id"`
+	UserID int64  `db:"user_id" json:"user_id"`
+	Status string `db:"status" json:"status"`
+	// OrderID is the order the cart was checked out into
+	OrderID   *int64     `db:"order_id" json:"order_id,omitempty"`
+	Items     []CartItem `db:"-" json:"items"`
+	ExpiresAt time.Time  `db:"expires_at" json:"expires_at"`
+	TenantID  string     `db:"tenant_id" json:"-"`
+	CreatedAt time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt time.Time  `db:"updated_at" json:"updated_at"`
+}
+
+// CartItem is a variant in a cart
+type CartItem struct {
+	CartID    string    `db:"cart_id" json:"-"`
+	ProductID int64     `db:"product_id" json:"product_id"`
+	VariantID int64     `db:"variant_id" json:"variant_id"`
+	Quantity  int       `db:"quantity" json:"quantity"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
+}
+
+// CompensationFilter selects compensations; zero fields match everything
+type CompensationFilter struct {
+	Status  string
+	OrderID int64
+	Limit   int
+}
+
+// StatusChange describes why and by whom an order status was changed
+type StatusChange struct {
+	Reason  string
+	Actor   string
+	EventID string
+	// Forced skips the check that the order may move to the new status, for
+	// operators repairing an order the saga cannot move
+	Forced bool
+}
+
+// Actors recorded in the order status history
+const (
+	ActorOrderService   = "order-service"
+	ActorSaga           = "saga"
+	ActorTimeoutReaper  = "timeout-reaper"
+	ActorSagaRecovery   = "saga-recovery"
+	ActorAdmin          = "admin"
+	ActorCustomer       = "customer"
+	ActorPaymentService = "payment-service"
+	ActorCompensation   = "compensation-retrier"
+)
+
+// OrderStatusHistory is one entry of an order's status audit log
+type OrderStatusHistory struct {
+	ID        int64     `db:"id" json:"id"`
+	OrderID   int64     `db:"order_id" json:"order_id"`
+	OldStatus *string   `db:"old_status" json:"old_status"`
+	NewStatus string    `db:"new_status" json:"new_status"`
+	Reason    string    `db:"reason" json:"reason"`
+	Actor     string    `db:"actor" json:"actor"`
+	EventID   *string   `db:"event_id" json:"event_id,omitempty"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+	// TxID is the transaction that recorded the change, unset for changes
+	// recorded before the notify bridge existed
+	TxID *int64 `db:"txid" json:"-"`
+}
+
+// OrderTimelineEntry is one event in an order's support timeline
+type OrderTimelineEntry struct {
+	ID         int64     `db:"id" json:"-"`
+	OrderID    int64     `db:"order_id" json:"-"`
+	EventID    string    `db:"event_id" json:"event_id"`
+	EventType  string    `db:"event_type" json:"event_type"`
+	Direction  string    `db:"direction" json:"direction"`
+	Summary    string    `db:"summary" json:"summary"`
+	OccurredAt time.Time `db:"occurred_at" json:"occurred_at"`
+	CreatedAt  time.Time `db:"created_at" json:"-"`
+}
+
+// AnonymousUserID replaces the user ID of anonymized orders
+const AnonymousUserID int64 = 0
+
+// AnonymizationJob tracks the anonymization of a deleted user's order history
+type AnonymizationJob struct {
+	ID               int64      `db:"id" json:"id"`
+	EventID          string     `db:"event_id" json:"event_id"`
+	UserID           int64      `db:"user_id" json:"user_id"`
+	Status           string     `db:"status" json:"status"`
+	OrdersTotal      int        `db:"orders_total" json:"orders_total"`
+	OrdersAnonymized int        `db:"orders_anonymized" json:"orders_anonymized"`
+	OrdersDeleted    int        `db:"orders_deleted" json:"orders_deleted"`
+	CreatedAt        time.Time  `db:"created_at" json:"created_at"`
+	CompletedAt      *time.Time `db:"completed_at" json:"completed_at,omitempty"`
+}
+
+// Anonymization job statuses
+const (
+	AnonymizationStatusRunning   = "RUNNING"
+	AnonymizationStatusCompleted = "COMPLETED"
+)
+
+// DeadLetter is a consumed message whose handler failed
+type DeadLetter struct {
+	ID            int64     `db:"id" json:"id"`
+	ConsumerGroup string    `db:"consumer_group" json:"consumer_group"`
+	Topic         string    `db:"topic" json:"topic"`
+	Partition     int       `db:"message_partition" json:"partition"`
+	Offset        int64     `db:"message_offset" json:"offset"`
+	Key           string    `db:"message_key" json:"key"`
+	Payload       string    `db:"payload" json:"payload"`
+	Error         string    `db:"error" json:"error"`
+	CreatedAt     time.Time `db:"created_at" json:"created_at"`
+
+	// RedrivenAt is when the message was re-published to its topic
+	RedrivenAt *time.Time `db:"redriven_at" json:"redriven_at,omitempty"`
+	// RedriveOf is the dead letter this message was re-driven from, if any
+	RedriveOf *int64 `db:"redrive_of" json:"redrive_of,omitempty"`
+	// RedriveAttempt counts the re-drives that led to this message
+	RedriveAttempt int `db:"redrive_attempt" json:"redrive_attempt"`
+}
+
+// DeadLetterFilter selects dead letters to list or re-drive; zero fields match all
+type DeadLetterFilter struct {
+	IDs           []int64
+	ConsumerGroup string
+	Topic         string
+	// Error matches letters whose handler error contains it
+	Error   string
+	Since   time.Time
+	Until   time.Time
+	Pending bool
+	Limit   int
+}
+
+// DeadLetterRedrive records a dead letter re-published to its topic
+type DeadLetterRedrive struct {
 	ID           int64     `db:"id" json:"id"`
-	OrderID      int64     `db:"order_id" json:"order_id"`
-	Status       string    `db:"status" json:"status"`
-	ProviderTxID string    `db:"provider_tx_id" json:"provider_tx_id,omitempty"`
-	Amount       int64     `db:"amount" json:"amount"`
+	DeadLetterID int64     `db:"dead_letter_id" json:"dead_letter_id"`
+	Attempt      int       `db:"attempt" json:"attempt"`
+	Actor        string    `db:"actor" json:"actor"`
+	Transformed  bool      `db:"transformed" json:"transformed"`
+	Payload      string    `db:"payload" json:"payload"`
 	CreatedAt    time.Time `db:"created_at" json:"created_at"`
-	UpdatedAt    time.Time `db:"updated_at" json:"updated_at"`
+}
+
+// Drop is a scheduled product drop. Users register before StartsAt and their
+// registrations are converted into orders once it starts.
+type Drop struct {
+	ID                 int64      `db:"id" json:"id"`
+	ProductID          int64      `db:"product_id" json:"product_id"`
+	VariantID          int64      `db:"variant_id" json:"variant_id"`
+	Name               string     `db:"name" json:"name"`
+	StartsAt           time.Time  `db:"starts_at" json:"starts_at"`
+	Policy             string     `db:"policy" json:"policy"`
+	MaxQuantityPerUser int        `db:"max_quantity_per_user" json:"max_quantity_per_user"`
+	Status             string     `db:"status" json:"status"`
+	Seed               *int64     `db:"seed" json:"-"`
+	ClaimedAt          *time.Time `db:"claimed_at" json:"-"`
+	TenantID           string     `db:"tenant_id" json:"-"`
+	CreatedAt          time.Time  `db:"created_at" json:"created_at"`
+	CompletedAt        *time.Time `db:"completed_at" json:"completed_at,omitempty"`
+}
+
+// DropRegistration is a user's intent to order a drop
+type DropRegistration struct {
+	ID            int64      `db:"id" json:"id"`
+	DropID        int64      `db:"drop_id" json:"drop_id"`
+	UserID        int64      `db:"user_id" json:"user_id"`
+	Quantity      int        `db:"quantity" json:"quantity"`
+	PaymentMethod string     `db:"payment_method" json:"payment_method"`
+	Status        string     `db:"status" json:"status"`
+	OrderID       *int64     `db:"order_id" json:"order_id,omitempty"`
+	Error         string     `db:"error" json:"error,omitempty"`
+	TenantID      string     `db:"tenant_id" json:"-"`
+	CreatedAt     time.Time  `db:"created_at" json:"created_at"`
+	ProcessedAt   *time.Time `db:"processed_at" json:"processed_at,omitempty"`
+}
+
+// IncomingStock is a purchase order of a variant expected to arrive on ETA.
+// Backordered items are planned against it until it is received.
+type IncomingStock struct {
+	ID        int64     `db:"id" json:"id"`
+	VariantID int64     `db:"variant_id" json:"variant_id"`
+	Reference string    `db:"reference" json:"reference"`
+	Quantity  int       `db:"quantity" json:"quantity"`
+	ETA       time.Time `db:"eta" json:"eta"`
+	Status    string    `db:"status" json:"status"`
+	// Allocated is the quantity of backordered items planned against it
+	Allocated int       `db:"allocated" json:"allocated"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
 }
 
 // Order statuses
@@ -58,15 +676,201 @@ const (
 	OrderStatusConfirmed = "CONFIRMED"
 	OrderStatusCancelled = "CANCELLED"
 	OrderStatusFailed    = "FAILED"
+	OrderStatusOnHold    = "ON_HOLD"
+)
+
+// IsOrderStatus reports whether status is a known order status
+func IsOrderStatus(status string) bool {
+	switch status {
+	case OrderStatusCreated, OrderStatusReserved, OrderStatusPaid, OrderStatusConfirmed,
+		OrderStatusCancelled, OrderStatusFailed, OrderStatusOnHold:
+		return true
+	}
+	return false
+}
+
+// orderTransitions lists the statuses an order in each status may move to.
+// Cancelled and failed orders move nowhere, so e.g. a payment landing after
+// the order was cancelled cannot confirm it.
+var orderTransitions = map[string][]string{
+	OrderStatusCreated:   {OrderStatusReserved, OrderStatusCancelled, OrderStatusFailed},
+	OrderStatusReserved:  {OrderStatusPaid, OrderStatusCancelled, OrderStatusFailed},
+	OrderStatusPaid:      {OrderStatusConfirmed, OrderStatusOnHold, OrderStatusCancelled},
+	OrderStatusOnHold:    {OrderStatusOnHold, OrderStatusConfirmed, OrderStatusCancelled},
+	OrderStatusConfirmed: {OrderStatusCancelled},
+}
+
+// CanTransition reports whether an order in status from may move to status to
+func CanTransition(from, to string) bool {
+	for _, status := range orderTransitions[from] {
+		if status == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Order priorities: express orders are processed ahead of the backlog of
+// standard ones
+const (
+	PriorityStandard = "standard"
+	PriorityExpress  = "express"
+)
+
+// Order item fulfillment statuses
+const (
+	FulfillmentStatusAllocated   = "ALLOCATED"
+	FulfillmentStatusBackordered = "BACKORDERED"
+)
+
+// Drop fairness policies: registrations are converted in registration order or
+// in a random order drawn when the drop starts
+const (
+	DropPolicyFIFO   = "fifo"
+	DropPolicyRandom = "random"
+)
+
+// Drop statuses
+const (
+	DropStatusScheduled = "SCHEDULED"
+	DropStatusRunning   = "RUNNING"
+	DropStatusCompleted = "COMPLETED"
+)
+
+// Drop registration statuses
+const (
+	DropRegistrationRegistered = "REGISTERED"
+	DropRegistrationOrdered    = "ORDERED"
+	DropRegistrationSoldOut    = "SOLD_OUT"
+	DropRegistrationFailed     = "FAILED"
+)
+
+// Incoming stock statuses
+const (
+	IncomingStockOpen      = "OPEN"
+	IncomingStockReceived  = "RECEIVED"
+	IncomingStockCancelled = "CANCELLED"
+)
+
+// Reservation statuses
+const (
+	ReservationStatusHeld      = "HELD"
+	ReservationStatusReleased  = "RELEASED"
+	ReservationStatusCommitted = "COMMITTED"
 )
 
 // Payment statuses
 const (
-	PaymentStatusPending = "PENDING"
-	PaymentStatusSuccess = "SUCCESS"
-	PaymentStatusFailed  = "FAILED"
+	PaymentStatusPending  = "PENDING"
+	PaymentStatusSuccess  = "SUCCESS"
+	PaymentStatusFailed   = "FAILED"
+	PaymentStatusVoided   = "VOIDED"
+	PaymentStatusRefunded = "REFUNDED"
+)
+
+// Wallet transaction kinds
+const (
+	WalletDebit  = "DEBIT"
+	WalletCredit = "CREDIT"
+)
+
+// Customer statuses. Only active customers can place orders.
+const (
+	CustomerActive    = "ACTIVE"
+	CustomerSuspended = "SUSPENDED"
+	CustomerClosed    = "CLOSED"
+)
+
+// PaymentMethodWallet settles an order entirely from the user's wallet
+const PaymentMethodWallet = "wallet"
+
+// Decline classes: soft declines are transient provider failures worth
+// retrying, hard declines are final
+const (
+	DeclineClassSoft = "SOFT"
+	DeclineClassHard = "HARD"
+)
+
+// Payment attempt statuses
+const (
+	PaymentAttemptScheduled = "SCHEDULED"
+	PaymentAttemptRetried   = "RETRIED"
+	PaymentAttemptFinal     = "FINAL"
+	PaymentAttemptDropped   = "DROPPED"
 )
 
+// Compensation kinds, most urgent first
+const (
+	CompensationWalletRefund = "WALLET_REFUND"
+	CompensationVoidPayment  = "VOID_PAYMENT"
+	// CompensationCancellationRefund voids or refunds the payment of an order
+	// a customer cancelled
+	CompensationCancellationRefund = "CANCELLATION_REFUND"
+	CompensationCommitStock        = "COMMIT_STOCK"
+	CompensationReleaseStock       = "RELEASE_STOCK"
+)
+
+// Compensation statuses
+const (
+	CompensationPending   = "PENDING"
+	CompensationDone      = "DONE"
+	CompensationEscalated = "ESCALATED"
+	CompensationResolved  = "RESOLVED"
+)
+
+// Notification channels
+const (
+	NotificationEmail   = "email"
+	NotificationSMS     = "sms"
+	NotificationWebhook = "webhook"
+)
+
+// Notification statuses
+const (
+	NotificationPending = "PENDING"
+	NotificationSent    = "SENT"
+	NotificationFailed  = "FAILED"
+)
+
+// Cart statuses
+const (
+	CartOpen       = "OPEN"
+	CartCheckedOut = "CHECKED_OUT"
+)
+
+// Webhook subscription statuses
+const (
+	WebhookActive   = "ACTIVE"
+	WebhookDisabled = "DISABLED"
+)
+
+// Webhook delivery statuses
+const (
+	WebhookDeliveryPending   = "PENDING"
+	WebhookDeliveryDelivered = "DELIVERED"
+	WebhookDeliveryFailed    = "FAILED"
+)
+
+// OrderReportRow aggregates a UTC day of orders for the order report. Amounts
+// are in minor units; synthetic orders are left out.
+type OrderReportRow struct {
+	// Date is the day, YYYY-MM-DD
+	Date          string `db:"date" json:"date" parquet:"date"`
+	OrdersCreated int64  `db:"orders_created" json:"orders_created" parquet:"orders_created"`
+	// Sales are the orders paid on the day
+	OrdersPaid int64 `db:"orders_paid" json:"orders_paid" parquet:"orders_paid"`
+	GrossSales int64 `db:"gross_sales" json:"gross_sales" parquet:"gross_sales"`
+	UnitsSold  int64 `db:"units_sold" json:"units_sold" parquet:"units_sold"`
+	// Cancellations are the orders cancelled on the day, with the fees kept
+	OrdersCancelled  int64 `db:"orders_cancelled" json:"orders_cancelled" parquet:"orders_cancelled"`
+	CancellationFees int64 `db:"cancellation_fees" json:"cancellation_fees" parquet:"cancellation_fees"`
+	// Payment failures are the declined payment attempts of the day, those
+	// that cancelled their order, and the amounts declined
+	PaymentDeclines      int64 `db:"payment_declines" json:"payment_declines" parquet:"payment_declines"`
+	PaymentDeclinesFinal int64 `db:"payment_declines_final" json:"payment_declines_final" parquet:"payment_declines_final"`
+	DeclinedAmount       int64 `db:"declined_amount" json:"declined_amount" parquet:"declined_amount"`
+}
+
 // ProcessedEvent for idempotency
 type ProcessedEvent struct {
 	EventID     string    `db:"event_id"`
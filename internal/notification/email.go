@@ -0,0 +1,64 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+	"time"
+)
+
+// SMTPNotifier sends notifications as plain text email through an SMTP relay
+type SMTPNotifier struct {
+	addr string
+	from string
+	auth smtp.Auth
+	// send is smtp.SendMail, swapped in tests
+	send func(addr string, a smtp.Auth, from string, to []string, msg []byte) error
+}
+
+// NewSMTPNotifier creates a notifier sending from the given address through
+// the relay at addr (host:port), authenticating if username is set
+func NewSMTPNotifier(addr, username, password, from string) *SMTPNotifier {
+	n := &SMTPNotifier{addr: addr, from: from, send: smtp.SendMail}
+	if username != "" {
+		host, _, _ := net.SplitHostPort(addr)
+		n.auth = smtp.PlainAuth("", username, password, host)
+	}
+	return n
+}
+
+// Send mails msg to its recipient. Permanent SMTP failures (5xx), e.g. an
+// unknown mailbox, are rejections.
+func (n *SMTPNotifier) Send(ctx context.Context, msg Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	err := n.send(n.addr, n.auth, n.from, []string{msg.Recipient}, n.compose(msg))
+	var smtpErr *textproto.Error
+	if errors.As(err, &smtpErr) && smtpErr.Code >= 500 {
+		return fmt.Errorf("%w: %v", ErrRejected, err)
+	}
+	return err
+}
+
+// headerBreaks strips line breaks from header values, so a recipient or
+// subject cannot inject headers
+var headerBreaks = strings.NewReplacer("\r", "", "\n", "")
+
+// compose builds the RFC 5322 message
+func (n *SMTPNotifier) compose(msg Message) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", headerBreaks.Replace(n.from))
+	fmt.Fprintf(&b, "To: %s\r\n", headerBreaks.Replace(msg.Recipient))
+	fmt.Fprintf(&b, "Subject: %s\r\n", headerBreaks.Replace(msg.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	b.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+	return []byte(b.String())
+}
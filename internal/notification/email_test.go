@@ -0,0 +1,31 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"net/smtp"
+	"net/textproto"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSMTPNotifierRejectsOnPermanentFailures(t *testing.T) {
+	notifier := NewSMTPNotifier("localhost:25", "", "", "orders@example.com")
+	var mail string
+	notifier.send = func(addr string, a smtp.Auth, from string, to []string, msg []byte) error {
+		mail = string(msg)
+		return &textproto.Error{Code: 550, Msg: "mailbox unavailable"}
+	}
+
+	err := notifier.Send(context.Background(), Message{Recipient: "ana@example.com", Subject: "Hi", Body: "line 1\nline 2"})
+	assert.True(t, errors.Is(err, ErrRejected))
+	assert.Contains(t, mail, "To: ana@example.com\r\nSubject: Hi\r\n")
+	assert.Contains(t, mail, "\r\n\r\nline 1\r\nline 2")
+
+	notifier.send = func(addr string, a smtp.Auth, from string, to []string, msg []byte) error {
+		return &textproto.Error{Code: 451, Msg: "try again later"}
+	}
+	err = notifier.Send(context.Background(), Message{Recipient: "ana@example.com"})
+	assert.False(t, errors.Is(err, ErrRejected))
+}
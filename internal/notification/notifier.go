@@ -0,0 +1,47 @@
+// Package notification tells customers about their orders: it renders a
+// message per order lifecycle event and channel, queues it in the
+// notifications table and delivers it by email, SMS or webhook, retrying with
+// backoff until it is sent or runs out of attempts.
+package notification
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// ErrRejected marks a delivery the receiving end refused for good, e.g. an
+// unknown mailbox or a webhook answering 404; it is not retried
+var ErrRejected = errors.New("notification rejected")
+
+// Message is a rendered notification addressed to one recipient
+type Message struct {
+	Recipient string
+	Subject   string
+	Body      string
+}
+
+// Notifier delivers messages on one channel
+type Notifier interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// checkResponse turns an HTTP response of a gateway or webhook into an error:
+// client errors other than timeouts and rate limiting are rejections, server
+// errors are retried
+func checkResponse(resp *http.Response) error {
+	defer resp.Body.Close()
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+
+	detail, _ := io.ReadAll(io.LimitReader(resp.Body, 256))
+	err := fmt.Errorf("%s answered %d: %s", resp.Request.URL.Host, resp.StatusCode, detail)
+	if resp.StatusCode >= 400 && resp.StatusCode < 500 &&
+		resp.StatusCode != http.StatusRequestTimeout && resp.StatusCode != http.StatusTooManyRequests {
+		return fmt.Errorf("%w: %v", ErrRejected, err)
+	}
+	return err
+}
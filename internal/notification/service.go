@@ -0,0 +1,265 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"order-service/internal/apperrors"
+	"order-service/internal/models"
+	"order-service/internal/resilience/retry"
+	"order-service/internal/store"
+	"order-service/internal/tenant"
+	"order-service/internal/util"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// claimTTL is how long a claimed notification is hidden from other
+	// dispatchers while it is sent
+	claimTTL = 2 * time.Minute
+	// batchSize caps the notifications sent per poll
+	batchSize = 50
+)
+
+// channels is the order the notifications of an event are queued in
+var channels = []string{models.NotificationEmail, models.NotificationSMS, models.NotificationWebhook}
+
+// Service queues notifications of order lifecycle events on the channels
+// that have a notifier and the customer has an address for, and delivers them
+type Service struct {
+	notifications store.NotificationRepository
+	orders        store.OrderRepository
+	customers     store.CustomerRepository
+	templates     *Templates
+	notifiers     map[string]Notifier
+	policy        retry.Policy
+	logger        *zap.Logger
+}
+
+// NewService creates a new notification service retrying deliveries under
+// policy, whose MaxAttempts counts every attempt: a notification is marked
+// FAILED once they run out. Register a notifier per channel to send on.
+func NewService(
+	notifications store.NotificationRepository,
+	orders store.OrderRepository,
+	customers store.CustomerRepository,
+	policy retry.Policy,
+) (*Service, error) {
+	templates, err := NewTemplates()
+	if err != nil {
+		return nil, err
+	}
+	if policy.MaxAttempts < 1 {
+		policy.MaxAttempts = 1
+	}
+	return &Service{
+		notifications: notifications,
+		orders:        orders,
+		customers:     customers,
+		templates:     templates,
+		notifiers:     make(map[string]Notifier),
+		policy:        policy,
+		logger:        util.GetLogger(),
+	}, nil
+}
+
+// Register sends the notifications of a channel with notifier
+func (s *Service) Register(channel string, notifier Notifier) {
+	s.notifiers[channel] = notifier
+}
+
+// Enabled reports whether any channel has a notifier
+func (s *Service) Enabled() bool {
+	return len(s.notifiers) > 0
+}
+
+// HandleOrderConfirmed queues the notifications of a confirmed order
+func (s *Service) HandleOrderConfirmed(ctx context.Context, event *models.OrderConfirmedEvent) error {
+	return s.notify(ctx, event.BaseEvent, event.OrderID, "")
+}
+
+// HandleOrderCancelled queues the notifications of a cancelled order
+func (s *Service) HandleOrderCancelled(ctx context.Context, event *models.OrderCancelledEvent) error {
+	return s.notify(ctx, event.BaseEvent, event.OrderID, event.Reason)
+}
+
+// notify renders and queues a notification of the event per channel. Orders
+// that are synthetic, deleted or anonymized, and closed customers, are not
+// notified. A redelivered event queues nothing new.
+func (s *Service) notify(ctx context.Context, event models.BaseEvent, orderID int64, reason string) error {
+	ctx, span := util.StartSpan(ctx, "NotificationService.Notify")
+	defer span.End()
+
+	if !s.templates.Supports(event.EventType) {
+		return nil
+	}
+
+	order, err := s.orders.GetOrderByID(ctx, orderID)
+	if errors.Is(err, apperrors.ErrOrderNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to load order: %w", err)
+	}
+	if order.Synthetic || order.AnonymizedAt != nil {
+		return nil
+	}
+
+	customer, err := s.customers.GetCustomer(tenant.WithID(ctx, order.TenantID), order.UserID)
+	if err != nil {
+		return fmt.Errorf("failed to load customer: %w", err)
+	}
+	if customer != nil && customer.Status == models.CustomerClosed {
+		return nil
+	}
+
+	data := TemplateData{
+		EventID:     event.EventID,
+		EventType:   event.EventType,
+		OccurredAt:  event.Timestamp,
+		OrderID:     order.ID,
+		Status:      order.Status,
+		TotalAmount: order.TotalAmount,
+		Reason:      reason,
+	}
+	if customer != nil {
+		data.CustomerName = customer.Name
+	} else if order.CustomerName != nil {
+		data.CustomerName = *order.CustomerName
+	}
+
+	for _, channel := range channels {
+		if _, ok := s.notifiers[channel]; !ok {
+			continue
+		}
+		recipient := recipientOn(channel, order, customer)
+		if recipient == "" {
+			continue
+		}
+
+		subject, body, err := s.templates.Render(channel, data)
+		if err != nil {
+			return err
+		}
+		n := &models.Notification{
+			OrderID:       order.ID,
+			EventID:       event.EventID,
+			Kind:          event.EventType,
+			Channel:       channel,
+			Recipient:     recipient,
+			Subject:       subject,
+			Body:          body,
+			Status:        models.NotificationPending,
+			NextAttemptAt: time.Now(),
+		}
+		created, err := s.notifications.CreateNotification(ctx, n)
+		if err != nil {
+			return fmt.Errorf("failed to queue %s notification: %w", channel, err)
+		}
+		if created {
+			util.NotificationsTotal.WithLabelValues(channel, "queued").Inc()
+		}
+	}
+	return nil
+}
+
+// recipientOn returns where a notification on channel goes, empty if the
+// customer has no address for it. The email recorded on the order is used
+// when the customer is not known.
+func recipientOn(channel string, order *models.Order, customer *models.Customer) string {
+	switch channel {
+	case models.NotificationEmail:
+		if customer != nil {
+			return customer.Email
+		}
+		if order.CustomerEmail != nil {
+			return *order.CustomerEmail
+		}
+	case models.NotificationSMS:
+		if customer != nil {
+			return customer.Phone
+		}
+	case models.NotificationWebhook:
+		if customer != nil {
+			return customer.WebhookURL
+		}
+	}
+	return ""
+}
+
+// DispatchDue sends the notifications that are due. Returns the number sent.
+func (s *Service) DispatchDue(ctx context.Context) (int, error) {
+	ctx, span := util.StartSpan(ctx, "NotificationService.DispatchDue")
+	defer span.End()
+
+	due, err := s.notifications.ClaimDueNotifications(ctx, time.Now(), claimTTL, batchSize)
+	if err != nil {
+		return 0, fmt.Errorf("failed to claim due notifications: %w", err)
+	}
+
+	sent := 0
+	for i := range due {
+		n := &due[i]
+		sendErr := s.send(ctx, n)
+		if err := s.recordAttempt(ctx, n, sendErr); err != nil {
+			s.logger.Error("Failed to record notification attempt",
+				zap.Int64("notification_id", n.ID),
+				zap.Error(err))
+			continue
+		}
+		if sendErr == nil {
+			sent++
+		}
+	}
+	return sent, nil
+}
+
+// send delivers a notification with the notifier of its channel
+func (s *Service) send(ctx context.Context, n *models.Notification) error {
+	notifier, ok := s.notifiers[n.Channel]
+	if !ok {
+		return fmt.Errorf("%w: channel %s is not configured", ErrRejected, n.Channel)
+	}
+	return notifier.Send(ctx, Message{Recipient: n.Recipient, Subject: n.Subject, Body: n.Body})
+}
+
+// recordAttempt records the outcome of a delivery: sent, rescheduled with
+// backoff, or failed once rejected or out of attempts
+func (s *Service) recordAttempt(ctx context.Context, n *models.Notification, sendErr error) error {
+	n.Attempts++
+	switch {
+	case sendErr == nil:
+		now := time.Now()
+		n.Status, n.SentAt, n.LastError = models.NotificationSent, &now, ""
+		util.NotificationsTotal.WithLabelValues(n.Channel, "sent").Inc()
+	case errors.Is(sendErr, ErrRejected) || n.Attempts >= s.policy.MaxAttempts:
+		n.Status, n.LastError = models.NotificationFailed, sendErr.Error()
+		util.NotificationsTotal.WithLabelValues(n.Channel, "failed").Inc()
+		s.logger.Warn("Notification failed",
+			zap.Int64("notification_id", n.ID),
+			zap.Int64("order_id", n.OrderID),
+			zap.String("channel", n.Channel),
+			zap.Int("attempts", n.Attempts),
+			zap.Error(sendErr))
+	default:
+		n.LastError = sendErr.Error()
+		n.NextAttemptAt = time.Now().Add(s.policy.Delay(n.Attempts))
+		util.NotificationsTotal.WithLabelValues(n.Channel, "rescheduled").Inc()
+		s.logger.Info("Notification failed, rescheduled",
+			zap.Int64("notification_id", n.ID),
+			zap.String("channel", n.Channel),
+			zap.Int("attempts", n.Attempts),
+			zap.Time("next_attempt_at", n.NextAttemptAt),
+			zap.Error(sendErr))
+	}
+	return s.notifications.UpdateNotification(ctx, n)
+}
+
+// OrderNotifications returns the notifications of an order with their
+// delivery status
+func (s *Service) OrderNotifications(ctx context.Context, orderID int64) ([]models.Notification, error) {
+	return s.notifications.GetOrderNotifications(ctx, orderID)
+}
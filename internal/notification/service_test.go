@@ -0,0 +1,138 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"order-service/internal/models"
+	"order-service/internal/resilience/retry"
+	"order-service/internal/store/mocks"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeNotifier records the messages sent and fails with err
+type fakeNotifier struct {
+	sent []Message
+	err  error
+}
+
+func (f *fakeNotifier) Send(ctx context.Context, msg Message) error {
+	f.sent = append(f.sent, msg)
+	return f.err
+}
+
+func newTestService(t *testing.T) (*Service, *mocks.NotificationRepository, *mocks.OrderRepository, *mocks.CustomerRepository) {
+	notifications := mocks.NewNotificationRepository(t)
+	orders := mocks.NewOrderRepository(t)
+	customers := mocks.NewCustomerRepository(t)
+	s, err := NewService(notifications, orders, customers, retry.Policy{MaxAttempts: 3, BaseDelay: time.Second, MaxDelay: time.Minute})
+	require.NoError(t, err)
+	return s, notifications, orders, customers
+}
+
+func TestNotifyQueuesChannelsTheCustomerHasAnAddressFor(t *testing.T) {
+	s, notifications, orders, customers := newTestService(t)
+	s.Register(models.NotificationEmail, &fakeNotifier{})
+	s.Register(models.NotificationSMS, &fakeNotifier{})
+	s.Register(models.NotificationWebhook, &fakeNotifier{})
+
+	orders.On("GetOrderByID", mock.Anything, int64(9)).
+		Return(&models.Order{ID: 9, UserID: 7, Status: models.OrderStatusCancelled, TotalAmount: 12550}, nil).Once()
+	customers.On("GetCustomer", mock.Anything, int64(7)).
+		Return(&models.Customer{UserID: 7, Name: "Ana", Email: "ana@example.com", WebhookURL: "https://ana.example.com/hook", Status: models.CustomerActive}, nil).Once()
+
+	var queued []*models.Notification
+	notifications.On("CreateNotification", mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) { queued = append(queued, args.Get(1).(*models.Notification)) }).
+		Return(true, nil)
+
+	err := s.HandleOrderCancelled(context.Background(), &models.OrderCancelledEvent{
+		BaseEvent: models.BaseEvent{EventID: "e1", EventType: models.EventTypeOrderCancelled},
+		OrderID:   9,
+		Reason:    "payment_failed",
+	})
+	require.NoError(t, err)
+
+	require.Len(t, queued, 2, "no SMS without a phone number")
+	assert.Equal(t, models.NotificationEmail, queued[0].Channel)
+	assert.Equal(t, "ana@example.com", queued[0].Recipient)
+	assert.Equal(t, "Your order #9 was cancelled", queued[0].Subject)
+	assert.Contains(t, queued[0].Body, "Hello Ana,")
+	assert.Contains(t, queued[0].Body, "125.50 was cancelled (payment_failed)")
+	assert.Equal(t, models.NotificationWebhook, queued[1].Channel)
+	assert.JSONEq(t, `{"event_id":"e1","event_type":"ORDER_CANCELLED","occurred_at":"0001-01-01T00:00:00Z","order_id":9,"status":"CANCELLED","total_amount":12550,"reason":"payment_failed"}`, queued[1].Body)
+}
+
+func TestNotifyFallsBackToTheEmailOnTheOrder(t *testing.T) {
+	s, notifications, orders, customers := newTestService(t)
+	s.Register(models.NotificationEmail, &fakeNotifier{})
+
+	email := "bo@example.com"
+	orders.On("GetOrderByID", mock.Anything, int64(9)).
+		Return(&models.Order{ID: 9, UserID: 7, CustomerEmail: &email}, nil).Once()
+	customers.On("GetCustomer", mock.Anything, int64(7)).Return(nil, nil).Once()
+	notifications.On("CreateNotification", mock.Anything, mock.MatchedBy(func(n *models.Notification) bool {
+		return n.Recipient == email && n.Kind == models.EventTypeOrderConfirmed
+	})).Return(true, nil).Once()
+
+	err := s.HandleOrderConfirmed(context.Background(), &models.OrderConfirmedEvent{
+		BaseEvent: models.BaseEvent{EventID: "e1", EventType: models.EventTypeOrderConfirmed},
+		OrderID:   9,
+	})
+	assert.NoError(t, err)
+}
+
+func TestNotifySkipsSyntheticOrdersAndClosedCustomers(t *testing.T) {
+	s, _, orders, customers := newTestService(t)
+	s.Register(models.NotificationEmail, &fakeNotifier{})
+
+	orders.On("GetOrderByID", mock.Anything, int64(1)).Return(&models.Order{ID: 1, UserID: 7, Synthetic: true}, nil).Once()
+	orders.On("GetOrderByID", mock.Anything, int64(2)).Return(&models.Order{ID: 2, UserID: 8}, nil).Once()
+	customers.On("GetCustomer", mock.Anything, int64(8)).
+		Return(&models.Customer{UserID: 8, Status: models.CustomerClosed}, nil).Once()
+
+	for _, orderID := range []int64{1, 2} {
+		err := s.HandleOrderConfirmed(context.Background(), &models.OrderConfirmedEvent{
+			BaseEvent: models.BaseEvent{EventID: "e1", EventType: models.EventTypeOrderConfirmed},
+			OrderID:   orderID,
+		})
+		assert.NoError(t, err)
+	}
+}
+
+func TestDispatchDueRetriesThenFails(t *testing.T) {
+	s, notifications, _, _ := newTestService(t)
+	email := &fakeNotifier{err: errors.New("connection refused")}
+	sms := &fakeNotifier{err: ErrRejected}
+	s.Register(models.NotificationEmail, email)
+	s.Register(models.NotificationSMS, sms)
+
+	notifications.On("ClaimDueNotifications", mock.Anything, mock.Anything, claimTTL, batchSize).
+		Return([]models.Notification{
+			{ID: 1, Channel: models.NotificationEmail, Recipient: "ana@example.com", Status: models.NotificationPending},
+			{ID: 2, Channel: models.NotificationEmail, Recipient: "bo@example.com", Status: models.NotificationPending, Attempts: 2},
+			{ID: 3, Channel: models.NotificationSMS, Recipient: "+6281", Status: models.NotificationPending},
+		}, nil).Once()
+
+	outcomes := map[int64]models.Notification{}
+	notifications.On("UpdateNotification", mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) {
+			n := args.Get(1).(*models.Notification)
+			outcomes[n.ID] = *n
+		}).Return(nil)
+
+	sent, err := s.DispatchDue(context.Background())
+	require.NoError(t, err)
+	assert.Zero(t, sent)
+
+	assert.Equal(t, models.NotificationPending, outcomes[1].Status)
+	assert.True(t, outcomes[1].NextAttemptAt.After(time.Now()))
+	assert.Equal(t, models.NotificationFailed, outcomes[2].Status, "out of attempts")
+	assert.Equal(t, models.NotificationFailed, outcomes[3].Status, "rejections are not retried")
+	assert.Equal(t, 1, outcomes[3].Attempts)
+}
@@ -0,0 +1,64 @@
+package notification
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// SMSProvider sends text messages; implement it to plug in an SMS provider
+type SMSProvider interface {
+	SendSMS(ctx context.Context, to, text string) error
+}
+
+// SMSNotifier sends notifications as text messages through a provider
+type SMSNotifier struct {
+	provider SMSProvider
+}
+
+// NewSMSNotifier creates a notifier sending through provider
+func NewSMSNotifier(provider SMSProvider) *SMSNotifier {
+	return &SMSNotifier{provider: provider}
+}
+
+// Send texts the body of msg to its recipient's phone number
+func (n *SMSNotifier) Send(ctx context.Context, msg Message) error {
+	return n.provider.SendSMS(ctx, msg.Recipient, msg.Body)
+}
+
+// HTTPSMSGateway is an SMSProvider posting {"to", "text"} as JSON to an SMS
+// gateway, authenticated with a bearer token if one is set
+type HTTPSMSGateway struct {
+	url    string
+	token  string
+	client *http.Client
+}
+
+// NewHTTPSMSGateway creates a provider for the gateway at url
+func NewHTTPSMSGateway(url, token string) *HTTPSMSGateway {
+	return &HTTPSMSGateway{url: url, token: token, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// SendSMS posts the text message to the gateway
+func (g *HTTPSMSGateway) SendSMS(ctx context.Context, to, text string) error {
+	payload, err := json.Marshal(map[string]string{"to": to, "text": text})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, g.url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if g.token != "" {
+		req.Header.Set("Authorization", "Bearer "+g.token)
+	}
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return err
+	}
+	return checkResponse(resp)
+}
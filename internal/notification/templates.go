@@ -0,0 +1,132 @@
+package notification
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"order-service/internal/models"
+)
+
+// TemplateData is what notification templates are rendered with
+type TemplateData struct {
+	EventID      string
+	EventType    string
+	OccurredAt   time.Time
+	OrderID      int64
+	Status       string
+	TotalAmount  int64
+	CustomerName string
+	// Reason is why a cancelled order was cancelled
+	Reason string
+}
+
+// messageTemplate is the subject and body of one kind of notification; SMS
+// have no subject
+type messageTemplate struct {
+	subject string
+	email   string
+	sms     string
+}
+
+var funcs = template.FuncMap{
+	// amount formats an amount in minor units
+	"amount": func(minor int64) string { return fmt.Sprintf("%d.%02d", minor/100, minor%100) },
+	// greeting addresses the customer by name where known
+	"greeting": func(name string) string {
+		if name == "" {
+			return "Hello,"
+		}
+		return "Hello " + name + ","
+	},
+}
+
+var messageTemplates = map[string]messageTemplate{
+	models.EventTypeOrderConfirmed: {
+		subject: "Your order #{{.OrderID}} is confirmed",
+		email: `{{greeting .CustomerName}}
+
+your order #{{.OrderID}} of {{amount .TotalAmount}} is paid and confirmed.
+We will let you know when it ships.
+`,
+		sms: "Order #{{.OrderID}} ({{amount .TotalAmount}}) is confirmed.",
+	},
+	models.EventTypeOrderCancelled: {
+		subject: "Your order #{{.OrderID}} was cancelled",
+		email: `{{greeting .CustomerName}}
+
+your order #{{.OrderID}} of {{amount .TotalAmount}} was cancelled{{if .Reason}} ({{.Reason}}){{end}}.
+Any payment taken is refunded to you.
+`,
+		sms: "Order #{{.OrderID}} was cancelled{{if .Reason}} ({{.Reason}}){{end}}.",
+	},
+}
+
+// Templates renders the notifications of each event type and channel
+type Templates struct {
+	parsed map[string]*template.Template
+}
+
+// NewTemplates parses the notification templates
+func NewTemplates() (*Templates, error) {
+	t := &Templates{parsed: make(map[string]*template.Template)}
+	for eventType, m := range messageTemplates {
+		for part, text := range map[string]string{"subject": m.subject, "email": m.email, "sms": m.sms} {
+			name := eventType + "." + part
+			parsed, err := template.New(name).Funcs(funcs).Option("missingkey=error").Parse(text)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse template %s: %w", name, err)
+			}
+			t.parsed[name] = parsed
+		}
+	}
+	return t, nil
+}
+
+// Supports reports whether there are templates for an event type
+func (t *Templates) Supports(eventType string) bool {
+	_, ok := messageTemplates[eventType]
+	return ok
+}
+
+// Render returns the subject and body of a notification about an event on a
+// channel. Webhooks get the event data as JSON rather than a text.
+func (t *Templates) Render(channel string, data TemplateData) (subject, body string, err error) {
+	switch channel {
+	case models.NotificationEmail:
+		if subject, err = t.execute(data.EventType+".subject", data); err != nil {
+			return "", "", err
+		}
+		body, err = t.execute(data.EventType+".email", data)
+		return subject, body, err
+	case models.NotificationSMS:
+		body, err = t.execute(data.EventType+".sms", data)
+		return "", body, err
+	case models.NotificationWebhook:
+		payload, err := json.Marshal(map[string]interface{}{
+			"event_id":     data.EventID,
+			"event_type":   data.EventType,
+			"occurred_at":  data.OccurredAt,
+			"order_id":     data.OrderID,
+			"status":       data.Status,
+			"total_amount": data.TotalAmount,
+			"reason":       data.Reason,
+		})
+		return "", string(payload), err
+	}
+	return "", "", fmt.Errorf("unknown notification channel %q", channel)
+}
+
+func (t *Templates) execute(name string, data TemplateData) (string, error) {
+	parsed, ok := t.parsed[name]
+	if !ok {
+		return "", fmt.Errorf("no template %s", name)
+	}
+	var b strings.Builder
+	if err := parsed.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("failed to render template %s: %w", name, err)
+	}
+	return b.String(), nil
+}
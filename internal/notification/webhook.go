@@ -0,0 +1,52 @@
+package notification
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// SignatureHeader carries the HMAC-SHA256 of a webhook body, hex encoded and
+// prefixed "sha256=", so customers can verify it came from us
+const SignatureHeader = "X-Order-Signature"
+
+// WebhookNotifier posts notifications as JSON to the customer's webhook URL
+type WebhookNotifier struct {
+	secret []byte
+	client *http.Client
+}
+
+// NewWebhookNotifier creates a notifier signing bodies with secret, if set
+func NewWebhookNotifier(secret string) *WebhookNotifier {
+	return &WebhookNotifier{secret: []byte(secret), client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// Send posts the body of msg to the recipient URL
+func (n *WebhookNotifier) Send(ctx context.Context, msg Message) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, msg.Recipient, bytes.NewReader([]byte(msg.Body)))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrRejected, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if len(n.secret) > 0 {
+		req.Header.Set(SignatureHeader, Sign(n.secret, []byte(msg.Body)))
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	return checkResponse(resp)
+}
+
+// Sign returns the signature header value of body
+func Sign(secret, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,49 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhookNotifierSignsTheBody(t *testing.T) {
+	var signature, body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		raw, _ := io.ReadAll(r.Body)
+		signature, body = r.Header.Get(SignatureHeader), string(raw)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	err := NewWebhookNotifier("s3cret").Send(context.Background(), Message{Recipient: server.URL, Body: `{"order_id":9}`})
+	require.NoError(t, err)
+	assert.Equal(t, `{"order_id":9}`, body)
+	assert.Equal(t, Sign([]byte("s3cret"), []byte(body)), signature)
+}
+
+func TestWebhookNotifierRejectsOnClientErrors(t *testing.T) {
+	status := http.StatusGone
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+	notifier := NewWebhookNotifier("")
+
+	err := notifier.Send(context.Background(), Message{Recipient: server.URL, Body: "{}"})
+	assert.True(t, errors.Is(err, ErrRejected))
+
+	status = http.StatusTooManyRequests
+	err = notifier.Send(context.Background(), Message{Recipient: server.URL, Body: "{}"})
+	assert.Error(t, err)
+	assert.False(t, errors.Is(err, ErrRejected), "rate limiting is retried")
+
+	status = http.StatusBadGateway
+	err = notifier.Send(context.Background(), Message{Recipient: server.URL, Body: "{}"})
+	assert.False(t, errors.Is(err, ErrRejected))
+}
@@ -0,0 +1,76 @@
+package pii
+
+import (
+	"context"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"strings"
+)
+
+// LocalKeys wraps data keys with AES-256 keys held in configuration. Retired
+// keys stay in the keyring to unwrap data keys until everything is sealed
+// again under the active key.
+type LocalKeys struct {
+	active string
+	keys   map[string]cipher.AEAD
+}
+
+// ParseLocalKeys parses comma-separated id:base64-key pairs of 32-byte keys;
+// active names the key new data keys are wrapped with
+func ParseLocalKeys(raw, active string) (*LocalKeys, error) {
+	l := &LocalKeys{active: active, keys: make(map[string]cipher.AEAD)}
+	for _, pair := range strings.Split(raw, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		id, encoded, ok := strings.Cut(pair, ":")
+		if !ok || id == "" {
+			return nil, fmt.Errorf("key entry is not id:base64-key")
+		}
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil || len(key) != 32 {
+			return nil, fmt.Errorf("key %q is not 32 base64-encoded bytes", id)
+		}
+		aead, err := newAEAD(key)
+		if err != nil {
+			return nil, err
+		}
+		l.keys[id] = aead
+	}
+
+	if _, ok := l.keys[active]; !ok {
+		return nil, fmt.Errorf("active key %q is not in the keyring", active)
+	}
+	return l, nil
+}
+
+// ActiveKeyID names the key new data keys are wrapped with
+func (l *LocalKeys) ActiveKeyID() string {
+	return l.active
+}
+
+// WrapKey encrypts dataKey with the active key
+func (l *LocalKeys) WrapKey(_ context.Context, dataKey []byte) ([]byte, error) {
+	aead := l.keys[l.active]
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, dataKey, nil), nil
+}
+
+// UnwrapKey decrypts a data key wrapped with key keyID
+func (l *LocalKeys) UnwrapKey(_ context.Context, keyID string, wrapped []byte) ([]byte, error) {
+	aead, ok := l.keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("key %q is not in the keyring", keyID)
+	}
+	if len(wrapped) < aead.NonceSize() {
+		return nil, fmt.Errorf("wrapped data key is too short")
+	}
+	return aead.Open(nil, wrapped[:aead.NonceSize()], wrapped[aead.NonceSize():], nil)
+}
@@ -0,0 +1,175 @@
+// Package pii encrypts personal data before it is stored, with envelope
+// encryption: values are sealed with AES-GCM data keys, and data keys are
+// wrapped by a KeyProvider holding the key encryption keys (a local keyring or
+// a KMS). Only wrapped data keys are stored, next to the values they seal.
+package pii
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// prefix marks sealed values; values without it are plain text stored
+// before encryption was enabled
+const prefix = "pii:v1:"
+
+// KeyProvider wraps data keys with key encryption keys
+type KeyProvider interface {
+	// ActiveKeyID names the key new data keys are wrapped with. IDs contain
+	// no colons.
+	ActiveKeyID() string
+	// WrapKey encrypts dataKey with the active key
+	WrapKey(ctx context.Context, dataKey []byte) ([]byte, error)
+	// UnwrapKey decrypts a data key wrapped with key keyID
+	UnwrapKey(ctx context.Context, keyID string, wrapped []byte) ([]byte, error)
+}
+
+// Cipher seals and opens personal data. A process seals everything with one
+// data key, wrapped once; unwrapped data keys are cached so reads do not call
+// the provider per value.
+type Cipher struct {
+	keys KeyProvider
+
+	mu     sync.Mutex
+	active *dataKey
+	opened map[string]cipher.AEAD
+}
+
+type dataKey struct {
+	keyID   string
+	wrapped string
+	aead    cipher.AEAD
+}
+
+// NewCipher creates a cipher wrapping its data keys with keys
+func NewCipher(keys KeyProvider) *Cipher {
+	return &Cipher{keys: keys, opened: make(map[string]cipher.AEAD)}
+}
+
+// Seal encrypts value; empty values stay empty
+func (c *Cipher) Seal(ctx context.Context, value string) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+	key, err := c.activeKey(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, key.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := key.aead.Seal(nonce, nonce, []byte(value), nil)
+	return prefix + key.keyID + ":" + key.wrapped + ":" + base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// Open decrypts a value sealed by Seal. Plain text values are returned as
+// they are.
+func (c *Cipher) Open(ctx context.Context, value string) (string, error) {
+	rest, sealed := strings.CutPrefix(value, prefix)
+	if !sealed {
+		return value, nil
+	}
+	parts := strings.Split(rest, ":")
+	if len(parts) != 3 {
+		return "", errors.New("malformed sealed value")
+	}
+
+	aead, err := c.open(ctx, parts[0], parts[1])
+	if err != nil {
+		return "", err
+	}
+	data, err := base64.RawStdEncoding.DecodeString(parts[2])
+	if err != nil || len(data) < aead.NonceSize() {
+		return "", errors.New("malformed sealed value")
+	}
+	plain, err := aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt value: %w", err)
+	}
+	return string(plain), nil
+}
+
+// Stale reports whether value needs sealing again: it is plain text, or its
+// data key is wrapped with a key other than the active one
+func (c *Cipher) Stale(value string) bool {
+	if value == "" {
+		return false
+	}
+	rest, sealed := strings.CutPrefix(value, prefix)
+	if !sealed {
+		return true
+	}
+	keyID, _, _ := strings.Cut(rest, ":")
+	return keyID != c.keys.ActiveKeyID()
+}
+
+// activeKey returns the data key new values are sealed with, creating it on
+// first use and again after the active key encryption key changes
+func (c *Cipher) activeKey(ctx context.Context) (*dataKey, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	keyID := c.keys.ActiveKeyID()
+	if c.active != nil && c.active.keyID == keyID {
+		return c.active, nil
+	}
+
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, err
+	}
+	wrapped, err := c.keys.WrapKey(ctx, raw)
+	if err != nil {
+		return nil, fmt.Errorf("failed to wrap data key: %w", err)
+	}
+	aead, err := newAEAD(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	c.active = &dataKey{keyID: keyID, wrapped: base64.RawStdEncoding.EncodeToString(wrapped), aead: aead}
+	c.opened[keyID+":"+c.active.wrapped] = aead
+	return c.active, nil
+}
+
+// open returns the data key wrapped as wrapped by key keyID
+func (c *Cipher) open(ctx context.Context, keyID, wrapped string) (cipher.AEAD, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if aead, ok := c.opened[keyID+":"+wrapped]; ok {
+		return aead, nil
+	}
+
+	data, err := base64.RawStdEncoding.DecodeString(wrapped)
+	if err != nil {
+		return nil, errors.New("malformed sealed value")
+	}
+	raw, err := c.keys.UnwrapKey(ctx, keyID, data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap data key: %w", err)
+	}
+	aead, err := newAEAD(raw)
+	if err != nil {
+		return nil, err
+	}
+	c.opened[keyID+":"+wrapped] = aead
+	return aead, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
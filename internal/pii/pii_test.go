@@ -0,0 +1,108 @@
+package pii
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testKey(b byte) string {
+	return base64.StdEncoding.EncodeToString([]byte(strings.Repeat(string(rune('a'+b)), 32)))
+}
+
+func TestSealOpen(t *testing.T) {
+	ctx := context.Background()
+	keys, err := ParseLocalKeys("k1:"+testKey(1), "k1")
+	require.NoError(t, err)
+	c := NewCipher(keys)
+
+	sealed, err := c.Seal(ctx, "jane@example.com")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(sealed, "pii:v1:k1:"))
+	assert.NotContains(t, sealed, "jane")
+	assert.False(t, c.Stale(sealed))
+
+	opened, err := c.Open(ctx, sealed)
+	require.NoError(t, err)
+	assert.Equal(t, "jane@example.com", opened)
+
+	empty, err := c.Seal(ctx, "")
+	require.NoError(t, err)
+	assert.Empty(t, empty)
+
+	plain, err := c.Open(ctx, "legacy@example.com")
+	require.NoError(t, err)
+	assert.Equal(t, "legacy@example.com", plain, "values stored before encryption are read as they are")
+	assert.True(t, c.Stale("legacy@example.com"))
+}
+
+func TestRotation(t *testing.T) {
+	ctx := context.Background()
+	old, err := ParseLocalKeys("k1:"+testKey(1), "k1")
+	require.NoError(t, err)
+	sealed, err := NewCipher(old).Seal(ctx, "Jane Doe")
+	require.NoError(t, err)
+
+	rotated, err := ParseLocalKeys("k1:"+testKey(1)+",k2:"+testKey(2), "k2")
+	require.NoError(t, err)
+	c := NewCipher(rotated)
+	assert.True(t, c.Stale(sealed))
+
+	opened, err := c.Open(ctx, sealed)
+	require.NoError(t, err)
+	assert.Equal(t, "Jane Doe", opened)
+
+	resealed, err := c.Seal(ctx, opened)
+	require.NoError(t, err)
+	assert.False(t, c.Stale(resealed))
+
+	retired, err := ParseLocalKeys("k2:"+testKey(2), "k2")
+	require.NoError(t, err)
+	_, err = NewCipher(retired).Open(ctx, sealed)
+	assert.Error(t, err, "values under a dropped key cannot be read")
+}
+
+func TestParseLocalKeys(t *testing.T) {
+	_, err := ParseLocalKeys("k1:"+testKey(1), "k2")
+	assert.Error(t, err)
+	_, err = ParseLocalKeys("k1:c2hvcnQ=", "k1")
+	assert.Error(t, err)
+}
+
+func TestVaultTransit(t *testing.T) {
+	var calls int
+	vault := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		assert.Equal(t, "s.token", r.Header.Get("X-Vault-Token"))
+		var body map[string]string
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		switch r.URL.Path {
+		case "/v1/transit/encrypt/pii":
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]string{"ciphertext": "vault:v1:" + body["plaintext"]}})
+		case "/v1/transit/decrypt/pii":
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]string{"plaintext": strings.TrimPrefix(body["ciphertext"], "vault:v1:")}})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer vault.Close()
+
+	ctx := context.Background()
+	sealed, err := NewCipher(NewVaultTransit(vault.URL, "s.token", "pii")).Seal(ctx, "+62811")
+	require.NoError(t, err)
+
+	c := NewCipher(NewVaultTransit(vault.URL, "s.token", "pii"))
+	for i := 0; i < 2; i++ {
+		opened, err := c.Open(ctx, sealed)
+		require.NoError(t, err)
+		assert.Equal(t, "+62811", opened)
+	}
+	assert.Equal(t, 2, calls, "one wrap and one unwrap; the unwrapped data key is cached")
+}
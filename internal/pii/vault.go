@@ -0,0 +1,93 @@
+package pii
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// VaultTransit wraps data keys with a key of HashiCorp Vault's transit
+// secrets engine, so the key encryption key never leaves Vault. Vault keeps
+// the versions of a rotated key, and its ciphertexts name the version, so
+// rotating the key in Vault needs no re-encryption here.
+type VaultTransit struct {
+	addr   string
+	token  string
+	key    string
+	client *http.Client
+}
+
+// NewVaultTransit creates a provider for transit key key of the Vault at addr
+func NewVaultTransit(addr, token, key string) *VaultTransit {
+	return &VaultTransit{
+		addr:   strings.TrimSuffix(addr, "/"),
+		token:  token,
+		key:    key,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// ActiveKeyID names the transit key
+func (v *VaultTransit) ActiveKeyID() string {
+	return "vault." + v.key
+}
+
+// WrapKey encrypts dataKey with the transit key
+func (v *VaultTransit) WrapKey(ctx context.Context, dataKey []byte) ([]byte, error) {
+	var resp struct {
+		Data struct {
+			Ciphertext string `json:"ciphertext"`
+		} `json:"data"`
+	}
+	err := v.call(ctx, "encrypt", map[string]string{"plaintext": base64.StdEncoding.EncodeToString(dataKey)}, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(resp.Data.Ciphertext), nil
+}
+
+// UnwrapKey decrypts a data key wrapped with the transit key keyID names
+func (v *VaultTransit) UnwrapKey(ctx context.Context, keyID string, wrapped []byte) ([]byte, error) {
+	if keyID != v.ActiveKeyID() {
+		return nil, fmt.Errorf("key %q is not transit key %q", keyID, v.key)
+	}
+
+	var resp struct {
+		Data struct {
+			Plaintext string `json:"plaintext"`
+		} `json:"data"`
+	}
+	if err := v.call(ctx, "decrypt", map[string]string{"ciphertext": string(wrapped)}, &resp); err != nil {
+		return nil, err
+	}
+	return base64.StdEncoding.DecodeString(resp.Data.Plaintext)
+}
+
+// call posts body to the transit endpoint op of the key and decodes the response into out
+func (v *VaultTransit) call(ctx context.Context, op string, body interface{}, out interface{}) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, v.addr+"/v1/transit/"+op+"/"+v.key, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Vault-Token", v.token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("vault transit %s failed: %w", op, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("vault transit %s failed: %s", op, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
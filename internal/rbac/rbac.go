@@ -0,0 +1,104 @@
+// Package rbac decides which roles may perform which actions on which
+// resources.
+//
+// Customers are callers authenticated by a bearer JWT naming their user ID;
+// what they may do to an order is further limited to their own orders, see
+// OwnsOrder. Staff roles come from admin tokens.
+package rbac
+
+import "context"
+
+// Roles
+const (
+	RoleCustomer = "customer"
+	// RoleViewer is read-only staff, e.g. dashboards
+	RoleViewer  = "viewer"
+	RoleSupport = "support"
+	RoleOps     = "ops"
+	RoleAdmin   = "admin"
+)
+
+// Actions
+const (
+	Read  = "read"
+	Write = "write"
+)
+
+// Resources
+const (
+	Orders = "orders"
+	// Refunds covers wallet credits and refunds issued by hand
+	Refunds = "refunds"
+	// Inventory covers stock, warehouses, incoming stock and drops
+	Inventory = "inventory"
+	Webhooks  = "webhooks"
+	Reports   = "reports"
+	// Operations covers dead letters, event replay, kill switches, workers,
+	// compensations and idempotency abuse
+	Operations = "operations"
+	// Audit is the audit log of staff mutations, read by ops and admins
+	Audit = "audit"
+)
+
+var staffReads = []string{
+	Read + ":" + Orders,
+	Read + ":" + Refunds,
+	Read + ":" + Inventory,
+	Read + ":" + Webhooks,
+	Read + ":" + Reports,
+	Read + ":" + Operations,
+}
+
+// permissions lists what each role may do as action:resource
+var permissions = map[string]map[string]bool{
+	RoleCustomer: grant(nil, Read+":"+Orders, Write+":"+Orders),
+	RoleViewer:   grant(staffReads),
+	RoleSupport:  grant(staffReads, Write+":"+Orders, Write+":"+Refunds),
+	RoleOps: grant(staffReads, Read+":"+Audit,
+		Write+":"+Inventory, Write+":"+Webhooks, Write+":"+Operations),
+	RoleAdmin: grant(staffReads, Read+":"+Audit,
+		Write+":"+Orders, Write+":"+Refunds, Write+":"+Inventory, Write+":"+Webhooks, Write+":"+Operations),
+}
+
+func grant(base []string, extra ...string) map[string]bool {
+	set := make(map[string]bool, len(base)+len(extra))
+	for _, permission := range append(append([]string(nil), base...), extra...) {
+		set[permission] = true
+	}
+	return set
+}
+
+// Can reports whether actor, a role, may perform action on resource
+func Can(actor, action, resource string) bool {
+	return permissions[actor][action+":"+resource]
+}
+
+// customerKey carries the user ID of the customer a request is authenticated as
+type customerKey struct{}
+
+// WithCustomer makes ctx act for the customer with userID
+func WithCustomer(ctx context.Context, userID int64) context.Context {
+	return context.WithValue(ctx, customerKey{}, userID)
+}
+
+// Customer returns the user ID of the customer ctx acts for
+func Customer(ctx context.Context) (int64, bool) {
+	userID, ok := ctx.Value(customerKey{}).(int64)
+	return userID, ok
+}
+
+// OwnsOrder reports whether ctx may see an order placed by userID: it acts
+// for that customer, or for no customer, as staff and internal work do
+func OwnsOrder(ctx context.Context, userID int64) bool {
+	customer, ok := Customer(ctx)
+	return !ok || customer == userID
+}
+
+// IsStaffRole reports whether role can be given to an admin token
+func IsStaffRole(role string) bool {
+	switch role {
+	case RoleViewer, RoleSupport, RoleOps, RoleAdmin:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,44 @@
+package rbac
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCan(t *testing.T) {
+	cases := []struct {
+		actor, action, resource string
+		want                    bool
+	}{
+		{RoleCustomer, Read, Orders, true},
+		{RoleCustomer, Read, Inventory, false},
+		{RoleViewer, Read, Operations, true},
+		{RoleViewer, Write, Orders, false},
+		{RoleSupport, Write, Refunds, true},
+		{RoleSupport, Write, Inventory, false},
+		{RoleOps, Write, Inventory, true},
+		{RoleOps, Write, Refunds, false},
+		{RoleAdmin, Write, Refunds, true},
+		{RoleAdmin, Write, Operations, true},
+		{"root", Read, Orders, false},
+		{RoleAdmin, "delete", Orders, false},
+	}
+	for _, tc := range cases {
+		assert.Equal(t, tc.want, Can(tc.actor, tc.action, tc.resource), "%s %s %s", tc.actor, tc.action, tc.resource)
+	}
+}
+
+func TestIsStaffRole(t *testing.T) {
+	assert.True(t, IsStaffRole(RoleSupport))
+	assert.False(t, IsStaffRole(RoleCustomer))
+	assert.False(t, IsStaffRole("operator"))
+}
+
+func TestOwnsOrder(t *testing.T) {
+	ctx := context.Background()
+	assert.True(t, OwnsOrder(ctx, 7), "work for no customer sees every order")
+	assert.True(t, OwnsOrder(WithCustomer(ctx, 7), 7))
+	assert.False(t, OwnsOrder(WithCustomer(ctx, 8), 7))
+}
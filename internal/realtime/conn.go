@@ -0,0 +1,80 @@
+package realtime
+
+import (
+	"sync"
+	"time"
+
+	"order-service/internal/util"
+
+	"github.com/gorilla/websocket"
+)
+
+// closeReason is the close frame sent when the server ends a socket
+type closeReason struct {
+	code int
+	text string
+}
+
+// conn is one user's socket. Notifications queue on send and are written by
+// writePump; readPump only handles control frames and notices the client leaving.
+type conn struct {
+	user user
+	ws   *websocket.Conn
+	send chan Notification
+
+	done      chan closeReason
+	closeOnce sync.Once
+}
+
+// close asks writePump to send a close frame and end the socket
+func (c *conn) close(code int, text string) {
+	c.closeOnce.Do(func() {
+		c.done <- closeReason{code: code, text: text}
+	})
+}
+
+// writePump writes queued notifications and keepalive pings until the socket is closed
+func (c *conn) writePump() {
+	ticker := time.NewTicker(pingInterval)
+	defer func() {
+		ticker.Stop()
+		c.ws.Close()
+	}()
+
+	for {
+		select {
+		case n := <-c.send:
+			_ = c.ws.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.ws.WriteJSON(n); err != nil {
+				return
+			}
+			util.RealtimeNotificationsTotal.Inc()
+		case <-ticker.C:
+			if err := c.ws.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeWait)); err != nil {
+				return
+			}
+		case reason := <-c.done:
+			_ = c.ws.WriteControl(websocket.CloseMessage,
+				websocket.FormatCloseMessage(reason.code, reason.text), time.Now().Add(writeWait))
+			return
+		}
+	}
+}
+
+// readPump discards client messages and returns once the client is gone or
+// stops answering pings
+func (c *conn) readPump() {
+	defer c.close(websocket.CloseNormalClosure, "")
+
+	c.ws.SetReadLimit(512)
+	_ = c.ws.SetReadDeadline(time.Now().Add(pongWait))
+	c.ws.SetPongHandler(func(string) error {
+		return c.ws.SetReadDeadline(time.Now().Add(pongWait))
+	})
+
+	for {
+		if _, _, err := c.ws.ReadMessage(); err != nil {
+			return
+		}
+	}
+}
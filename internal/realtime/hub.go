@@ -0,0 +1,288 @@
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"order-service/internal/models"
+	"order-service/internal/tenant"
+	"order-service/internal/util"
+
+	"github.com/gorilla/websocket"
+	"github.com/segmentio/kafka-go"
+	"go.uber.org/zap"
+)
+
+const (
+	writeWait    = 10 * time.Second
+	pongWait     = 60 * time.Second
+	pingInterval = pongWait * 9 / 10
+
+	// ownerCacheSize bounds the remembered order owners; the cache is reset when full
+	ownerCacheSize = 10000
+)
+
+// ErrAtCapacity is returned when the instance or the user has too many sockets open
+var ErrAtCapacity = errors.New("too many order status connections")
+
+// eventStatuses maps the order events pushed to users to the status they announce
+var eventStatuses = map[string]string{
+	models.EventTypeOrderCreated:   models.OrderStatusCreated,
+	models.EventTypeOrderReserved:  models.OrderStatusReserved,
+	models.EventTypeOrderPaid:      models.OrderStatusPaid,
+	models.EventTypeOrderConfirmed: models.OrderStatusConfirmed,
+	models.EventTypeOrderCancelled: models.OrderStatusCancelled,
+	models.EventTypeOrderFailed:    models.OrderStatusFailed,
+	models.EventTypeOrderOnHold:    models.OrderStatusOnHold,
+}
+
+// Notification is the message pushed to a socket when one of the user's orders changes status
+type Notification struct {
+	OrderID   int64     `json:"order_id"`
+	Status    string    `json:"status"`
+	EventType string    `json:"event_type"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// Limits bounds the sockets a hub holds and how far each may fall behind
+type Limits struct {
+	MaxConnections        int
+	MaxConnectionsPerUser int
+	// SendBuffer is how many notifications may queue for a socket before it is
+	// closed as a slow consumer
+	SendBuffer int
+}
+
+// OrderLookup finds the owner of orders whose events don't carry a user ID
+// or a tenant
+type OrderLookup interface {
+	GetOrderByID(ctx context.Context, orderID int64) (*models.Order, error)
+}
+
+// user is a customer of a tenant; storefronts number their users
+// independently, so a user ID alone does not name one
+type user struct {
+	tenant string
+	id     int64
+}
+
+// Hub pushes order status changes read from the order event stream to the
+// WebSockets of the users owning the orders
+type Hub struct {
+	orders   OrderLookup
+	limits   Limits
+	upgrader websocket.Upgrader
+	logger   *zap.Logger
+
+	mu          sync.Mutex
+	users       map[user]map[*conn]bool
+	connections int
+	owners      map[int64]user
+}
+
+// NewHub creates a hub. Sockets are authenticated by the API, which passes
+// the tenant and customer of the request to Serve.
+func NewHub(orders OrderLookup, limits Limits) *Hub {
+	if limits.SendBuffer <= 0 {
+		limits.SendBuffer = 1
+	}
+	return &Hub{
+		orders: orders,
+		limits: limits,
+		upgrader: websocket.Upgrader{
+			// Sockets authenticate with a token, not cookies, so any origin may connect
+			CheckOrigin: func(*http.Request) bool { return true },
+		},
+		logger: util.GetLogger(),
+		users:  make(map[user]map[*conn]bool),
+		owners: make(map[int64]user),
+	}
+}
+
+// Serve upgrades the request to a WebSocket for the customer userID of
+// tenantID and pushes notifications to it until either side closes it.
+// Returns ErrAtCapacity, before writing any response, when the connection
+// limits are reached.
+func (h *Hub) Serve(w http.ResponseWriter, r *http.Request, tenantID string, userID int64) error {
+	c, err := h.register(user{tenant: tenantOrDefault(tenantID), id: userID})
+	if err != nil {
+		util.RealtimeRejectedTotal.WithLabelValues("capacity").Inc()
+		return err
+	}
+	defer h.unregister(c)
+
+	ws, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// The upgrader has already answered the request
+		h.logger.Debug("WebSocket upgrade failed",
+			zap.String("tenant_id", c.user.tenant), zap.Int64("user_id", userID), zap.Error(err))
+		return nil
+	}
+	c.ws = ws
+
+	go c.writePump()
+	c.readPump()
+	return nil
+}
+
+// HandleMessage pushes the status change announced by an order event to the
+// owner's sockets. ctx acts for the tenant the event was published for; the
+// owner of events published for no tenant is looked up. Notifications are
+// best effort: undecodable events and failed owner lookups are logged and
+// skipped rather than retried.
+func (h *Hub) HandleMessage(ctx context.Context, msg kafka.Message) error {
+	var event struct {
+		EventType string    `json:"event_type"`
+		Timestamp time.Time `json:"timestamp"`
+		OrderID   int64     `json:"order_id"`
+		UserID    int64     `json:"user_id"`
+	}
+	if err := json.Unmarshal(msg.Value, &event); err != nil {
+		h.logger.Warn("Skipping undecodable order event", zap.Error(err))
+		return nil
+	}
+
+	status, ok := eventStatuses[event.EventType]
+	if !ok || event.OrderID == 0 {
+		return nil
+	}
+	if id, ok := tenant.ID(ctx); ok && event.UserID != 0 {
+		h.rememberOwner(event.OrderID, user{tenant: id, id: event.UserID})
+	}
+	if !h.hasConnections() {
+		return nil
+	}
+
+	owner, err := h.owner(ctx, event.OrderID)
+	if err != nil {
+		h.logger.Warn("Failed to find order owner for notification",
+			zap.Int64("order_id", event.OrderID), zap.Error(err))
+		return nil
+	}
+
+	h.notify(owner, Notification{
+		OrderID:   event.OrderID,
+		Status:    status,
+		EventType: event.EventType,
+		Timestamp: event.Timestamp,
+	})
+	return nil
+}
+
+// Close closes every open socket, e.g. on shutdown
+func (h *Hub) Close(context.Context) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, conns := range h.users {
+		for c := range conns {
+			c.close(websocket.CloseGoingAway, "server shutting down")
+		}
+	}
+	return nil
+}
+
+// register reserves a connection slot for u
+func (h *Hub) register(u user) (*conn, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.connections >= h.limits.MaxConnections || len(h.users[u]) >= h.limits.MaxConnectionsPerUser {
+		return nil, ErrAtCapacity
+	}
+
+	c := &conn{
+		user: u,
+		send: make(chan Notification, h.limits.SendBuffer),
+		done: make(chan closeReason, 1),
+	}
+	if h.users[u] == nil {
+		h.users[u] = make(map[*conn]bool)
+	}
+	h.users[u][c] = true
+	h.connections++
+	util.RealtimeConnections.Inc()
+	return c, nil
+}
+
+// unregister frees the slot of a closed connection
+func (h *Hub) unregister(c *conn) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if !h.users[c.user][c] {
+		return
+	}
+	delete(h.users[c.user], c)
+	if len(h.users[c.user]) == 0 {
+		delete(h.users, c.user)
+	}
+	h.connections--
+	util.RealtimeConnections.Dec()
+}
+
+func (h *Hub) hasConnections() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.connections > 0
+}
+
+// notify queues a notification on the user's sockets, closing those too far behind
+func (h *Hub) notify(u user, n Notification) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for c := range h.users[u] {
+		select {
+		case c.send <- n:
+		default:
+			util.RealtimeSlowConsumersTotal.Inc()
+			h.logger.Warn("Closing slow order status socket",
+				zap.String("tenant_id", u.tenant), zap.Int64("user_id", u.id))
+			c.close(websocket.CloseTryAgainLater, "too slow to keep up")
+		}
+	}
+}
+
+// owner returns the user an order belongs to, looking it up once per order.
+// The lookup acts for the tenant of ctx, so an event tagged with another
+// tenant than its order's finds no owner.
+func (h *Hub) owner(ctx context.Context, orderID int64) (user, error) {
+	h.mu.Lock()
+	owner, ok := h.owners[orderID]
+	h.mu.Unlock()
+	if ok && tenant.Owns(ctx, owner.tenant) {
+		return owner, nil
+	}
+
+	order, err := h.orders.GetOrderByID(ctx, orderID)
+	if err != nil {
+		return user{}, err
+	}
+	owner = user{tenant: tenantOrDefault(order.TenantID), id: order.UserID}
+	h.rememberOwner(orderID, owner)
+	return owner, nil
+}
+
+func (h *Hub) rememberOwner(orderID int64, owner user) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if len(h.owners) >= ownerCacheSize {
+		h.owners = make(map[int64]user)
+	}
+	h.owners[orderID] = owner
+}
+
+// tenantOrDefault returns id, or the default tenant for rows and requests
+// from before tenancy
+func tenantOrDefault(id string) string {
+	if id == "" {
+		return tenant.Default
+	}
+	return id
+}
@@ -0,0 +1,90 @@
+package realtime
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"order-service/internal/models"
+	"order-service/internal/tenant"
+
+	"github.com/gorilla/websocket"
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeOrders are orders by ID, found whatever the tenant of ctx as long as
+// it owns them
+type fakeOrders map[int64]models.Order
+
+func (f fakeOrders) GetOrderByID(ctx context.Context, orderID int64) (*models.Order, error) {
+	order, ok := f[orderID]
+	if !ok || !tenant.Owns(ctx, order.TenantID) {
+		return nil, errors.New("order not found")
+	}
+	return &order, nil
+}
+
+func TestHubPushesStatusChangesToOrderOwner(t *testing.T) {
+	hub := NewHub(fakeOrders{
+		7: {ID: 7, TenantID: "acme", UserID: 42},
+		9: {ID: 9, TenantID: "globex", UserID: 42},
+	}, Limits{MaxConnections: 10, MaxConnectionsPerUser: 2, SendBuffer: 4})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = hub.Serve(w, r, "acme", 42)
+	}))
+	defer server.Close()
+
+	ws, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	require.NoError(t, err)
+	defer ws.Close()
+
+	require.Eventually(t, hub.hasConnections, time.Second, 10*time.Millisecond)
+
+	// Another user's order, the same user ID's order at another tenant, then
+	// one of ours whose event lacks the user ID and the tenant
+	require.NoError(t, hub.HandleMessage(tenant.WithID(context.Background(), "acme"), kafka.Message{
+		Value: []byte(`{"event_type":"ORDER_CREATED","order_id":8,"user_id":99}`),
+	}))
+	require.NoError(t, hub.HandleMessage(context.Background(), kafka.Message{
+		Value: []byte(`{"event_type":"ORDER_CREATED","order_id":9}`),
+	}))
+	require.NoError(t, hub.HandleMessage(context.Background(), kafka.Message{
+		Value: []byte(`{"event_type":"ORDER_PAID","order_id":7}`),
+	}))
+
+	var n Notification
+	_ = ws.SetReadDeadline(time.Now().Add(time.Second))
+	require.NoError(t, ws.ReadJSON(&n))
+	assert.Equal(t, int64(7), n.OrderID)
+	assert.Equal(t, models.OrderStatusPaid, n.Status)
+}
+
+func TestHubClosesSlowConsumers(t *testing.T) {
+	hub := NewHub(fakeOrders{}, Limits{MaxConnections: 10, MaxConnectionsPerUser: 1, SendBuffer: 1})
+	customer := user{tenant: "acme", id: 42}
+
+	c, err := hub.register(customer)
+	require.NoError(t, err)
+	_, err = hub.register(customer)
+	assert.ErrorIs(t, err, ErrAtCapacity)
+	other, err := hub.register(user{tenant: "globex", id: 42})
+	require.NoError(t, err, "users are counted per tenant")
+	defer hub.unregister(other)
+
+	hub.notify(customer, Notification{OrderID: 1})
+	hub.notify(customer, Notification{OrderID: 1})
+
+	select {
+	case reason := <-c.done:
+		assert.Equal(t, websocket.CloseTryAgainLater, reason.code)
+	default:
+		t.Fatal("slow consumer was not closed")
+	}
+}
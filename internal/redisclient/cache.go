@@ -0,0 +1,62 @@
+package redisclient
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// cacheGenerationTTL is how long the invalidation count of a cached entry is
+// kept, far longer than loading an entry to cache takes
+const cacheGenerationTTL = time.Hour
+
+//go:embed scripts/set_cached.lua
+var setCachedScriptSource string
+
+var setCachedScript = redis.NewScript(setCachedScriptSource)
+
+// invalidate deletes a cached entry and bumps its generation so that copies
+// loaded before are not cached, then publishes id on channel for replicas to
+// evict their local copies
+func (c *Client) invalidate(ctx context.Context, key, generationKey, channel string, id int64) error {
+	pipe := c.rdb.TxPipeline()
+	pipe.Del(ctx, key)
+	pipe.Incr(ctx, generationKey)
+	pipe.Expire(ctx, generationKey, cacheGenerationTTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to delete cached entry: %w", err)
+	}
+	return c.rdb.Publish(ctx, channel, id).Err()
+}
+
+// subscribeInvalidations calls onInvalidate for every ID published on channel
+// until ctx is cancelled
+func (c *Client) subscribeInvalidations(ctx context.Context, channel string, onInvalidate func(id int64)) error {
+	pubsub := c.rdb.Subscribe(ctx, channel)
+	defer pubsub.Close()
+
+	if _, err := pubsub.Receive(ctx); err != nil {
+		return fmt.Errorf("failed to subscribe to %s: %w", channel, err)
+	}
+
+	ch := pubsub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case msg, ok := <-ch:
+			if !ok {
+				return nil
+			}
+			id, err := strconv.ParseInt(msg.Payload, 10, 64)
+			if err != nil {
+				continue
+			}
+			onInvalidate(id)
+		}
+	}
+}
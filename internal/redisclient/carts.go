@@ -0,0 +1,35 @@
+package redisclient
+
+import (
+	"context"
+	"time"
+
+	"order-service/internal/tenant"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// cartKey returns the key of a cached cart, per tenant so a cart ID read
+// under another tenant misses the cache and is not found in the database
+func cartKey(ctx context.Context, cartID string) string {
+	return tenant.Key(ctx, "cart:"+cartID)
+}
+
+// GetCachedCart returns the cached cart payload, or nil when not cached
+func (c *Client) GetCachedCart(ctx context.Context, cartID string) ([]byte, error) {
+	data, err := c.rdb.Get(ctx, cartKey(ctx, cartID)).Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	return data, err
+}
+
+// SetCachedCart stores a cart payload until the cart expires
+func (c *Client) SetCachedCart(ctx context.Context, cartID string, data []byte, ttl time.Duration) error {
+	return c.rdb.Set(ctx, cartKey(ctx, cartID), data, ttl).Err()
+}
+
+// DeleteCachedCart drops the cached cart
+func (c *Client) DeleteCachedCart(ctx context.Context, cartID string) error {
+	return c.rdb.Del(ctx, cartKey(ctx, cartID)).Err()
+}
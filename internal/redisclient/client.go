@@ -3,9 +3,16 @@ package redisclient
 import (
 	"context"
 	_ "embed"
+	"errors"
 	"fmt"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
+	"order-service/internal/resilience/retry"
+	"order-service/internal/tenant"
+
 	"github.com/go-redis/redis/v8"
 )
 
@@ -18,52 +25,210 @@ var releaseStockScript string
 //go:embed scripts/commit_stock.lua
 var commitStockScript string
 
+//go:embed scripts/replace_inventory.lua
+var replaceInventoryScriptSource string
+
+var replaceInventoryScript = redis.NewScript(replaceInventoryScriptSource)
+
+// ErrInventoryNotFound is returned when a variant has no inventory hash in a warehouse
+var ErrInventoryNotFound = errors.New("inventory not found")
+
+// Topology modes supported by NewClientWithOptions
+const (
+	ModeStandalone = "standalone"
+	ModeCluster    = "cluster"
+	ModeSentinel   = "sentinel"
+)
+
+// Options configures the Redis topology the client connects to
+type Options struct {
+	Mode             string
+	Addrs            []string
+	Password         string
+	DB               int
+	MasterName       string
+	SentinelPassword string
+	MaxRetries       int
+}
+
 type Client struct {
-	rdb           *redis.Client
+	rdb           redis.UniversalClient
 	reserveScript *redis.Script
 	releaseScript *redis.Script
 	commitScript  *redis.Script
+	maxRetries    int
 }
 
 // NewClient creates a new Redis client with Lua scripts loaded
 func NewClient(addr, password string, db int) (*Client, error) {
-	rdb := redis.NewClient(&redis.Options{
-		Addr:     addr,
+	return NewClientWithOptions(Options{
+		Mode:     ModeStandalone,
+		Addrs:    []string{addr},
 		Password: password,
 		DB:       db,
 	})
+}
+
+// NewClusterClient creates a client backed by a Redis Cluster
+func NewClusterClient(addrs []string, password string) (*Client, error) {
+	return NewClientWithOptions(Options{
+		Mode:     ModeCluster,
+		Addrs:    addrs,
+		Password: password,
+	})
+}
+
+// NewSentinelClient creates a failover client that discovers the master via Sentinel
+func NewSentinelClient(masterName string, sentinelAddrs []string, password string, db int) (*Client, error) {
+	return NewClientWithOptions(Options{
+		Mode:       ModeSentinel,
+		Addrs:      sentinelAddrs,
+		Password:   password,
+		DB:         db,
+		MasterName: masterName,
+	})
+}
+
+// NewClientWithOptions creates a Redis client for the configured topology
+func NewClientWithOptions(opts Options) (*Client, error) {
+	if len(opts.Addrs) == 0 {
+		return nil, fmt.Errorf("redis: at least one address is required")
+	}
+	if opts.MaxRetries <= 0 {
+		opts.MaxRetries = 3
+	}
+
+	var rdb redis.UniversalClient
+	switch opts.Mode {
+	case ModeStandalone, "":
+		rdb = redis.NewClient(&redis.Options{
+			Addr:       opts.Addrs[0],
+			Password:   opts.Password,
+			DB:         opts.DB,
+			MaxRetries: opts.MaxRetries,
+		})
+	case ModeCluster:
+		rdb = redis.NewClusterClient(&redis.ClusterOptions{
+			Addrs:      opts.Addrs,
+			Password:   opts.Password,
+			MaxRetries: opts.MaxRetries,
+		})
+	case ModeSentinel:
+		if opts.MasterName == "" {
+			return nil, fmt.Errorf("redis: sentinel mode requires a master name")
+		}
+		rdb = redis.NewFailoverClient(&redis.FailoverOptions{
+			MasterName:       opts.MasterName,
+			SentinelAddrs:    opts.Addrs,
+			SentinelPassword: opts.SentinelPassword,
+			Password:         opts.Password,
+			DB:               opts.DB,
+			MaxRetries:       opts.MaxRetries,
+		})
+	default:
+		return nil, fmt.Errorf("redis: unknown mode %q", opts.Mode)
+	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
 	if err := rdb.Ping(ctx).Err(); err != nil {
+		rdb.Close()
 		return nil, fmt.Errorf("redis ping failed: %w", err)
 	}
 
+	return Wrap(rdb, opts.MaxRetries), nil
+}
+
+// Wrap creates a client on top of an existing Redis connection, e.g. one
+// shared with the process embedding the order service
+func Wrap(rdb redis.UniversalClient, maxRetries int) *Client {
+	if maxRetries <= 0 {
+		maxRetries = 3
+	}
 	return &Client{
 		rdb:           rdb,
 		reserveScript: redis.NewScript(reserveStockScript),
 		releaseScript: redis.NewScript(releaseStockScript),
 		commitScript:  redis.NewScript(commitStockScript),
-	}, nil
+		maxRetries:    maxRetries,
+	}
 }
 
 // GetClient returns the underlying Redis client
-func (c *Client) GetClient() *redis.Client {
+func (c *Client) GetClient() redis.UniversalClient {
 	return c.rdb
 }
 
+// Ping verifies the Redis connection is alive
+func (c *Client) Ping(ctx context.Context) error {
+	return c.rdb.Ping(ctx).Err()
+}
+
 // Close closes the Redis connection
 func (c *Client) Close() error {
 	return c.rdb.Close()
 }
 
-// ReserveStock atomically reserves stock using Lua script
+// inventoryKey returns the inventory hash key for a product variant in a
+// warehouse. The variant ID is wrapped in a hash tag so every key for one
+// variant maps to the same cluster slot.
+func inventoryKey(variantID, warehouseID int64) string {
+	return fmt.Sprintf("inventory:{variant:%d}:%d", variantID, warehouseID)
+}
+
+// warehousesKey returns the key of the set of warehouses a product variant has
+// an inventory hash in
+func warehousesKey(variantID int64) string {
+	return fmt.Sprintf("inventory:{variant:%d}:warehouses", variantID)
+}
+
+// inventorySyncKey returns the key marking a variant in a warehouse as having
+// a reservation taken in Redis whose database sync is pending or recent
+func inventorySyncKey(variantID, warehouseID int64) string {
+	return fmt.Sprintf("inventory:{variant:%d}:%d:sync", variantID, warehouseID)
+}
+
+// runScript runs a Lua script, retrying when the node is failing over
+func (c *Client) runScript(ctx context.Context, script *redis.Script, keys []string, args ...interface{}) (interface{}, error) {
+	var result interface{}
+	err := c.withFailoverRetry(ctx, func() error {
+		var err error
+		result, err = script.Run(ctx, c.rdb, keys, args...).Result()
+		return err
+	})
+	return result, err
+}
+
+// withFailoverRetry retries fn with backoff while Redis reports a failover in progress
+func (c *Client) withFailoverRetry(ctx context.Context, fn func() error) error {
+	policy := retry.Policy{
+		MaxAttempts: c.maxRetries + 1,
+		BaseDelay:   50 * time.Millisecond,
+		MaxDelay:    2 * time.Second,
+		Jitter:      0.2,
+		Retryable:   isFailoverError,
+	}
+	return retry.Do(ctx, policy, func(context.Context) error { return fn() })
+}
+
+// isFailoverError reports whether err is a transient error raised during failover or resharding
+func isFailoverError(err error) bool {
+	msg := err.Error()
+	for _, prefix := range []string{"READONLY", "LOADING", "MASTERDOWN", "CLUSTERDOWN", "TRYAGAIN", "MOVED", "ASK"} {
+		if strings.HasPrefix(msg, prefix+" ") {
+			return true
+		}
+	}
+	return false
+}
+
+// ReserveStock atomically reserves stock in a warehouse using Lua script
 // Returns true if reservation successful, false if insufficient stock
-func (c *Client) ReserveStock(ctx context.Context, productID int64, quantity int) (bool, error) {
-	key := fmt.Sprintf("inventory:%d", productID)
+func (c *Client) ReserveStock(ctx context.Context, variantID, warehouseID int64, quantity int) (bool, error) {
+	key := inventoryKey(variantID, warehouseID)
 
-	result, err := c.reserveScript.Run(ctx, c.rdb, []string{key}, quantity).Result()
+	result, err := c.runScript(ctx, c.reserveScript, []string{key}, quantity)
 	if err != nil {
 		return false, fmt.Errorf("reserve stock script failed: %w", err)
 	}
@@ -76,11 +241,11 @@ func (c *Client) ReserveStock(ctx context.Context, productID int64, quantity int
 	return success == 1, nil
 }
 
-// ReleaseStock atomically releases reserved stock (compensation)
-func (c *Client) ReleaseStock(ctx context.Context, productID int64, quantity int) error {
-	key := fmt.Sprintf("inventory:%d", productID)
+// ReleaseStock atomically releases reserved stock in a warehouse (compensation)
+func (c *Client) ReleaseStock(ctx context.Context, variantID, warehouseID int64, quantity int) error {
+	key := inventoryKey(variantID, warehouseID)
 
-	_, err := c.releaseScript.Run(ctx, c.rdb, []string{key}, quantity).Result()
+	_, err := c.runScript(ctx, c.releaseScript, []string{key}, quantity)
 	if err != nil {
 		return fmt.Errorf("release stock script failed: %w", err)
 	}
@@ -88,11 +253,11 @@ func (c *Client) ReleaseStock(ctx context.Context, productID int64, quantity int
 	return nil
 }
 
-// CommitStock atomically commits reserved stock (final deduction)
-func (c *Client) CommitStock(ctx context.Context, productID int64, quantity int) error {
-	key := fmt.Sprintf("inventory:%d", productID)
+// CommitStock atomically commits reserved stock in a warehouse (final deduction)
+func (c *Client) CommitStock(ctx context.Context, variantID, warehouseID int64, quantity int) error {
+	key := inventoryKey(variantID, warehouseID)
 
-	_, err := c.commitScript.Run(ctx, c.rdb, []string{key}, quantity).Result()
+	_, err := c.runScript(ctx, c.commitScript, []string{key}, quantity)
 	if err != nil {
 		return fmt.Errorf("commit stock script failed: %w", err)
 	}
@@ -100,21 +265,137 @@ func (c *Client) CommitStock(ctx context.Context, productID int64, quantity int)
 	return nil
 }
 
-// InitInventory initializes inventory count in Redis
-func (c *Client) InitInventory(ctx context.Context, productID int64, available, reserved int) error {
-	key := fmt.Sprintf("inventory:%d", productID)
+// RestockInventory adds units back to available stock in a warehouse
+func (c *Client) RestockInventory(ctx context.Context, variantID, warehouseID int64, quantity int) error {
+	return c.rdb.HIncrBy(ctx, inventoryKey(variantID, warehouseID), "available", int64(quantity)).Err()
+}
+
+// InitInventory initializes the inventory count of a variant in a warehouse
+func (c *Client) InitInventory(ctx context.Context, variantID, warehouseID int64, available, reserved int) error {
+	key := inventoryKey(variantID, warehouseID)
 
 	pipe := c.rdb.Pipeline()
 	pipe.HSet(ctx, key, "available", available)
 	pipe.HSet(ctx, key, "reserved", reserved)
+	pipe.SAdd(ctx, warehousesKey(variantID), warehouseID)
 
 	_, err := pipe.Exec(ctx)
 	return err
 }
 
-// GetInventory retrieves current inventory counts
-func (c *Client) GetInventory(ctx context.Context, productID int64) (available, reserved int, err error) {
-	key := fmt.Sprintf("inventory:%d", productID)
+// ReseedInventory initializes the inventory count of a variant in a warehouse
+// unless its hash has been created since it was found missing, reporting
+// whether it was written
+func (c *Client) ReseedInventory(ctx context.Context, variantID, warehouseID int64, available, reserved int) (bool, error) {
+	return c.replaceInventory(ctx, variantID, warehouseID, "", "", available, reserved)
+}
+
+// ReplaceInventory overwrites the inventory count of a variant in a warehouse
+// unless it has changed from seenAvailable and seenReserved, so stock reserved
+// or released since it was read is not lost. Reports whether it was written.
+func (c *Client) ReplaceInventory(ctx context.Context, variantID, warehouseID int64, seenAvailable, seenReserved, available, reserved int) (bool, error) {
+	return c.replaceInventory(ctx, variantID, warehouseID,
+		strconv.Itoa(seenAvailable), strconv.Itoa(seenReserved), available, reserved)
+}
+
+func (c *Client) replaceInventory(ctx context.Context, variantID, warehouseID int64, seenAvailable, seenReserved string, available, reserved int) (bool, error) {
+	keys := []string{inventoryKey(variantID, warehouseID), warehousesKey(variantID)}
+	result, err := c.runScript(ctx, replaceInventoryScript, keys, seenAvailable, seenReserved, available, reserved, warehouseID)
+	if err != nil {
+		return false, fmt.Errorf("replace inventory script failed: %w", err)
+	}
+	replaced, _ := result.(int64)
+	return replaced == 1, nil
+}
+
+// MarkInventorySync marks a variant in a warehouse as having its Redis
+// reservations synced to the database for the next grace period. It is set
+// before a reservation is taken in Redis and again once the database has it.
+func (c *Client) MarkInventorySync(ctx context.Context, variantID, warehouseID int64, grace time.Duration) error {
+	return c.rdb.Set(ctx, inventorySyncKey(variantID, warehouseID), 1, grace).Err()
+}
+
+// InventorySyncPending reports whether a variant in a warehouse was marked by
+// MarkInventorySync within its grace period
+func (c *Client) InventorySyncPending(ctx context.Context, variantID, warehouseID int64) (bool, error) {
+	n, err := c.rdb.Exists(ctx, inventorySyncKey(variantID, warehouseID)).Result()
+	return n > 0, err
+}
+
+// WarehouseStock is the stock of a product variant in one warehouse
+type WarehouseStock struct {
+	WarehouseID int64
+	Available   int
+	Reserved    int
+}
+
+// GetWarehouseStock retrieves the inventory counts of a variant in each
+// warehouse it has stock in, ordered by warehouse
+func (c *Client) GetWarehouseStock(ctx context.Context, variantID int64) ([]WarehouseStock, error) {
+	warehouseIDs, err := c.variantWarehouses(ctx, variantID)
+	if err != nil {
+		return nil, err
+	}
+
+	pipe := c.rdb.Pipeline()
+	results := make([]*redis.StringStringMapCmd, len(warehouseIDs))
+	for i, warehouseID := range warehouseIDs {
+		results[i] = pipe.HGetAll(ctx, inventoryKey(variantID, warehouseID))
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+
+	stock := make([]WarehouseStock, 0, len(warehouseIDs))
+	for i, warehouseID := range warehouseIDs {
+		counts := results[i].Val()
+		if len(counts) == 0 {
+			continue
+		}
+		ws := WarehouseStock{WarehouseID: warehouseID}
+		fmt.Sscanf(counts["available"], "%d", &ws.Available)
+		fmt.Sscanf(counts["reserved"], "%d", &ws.Reserved)
+		stock = append(stock, ws)
+	}
+	if len(stock) == 0 {
+		return nil, fmt.Errorf("inventory not found for variant %d", variantID)
+	}
+	return stock, nil
+}
+
+// variantWarehouses returns the warehouses a variant has an inventory hash
+// in, ordered by ID
+func (c *Client) variantWarehouses(ctx context.Context, variantID int64) ([]int64, error) {
+	members, err := c.rdb.SMembers(ctx, warehousesKey(variantID)).Result()
+	if err != nil {
+		return nil, err
+	}
+	warehouseIDs := make([]int64, 0, len(members))
+	for _, member := range members {
+		if id, err := strconv.ParseInt(member, 10, 64); err == nil {
+			warehouseIDs = append(warehouseIDs, id)
+		}
+	}
+	sort.Slice(warehouseIDs, func(i, j int) bool { return warehouseIDs[i] < warehouseIDs[j] })
+	return warehouseIDs, nil
+}
+
+// GetInventory retrieves current inventory counts of a variant across warehouses
+func (c *Client) GetInventory(ctx context.Context, variantID int64) (available, reserved int, err error) {
+	stock, err := c.GetWarehouseStock(ctx, variantID)
+	if err != nil {
+		return 0, 0, err
+	}
+	for _, ws := range stock {
+		available += ws.Available
+		reserved += ws.Reserved
+	}
+	return available, reserved, nil
+}
+
+// GetWarehouseInventory retrieves current inventory counts of a variant in a warehouse
+func (c *Client) GetWarehouseInventory(ctx context.Context, variantID, warehouseID int64) (available, reserved int, err error) {
+	key := inventoryKey(variantID, warehouseID)
 
 	result, err := c.rdb.HGetAll(ctx, key).Result()
 	if err != nil {
@@ -122,7 +403,7 @@ func (c *Client) GetInventory(ctx context.Context, productID int64) (available,
 	}
 
 	if len(result) == 0 {
-		return 0, 0, fmt.Errorf("inventory not found for product %d", productID)
+		return 0, 0, fmt.Errorf("%w for variant %d in warehouse %d", ErrInventoryNotFound, variantID, warehouseID)
 	}
 
 	var availableInt, reservedInt int
@@ -132,26 +413,137 @@ func (c *Client) GetInventory(ctx context.Context, productID int64) (available,
 	return availableInt, reservedInt, nil
 }
 
+// idempotencyKey is the Redis key of a client's idempotency key, in the
+// namespace of its tenant
+func idempotencyKey(ctx context.Context, key string) string {
+	return tenant.Key(ctx, "idempotency:"+key)
+}
+
 // SetIdempotencyKey stores an idempotency key with TTL
 func (c *Client) SetIdempotencyKey(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
-	return c.rdb.Set(ctx, fmt.Sprintf("idempotency:%s", key), value, ttl).Err()
+	return c.rdb.Set(ctx, idempotencyKey(ctx, key), value, ttl).Err()
+}
+
+// ClaimIdempotencyKey stores value only if the key does not exist yet
+func (c *Client) ClaimIdempotencyKey(ctx context.Context, key string, value interface{}, ttl time.Duration) (bool, error) {
+	return c.rdb.SetNX(ctx, idempotencyKey(ctx, key), value, ttl).Result()
+}
+
+// GetIdempotencyKey returns the value stored for an idempotency key, or nil if absent
+func (c *Client) GetIdempotencyKey(ctx context.Context, key string) ([]byte, error) {
+	data, err := c.rdb.Get(ctx, idempotencyKey(ctx, key)).Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	return data, err
+}
+
+// DeleteIdempotencyKey removes an idempotency key
+func (c *Client) DeleteIdempotencyKey(ctx context.Context, key string) error {
+	return c.rdb.Del(ctx, idempotencyKey(ctx, key)).Err()
+}
+
+func adminPlanKey(token string) string {
+	return "admin-plan:" + token
+}
+
+// SaveAdminPlan stores a previewed admin operation until it is applied or expires
+func (c *Client) SaveAdminPlan(ctx context.Context, token string, plan []byte, ttl time.Duration) error {
+	return c.rdb.Set(ctx, adminPlanKey(token), plan, ttl).Err()
+}
+
+// TakeAdminPlan returns and removes a stored admin plan, or nil if it is
+// unknown or expired, so each plan is applied at most once
+func (c *Client) TakeAdminPlan(ctx context.Context, token string) ([]byte, error) {
+	pipe := c.rdb.TxPipeline()
+	get := pipe.Get(ctx, adminPlanKey(token))
+	pipe.Del(ctx, adminPlanKey(token))
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return nil, err
+	}
+
+	data, err := get.Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	return data, err
+}
+
+// ReplayCount is the number of idempotent replays recorded for a client
+type ReplayCount struct {
+	Client  string `json:"client"`
+	Replays int64  `json:"replays"`
+}
+
+func idempotencyReplayKey(t time.Time) string {
+	return "idempotency-replays:" + t.UTC().Format("2006010215")
+}
+
+// RecordIdempotencyReplay counts a replay for client in the current hourly window
+// and returns the client's count so far
+func (c *Client) RecordIdempotencyReplay(ctx context.Context, client string) (int64, error) {
+	key := idempotencyReplayKey(time.Now())
+
+	pipe := c.rdb.TxPipeline()
+	incr := pipe.ZIncrBy(ctx, key, 1, client)
+	pipe.Expire(ctx, key, 2*time.Hour)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, err
+	}
+	return int64(incr.Val()), nil
+}
+
+// TopIdempotencyReplayers returns the clients with the most replays in the current window
+func (c *Client) TopIdempotencyReplayers(ctx context.Context, limit int) ([]ReplayCount, error) {
+	entries, err := c.rdb.ZRevRangeWithScores(ctx, idempotencyReplayKey(time.Now()), 0, int64(limit-1)).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make([]ReplayCount, 0, len(entries))
+	for _, entry := range entries {
+		member, _ := entry.Member.(string)
+		counts = append(counts, ReplayCount{Client: member, Replays: int64(entry.Score)})
+	}
+	return counts, nil
 }
 
 // CheckIdempotencyKey checks if an idempotency key exists
 func (c *Client) CheckIdempotencyKey(ctx context.Context, key string) (bool, error) {
-	result, err := c.rdb.Exists(ctx, fmt.Sprintf("idempotency:%s", key)).Result()
+	result, err := c.rdb.Exists(ctx, idempotencyKey(ctx, key)).Result()
 	if err != nil {
 		return false, err
 	}
 	return result > 0, nil
 }
 
-// AcquireLock acquires a distributed lock
-func (c *Client) AcquireLock(ctx context.Context, lockKey string, ttl time.Duration) (bool, error) {
-	return c.rdb.SetNX(ctx, fmt.Sprintf("lock:%s", lockKey), "1", ttl).Result()
+// LoadScripts loads every Lua script the service runs into the script cache,
+// which fails on a script Redis cannot compile or a server that does not run
+// scripts
+func (c *Client) LoadScripts(ctx context.Context) error {
+	scripts := map[string]*redis.Script{
+		"reserve_stock":     c.reserveScript,
+		"release_stock":     c.releaseScript,
+		"commit_stock":      c.commitScript,
+		"acquire_lock":      lockAcquireScript,
+		"release_lock":      lockReleaseScript,
+		"renew_lock":        lockRenewScript,
+		"claim_scheduled":   claimScheduledScript,
+		"hold_stock":        holdStockScript,
+		"reserve_unheld":    reserveUnheldStockScript,
+		"enqueue_waiting":   enqueueWaitingScript,
+		"set_cached":        setCachedScript,
+		"replace_inventory": replaceInventoryScript,
+	}
+	for name, script := range scripts {
+		if err := script.Load(ctx, c.rdb).Err(); err != nil {
+			return fmt.Errorf("failed to load %s script: %w", name, err)
+		}
+	}
+	return nil
 }
 
-// ReleaseLock releases a distributed lock
-func (c *Client) ReleaseLock(ctx context.Context, lockKey string) error {
-	return c.rdb.Del(ctx, fmt.Sprintf("lock:%s", lockKey)).Err()
+// Time returns the clock of the Redis server
+func (c *Client) Time(ctx context.Context) (time.Time, error) {
+	return c.rdb.Time(ctx).Result()
 }
@@ -0,0 +1,148 @@
+package redisclient
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"order-service/internal/tenant"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInventoryKeyUsesHashTag(t *testing.T) {
+	assert.Equal(t, "inventory:{variant:42}:3", inventoryKey(42, 3))
+	assert.Equal(t, "inventory:{variant:42}:warehouses", warehousesKey(42))
+}
+
+func TestIsFailoverError(t *testing.T) {
+	assert.True(t, isFailoverError(errors.New("READONLY You can't write against a read only replica.")))
+	assert.True(t, isFailoverError(errors.New("CLUSTERDOWN The cluster is down")))
+	assert.True(t, isFailoverError(errors.New("LOADING Redis is loading the dataset in memory")))
+	assert.False(t, isFailoverError(errors.New("ERR wrong number of arguments")))
+}
+
+func TestLoadScriptsCompilesEveryScript(t *testing.T) {
+	client, err := NewClient(miniredis.RunT(t).Addr(), "", 0)
+	require.NoError(t, err)
+
+	assert.NoError(t, client.LoadScripts(context.Background()))
+}
+
+func TestHoldsLeaveHeldStockToTheirHolder(t *testing.T) {
+	server := miniredis.RunT(t)
+	client, err := NewClient(server.Addr(), "", 0)
+	require.NoError(t, err)
+	ctx := context.Background()
+	require.NoError(t, client.InitInventory(ctx, 1, 1, 3, 0))
+	require.NoError(t, client.InitInventory(ctx, 1, 2, 2, 0))
+
+	held, err := client.HoldStock(ctx, 1, "cart-a", 4, time.Minute)
+	require.NoError(t, err)
+	assert.True(t, held, "holds count stock in every warehouse")
+
+	held, err = client.HoldStock(ctx, 1, "cart-b", 2, time.Minute)
+	require.NoError(t, err)
+	assert.False(t, held, "only 1 unit is not held")
+
+	reserved, err := client.ReserveUnheldStock(ctx, 1, 2, 2, "")
+	require.NoError(t, err)
+	assert.False(t, reserved)
+
+	reserved, err = client.ReserveUnheldStock(ctx, 1, 1, 4, "cart-a")
+	require.NoError(t, err)
+	assert.False(t, reserved, "warehouse 1 only has 3 units")
+
+	reserved, err = client.ReserveUnheldStock(ctx, 1, 1, 3, "cart-a")
+	require.NoError(t, err)
+	assert.True(t, reserved)
+	assert.Empty(t, server.HGet("holds:{variant:1}", "cart-a"), "the hold is consumed")
+
+	available, reservedUnits, err := client.GetInventory(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, 2, available)
+	assert.Equal(t, 3, reservedUnits)
+
+	available, reservedUnits, err = client.GetWarehouseInventory(ctx, 1, 2)
+	require.NoError(t, err)
+	assert.Equal(t, 2, available)
+	assert.Equal(t, 0, reservedUnits)
+}
+
+func TestExpiredHoldsReturnToAvailableStock(t *testing.T) {
+	server := miniredis.RunT(t)
+	client, err := NewClient(server.Addr(), "", 0)
+	require.NoError(t, err)
+	ctx := context.Background()
+	require.NoError(t, client.InitInventory(ctx, 1, 1, 3, 0))
+
+	held, err := client.HoldStock(ctx, 1, "cart-a", 3, time.Minute)
+	require.NoError(t, err)
+	require.True(t, held)
+
+	reserved, err := client.ReserveUnheldStock(ctx, 1, 1, 1, "")
+	require.NoError(t, err)
+	assert.False(t, reserved)
+
+	server.SetTime(time.Now().Add(2 * time.Minute))
+	reserved, err = client.ReserveUnheldStock(ctx, 1, 1, 1, "")
+	require.NoError(t, err)
+	assert.True(t, reserved)
+}
+
+func TestHoldsBelongToTheirHoldersTenant(t *testing.T) {
+	server := miniredis.RunT(t)
+	client, err := NewClient(server.Addr(), "", 0)
+	require.NoError(t, err)
+	acme := tenant.WithID(context.Background(), "acme")
+	require.NoError(t, client.InitInventory(acme, 1, 1, 2, 0))
+
+	held, err := client.HoldStock(acme, 1, "cart-a", 2, time.Minute)
+	require.NoError(t, err)
+	require.True(t, held)
+	assert.NotEmpty(t, server.HGet("holds:{variant:1}", "tenant:acme:cart-a"))
+
+	other := tenant.WithID(context.Background(), "globex")
+	reserved, err := client.ReserveUnheldStock(other, 1, 1, 2, "cart-a")
+	require.NoError(t, err)
+	assert.False(t, reserved, "another tenant's cart of the same name does not own the hold")
+
+	reserved, err = client.ReserveUnheldStock(acme, 1, 1, 2, "cart-a")
+	require.NoError(t, err)
+	assert.True(t, reserved)
+}
+
+func TestReplaceInventoryLeavesCountsChangedSinceTheyWereRead(t *testing.T) {
+	client, err := NewClient(miniredis.RunT(t).Addr(), "", 0)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	_, _, err = client.GetWarehouseInventory(ctx, 1, 1)
+	assert.ErrorIs(t, err, ErrInventoryNotFound)
+	reseeded, err := client.ReseedInventory(ctx, 1, 1, 10, 0)
+	require.NoError(t, err)
+	assert.True(t, reseeded)
+	reseeded, err = client.ReseedInventory(ctx, 1, 1, 20, 0)
+	require.NoError(t, err)
+	assert.False(t, reseeded, "a hash that exists is not reseeded")
+
+	replaced, err := client.ReplaceInventory(ctx, 1, 1, 10, 0, 8, 2)
+	require.NoError(t, err)
+	assert.True(t, replaced)
+
+	// A reservation lands between the read and the write
+	ok, err := client.ReserveStock(ctx, 1, 1, 3)
+	require.NoError(t, err)
+	require.True(t, ok)
+	replaced, err = client.ReplaceInventory(ctx, 1, 1, 8, 2, 10, 0)
+	require.NoError(t, err)
+	assert.False(t, replaced)
+
+	available, reserved, err := client.GetWarehouseInventory(ctx, 1, 1)
+	require.NoError(t, err)
+	assert.Equal(t, 5, available)
+	assert.Equal(t, 5, reserved, "the reservation is kept")
+}
@@ -0,0 +1,54 @@
+package redisclient
+
+import (
+	"context"
+	"time"
+
+	"order-service/internal/tenant"
+
+	"github.com/go-redis/redis/v8"
+)
+
+func flashOrderKey(token string) string {
+	return "flash-order:" + token
+}
+
+func flashOrderClaimKey(ctx context.Context, idempotencyKey string) string {
+	return tenant.Key(ctx, "flash-order:key:"+idempotencyKey)
+}
+
+// ClaimFlashOrder records token as the flash order of an idempotency key for
+// ttl. If another request claimed the key first it returns that request's
+// token and false.
+func (c *Client) ClaimFlashOrder(ctx context.Context, idempotencyKey, token string, ttl time.Duration) (string, bool, error) {
+	claimed, err := c.rdb.SetNX(ctx, flashOrderClaimKey(ctx, idempotencyKey), token, ttl).Result()
+	if err != nil {
+		return "", false, err
+	}
+	if claimed {
+		return token, true, nil
+	}
+	existing, err := c.rdb.Get(ctx, flashOrderClaimKey(ctx, idempotencyKey)).Result()
+	return existing, false, err
+}
+
+// ReleaseFlashOrderClaim forgets the claim of an idempotency key, so the
+// request can be made again after it was turned away
+func (c *Client) ReleaseFlashOrderClaim(ctx context.Context, idempotencyKey string) error {
+	return c.rdb.Del(ctx, flashOrderClaimKey(ctx, idempotencyKey)).Err()
+}
+
+// SetFlashOrderStatus stores the status payload of a flash order for ttl
+func (c *Client) SetFlashOrderStatus(ctx context.Context, token string, data []byte, ttl time.Duration) error {
+	return c.rdb.Set(ctx, flashOrderKey(token), data, ttl).Err()
+}
+
+// GetFlashOrderStatus returns the status payload of a flash order, or nil
+// when the token is unknown or expired
+func (c *Client) GetFlashOrderStatus(ctx context.Context, token string) ([]byte, error) {
+	data, err := c.rdb.Get(ctx, flashOrderKey(token)).Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	return data, err
+}
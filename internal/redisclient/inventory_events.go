@@ -0,0 +1,107 @@
+package redisclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// InventoryChangesChannel carries availability updates for product variants
+const InventoryChangesChannel = "inventory:changes"
+
+// inventoryVersionKey counts the availability changes of every variant
+const inventoryVersionKey = "inventory:version"
+
+// InventoryChange is the payload published on InventoryChangesChannel
+type InventoryChange struct {
+	ProductID int64     `json:"product_id"`
+	VariantID int64     `json:"variant_id"`
+	Available int       `json:"available"`
+	Timestamp time.Time `json:"timestamp"`
+	// ConsistencyToken is the inventory version the availability is as of
+	ConsistencyToken int64 `json:"consistency_token,omitempty"`
+}
+
+// BumpInventoryVersion advances the inventory version after an availability
+// change and returns the new version
+func (c *Client) BumpInventoryVersion(ctx context.Context) (int64, error) {
+	return c.rdb.Incr(ctx, inventoryVersionKey).Result()
+}
+
+// GetInventoryVersion returns the inventory version, 0 before the first change
+func (c *Client) GetInventoryVersion(ctx context.Context) (int64, error) {
+	version, err := c.rdb.Get(ctx, inventoryVersionKey).Int64()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	return version, err
+}
+
+// PublishInventoryChange reads the current available count of a product
+// variant across warehouses and broadcasts it as of the given inventory version
+func (c *Client) PublishInventoryChange(ctx context.Context, productID, variantID, version int64) error {
+	available, _, err := c.GetInventory(ctx, variantID)
+	if err != nil {
+		return fmt.Errorf("failed to read available stock: %w", err)
+	}
+
+	payload, err := json.Marshal(InventoryChange{
+		ProductID:        productID,
+		VariantID:        variantID,
+		Available:        available,
+		Timestamp:        time.Now(),
+		ConsistencyToken: version,
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.rdb.Publish(ctx, InventoryChangesChannel, payload).Err()
+}
+
+// SubscribeInventoryChanges calls onChange for every inventory change until ctx is cancelled
+func (c *Client) SubscribeInventoryChanges(ctx context.Context, onChange func(InventoryChange)) error {
+	pubsub := c.rdb.Subscribe(ctx, InventoryChangesChannel)
+	defer pubsub.Close()
+
+	if _, err := pubsub.Receive(ctx); err != nil {
+		return fmt.Errorf("failed to subscribe to %s: %w", InventoryChangesChannel, err)
+	}
+
+	ch := pubsub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case msg, ok := <-ch:
+			if !ok {
+				return nil
+			}
+			var change InventoryChange
+			if err := json.Unmarshal([]byte(msg.Payload), &change); err != nil {
+				continue
+			}
+			onChange(change)
+		}
+	}
+}
+
+// lowStockKey returns the key flagging a product variant whose stock fell to
+// its reorder threshold
+func lowStockKey(variantID int64) string {
+	return fmt.Sprintf("inventory:{variant:%d}:low", variantID)
+}
+
+// MarkLowStock flags a product variant as low on stock and reports whether it
+// was not flagged yet, so each fall to the reorder threshold alerts once
+func (c *Client) MarkLowStock(ctx context.Context, variantID int64) (bool, error) {
+	return c.rdb.SetNX(ctx, lowStockKey(variantID), 1, 0).Result()
+}
+
+// ClearLowStock unflags a product variant, e.g. once its stock recovered
+func (c *Client) ClearLowStock(ctx context.Context, variantID int64) error {
+	return c.rdb.Del(ctx, lowStockKey(variantID)).Err()
+}
@@ -0,0 +1,71 @@
+package redisclient
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-redis/redis/v8"
+)
+
+func killSwitchKey(kind string) string {
+	return "killswitch:" + kind
+}
+
+// EngageKillSwitch blocks value of the given kind; payload describes why
+func (c *Client) EngageKillSwitch(ctx context.Context, kind, value string, payload []byte) error {
+	return c.rdb.HSet(ctx, killSwitchKey(kind), value, payload).Err()
+}
+
+// ReleaseKillSwitch unblocks value of the given kind, reporting whether it was blocked
+func (c *Client) ReleaseKillSwitch(ctx context.Context, kind, value string) (bool, error) {
+	removed, err := c.rdb.HDel(ctx, killSwitchKey(kind), value).Result()
+	return removed > 0, err
+}
+
+// GetKillSwitches returns the engaged kill switches of a kind keyed by value
+func (c *Client) GetKillSwitches(ctx context.Context, kind string) (map[string][]byte, error) {
+	entries, err := c.rdb.HGetAll(ctx, killSwitchKey(kind)).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string][]byte, len(entries))
+	for value, payload := range entries {
+		result[value] = []byte(payload)
+	}
+	return result, nil
+}
+
+// MatchKillSwitches returns the payloads of the values, keyed by kind, that
+// have a kill switch engaged. Values without one are absent from the result.
+func (c *Client) MatchKillSwitches(ctx context.Context, values map[string][]string) (map[string]map[string][]byte, error) {
+	pipe := c.rdb.Pipeline()
+	cmds := make(map[string]*redis.SliceCmd, len(values))
+	for kind, vals := range values {
+		if len(vals) > 0 {
+			cmds[kind] = pipe.HMGet(ctx, killSwitchKey(kind), vals...)
+		}
+	}
+	if len(cmds) == 0 {
+		return nil, nil
+	}
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, fmt.Errorf("failed to read kill switches: %w", err)
+	}
+
+	result := make(map[string]map[string][]byte)
+	for kind, cmd := range cmds {
+		for i, payload := range cmd.Val() {
+			s, ok := payload.(string)
+			if !ok {
+				continue
+			}
+			if result[kind] == nil {
+				result[kind] = make(map[string][]byte)
+			}
+			result[kind][values[kind][i]] = []byte(s)
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,169 @@
+package redisclient
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+)
+
+//go:embed scripts/acquire_lock.lua
+var acquireLockScript string
+
+//go:embed scripts/release_lock.lua
+var releaseLockScript string
+
+//go:embed scripts/renew_lock.lua
+var renewLockScript string
+
+var (
+	lockAcquireScript = redis.NewScript(acquireLockScript)
+	lockReleaseScript = redis.NewScript(releaseLockScript)
+	lockRenewScript   = redis.NewScript(renewLockScript)
+)
+
+var (
+	// ErrLockNotObtained is returned when the lock is held by another owner
+	ErrLockNotObtained = errors.New("lock not obtained")
+	// ErrLockNotHeld is returned when releasing or renewing a lock that has expired or changed owner
+	ErrLockNotHeld = errors.New("lock not held")
+)
+
+// Lock is a held distributed lock. A watchdog goroutine keeps extending its
+// TTL until Release is called or the lock is lost.
+type Lock struct {
+	client *Client
+	key    string
+	value  string
+	token  int64
+	ttl    time.Duration
+
+	stopOnce sync.Once
+	stop     chan struct{}
+	lost     chan struct{}
+}
+
+func lockKeys(name string) []string {
+	return []string{
+		fmt.Sprintf("lock:{%s}", name),
+		fmt.Sprintf("lock-fence:{%s}", name),
+	}
+}
+
+// ObtainLock acquires the named lock with the given TTL and starts auto-renewal.
+// Returns ErrLockNotObtained if another holder owns the lock.
+func (c *Client) ObtainLock(ctx context.Context, name string, ttl time.Duration) (*Lock, error) {
+	keys := lockKeys(name)
+	value := uuid.New().String()
+
+	// The TTL runs from when the script runs, which is after now
+	acquired := time.Now()
+	result, err := c.runScript(ctx, lockAcquireScript, keys, value, ttl.Milliseconds())
+	if err != nil {
+		return nil, fmt.Errorf("acquire lock script failed: %w", err)
+	}
+
+	token, ok := result.(int64)
+	if !ok {
+		return nil, fmt.Errorf("unexpected script result type")
+	}
+	if token == 0 {
+		return nil, ErrLockNotObtained
+	}
+
+	lock := &Lock{
+		client: c,
+		key:    keys[0],
+		value:  value,
+		token:  token,
+		ttl:    ttl,
+		stop:   make(chan struct{}),
+		lost:   make(chan struct{}),
+	}
+	go lock.watchdog(acquired)
+
+	return lock, nil
+}
+
+// Token returns the fencing token issued when the lock was acquired. Tokens
+// increase monotonically per lock name, so writers can reject stale holders.
+func (l *Lock) Token() int64 {
+	return l.token
+}
+
+// Lost is closed when the watchdog finds the lock taken over, or when renewals
+// keep failing until the lock would expire before the next one
+func (l *Lock) Lost() <-chan struct{} {
+	return l.lost
+}
+
+// Context returns a copy of ctx that is cancelled once the lock is lost, with
+// ErrLockNotHeld as its cause, or released, so that work done under the lock
+// stops when it no longer holds it
+func (l *Lock) Context(ctx context.Context) context.Context {
+	ctx, cancel := context.WithCancelCause(ctx)
+	go func() {
+		select {
+		case <-l.lost:
+			cancel(ErrLockNotHeld)
+		case <-l.stop:
+			cancel(nil)
+		case <-ctx.Done():
+		}
+	}()
+	return ctx
+}
+
+// Release stops auto-renewal and deletes the lock if it is still held
+func (l *Lock) Release(ctx context.Context) error {
+	l.stopOnce.Do(func() { close(l.stop) })
+
+	result, err := l.client.runScript(ctx, lockReleaseScript, []string{l.key}, l.value)
+	if err != nil {
+		return fmt.Errorf("release lock script failed: %w", err)
+	}
+	if n, _ := result.(int64); n == 0 {
+		return ErrLockNotHeld
+	}
+	return nil
+}
+
+// watchdog renews the lock every third of its TTL. Failed renewals are
+// retried on the next tick while the last successful one, or the acquisition,
+// still covers it; once the lock could expire before the next tick it is
+// given up as lost, as another owner may obtain it.
+func (l *Lock) watchdog(renewed time.Time) {
+	interval := l.ttl / 3
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-ticker.C:
+			attempted := time.Now()
+			ctx, cancel := context.WithTimeout(context.Background(), interval)
+			result, err := l.client.runScript(ctx, lockRenewScript, []string{l.key}, l.value, l.ttl.Milliseconds())
+			cancel()
+
+			if err != nil {
+				if time.Until(renewed.Add(l.ttl)) > interval {
+					continue
+				}
+				close(l.lost)
+				return
+			}
+			if n, _ := result.(int64); n == 0 {
+				close(l.lost)
+				return
+			}
+			renewed = attempted
+		}
+	}
+}
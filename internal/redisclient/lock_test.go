@@ -0,0 +1,122 @@
+package redisclient
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestClient(t *testing.T) (*Client, *miniredis.Miniredis) {
+	t.Helper()
+
+	server := miniredis.RunT(t)
+	client, err := NewClient(server.Addr(), "", 0)
+	require.NoError(t, err)
+	t.Cleanup(func() { client.Close() })
+	return client, server
+}
+
+func TestLockFencingTokensIncrease(t *testing.T) {
+	client, _ := newTestClient(t)
+	ctx := context.Background()
+
+	first, err := client.ObtainLock(ctx, "order:1", time.Minute)
+	require.NoError(t, err)
+	_, err = client.ObtainLock(ctx, "order:1", time.Minute)
+	assert.ErrorIs(t, err, ErrLockNotObtained, "the lock is held")
+	require.NoError(t, first.Release(ctx))
+
+	second, err := client.ObtainLock(ctx, "order:1", time.Minute)
+	require.NoError(t, err)
+	defer second.Release(ctx)
+	assert.Greater(t, second.Token(), first.Token())
+
+	other, err := client.ObtainLock(ctx, "order:2", time.Minute)
+	require.NoError(t, err)
+	defer other.Release(ctx)
+	assert.Equal(t, int64(1), other.Token(), "tokens are counted per lock name")
+}
+
+func TestLockReleaseLeavesTheNextHolderAlone(t *testing.T) {
+	client, server := newTestClient(t)
+	ctx := context.Background()
+
+	stale, err := client.ObtainLock(ctx, "order:1", time.Minute)
+	require.NoError(t, err)
+	server.FastForward(time.Minute)
+	holder, err := client.ObtainLock(ctx, "order:1", time.Minute)
+	require.NoError(t, err)
+	defer holder.Release(ctx)
+
+	assert.ErrorIs(t, stale.Release(ctx), ErrLockNotHeld)
+	value, err := server.Get("lock:{order:1}")
+	require.NoError(t, err)
+	assert.Equal(t, holder.value, value, "the next holder keeps the lock")
+}
+
+func TestLockWatchdogRenewsTheLock(t *testing.T) {
+	client, server := newTestClient(t)
+	ctx := context.Background()
+
+	lock, err := client.ObtainLock(ctx, "order:1", 300*time.Millisecond)
+	require.NoError(t, err)
+	defer lock.Release(ctx)
+	server.FastForward(200 * time.Millisecond)
+
+	assert.Eventually(t, func() bool {
+		return server.TTL("lock:{order:1}") > 200*time.Millisecond
+	}, time.Second, 10*time.Millisecond)
+	select {
+	case <-lock.Lost():
+		t.Fatal("a renewed lock is not lost")
+	default:
+	}
+}
+
+func TestLockIsLostWhenRenewalsKeepFailing(t *testing.T) {
+	client, server := newTestClient(t)
+	ctx := context.Background()
+
+	lock, err := client.ObtainLock(ctx, "order:1", 300*time.Millisecond)
+	require.NoError(t, err)
+	held := lock.Context(ctx)
+
+	server.SetError("LOADING Redis is loading the dataset in memory")
+	select {
+	case <-lock.Lost():
+	case <-time.After(time.Second):
+		t.Fatal("the lock outlived its TTL without renewals")
+	}
+	<-held.Done()
+	assert.ErrorIs(t, context.Cause(held), ErrLockNotHeld)
+}
+
+func TestLockContextEndsWithTheLock(t *testing.T) {
+	client, server := newTestClient(t)
+	ctx := context.Background()
+
+	lock, err := client.ObtainLock(ctx, "order:1", 300*time.Millisecond)
+	require.NoError(t, err)
+	held := lock.Context(ctx)
+
+	// Another holder takes over, e.g. after the lock expired during a pause
+	require.NoError(t, server.Set("lock:{order:1}", "other"))
+	select {
+	case <-held.Done():
+	case <-time.After(time.Second):
+		t.Fatal("the context outlived the lock")
+	}
+	assert.ErrorIs(t, context.Cause(held), ErrLockNotHeld)
+	assert.ErrorIs(t, lock.Release(ctx), ErrLockNotHeld)
+
+	lock, err = client.ObtainLock(ctx, "order:2", time.Minute)
+	require.NoError(t, err)
+	held = lock.Context(ctx)
+	require.NoError(t, lock.Release(ctx))
+	<-held.Done()
+	assert.ErrorIs(t, context.Cause(held), context.Canceled, "releasing the lock ends its context")
+}
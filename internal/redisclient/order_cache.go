@@ -0,0 +1,61 @@
+package redisclient
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// OrderInvalidationChannel is the pub/sub channel API replicas listen on to evict cached orders
+const OrderInvalidationChannel = "order-cache:invalidate"
+
+// The keys of an order share a hash tag so the set script works in cluster mode
+func orderCacheKey(orderID int64) string {
+	return fmt.Sprintf("order-cache:{order:%d}", orderID)
+}
+
+// orderCacheGenerationKey counts the invalidations of an order
+func orderCacheGenerationKey(orderID int64) string {
+	return fmt.Sprintf("order-cache:{order:%d}:generation", orderID)
+}
+
+// GetCachedOrder returns the cached order payload, or nil when not cached
+func (c *Client) GetCachedOrder(ctx context.Context, orderID int64) ([]byte, error) {
+	data, err := c.rdb.Get(ctx, orderCacheKey(orderID)).Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	return data, err
+}
+
+// OrderCacheGeneration returns how often an order has been invalidated, to be
+// read before the order is loaded and passed to SetCachedOrder
+func (c *Client) OrderCacheGeneration(ctx context.Context, orderID int64) (int64, error) {
+	generation, err := c.rdb.Get(ctx, orderCacheGenerationKey(orderID)).Int64()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	return generation, err
+}
+
+// SetCachedOrder stores an order payload with TTL unless the order has been
+// invalidated since generation was read, reporting whether it was stored
+func (c *Client) SetCachedOrder(ctx context.Context, orderID, generation int64, data []byte, ttl time.Duration) (bool, error) {
+	keys := []string{orderCacheKey(orderID), orderCacheGenerationKey(orderID)}
+	stored, err := setCachedScript.Run(ctx, c.rdb, keys, generation, data, ttl.Milliseconds()).Int()
+	return stored == 1, err
+}
+
+// InvalidateOrder deletes the cached order, bumps its generation so that
+// copies loaded before are not cached, and notifies all subscribers
+func (c *Client) InvalidateOrder(ctx context.Context, orderID int64) error {
+	return c.invalidate(ctx, orderCacheKey(orderID), orderCacheGenerationKey(orderID), OrderInvalidationChannel, orderID)
+}
+
+// SubscribeOrderInvalidations calls onInvalidate for every order ID published on
+// the invalidation channel until ctx is cancelled
+func (c *Client) SubscribeOrderInvalidations(ctx context.Context, onInvalidate func(orderID int64)) error {
+	return c.subscribeInvalidations(ctx, OrderInvalidationChannel, onInvalidate)
+}
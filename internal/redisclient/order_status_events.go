@@ -0,0 +1,54 @@
+package redisclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// OrderStatusChannel carries order status transitions
+const OrderStatusChannel = "orders:status"
+
+// OrderStatusChange is the payload published on OrderStatusChannel
+type OrderStatusChange struct {
+	OrderID   int64     `json:"order_id"`
+	Status    string    `json:"status"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// PublishOrderStatusChange broadcasts an order status transition
+func (c *Client) PublishOrderStatusChange(ctx context.Context, change OrderStatusChange) error {
+	payload, err := json.Marshal(change)
+	if err != nil {
+		return err
+	}
+	return c.rdb.Publish(ctx, OrderStatusChannel, payload).Err()
+}
+
+// SubscribeOrderStatusChanges calls onChange for every order status transition until ctx is cancelled
+func (c *Client) SubscribeOrderStatusChanges(ctx context.Context, onChange func(OrderStatusChange)) error {
+	pubsub := c.rdb.Subscribe(ctx, OrderStatusChannel)
+	defer pubsub.Close()
+
+	if _, err := pubsub.Receive(ctx); err != nil {
+		return fmt.Errorf("failed to subscribe to %s: %w", OrderStatusChannel, err)
+	}
+
+	ch := pubsub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case msg, ok := <-ch:
+			if !ok {
+				return nil
+			}
+			var change OrderStatusChange
+			if err := json.Unmarshal([]byte(msg.Payload), &change); err != nil {
+				continue
+			}
+			onChange(change)
+		}
+	}
+}
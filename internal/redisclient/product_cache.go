@@ -0,0 +1,107 @@
+package redisclient
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// ProductInvalidationChannel is the pub/sub channel API replicas listen on to
+// evict locally cached products
+const ProductInvalidationChannel = "product-cache:invalidate"
+
+// The keys of a product share a hash tag so the set script works in cluster mode
+func productCacheKey(productID int64) string {
+	return fmt.Sprintf("product:{%d}", productID)
+}
+
+// productCacheGenerationKey counts the invalidations of a product
+func productCacheGenerationKey(productID int64) string {
+	return fmt.Sprintf("product:{%d}:generation", productID)
+}
+
+// GetCachedProducts returns cached product payloads keyed by product ID.
+// Products that are not cached are absent from the result.
+func (c *Client) GetCachedProducts(ctx context.Context, productIDs []int64) (map[int64][]byte, error) {
+	pipe := c.rdb.Pipeline()
+	cmds := make(map[int64]*redis.StringCmd, len(productIDs))
+	for _, id := range productIDs {
+		cmds[id] = pipe.Get(ctx, productCacheKey(id))
+	}
+
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return nil, fmt.Errorf("failed to read product cache: %w", err)
+	}
+
+	result := make(map[int64][]byte, len(productIDs))
+	for id, cmd := range cmds {
+		data, err := cmd.Bytes()
+		if err != nil {
+			continue
+		}
+		result[id] = data
+	}
+
+	return result, nil
+}
+
+// ProductCacheGenerations returns how often products have been invalidated,
+// keyed by product ID, to be read before they are loaded and passed to
+// SetCachedProducts
+func (c *Client) ProductCacheGenerations(ctx context.Context, productIDs []int64) (map[int64]int64, error) {
+	pipe := c.rdb.Pipeline()
+	cmds := make(map[int64]*redis.StringCmd, len(productIDs))
+	for _, id := range productIDs {
+		cmds[id] = pipe.Get(ctx, productCacheGenerationKey(id))
+	}
+
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return nil, fmt.Errorf("failed to read product cache generations: %w", err)
+	}
+
+	generations := make(map[int64]int64, len(productIDs))
+	for id, cmd := range cmds {
+		generation, err := cmd.Int64()
+		if err != nil && err != redis.Nil {
+			return nil, fmt.Errorf("failed to read product cache generation: %w", err)
+		}
+		generations[id] = generation
+	}
+	return generations, nil
+}
+
+// SetCachedProducts stores product payloads with TTL, except those of products
+// invalidated since their generation was read. Returns the IDs stored.
+func (c *Client) SetCachedProducts(ctx context.Context, products map[int64][]byte, generations map[int64]int64, ttl time.Duration) (map[int64]bool, error) {
+	pipe := c.rdb.Pipeline()
+	cmds := make(map[int64]*redis.Cmd, len(products))
+	for id, data := range products {
+		keys := []string{productCacheKey(id), productCacheGenerationKey(id)}
+		cmds[id] = setCachedScript.Eval(ctx, pipe, keys, generations[id], data, ttl.Milliseconds())
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, fmt.Errorf("failed to write product cache: %w", err)
+	}
+
+	stored := make(map[int64]bool, len(cmds))
+	for id, cmd := range cmds {
+		if n, _ := cmd.Int(); n == 1 {
+			stored[id] = true
+		}
+	}
+	return stored, nil
+}
+
+// InvalidateProduct deletes the cached product, bumps its generation so that
+// copies loaded before are not cached, and notifies all subscribers
+func (c *Client) InvalidateProduct(ctx context.Context, productID int64) error {
+	return c.invalidate(ctx, productCacheKey(productID), productCacheGenerationKey(productID), ProductInvalidationChannel, productID)
+}
+
+// SubscribeProductInvalidations calls onInvalidate for every product ID
+// published on the invalidation channel until ctx is cancelled
+func (c *Client) SubscribeProductInvalidations(ctx context.Context, onInvalidate func(productID int64)) error {
+	return c.subscribeInvalidations(ctx, ProductInvalidationChannel, onInvalidate)
+}
@@ -0,0 +1,27 @@
+package redisclient
+
+import (
+	"context"
+	"time"
+)
+
+func reportRunKey(run string) string {
+	return "reports:run:" + run
+}
+
+// ClaimReportRun claims a scheduled report run for lease, so one instance
+// generates it. Returns false when the run is claimed or done.
+func (c *Client) ClaimReportRun(ctx context.Context, run string, lease time.Duration) (bool, error) {
+	return c.rdb.SetNX(ctx, reportRunKey(run), "running", lease).Result()
+}
+
+// CompleteReportRun marks a claimed run done for retention, keeping it from
+// being generated again
+func (c *Client) CompleteReportRun(ctx context.Context, run string, retention time.Duration) error {
+	return c.rdb.Set(ctx, reportRunKey(run), "done", retention).Err()
+}
+
+// ReleaseReportRun gives up a claimed run that failed, so it is retried
+func (c *Client) ReleaseReportRun(ctx context.Context, run string) error {
+	return c.rdb.Del(ctx, reportRunKey(run)).Err()
+}
@@ -0,0 +1,70 @@
+package redisclient
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Reservation queue keys of a product share a hash tag so they live on one
+// slot in cluster mode
+func reservationQueueKey(productID int64) string {
+	return fmt.Sprintf("reservation-queue:{%d}:queue", productID)
+}
+
+func reservationResultKey(productID int64, requestID string) string {
+	return fmt.Sprintf("reservation-queue:{%d}:result:%s", productID, requestID)
+}
+
+// EnqueueReservation puts a reservation request at the back of a product's
+// queue and returns its position, 1 for the front
+func (c *Client) EnqueueReservation(ctx context.Context, productID int64, request []byte) (int64, error) {
+	return c.rdb.RPush(ctx, reservationQueueKey(productID), request).Result()
+}
+
+// NextReservation takes the reservation request at the front of a product's
+// queue, waiting up to timeout for one. It returns nil when none arrived.
+func (c *Client) NextReservation(ctx context.Context, productID int64, timeout time.Duration) ([]byte, error) {
+	values, err := c.rdb.BLPop(ctx, timeout, reservationQueueKey(productID)).Result()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return []byte(values[1]), nil
+}
+
+// ReservationQueueLength returns how many reservation requests wait in a
+// product's queue
+func (c *Client) ReservationQueueLength(ctx context.Context, productID int64) (int64, error) {
+	return c.rdb.LLen(ctx, reservationQueueKey(productID)).Result()
+}
+
+// CompleteReservation hands the result of a queued reservation request to the
+// requester waiting on it, kept for ttl in case it has not started waiting
+func (c *Client) CompleteReservation(ctx context.Context, productID int64, requestID string, result []byte, ttl time.Duration) error {
+	key := reservationResultKey(productID, requestID)
+	_, err := c.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.RPush(ctx, key, result)
+		pipe.PExpire(ctx, key, ttl)
+		return nil
+	})
+	return err
+}
+
+// AwaitReservation waits up to timeout for the result of a queued reservation
+// request. It returns nil when no result arrived.
+func (c *Client) AwaitReservation(ctx context.Context, productID int64, requestID string, timeout time.Duration) ([]byte, error) {
+	key := reservationResultKey(productID, requestID)
+	values, err := c.rdb.BLPop(ctx, timeout, key).Result()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return []byte(values[1]), nil
+}
@@ -0,0 +1,89 @@
+package redisclient
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+//go:embed scripts/claim_scheduled_events.lua
+var claimScheduledEventsScript string
+
+var claimScheduledScript = redis.NewScript(claimScheduledEventsScript)
+
+// Scheduled event keys share a hash tag so the claim script works in cluster mode
+const (
+	scheduledDueKey     = "events:{scheduled}:due"
+	scheduledClaimedKey = "events:{scheduled}:claimed"
+	scheduledDataKey    = "events:{scheduled}:data"
+)
+
+// ScheduledEvent is an event persisted in Redis until it is due for publishing
+type ScheduledEvent struct {
+	ID      string          `json:"id"`
+	Key     string          `json:"key"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+// ScheduleEvent persists an event to be published under key at the given time.
+// Scheduling an ID again replaces the pending event.
+func (c *Client) ScheduleEvent(ctx context.Context, id string, at time.Time, key string, payload []byte) error {
+	data, err := json.Marshal(ScheduledEvent{ID: id, Key: key, Payload: payload})
+	if err != nil {
+		return err
+	}
+
+	return c.withFailoverRetry(ctx, func() error {
+		_, err := c.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.HSet(ctx, scheduledDataKey, id, data)
+			pipe.ZAdd(ctx, scheduledDueKey, &redis.Z{Score: float64(at.UnixMilli()), Member: id})
+			return nil
+		})
+		return err
+	})
+}
+
+// ClaimDueEvents claims up to limit events due by now. A claimed event that is
+// not acknowledged within claimTTL becomes due again, so events survive a
+// dispatcher crash and are delivered at least once.
+func (c *Client) ClaimDueEvents(ctx context.Context, now time.Time, claimTTL time.Duration, limit int) ([]ScheduledEvent, error) {
+	keys := []string{scheduledDueKey, scheduledClaimedKey, scheduledDataKey}
+	result, err := c.runScript(ctx, claimScheduledScript, keys, now.UnixMilli(), now.Add(claimTTL).UnixMilli(), limit)
+	if err != nil {
+		return nil, fmt.Errorf("claim scheduled events script failed: %w", err)
+	}
+
+	rows, ok := result.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("unexpected script result type")
+	}
+	events := make([]ScheduledEvent, 0, len(rows))
+	for _, row := range rows {
+		data, _ := row.(string)
+		var event ScheduledEvent
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			return nil, fmt.Errorf("failed to decode scheduled event: %w", err)
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+// AckScheduledEvent removes a claimed event once it has been published or discarded
+func (c *Client) AckScheduledEvent(ctx context.Context, id string) error {
+	_, err := c.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZRem(ctx, scheduledClaimedKey, id)
+		pipe.HDel(ctx, scheduledDataKey, id)
+		return nil
+	})
+	return err
+}
+
+// CountScheduledEvents returns how many events are waiting to become due
+func (c *Client) CountScheduledEvents(ctx context.Context) (int64, error) {
+	return c.rdb.ZCard(ctx, scheduledDueKey).Result()
+}
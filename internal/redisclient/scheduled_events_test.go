@@ -0,0 +1,46 @@
+package redisclient
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScheduledEventsAreRedeliveredUntilAcked(t *testing.T) {
+	ctx := context.Background()
+	client, err := NewClient(miniredis.RunT(t).Addr(), "", 0)
+	require.NoError(t, err)
+	defer client.Close()
+
+	now := time.Now()
+	require.NoError(t, client.ScheduleEvent(ctx, "due", now.Add(-time.Second), "order-1", []byte(`{"order_id":1}`)))
+	require.NoError(t, client.ScheduleEvent(ctx, "later", now.Add(time.Hour), "order-2", []byte(`{"order_id":2}`)))
+
+	events, err := client.ClaimDueEvents(ctx, now, time.Minute, 10)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, "due", events[0].ID)
+	assert.Equal(t, "order-1", events[0].Key)
+	assert.JSONEq(t, `{"order_id":1}`, string(events[0].Payload))
+
+	// Held while claimed, due again once the claim expires
+	events, err = client.ClaimDueEvents(ctx, now, time.Minute, 10)
+	require.NoError(t, err)
+	assert.Empty(t, events)
+	events, err = client.ClaimDueEvents(ctx, now.Add(2*time.Minute), time.Minute, 10)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+
+	require.NoError(t, client.AckScheduledEvent(ctx, "due"))
+	events, err = client.ClaimDueEvents(ctx, now.Add(10*time.Minute), time.Minute, 10)
+	require.NoError(t, err)
+	assert.Empty(t, events)
+
+	pending, err := client.CountScheduledEvents(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), pending)
+}
@@ -0,0 +1,11 @@
+-- Acquire a lock and issue a fencing token
+-- KEYS[1] = lock key (e.g., "lock:{order:123}")
+-- KEYS[2] = fencing counter key (e.g., "lock-fence:{order:123}")
+-- ARGV[1] = unique lock value
+-- ARGV[2] = TTL in milliseconds
+
+if redis.call("SET", KEYS[1], ARGV[1], "NX", "PX", ARGV[2]) then
+    return redis.call("INCR", KEYS[2])  -- monotonically increasing fencing token
+end
+
+return 0  -- lock held by someone else
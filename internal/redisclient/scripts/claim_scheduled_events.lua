@@ -0,0 +1,27 @@
+-- Claim scheduled events that are due, returning expired claims to the queue first
+-- KEYS[1] = due sorted set (score = due time in ms)
+-- KEYS[2] = claimed sorted set (score = claim expiry in ms)
+-- KEYS[3] = event data hash
+-- ARGV[1] = now in ms
+-- ARGV[2] = claim expiry in ms
+-- ARGV[3] = maximum number of events to claim
+
+-- A dispatcher that died mid-publish leaves its claims to expire
+local expired = redis.call("ZRANGEBYSCORE", KEYS[2], "-inf", ARGV[1])
+for _, id in ipairs(expired) do
+    redis.call("ZREM", KEYS[2], id)
+    redis.call("ZADD", KEYS[1], ARGV[1], id)
+end
+
+local claimed = {}
+local ids = redis.call("ZRANGEBYSCORE", KEYS[1], "-inf", ARGV[1], "LIMIT", 0, ARGV[3])
+for _, id in ipairs(ids) do
+    redis.call("ZREM", KEYS[1], id)
+    local data = redis.call("HGET", KEYS[3], id)
+    if data then
+        redis.call("ZADD", KEYS[2], ARGV[2], id)
+        table.insert(claimed, data)
+    end
+end
+
+return claimed
@@ -1,5 +1,5 @@
 -- Commit reserved stock (final deduction)
--- KEYS[1] = inventory key
+-- KEYS[1] = inventory key of a product variant in a warehouse
 -- ARGV[1] = quantity to commit
 
 local reserved = tonumber(redis.call("HGET", KEYS[1], "reserved") or "0")
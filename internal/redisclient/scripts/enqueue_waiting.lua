@@ -0,0 +1,17 @@
+-- Put a request in line in the waiting room, numbering it after the last one
+-- KEYS[1] = ticket sequence counter
+-- KEYS[2] = waiting stream
+-- KEYS[3] = last admitted ticket
+-- KEYS[4] = ticket hash of the queue token
+-- ARGV[1] = queue token
+-- ARGV[2] = request payload
+-- ARGV[3] = ticket TTL in ms
+-- ARGV[4] = waiting status
+
+local ticket = redis.call("INCR", KEYS[1])
+redis.call("XADD", KEYS[2], "*", "token", ARGV[1], "ticket", ticket, "request", ARGV[2])
+redis.call("HSET", KEYS[4], "ticket", ticket, "status", ARGV[4])
+redis.call("PEXPIRE", KEYS[4], ARGV[3])
+
+local admitted = tonumber(redis.call("GET", KEYS[3]) or "0")
+return {ticket, admitted}
@@ -0,0 +1,39 @@
+-- Soft-hold stock for a holder, e.g. a cart in checkout, without reserving it
+-- KEYS[1]    = holds key of a product variant (e.g., "holds:{variant:123}"), a hash of
+--              holder -> "<quantity>:<expires at, Unix ms>"
+-- KEYS[2..n] = inventory key of the same variant in each warehouse
+-- ARGV[1] = holder
+-- ARGV[2] = quantity to hold
+-- ARGV[3] = hold TTL in milliseconds
+
+local time = redis.call("TIME")
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+local qty = tonumber(ARGV[2])
+local ttl = tonumber(ARGV[3])
+
+local available = 0
+for i = 2, #KEYS do
+    available = available + tonumber(redis.call("HGET", KEYS[i], "available") or "0")
+end
+
+-- Drop expired holds and count the units held for others
+local held = 0
+local holds = redis.call("HGETALL", KEYS[1])
+for i = 1, #holds, 2 do
+    local units, expires = string.match(holds[i + 1], "^(%d+):(%d+)$")
+    if tonumber(expires) <= now then
+        redis.call("HDEL", KEYS[1], holds[i])
+    elseif holds[i] ~= ARGV[1] then
+        held = held + tonumber(units)
+    end
+end
+
+if available - held < qty then
+    return 0  -- insufficient unheld stock
+end
+
+redis.call("HSET", KEYS[1], ARGV[1], qty .. ":" .. (now + ttl))
+if redis.call("PTTL", KEYS[1]) < ttl then
+    redis.call("PEXPIRE", KEYS[1], ttl)
+end
+return 1  -- success
@@ -0,0 +1,9 @@
+-- Release a lock only if it is still held by the caller
+-- KEYS[1] = lock key
+-- ARGV[1] = unique lock value
+
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+    return redis.call("DEL", KEYS[1])
+end
+
+return 0  -- lock expired or owned by another holder
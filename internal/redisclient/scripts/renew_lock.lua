@@ -0,0 +1,10 @@
+-- Extend a lock TTL only if it is still held by the caller
+-- KEYS[1] = lock key
+-- ARGV[1] = unique lock value
+-- ARGV[2] = TTL in milliseconds
+
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+    return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+
+return 0  -- lock lost
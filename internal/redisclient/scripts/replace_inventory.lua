@@ -0,0 +1,18 @@
+-- Overwrite the counts of a variant in a warehouse only if they are still the
+-- ones the caller read, so reservations made since are not wiped out
+-- KEYS[1] = inventory hash key
+-- KEYS[2] = warehouses set key of the variant
+-- ARGV[1] = available read, empty if the hash was missing
+-- ARGV[2] = reserved read, empty if the hash was missing
+-- ARGV[3] = new available
+-- ARGV[4] = new reserved
+-- ARGV[5] = warehouse ID
+
+local current = redis.call("HMGET", KEYS[1], "available", "reserved")
+if (current[1] or "") ~= ARGV[1] or (current[2] or "") ~= ARGV[2] then
+    return 0  -- changed since it was read
+end
+
+redis.call("HSET", KEYS[1], "available", ARGV[3], "reserved", ARGV[4])
+redis.call("SADD", KEYS[2], ARGV[5])
+return 1
@@ -1,5 +1,5 @@
 -- Reserve stock atomically
--- KEYS[1] = inventory key (e.g., "inventory:123")
+-- KEYS[1] = inventory key of a product variant in a warehouse (e.g., "inventory:{variant:123}:1", hash-tagged so it stays on one cluster slot)
 -- ARGV[1] = quantity to reserve
 
 local available = tonumber(redis.call("HGET", KEYS[1], "available") or "0")
@@ -0,0 +1,38 @@
+-- Reserve stock in a warehouse atomically, leaving the units soft-held for others
+-- KEYS[1]    = holds key of a product variant (see hold_stock.lua)
+-- KEYS[2]    = inventory key of the variant in the warehouse to reserve in
+-- KEYS[3..n] = inventory key of the variant in each warehouse, KEYS[2] included
+-- ARGV[1] = quantity to reserve
+-- ARGV[2] = holder reserving, whose own hold is consumed; empty for none
+
+local time = redis.call("TIME")
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+local qty = tonumber(ARGV[1])
+
+-- Holds are on the variant, so they count against stock in every warehouse
+local available = 0
+for i = 3, #KEYS do
+    available = available + tonumber(redis.call("HGET", KEYS[i], "available") or "0")
+end
+
+-- Count the unexpired units held for others
+local held = 0
+local holds = redis.call("HGETALL", KEYS[1])
+for i = 1, #holds, 2 do
+    local units, expires = string.match(holds[i + 1], "^(%d+):(%d+)$")
+    if holds[i] ~= ARGV[2] and tonumber(expires) > now then
+        held = held + tonumber(units)
+    end
+end
+
+local here = tonumber(redis.call("HGET", KEYS[2], "available") or "0")
+if here >= qty and available - held >= qty then
+    redis.call("HINCRBY", KEYS[2], "available", -qty)
+    redis.call("HINCRBY", KEYS[2], "reserved", qty)
+    if ARGV[2] ~= "" then
+        redis.call("HDEL", KEYS[1], ARGV[2])
+    end
+    return 1  -- success
+end
+
+return 0  -- insufficient stock
@@ -0,0 +1,14 @@
+-- Cache an entry only if it has not been invalidated since it was read
+-- KEYS[1] = cache key
+-- KEYS[2] = cache generation key
+-- ARGV[1] = generation read before the entry was loaded
+-- ARGV[2] = cached payload
+-- ARGV[3] = TTL in milliseconds
+
+local generation = redis.call("GET", KEYS[2]) or "0"
+if generation ~= ARGV[1] then
+    return 0  -- invalidated while the entry was loaded, it may be stale
+end
+
+redis.call("SET", KEYS[1], ARGV[2], "PX", ARGV[3])
+return 1
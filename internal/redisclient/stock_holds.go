@@ -0,0 +1,103 @@
+package redisclient
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+	"time"
+
+	"order-service/internal/tenant"
+
+	"github.com/go-redis/redis/v8"
+)
+
+//go:embed scripts/hold_stock.lua
+var holdStockScriptSource string
+
+//go:embed scripts/reserve_unheld_stock.lua
+var reserveUnheldStockScriptSource string
+
+var (
+	holdStockScript          = redis.NewScript(holdStockScriptSource)
+	reserveUnheldStockScript = redis.NewScript(reserveUnheldStockScriptSource)
+)
+
+// holdsKey returns the key of the soft holds on a product variant. It shares
+// the inventory keys' hash tag so scripts can read them on one cluster slot.
+func holdsKey(variantID int64) string {
+	return fmt.Sprintf("holds:{variant:%d}", variantID)
+}
+
+// holdField returns the field of holder's hold in the holds hash of a
+// variant, per tenant so one tenant's holder cannot consume another's hold.
+// The hash itself is shared, as a variant's stock is.
+func holdField(ctx context.Context, holder string) string {
+	if holder == "" {
+		return ""
+	}
+	return tenant.Key(ctx, holder)
+}
+
+// HoldStock soft-holds quantity units of a variant for holder until ttl
+// passes, replacing any hold holder already had on it. Held units stay
+// available but are left out of reservations by others; an expired hold
+// simply stops counting. Holds are on the variant, not a warehouse. Returns
+// false when fewer units are available across warehouses than are held for
+// others plus quantity.
+func (c *Client) HoldStock(ctx context.Context, variantID int64, holder string, quantity int, ttl time.Duration) (bool, error) {
+	keys, err := c.holdKeys(ctx, variantID)
+	if err != nil {
+		return false, err
+	}
+	result, err := c.runScript(ctx, holdStockScript, keys, holdField(ctx, holder), quantity, ttl.Milliseconds())
+	if err != nil {
+		return false, fmt.Errorf("hold stock script failed: %w", err)
+	}
+
+	success, ok := result.(int64)
+	if !ok {
+		return false, fmt.Errorf("unexpected script result type")
+	}
+	return success == 1, nil
+}
+
+// ReleaseHold drops holder's soft hold on a variant, if any
+func (c *Client) ReleaseHold(ctx context.Context, variantID int64, holder string) error {
+	return c.rdb.HDel(ctx, holdsKey(variantID), holdField(ctx, holder)).Err()
+}
+
+// ReserveUnheldStock reserves stock in a warehouse like ReserveStock, but
+// only out of the units of the variant not soft-held for others. A holder
+// reserving consumes its own hold.
+func (c *Client) ReserveUnheldStock(ctx context.Context, variantID, warehouseID int64, quantity int, holder string) (bool, error) {
+	keys, err := c.holdKeys(ctx, variantID)
+	if err != nil {
+		return false, err
+	}
+	keys = append([]string{keys[0], inventoryKey(variantID, warehouseID)}, keys[1:]...)
+	result, err := c.runScript(ctx, reserveUnheldStockScript, keys, quantity, holdField(ctx, holder))
+	if err != nil {
+		return false, fmt.Errorf("reserve unheld stock script failed: %w", err)
+	}
+
+	success, ok := result.(int64)
+	if !ok {
+		return false, fmt.Errorf("unexpected script result type")
+	}
+	return success == 1, nil
+}
+
+// holdKeys returns the holds key of a variant followed by its inventory key in
+// every warehouse, as the hold scripts take them
+func (c *Client) holdKeys(ctx context.Context, variantID int64) ([]string, error) {
+	warehouseIDs, err := c.variantWarehouses(ctx, variantID)
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]string, 0, len(warehouseIDs)+1)
+	keys = append(keys, holdsKey(variantID))
+	for _, warehouseID := range warehouseIDs {
+		keys = append(keys, inventoryKey(variantID, warehouseID))
+	}
+	return keys, nil
+}
@@ -0,0 +1,137 @@
+package redisclient
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+//go:embed scripts/enqueue_waiting.lua
+var enqueueWaitingScriptSource string
+
+var enqueueWaitingScript = redis.NewScript(enqueueWaitingScriptSource)
+
+// Waiting room keys share a hash tag so the enqueue script works in cluster mode
+const (
+	waitingSeqKey      = "waiting-room:{orders}:seq"
+	waitingStreamKey   = "waiting-room:{orders}:stream"
+	waitingAdmittedKey = "waiting-room:{orders}:admitted"
+	waitingClaimKey    = "waiting-room:{orders}:admission"
+)
+
+func waitingTicketKey(token string) string {
+	return "waiting-room:{orders}:ticket:" + token
+}
+
+// WaitingEntry is a request waiting in line in the waiting room
+type WaitingEntry struct {
+	// ID is the entry's stream ID
+	ID      string
+	Token   string
+	Ticket  int64
+	Request []byte
+}
+
+// WaitingTicket is the state of a queue token
+type WaitingTicket struct {
+	Ticket int64
+	Status string
+	// Result of the request once it was admitted
+	Result []byte
+	// Admitted is the ticket admitted last
+	Admitted int64
+}
+
+// EnqueueWaiting puts a request in line under token with status, and returns
+// its ticket number and the ticket admitted last. The token can be looked up
+// for ttl.
+func (c *Client) EnqueueWaiting(ctx context.Context, token string, request []byte, status string, ttl time.Duration) (int64, int64, error) {
+	keys := []string{waitingSeqKey, waitingStreamKey, waitingAdmittedKey, waitingTicketKey(token)}
+	result, err := c.runScript(ctx, enqueueWaitingScript, keys, token, request, ttl.Milliseconds(), status)
+	if err != nil {
+		return 0, 0, fmt.Errorf("enqueue waiting script failed: %w", err)
+	}
+
+	values, ok := result.([]interface{})
+	if !ok || len(values) != 2 {
+		return 0, 0, fmt.Errorf("unexpected script result type")
+	}
+	ticket, _ := values[0].(int64)
+	admitted, _ := values[1].(int64)
+	return ticket, admitted, nil
+}
+
+// WaitingLength returns how many requests are waiting in line
+func (c *Client) WaitingLength(ctx context.Context) (int64, error) {
+	return c.rdb.XLen(ctx, waitingStreamKey).Result()
+}
+
+// PeekWaiting returns up to count requests at the front of the line, first
+// in line first, leaving them in line
+func (c *Client) PeekWaiting(ctx context.Context, count int) ([]WaitingEntry, error) {
+	messages, err := c.rdb.XRangeN(ctx, waitingStreamKey, "-", "+", int64(count)).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]WaitingEntry, 0, len(messages))
+	for _, msg := range messages {
+		token, _ := msg.Values["token"].(string)
+		request, _ := msg.Values["request"].(string)
+		ticketValue, _ := msg.Values["ticket"].(string)
+		ticket, _ := strconv.ParseInt(ticketValue, 10, 64)
+		entries = append(entries, WaitingEntry{ID: msg.ID, Token: token, Ticket: ticket, Request: []byte(request)})
+	}
+	return entries, nil
+}
+
+// AdmitWaiting takes a request out of line, recording its status and result
+// on its token for ttl
+func (c *Client) AdmitWaiting(ctx context.Context, entry WaitingEntry, status string, result []byte, ttl time.Duration) error {
+	_, err := c.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		key := waitingTicketKey(entry.Token)
+		pipe.HSet(ctx, key, "ticket", entry.Ticket, "status", status, "result", result)
+		pipe.PExpire(ctx, key, ttl)
+		pipe.XDel(ctx, waitingStreamKey, entry.ID)
+		pipe.Set(ctx, waitingAdmittedKey, entry.Ticket, 0)
+		return nil
+	})
+	return err
+}
+
+// GetWaitingTicket returns the state of a queue token, or nil when it is
+// unknown or expired
+func (c *Client) GetWaitingTicket(ctx context.Context, token string) (*WaitingTicket, error) {
+	var fields *redis.StringStringMapCmd
+	var admitted *redis.StringCmd
+	_, err := c.rdb.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		fields = pipe.HGetAll(ctx, waitingTicketKey(token))
+		admitted = pipe.Get(ctx, waitingAdmittedKey)
+		return nil
+	})
+	if err != nil && err != redis.Nil {
+		return nil, err
+	}
+	if len(fields.Val()) == 0 {
+		return nil, nil
+	}
+
+	ticket := &WaitingTicket{
+		Status: fields.Val()["status"],
+		Result: []byte(fields.Val()["result"]),
+	}
+	ticket.Ticket, _ = strconv.ParseInt(fields.Val()["ticket"], 10, 64)
+	ticket.Admitted, _ = admitted.Int64()
+	return ticket, nil
+}
+
+// ClaimWaitingAdmission claims the admission of waiting requests for the next
+// interval, so one instance admits per interval. Returns false when another
+// instance claimed it.
+func (c *Client) ClaimWaitingAdmission(ctx context.Context, interval time.Duration) (bool, error) {
+	return c.rdb.SetNX(ctx, waitingClaimKey, 1, interval).Result()
+}
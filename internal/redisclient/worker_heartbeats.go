@@ -0,0 +1,32 @@
+package redisclient
+
+import "context"
+
+const workerHeartbeatsKey = "workers:heartbeats"
+
+// SaveWorkerHeartbeat stores the latest heartbeat of a worker instance
+func (c *Client) SaveWorkerHeartbeat(ctx context.Context, field string, payload []byte) error {
+	return c.rdb.HSet(ctx, workerHeartbeatsKey, field, payload).Err()
+}
+
+// GetWorkerHeartbeats returns the stored heartbeats keyed by worker instance
+func (c *Client) GetWorkerHeartbeats(ctx context.Context) (map[string][]byte, error) {
+	entries, err := c.rdb.HGetAll(ctx, workerHeartbeatsKey).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string][]byte, len(entries))
+	for field, payload := range entries {
+		result[field] = []byte(payload)
+	}
+	return result, nil
+}
+
+// DeleteWorkerHeartbeats removes the heartbeats of worker instances
+func (c *Client) DeleteWorkerHeartbeats(ctx context.Context, fields ...string) error {
+	if len(fields) == 0 {
+		return nil
+	}
+	return c.rdb.HDel(ctx, workerHeartbeatsKey, fields...).Err()
+}
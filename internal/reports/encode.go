@@ -0,0 +1,80 @@
+package reports
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+
+	"order-service/internal/models"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+// encoders write report rows in each format, row by row
+var encoders = map[string]func(w io.Writer, rows []models.OrderReportRow) error{
+	FormatCSV:     encodeCSV,
+	FormatJSON:    encodeJSON,
+	FormatParquet: encodeParquet,
+}
+
+// columns are the JSON names of the report fields, which CSV headers reuse
+var columns = func() []string {
+	t := reflect.TypeOf(models.OrderReportRow{})
+	names := make([]string, t.NumField())
+	for i := range names {
+		names[i], _, _ = strings.Cut(t.Field(i).Tag.Get("json"), ",")
+	}
+	return names
+}()
+
+func encodeCSV(w io.Writer, rows []models.OrderReportRow) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(columns); err != nil {
+		return err
+	}
+	record := make([]string, len(columns))
+	for _, row := range rows {
+		v := reflect.ValueOf(row)
+		for i := range record {
+			record[i] = fmt.Sprint(v.Field(i).Interface())
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// encodeJSON writes a JSON array, a row per line
+func encodeJSON(w io.Writer, rows []models.OrderReportRow) error {
+	if _, err := io.WriteString(w, "["); err != nil {
+		return err
+	}
+	for i, row := range rows {
+		sep := ",\n"
+		if i == 0 {
+			sep = "\n"
+		}
+		data, err := json.Marshal(row)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(w, sep+string(data)); err != nil {
+			return err
+		}
+	}
+	_, err := io.WriteString(w, "\n]\n")
+	return err
+}
+
+func encodeParquet(w io.Writer, rows []models.OrderReportRow) error {
+	pw := parquet.NewGenericWriter[models.OrderReportRow](w)
+	if _, err := pw.Write(rows); err != nil {
+		return err
+	}
+	return pw.Close()
+}
@@ -0,0 +1,63 @@
+package reports
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// Publisher stores generated reports under a key
+type Publisher interface {
+	Publish(ctx context.Context, key, contentType string, body []byte) error
+}
+
+// S3Publisher publishes reports to a bucket of S3-compatible storage
+type S3Publisher struct {
+	client *minio.Client
+	bucket string
+}
+
+// NewS3Publisher creates a publisher to bucket on endpoint (host[:port]).
+// Without an access key, credentials come from the AWS_* environment or the
+// instance's IAM role.
+func NewS3Publisher(endpoint, region, bucket, accessKey, secretKey string, useSSL bool) (*S3Publisher, error) {
+	creds := credentials.NewStaticV4(accessKey, secretKey, "")
+	if accessKey == "" {
+		creds = credentials.NewChainCredentials([]credentials.Provider{&credentials.EnvAWS{}, &credentials.IAM{}})
+	}
+	client, err := minio.New(endpoint, &minio.Options{Creds: creds, Secure: useSSL, Region: region})
+	if err != nil {
+		return nil, fmt.Errorf("invalid report storage endpoint %q: %w", endpoint, err)
+	}
+	return &S3Publisher{client: client, bucket: bucket}, nil
+}
+
+// Publish uploads a report, replacing any under the same key
+func (p *S3Publisher) Publish(ctx context.Context, key, contentType string, body []byte) error {
+	_, err := p.client.PutObject(ctx, p.bucket, key, bytes.NewReader(body), int64(len(body)),
+		minio.PutObjectOptions{ContentType: contentType})
+	if err != nil {
+		return fmt.Errorf("failed to upload %s to bucket %s: %w", key, p.bucket, err)
+	}
+	return nil
+}
+
+// URL returns the s3:// URL of the object stored under key
+func (p *S3Publisher) URL(key string) string {
+	return "s3://" + p.bucket + "/" + key
+}
+
+// SignedURL returns a link downloading the object stored under key without
+// credentials, valid for ttl
+func (p *S3Publisher) SignedURL(ctx context.Context, key string, ttl time.Duration) (string, error) {
+	signed, err := p.client.PresignedGetObject(ctx, p.bucket, key, ttl, url.Values{})
+	if err != nil {
+		return "", fmt.Errorf("failed to sign %s in bucket %s: %w", key, p.bucket, err)
+	}
+	return signed.String(), nil
+}
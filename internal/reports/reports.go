@@ -0,0 +1,71 @@
+package reports
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"order-service/internal/apperrors"
+	"order-service/internal/store"
+)
+
+// Formats a report is exported in
+const (
+	FormatCSV     = "csv"
+	FormatJSON    = "json"
+	FormatParquet = "parquet"
+)
+
+// Formats lists every export format
+var Formats = []string{FormatCSV, FormatJSON, FormatParquet}
+
+// dateLayout is how report days are written, in UTC
+const dateLayout = "2006-01-02"
+
+// Service exports the order report: per UTC day, the orders created, sales,
+// cancellations and payment failures
+type Service struct {
+	reports store.ReportRepository
+	maxDays int
+}
+
+// NewService creates a new report service serving reports of up to maxDays days
+func NewService(reports store.ReportRepository, maxDays int) *Service {
+	return &Service{reports: reports, maxDays: maxDays}
+}
+
+// ContentType returns the media type of a format
+func ContentType(format string) string {
+	switch format {
+	case FormatJSON:
+		return "application/json"
+	case FormatParquet:
+		return "application/vnd.apache.parquet"
+	default:
+		return "text/csv; charset=utf-8"
+	}
+}
+
+// WriteOrderReport writes the order report of the UTC days in [from, to) to w
+// in format. The report is aggregated before anything is written, so an error
+// leaves w untouched unless encoding fails.
+func (s *Service) WriteOrderReport(ctx context.Context, w io.Writer, format string, from, to time.Time) error {
+	encode, ok := encoders[format]
+	if !ok {
+		return apperrors.New(apperrors.ErrInvalidRequest, "unknown format %q, expected csv, json or parquet", format)
+	}
+	from, to = from.UTC().Truncate(24*time.Hour), to.UTC().Truncate(24*time.Hour)
+	if !to.After(from) {
+		return apperrors.New(apperrors.ErrInvalidRequest, "report range is empty: %s to %s", from.Format(dateLayout), to.Format(dateLayout))
+	}
+	if days := int(to.Sub(from).Hours() / 24); days > s.maxDays {
+		return apperrors.New(apperrors.ErrInvalidRequest, "report covers %d days, at most %d are allowed", days, s.maxDays)
+	}
+
+	rows, err := s.reports.GetOrderReport(ctx, from, to)
+	if err != nil {
+		return fmt.Errorf("failed to aggregate order report: %w", err)
+	}
+	return encode(w, rows)
+}
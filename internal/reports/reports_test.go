@@ -0,0 +1,101 @@
+package reports
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"order-service/internal/apperrors"
+	"order-service/internal/models"
+	"order-service/internal/redisclient"
+	"order-service/internal/store/mocks"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/parquet-go/parquet-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+var testRows = []models.OrderReportRow{
+	{Date: "2026-10-13", OrdersCreated: 4, OrdersPaid: 3, GrossSales: 9000, UnitsSold: 5, OrdersCancelled: 1, PaymentDeclines: 2, DeclinedAmount: 4000},
+	{Date: "2026-10-14"},
+}
+
+func TestWriteOrderReportEncodesEveryFormat(t *testing.T) {
+	ctx := context.Background()
+	repo := mocks.NewReportRepository(t)
+	from := time.Date(2026, 10, 13, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 0, 2)
+	repo.On("GetOrderReport", mock.Anything, from, to).Return(testRows, nil)
+	s := NewService(repo, 31)
+
+	var buf bytes.Buffer
+	require.NoError(t, s.WriteOrderReport(ctx, &buf, FormatCSV, from.Add(5*time.Hour), to))
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 3)
+	assert.Equal(t, "date,orders_created,orders_paid,gross_sales,units_sold,orders_cancelled,cancellation_fees,payment_declines,payment_declines_final,declined_amount", lines[0])
+	assert.Equal(t, "2026-10-13,4,3,9000,5,1,0,2,0,4000", lines[1])
+
+	buf.Reset()
+	require.NoError(t, s.WriteOrderReport(ctx, &buf, FormatJSON, from, to))
+	var decoded []models.OrderReportRow
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &decoded))
+	assert.Equal(t, testRows, decoded)
+
+	buf.Reset()
+	require.NoError(t, s.WriteOrderReport(ctx, &buf, FormatParquet, from, to))
+	read, err := parquet.Read[models.OrderReportRow](bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	assert.Equal(t, testRows, read)
+}
+
+func TestWriteOrderReportRejectsBadRequests(t *testing.T) {
+	s := NewService(mocks.NewReportRepository(t), 31)
+	from := time.Date(2026, 10, 13, 0, 0, 0, 0, time.UTC)
+
+	for name, err := range map[string]error{
+		"format":   s.WriteOrderReport(context.Background(), &bytes.Buffer{}, "xlsx", from, from.AddDate(0, 0, 1)),
+		"empty":    s.WriteOrderReport(context.Background(), &bytes.Buffer{}, FormatCSV, from, from),
+		"too long": s.WriteOrderReport(context.Background(), &bytes.Buffer{}, FormatCSV, from, from.AddDate(0, 0, 32)),
+	} {
+		assert.ErrorIs(t, err, apperrors.ErrInvalidRequest, name)
+	}
+}
+
+// recordedReports records what is published
+type recordedReports map[string][]byte
+
+func (r recordedReports) Publish(_ context.Context, key, _ string, body []byte) error {
+	r[key] = body
+	return nil
+}
+
+func TestSchedulerPublishesEachRunOnce(t *testing.T) {
+	ctx := context.Background()
+	redis, err := redisclient.NewClient(miniredis.RunT(t).Addr(), "", 0)
+	require.NoError(t, err)
+	t.Cleanup(func() { redis.Close() })
+
+	repo := mocks.NewReportRepository(t)
+	day := time.Date(2026, 10, 14, 0, 0, 0, 0, time.UTC)
+	repo.On("GetOrderReport", mock.Anything, day, day.AddDate(0, 0, 1)).Return(testRows[:1], nil).Twice()
+	schedule, err := ParseSchedule("15 0 * * *")
+	require.NoError(t, err)
+	published := recordedReports{}
+	s := NewScheduler(NewService(repo, 31), published, redis, schedule, []string{FormatCSV, FormatJSON}, "reports/")
+
+	now := time.Date(2026, 10, 15, 0, 16, 0, 0, time.UTC)
+	ran, err := s.RunDue(ctx, now)
+	require.NoError(t, err)
+	assert.True(t, ran)
+	assert.Contains(t, published, "reports/orders/2026-10-14.csv")
+	assert.Contains(t, published, "reports/orders/2026-10-14.json")
+
+	ran, err = s.RunDue(ctx, now.Add(time.Minute))
+	require.NoError(t, err)
+	assert.False(t, ran, "a run is generated once")
+}
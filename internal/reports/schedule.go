@@ -0,0 +1,128 @@
+package reports
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule is a cron expression: minute hour day-of-month month day-of-week,
+// in UTC. Fields take *, values, ranges (1-5), lists (1,15) and steps (*/10,
+// 0-30/5); Sunday is 0 or 7. As in cron, when both days are restricted a time
+// matches either.
+type Schedule struct {
+	minutes, hours, days, months, weekdays map[int]bool
+	anyDay, anyWeekday                     bool
+}
+
+// ParseSchedule parses a 5-field cron expression
+func ParseSchedule(expr string) (*Schedule, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("schedule %q must have 5 fields: minute hour day month weekday", expr)
+	}
+
+	s := &Schedule{anyDay: fields[2] == "*", anyWeekday: fields[4] == "*"}
+	var err error
+	for i, f := range []struct {
+		dest     *map[int]bool
+		min, max int
+	}{
+		{&s.minutes, 0, 59},
+		{&s.hours, 0, 23},
+		{&s.days, 1, 31},
+		{&s.months, 1, 12},
+		{&s.weekdays, 0, 7},
+	} {
+		if *f.dest, err = parseField(fields[i], f.min, f.max); err != nil {
+			return nil, fmt.Errorf("schedule %q: %w", expr, err)
+		}
+	}
+	if s.weekdays[7] {
+		s.weekdays[0] = true
+	}
+	return s, nil
+}
+
+func parseField(field string, min, max int) (map[int]bool, error) {
+	values := make(map[int]bool)
+	for _, part := range strings.Split(field, ",") {
+		spec, stepStr, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepStr)
+			if err != nil || n <= 0 {
+				return nil, fmt.Errorf("invalid step in %q", part)
+			}
+			step = n
+		}
+
+		lo, hi := min, max
+		if spec != "*" {
+			loStr, hiStr, isRange := strings.Cut(spec, "-")
+			var err error
+			if lo, err = strconv.Atoi(loStr); err != nil {
+				return nil, fmt.Errorf("invalid value in %q", part)
+			}
+			hi = lo
+			if isRange {
+				if hi, err = strconv.Atoi(hiStr); err != nil {
+					return nil, fmt.Errorf("invalid range in %q", part)
+				}
+			} else if hasStep {
+				hi = max
+			}
+		}
+		if lo < min || hi > max || lo > hi {
+			return nil, fmt.Errorf("%q is out of range %d-%d", part, min, max)
+		}
+		for v := lo; v <= hi; v += step {
+			values[v] = true
+		}
+	}
+	return values, nil
+}
+
+// dayMatches reports whether any time of t's day matches
+func (s *Schedule) dayMatches(t time.Time) bool {
+	if !s.months[int(t.Month())] {
+		return false
+	}
+	day, weekday := s.days[t.Day()], s.weekdays[int(t.Weekday())]
+	switch {
+	case s.anyDay && s.anyWeekday:
+		return true
+	case s.anyDay:
+		return weekday
+	case s.anyWeekday:
+		return day
+	default:
+		return day || weekday
+	}
+}
+
+// Matches reports whether the minute of t is scheduled
+func (s *Schedule) Matches(t time.Time) bool {
+	t = t.UTC()
+	return s.dayMatches(t) && s.hours[t.Hour()] && s.minutes[t.Minute()]
+}
+
+// Prev returns the latest scheduled minute at or before t, or the zero time
+// if none was in the year before t
+func (s *Schedule) Prev(t time.Time) time.Time {
+	t = t.UTC().Truncate(time.Minute)
+	for stop := t.AddDate(-1, 0, 0); t.After(stop); {
+		switch {
+		case !s.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC).Add(-time.Minute)
+		case !s.hours[t.Hour()]:
+			t = t.Truncate(time.Hour).Add(-time.Minute)
+		case !s.minutes[t.Minute()]:
+			t = t.Add(-time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
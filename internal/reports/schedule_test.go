@@ -0,0 +1,45 @@
+package reports
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScheduleMatchesCronFields(t *testing.T) {
+	s, err := ParseSchedule("*/15 9-17 * * 1-5")
+	require.NoError(t, err)
+
+	assert.True(t, s.Matches(time.Date(2026, 10, 14, 9, 45, 0, 0, time.UTC)), "Wednesday 09:45")
+	assert.False(t, s.Matches(time.Date(2026, 10, 14, 9, 50, 0, 0, time.UTC)), "not on a quarter")
+	assert.False(t, s.Matches(time.Date(2026, 10, 14, 18, 0, 0, 0, time.UTC)), "after hours")
+	assert.False(t, s.Matches(time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC)), "Sunday")
+
+	// Restricted day and weekday match either, and 7 is Sunday
+	s, err = ParseSchedule("0 0 1 * 7")
+	require.NoError(t, err)
+	assert.True(t, s.Matches(time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)), "the 1st, a Thursday")
+	assert.True(t, s.Matches(time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC)), "a Sunday")
+	assert.False(t, s.Matches(time.Date(2026, 10, 14, 0, 0, 0, 0, time.UTC)))
+
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* * 0 * *", "*/0 * * * *", "5-1 * * * *", "a * * * *"} {
+		_, err := ParseSchedule(expr)
+		assert.Error(t, err, expr)
+	}
+}
+
+func TestSchedulePrevFindsTheLatestRun(t *testing.T) {
+	s, err := ParseSchedule("15 0 * * *")
+	require.NoError(t, err)
+
+	now := time.Date(2026, 10, 15, 0, 14, 59, 0, time.UTC)
+	assert.Equal(t, time.Date(2026, 10, 14, 0, 15, 0, 0, time.UTC), s.Prev(now))
+	assert.Equal(t, time.Date(2026, 10, 15, 0, 15, 0, 0, time.UTC), s.Prev(now.Add(time.Second)))
+
+	// The 29th of February only comes every four years
+	s, err = ParseSchedule("0 0 29 2 *")
+	require.NoError(t, err)
+	assert.True(t, s.Prev(now).IsZero())
+}
@@ -0,0 +1,93 @@
+package reports
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"time"
+
+	"order-service/internal/redisclient"
+	"order-service/internal/util"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// runLease bounds how long an instance that died while generating a run
+	// keeps the others from retrying it
+	runLease = 10 * time.Minute
+	// runRetention is how long a run is remembered as done; a run older than
+	// the schedule's previous one is never due again
+	runRetention = 8 * 24 * time.Hour
+)
+
+// Scheduler generates the order report of the day before on a schedule, in
+// every configured format, and publishes it to <prefix>orders/<date>.<format>
+type Scheduler struct {
+	reports   *Service
+	publisher Publisher
+	redis     *redisclient.Client
+	schedule  *Schedule
+	formats   []string
+	prefix    string
+	logger    *zap.Logger
+}
+
+// NewScheduler creates a new report scheduler
+func NewScheduler(reports *Service, publisher Publisher, redis *redisclient.Client, schedule *Schedule, formats []string, prefix string) *Scheduler {
+	return &Scheduler{
+		reports:   reports,
+		publisher: publisher,
+		redis:     redis,
+		schedule:  schedule,
+		formats:   formats,
+		prefix:    prefix,
+		logger:    util.GetLogger(),
+	}
+}
+
+// RunDue generates the latest scheduled run at or before now unless an
+// instance already did, and reports whether it did. A run that fails is
+// retried on the next call, and a run missed while no instance was up is
+// caught up until the next one is due.
+func (s *Scheduler) RunDue(ctx context.Context, now time.Time) (bool, error) {
+	due := s.schedule.Prev(now)
+	if due.IsZero() {
+		return false, nil
+	}
+	run := due.Format(time.RFC3339)
+	claimed, err := s.redis.ClaimReportRun(ctx, run, runLease)
+	if err != nil || !claimed {
+		return false, err
+	}
+
+	if err := s.generate(ctx, due); err != nil {
+		if releaseErr := s.redis.ReleaseReportRun(ctx, run); releaseErr != nil {
+			s.logger.Warn("Failed to release report run", zap.String("run", run), zap.Error(releaseErr))
+		}
+		return false, err
+	}
+	return true, s.redis.CompleteReportRun(ctx, run, runRetention)
+}
+
+// generate publishes the order report of the UTC day before due in every format
+func (s *Scheduler) generate(ctx context.Context, due time.Time) error {
+	to := due.UTC().Truncate(24 * time.Hour)
+	from := to.AddDate(0, 0, -1)
+
+	for _, format := range s.formats {
+		var buf bytes.Buffer
+		if err := s.reports.WriteOrderReport(ctx, &buf, format, from, to); err != nil {
+			util.ReportsPublishedTotal.WithLabelValues(format, "failed").Inc()
+			return err
+		}
+		key := fmt.Sprintf("%sorders/%s.%s", s.prefix, from.Format(dateLayout), format)
+		if err := s.publisher.Publish(ctx, key, ContentType(format), buf.Bytes()); err != nil {
+			util.ReportsPublishedTotal.WithLabelValues(format, "failed").Inc()
+			return err
+		}
+		util.ReportsPublishedTotal.WithLabelValues(format, "published").Inc()
+		s.logger.Info("Published order report", zap.String("key", key), zap.Int("bytes", buf.Len()))
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+// Package retry runs operations with jittered exponential backoff.
+package retry
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"time"
+)
+
+// Policy controls how an operation is retried
+type Policy struct {
+	// MaxAttempts is the total number of attempts including the first; values below 1 mean one attempt
+	MaxAttempts int
+	// BaseDelay is the delay before the first retry; it doubles on every retry
+	BaseDelay time.Duration
+	// MaxDelay caps a single delay; zero means uncapped
+	MaxDelay time.Duration
+	// Jitter randomizes each delay by ±Jitter of its value (0 to 1)
+	Jitter float64
+	// Retryable classifies errors; nil retries every error
+	Retryable func(error) bool
+	// OnRetry is called before sleeping for retry number attempt
+	OnRetry func(attempt int, err error)
+}
+
+// permanentError stops retries regardless of the policy's classifier
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent wraps err so Do returns it without further attempts
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// Do runs fn until it succeeds, fails with a non-retryable error, runs out of
+// attempts or ctx is done. The last error from fn is returned unwrapped.
+func Do(ctx context.Context, p Policy, fn func(ctx context.Context) error) error {
+	attempts := p.MaxAttempts
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = fn(ctx)
+		if err == nil {
+			return nil
+		}
+
+		var perm *permanentError
+		if errors.As(err, &perm) {
+			return perm.err
+		}
+		if p.Retryable != nil && !p.Retryable(err) {
+			return err
+		}
+		if attempt >= attempts {
+			return err
+		}
+
+		if p.OnRetry != nil {
+			p.OnRetry(attempt, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(p.Delay(attempt)):
+		}
+	}
+}
+
+// Delay returns the jittered backoff before retry number n (starting at 1)
+func (p Policy) Delay(n int) time.Duration {
+	delay := p.BaseDelay
+	for i := 1; i < n && (p.MaxDelay == 0 || delay < p.MaxDelay); i++ {
+		delay *= 2
+	}
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+
+	if p.Jitter > 0 && delay > 0 {
+		spread := float64(delay) * p.Jitter
+		delay = time.Duration(float64(delay) - spread + rand.Float64()*2*spread)
+	}
+	return delay
+}
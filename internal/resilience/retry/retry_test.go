@@ -0,0 +1,87 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var errTransient = errors.New("transient")
+
+func TestDoRetriesUntilSuccess(t *testing.T) {
+	calls := 0
+	err := Do(context.Background(), Policy{MaxAttempts: 3, BaseDelay: time.Millisecond}, func(ctx context.Context) error {
+		calls++
+		if calls < 3 {
+			return errTransient
+		}
+		return nil
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, calls)
+}
+
+func TestDoStopsOnNonRetryableError(t *testing.T) {
+	policy := Policy{
+		MaxAttempts: 5,
+		BaseDelay:   time.Millisecond,
+		Retryable:   func(err error) bool { return errors.Is(err, errTransient) },
+	}
+
+	calls := 0
+	err := Do(context.Background(), policy, func(ctx context.Context) error {
+		calls++
+		return errors.New("bad request")
+	})
+
+	assert.EqualError(t, err, "bad request")
+	assert.Equal(t, 1, calls)
+}
+
+func TestDoStopsOnPermanentError(t *testing.T) {
+	calls := 0
+	err := Do(context.Background(), Policy{MaxAttempts: 5}, func(ctx context.Context) error {
+		calls++
+		return Permanent(errTransient)
+	})
+
+	assert.Equal(t, errTransient, err)
+	assert.Equal(t, 1, calls)
+}
+
+func TestDoReturnsLastErrorWhenExhausted(t *testing.T) {
+	retries := 0
+	policy := Policy{
+		MaxAttempts: 3,
+		BaseDelay:   time.Millisecond,
+		OnRetry:     func(attempt int, err error) { retries++ },
+	}
+
+	err := Do(context.Background(), policy, func(ctx context.Context) error { return errTransient })
+
+	assert.Equal(t, errTransient, err)
+	assert.Equal(t, 2, retries)
+}
+
+func TestDelayBacksOffExponentiallyWithCap(t *testing.T) {
+	p := Policy{BaseDelay: 10 * time.Millisecond, MaxDelay: 50 * time.Millisecond}
+
+	assert.Equal(t, 10*time.Millisecond, p.Delay(1))
+	assert.Equal(t, 20*time.Millisecond, p.Delay(2))
+	assert.Equal(t, 40*time.Millisecond, p.Delay(3))
+	assert.Equal(t, 50*time.Millisecond, p.Delay(4))
+}
+
+func TestDelayJitterStaysInBounds(t *testing.T) {
+	p := Policy{BaseDelay: 100 * time.Millisecond, Jitter: 0.5}
+
+	for i := 0; i < 100; i++ {
+		d := p.Delay(1)
+		assert.GreaterOrEqual(t, d, 50*time.Millisecond)
+		assert.LessOrEqual(t, d, 150*time.Millisecond)
+	}
+}
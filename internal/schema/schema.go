@@ -0,0 +1,177 @@
+package schema
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+
+	"order-service/internal/models"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+//go:embed schemas/*.json
+var files embed.FS
+
+// Request schema names
+const (
+	CreateOrderRequest = "create_order_request.json"
+)
+
+// eventSchemas maps event types to their schema file
+var eventSchemas = map[string]string{
+	models.EventTypeOrderCreated:   "order_created_event.json",
+	models.EventTypeOrderReserved:  "order_reserved_event.json",
+	models.EventTypeOrderPaid:      "order_paid_event.json",
+	models.EventTypeOrderCancelled: "order_cancelled_event.json",
+	models.EventTypePaymentSuccess: "payment_success_event.json",
+	models.EventTypePaymentFailed:  "payment_failed_event.json",
+	models.EventTypePaymentVoided:  "payment_voided_event.json",
+	models.EventTypeOrderOnHold:    "order_on_hold_event.json",
+
+	models.EventTypePaymentRefunded: "payment_refunded_event.json",
+
+	models.EventTypeOrderStatusChanged: "order_status_changed_event.json",
+
+	models.EventTypePaymentReminder:     "payment_reminder_event.json",
+	models.EventTypeReservationExpiring: "reservation_expiring_event.json",
+
+	models.EventTypeOrderBackordered:     "order_backordered_event.json",
+	models.EventTypeBackorderRescheduled: "backorder_rescheduled_event.json",
+	models.EventTypeBackorderFulfilled:   "backorder_fulfilled_event.json",
+
+	models.EventTypeOrderAmended: "order_amended_event.json",
+
+	models.EventTypeInventoryLow: "inventory_low_event.json",
+
+	models.EventTypeUserAnonymizationProgress:  "user_anonymization_progress_event.json",
+	models.EventTypeUserAnonymizationCompleted: "user_anonymization_completed_event.json",
+}
+
+// Registry holds the compiled JSON Schemas for requests and events
+type Registry struct {
+	raw      map[string][]byte
+	compiled map[string]*jsonschema.Schema
+}
+
+// NewRegistry compiles all embedded schemas
+func NewRegistry() (*Registry, error) {
+	compiler := jsonschema.NewCompiler()
+	compiler.Draft = jsonschema.Draft7
+	compiler.AssertFormat = true
+
+	entries, err := fs.ReadDir(files, "schemas")
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Registry{
+		raw:      make(map[string][]byte, len(entries)),
+		compiled: make(map[string]*jsonschema.Schema, len(entries)),
+	}
+
+	for _, entry := range entries {
+		data, err := files.ReadFile(path.Join("schemas", entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		r.raw[entry.Name()] = data
+		if err := compiler.AddResource(entry.Name(), bytes.NewReader(data)); err != nil {
+			return nil, fmt.Errorf("failed to load schema %s: %w", entry.Name(), err)
+		}
+	}
+
+	for name := range r.raw {
+		compiled, err := compiler.Compile(name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compile schema %s: %w", name, err)
+		}
+		r.compiled[name] = compiled
+	}
+
+	return r, nil
+}
+
+// Names returns the names of all published schemas
+func (r *Registry) Names() []string {
+	names := make([]string, 0, len(r.raw))
+	for name := range r.raw {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Raw returns the schema document with the given name
+func (r *Registry) Raw(name string) ([]byte, bool) {
+	data, ok := r.raw[name]
+	return data, ok
+}
+
+// Validate validates a JSON document against the named schema
+func (r *Registry) Validate(name string, payload []byte) error {
+	compiled, ok := r.compiled[name]
+	if !ok {
+		return fmt.Errorf("unknown schema %s", name)
+	}
+
+	var doc interface{}
+	if err := json.Unmarshal(payload, &doc); err != nil {
+		return fmt.Errorf("invalid JSON: %w", err)
+	}
+
+	if err := compiled.Validate(doc); err != nil {
+		return flatten(err)
+	}
+	return nil
+}
+
+// ValidateEvent validates an event payload against the schema of its event_type.
+// Events without a published schema are accepted.
+func (r *Registry) ValidateEvent(payload []byte) error {
+	var base models.BaseEvent
+	if err := json.Unmarshal(payload, &base); err != nil {
+		return fmt.Errorf("invalid event JSON: %w", err)
+	}
+
+	name, ok := eventSchemas[base.EventType]
+	if !ok {
+		return nil
+	}
+	if err := r.Validate(name, payload); err != nil {
+		return fmt.Errorf("event %s violates schema: %w", base.EventType, err)
+	}
+	return nil
+}
+
+// flatten turns a nested validation error into a single readable line
+func flatten(err error) error {
+	verr, ok := err.(*jsonschema.ValidationError)
+	if !ok {
+		return err
+	}
+
+	var msgs []string
+	var walk func(e *jsonschema.ValidationError)
+	walk = func(e *jsonschema.ValidationError) {
+		if len(e.Causes) == 0 {
+			loc := e.InstanceLocation
+			if loc == "" {
+				loc = "/"
+			}
+			msgs = append(msgs, fmt.Sprintf("%s: %s", loc, e.Message))
+			return
+		}
+		for _, cause := range e.Causes {
+			walk(cause)
+		}
+	}
+	walk(verr)
+
+	return fmt.Errorf("%s", strings.Join(msgs, "; "))
+}
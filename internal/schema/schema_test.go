@@ -0,0 +1,43 @@
+package schema
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateCreateOrderRequest(t *testing.T) {
+	r, err := NewRegistry()
+	require.NoError(t, err)
+
+	valid := `{"user_id": 1, "items": [{"product_id": 1, "quantity": 2}], "payment_method": "mock"}`
+	assert.NoError(t, r.Validate(CreateOrderRequest, []byte(valid)))
+
+	invalid := `{"user_id": 1, "items": [{"product_id": 1, "quantity": 0}], "payment_method": "mock"}`
+	err = r.Validate(CreateOrderRequest, []byte(invalid))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "/items/0/quantity")
+
+	billing := `{"user_id": 1, "items": [{"product_id": 1, "quantity": 2}], "payment_method": "mock",
+		"billing": {"company": "Acme", "country": "DE", "tax_id": "DE123456789"}}`
+	assert.NoError(t, r.Validate(CreateOrderRequest, []byte(billing)))
+
+	noTaxID := `{"user_id": 1, "items": [{"product_id": 1, "quantity": 2}], "payment_method": "mock",
+		"billing": {"company": "Acme", "country": "DE"}}`
+	assert.Error(t, r.Validate(CreateOrderRequest, []byte(noTaxID)))
+}
+
+func TestValidateEvent(t *testing.T) {
+	r, err := NewRegistry()
+	require.NoError(t, err)
+
+	valid := `{"event_id": "e1", "event_type": "ORDER_CANCELLED", "timestamp": "2026-10-15T10:00:00Z", "order_id": 5, "reason": "payment_timeout"}`
+	assert.NoError(t, r.ValidateEvent([]byte(valid)))
+
+	missingReason := `{"event_id": "e1", "event_type": "ORDER_CANCELLED", "timestamp": "2026-10-15T10:00:00Z", "order_id": 5}`
+	assert.Error(t, r.ValidateEvent([]byte(missingReason)))
+
+	unknown := `{"event_id": "e1", "event_type": "SOMETHING_ELSE", "timestamp": "2026-10-15T10:00:00Z"}`
+	assert.NoError(t, r.ValidateEvent([]byte(unknown)))
+}
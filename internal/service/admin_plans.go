@@ -0,0 +1,233 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"order-service/internal/apperrors"
+	"order-service/internal/redisclient"
+	"order-service/internal/util"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// Destructive admin operations that can be previewed with dry_run and applied by plan token
+const (
+	PlanForceTransition = "force_transition"
+	PlanInventoryResync = "inventory_resync"
+)
+
+// AdminPlan is a previewed destructive admin operation. Applying it checks
+// that the operation would still have the previewed effect.
+type AdminPlan struct {
+	Token     string          `json:"plan_token"`
+	Operation string          `json:"operation"`
+	Params    json.RawMessage `json:"params"`
+	Effect    json.RawMessage `json:"effect"`
+	CreatedBy string          `json:"created_by"`
+	ExpiresAt time.Time       `json:"expires_at"`
+}
+
+type forceTransitionParams struct {
+	OrderID int64  `json:"order_id"`
+	Status  string `json:"status"`
+	Reason  string `json:"reason"`
+}
+
+type inventoryResyncParams struct {
+	Strategy string `json:"strategy"`
+}
+
+// StockDelta is how a resync would change the counts of one drifted variant
+type StockDelta struct {
+	ProductID      int64  `json:"product_id"`
+	VariantID      int64  `json:"variant_id"`
+	Target         string `json:"target"` // redis or database
+	AvailableDelta int    `json:"available_delta"`
+	ReservedDelta  int    `json:"reserved_delta"`
+}
+
+// ResyncEffect is what an inventory resync would change
+type ResyncEffect struct {
+	Strategy     string           `json:"strategy"`
+	Drifted      []InventoryDrift `json:"drifted"`
+	StockDeltas  []StockDelta     `json:"stock_deltas"`
+	RowsAffected int              `json:"rows_affected"`
+}
+
+// AdminPlanner previews destructive admin operations and applies previewed
+// plans by token, at most once and only while their effect is unchanged
+type AdminPlanner struct {
+	redis     *redisclient.Client
+	saga      *SagaOrchestrator
+	inventory *InventoryClient
+	ttl       time.Duration
+	logger    *zap.Logger
+}
+
+// NewAdminPlanner creates a new admin planner
+func NewAdminPlanner(redis *redisclient.Client, saga *SagaOrchestrator, inventory *InventoryClient, ttl time.Duration) *AdminPlanner {
+	return &AdminPlanner{
+		redis:     redis,
+		saga:      saga,
+		inventory: inventory,
+		ttl:       ttl,
+		logger:    util.GetLogger(),
+	}
+}
+
+// PlanForceTransition previews forcing an order's status
+func (p *AdminPlanner) PlanForceTransition(ctx context.Context, orderID int64, status, reason, actor string) (*AdminPlan, error) {
+	effect, err := p.saga.PreviewForceTransition(ctx, orderID, status)
+	if err != nil {
+		return nil, err
+	}
+	return p.save(ctx, PlanForceTransition, forceTransitionParams{OrderID: orderID, Status: status, Reason: reason}, effect, actor)
+}
+
+// PlanInventoryResync previews reconciling Redis inventory against the database
+func (p *AdminPlanner) PlanInventoryResync(ctx context.Context, strategy, actor string) (*AdminPlan, error) {
+	effect, err := p.resyncEffect(ctx, strategy)
+	if err != nil {
+		return nil, err
+	}
+	return p.save(ctx, PlanInventoryResync, inventoryResyncParams{Strategy: strategy}, effect, actor)
+}
+
+// resyncEffect computes the stock each side of a resync would change by
+func (p *AdminPlanner) resyncEffect(ctx context.Context, strategy string) (*ResyncEffect, error) {
+	drifts, err := p.inventory.FindInventoryDrift(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	effect := &ResyncEffect{Strategy: strategy, Drifted: []InventoryDrift{}, StockDeltas: []StockDelta{}}
+	for _, drift := range drifts {
+		effect.Drifted = append(effect.Drifted, drift)
+
+		delta := StockDelta{ProductID: drift.ProductID, VariantID: drift.VariantID, Target: "redis"}
+		switch {
+		case strategy == ReconcileAlertOnly:
+			continue
+		case drift.Missing:
+			delta.AvailableDelta, delta.ReservedDelta = drift.DBAvailable, drift.DBReserved
+		case strategy == ReconcileDBWins:
+			delta.AvailableDelta = drift.DBAvailable - drift.RedisAvailable
+			delta.ReservedDelta = drift.DBReserved - drift.RedisReserved
+		case strategy == ReconcileRedisWins:
+			delta.Target = "database"
+			delta.AvailableDelta = drift.RedisAvailable - drift.DBAvailable
+			delta.ReservedDelta = drift.RedisReserved - drift.DBReserved
+		}
+		effect.StockDeltas = append(effect.StockDeltas, delta)
+		effect.RowsAffected++
+	}
+	return effect, nil
+}
+
+// save stores a plan under a fresh token
+func (p *AdminPlanner) save(ctx context.Context, operation string, params, effect interface{}, actor string) (*AdminPlan, error) {
+	plan := &AdminPlan{
+		Token:     uuid.New().String(),
+		Operation: operation,
+		CreatedBy: actor,
+		ExpiresAt: time.Now().Add(p.ttl).UTC(),
+	}
+
+	var err error
+	if plan.Params, err = json.Marshal(params); err != nil {
+		return nil, fmt.Errorf("failed to marshal plan params: %w", err)
+	}
+	if plan.Effect, err = json.Marshal(effect); err != nil {
+		return nil, fmt.Errorf("failed to marshal plan effect: %w", err)
+	}
+
+	data, err := json.Marshal(plan)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal plan: %w", err)
+	}
+	if err := p.redis.SaveAdminPlan(ctx, plan.Token, data, p.ttl); err != nil {
+		return nil, fmt.Errorf("failed to save plan: %w", err)
+	}
+
+	util.AdminActionsTotal.WithLabelValues("dry_run_" + operation).Inc()
+	return plan, nil
+}
+
+// Apply executes a previewed plan if authorize allows its operation. The plan
+// is consumed even if it turns out to be stale or is refused, so a changed
+// state always needs a fresh preview.
+func (p *AdminPlanner) Apply(ctx context.Context, token, actor string, authorize func(operation string) error) (*AdminPlan, error) {
+	data, err := p.redis.TakeAdminPlan(ctx, token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load plan: %w", err)
+	}
+	if data == nil {
+		return nil, apperrors.New(apperrors.ErrNotFound, "plan %s not found or expired", token)
+	}
+
+	var plan AdminPlan
+	if err := json.Unmarshal(data, &plan); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal plan: %w", err)
+	}
+	if err := authorize(plan.Operation); err != nil {
+		return nil, err
+	}
+
+	switch plan.Operation {
+	case PlanForceTransition:
+		err = p.applyForceTransition(ctx, &plan, actor)
+	case PlanInventoryResync:
+		err = p.applyInventoryResync(ctx, &plan)
+	default:
+		err = apperrors.New(apperrors.ErrInvalidRequest, "unknown plan operation %q", plan.Operation)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	p.logger.Warn("Admin plan applied",
+		zap.String("plan_token", plan.Token),
+		zap.String("operation", plan.Operation),
+		zap.String("created_by", plan.CreatedBy),
+		zap.String("applied_by", actor))
+	return &plan, nil
+}
+
+func (p *AdminPlanner) applyForceTransition(ctx context.Context, plan *AdminPlan, actor string) error {
+	var params forceTransitionParams
+	var effect TransitionEffect
+	if err := json.Unmarshal(plan.Params, &params); err != nil {
+		return fmt.Errorf("failed to unmarshal plan params: %w", err)
+	}
+	if err := json.Unmarshal(plan.Effect, &effect); err != nil {
+		return fmt.Errorf("failed to unmarshal plan effect: %w", err)
+	}
+	return p.saga.ForceTransitionFrom(ctx, params.OrderID, effect.FromStatus, params.Status, params.Reason, actor)
+}
+
+func (p *AdminPlanner) applyInventoryResync(ctx context.Context, plan *AdminPlan) error {
+	var params inventoryResyncParams
+	if err := json.Unmarshal(plan.Params, &params); err != nil {
+		return fmt.Errorf("failed to unmarshal plan params: %w", err)
+	}
+
+	current, err := p.resyncEffect(ctx, params.Strategy)
+	if err != nil {
+		return err
+	}
+	currentEffect, err := json.Marshal(current)
+	if err != nil {
+		return fmt.Errorf("failed to marshal plan effect: %w", err)
+	}
+	if !bytes.Equal(currentEffect, plan.Effect) {
+		return apperrors.New(apperrors.ErrStalePlan, "inventory drift changed since the plan was previewed")
+	}
+
+	_, err = p.inventory.ReconcileInventory(ctx, params.Strategy)
+	return err
+}
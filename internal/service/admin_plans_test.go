@@ -0,0 +1,111 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"order-service/internal/apperrors"
+	"order-service/internal/models"
+	"order-service/internal/store/mocks"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlanInventoryResyncReportsStockDeltas(t *testing.T) {
+	ctx := context.Background()
+	inventory := mocks.NewInventoryRepository(t)
+	inventory.On("GetProducts", mock.Anything).Return([]models.Product{{ID: 10}}, nil)
+	inventory.On("GetInventories", mock.Anything).
+		Return([]models.Inventory{{ProductID: 10, VariantID: 11, WarehouseID: models.DefaultWarehouseID, Available: 90, Reserved: 10}}, nil)
+
+	redis := newTestRedis(t)
+	require.NoError(t, redis.InitInventory(ctx, 11, models.DefaultWarehouseID, 95, 5))
+	planner := NewAdminPlanner(redis, nil, NewInventoryClient(inventory, redis), time.Minute)
+
+	plan, err := planner.PlanInventoryResync(ctx, ReconcileDBWins, "admin:operator")
+	require.NoError(t, err)
+
+	var effect ResyncEffect
+	require.NoError(t, json.Unmarshal(plan.Effect, &effect))
+	assert.Equal(t, 1, effect.RowsAffected)
+	assert.Equal(t, []StockDelta{{ProductID: 10, VariantID: 11, Target: "redis", AvailableDelta: -5, ReservedDelta: 5}}, effect.StockDeltas)
+
+	available, _, err := redis.GetInventory(ctx, 11)
+	require.NoError(t, err)
+	assert.Equal(t, 95, available, "a dry run changes nothing")
+}
+
+func TestApplyRejectsStaleAndReusedPlans(t *testing.T) {
+	ctx := context.Background()
+	inventory := mocks.NewInventoryRepository(t)
+	inventory.On("GetProducts", mock.Anything).Return([]models.Product{{ID: 10}}, nil)
+	inventory.On("GetInventories", mock.Anything).
+		Return([]models.Inventory{{ProductID: 10, VariantID: 11, WarehouseID: models.DefaultWarehouseID, Available: 90, Reserved: 10}}, nil)
+
+	redis := newTestRedis(t)
+	require.NoError(t, redis.InitInventory(ctx, 11, models.DefaultWarehouseID, 95, 5))
+	planner := NewAdminPlanner(redis, nil, NewInventoryClient(inventory, redis), time.Minute)
+
+	plan, err := planner.PlanInventoryResync(ctx, ReconcileDBWins, "admin:operator")
+	require.NoError(t, err)
+
+	require.NoError(t, redis.InitInventory(ctx, 11, models.DefaultWarehouseID, 94, 6))
+	_, err = planner.Apply(ctx, plan.Token, "admin:admin", allowAll)
+	assert.True(t, errors.Is(err, apperrors.ErrStalePlan))
+
+	_, err = planner.Apply(ctx, plan.Token, "admin:admin", allowAll)
+	assert.True(t, errors.Is(err, apperrors.ErrNotFound))
+}
+
+func TestApplyInventoryResyncHealsPreviewedDrift(t *testing.T) {
+	ctx := context.Background()
+	inventory := mocks.NewInventoryRepository(t)
+	inventory.On("GetProducts", mock.Anything).Return([]models.Product{{ID: 10}}, nil)
+	inventory.On("GetInventories", mock.Anything).
+		Return([]models.Inventory{{ProductID: 10, VariantID: 11, WarehouseID: models.DefaultWarehouseID, Available: 90, Reserved: 10}}, nil)
+
+	redis := newTestRedis(t)
+	require.NoError(t, redis.InitInventory(ctx, 11, models.DefaultWarehouseID, 95, 5))
+	planner := NewAdminPlanner(redis, nil, NewInventoryClient(inventory, redis), time.Minute)
+
+	plan, err := planner.PlanInventoryResync(ctx, ReconcileDBWins, "admin:operator")
+	require.NoError(t, err)
+	_, err = planner.Apply(ctx, plan.Token, "admin:admin", allowAll)
+	require.NoError(t, err)
+
+	available, reserved, err := redis.GetInventory(ctx, 11)
+	require.NoError(t, err)
+	assert.Equal(t, 90, available)
+	assert.Equal(t, 10, reserved)
+}
+
+func TestApplyRefusedPlanChangesNothing(t *testing.T) {
+	ctx := context.Background()
+	inventory := mocks.NewInventoryRepository(t)
+	inventory.On("GetProducts", mock.Anything).Return([]models.Product{{ID: 10}}, nil)
+	inventory.On("GetInventories", mock.Anything).
+		Return([]models.Inventory{{ProductID: 10, VariantID: 11, WarehouseID: models.DefaultWarehouseID, Available: 90, Reserved: 10}}, nil)
+
+	redis := newTestRedis(t)
+	require.NoError(t, redis.InitInventory(ctx, 11, models.DefaultWarehouseID, 95, 5))
+	planner := NewAdminPlanner(redis, nil, NewInventoryClient(inventory, redis), time.Minute)
+
+	plan, err := planner.PlanInventoryResync(ctx, ReconcileDBWins, "admin:admin")
+	require.NoError(t, err)
+	_, err = planner.Apply(ctx, plan.Token, "admin:support", func(operation string) error {
+		assert.Equal(t, PlanInventoryResync, operation)
+		return apperrors.New(apperrors.ErrForbidden, "support role may not write inventory")
+	})
+	assert.True(t, errors.Is(err, apperrors.ErrForbidden))
+
+	available, _, err := redis.GetInventory(ctx, 11)
+	require.NoError(t, err)
+	assert.Equal(t, 95, available)
+}
+
+func allowAll(string) error { return nil }
@@ -0,0 +1,183 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"order-service/internal/models"
+	"order-service/internal/store"
+	"order-service/internal/util"
+)
+
+// Discrepancy codes of an amount audit
+const (
+	DiscrepancyInvalidLine      = "invalid_line"
+	DiscrepancyTotalMismatch    = "total_mismatch"
+	DiscrepancyPaymentMismatch  = "payment_amount_mismatch"
+	DiscrepancyMissingCapture   = "missing_capture"
+	DiscrepancyMultipleCaptures = "multiple_captures"
+	DiscrepancyUnvoidedCapture  = "unvoided_capture"
+)
+
+// AmountAuditor recomputes order totals from the prices snapshotted on their
+// items and compares them to what was persisted and charged, for finance to
+// investigate payment disputes
+type AmountAuditor struct {
+	orders   store.OrderRepository
+	payments store.PaymentRepository
+}
+
+// NewAmountAuditor creates a new amount auditor
+func NewAmountAuditor(orders store.OrderRepository, payments store.PaymentRepository) *AmountAuditor {
+	return &AmountAuditor{orders: orders, payments: payments}
+}
+
+// AmountAuditLine is one item line of a recomputed order total
+type AmountAuditLine struct {
+	ItemID    int64 `json:"item_id"`
+	ProductID int64 `json:"product_id"`
+	VariantID int64 `json:"variant_id"`
+	Quantity  int   `json:"quantity"`
+	UnitPrice int64 `json:"unit_price"`
+	Amount    int64 `json:"amount"`
+	// RunningTotal is the total after this line
+	RunningTotal int64 `json:"running_total"`
+}
+
+// AmountDiscrepancy is a mismatch found by an amount audit
+type AmountDiscrepancy struct {
+	Code     string `json:"code"`
+	Message  string `json:"message"`
+	Expected int64  `json:"expected"`
+	Actual   int64  `json:"actual"`
+}
+
+// AmountAudit is the step by step recomputation of an order's total. Orders
+// carry no discounts or tax and amounts are whole minor units, so the item
+// lines add up to the total without rounding adjustments.
+type AmountAudit struct {
+	OrderID     int64             `json:"order_id"`
+	Status      string            `json:"status"`
+	PriceListID *int64            `json:"price_list_id,omitempty"`
+	Lines       []AmountAuditLine `json:"lines"`
+	// SkippedItems were left out of an allow_partial order and are not charged
+	SkippedItems   []models.SkippedOrderItem `json:"skipped_items"`
+	ComputedTotal  int64                     `json:"computed_total"`
+	PersistedTotal int64                     `json:"persisted_total"`
+	// Captured is the sum of the successful payments
+	Captured      int64               `json:"captured"`
+	Payments      []models.Payment    `json:"payments"`
+	Discrepancies []AmountDiscrepancy `json:"discrepancies"`
+	Balanced      bool                `json:"balanced"`
+}
+
+// Audit recomputes the total of an order and flags where it disagrees with the
+// persisted total or the payments
+func (a *AmountAuditor) Audit(ctx context.Context, orderID int64) (*AmountAudit, error) {
+	ctx, span := util.StartSpan(ctx, "AmountAuditor.Audit")
+	defer span.End()
+
+	order, err := a.orders.GetOrderByID(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	items, err := a.orders.GetOrderItemsByOrderID(ctx, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load order items: %w", err)
+	}
+	skipped, err := a.orders.GetSkippedOrderItems(ctx, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load skipped items: %w", err)
+	}
+	payments, err := a.payments.GetPaymentsByOrderID(ctx, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load payments: %w", err)
+	}
+
+	audit := auditAmounts(order, items, payments)
+	audit.SkippedItems = skipped
+	if audit.SkippedItems == nil {
+		audit.SkippedItems = []models.SkippedOrderItem{}
+	}
+	return audit, nil
+}
+
+// auditAmounts recomputes the total of order from its items and checks it
+// against the persisted total and the payments
+func auditAmounts(order *models.Order, items []models.OrderItem, payments []models.Payment) *AmountAudit {
+	audit := &AmountAudit{
+		OrderID:        order.ID,
+		Status:         order.Status,
+		PriceListID:    order.PriceListID,
+		Lines:          make([]AmountAuditLine, 0, len(items)),
+		PersistedTotal: order.TotalAmount,
+		Payments:       payments,
+		Discrepancies:  []AmountDiscrepancy{},
+	}
+	if audit.Payments == nil {
+		audit.Payments = []models.Payment{}
+	}
+	flag := func(code string, expected, actual int64, format string, args ...interface{}) {
+		audit.Discrepancies = append(audit.Discrepancies, AmountDiscrepancy{
+			Code:     code,
+			Message:  fmt.Sprintf(format, args...),
+			Expected: expected,
+			Actual:   actual,
+		})
+	}
+
+	for _, item := range items {
+		line := AmountAuditLine{
+			ItemID:    item.ID,
+			ProductID: item.ProductID,
+			VariantID: item.VariantID,
+			Quantity:  item.Quantity,
+			UnitPrice: item.UnitPrice,
+			Amount:    item.UnitPrice * int64(item.Quantity),
+		}
+		if item.Quantity < 1 || item.UnitPrice < 0 {
+			flag(DiscrepancyInvalidLine, 0, line.Amount,
+				"item %d has quantity %d at unit price %d", item.ID, item.Quantity, item.UnitPrice)
+		}
+		audit.ComputedTotal += line.Amount
+		line.RunningTotal = audit.ComputedTotal
+		audit.Lines = append(audit.Lines, line)
+	}
+
+	if audit.ComputedTotal != order.TotalAmount {
+		flag(DiscrepancyTotalMismatch, audit.ComputedTotal, order.TotalAmount,
+			"persisted total %d differs from the item lines by %d", order.TotalAmount, order.TotalAmount-audit.ComputedTotal)
+	}
+
+	captures := 0
+	for _, payment := range payments {
+		if payment.Status == models.PaymentStatusSuccess {
+			captures++
+			audit.Captured += payment.Amount
+		}
+		if payment.Amount != order.TotalAmount {
+			flag(DiscrepancyPaymentMismatch, order.TotalAmount, payment.Amount,
+				"%s payment %d is for %d, the order total is %d", payment.Status, payment.ID, payment.Amount, order.TotalAmount)
+		}
+	}
+
+	switch order.Status {
+	case models.OrderStatusPaid, models.OrderStatusConfirmed:
+		if captures == 0 {
+			flag(DiscrepancyMissingCapture, order.TotalAmount, 0,
+				"order is %s without a successful payment", order.Status)
+		}
+	case models.OrderStatusCancelled, models.OrderStatusFailed:
+		if captures > 0 {
+			flag(DiscrepancyUnvoidedCapture, 0, audit.Captured,
+				"order is %s but %d captured is not voided", order.Status, audit.Captured)
+		}
+	}
+	if captures > 1 {
+		flag(DiscrepancyMultipleCaptures, order.TotalAmount, audit.Captured,
+			"order was captured %d times", captures)
+	}
+
+	audit.Balanced = len(audit.Discrepancies) == 0
+	return audit
+}
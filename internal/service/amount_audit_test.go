@@ -0,0 +1,86 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"order-service/internal/models"
+	"order-service/internal/store/mocks"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuditAmountsBalancesPaidOrder(t *testing.T) {
+	order := &models.Order{ID: 7, Status: models.OrderStatusPaid, TotalAmount: 2500}
+	items := []models.OrderItem{
+		{ID: 1, ProductID: 10, Quantity: 2, UnitPrice: 1000},
+		{ID: 2, ProductID: 11, Quantity: 1, UnitPrice: 500},
+	}
+	payments := []models.Payment{
+		{ID: 1, Status: models.PaymentStatusFailed, Amount: 2500},
+		{ID: 2, Status: models.PaymentStatusSuccess, Amount: 2500},
+	}
+
+	audit := auditAmounts(order, items, payments)
+	assert.True(t, audit.Balanced)
+	assert.Empty(t, audit.Discrepancies)
+	assert.Equal(t, int64(2500), audit.ComputedTotal)
+	assert.Equal(t, int64(2500), audit.Captured)
+	require.Len(t, audit.Lines, 2)
+	assert.Equal(t, int64(2000), audit.Lines[0].RunningTotal)
+	assert.Equal(t, int64(2500), audit.Lines[1].RunningTotal)
+}
+
+func TestAuditAmountsFlagsDiscrepancies(t *testing.T) {
+	order := &models.Order{ID: 7, Status: models.OrderStatusCancelled, TotalAmount: 3000}
+	items := []models.OrderItem{{ID: 1, ProductID: 10, Quantity: 2, UnitPrice: 1000}}
+	payments := []models.Payment{
+		{ID: 1, Status: models.PaymentStatusSuccess, Amount: 3000},
+		{ID: 2, Status: models.PaymentStatusSuccess, Amount: 2000},
+	}
+
+	audit := auditAmounts(order, items, payments)
+	assert.False(t, audit.Balanced)
+
+	var codes []string
+	for _, d := range audit.Discrepancies {
+		codes = append(codes, d.Code)
+	}
+	assert.Equal(t, []string{
+		DiscrepancyTotalMismatch,
+		DiscrepancyPaymentMismatch,
+		DiscrepancyUnvoidedCapture,
+		DiscrepancyMultipleCaptures,
+	}, codes)
+	assert.Equal(t, int64(2000), audit.Discrepancies[0].Expected)
+	assert.Equal(t, int64(3000), audit.Discrepancies[0].Actual)
+}
+
+func TestAuditAmountsFlagsPaidOrderWithoutCapture(t *testing.T) {
+	order := &models.Order{ID: 7, Status: models.OrderStatusConfirmed, TotalAmount: 1000}
+	items := []models.OrderItem{{ID: 1, Quantity: 1, UnitPrice: 1000}}
+
+	audit := auditAmounts(order, items, nil)
+	require.Len(t, audit.Discrepancies, 1)
+	assert.Equal(t, DiscrepancyMissingCapture, audit.Discrepancies[0].Code)
+	assert.NotNil(t, audit.Payments)
+}
+
+func TestAuditLoadsSkippedItemsAndPayments(t *testing.T) {
+	orders := mocks.NewOrderRepository(t)
+	payments := mocks.NewPaymentRepository(t)
+	orders.On("GetOrderByID", mock.Anything, int64(7)).
+		Return(&models.Order{ID: 7, Status: models.OrderStatusReserved, TotalAmount: 1000}, nil)
+	orders.On("GetOrderItemsByOrderID", mock.Anything, int64(7)).
+		Return([]models.OrderItem{{ID: 1, Quantity: 1, UnitPrice: 1000}}, nil)
+	orders.On("GetSkippedOrderItems", mock.Anything, int64(7)).
+		Return([]models.SkippedOrderItem{{ProductID: 12, Quantity: 1, Reason: "product_not_found"}}, nil)
+	payments.On("GetPaymentsByOrderID", mock.Anything, int64(7)).Return(nil, nil)
+
+	audit, err := NewAmountAuditor(orders, payments).Audit(context.Background(), 7)
+	require.NoError(t, err)
+	assert.True(t, audit.Balanced)
+	assert.Len(t, audit.SkippedItems, 1)
+}
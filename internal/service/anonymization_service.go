@@ -0,0 +1,131 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"order-service/internal/broker"
+	"order-service/internal/models"
+	"order-service/internal/store"
+	"order-service/internal/util"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// AnonymizationService anonymizes the order history of closed accounts. Orders
+// still inside the retention period are kept for accounting with the user
+// detached; older orders are deleted together with their items and payments.
+type AnonymizationService struct {
+	repo           store.AnonymizationRepository
+	eventPublisher *broker.EventPublisher
+	retention      time.Duration
+	batchSize      int
+	logger         *zap.Logger
+}
+
+// NewAnonymizationService creates a new anonymization service
+func NewAnonymizationService(
+	repo store.AnonymizationRepository,
+	eventPublisher *broker.EventPublisher,
+	retention time.Duration,
+	batchSize int,
+) *AnonymizationService {
+	if batchSize <= 0 {
+		batchSize = 100
+	}
+	return &AnonymizationService{
+		repo:           repo,
+		eventPublisher: eventPublisher,
+		retention:      retention,
+		batchSize:      batchSize,
+		logger:         util.GetLogger(),
+	}
+}
+
+// HandleUserDeleted anonymizes all orders of the deleted user in batches,
+// publishing a progress event per batch and a completion event at the end.
+// A redelivered event resumes the same job where it stopped.
+func (s *AnonymizationService) HandleUserDeleted(ctx context.Context, event *models.UserDeletedEvent) error {
+	ctx, span := util.StartSpan(ctx, "AnonymizationService.HandleUserDeleted")
+	defer span.End()
+
+	processed, err := s.repo.IsEventProcessed(ctx, event.EventID)
+	if err != nil {
+		return fmt.Errorf("failed to check event processed: %w", err)
+	}
+	if processed {
+		s.logger.Info("Event already processed", zap.String("event_id", event.EventID))
+		return nil
+	}
+
+	job, err := s.repo.CreateAnonymizationJob(ctx, event.EventID, event.UserID)
+	if err != nil {
+		return fmt.Errorf("failed to create anonymization job: %w", err)
+	}
+
+	s.logger.Info("Anonymizing orders of deleted user",
+		zap.Int64("user_id", event.UserID),
+		zap.Int64("job_id", job.ID),
+		zap.Int("orders_total", job.OrdersTotal))
+
+	retainAfter := time.Now().Add(-s.retention).UTC()
+	for {
+		anonymized, deleted, err := s.repo.AnonymizeUserOrders(ctx, event.UserID, retainAfter, s.batchSize)
+		if err != nil {
+			return fmt.Errorf("failed to anonymize orders of user %d: %w", event.UserID, err)
+		}
+		if anonymized+deleted == 0 {
+			break
+		}
+
+		util.OrdersAnonymizedTotal.WithLabelValues("anonymized").Add(float64(anonymized))
+		util.OrdersAnonymizedTotal.WithLabelValues("deleted").Add(float64(deleted))
+
+		job, err = s.repo.UpdateAnonymizationJobProgress(ctx, job.ID, anonymized, deleted)
+		if err != nil {
+			return fmt.Errorf("failed to record anonymization progress: %w", err)
+		}
+		s.publish(ctx, models.EventTypeUserAnonymizationProgress, job)
+	}
+
+	if err := s.repo.CompleteAnonymizationJob(ctx, job.ID); err != nil {
+		return fmt.Errorf("failed to complete anonymization job: %w", err)
+	}
+	util.UsersAnonymizedTotal.Inc()
+	s.publish(ctx, models.EventTypeUserAnonymizationCompleted, job)
+
+	if err := s.repo.MarkEventProcessed(ctx, event.EventID, event.EventType); err != nil {
+		s.logger.Error("Failed to mark event processed", zap.Error(err))
+	}
+
+	s.logger.Info("User anonymization completed",
+		zap.Int64("user_id", event.UserID),
+		zap.Int64("job_id", job.ID),
+		zap.Int("orders_anonymized", job.OrdersAnonymized),
+		zap.Int("orders_deleted", job.OrdersDeleted))
+	return nil
+}
+
+// publish reports anonymization progress back to the identity service
+func (s *AnonymizationService) publish(ctx context.Context, eventType string, job *models.AnonymizationJob) {
+	event := &models.UserAnonymizationEvent{
+		BaseEvent: models.BaseEvent{
+			EventID:   uuid.New().String(),
+			EventType: eventType,
+			Timestamp: time.Now(),
+		},
+		UserID:           job.UserID,
+		JobID:            job.ID,
+		OrdersTotal:      job.OrdersTotal,
+		OrdersAnonymized: job.OrdersAnonymized,
+		OrdersDeleted:    job.OrdersDeleted,
+	}
+
+	if err := s.eventPublisher.PublishUserAnonymization(ctx, event); err != nil {
+		s.logger.Error("Failed to publish anonymization event",
+			zap.String("event_type", eventType),
+			zap.Error(err))
+	}
+}
@@ -0,0 +1,44 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"order-service/internal/models"
+	"order-service/internal/store/mocks"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestHandleUserDeletedSkipsProcessedEvents(t *testing.T) {
+	repo := mocks.NewAnonymizationRepository(t)
+	repo.On("IsEventProcessed", mock.Anything, "evt-1").Return(true, nil).Once()
+
+	s := NewAnonymizationService(repo, nil, 24*time.Hour, 10)
+
+	err := s.HandleUserDeleted(context.Background(), &models.UserDeletedEvent{
+		BaseEvent: models.BaseEvent{EventID: "evt-1", EventType: models.EventTypeUserDeleted},
+		UserID:    42,
+	})
+	assert.NoError(t, err)
+}
+
+func TestHandleUserDeletedLeavesJobOpenOnFailure(t *testing.T) {
+	repo := mocks.NewAnonymizationRepository(t)
+	repo.On("IsEventProcessed", mock.Anything, "evt-2").Return(false, nil).Once()
+	repo.On("CreateAnonymizationJob", mock.Anything, "evt-2", int64(42)).
+		Return(&models.AnonymizationJob{ID: 7, UserID: 42, OrdersTotal: 3}, nil).Once()
+	repo.On("AnonymizeUserOrders", mock.Anything, int64(42), mock.Anything, 10).
+		Return(0, 0, errors.New("connection reset")).Once()
+
+	s := NewAnonymizationService(repo, nil, 24*time.Hour, 10)
+
+	err := s.HandleUserDeleted(context.Background(), &models.UserDeletedEvent{
+		BaseEvent: models.BaseEvent{EventID: "evt-2", EventType: models.EventTypeUserDeleted},
+		UserID:    42,
+	})
+	assert.Error(t, err)
+}
@@ -0,0 +1,96 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+
+	"order-service/internal/models"
+	"order-service/internal/store"
+	"order-service/internal/util"
+
+	"go.uber.org/zap"
+)
+
+// AuditLog records admin mutations, with what they changed, for compliance
+// audits
+type AuditLog struct {
+	entries store.AuditRepository
+	logger  *zap.Logger
+}
+
+// NewAuditLog creates a new audit log
+func NewAuditLog(entries store.AuditRepository) *AuditLog {
+	return &AuditLog{entries: entries, logger: util.GetLogger()}
+}
+
+// Record stores entry with the diff of its Before and After. A failure is
+// logged rather than returned, since the mutation has already happened.
+func (a *AuditLog) Record(ctx context.Context, entry *models.AuditEntry) {
+	entry.Diff = auditDiff(entry.Before, entry.After)
+	if err := a.entries.CreateAuditEntry(ctx, entry); err != nil {
+		util.AuditEntriesTotal.WithLabelValues("failed").Inc()
+		a.logger.Error("Failed to record audit entry",
+			zap.String("actor", entry.Actor),
+			zap.String("action", entry.Action),
+			zap.String("target", entry.Target),
+			zap.String("request_id", entry.RequestID),
+			zap.Int("status", entry.Status),
+			zap.Error(err))
+		return
+	}
+	util.AuditEntriesTotal.WithLabelValues("recorded").Inc()
+}
+
+// List returns the entries matching filter, newest first
+func (a *AuditLog) List(ctx context.Context, filter models.AuditFilter) ([]models.AuditEntry, error) {
+	return a.entries.ListAuditEntries(ctx, filter)
+}
+
+// auditChange is the from and to value of a changed field
+type auditChange struct {
+	From json.RawMessage `json:"from"`
+	To   json.RawMessage `json:"to"`
+}
+
+// auditDiff maps each top-level field that differs between before and after
+// to its change, or returns nil unless both are JSON objects
+func auditDiff(before, after *json.RawMessage) *json.RawMessage {
+	if before == nil || after == nil {
+		return nil
+	}
+	var from, to map[string]json.RawMessage
+	if json.Unmarshal(*before, &from) != nil || json.Unmarshal(*after, &to) != nil || from == nil || to == nil {
+		return nil
+	}
+
+	null := json.RawMessage("null")
+	changes := make(map[string]auditChange)
+	for field, value := range from {
+		if next, ok := to[field]; !ok {
+			changes[field] = auditChange{From: value, To: null}
+		} else if !bytes.Equal(compactJSON(value), compactJSON(next)) {
+			changes[field] = auditChange{From: value, To: next}
+		}
+	}
+	for field, value := range to {
+		if _, ok := from[field]; !ok {
+			changes[field] = auditChange{From: null, To: value}
+		}
+	}
+
+	diff, err := json.Marshal(changes)
+	if err != nil {
+		return nil
+	}
+	raw := json.RawMessage(diff)
+	return &raw
+}
+
+func compactJSON(value json.RawMessage) []byte {
+	var b bytes.Buffer
+	if err := json.Compact(&b, value); err != nil {
+		return value
+	}
+	return b.Bytes()
+}
@@ -0,0 +1,45 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"order-service/internal/models"
+	"order-service/internal/store/mocks"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func rawJSON(s string) *json.RawMessage {
+	raw := json.RawMessage(s)
+	return &raw
+}
+
+func TestAuditLogRecordsDiff(t *testing.T) {
+	entries := mocks.NewAuditRepository(t)
+	entries.On("CreateAuditEntry", mock.Anything, mock.Anything).Return(nil)
+
+	entry := &models.AuditEntry{
+		Actor:  "admin:support",
+		Action: "order.transition",
+		Target: "order:42",
+		Status: 200,
+		Before: rawJSON(`{"id": 42, "status": "PAID", "note": "x"}`),
+		After:  rawJSON(`{"id":42,"status":"CANCELLED","reason":"fraud"}`),
+	}
+	NewAuditLog(entries).Record(context.Background(), entry)
+
+	assert.JSONEq(t, `{
+		"status": {"from": "PAID", "to": "CANCELLED"},
+		"note": {"from": "x", "to": null},
+		"reason": {"from": null, "to": "fraud"}
+	}`, string(*entry.Diff))
+}
+
+func TestAuditDiffNeedsBothSides(t *testing.T) {
+	assert.Nil(t, auditDiff(nil, rawJSON(`{"id": 1}`)))
+	assert.Nil(t, auditDiff(rawJSON(`[1]`), rawJSON(`{"id": 1}`)))
+	assert.JSONEq(t, `{}`, string(*auditDiff(rawJSON(`{"id": 1}`), rawJSON(`{"id": 1}`))))
+}
@@ -0,0 +1,167 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"order-service/internal/redisclient"
+	"order-service/internal/util"
+
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrFeedAtCapacity is returned when the feed has reached its connection limit
+	ErrFeedAtCapacity = errors.New("availability feed at capacity")
+	// ErrTooManyClientConnections is returned when one client exceeds its connection limit
+	ErrTooManyClientConnections = errors.New("too many availability feed connections for client")
+)
+
+// availabilityBuffer is the per-subscriber backlog before updates are dropped
+const availabilityBuffer = 32
+
+// AvailabilityFeed fans out availability changes of hot products to streaming clients
+type AvailabilityFeed struct {
+	redis          *redisclient.Client
+	inventory      *InventoryClient
+	hotProducts    map[int64]bool
+	maxConnections int
+	maxPerClient   int
+	logger         *zap.Logger
+
+	mu          sync.Mutex
+	subscribers map[chan redisclient.InventoryChange]string
+	perClient   map[string]int
+}
+
+// NewAvailabilityFeed creates a feed for the variants of the given hot products
+func NewAvailabilityFeed(redis *redisclient.Client, inventory *InventoryClient, hotProducts []int64, maxConnections, maxPerClient int) *AvailabilityFeed {
+	hot := make(map[int64]bool, len(hotProducts))
+	for _, id := range hotProducts {
+		hot[id] = true
+	}
+
+	return &AvailabilityFeed{
+		redis:          redis,
+		inventory:      inventory,
+		hotProducts:    hot,
+		maxConnections: maxConnections,
+		maxPerClient:   maxPerClient,
+		logger:         util.GetLogger(),
+		subscribers:    make(map[chan redisclient.InventoryChange]string),
+		perClient:      make(map[string]int),
+	}
+}
+
+// SetLimits changes the connection limits; existing streams above a lowered limit are kept
+func (f *AvailabilityFeed) SetLimits(maxConnections, maxPerClient int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.maxConnections = maxConnections
+	f.maxPerClient = maxPerClient
+}
+
+// HotProducts returns the product IDs broadcast by the feed
+func (f *AvailabilityFeed) HotProducts() []int64 {
+	ids := make([]int64, 0, len(f.hotProducts))
+	for id := range f.hotProducts {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// IsHot reports whether a product is broadcast by the feed
+func (f *AvailabilityFeed) IsHot(productID int64) bool {
+	return f.hotProducts[productID]
+}
+
+// Snapshot returns the current availability of every variant of the hot products
+func (f *AvailabilityFeed) Snapshot(ctx context.Context) []redisclient.InventoryChange {
+	variants, err := f.inventory.GetProductVariants(ctx, f.HotProducts())
+	if err != nil {
+		f.logger.Warn("Failed to load hot product variants", zap.Error(err))
+		return []redisclient.InventoryChange{}
+	}
+	token, err := f.inventory.ConsistencyToken(ctx)
+	if err != nil {
+		f.logger.Warn("Failed to read inventory version", zap.Error(err))
+	}
+
+	snapshot := make([]redisclient.InventoryChange, 0, len(variants))
+	for _, variant := range variants {
+		available, _, err := f.redis.GetInventory(ctx, variant.ID)
+		if err != nil {
+			f.logger.Warn("Failed to read hot product availability",
+				zap.Int64("product_id", variant.ProductID),
+				zap.Int64("variant_id", variant.ID),
+				zap.Error(err))
+			continue
+		}
+		snapshot = append(snapshot, redisclient.InventoryChange{
+			ProductID:        variant.ProductID,
+			VariantID:        variant.ID,
+			Available:        available,
+			Timestamp:        time.Now(),
+			ConsistencyToken: token,
+		})
+	}
+	return snapshot
+}
+
+// Subscribe registers a streaming client. The returned cancel func must be called on disconnect.
+func (f *AvailabilityFeed) Subscribe(clientID string) (<-chan redisclient.InventoryChange, func(), error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if len(f.subscribers) >= f.maxConnections {
+		return nil, nil, ErrFeedAtCapacity
+	}
+	if f.perClient[clientID] >= f.maxPerClient {
+		return nil, nil, ErrTooManyClientConnections
+	}
+
+	ch := make(chan redisclient.InventoryChange, availabilityBuffer)
+	f.subscribers[ch] = clientID
+	f.perClient[clientID]++
+	util.AvailabilityFeedConnections.Inc()
+
+	cancel := func() {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+
+		if _, ok := f.subscribers[ch]; !ok {
+			return
+		}
+		delete(f.subscribers, ch)
+		if f.perClient[clientID]--; f.perClient[clientID] <= 0 {
+			delete(f.perClient, clientID)
+		}
+		util.AvailabilityFeedConnections.Dec()
+	}
+
+	return ch, cancel, nil
+}
+
+// Run relays inventory changes from Redis to subscribers until ctx is cancelled
+func (f *AvailabilityFeed) Run(ctx context.Context) error {
+	return f.redis.SubscribeInventoryChanges(ctx, f.broadcast)
+}
+
+func (f *AvailabilityFeed) broadcast(change redisclient.InventoryChange) {
+	if !f.hotProducts[change.ProductID] {
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for ch := range f.subscribers {
+		select {
+		case ch <- change:
+		default:
+			util.AvailabilityFeedDroppedTotal.Inc()
+		}
+	}
+}
@@ -0,0 +1,182 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"order-service/internal/broker"
+	"order-service/internal/models"
+	"order-service/internal/redisclient"
+	"order-service/internal/store"
+	"order-service/internal/util"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// Results of processing one backordered item
+const (
+	backorderFulfilled  = "fulfilled"
+	backorderOutOfStock = "out_of_stock"
+	backorderSkipped    = "skipped"
+)
+
+// BackorderFulfiller reserves stock for backordered items once it is replenished
+type BackorderFulfiller struct {
+	orders          store.OrderRepository
+	redis           *redisclient.Client
+	inventoryClient *InventoryClient
+	eventPublisher  *broker.EventPublisher
+	orderCache      *OrderCache
+	batchSize       int
+	logger          *zap.Logger
+}
+
+// NewBackorderFulfiller creates a new backorder fulfiller
+func NewBackorderFulfiller(
+	orders store.OrderRepository,
+	redis *redisclient.Client,
+	inventoryClient *InventoryClient,
+	eventPublisher *broker.EventPublisher,
+	orderCache *OrderCache,
+) *BackorderFulfiller {
+	return &BackorderFulfiller{
+		orders:          orders,
+		redis:           redis,
+		inventoryClient: inventoryClient,
+		eventPublisher:  eventPublisher,
+		orderCache:      orderCache,
+		batchSize:       100,
+		logger:          util.GetLogger(),
+	}
+}
+
+// FulfillBackorders reserves stock for backordered items, oldest first. Once a
+// variant runs out, its later backorders wait for the next run so earlier
+// orders are served first. Returns the number of items fulfilled.
+func (f *BackorderFulfiller) FulfillBackorders(ctx context.Context) (int, error) {
+	ctx, span := util.StartSpan(ctx, "BackorderFulfiller.FulfillBackorders")
+	defer span.End()
+
+	outOfStock := make(map[int64]bool)
+	fulfilled := 0
+	var afterID int64
+	for {
+		items, err := f.orders.GetBackorderedItems(ctx, afterID, f.batchSize)
+		if err != nil {
+			return fulfilled, fmt.Errorf("failed to get backordered items: %w", err)
+		}
+
+		for _, item := range items {
+			afterID = item.ID
+			if outOfStock[item.VariantID] {
+				continue
+			}
+
+			result, err := f.fulfill(ctx, item)
+			if err != nil {
+				f.logger.Warn("Failed to fulfill backordered item",
+					zap.Int64("order_id", item.OrderID),
+					zap.Int64("variant_id", item.VariantID),
+					zap.Error(err))
+				continue
+			}
+			util.BackordersFulfilledTotal.WithLabelValues(result).Inc()
+
+			switch result {
+			case backorderFulfilled:
+				fulfilled++
+			case backorderOutOfStock:
+				outOfStock[item.VariantID] = true
+			}
+		}
+
+		if len(items) < f.batchSize {
+			return fulfilled, nil
+		}
+	}
+}
+
+// fulfill reserves the stock of one backordered item under the order's saga
+// lock, committing it straight away if the order has already been paid
+func (f *BackorderFulfiller) fulfill(ctx context.Context, item models.OrderItem) (string, error) {
+	lock, err := f.redis.ObtainLock(ctx, fmt.Sprintf("order:%d", item.OrderID), orderLockTTL)
+	if errors.Is(err, redisclient.ErrLockNotObtained) {
+		return backorderSkipped, nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to lock order %d: %w", item.OrderID, err)
+	}
+	defer func() {
+		releaseCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := lock.Release(releaseCtx); err != nil {
+			f.logger.Warn("Failed to release order lock", zap.Int64("order_id", item.OrderID), zap.Error(err))
+		}
+	}()
+
+	order, err := f.orders.GetOrderByID(ctx, item.OrderID)
+	if err != nil {
+		return "", err
+	}
+	switch order.Status {
+	case models.OrderStatusReserved, models.OrderStatusPaid, models.OrderStatusConfirmed:
+	default:
+		return backorderSkipped, nil
+	}
+
+	reserved, err := f.inventoryClient.ReserveStock(ctx, item.OrderID, item.VariantID, item.Quantity)
+	if err != nil {
+		return "", err
+	}
+	if !reserved {
+		return backorderOutOfStock, nil
+	}
+
+	allocated, err := f.orders.MarkOrderItemAllocated(ctx, item.ID)
+	if err != nil || !allocated {
+		if releaseErr := f.inventoryClient.ReleaseStock(ctx, item.OrderID, item.VariantID); releaseErr != nil {
+			f.logger.Error("Failed to release stock of unallocated backorder",
+				zap.Int64("order_id", item.OrderID),
+				zap.Int64("variant_id", item.VariantID),
+				zap.Error(releaseErr))
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to allocate item: %w", err)
+		}
+		return backorderSkipped, nil
+	}
+	item.FulfillmentStatus = models.FulfillmentStatusAllocated
+
+	if order.Status != models.OrderStatusReserved {
+		if err := f.inventoryClient.CommitOrderStock(ctx, order.ID, []models.OrderItem{item}); err != nil {
+			f.logger.Error("Failed to commit stock of fulfilled backorder for paid order",
+				zap.Int64("order_id", order.ID),
+				zap.Int64("variant_id", item.VariantID),
+				zap.Error(err))
+		}
+	}
+	f.orderCache.Invalidate(ctx, order.ID)
+
+	event := &models.BackorderFulfilledEvent{
+		BaseEvent: models.BaseEvent{
+			EventID:   uuid.New().String(),
+			EventType: models.EventTypeBackorderFulfilled,
+			Timestamp: time.Now(),
+		},
+		OrderID: order.ID,
+		UserID:  order.UserID,
+		Items: []models.OrderItemData{{
+			ProductID: item.ProductID,
+			VariantID: item.VariantID,
+			Quantity:  item.Quantity,
+			UnitPrice: item.UnitPrice,
+		}},
+	}
+	if err := f.eventPublisher.PublishBackorderFulfilled(ctx, event); err != nil {
+		f.logger.Error("Failed to publish BackorderFulfilled event", zap.Error(err))
+	}
+	return backorderFulfilled, nil
+}
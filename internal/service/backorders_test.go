@@ -0,0 +1,44 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"order-service/internal/apperrors"
+	"order-service/internal/models"
+	"order-service/internal/store/mocks"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFulfillBackordersServesOldestFirst(t *testing.T) {
+	items := []models.OrderItem{
+		{ID: 1, OrderID: 1, ProductID: 10, VariantID: 11, Quantity: 5, FulfillmentStatus: models.FulfillmentStatusBackordered},
+		{ID: 2, OrderID: 2, ProductID: 10, VariantID: 11, Quantity: 1, FulfillmentStatus: models.FulfillmentStatusBackordered},
+	}
+
+	orders := mocks.NewOrderRepository(t)
+	orders.On("GetBackorderedItems", mock.Anything, int64(0), 100).Return(items, nil).Once()
+	orders.On("GetOrderByID", mock.Anything, int64(1)).
+		Return(&models.Order{ID: 1, Status: models.OrderStatusPaid}, nil).Once()
+
+	inventory := mocks.NewInventoryRepository(t)
+	expectVariant(inventory, 11, 10)
+	inventory.On("GetProductByID", mock.Anything, int64(10)).
+		Return(&models.Product{ID: 10, ReservationStrategy: StrategyDBStrict}, nil).Once()
+	inventory.On("HoldReservation", mock.Anything, int64(1), int64(10), int64(11), 5).Return(true, nil).Once()
+	inventory.On("ReserveStockTx", mock.Anything, int64(11), int64(models.DefaultWarehouseID), 5).
+		Return(apperrors.New(apperrors.ErrInsufficientStock, "variant 11")).Once()
+	inventory.On("DeleteReservation", mock.Anything, int64(1), int64(11)).Return(nil).Once()
+	expectDBStock(inventory, 11, 5)
+
+	redis := newTestRedis(t)
+	f := NewBackorderFulfiller(orders, redis, NewInventoryClient(inventory, redis), nil, nil)
+
+	// The second item would fit, but must wait behind the first
+	fulfilled, err := f.FulfillBackorders(context.Background())
+	require.NoError(t, err)
+	assert.Zero(t, fulfilled)
+}
@@ -0,0 +1,127 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"order-service/internal/models"
+	"order-service/internal/store"
+)
+
+// DefaultCancellationPolicy names the policy of customers whose group has none,
+// including retail customers
+const DefaultCancellationPolicy = "default"
+
+// CancellationPolicy lets customers cancel free of charge within a window
+// after ordering, and keeps a fee from the refund when they cancel later
+type CancellationPolicy struct {
+	Name       string
+	FreeWindow time.Duration
+	// LateFeeBps is the fee of a late cancellation in basis points of the
+	// captured amount
+	LateFeeBps int64
+}
+
+// String formats the policy as a CANCELLATION_POLICIES rule, as recorded on
+// cancelled orders
+func (p CancellationPolicy) String() string {
+	return fmt.Sprintf("%s=%s/%s%%", p.Name, p.FreeWindow, strconv.FormatFloat(float64(p.LateFeeBps)/100, 'f', -1, 64))
+}
+
+// CancellationQuote is what cancelling an order under a policy gives back
+type CancellationQuote struct {
+	OrderID   int64     `json:"order_id"`
+	Policy    string    `json:"policy"`
+	FreeUntil time.Time `json:"free_until"`
+	Late      bool      `json:"late"`
+	// Captured is the amount paid; Refund is Captured less Fee
+	Captured int64 `json:"captured"`
+	Fee      int64 `json:"fee"`
+	Refund   int64 `json:"refund"`
+}
+
+// Quote calculates the refund of cancelling order at the given time, with
+// captured paid for it. The fee is rounded down to whole minor units.
+func (p CancellationPolicy) Quote(order *models.Order, captured int64, at time.Time) CancellationQuote {
+	quote := CancellationQuote{
+		OrderID:   order.ID,
+		Policy:    p.String(),
+		FreeUntil: order.CreatedAt.Add(p.FreeWindow),
+		Captured:  captured,
+	}
+	quote.Late = at.After(quote.FreeUntil)
+	if quote.Late {
+		quote.Fee = captured * p.LateFeeBps / 10000
+	}
+	quote.Refund = captured - quote.Fee
+	return quote
+}
+
+// CancellationPolicies resolves the cancellation policy of a customer from
+// their customer group
+type CancellationPolicies struct {
+	groups   store.PricingRepository
+	policies map[string]CancellationPolicy
+}
+
+// NewCancellationPolicies parses comma-separated group=window/fee% rules, e.g.
+// "default=30m/10%,wholesale=24h/2.5%". Without a default rule, customers of
+// other groups cancel free of charge.
+func NewCancellationPolicies(groups store.PricingRepository, rules string) (*CancellationPolicies, error) {
+	p := &CancellationPolicies{
+		groups:   groups,
+		policies: make(map[string]CancellationPolicy),
+	}
+
+	for _, entry := range strings.Split(rules, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		policy, err := parseCancellationPolicy(entry)
+		if err != nil {
+			return nil, err
+		}
+		p.policies[policy.Name] = policy
+	}
+	return p, nil
+}
+
+func parseCancellationPolicy(entry string) (CancellationPolicy, error) {
+	name, rule, _ := strings.Cut(entry, "=")
+	window, fee, ok := strings.Cut(rule, "/")
+	if !ok || strings.TrimSpace(name) == "" {
+		return CancellationPolicy{}, fmt.Errorf("invalid cancellation policy %q, want group=window/fee%%", entry)
+	}
+
+	policy := CancellationPolicy{Name: strings.TrimSpace(name)}
+	var err error
+	policy.FreeWindow, err = time.ParseDuration(strings.TrimSpace(window))
+	if err != nil || policy.FreeWindow < 0 {
+		return CancellationPolicy{}, fmt.Errorf("invalid free-cancel window in cancellation policy %q", entry)
+	}
+	percent, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(fee), "%"), 64)
+	if err != nil || percent < 0 || percent > 100 {
+		return CancellationPolicy{}, fmt.Errorf("invalid late-cancel fee in cancellation policy %q, want 0-100%%", entry)
+	}
+	policy.LateFeeBps = int64(percent*100 + 0.5)
+	return policy, nil
+}
+
+// For returns the cancellation policy of a customer
+func (p *CancellationPolicies) For(ctx context.Context, userID int64) (CancellationPolicy, error) {
+	group, err := p.groups.GetUserCustomerGroup(ctx, userID)
+	if err != nil {
+		return CancellationPolicy{}, fmt.Errorf("failed to get customer group: %w", err)
+	}
+	if policy, ok := p.policies[group]; ok && group != "" {
+		return policy, nil
+	}
+	if policy, ok := p.policies[DefaultCancellationPolicy]; ok {
+		return policy, nil
+	}
+	return CancellationPolicy{Name: DefaultCancellationPolicy}, nil
+}
@@ -0,0 +1,77 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"order-service/internal/models"
+	"order-service/internal/store/mocks"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCancellationPolicy(t *testing.T) {
+	policy, err := parseCancellationPolicy(" wholesale = 24h / 2.5% ")
+	require.NoError(t, err)
+	assert.Equal(t, CancellationPolicy{Name: "wholesale", FreeWindow: 24 * time.Hour, LateFeeBps: 250}, policy)
+	assert.Equal(t, "wholesale=24h0m0s/2.5%", policy.String())
+
+	for _, entry := range []string{"default", "default=30m", "=30m/10%", "default=soon/10%", "default=30m/101%", "default=-1m/10%"} {
+		_, err := parseCancellationPolicy(entry)
+		assert.Error(t, err, entry)
+	}
+}
+
+func TestCancellationPolicyQuote(t *testing.T) {
+	created := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	order := &models.Order{ID: 7, CreatedAt: created}
+	policy := CancellationPolicy{Name: "default", FreeWindow: 30 * time.Minute, LateFeeBps: 1000}
+
+	free := policy.Quote(order, 1999, created.Add(30*time.Minute))
+	assert.False(t, free.Late)
+	assert.Equal(t, int64(0), free.Fee)
+	assert.Equal(t, int64(1999), free.Refund)
+	assert.Equal(t, created.Add(30*time.Minute), free.FreeUntil)
+
+	late := policy.Quote(order, 1999, created.Add(31*time.Minute))
+	assert.True(t, late.Late)
+	assert.Equal(t, int64(199), late.Fee, "fee is rounded down")
+	assert.Equal(t, int64(1800), late.Refund)
+}
+
+func TestCancellationPoliciesFor(t *testing.T) {
+	ctx := context.Background()
+	groups := mocks.NewPricingRepository(t)
+	groups.On("GetUserCustomerGroup", ctx, int64(1)).Return("wholesale", nil)
+	groups.On("GetUserCustomerGroup", ctx, int64(2)).Return("", nil)
+	groups.On("GetUserCustomerGroup", ctx, int64(3)).Return("vip", nil)
+
+	policies, err := NewCancellationPolicies(groups, "default=30m/10%, wholesale=24h/2.5%")
+	require.NoError(t, err)
+
+	policy, err := policies.For(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "wholesale", policy.Name)
+
+	for _, userID := range []int64{2, 3} {
+		policy, err := policies.For(ctx, userID)
+		require.NoError(t, err)
+		assert.Equal(t, DefaultCancellationPolicy, policy.Name)
+		assert.Equal(t, int64(1000), policy.LateFeeBps)
+	}
+}
+
+func TestCancellationPoliciesForWithoutDefaultIsFree(t *testing.T) {
+	ctx := context.Background()
+	groups := mocks.NewPricingRepository(t)
+	groups.On("GetUserCustomerGroup", ctx, int64(2)).Return("", nil)
+
+	policies, err := NewCancellationPolicies(groups, "wholesale=24h/2.5%")
+	require.NoError(t, err)
+
+	policy, err := policies.For(ctx, 2)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), policy.LateFeeBps)
+}
@@ -0,0 +1,354 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"order-service/internal/apperrors"
+	"order-service/internal/models"
+	"order-service/internal/redisclient"
+	"order-service/internal/store"
+	"order-service/internal/util"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// cartPurgeBatchSize caps the expired carts deleted per statement
+const cartPurgeBatchSize = 500
+
+// CartPolicy limits carts and how long they are kept
+type CartPolicy struct {
+	// TTL is how long a cart is kept after its last change
+	TTL time.Duration
+	// MaxItems caps the distinct variants in a cart and MaxQuantity the
+	// quantity of each
+	MaxItems    int
+	MaxQuantity int
+}
+
+// CartService keeps shopping carts in Postgres, with a copy of each in Redis
+// for reads until it expires, and checks them out into orders
+type CartService struct {
+	carts    store.CartRepository
+	products store.InventoryRepository
+	orders   *OrderService
+	redis    *redisclient.Client
+	policy   CartPolicy
+	logger   *zap.Logger
+
+	inventory *InventoryClient
+	holdTTL   time.Duration
+}
+
+// NewCartService creates a new cart service
+func NewCartService(
+	carts store.CartRepository,
+	products store.InventoryRepository,
+	orders *OrderService,
+	redis *redisclient.Client,
+	policy CartPolicy,
+) *CartService {
+	return &CartService{
+		carts:    carts,
+		products: products,
+		orders:   orders,
+		redis:    redis,
+		policy:   policy,
+		logger:   util.GetLogger(),
+	}
+}
+
+// SetHolds enables soft holds of a cart's stock from the start of its checkout
+// for ttl
+func (s *CartService) SetHolds(inventory *InventoryClient, ttl time.Duration) {
+	s.inventory = inventory
+	s.holdTTL = ttl
+}
+
+// CreateCartRequest opens a cart for a user
+type CreateCartRequest struct {
+	// UserID is the customer the cart is for; the API sets it to the
+	// authenticated customer, staff name it
+	UserID int64 `json:"user_id,omitempty"`
+}
+
+// CartItemRequest adds a quantity of a variant to a cart
+type CartItemRequest struct {
+	ProductID int64 `json:"product_id" binding:"required"`
+	// VariantID is the variant added, the product's default variant when unset
+	VariantID int64 `json:"variant_id,omitempty"`
+	Quantity  int   `json:"quantity" binding:"required,min=1"`
+}
+
+// CheckoutRequest is how a cart is paid for. The user and items come from
+// the cart.
+type CheckoutRequest struct {
+	PaymentMethod string                 `json:"payment_method" binding:"required"`
+	WalletAmount  int64                  `json:"wallet_amount,omitempty"`
+	Billing       *models.BillingDetails `json:"billing,omitempty"`
+}
+
+// Create opens an empty cart for a user
+func (s *CartService) Create(ctx context.Context, req CreateCartRequest) (*models.Cart, error) {
+	cart := &models.Cart{
+		ID:        uuid.New().String(),
+		UserID:    req.UserID,
+		Status:    models.CartOpen,
+		ExpiresAt: time.Now().Add(s.policy.TTL),
+	}
+	if err := s.carts.CreateCart(ctx, cart); err != nil {
+		return nil, fmt.Errorf("failed to create cart: %w", err)
+	}
+	cart.Items = []models.CartItem{}
+	s.cache(ctx, cart)
+	return cart, nil
+}
+
+// Get returns an unexpired cart, from Redis when it holds a copy
+func (s *CartService) Get(ctx context.Context, id string) (*models.Cart, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, apperrors.New(apperrors.ErrNotFound, "cart %s not found", id)
+	}
+
+	data, err := s.redis.GetCachedCart(ctx, id)
+	if err != nil {
+		s.logger.Warn("Cart cache unavailable, falling back to DB", zap.String("cart_id", id), zap.Error(err))
+	}
+	if data != nil {
+		var cart models.Cart
+		if err := json.Unmarshal(data, &cart); err == nil {
+			return &cart, nil
+		}
+	}
+
+	return s.load(ctx, id)
+}
+
+// load reads an unexpired cart from Postgres and caches it
+func (s *CartService) load(ctx context.Context, id string) (*models.Cart, error) {
+	cart, err := s.carts.GetCart(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !cart.ExpiresAt.After(time.Now()) {
+		return nil, apperrors.New(apperrors.ErrNotFound, "cart %s not found", id)
+	}
+	s.cache(ctx, cart)
+	return cart, nil
+}
+
+// cache stores a copy of a cart in Redis until it expires. Checked out carts
+// are not cached, as they no longer change and are rarely read.
+func (s *CartService) cache(ctx context.Context, cart *models.Cart) {
+	ttl := time.Until(cart.ExpiresAt)
+	if cart.Status != models.CartOpen || ttl <= 0 {
+		return
+	}
+	data, err := json.Marshal(cart)
+	if err != nil {
+		return
+	}
+	if err := s.redis.SetCachedCart(ctx, cart.ID, data, ttl); err != nil {
+		s.logger.Warn("Failed to cache cart", zap.String("cart_id", cart.ID), zap.Error(err))
+	}
+}
+
+// AddItem adds a quantity of a variant to a cart, on top of any already in it
+func (s *CartService) AddItem(ctx context.Context, id string, req CartItemRequest) (*models.Cart, error) {
+	cart, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	items := []OrderItemRequest{{ProductID: req.ProductID, VariantID: req.VariantID, Quantity: req.Quantity}}
+	variants, err := s.products.GetVariantsByProductIDs(ctx, []int64{req.ProductID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load product variants: %w", err)
+	}
+	if len(variants) == 0 {
+		return nil, apperrors.New(apperrors.ErrProductNotFound, "product %d not found", req.ProductID)
+	}
+	if err := resolveVariants(items, variants); err != nil {
+		return nil, err
+	}
+	item := &models.CartItem{CartID: cart.ID, ProductID: req.ProductID, VariantID: items[0].VariantID, Quantity: req.Quantity}
+
+	quantity, held := item.Quantity, false
+	for _, existing := range cart.Items {
+		if existing.VariantID == item.VariantID {
+			quantity += existing.Quantity
+			held = true
+		}
+	}
+	if !held && len(cart.Items) >= s.policy.MaxItems {
+		return nil, apperrors.New(apperrors.ErrInvalidRequest, "a cart holds at most %d items", s.policy.MaxItems)
+	}
+	if quantity > s.policy.MaxQuantity {
+		return nil, apperrors.New(apperrors.ErrInvalidRequest, "at most %d of a variant fit in a cart", s.policy.MaxQuantity)
+	}
+
+	return s.update(ctx, cart.ID, func(expiresAt time.Time) error {
+		return s.carts.AddCartItem(ctx, item, expiresAt)
+	})
+}
+
+// UpdateItem sets the quantity of a variant in a cart; zero removes it
+func (s *CartService) UpdateItem(ctx context.Context, id string, variantID int64, quantity int) (*models.Cart, error) {
+	if quantity < 0 || quantity > s.policy.MaxQuantity {
+		return nil, apperrors.New(apperrors.ErrInvalidRequest, "quantity must be between 0 and %d", s.policy.MaxQuantity)
+	}
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, apperrors.New(apperrors.ErrNotFound, "cart %s not found", id)
+	}
+
+	return s.update(ctx, id, func(expiresAt time.Time) error {
+		return s.carts.SetCartItemQuantity(ctx, id, variantID, quantity, expiresAt)
+	})
+}
+
+// update applies a change to a cart, extending it by the TTL, and refreshes
+// its cached copy. The copy is dropped first, so a failed refresh leaves reads
+// to Postgres rather than to a stale copy.
+func (s *CartService) update(ctx context.Context, id string, change func(expiresAt time.Time) error) (*models.Cart, error) {
+	if err := s.redis.DeleteCachedCart(ctx, id); err != nil {
+		return nil, fmt.Errorf("failed to invalidate cached cart: %w", err)
+	}
+	if err := change(time.Now().Add(s.policy.TTL)); err != nil {
+		return nil, err
+	}
+	return s.load(ctx, id)
+}
+
+// CartHold is the stock soft-held for a cart in checkout
+type CartHold struct {
+	CartID    string    `json:"cart_id"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// StartCheckout soft-holds the stock of an open cart's items, so it is not
+// taken by other orders while the user completes checkout. Checkout reserves
+// out of the hold; if it does not complete in time the hold expires back into
+// available stock. Starting again replaces the hold and restarts its window.
+func (s *CartService) StartCheckout(ctx context.Context, id string) (*CartHold, error) {
+	ctx, span := util.StartSpan(ctx, "CartService.StartCheckout")
+	defer span.End()
+
+	if s.inventory == nil {
+		return nil, apperrors.New(apperrors.ErrNotFound, "stock holds are disabled")
+	}
+	cart, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if cart.Status != models.CartOpen {
+		return nil, apperrors.New(apperrors.ErrCartClosed, "cart %s is checked out", id)
+	}
+	if len(cart.Items) == 0 {
+		return nil, apperrors.New(apperrors.ErrInvalidRequest, "cart %s is empty", id)
+	}
+
+	expiresAt := time.Now().Add(s.holdTTL)
+	held, err := s.inventory.HoldStock(ctx, cart.ID, cartOrderItems(cart), s.holdTTL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hold stock: %w", err)
+	}
+	if !held {
+		return nil, apperrors.New(apperrors.ErrInsufficientStock, "insufficient stock to hold cart %s", id)
+	}
+	return &CartHold{CartID: cart.ID, ExpiresAt: expiresAt}, nil
+}
+
+// cartOrderItems returns the items of a cart as order items
+func cartOrderItems(cart *models.Cart) []OrderItemRequest {
+	items := make([]OrderItemRequest, 0, len(cart.Items))
+	for _, item := range cart.Items {
+		items = append(items, OrderItemRequest{ProductID: item.ProductID, VariantID: item.VariantID, Quantity: item.Quantity})
+	}
+	return items
+}
+
+// Checkout converts a cart into an order through the order saga. The order
+// is created with the cart's idempotency key, so checking out again, e.g.
+// after a timeout, returns the same order rather than a second one.
+func (s *CartService) Checkout(ctx context.Context, id string, req CheckoutRequest) (*CreateOrderResponse, error) {
+	ctx, span := util.StartSpan(ctx, "CartService.Checkout")
+	defer span.End()
+
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, apperrors.New(apperrors.ErrNotFound, "cart %s not found", id)
+	}
+	cart, err := s.carts.GetCart(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if cart.Status == models.CartOpen {
+		if !cart.ExpiresAt.After(time.Now()) {
+			return nil, apperrors.New(apperrors.ErrNotFound, "cart %s not found", id)
+		}
+		if len(cart.Items) == 0 {
+			return nil, apperrors.New(apperrors.ErrInvalidRequest, "cart %s is empty", id)
+		}
+	}
+
+	// Reserve as the cart, so the stock held for it since StartCheckout counts
+	items := cartOrderItems(cart)
+	resp, err := s.orders.CreateOrder(WithStockHolder(ctx, cart.ID), &CreateOrderRequest{
+		UserID:         cart.UserID,
+		Items:          items,
+		PaymentMethod:  req.PaymentMethod,
+		WalletAmount:   req.WalletAmount,
+		Billing:        req.Billing,
+		IdempotencyKey: "cart-" + cart.ID,
+	})
+	if err != nil {
+		util.CartCheckoutsTotal.WithLabelValues("failed").Inc()
+		return nil, err
+	}
+
+	if cart.Status == models.CartOpen {
+		if _, err := s.carts.CheckOutCart(ctx, cart.ID, resp.OrderID); err != nil {
+			return nil, fmt.Errorf("failed to check out cart: %w", err)
+		}
+		if err := s.redis.DeleteCachedCart(ctx, cart.ID); err != nil {
+			s.logger.Warn("Failed to drop cached cart", zap.String("cart_id", cart.ID), zap.Error(err))
+		}
+		if s.inventory != nil {
+			// Holds not consumed by a reservation, e.g. of backordered items
+			s.inventory.ReleaseHolds(ctx, cart.ID, cartVariantIDs(cart))
+		}
+		util.CartCheckoutsTotal.WithLabelValues("ordered").Inc()
+		s.logger.Info("Cart checked out",
+			zap.String("cart_id", cart.ID),
+			zap.Int64("order_id", resp.OrderID),
+			zap.Int("items", len(items)))
+	}
+	return resp, nil
+}
+
+// cartVariantIDs returns the variants in a cart
+func cartVariantIDs(cart *models.Cart) []int64 {
+	ids := make([]int64, 0, len(cart.Items))
+	for _, item := range cart.Items {
+		ids = append(ids, item.VariantID)
+	}
+	return ids
+}
+
+// PurgeExpired deletes the carts that expired. Returns the number deleted.
+func (s *CartService) PurgeExpired(ctx context.Context) (int64, error) {
+	var purged int64
+	for {
+		n, err := s.carts.DeleteExpiredCarts(ctx, time.Now(), cartPurgeBatchSize)
+		purged += n
+		if err != nil {
+			return purged, fmt.Errorf("failed to delete expired carts: %w", err)
+		}
+		if n < cartPurgeBatchSize {
+			return purged, nil
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"order-service/internal/apperrors"
+	"order-service/internal/models"
+	"order-service/internal/store/mocks"
+	"order-service/internal/util"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+const testCartID = "0b7c3c52-0d4e-4d8c-9a55-3f0e6a1b2c3d"
+
+var testCartPolicy = CartPolicy{TTL: time.Hour, MaxItems: 2, MaxQuantity: 5}
+
+func openCart(items ...models.CartItem) *models.Cart {
+	if items == nil {
+		items = []models.CartItem{}
+	}
+	return &models.Cart{ID: testCartID, UserID: 7, Status: models.CartOpen, Items: items, ExpiresAt: time.Now().Add(time.Hour)}
+}
+
+func TestCartGetIsServedFromRedis(t *testing.T) {
+	carts := mocks.NewCartRepository(t)
+	carts.On("GetCart", mock.Anything, testCartID).Return(openCart(), nil).Once()
+
+	service := NewCartService(carts, nil, nil, newTestRedis(t), testCartPolicy)
+
+	for i := 0; i < 2; i++ {
+		cart, err := service.Get(context.Background(), testCartID)
+		require.NoError(t, err)
+		assert.Equal(t, int64(7), cart.UserID)
+	}
+}
+
+func TestCartGetHidesExpiredCarts(t *testing.T) {
+	cart := openCart()
+	cart.ExpiresAt = time.Now().Add(-time.Minute)
+	carts := mocks.NewCartRepository(t)
+	carts.On("GetCart", mock.Anything, testCartID).Return(cart, nil).Once()
+
+	service := NewCartService(carts, nil, nil, newTestRedis(t), testCartPolicy)
+
+	_, err := service.Get(context.Background(), testCartID)
+	assert.True(t, errors.Is(err, apperrors.ErrNotFound))
+
+	_, err = service.Get(context.Background(), "not-a-cart")
+	assert.True(t, errors.Is(err, apperrors.ErrNotFound))
+}
+
+func TestCartAddItemResolvesDefaultVariant(t *testing.T) {
+	carts := mocks.NewCartRepository(t)
+	carts.On("GetCart", mock.Anything, testCartID).Return(openCart(), nil).Once()
+	carts.On("AddCartItem", mock.Anything, mock.MatchedBy(func(item *models.CartItem) bool {
+		return item.CartID == testCartID && item.ProductID == 1 && item.VariantID == 11 && item.Quantity == 2
+	}), mock.Anything).Return(nil).Once()
+	carts.On("GetCart", mock.Anything, testCartID).
+		Return(openCart(models.CartItem{ProductID: 1, VariantID: 11, Quantity: 2}), nil).Once()
+
+	products := mocks.NewInventoryRepository(t)
+	products.On("GetVariantsByProductIDs", mock.Anything, []int64{1}).Return([]models.ProductVariant{
+		{ID: 10, ProductID: 1},
+		{ID: 11, ProductID: 1, IsDefault: true},
+	}, nil).Once()
+
+	service := NewCartService(carts, products, nil, newTestRedis(t), testCartPolicy)
+
+	cart, err := service.AddItem(context.Background(), testCartID, CartItemRequest{ProductID: 1, Quantity: 2})
+	require.NoError(t, err)
+	require.Len(t, cart.Items, 1)
+	assert.Equal(t, int64(11), cart.Items[0].VariantID)
+}
+
+func TestCartAddItemEnforcesLimits(t *testing.T) {
+	carts := mocks.NewCartRepository(t)
+	carts.On("GetCart", mock.Anything, testCartID).Return(openCart(
+		models.CartItem{ProductID: 1, VariantID: 11, Quantity: 4},
+		models.CartItem{ProductID: 2, VariantID: 21, Quantity: 1},
+	), nil).Once()
+
+	products := mocks.NewInventoryRepository(t)
+	products.On("GetVariantsByProductIDs", mock.Anything, []int64{1}).
+		Return([]models.ProductVariant{{ID: 11, ProductID: 1, IsDefault: true}}, nil).Once()
+	products.On("GetVariantsByProductIDs", mock.Anything, []int64{3}).
+		Return([]models.ProductVariant{{ID: 31, ProductID: 3, IsDefault: true}}, nil).Once()
+
+	service := NewCartService(carts, products, nil, newTestRedis(t), testCartPolicy)
+
+	_, err := service.AddItem(context.Background(), testCartID, CartItemRequest{ProductID: 1, Quantity: 2})
+	assert.ErrorContains(t, err, "at most 5 of a variant")
+
+	_, err = service.AddItem(context.Background(), testCartID, CartItemRequest{ProductID: 3, Quantity: 1})
+	assert.ErrorContains(t, err, "at most 2 items")
+}
+
+func TestCartCheckoutConvertsCartIntoOrder(t *testing.T) {
+	carts := mocks.NewCartRepository(t)
+	carts.On("GetCart", mock.Anything, testCartID).
+		Return(openCart(models.CartItem{ProductID: 1, VariantID: 11, Quantity: 2}), nil).Once()
+	carts.On("CheckOutCart", mock.Anything, testCartID, int64(900)).Return(true, nil).Once()
+
+	// The order was created by an earlier checkout that failed to mark the cart
+	orders := mocks.NewOrderRepository(t)
+	orders.On("GetOrderByIdempotencyKey", mock.Anything, "cart-"+testCartID).
+		Return(&models.Order{ID: 900, Status: models.OrderStatusReserved}, nil).Once()
+
+	service := NewCartService(carts, nil, &OrderService{orders: orders, logger: util.GetLogger()}, newTestRedis(t), testCartPolicy)
+
+	resp, err := service.Checkout(context.Background(), testCartID, CheckoutRequest{PaymentMethod: "card"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(900), resp.OrderID)
+}
+
+func TestCartCheckoutOfCheckedOutCartReturnsItsOrder(t *testing.T) {
+	orderID := int64(900)
+	cart := openCart(models.CartItem{ProductID: 1, VariantID: 11, Quantity: 2})
+	cart.Status, cart.OrderID = models.CartCheckedOut, &orderID
+	carts := mocks.NewCartRepository(t)
+	carts.On("GetCart", mock.Anything, testCartID).Return(cart, nil).Once()
+
+	orders := mocks.NewOrderRepository(t)
+	orders.On("GetOrderByIdempotencyKey", mock.Anything, "cart-"+testCartID).
+		Return(&models.Order{ID: 900, Status: models.OrderStatusPaid}, nil).Once()
+
+	service := NewCartService(carts, nil, &OrderService{orders: orders, logger: util.GetLogger()}, newTestRedis(t), testCartPolicy)
+
+	resp, err := service.Checkout(context.Background(), testCartID, CheckoutRequest{PaymentMethod: "card"})
+	require.NoError(t, err)
+	assert.Equal(t, models.OrderStatusPaid, resp.Status)
+	carts.AssertNotCalled(t, "CheckOutCart", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestCartCheckoutRejectsEmptyCart(t *testing.T) {
+	carts := mocks.NewCartRepository(t)
+	carts.On("GetCart", mock.Anything, testCartID).Return(openCart(), nil).Once()
+
+	service := NewCartService(carts, nil, nil, newTestRedis(t), testCartPolicy)
+
+	_, err := service.Checkout(context.Background(), testCartID, CheckoutRequest{PaymentMethod: "card"})
+	assert.True(t, errors.Is(err, apperrors.ErrInvalidRequest))
+}
+
+func TestCartStartCheckoutHoldsAllItemsOrNone(t *testing.T) {
+	ctx := context.Background()
+	carts := mocks.NewCartRepository(t)
+	carts.On("GetCart", mock.Anything, testCartID).Return(openCart(
+		models.CartItem{ProductID: 1, VariantID: 11, Quantity: 2},
+		models.CartItem{ProductID: 1, VariantID: 12, Quantity: 1},
+	), nil).Once()
+
+	inventory := mocks.NewInventoryRepository(t)
+	inventory.On("GetVariantByID", mock.Anything, int64(11)).Return(&models.ProductVariant{ID: 11, ProductID: 1}, nil).Once()
+	inventory.On("GetProductByID", mock.Anything, int64(1)).Return(&models.Product{ID: 1}, nil).Once()
+
+	redis := newTestRedis(t)
+	require.NoError(t, redis.InitInventory(ctx, 11, models.DefaultWarehouseID, 5, 0))
+	require.NoError(t, redis.InitInventory(ctx, 12, models.DefaultWarehouseID, 0, 0))
+	ic := NewInventoryClient(inventory, redis)
+	ic.rememberVariants([]models.ProductVariant{{ID: 12, ProductID: 1}})
+
+	service := NewCartService(carts, nil, nil, redis, testCartPolicy)
+	service.SetHolds(ic, time.Minute)
+
+	_, err := service.StartCheckout(ctx, testCartID)
+	assert.True(t, errors.Is(err, apperrors.ErrInsufficientStock))
+
+	held, err := redis.HoldStock(ctx, 11, "another-cart", 5, time.Minute)
+	require.NoError(t, err)
+	assert.True(t, held, "the hold on variant 11 was released")
+}
@@ -0,0 +1,283 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"order-service/internal/apperrors"
+	"order-service/internal/models"
+	"order-service/internal/resilience/retry"
+	"order-service/internal/store"
+	"order-service/internal/util"
+
+	"go.uber.org/zap"
+)
+
+// compensationPriorities orders the retries of compensations: money owed to
+// customers is put right before stock
+var compensationPriorities = map[string]int{
+	models.CompensationWalletRefund:       0,
+	models.CompensationVoidPayment:        0,
+	models.CompensationCancellationRefund: 0,
+	models.CompensationCommitStock:        1,
+	models.CompensationReleaseStock:       2,
+}
+
+const (
+	// compensationClaimTTL is how long a claimed compensation is hidden from
+	// other retriers while it runs
+	compensationClaimTTL = 2 * time.Minute
+	// compensationBatchSize caps the compensations run per poll
+	compensationBatchSize = 20
+)
+
+// CompensationQueue queues saga compensation steps that failed, e.g. a stock
+// release during an outage, for the compensation retrier, and holds those
+// that ran out of retries for manual resolution
+type CompensationQueue struct {
+	compensations store.CompensationRepository
+	policy        retry.Policy
+	logger        *zap.Logger
+}
+
+// NewCompensationQueue creates a new compensation queue retrying under policy.
+// Its MaxAttempts counts every attempt, including the one that failed in the
+// saga; a compensation is escalated once they run out.
+func NewCompensationQueue(compensations store.CompensationRepository, policy retry.Policy) *CompensationQueue {
+	if policy.MaxAttempts < 1 {
+		policy.MaxAttempts = 1
+	}
+	return &CompensationQueue{
+		compensations: compensations,
+		policy:        policy,
+		logger:        util.GetLogger(),
+	}
+}
+
+// Enqueue queues a compensation step that failed with cause. A step already
+// queued is not queued again. Failing to queue it is logged, as the step is
+// then left to reconciliation or an operator.
+func (q *CompensationQueue) Enqueue(ctx context.Context, c models.Compensation, cause error) {
+	if q == nil {
+		return
+	}
+
+	c.Priority = compensationPriorities[c.Kind]
+	c.Status = models.CompensationPending
+	c.Attempts = 1
+	c.LastError = cause.Error()
+	c.NextAttemptAt = time.Now().Add(q.policy.Delay(1))
+	if q.policy.MaxAttempts <= 1 {
+		c.Status = models.CompensationEscalated
+	}
+
+	created, err := q.compensations.CreateCompensation(ctx, &c)
+	if err != nil {
+		q.logger.Error("Failed to queue compensation",
+			zap.Int64("order_id", c.OrderID),
+			zap.String("kind", c.Kind),
+			zap.NamedError("cause", cause),
+			zap.Error(err))
+		return
+	}
+	if !created {
+		return
+	}
+
+	util.CompensationsTotal.WithLabelValues(c.Kind, "queued").Inc()
+	q.logger.Warn("Compensation queued for retry",
+		zap.Int64("compensation_id", c.ID),
+		zap.Int64("order_id", c.OrderID),
+		zap.String("kind", c.Kind),
+		zap.Time("next_attempt_at", c.NextAttemptAt),
+		zap.Error(cause))
+}
+
+// claimDue claims the compensations due for a retry, most urgent first
+func (q *CompensationQueue) claimDue(ctx context.Context) ([]models.Compensation, error) {
+	return q.compensations.ClaimDueCompensations(ctx, time.Now(), compensationClaimTTL, compensationBatchSize)
+}
+
+// recordAttempt records the outcome of a retry: done, rescheduled with
+// backoff, or escalated for manual resolution once the budget is spent
+func (q *CompensationQueue) recordAttempt(ctx context.Context, c *models.Compensation, attemptErr error) error {
+	c.Attempts++
+	switch {
+	case attemptErr == nil:
+		c.Status = models.CompensationDone
+		c.LastError = ""
+		util.CompensationsTotal.WithLabelValues(c.Kind, "succeeded").Inc()
+		q.logger.Info("Compensation retried",
+			zap.Int64("compensation_id", c.ID),
+			zap.Int64("order_id", c.OrderID),
+			zap.String("kind", c.Kind),
+			zap.Int("attempts", c.Attempts))
+	case c.Attempts >= q.policy.MaxAttempts:
+		c.Status = models.CompensationEscalated
+		c.LastError = attemptErr.Error()
+		util.CompensationsTotal.WithLabelValues(c.Kind, "escalated").Inc()
+		q.logger.Error("Compensation out of retries - escalated for manual resolution",
+			zap.Int64("compensation_id", c.ID),
+			zap.Int64("order_id", c.OrderID),
+			zap.String("kind", c.Kind),
+			zap.Int("attempts", c.Attempts),
+			zap.Error(attemptErr))
+	default:
+		c.LastError = attemptErr.Error()
+		c.NextAttemptAt = time.Now().Add(q.policy.Delay(c.Attempts))
+		util.CompensationsTotal.WithLabelValues(c.Kind, "rescheduled").Inc()
+		q.logger.Warn("Compensation failed, rescheduled",
+			zap.Int64("compensation_id", c.ID),
+			zap.Int64("order_id", c.OrderID),
+			zap.String("kind", c.Kind),
+			zap.Int("attempts", c.Attempts),
+			zap.Time("next_attempt_at", c.NextAttemptAt),
+			zap.Error(attemptErr))
+	}
+	return q.compensations.UpdateCompensation(ctx, c)
+}
+
+// RefreshBacklog updates the gauge of escalated compensations awaiting
+// manual resolution, which is alerted on
+func (q *CompensationQueue) RefreshBacklog(ctx context.Context) error {
+	escalated, err := q.compensations.CountCompensations(ctx, models.CompensationEscalated)
+	if err != nil {
+		return fmt.Errorf("failed to count escalated compensations: %w", err)
+	}
+	util.CompensationsAwaitingResolution.Set(float64(escalated))
+	return nil
+}
+
+// List returns the compensations matching filter, most urgent first
+func (q *CompensationQueue) List(ctx context.Context, filter models.CompensationFilter) ([]models.Compensation, error) {
+	return q.compensations.ListCompensations(ctx, filter)
+}
+
+// Resolve closes a pending or escalated compensation an operator put right by
+// hand, recording how
+func (q *CompensationQueue) Resolve(ctx context.Context, id int64, resolution, actor string) (*models.Compensation, error) {
+	c, err := q.compensations.GetCompensation(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if c.Status != models.CompensationPending && c.Status != models.CompensationEscalated {
+		return nil, apperrors.New(apperrors.ErrCompensationClosed, "compensation %d is already %s", id, c.Status)
+	}
+
+	c.Status = models.CompensationResolved
+	c.ResolvedBy, c.Resolution = &actor, &resolution
+	if err := q.compensations.UpdateCompensation(ctx, c); err != nil {
+		return nil, fmt.Errorf("failed to resolve compensation: %w", err)
+	}
+
+	util.AdminActionsTotal.WithLabelValues("resolve_compensation").Inc()
+	q.logger.Info("Compensation resolved",
+		zap.Int64("compensation_id", id),
+		zap.Int64("order_id", c.OrderID),
+		zap.String("actor", actor))
+	return c, nil
+}
+
+// Retry hands an escalated compensation back to the retrier with a fresh
+// retry budget, e.g. once the outage that exhausted it is over
+func (q *CompensationQueue) Retry(ctx context.Context, id int64, actor string) (*models.Compensation, error) {
+	c, err := q.compensations.GetCompensation(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if c.Status != models.CompensationEscalated {
+		return nil, apperrors.New(apperrors.ErrCompensationClosed, "only escalated compensations can be retried, compensation %d is %s", id, c.Status)
+	}
+
+	c.Status = models.CompensationPending
+	c.Attempts = 0
+	c.NextAttemptAt = time.Now()
+	if err := q.compensations.UpdateCompensation(ctx, c); err != nil {
+		return nil, fmt.Errorf("failed to requeue compensation: %w", err)
+	}
+
+	util.AdminActionsTotal.WithLabelValues("retry_compensation").Inc()
+	q.logger.Info("Compensation requeued",
+		zap.Int64("compensation_id", id),
+		zap.Int64("order_id", c.OrderID),
+		zap.String("actor", actor))
+	return c, nil
+}
+
+// SetCompensations queues compensation steps that fail for background retries
+func (so *SagaOrchestrator) SetCompensations(compensations *CompensationQueue) {
+	so.compensations = compensations
+}
+
+// RetryDueCompensations retries the compensations that are due, most urgent
+// first. Returns the number that succeeded.
+func (so *SagaOrchestrator) RetryDueCompensations(ctx context.Context) (int, error) {
+	ctx, span := util.StartSpan(ctx, "SagaOrchestrator.RetryDueCompensations")
+	defer span.End()
+
+	due, err := so.compensations.claimDue(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to claim due compensations: %w", err)
+	}
+
+	succeeded := 0
+	for i := range due {
+		c := &due[i]
+		attemptErr := so.runCompensation(ctx, c)
+		if err := so.compensations.recordAttempt(ctx, c, attemptErr); err != nil {
+			so.logger.Error("Failed to record compensation attempt",
+				zap.Int64("compensation_id", c.ID),
+				zap.Error(err))
+			continue
+		}
+		if attemptErr == nil {
+			succeeded++
+		}
+	}
+
+	if err := so.compensations.RefreshBacklog(ctx); err != nil {
+		so.logger.Warn("Failed to refresh compensation backlog", zap.Error(err))
+	}
+	return succeeded, nil
+}
+
+// runCompensation runs a compensation step again. Steps of orders that were
+// settled in the meantime, e.g. by an operator, succeed without doing anything.
+func (so *SagaOrchestrator) runCompensation(ctx context.Context, c *models.Compensation) error {
+	switch c.Kind {
+	case models.CompensationWalletRefund:
+		return so.paymentService.refundToWallet(ctx, c.PaymentID, c.Amount, c.Reason)
+	case models.CompensationCancellationRefund:
+		return so.retryCancellationRefund(ctx, c)
+	case models.CompensationReleaseStock:
+		return so.inventoryClient.ReleaseStock(ctx, c.OrderID, c.VariantID)
+	case models.CompensationCommitStock, models.CompensationVoidPayment:
+		return so.settleHeldOrder(ctx, c)
+	}
+	return fmt.Errorf("unknown compensation kind %q", c.Kind)
+}
+
+// settleHeldOrder commits the stock of an order held after its stock commit
+// failed, or voids its payment and cancels it, per the compensation
+func (so *SagaOrchestrator) settleHeldOrder(ctx context.Context, c *models.Compensation) error {
+	ctx, lock, err := so.lockOrder(ctx, c.OrderID)
+	if err != nil {
+		return err
+	}
+	defer so.unlockOrder(lock, c.OrderID)
+
+	order, err := so.orders.GetOrderByID(ctx, c.OrderID)
+	if err != nil {
+		return err
+	}
+	if order.Status != models.OrderStatusOnHold {
+		return nil
+	}
+
+	change := models.StatusChange{Reason: "compensation retried: " + c.Reason, Actor: models.ActorCompensation}
+	if c.Kind == models.CompensationCommitStock {
+		return so.replayCommitStock(ctx, order, lock.Token(), change)
+	}
+	return so.replayCompensation(ctx, order, lock.Token(), change, c.Reason)
+}
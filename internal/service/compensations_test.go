@@ -0,0 +1,102 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"order-service/internal/models"
+	"order-service/internal/resilience/retry"
+	"order-service/internal/store/mocks"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+var testCompensationPolicy = retry.Policy{MaxAttempts: 3, BaseDelay: time.Minute, MaxDelay: time.Hour}
+
+// newWalletRefundSaga returns a saga whose wallet refunds fail
+func newWalletRefundSaga(t *testing.T, compensations *mocks.CompensationRepository) *SagaOrchestrator {
+	wallets := mocks.NewWalletRepository(t)
+	wallets.On("GetPaymentWalletDebit", mock.Anything, int64(5)).Return(nil, errors.New("connection refused"))
+
+	payments := NewPaymentService(nil, nil)
+	payments.SetWallets(NewWalletService(wallets))
+	so := NewSagaOrchestrator(nil, nil, nil, payments, nil, nil, CommitFailurePolicy{})
+	so.SetCompensations(NewCompensationQueue(compensations, testCompensationPolicy))
+	return so
+}
+
+func TestRetryDueCompensationsReschedulesWithBackoff(t *testing.T) {
+	compensations := mocks.NewCompensationRepository(t)
+	compensations.On("ClaimDueCompensations", mock.Anything, mock.Anything, compensationClaimTTL, compensationBatchSize).
+		Return([]models.Compensation{{ID: 1, OrderID: 9, Kind: models.CompensationWalletRefund, PaymentID: 5, Amount: 300,
+			Status: models.CompensationPending, Attempts: 1}}, nil).Once()
+	compensations.On("UpdateCompensation", mock.Anything, mock.MatchedBy(func(c *models.Compensation) bool {
+		// The second retry waits twice the backoff, give or take the jitter
+		wait := time.Until(c.NextAttemptAt)
+		return c.Status == models.CompensationPending && c.Attempts == 2 &&
+			wait > 90*time.Second && wait <= 145*time.Second && strings.Contains(c.LastError, "connection refused")
+	})).Return(nil).Once()
+	compensations.On("CountCompensations", mock.Anything, models.CompensationEscalated).Return(0, nil).Once()
+
+	succeeded, err := newWalletRefundSaga(t, compensations).RetryDueCompensations(context.Background())
+	require.NoError(t, err)
+	assert.Zero(t, succeeded)
+}
+
+func TestRetryDueCompensationsEscalatesOnceOutOfRetries(t *testing.T) {
+	compensations := mocks.NewCompensationRepository(t)
+	compensations.On("ClaimDueCompensations", mock.Anything, mock.Anything, compensationClaimTTL, compensationBatchSize).
+		Return([]models.Compensation{{ID: 1, OrderID: 9, Kind: models.CompensationWalletRefund, PaymentID: 5, Amount: 300,
+			Status: models.CompensationPending, Attempts: 2}}, nil).Once()
+	compensations.On("UpdateCompensation", mock.Anything, mock.MatchedBy(func(c *models.Compensation) bool {
+		return c.Status == models.CompensationEscalated && c.Attempts == 3
+	})).Return(nil).Once()
+	compensations.On("CountCompensations", mock.Anything, models.CompensationEscalated).Return(1, nil).Once()
+
+	_, err := newWalletRefundSaga(t, compensations).RetryDueCompensations(context.Background())
+	require.NoError(t, err)
+}
+
+func TestRetryDueCompensationsSkipsSettledHeldOrders(t *testing.T) {
+	orders := mocks.NewOrderRepository(t)
+	orders.On("GetOrderByID", mock.Anything, int64(9)).
+		Return(&models.Order{ID: 9, Status: models.OrderStatusCancelled}, nil).Once()
+	compensations := mocks.NewCompensationRepository(t)
+	compensations.On("ClaimDueCompensations", mock.Anything, mock.Anything, compensationClaimTTL, compensationBatchSize).
+		Return([]models.Compensation{{ID: 1, OrderID: 9, Kind: models.CompensationCommitStock,
+			Status: models.CompensationPending, Attempts: 1}}, nil).Once()
+	compensations.On("UpdateCompensation", mock.Anything, mock.MatchedBy(func(c *models.Compensation) bool {
+		return c.Status == models.CompensationDone
+	})).Return(nil).Once()
+	compensations.On("CountCompensations", mock.Anything, models.CompensationEscalated).Return(0, nil).Once()
+
+	so := NewSagaOrchestrator(orders, newTestRedis(t), nil, nil, nil, nil, CommitFailurePolicy{})
+	so.SetCompensations(NewCompensationQueue(compensations, testCompensationPolicy))
+
+	succeeded, err := so.RetryDueCompensations(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, succeeded)
+}
+
+func TestEnqueueCompensationPutsMoneyFirst(t *testing.T) {
+	compensations := mocks.NewCompensationRepository(t)
+	compensations.On("CreateCompensation", mock.Anything, mock.MatchedBy(func(c *models.Compensation) bool {
+		return c.Kind == models.CompensationWalletRefund && c.Priority == 0 && c.Attempts == 1 &&
+			c.Status == models.CompensationPending && c.NextAttemptAt.After(time.Now())
+	})).Return(true, nil).Once()
+	compensations.On("CreateCompensation", mock.Anything, mock.MatchedBy(func(c *models.Compensation) bool {
+		return c.Kind == models.CompensationReleaseStock && c.Priority == 2
+	})).Return(true, nil).Once()
+
+	q := NewCompensationQueue(compensations, testCompensationPolicy)
+	q.Enqueue(context.Background(), models.Compensation{OrderID: 9, Kind: models.CompensationWalletRefund, PaymentID: 5, Amount: 300}, errors.New("timeout"))
+	q.Enqueue(context.Background(), models.Compensation{OrderID: 9, Kind: models.CompensationReleaseStock, VariantID: 3}, errors.New("timeout"))
+
+	var disabled *CompensationQueue
+	disabled.Enqueue(context.Background(), models.Compensation{OrderID: 9, Kind: models.CompensationReleaseStock}, errors.New("timeout"))
+}
@@ -0,0 +1,90 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"order-service/internal/apperrors"
+	"order-service/internal/models"
+	"order-service/internal/store"
+	"order-service/internal/util"
+
+	"go.uber.org/zap"
+)
+
+// CustomerService keeps the projection of identity service users current from
+// their events and checks at order time that the customer may order
+type CustomerService struct {
+	customers store.CustomerRepository
+	// required rejects orders of users no event was received for yet
+	required bool
+	logger   *zap.Logger
+}
+
+// NewCustomerService creates a new customer service
+func NewCustomerService(customers store.CustomerRepository, required bool) *CustomerService {
+	return &CustomerService{
+		customers: customers,
+		required:  required,
+		logger:    util.GetLogger(),
+	}
+}
+
+// CheckCanOrder returns the customer placing an order, failing if they are
+// suspended or closed. Users not seen yet are let through with no customer
+// unless customers are required.
+func (s *CustomerService) CheckCanOrder(ctx context.Context, userID int64) (*models.Customer, error) {
+	customer, err := s.customers.GetCustomer(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load customer: %w", err)
+	}
+	if customer == nil {
+		if s.required {
+			return nil, apperrors.New(apperrors.ErrCustomerNotFound, "user %d is not a known customer", userID)
+		}
+		return nil, nil
+	}
+	if customer.Status != models.CustomerActive {
+		return nil, apperrors.New(apperrors.ErrCustomerInactive, "customer %d is %s", userID, customer.Status)
+	}
+	return customer, nil
+}
+
+// HandleUserUpdated records the details and status of a user. Events older
+// than the last one applied are ignored, so redeliveries and reordering are
+// harmless.
+func (s *CustomerService) HandleUserUpdated(ctx context.Context, event *models.UserUpdatedEvent) error {
+	switch event.Status {
+	case models.CustomerActive, models.CustomerSuspended, models.CustomerClosed:
+	default:
+		return fmt.Errorf("unknown customer status %q", event.Status)
+	}
+
+	applied, err := s.customers.UpsertCustomer(ctx, &models.Customer{
+		UserID:          event.UserID,
+		Email:           event.Email,
+		Name:            event.Name,
+		Status:          event.Status,
+		Phone:           event.Phone,
+		WebhookURL:      event.WebhookURL,
+		SourceUpdatedAt: event.Timestamp,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update customer: %w", err)
+	}
+	if !applied {
+		s.logger.Info("Stale user update ignored",
+			zap.String("event_id", event.EventID),
+			zap.Int64("user_id", event.UserID))
+	}
+	return nil
+}
+
+// HandleUserDeleted closes the customer of a deleted account and erases their
+// details; their orders are anonymized separately
+func (s *CustomerService) HandleUserDeleted(ctx context.Context, event *models.UserDeletedEvent) error {
+	if err := s.customers.CloseCustomer(ctx, event.UserID, event.Timestamp); err != nil {
+		return fmt.Errorf("failed to close customer: %w", err)
+	}
+	return nil
+}
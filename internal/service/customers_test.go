@@ -0,0 +1,64 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"order-service/internal/apperrors"
+	"order-service/internal/models"
+	"order-service/internal/store/mocks"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckCanOrderRejectsInactiveCustomers(t *testing.T) {
+	customers := mocks.NewCustomerRepository(t)
+	customers.On("GetCustomer", mock.Anything, int64(7)).
+		Return(&models.Customer{UserID: 7, Email: "ana@example.com", Status: models.CustomerActive}, nil).Once()
+	customers.On("GetCustomer", mock.Anything, int64(8)).
+		Return(&models.Customer{UserID: 8, Status: models.CustomerSuspended}, nil).Once()
+
+	cs := NewCustomerService(customers, false)
+
+	customer, err := cs.CheckCanOrder(context.Background(), 7)
+	require.NoError(t, err)
+	assert.Equal(t, "ana@example.com", customer.Email)
+
+	_, err = cs.CheckCanOrder(context.Background(), 8)
+	assert.ErrorIs(t, err, apperrors.ErrCustomerInactive)
+}
+
+func TestCheckCanOrderRejectsUnknownUsersOnlyWhenRequired(t *testing.T) {
+	customers := mocks.NewCustomerRepository(t)
+	customers.On("GetCustomer", mock.Anything, int64(7)).Return(nil, nil).Twice()
+
+	customer, err := NewCustomerService(customers, false).CheckCanOrder(context.Background(), 7)
+	require.NoError(t, err)
+	assert.Nil(t, customer)
+
+	_, err = NewCustomerService(customers, true).CheckCanOrder(context.Background(), 7)
+	assert.ErrorIs(t, err, apperrors.ErrCustomerNotFound)
+}
+
+func TestHandleUserUpdatedRecordsEventTimestamp(t *testing.T) {
+	at := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	customers := mocks.NewCustomerRepository(t)
+	customers.On("UpsertCustomer", mock.Anything, mock.MatchedBy(func(c *models.Customer) bool {
+		return c.UserID == 7 && c.Status == models.CustomerSuspended && c.SourceUpdatedAt.Equal(at)
+	})).Return(false, nil).Once()
+
+	cs := NewCustomerService(customers, false)
+
+	err := cs.HandleUserUpdated(context.Background(), &models.UserUpdatedEvent{
+		BaseEvent: models.BaseEvent{EventID: "e1", EventType: models.EventTypeUserUpdated, Timestamp: at},
+		UserID:    7,
+		Status:    models.CustomerSuspended,
+	})
+	assert.NoError(t, err, "a stale update is not an error")
+
+	err = cs.HandleUserUpdated(context.Background(), &models.UserUpdatedEvent{UserID: 7, Status: "BANNED"})
+	assert.Error(t, err)
+}
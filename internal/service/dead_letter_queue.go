@@ -0,0 +1,259 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"order-service/internal/apperrors"
+	"order-service/internal/broker"
+	"order-service/internal/models"
+	"order-service/internal/store"
+	"order-service/internal/util"
+
+	"github.com/segmentio/kafka-go"
+	"go.uber.org/zap"
+)
+
+// DeadLetterQueue parks consumed messages whose handler failed so they stop
+// blocking their partition and can be inspected by operators
+type DeadLetterQueue struct {
+	letters     store.DeadLetterRepository
+	republish   func(ctx context.Context, msg kafka.Message) error
+	maxRedrives int
+	logger      *zap.Logger
+}
+
+// NewDeadLetterQueue creates a new dead letter queue
+func NewDeadLetterQueue(letters store.DeadLetterRepository) *DeadLetterQueue {
+	return &DeadLetterQueue{
+		letters: letters,
+		logger:  util.GetLogger(),
+	}
+}
+
+// Handler returns the dead letter function for a consumer group's consumer
+func (q *DeadLetterQueue) Handler(consumerGroup string) broker.DeadLetterFunc {
+	return func(ctx context.Context, msg kafka.Message, handlerErr error) error {
+		letter := &models.DeadLetter{
+			ConsumerGroup: consumerGroup,
+			Topic:         msg.Topic,
+			Partition:     msg.Partition,
+			Offset:        msg.Offset,
+			Key:           string(msg.Key),
+			Payload:       string(msg.Value),
+			Error:         handlerErr.Error(),
+		}
+		if redriveOf, attempt, ok := broker.RedriveHeaders(msg); ok {
+			letter.RedriveOf = &redriveOf
+			letter.RedriveAttempt = attempt
+		}
+		if err := q.letters.CreateDeadLetter(ctx, letter); err != nil {
+			return err
+		}
+
+		util.DeadLettersTotal.WithLabelValues(consumerGroup).Inc()
+		q.logger.Warn("Message dead-lettered",
+			zap.Int64("dead_letter_id", letter.ID),
+			zap.String("consumer_group", consumerGroup),
+			zap.String("topic", msg.Topic),
+			zap.Int64("offset", msg.Offset),
+			zap.Error(handlerErr))
+		return nil
+	}
+}
+
+// SetRedrive enables re-driving parked messages through republish, at most
+// maxRedrives times per message
+func (q *DeadLetterQueue) SetRedrive(republish func(ctx context.Context, msg kafka.Message) error, maxRedrives int) {
+	q.republish = republish
+	q.maxRedrives = maxRedrives
+}
+
+// List returns the parked messages matching filter, newest first
+func (q *DeadLetterQueue) List(ctx context.Context, filter models.DeadLetterFilter) ([]models.DeadLetter, error) {
+	return q.letters.ListDeadLetters(ctx, filter)
+}
+
+// History returns the re-drives of a parked message and of the messages it
+// was re-driven from, oldest first
+func (q *DeadLetterQueue) History(ctx context.Context, deadLetterID int64) ([]models.DeadLetterRedrive, error) {
+	return q.letters.GetDeadLetterRedrives(ctx, deadLetterID)
+}
+
+// RedriveRequest selects parked messages to publish again to their source topic
+type RedriveRequest struct {
+	Filter models.DeadLetterFilter
+	// MergePatch is a JSON merge patch (RFC 7396) applied to each payload
+	// before it is published, to fix what made the handler fail
+	MergePatch json.RawMessage
+	Actor      string
+}
+
+// RedriveSkip is a selected message that was not re-driven
+type RedriveSkip struct {
+	DeadLetterID int64  `json:"dead_letter_id"`
+	Reason       string `json:"reason"`
+}
+
+// RedriveResult lists the messages a re-drive published and those it skipped
+type RedriveResult struct {
+	Redriven []models.DeadLetterRedrive `json:"redriven"`
+	Skipped  []RedriveSkip              `json:"skipped"`
+}
+
+// Redrive publishes the selected messages again to the topic they were
+// consumed from. Every consumer group of the topic sees them again, so this
+// relies on handlers being idempotent. Each message is re-driven once; a copy
+// that fails again is parked as a new message with its attempt counted, and
+// is not re-driven past the limit.
+func (q *DeadLetterQueue) Redrive(ctx context.Context, req RedriveRequest) (*RedriveResult, error) {
+	if q.republish == nil {
+		return nil, apperrors.New(apperrors.ErrUnavailable, "dead letter re-drive is not enabled")
+	}
+
+	var patch interface{}
+	if len(req.MergePatch) > 0 {
+		if err := json.Unmarshal(req.MergePatch, &patch); err != nil {
+			return nil, apperrors.New(apperrors.ErrInvalidRequest, "merge_patch is not valid JSON: %v", err)
+		}
+	}
+
+	// Without explicit IDs only letters that were never re-driven are selected
+	if len(req.Filter.IDs) == 0 {
+		req.Filter.Pending = true
+	}
+	letters, err := q.letters.ListDeadLetters(ctx, req.Filter)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &RedriveResult{Redriven: []models.DeadLetterRedrive{}, Skipped: []RedriveSkip{}}
+	skip := func(letter models.DeadLetter, reason string) {
+		util.DeadLetterRedrivesTotal.WithLabelValues("skipped").Inc()
+		result.Skipped = append(result.Skipped, RedriveSkip{DeadLetterID: letter.ID, Reason: reason})
+	}
+
+	// Oldest first, so messages of one key are published in their original order
+	for i := len(letters) - 1; i >= 0; i-- {
+		letter := letters[i]
+		if letter.RedrivenAt != nil {
+			skip(letter, "already re-driven")
+			continue
+		}
+		if letter.RedriveAttempt >= q.maxRedrives {
+			skip(letter, fmt.Sprintf("re-drive limit of %d reached", q.maxRedrives))
+			continue
+		}
+
+		payload := letter.Payload
+		if patch != nil {
+			patched, err := applyMergePatch(payload, patch)
+			if err != nil {
+				skip(letter, err.Error())
+				continue
+			}
+			payload = patched
+		}
+
+		redrive, err := q.redriveLetter(ctx, letter, payload, patch != nil, req.Actor)
+		if err != nil {
+			util.DeadLetterRedrivesTotal.WithLabelValues("failed").Inc()
+			q.logger.Error("Failed to re-drive dead letter",
+				zap.Int64("dead_letter_id", letter.ID),
+				zap.Error(err))
+			result.Skipped = append(result.Skipped, RedriveSkip{DeadLetterID: letter.ID, Reason: err.Error()})
+			continue
+		}
+		if redrive == nil {
+			skip(letter, "already re-driven")
+			continue
+		}
+
+		util.DeadLetterRedrivesTotal.WithLabelValues("redriven").Inc()
+		result.Redriven = append(result.Redriven, *redrive)
+	}
+
+	if len(result.Redriven) > 0 {
+		util.AdminActionsTotal.WithLabelValues("redrive_dead_letters").Inc()
+	}
+	q.logger.Info("Dead letters re-driven",
+		zap.Int("redriven", len(result.Redriven)),
+		zap.Int("skipped", len(result.Skipped)),
+		zap.String("actor", req.Actor))
+	return result, nil
+}
+
+// redriveLetter claims a letter, publishes payload to its topic and records
+// the re-drive, undoing the claim if publishing fails. Returns nil if another
+// re-drive claimed the letter first.
+func (q *DeadLetterQueue) redriveLetter(ctx context.Context, letter models.DeadLetter, payload string, transformed bool, actor string) (*models.DeadLetterRedrive, error) {
+	redrive := &models.DeadLetterRedrive{
+		DeadLetterID: letter.ID,
+		Attempt:      letter.RedriveAttempt + 1,
+		Actor:        actor,
+		Transformed:  transformed,
+		Payload:      payload,
+	}
+	claimed, err := q.letters.ClaimDeadLetterRedrive(ctx, redrive)
+	if err != nil || !claimed {
+		return nil, err
+	}
+
+	err = q.republish(ctx, kafka.Message{
+		Topic: letter.Topic,
+		Key:   []byte(letter.Key),
+		Value: []byte(payload),
+		Headers: []kafka.Header{
+			{Key: broker.HeaderDeadLetterID, Value: []byte(strconv.FormatInt(letter.ID, 10))},
+			{Key: broker.HeaderRedriveAttempt, Value: []byte(strconv.Itoa(redrive.Attempt))},
+		},
+	})
+	if err != nil {
+		if releaseErr := q.letters.ReleaseDeadLetterRedrive(ctx, redrive); releaseErr != nil {
+			q.logger.Error("Failed to release dead letter claim",
+				zap.Int64("dead_letter_id", letter.ID),
+				zap.Error(releaseErr))
+		}
+		return nil, err
+	}
+	return redrive, nil
+}
+
+// applyMergePatch applies a JSON merge patch to a JSON object payload
+func applyMergePatch(payload string, patch interface{}) (string, error) {
+	var doc map[string]interface{}
+	if err := json.Unmarshal([]byte(payload), &doc); err != nil {
+		return "", errors.New("payload is not a JSON object")
+	}
+
+	patched, err := json.Marshal(mergePatch(doc, patch))
+	if err != nil {
+		return "", err
+	}
+	return string(patched), nil
+}
+
+// mergePatch implements RFC 7396: objects are merged recursively, null
+// removes a member and any other value replaces the target
+func mergePatch(target, patch interface{}) interface{} {
+	patchObj, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	targetObj, ok := target.(map[string]interface{})
+	if !ok {
+		targetObj = make(map[string]interface{})
+	}
+	for key, value := range patchObj {
+		if value == nil {
+			delete(targetObj, key)
+			continue
+		}
+		targetObj[key] = mergePatch(targetObj[key], value)
+	}
+	return targetObj
+}
@@ -0,0 +1,107 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"order-service/internal/apperrors"
+	"order-service/internal/broker"
+	"order-service/internal/models"
+	"order-service/internal/store/mocks"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedrivePublishesPatchedLettersOldestFirst(t *testing.T) {
+	letters := mocks.NewDeadLetterRepository(t)
+	redrivenAt := time.Now()
+	letters.On("ListDeadLetters", mock.Anything, models.DeadLetterFilter{Topic: "orders", Pending: true, Limit: 10}).
+		Return([]models.DeadLetter{
+			{ID: 4, Topic: "orders", Key: "2", Payload: `{"order_id":2}`, RedriveAttempt: 3},
+			{ID: 3, Topic: "orders", Key: "1", Payload: `{"order_id":1,"status":"bad"}`},
+			{ID: 2, Topic: "orders", Payload: `not json`},
+			{ID: 1, Topic: "orders", Payload: `{}`, RedrivenAt: &redrivenAt},
+		}, nil).Once()
+	letters.On("ClaimDeadLetterRedrive", mock.Anything, mock.MatchedBy(func(r *models.DeadLetterRedrive) bool {
+		return r.DeadLetterID == 3 && r.Attempt == 1 && r.Transformed && r.Actor == "admin:operator"
+	})).Return(true, nil).Once()
+
+	var published []kafka.Message
+	q := NewDeadLetterQueue(letters)
+	q.SetRedrive(func(ctx context.Context, msg kafka.Message) error {
+		published = append(published, msg)
+		return nil
+	}, 3)
+
+	result, err := q.Redrive(context.Background(), RedriveRequest{
+		Filter:     models.DeadLetterFilter{Topic: "orders", Limit: 10},
+		MergePatch: json.RawMessage(`{"status":null,"fixed":true}`),
+		Actor:      "admin:operator",
+	})
+	require.NoError(t, err)
+
+	require.Len(t, published, 1)
+	assert.Equal(t, "orders", published[0].Topic)
+	assert.JSONEq(t, `{"order_id":1,"fixed":true}`, string(published[0].Value))
+	id, attempt, ok := broker.RedriveHeaders(published[0])
+	assert.True(t, ok)
+	assert.Equal(t, int64(3), id)
+	assert.Equal(t, 1, attempt)
+
+	require.Len(t, result.Redriven, 1)
+	assert.Equal(t, []RedriveSkip{
+		{DeadLetterID: 1, Reason: "already re-driven"},
+		{DeadLetterID: 2, Reason: "payload is not a JSON object"},
+		{DeadLetterID: 4, Reason: "re-drive limit of 3 reached"},
+	}, result.Skipped)
+}
+
+func TestRedriveReleasesClaimWhenPublishFails(t *testing.T) {
+	letters := mocks.NewDeadLetterRepository(t)
+	letters.On("ListDeadLetters", mock.Anything, models.DeadLetterFilter{IDs: []int64{7}, Limit: 1}).
+		Return([]models.DeadLetter{{ID: 7, Topic: "orders", Payload: `{}`}}, nil).Once()
+	letters.On("ClaimDeadLetterRedrive", mock.Anything, mock.Anything).Return(true, nil).Once()
+	letters.On("ReleaseDeadLetterRedrive", mock.Anything, mock.MatchedBy(func(r *models.DeadLetterRedrive) bool {
+		return r.DeadLetterID == 7
+	})).Return(nil).Once()
+
+	q := NewDeadLetterQueue(letters)
+	q.SetRedrive(func(ctx context.Context, msg kafka.Message) error {
+		return errors.New("broker down")
+	}, 3)
+
+	result, err := q.Redrive(context.Background(), RedriveRequest{Filter: models.DeadLetterFilter{IDs: []int64{7}, Limit: 1}})
+	require.NoError(t, err)
+	assert.Empty(t, result.Redriven)
+	require.Len(t, result.Skipped, 1)
+	assert.Equal(t, "broker down", result.Skipped[0].Reason)
+}
+
+func TestRedriveRequiresRepublisher(t *testing.T) {
+	_, err := NewDeadLetterQueue(mocks.NewDeadLetterRepository(t)).Redrive(context.Background(), RedriveRequest{})
+	assert.ErrorIs(t, err, apperrors.ErrUnavailable)
+}
+
+func TestDeadLetterHandlerLinksRedrivenMessages(t *testing.T) {
+	letters := mocks.NewDeadLetterRepository(t)
+	letters.On("CreateDeadLetter", mock.Anything, mock.MatchedBy(func(letter *models.DeadLetter) bool {
+		return letter.RedriveOf != nil && *letter.RedriveOf == 3 && letter.RedriveAttempt == 2
+	})).Return(nil).Once()
+
+	handler := NewDeadLetterQueue(letters).Handler("order-service-group")
+	err := handler(context.Background(), kafka.Message{
+		Topic: "orders",
+		Value: []byte(`{}`),
+		Headers: []kafka.Header{
+			{Key: broker.HeaderDeadLetterID, Value: []byte("3")},
+			{Key: broker.HeaderRedriveAttempt, Value: []byte("2")},
+		},
+	}, errors.New("still failing"))
+	assert.NoError(t, err)
+}
@@ -0,0 +1,296 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"order-service/internal/apperrors"
+	"order-service/internal/models"
+	"order-service/internal/store"
+	"order-service/internal/tenant"
+	"order-service/internal/util"
+
+	"go.uber.org/zap"
+)
+
+// DropService runs scheduled product drops. Users register their intent
+// before a drop starts; once it starts, registrations are converted into
+// orders one at a time in the order the drop's fairness policy sets, instead
+// of every user racing POST /orders at drop time.
+type DropService struct {
+	drops    store.DropRepository
+	orders   *OrderService
+	claimTTL time.Duration
+	logger   *zap.Logger
+}
+
+// NewDropService creates a new drop service. A drop whose conversion stalls
+// for claimTTL is taken over by the next run.
+func NewDropService(drops store.DropRepository, orders *OrderService, claimTTL time.Duration) *DropService {
+	return &DropService{
+		drops:    drops,
+		orders:   orders,
+		claimTTL: claimTTL,
+		logger:   util.GetLogger(),
+	}
+}
+
+// CreateDropRequest schedules a product drop
+type CreateDropRequest struct {
+	ProductID int64 `json:"product_id"`
+	// VariantID is the variant dropped, the product's default variant when unset
+	VariantID int64     `json:"variant_id,omitempty"`
+	Name      string    `json:"name"`
+	StartsAt  time.Time `json:"starts_at"`
+	// Policy is fifo or random (the default)
+	Policy             string `json:"policy,omitempty"`
+	MaxQuantityPerUser int    `json:"max_quantity_per_user,omitempty"`
+}
+
+// DropView is a drop with its registrations counted by status
+type DropView struct {
+	*models.Drop
+	Registrations map[string]int `json:"registrations"`
+}
+
+// DropRegistrationRequest registers a user for a drop
+type DropRegistrationRequest struct {
+	UserID        int64  `json:"user_id"`
+	Quantity      int    `json:"quantity"`
+	PaymentMethod string `json:"payment_method"`
+}
+
+// CreateDrop schedules a drop of a product variant
+func (s *DropService) CreateDrop(ctx context.Context, req CreateDropRequest) (*models.Drop, error) {
+	if req.Policy == "" {
+		req.Policy = models.DropPolicyRandom
+	}
+	if req.MaxQuantityPerUser == 0 {
+		req.MaxQuantityPerUser = 1
+	}
+
+	switch {
+	case strings.TrimSpace(req.Name) == "":
+		return nil, apperrors.New(apperrors.ErrInvalidRequest, "name is required")
+	case !req.StartsAt.After(time.Now()):
+		return nil, apperrors.New(apperrors.ErrInvalidRequest, "starts_at must be in the future")
+	case req.Policy != models.DropPolicyFIFO && req.Policy != models.DropPolicyRandom:
+		return nil, apperrors.New(apperrors.ErrInvalidRequest, "policy must be %s or %s", models.DropPolicyFIFO, models.DropPolicyRandom)
+	case req.MaxQuantityPerUser < 0:
+		return nil, apperrors.New(apperrors.ErrInvalidRequest, "max_quantity_per_user must be positive")
+	}
+
+	items := []OrderItemRequest{{ProductID: req.ProductID, VariantID: req.VariantID, Quantity: 1}}
+	if _, _, err := s.orders.validateOrderItems(ctx, items); err != nil {
+		return nil, err
+	}
+
+	drop := &models.Drop{
+		ProductID:          req.ProductID,
+		VariantID:          items[0].VariantID,
+		Name:               req.Name,
+		StartsAt:           req.StartsAt,
+		Policy:             req.Policy,
+		MaxQuantityPerUser: req.MaxQuantityPerUser,
+	}
+	if err := s.drops.CreateDrop(ctx, drop); err != nil {
+		return nil, fmt.Errorf("failed to create drop: %w", err)
+	}
+
+	util.AdminActionsTotal.WithLabelValues("drop_create").Inc()
+	s.logger.Info("Drop scheduled",
+		zap.Int64("drop_id", drop.ID),
+		zap.Int64("variant_id", drop.VariantID),
+		zap.Time("starts_at", drop.StartsAt))
+	return drop, nil
+}
+
+// GetDrop returns a drop with its registration counts
+func (s *DropService) GetDrop(ctx context.Context, id int64) (*DropView, error) {
+	drop, err := s.drops.GetDrop(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	counts, err := s.drops.CountDropRegistrations(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count drop registrations: %w", err)
+	}
+	return &DropView{Drop: drop, Registrations: counts}, nil
+}
+
+// Register registers a user for a drop that has not started, replacing the
+// user's earlier registration
+func (s *DropService) Register(ctx context.Context, dropID int64, req DropRegistrationRequest) (*models.DropRegistration, error) {
+	drop, err := s.drops.GetDrop(ctx, dropID)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case req.UserID <= 0:
+		return nil, apperrors.New(apperrors.ErrInvalidRequest, "user_id is required")
+	case req.PaymentMethod == "":
+		return nil, apperrors.New(apperrors.ErrInvalidRequest, "payment_method is required")
+	case req.Quantity < 1 || req.Quantity > drop.MaxQuantityPerUser:
+		return nil, apperrors.New(apperrors.ErrInvalidRequest, "quantity must be between 1 and %d", drop.MaxQuantityPerUser)
+	}
+
+	reg := &models.DropRegistration{
+		DropID:        dropID,
+		UserID:        req.UserID,
+		Quantity:      req.Quantity,
+		PaymentMethod: req.PaymentMethod,
+	}
+	open := drop.Status == models.DropStatusScheduled && time.Now().Before(drop.StartsAt)
+	if open {
+		if open, err = s.drops.RegisterForDrop(ctx, reg); err != nil {
+			return nil, fmt.Errorf("failed to register for drop: %w", err)
+		}
+	}
+	if !open {
+		return nil, apperrors.New(apperrors.ErrDropClosed, "drop %d started at %s", dropID, drop.StartsAt.Format(time.RFC3339))
+	}
+
+	util.DropRegistrationsTotal.Inc()
+	return reg, nil
+}
+
+// GetRegistration returns a user's registration for a drop
+func (s *DropService) GetRegistration(ctx context.Context, dropID, userID int64) (*models.DropRegistration, error) {
+	return s.drops.GetDropRegistration(ctx, dropID, userID)
+}
+
+// ConvertDueDrops converts the registrations of every drop that has started
+// into orders. A drop left running by a failed conversion is resumed once its
+// claim expires. Returns the number of orders created.
+func (s *DropService) ConvertDueDrops(ctx context.Context) (int, error) {
+	ctx, span := util.StartSpan(ctx, "DropService.ConvertDueDrops")
+	defer span.End()
+
+	ordered := 0
+	for {
+		drop, err := s.drops.ClaimDueDrop(ctx, time.Now(), s.claimTTL, rand.Int63())
+		if err != nil {
+			return ordered, fmt.Errorf("failed to claim drop: %w", err)
+		}
+		if drop == nil {
+			return ordered, nil
+		}
+
+		n, err := s.convert(ctx, drop)
+		ordered += n
+		if err != nil {
+			return ordered, fmt.Errorf("failed to convert drop %d: %w", drop.ID, err)
+		}
+	}
+}
+
+// convert orders the pending registrations of a claimed drop. Once a quantity
+// sells out, registrations for as many or more are marked sold out without
+// trying to order them.
+func (s *DropService) convert(ctx context.Context, drop *models.Drop) (int, error) {
+	regs, err := s.drops.GetPendingDropRegistrations(ctx, drop.ID)
+	if err != nil {
+		return 0, err
+	}
+	if drop.Policy == models.DropPolicyRandom && drop.Seed != nil {
+		shuffleRegistrations(regs, *drop.Seed)
+	}
+
+	s.logger.Info("Converting drop registrations",
+		zap.Int64("drop_id", drop.ID),
+		zap.String("policy", drop.Policy),
+		zap.Int("pending", len(regs)))
+
+	ordered, soldOutAt := 0, 0
+	renewAt := time.Now().Add(s.claimTTL / 3)
+	for i := range regs {
+		reg := &regs[i]
+		if soldOutAt > 0 && reg.Quantity >= soldOutAt {
+			reg.Status = models.DropRegistrationSoldOut
+		} else if err := s.order(ctx, drop, reg); err != nil {
+			return ordered, err
+		}
+
+		switch reg.Status {
+		case models.DropRegistrationOrdered:
+			ordered++
+		case models.DropRegistrationSoldOut:
+			if soldOutAt == 0 || reg.Quantity < soldOutAt {
+				soldOutAt = reg.Quantity
+			}
+		}
+
+		if err := s.drops.FinishDropRegistration(ctx, reg); err != nil {
+			return ordered, fmt.Errorf("failed to record registration %d: %w", reg.ID, err)
+		}
+		util.DropConversionsTotal.WithLabelValues(strings.ToLower(reg.Status)).Inc()
+
+		if now := time.Now(); now.After(renewAt) {
+			if err := s.drops.RenewDropClaim(ctx, drop.ID, now); err != nil {
+				return ordered, fmt.Errorf("failed to renew drop claim: %w", err)
+			}
+			renewAt = now.Add(s.claimTTL / 3)
+		}
+	}
+
+	if err := s.drops.CompleteDrop(ctx, drop.ID); err != nil {
+		return ordered, fmt.Errorf("failed to complete drop: %w", err)
+	}
+	s.logger.Info("Drop completed", zap.Int64("drop_id", drop.ID), zap.Int("ordered", ordered))
+	return ordered, nil
+}
+
+// order creates the order of a registration and sets its outcome. Errors the
+// order was rejected with fail the registration; server errors are returned so
+// the drop is retried. Retries reuse the registration's idempotency key, so a
+// user never gets two orders from one drop. The order belongs to the drop's
+// tenant.
+func (s *DropService) order(ctx context.Context, drop *models.Drop, reg *models.DropRegistration) error {
+	resp, err := s.orders.CreateOrder(tenant.WithID(ctx, drop.TenantID), &CreateOrderRequest{
+		UserID:         reg.UserID,
+		Items:          []OrderItemRequest{{ProductID: drop.ProductID, VariantID: drop.VariantID, Quantity: reg.Quantity}},
+		PaymentMethod:  reg.PaymentMethod,
+		IdempotencyKey: fmt.Sprintf("drop-%d-%d", drop.ID, reg.UserID),
+		NoBackorder:    true,
+	})
+
+	var appErr *apperrors.Error
+	switch {
+	case errors.Is(err, apperrors.ErrInsufficientStock):
+		reg.Status = models.DropRegistrationSoldOut
+	case errors.As(err, &appErr) && appErr.Status < http.StatusInternalServerError:
+		reg.Status, reg.Error = models.DropRegistrationFailed, err.Error()
+	case err != nil:
+		return err
+	case resp.Status == models.OrderStatusFailed:
+		// An earlier attempt created the order but failed to reserve its stock
+		reg.Status, reg.Error, reg.OrderID = models.DropRegistrationFailed, "order failed", &resp.OrderID
+	default:
+		reg.Status, reg.OrderID = models.DropRegistrationOrdered, &resp.OrderID
+	}
+	return nil
+}
+
+// shuffleRegistrations orders registrations randomly by seed. Each
+// registration's rank depends only on the seed and its ID, so a drop resumed
+// after a failure continues in the same order.
+func shuffleRegistrations(regs []models.DropRegistration, seed int64) {
+	sort.Slice(regs, func(i, j int) bool {
+		return dropRank(seed, regs[i].ID) < dropRank(seed, regs[j].ID)
+	})
+}
+
+// dropRank mixes seed and id with splitmix64
+func dropRank(seed, id int64) uint64 {
+	z := uint64(seed) + uint64(id)*0x9e3779b97f4a7c15
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	return z ^ (z >> 31)
+}
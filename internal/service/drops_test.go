@@ -0,0 +1,104 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"order-service/internal/apperrors"
+	"order-service/internal/models"
+	"order-service/internal/store/mocks"
+	"order-service/internal/util"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConvertDueDropsOrdersRegistrationsInFIFOOrder(t *testing.T) {
+	drops := mocks.NewDropRepository(t)
+	drop := &models.Drop{ID: 5, ProductID: 10, VariantID: 11, Policy: models.DropPolicyFIFO, Status: models.DropStatusRunning}
+	drops.On("ClaimDueDrop", mock.Anything, mock.Anything, time.Minute, mock.Anything).Return(drop, nil).Once()
+	drops.On("ClaimDueDrop", mock.Anything, mock.Anything, time.Minute, mock.Anything).Return(nil, nil).Once()
+	drops.On("GetPendingDropRegistrations", mock.Anything, int64(5)).Return([]models.DropRegistration{
+		{ID: 1, DropID: 5, UserID: 101, Quantity: 1, PaymentMethod: "card"},
+		{ID: 2, DropID: 5, UserID: 102, Quantity: 1, PaymentMethod: "card"},
+	}, nil).Once()
+
+	var finished []models.DropRegistration
+	drops.On("FinishDropRegistration", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		finished = append(finished, *args.Get(1).(*models.DropRegistration))
+	}).Return(nil).Twice()
+	drops.On("CompleteDrop", mock.Anything, int64(5)).Return(nil).Once()
+
+	// Both orders were created by an earlier, interrupted run
+	orders := mocks.NewOrderRepository(t)
+	orders.On("GetOrderByIdempotencyKey", mock.Anything, "drop-5-101").
+		Return(&models.Order{ID: 900, Status: models.OrderStatusReserved}, nil).Once()
+	orders.On("GetOrderByIdempotencyKey", mock.Anything, "drop-5-102").
+		Return(&models.Order{ID: 901, Status: models.OrderStatusFailed}, nil).Once()
+
+	service := NewDropService(drops, &OrderService{orders: orders, logger: util.GetLogger()}, time.Minute)
+	ordered, err := service.ConvertDueDrops(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, ordered)
+
+	require.Len(t, finished, 2)
+	assert.Equal(t, int64(101), finished[0].UserID)
+	assert.Equal(t, models.DropRegistrationOrdered, finished[0].Status)
+	assert.Equal(t, int64(900), *finished[0].OrderID)
+	assert.Equal(t, models.DropRegistrationFailed, finished[1].Status)
+}
+
+func TestConvertDueDropsLeavesDropRunningOnServerErrors(t *testing.T) {
+	drops := mocks.NewDropRepository(t)
+	drops.On("ClaimDueDrop", mock.Anything, mock.Anything, time.Minute, mock.Anything).
+		Return(&models.Drop{ID: 5, Policy: models.DropPolicyFIFO}, nil).Once()
+	drops.On("GetPendingDropRegistrations", mock.Anything, int64(5)).
+		Return([]models.DropRegistration{{ID: 1, UserID: 101, Quantity: 1}}, nil).Once()
+
+	orders := mocks.NewOrderRepository(t)
+	orders.On("GetOrderByIdempotencyKey", mock.Anything, "drop-5-101").
+		Return(nil, errors.New("connection refused")).Once()
+
+	service := NewDropService(drops, &OrderService{orders: orders, logger: util.GetLogger()}, time.Minute)
+	_, err := service.ConvertDueDrops(context.Background())
+	assert.ErrorContains(t, err, "connection refused")
+}
+
+func TestShuffleRegistrationsIsStableForSeed(t *testing.T) {
+	regs := make([]models.DropRegistration, 20)
+	for i := range regs {
+		regs[i].ID = int64(i + 1)
+	}
+	shuffleRegistrations(regs, 42)
+
+	var ids []int64
+	for _, reg := range regs {
+		ids = append(ids, reg.ID)
+	}
+	assert.NotEqual(t, []int64{1, 2, 3, 4, 5}, ids[:5])
+
+	// A resumed drop converts what is left in the same relative order
+	rest := append([]models.DropRegistration(nil), regs[5:]...)
+	for i, j := 0, len(rest)-1; i < j; i, j = i+1, j-1 {
+		rest[i], rest[j] = rest[j], rest[i]
+	}
+	shuffleRegistrations(rest, 42)
+	assert.Equal(t, regs[5:], rest)
+}
+
+func TestRegisterRejectsClosedDropsAndLargeQuantities(t *testing.T) {
+	drops := mocks.NewDropRepository(t)
+	drops.On("GetDrop", mock.Anything, int64(5)).Return(&models.Drop{
+		ID: 5, Status: models.DropStatusScheduled, StartsAt: time.Now().Add(-time.Second), MaxQuantityPerUser: 2,
+	}, nil)
+	service := NewDropService(drops, nil, time.Minute)
+
+	_, err := service.Register(context.Background(), 5, DropRegistrationRequest{UserID: 1, Quantity: 1, PaymentMethod: "card"})
+	assert.ErrorIs(t, err, apperrors.ErrDropClosed)
+
+	_, err = service.Register(context.Background(), 5, DropRegistrationRequest{UserID: 1, Quantity: 3, PaymentMethod: "card"})
+	assert.ErrorIs(t, err, apperrors.ErrInvalidRequest)
+}
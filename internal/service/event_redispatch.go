@@ -0,0 +1,166 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"order-service/internal/models"
+	"order-service/internal/util"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// RedispatchPolicy controls which orders the startup event re-dispatch scans
+type RedispatchPolicy struct {
+	// Lookback is how far back order updates are scanned
+	Lookback time.Duration
+	// Grace skips orders updated this recently, whose event may still be in flight
+	Grace     time.Duration
+	BatchSize int
+}
+
+// RedispatchMissingEvents re-publishes the event implied by the status of
+// orders whose event never reached Kafka, e.g. because the process crashed
+// between the status update and the publish. The order timeline is the record
+// of what was published. Returns the number of events re-published.
+func (so *SagaOrchestrator) RedispatchMissingEvents(ctx context.Context, policy RedispatchPolicy) (int, error) {
+	ctx, span := util.StartSpan(ctx, "SagaOrchestrator.RedispatchMissingEvents")
+	defer span.End()
+
+	if so.timeline == nil {
+		return 0, nil
+	}
+	if policy.BatchSize <= 0 {
+		policy.BatchSize = 100
+	}
+
+	now := time.Now()
+	since, before := now.Add(-policy.Lookback), now.Add(-policy.Grace)
+
+	redispatched := 0
+	var afterID int64
+	for {
+		orders, err := so.timeline.GetOrdersMissingEvents(ctx, since, before, afterID, policy.BatchSize)
+		if err != nil {
+			return redispatched, fmt.Errorf("failed to get orders missing events: %w", err)
+		}
+
+		for _, order := range orders {
+			afterID = order.ID
+			sent, err := so.redispatchOrder(ctx, order.ID, order.Status)
+			if err != nil {
+				so.logger.Error("Failed to re-dispatch order event",
+					zap.Int64("order_id", order.ID),
+					zap.String("status", order.Status),
+					zap.Error(err))
+				continue
+			}
+			if sent {
+				redispatched++
+			}
+		}
+
+		if len(orders) < policy.BatchSize {
+			return redispatched, nil
+		}
+	}
+}
+
+// redispatchOrder re-publishes the event of one order if it is still in the
+// scanned status and the event is still missing
+func (so *SagaOrchestrator) redispatchOrder(ctx context.Context, orderID int64, status string) (bool, error) {
+	ctx, lock, err := so.lockOrder(ctx, orderID)
+	if err != nil {
+		return false, err
+	}
+	defer so.unlockOrder(lock, orderID)
+
+	// Re-read under the lock: the saga may have moved, or published, since the scan
+	order, err := so.orders.GetOrderByID(ctx, orderID)
+	if err != nil {
+		return false, err
+	}
+	if order.Status != status {
+		return false, nil
+	}
+
+	eventType := statusEventType(status)
+	entries, err := so.timeline.GetOrderTimeline(ctx, orderID)
+	if err != nil {
+		return false, fmt.Errorf("failed to get order timeline: %w", err)
+	}
+	for _, entry := range entries {
+		if entry.EventType == eventType {
+			return false, nil
+		}
+	}
+
+	switch status {
+	case models.OrderStatusReserved:
+		event, err := so.orderReservedEvent(ctx, order)
+		if err != nil {
+			return false, err
+		}
+		err = so.eventPublisher.PublishOrderReserved(ctx, event)
+	case models.OrderStatusCancelled:
+		err = so.eventPublisher.PublishOrderCancelled(ctx, &models.OrderCancelledEvent{
+			BaseEvent: redispatchBase(eventType),
+			OrderID:   order.ID,
+			Reason:    so.lastStatusReason(ctx, order.ID, status),
+		})
+	case models.OrderStatusOnHold:
+		err = so.eventPublisher.PublishOrderOnHold(ctx, &models.OrderOnHoldEvent{
+			BaseEvent: redispatchBase(eventType),
+			OrderID:   order.ID,
+			Reason:    so.lastStatusReason(ctx, order.ID, status),
+		})
+	default:
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to re-publish %s: %w", eventType, err)
+	}
+
+	so.logger.Warn("Re-dispatched missing order event",
+		zap.Int64("order_id", order.ID),
+		zap.String("event_type", eventType))
+	util.EventsRedispatchedTotal.WithLabelValues(eventType).Inc()
+	return true, nil
+}
+
+// statusEventType returns the event an order publishes when entering status
+func statusEventType(status string) string {
+	switch status {
+	case models.OrderStatusReserved:
+		return models.EventTypeOrderReserved
+	case models.OrderStatusCancelled:
+		return models.EventTypeOrderCancelled
+	case models.OrderStatusOnHold:
+		return models.EventTypeOrderOnHold
+	}
+	return ""
+}
+
+// redispatchBase returns the envelope of a re-published event
+func redispatchBase(eventType string) models.BaseEvent {
+	return models.BaseEvent{EventID: uuid.New().String(), EventType: eventType, Timestamp: time.Now()}
+}
+
+// lastStatusReason returns the reason recorded when the order last entered status
+func (so *SagaOrchestrator) lastStatusReason(ctx context.Context, orderID int64, status string) string {
+	history, err := so.orders.GetOrderStatusHistory(ctx, orderID)
+	if err != nil {
+		so.logger.Warn("Failed to get order status history", zap.Int64("order_id", orderID), zap.Error(err))
+		return ""
+	}
+
+	reason := ""
+	for _, entry := range history {
+		if entry.NewStatus == status {
+			reason = entry.Reason
+		}
+	}
+	return reason
+}
@@ -0,0 +1,211 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"order-service/internal/apperrors"
+	"order-service/internal/broker"
+	"order-service/internal/models"
+	"order-service/internal/store"
+	"order-service/internal/util"
+
+	"github.com/segmentio/kafka-go"
+	"go.uber.org/zap"
+)
+
+// Replay modes
+const (
+	ReplayModeDryRun = "dry-run"
+	ReplayModeApply  = "apply"
+)
+
+// Outcomes of a replayed event
+const (
+	ReplayWouldHandle = "would_handle"
+	ReplayHandled     = "handled"
+	ReplayProcessed   = "already_processed"
+	ReplayFailed      = "failed"
+	ReplayInvalid     = "invalid"
+)
+
+const (
+	defaultReplayLimit = 1000
+	maxReplayLimit     = 10000
+)
+
+// HistoryReader reads the messages of the order events topic in a range
+type HistoryReader func(ctx context.Context, rng broker.HistoryRange, fn func(kafka.Message) (bool, error)) error
+
+// EventReplayer re-delivers historical events from Kafka through the saga
+// handlers, to recover orders a consumer bug mishandled
+type EventReplayer struct {
+	read    HistoryReader
+	handler broker.MessageHandler
+	orders  store.OrderRepository
+	logger  *zap.Logger
+}
+
+// NewEventReplayer creates a new event replayer delivering events read by
+// read to handler
+func NewEventReplayer(read HistoryReader, handler broker.MessageHandler, orders store.OrderRepository) *EventReplayer {
+	return &EventReplayer{
+		read:    read,
+		handler: handler,
+		orders:  orders,
+		logger:  util.GetLogger(),
+	}
+}
+
+// ReplayRequest selects the events to replay and how
+type ReplayRequest struct {
+	Range broker.HistoryRange
+	// OrderID and EventTypes filter the events in Range when set
+	OrderID    int64
+	EventTypes []string
+	// Mode is dry-run, which only reports what would be handled, or apply
+	Mode string
+	// Reprocess handles events the saga already processed again; without it
+	// they are reported and left alone
+	Reprocess bool
+	// Limit caps the events matched
+	Limit int
+	Actor string
+}
+
+// ReplayedEvent is the outcome of one event matched by a replay
+type ReplayedEvent struct {
+	Partition int       `json:"partition"`
+	Offset    int64     `json:"offset"`
+	Time      time.Time `json:"time"`
+	EventID   string    `json:"event_id,omitempty"`
+	EventType string    `json:"event_type,omitempty"`
+	OrderID   int64     `json:"order_id,omitempty"`
+	Result    string    `json:"result"`
+	Error     string    `json:"error,omitempty"`
+}
+
+// ReplayReport lists the events a replay matched. Truncated is set when Limit
+// events were matched before the end of the range.
+type ReplayReport struct {
+	Mode      string          `json:"mode"`
+	Scanned   int             `json:"scanned"`
+	Matched   int             `json:"matched"`
+	Results   map[string]int  `json:"results"`
+	Events    []ReplayedEvent `json:"events"`
+	Truncated bool            `json:"truncated"`
+}
+
+// replayEnvelope holds the fields events are filtered by
+type replayEnvelope struct {
+	models.BaseEvent
+	OrderID int64 `json:"order_id"`
+}
+
+// Replay reads the events in req.Range and delivers those matching its
+// filters to the saga handlers in order, or only reports them in dry-run mode.
+// A failed event is reported and the replay continues.
+func (r *EventReplayer) Replay(ctx context.Context, req ReplayRequest) (*ReplayReport, error) {
+	ctx, span := util.StartSpan(ctx, "EventReplayer.Replay")
+	defer span.End()
+
+	if req.Mode == "" {
+		req.Mode = ReplayModeDryRun
+	}
+	if req.Limit == 0 {
+		req.Limit = defaultReplayLimit
+	}
+	switch {
+	case req.Mode != ReplayModeDryRun && req.Mode != ReplayModeApply:
+		return nil, apperrors.New(apperrors.ErrInvalidRequest, "mode must be %s or %s", ReplayModeDryRun, ReplayModeApply)
+	case req.Range.FromOffset < 0 && req.Range.Since.IsZero():
+		return nil, apperrors.New(apperrors.ErrInvalidRequest, "a start offset or time is required")
+	case req.Limit < 0 || req.Limit > maxReplayLimit:
+		return nil, apperrors.New(apperrors.ErrInvalidRequest, "limit must be between 1 and %d", maxReplayLimit)
+	}
+
+	types := make(map[string]bool, len(req.EventTypes))
+	for _, t := range req.EventTypes {
+		types[t] = true
+	}
+
+	report := &ReplayReport{Mode: req.Mode, Results: map[string]int{}, Events: []ReplayedEvent{}}
+	err := r.read(ctx, req.Range, func(msg kafka.Message) (bool, error) {
+		report.Scanned++
+
+		replayed := ReplayedEvent{Partition: msg.Partition, Offset: msg.Offset, Time: msg.Time}
+		var envelope replayEnvelope
+		value, err := broker.Decode(msg.Value)
+		if err == nil {
+			err = json.Unmarshal(value, &envelope)
+		}
+		if err == nil {
+			replayed.EventID, replayed.EventType, replayed.OrderID = envelope.EventID, envelope.EventType, envelope.OrderID
+			if (req.OrderID != 0 && envelope.OrderID != req.OrderID) || (len(types) > 0 && !types[envelope.EventType]) {
+				return true, nil
+			}
+		} else if req.OrderID != 0 || len(types) > 0 {
+			// An undecodable message cannot match a filter
+			return true, nil
+		}
+
+		if report.Matched == req.Limit {
+			report.Truncated = true
+			return false, nil
+		}
+		report.Matched++
+
+		if err != nil {
+			replayed.Result, replayed.Error = ReplayInvalid, err.Error()
+		} else {
+			msg.Value = value
+			replayed.Result, err = r.deliver(ctx, req, envelope.BaseEvent, msg)
+			if err != nil {
+				replayed.Error = err.Error()
+			}
+		}
+
+		report.Results[replayed.Result]++
+		report.Events = append(report.Events, replayed)
+		util.EventsReplayedTotal.WithLabelValues(req.Mode, replayed.Result).Inc()
+		return true, nil
+	})
+	if err != nil {
+		return report, fmt.Errorf("failed to read events after %d: %w", report.Scanned, err)
+	}
+
+	r.logger.Info("Events replayed",
+		zap.String("mode", req.Mode),
+		zap.String("actor", req.Actor),
+		zap.Int("scanned", report.Scanned),
+		zap.Int("matched", report.Matched),
+		zap.Any("results", report.Results))
+	return report, nil
+}
+
+// deliver hands one decoded event to the saga handlers, unless it is a dry
+// run or the event was processed and is not to be reprocessed
+func (r *EventReplayer) deliver(ctx context.Context, req ReplayRequest, event models.BaseEvent, msg kafka.Message) (string, error) {
+	processed, err := r.orders.IsEventProcessed(ctx, event.EventID)
+	if err != nil {
+		return ReplayFailed, fmt.Errorf("failed to check event processed: %w", err)
+	}
+	if processed && !req.Reprocess {
+		return ReplayProcessed, nil
+	}
+	if req.Mode == ReplayModeDryRun {
+		return ReplayWouldHandle, nil
+	}
+
+	if processed {
+		if err := r.orders.UnmarkEventProcessed(ctx, event.EventID); err != nil {
+			return ReplayFailed, fmt.Errorf("failed to unmark event processed: %w", err)
+		}
+	}
+	if err := r.handler(ctx, msg); err != nil {
+		return ReplayFailed, err
+	}
+	return ReplayHandled, nil
+}
@@ -0,0 +1,104 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"order-service/internal/apperrors"
+	"order-service/internal/broker"
+	"order-service/internal/store/mocks"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func replayMessages(values ...string) HistoryReader {
+	return func(ctx context.Context, rng broker.HistoryRange, fn func(kafka.Message) (bool, error)) error {
+		for i, value := range values {
+			more, err := fn(kafka.Message{Partition: 0, Offset: int64(i), Value: []byte(value)})
+			if err != nil || !more {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+var replayedEvents = []string{
+	`{"event_id":"e1","event_type":"PAYMENT_SUCCESS","order_id":1}`,
+	`{"event_id":"e2","event_type":"PAYMENT_SUCCESS","order_id":2}`,
+	`{"event_id":"e3","event_type":"PAYMENT_FAILED","order_id":1}`,
+	`{"specversion":"1.0","id":"e4","type":"PAYMENT_SUCCESS","data":{"orderId":1}}`,
+}
+
+func TestReplayDryRunFiltersAndDeliversNothing(t *testing.T) {
+	orders := mocks.NewOrderRepository(t)
+	orders.On("IsEventProcessed", mock.Anything, "e1").Return(true, nil).Once()
+	orders.On("IsEventProcessed", mock.Anything, "e4").Return(false, nil).Once()
+
+	handler := func(ctx context.Context, msg kafka.Message) error {
+		t.Fatal("dry run delivered an event")
+		return nil
+	}
+	replayer := NewEventReplayer(replayMessages(replayedEvents...), handler, orders)
+
+	report, err := replayer.Replay(context.Background(), ReplayRequest{
+		Range:      broker.HistoryRange{Partition: -1, FromOffset: 0, ToOffset: -1},
+		OrderID:    1,
+		EventTypes: []string{"PAYMENT_SUCCESS"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, ReplayModeDryRun, report.Mode)
+	assert.Equal(t, 4, report.Scanned)
+	assert.Equal(t, 2, report.Matched)
+	require.Len(t, report.Events, 2)
+	assert.Equal(t, ReplayProcessed, report.Events[0].Result)
+	assert.Equal(t, "e4", report.Events[1].EventID)
+	assert.Equal(t, ReplayWouldHandle, report.Events[1].Result)
+}
+
+func TestReplayApplyReprocessesAndStopsAtLimit(t *testing.T) {
+	orders := mocks.NewOrderRepository(t)
+	orders.On("IsEventProcessed", mock.Anything, "e1").Return(true, nil).Once()
+	orders.On("UnmarkEventProcessed", mock.Anything, "e1").Return(nil).Once()
+	orders.On("IsEventProcessed", mock.Anything, "e2").Return(false, nil).Once()
+
+	var delivered []string
+	handler := func(ctx context.Context, msg kafka.Message) error {
+		delivered = append(delivered, string(msg.Value))
+		if len(delivered) == 2 {
+			return assert.AnError
+		}
+		return nil
+	}
+	replayer := NewEventReplayer(replayMessages(append(replayedEvents, "not json")...), handler, orders)
+
+	report, err := replayer.Replay(context.Background(), ReplayRequest{
+		Range:     broker.HistoryRange{Partition: -1, FromOffset: 0, ToOffset: -1},
+		Mode:      ReplayModeApply,
+		Reprocess: true,
+		Limit:     2,
+	})
+	require.NoError(t, err)
+	assert.True(t, report.Truncated)
+	assert.Equal(t, map[string]int{ReplayHandled: 1, ReplayFailed: 1}, report.Results)
+	assert.Len(t, delivered, 2)
+	assert.Equal(t, assert.AnError.Error(), report.Events[1].Error)
+}
+
+func TestReplayRejectsUnboundedRangesAndUnknownModes(t *testing.T) {
+	replayer := NewEventReplayer(replayMessages(), nil, nil)
+
+	_, err := replayer.Replay(context.Background(), ReplayRequest{
+		Range: broker.HistoryRange{Partition: -1, FromOffset: -1, ToOffset: -1},
+	})
+	assert.ErrorIs(t, err, apperrors.ErrInvalidRequest)
+
+	_, err = replayer.Replay(context.Background(), ReplayRequest{
+		Range: broker.HistoryRange{Partition: -1, FromOffset: 0, ToOffset: -1},
+		Mode:  "force",
+	})
+	assert.ErrorIs(t, err, apperrors.ErrInvalidRequest)
+}
@@ -0,0 +1,240 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"order-service/internal/apperrors"
+	"order-service/internal/redisclient"
+	"order-service/internal/tenant"
+	"order-service/internal/util"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+const (
+	// fairReservationLockTTL bounds how long a queue goes undrained after the
+	// instance draining it died
+	fairReservationLockTTL = 10 * time.Second
+	// fairReservationPoll is how long a drainer blocks on an empty queue, and
+	// how often an instance not draining a queue tries to take it over
+	fairReservationPoll = time.Second
+	// fairReservationGrace bounds a reservation the drainer started just
+	// before its requester's deadline; the requester waits for it so the
+	// stock is never held for an order that already gave up
+	fairReservationGrace = 2 * time.Second
+)
+
+// fairReservation is a reservation request waiting in a product's queue
+type fairReservation struct {
+	ID        string `json:"id"`
+	Tenant    string `json:"tenant,omitempty"`
+	OrderID   int64  `json:"order_id"`
+	VariantID int64  `json:"variant_id"`
+	Quantity  int    `json:"quantity"`
+	// Enqueued and Deadline are Unix milliseconds; past its deadline the
+	// requester no longer waits and the request is skipped
+	Enqueued int64 `json:"enqueued"`
+	Deadline int64 `json:"deadline"`
+}
+
+// fairReservationResult is what the drainer answers a reservation request
+type fairReservationResult struct {
+	Reserved bool   `json:"reserved"`
+	Error    string `json:"error,omitempty"`
+}
+
+// FairReservations serializes the reservations of contended products first
+// come first served. Instead of racing each other for the last units, order
+// requests push their reservations to a Redis list per product, one instance
+// drains each list in order and answers every request on its own result key.
+type FairReservations struct {
+	inventory *InventoryClient
+	redis     *redisclient.Client
+	products  map[int64]bool
+	wait      time.Duration
+	logger    *zap.Logger
+}
+
+// NewFairReservations queues the reservations of productIDs, failing those
+// not served within wait. Wire it with InventoryClient.SetFairReservations.
+func NewFairReservations(redis *redisclient.Client, productIDs []int64, wait time.Duration) *FairReservations {
+	products := make(map[int64]bool, len(productIDs))
+	for _, id := range productIDs {
+		products[id] = true
+	}
+	return &FairReservations{
+		redis:    redis,
+		products: products,
+		wait:     wait,
+		logger:   util.GetLogger(),
+	}
+}
+
+// Queues reports whether the reservations of a product are queued
+func (f *FairReservations) Queues(productID int64) bool {
+	return f != nil && f.products[productID]
+}
+
+// Reserve queues a reservation of a variant of a product for an order and
+// waits for its turn. Requests not served in time fail as unavailable.
+func (f *FairReservations) Reserve(ctx context.Context, productID, orderID, variantID int64, quantity int) (bool, error) {
+	now := time.Now()
+	req := fairReservation{
+		ID:        uuid.New().String(),
+		OrderID:   orderID,
+		VariantID: variantID,
+		Quantity:  quantity,
+		Enqueued:  now.UnixMilli(),
+		Deadline:  now.Add(f.wait).UnixMilli(),
+	}
+	if id, ok := tenant.ID(ctx); ok {
+		req.Tenant = id
+	}
+	payload, err := json.Marshal(req)
+	if err != nil {
+		return false, fmt.Errorf("failed to encode reservation request: %w", err)
+	}
+
+	position, err := f.redis.EnqueueReservation(ctx, productID, payload)
+	if err != nil {
+		return false, apperrors.Wrap(apperrors.ErrUnavailable, err, "failed to queue reservation of product %d", productID)
+	}
+
+	answer, err := f.redis.AwaitReservation(ctx, productID, req.ID, f.wait+fairReservationGrace)
+	if err != nil {
+		return false, apperrors.Wrap(apperrors.ErrUnavailable, err, "failed to await reservation of product %d", productID)
+	}
+	if answer == nil {
+		util.FairReservationsTotal.WithLabelValues("timeout").Inc()
+		return false, apperrors.New(apperrors.ErrUnavailable,
+			"reservation of product %d was not served within %s from position %d in line", productID, f.wait, position)
+	}
+
+	var result fairReservationResult
+	if err := json.Unmarshal(answer, &result); err != nil {
+		return false, fmt.Errorf("failed to decode reservation result: %w", err)
+	}
+	if result.Error != "" {
+		return false, errors.New(result.Error)
+	}
+	return result.Reserved, nil
+}
+
+// Run drains the queue of every product until ctx is done. Each queue is
+// drained by one instance at a time, under a lock the others wait to take
+// over.
+func (f *FairReservations) Run(ctx context.Context) error {
+	var wg sync.WaitGroup
+	for productID := range f.products {
+		wg.Add(1)
+		go func(productID int64) {
+			defer wg.Done()
+			f.drain(ctx, productID)
+		}(productID)
+	}
+	wg.Wait()
+	return ctx.Err()
+}
+
+// drain serves a product's queue whenever this instance holds its lock
+func (f *FairReservations) drain(ctx context.Context, productID int64) {
+	name := fmt.Sprintf("reservation-queue:%d", productID)
+	for ctx.Err() == nil {
+		lock, err := f.redis.ObtainLock(ctx, name, fairReservationLockTTL)
+		if err == nil {
+			f.serve(ctx, productID, lock)
+			_ = lock.Release(context.Background())
+			continue
+		}
+		if !errors.Is(err, redisclient.ErrLockNotObtained) && ctx.Err() == nil {
+			f.logger.Warn("Failed to take over reservation queue", zap.Int64("product_id", productID), zap.Error(err))
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-time.After(fairReservationPoll):
+		}
+	}
+}
+
+// serve answers the requests in a product's queue in order until ctx is done
+// or the lock is lost
+func (f *FairReservations) serve(ctx context.Context, productID int64, lock *redisclient.Lock) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-lock.Lost():
+			f.logger.Warn("Lost reservation queue lock", zap.Int64("product_id", productID))
+			return
+		default:
+		}
+
+		payload, err := f.redis.NextReservation(ctx, productID, fairReservationPoll)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			f.logger.Warn("Failed to read reservation queue", zap.Int64("product_id", productID), zap.Error(err))
+			time.Sleep(fairReservationPoll)
+			continue
+		}
+		if payload != nil {
+			f.answer(ctx, productID, payload)
+		}
+	}
+}
+
+// answer reserves stock for a queued request, unless its requester already
+// gave up, and hands it the result
+func (f *FairReservations) answer(ctx context.Context, productID int64, payload []byte) {
+	var req fairReservation
+	if err := json.Unmarshal(payload, &req); err != nil {
+		f.logger.Error("Dropping malformed reservation request", zap.Int64("product_id", productID), zap.Error(err))
+		return
+	}
+
+	now := time.Now()
+	if now.After(time.UnixMilli(req.Deadline)) {
+		util.FairReservationsTotal.WithLabelValues("expired").Inc()
+		return
+	}
+	util.FairReservationQueueWait.Observe(now.Sub(time.UnixMilli(req.Enqueued)).Seconds())
+
+	reserveCtx, cancel := context.WithTimeout(ctx, fairReservationGrace)
+	defer cancel()
+	if req.Tenant != "" {
+		reserveCtx = tenant.WithID(reserveCtx, req.Tenant)
+	}
+	strategy := f.inventory.strategyFor(reserveCtx, req.VariantID)
+	reserved, err := f.inventory.reserveNow(reserveCtx, strategy, productID, req.OrderID, req.VariantID, req.Quantity)
+
+	result := fairReservationResult{Reserved: reserved}
+	outcome := "reserved"
+	switch {
+	case err != nil:
+		result.Error = err.Error()
+		outcome = "error"
+	case !reserved:
+		outcome = "insufficient_stock"
+	}
+	util.FairReservationsTotal.WithLabelValues(outcome).Inc()
+
+	answer, err := json.Marshal(result)
+	if err != nil {
+		f.logger.Error("Failed to encode reservation result", zap.Int64("order_id", req.OrderID), zap.Error(err))
+		return
+	}
+	if err := f.redis.CompleteReservation(ctx, productID, req.ID, answer, f.wait+fairReservationGrace); err != nil {
+		f.logger.Error("Failed to answer reservation request",
+			zap.Int64("order_id", req.OrderID),
+			zap.Int64("variant_id", req.VariantID),
+			zap.Error(err))
+	}
+}
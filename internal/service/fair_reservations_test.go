@@ -0,0 +1,87 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"order-service/internal/apperrors"
+	"order-service/internal/models"
+	"order-service/internal/redisclient"
+	"order-service/internal/store/mocks"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestFairReservations returns an inventory client queueing the
+// reservations of product 10, whose variant 11 has stock units in Redis
+func newTestFairReservations(t *testing.T, stock int, wait time.Duration) (*InventoryClient, *mocks.InventoryRepository, *redisclient.Client) {
+	t.Helper()
+
+	inventory := mocks.NewInventoryRepository(t)
+	expectVariant(inventory, 11, 10)
+	inventory.On("GetProductByID", mock.Anything, int64(10)).
+		Return(&models.Product{ID: 10, ReservationStrategy: StrategyRedisFast}, nil).Once()
+	inventory.On("ReserveStockTx", mock.Anything, int64(11), int64(models.DefaultWarehouseID), mock.Anything).Return(nil).Maybe()
+	expectWarehouses(inventory, models.DefaultWarehouseID)
+
+	redis := newTestRedis(t)
+	require.NoError(t, redis.InitInventory(context.Background(), 11, models.DefaultWarehouseID, stock, 0))
+	ic := NewInventoryClient(inventory, redis)
+	ic.SetFairReservations(NewFairReservations(redis, []int64{10}, wait))
+	return ic, inventory, redis
+}
+
+func TestFairReservationsServeFirstComeFirstServed(t *testing.T) {
+	ctx := context.Background()
+	ic, inventory, redis := newTestFairReservations(t, 2, 5*time.Second)
+	inventory.On("HoldReservation", mock.Anything, mock.Anything, int64(10), int64(11), 1).Return(true, nil).Times(3)
+	inventory.On("AllocateReservation", mock.Anything, int64(1), int64(11), int64(models.DefaultWarehouseID)).Return(nil).Once()
+	inventory.On("AllocateReservation", mock.Anything, int64(2), int64(11), int64(models.DefaultWarehouseID)).Return(nil).Once()
+	inventory.On("DeleteReservation", mock.Anything, int64(3), int64(11)).Return(nil).Once()
+
+	// Three orders line up for the last two units before the queue is drained
+	results := []chan bool{nil, make(chan bool, 1), make(chan bool, 1), make(chan bool, 1)}
+	for orderID := int64(1); orderID <= 3; orderID++ {
+		go func(orderID int64) {
+			ok, err := ic.ReserveStock(ctx, orderID, 11, 1)
+			assert.NoError(t, err)
+			results[orderID] <- ok
+		}(orderID)
+		require.Eventually(t, func() bool {
+			length, err := redis.ReservationQueueLength(ctx, 10)
+			return err == nil && length == orderID
+		}, time.Second, 5*time.Millisecond)
+	}
+
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() { _ = ic.fair.Run(runCtx) }()
+
+	assert.True(t, <-results[1])
+	assert.True(t, <-results[2])
+	assert.False(t, <-results[3], "the order that came last finds the stock gone")
+}
+
+func TestFairReservationsSkipRequestsTheirRequesterGaveUpOn(t *testing.T) {
+	ctx := context.Background()
+	ic, _, redis := newTestFairReservations(t, 5, 10*time.Millisecond)
+
+	ok, err := ic.ReserveStock(ctx, 1, 11, 1)
+	assert.False(t, ok)
+	assert.Equal(t, apperrors.ErrUnavailable.Code, apperrors.From(err).Code)
+
+	runCtx, cancel := context.WithCancel(ctx)
+	go func() { _ = ic.fair.Run(runCtx) }()
+	require.Eventually(t, func() bool {
+		length, err := redis.ReservationQueueLength(ctx, 10)
+		return err == nil && length == 0
+	}, 3*time.Second, 10*time.Millisecond)
+	cancel()
+
+	available, _, err := redis.GetInventory(ctx, 11)
+	require.NoError(t, err)
+	assert.Equal(t, 5, available, "the request that timed out holds no stock")
+}
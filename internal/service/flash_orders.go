@@ -0,0 +1,402 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"order-service/internal/apperrors"
+	"order-service/internal/models"
+	"order-service/internal/redisclient"
+	"order-service/internal/util"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// Statuses of an order accepted in flash-sale mode, polled by its token
+const (
+	FlashOrderPending = "PENDING"
+	FlashOrderPlaced  = "PLACED"
+	FlashOrderFailed  = "FAILED"
+)
+
+// flashOrderTTL is how long the status of a flash order can be polled, and
+// how long its idempotency key answers with the same token
+const flashOrderTTL = 24 * time.Hour
+
+// errFlashIneligible sends an order down the synchronous path, e.g. one of a
+// db-strict product
+var errFlashIneligible = errors.New("order is not eligible for flash-sale mode")
+
+// FlashOrder is an order accepted in flash-sale mode with its stock reserved
+// in Redis, queued to be persisted
+type FlashOrder struct {
+	Token      string             `json:"token"`
+	Request    CreateOrderRequest `json:"request"`
+	Units      []FlashUnit        `json:"units"`
+	AcceptedAt time.Time          `json:"accepted_at"`
+}
+
+// FlashUnit is stock of a variant a flash order reserved in Redis, in a
+// warehouse; warehouse 0 for untracked products
+type FlashUnit struct {
+	VariantID   int64 `json:"variant_id"`
+	WarehouseID int64 `json:"warehouse_id"`
+	Quantity    int   `json:"quantity"`
+}
+
+// FlashOrderStatus is what a flash order's token reports
+type FlashOrderStatus struct {
+	Token   string `json:"order_token"`
+	Status  string `json:"status"`
+	OrderID int64  `json:"order_id,omitempty"`
+	// Reason the order failed to be placed
+	Reason string `json:"reason,omitempty"`
+}
+
+// FlashOrderQueue queues accepted flash orders, e.g. a *broker.Producer on
+// the flash orders topic
+type FlashOrderQueue interface {
+	PublishEvent(ctx context.Context, key string, event interface{}) error
+}
+
+// FlashOrders accepts orders during spike traffic by validating and reserving
+// in Redis alone, answering with a provisional order token, and persists them
+// to Postgres from a Kafka topic
+type FlashOrders struct {
+	orders    *OrderService
+	inventory *InventoryClient
+	redis     *redisclient.Client
+	queue     FlashOrderQueue
+	logger    *zap.Logger
+}
+
+// NewFlashOrders creates the flash-sale order path
+func NewFlashOrders(orders *OrderService, inventory *InventoryClient, redis *redisclient.Client, queue FlashOrderQueue) *FlashOrders {
+	return &FlashOrders{
+		orders:    orders,
+		inventory: inventory,
+		redis:     redis,
+		queue:     queue,
+		logger:    util.GetLogger(),
+	}
+}
+
+// flashReservationKey marks a ctx persisting a flash order
+type flashReservationKey struct{}
+
+// flashReservation is the stock a flash order being persisted reserved in Redis
+type flashReservation struct {
+	units []FlashUnit
+	// adopted is set once the units became the order's reservations
+	adopted bool
+}
+
+// flashReservationFrom returns the reservation of the flash order ctx
+// persists, or nil for none
+func flashReservationFrom(ctx context.Context) *flashReservation {
+	reservation, _ := ctx.Value(flashReservationKey{}).(*flashReservation)
+	return reservation
+}
+
+// accepts reports whether req may be accepted in flash-sale mode. Internal
+// orders, which need the order right away, and orders persisting a flash
+// order are placed synchronously.
+func (f *FlashOrders) accepts(ctx context.Context, req *CreateOrderRequest) bool {
+	return f != nil && !req.Synthetic && !req.NoBackorder &&
+		stockHolder(ctx) == "" && flashReservationFrom(ctx) == nil
+}
+
+// Accept validates an order and reserves its stock in Redis, then queues it
+// to be persisted and answers with its token. Checks that need the database,
+// e.g. of the customer or wallet, run when it is persisted and fail it then.
+// Orders of db-strict products, or made while Redis is unavailable, return
+// errFlashIneligible.
+func (f *FlashOrders) Accept(ctx context.Context, req *CreateOrderRequest) (*CreateOrderResponse, error) {
+	ctx, span := util.StartSpan(ctx, "FlashOrders.Accept")
+	defer span.End()
+
+	products, variants, err := f.orders.validateOrderItems(ctx, req.Items)
+	if err != nil {
+		util.OrdersFailedTotal.WithLabelValues("invalid_items").Inc()
+		return nil, err
+	}
+	for _, item := range req.Items {
+		if f.inventory.strategyFor(ctx, item.VariantID) == StrategyDBStrict {
+			return nil, errFlashIneligible
+		}
+	}
+
+	if err := f.orders.checkConsistencyToken(ctx, req.ConsistencyToken); err != nil {
+		util.OrdersFailedTotal.WithLabelValues("stale_availability").Inc()
+		return nil, err
+	}
+	if f.orders.killSwitches != nil {
+		if err := f.orders.killSwitches.Check(ctx, req.PaymentMethod, orderedSKUs(req.Items, products, variants)); err != nil {
+			util.OrdersFailedTotal.WithLabelValues("kill_switch").Inc()
+			return nil, err
+		}
+	}
+
+	if req.IdempotencyKey == "" {
+		req.IdempotencyKey = uuid.New().String()
+	}
+	// Items cannot disappear mid-request from a validated, reserved order
+	req.AllowPartial = false
+
+	token, claimed, err := f.redis.ClaimFlashOrder(ctx, req.IdempotencyKey, uuid.New().String(), flashOrderTTL)
+	if err != nil {
+		f.logger.Warn("Failed to claim flash order, placing it synchronously", zap.Error(err))
+		return nil, errFlashIneligible
+	}
+	if !claimed {
+		f.logger.Info("Duplicate flash order request detected",
+			zap.String("idempotency_key", req.IdempotencyKey),
+			zap.String("order_token", token))
+		return f.response(ctx, token)
+	}
+
+	units, err := f.reserve(ctx, req.Items)
+	if err != nil {
+		_ = f.redis.ReleaseFlashOrderClaim(ctx, req.IdempotencyKey)
+		if apperrors.From(err).Status < 500 {
+			return nil, err
+		}
+		f.logger.Warn("Failed to reserve in Redis, placing order synchronously", zap.Error(err))
+		return nil, errFlashIneligible
+	}
+
+	order := &FlashOrder{Token: token, Request: *req, Units: units, AcceptedAt: time.Now()}
+	if err := f.setStatus(ctx, &FlashOrderStatus{Token: token, Status: FlashOrderPending}); err == nil {
+		err = f.queue.PublishEvent(ctx, token, order)
+	}
+	if err != nil {
+		f.inventory.releaseInRedis(ctx, units)
+		_ = f.redis.ReleaseFlashOrderClaim(ctx, req.IdempotencyKey)
+		_ = f.setStatus(ctx, &FlashOrderStatus{Token: token, Status: FlashOrderFailed, Reason: "not queued"})
+		return nil, apperrors.Wrap(apperrors.ErrUnavailable, err, "failed to queue order")
+	}
+
+	util.FlashOrdersTotal.WithLabelValues("accepted").Inc()
+	f.logger.Info("Flash order accepted", zap.String("order_token", token))
+	return &CreateOrderResponse{OrderToken: token, Status: FlashOrderPending}, nil
+}
+
+// reserve reserves the items in Redis, all or none. Running out of stock of
+// an item fails with ErrInsufficientStock.
+func (f *FlashOrders) reserve(ctx context.Context, items []OrderItemRequest) ([]FlashUnit, error) {
+	units := make([]FlashUnit, 0, len(items))
+	for _, item := range items {
+		warehouseID, success, err := f.inventory.reserveInRedis(ctx, item.VariantID, item.Quantity)
+		if err != nil || !success {
+			f.inventory.releaseInRedis(ctx, units)
+		}
+		if err != nil {
+			util.InventoryReservationsFailed.WithLabelValues("error").Inc()
+			return nil, err
+		}
+		if !success {
+			util.InventoryReservationsFailed.WithLabelValues("insufficient_stock").Inc()
+			util.OrdersFailedTotal.WithLabelValues("reservation_failed").Inc()
+			return nil, apperrors.New(apperrors.ErrInsufficientStock, "insufficient stock for variant %d of product %d", item.VariantID, item.ProductID)
+		}
+		units = append(units, FlashUnit{VariantID: item.VariantID, WarehouseID: warehouseID, Quantity: item.Quantity})
+	}
+	return units, nil
+}
+
+// response answers a repeated request with the flash order it made
+func (f *FlashOrders) response(ctx context.Context, token string) (*CreateOrderResponse, error) {
+	status, err := f.Status(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	return &CreateOrderResponse{OrderToken: token, OrderID: status.OrderID, Status: status.Status}, nil
+}
+
+// Persist places a queued flash order, adopting the stock it reserved in
+// Redis as its reservations. An order the business rules reject is marked
+// failed and its stock given back; infrastructure errors are returned so the
+// message is parked for a redrive.
+func (f *FlashOrders) Persist(ctx context.Context, order *FlashOrder) error {
+	ctx, span := util.StartSpan(ctx, "FlashOrders.Persist")
+	defer span.End()
+
+	if status, err := f.Status(ctx, order.Token); err == nil && status.Status != FlashOrderPending {
+		return nil
+	}
+
+	reservation := &flashReservation{units: order.Units}
+	req := order.Request
+	resp, err := f.orders.CreateOrder(context.WithValue(ctx, flashReservationKey{}, reservation), &req)
+	if err != nil && apperrors.From(err).Status >= 500 {
+		return err
+	}
+
+	if !reservation.adopted {
+		var orderID int64
+		if resp != nil {
+			orderID = resp.OrderID
+		}
+		if err := f.releaseUnadopted(ctx, orderID, order.Units); err != nil {
+			return err
+		}
+	}
+
+	status, outcome := &FlashOrderStatus{Token: order.Token, Status: FlashOrderFailed}, "failed"
+	switch {
+	case err != nil:
+		status.Reason = err.Error()
+	case resp.Status == models.OrderStatusFailed:
+		status.OrderID, status.Reason = resp.OrderID, "reservation failed"
+	default:
+		status.Status, status.OrderID, outcome = FlashOrderPlaced, resp.OrderID, "placed"
+	}
+	util.FlashOrdersTotal.WithLabelValues(outcome).Inc()
+
+	if err := f.setStatus(ctx, status); err != nil {
+		f.logger.Error("Failed to record flash order status",
+			zap.String("order_token", order.Token),
+			zap.Int64("order_id", status.OrderID),
+			zap.Error(err))
+	}
+	return nil
+}
+
+// releaseUnadopted gives back the units of a flash order that did not become
+// reservations of its order, e.g. when the order was rejected or its
+// idempotency key had placed an order already. Units of variants the order
+// holds reservations of were adopted by an earlier attempt.
+func (f *FlashOrders) releaseUnadopted(ctx context.Context, orderID int64, units []FlashUnit) error {
+	if orderID != 0 {
+		reservations, err := f.inventory.GetOrderReservations(ctx, orderID)
+		if err != nil {
+			return fmt.Errorf("failed to load reservations of order %d: %w", orderID, err)
+		}
+		reserved := make(map[int64]bool, len(reservations))
+		for _, reservation := range reservations {
+			reserved[reservation.VariantID] = true
+		}
+		var unadopted []FlashUnit
+		for _, unit := range units {
+			if !reserved[unit.VariantID] {
+				unadopted = append(unadopted, unit)
+			}
+		}
+		units = unadopted
+	}
+	f.inventory.releaseInRedis(ctx, units)
+	return nil
+}
+
+// Status returns the status of the flash order with a token
+func (f *FlashOrders) Status(ctx context.Context, token string) (*FlashOrderStatus, error) {
+	data, err := f.redis.GetFlashOrderStatus(ctx, token)
+	if err != nil {
+		return nil, apperrors.Wrap(apperrors.ErrUnavailable, err, "failed to read order token %s", token)
+	}
+	if data == nil {
+		return nil, apperrors.New(apperrors.ErrNotFound, "order token %s not found", token)
+	}
+
+	var status FlashOrderStatus
+	if err := json.Unmarshal(data, &status); err != nil {
+		return nil, fmt.Errorf("failed to decode status of order token %s: %w", token, err)
+	}
+	return &status, nil
+}
+
+// setStatus records the status of a flash order
+func (f *FlashOrders) setStatus(ctx context.Context, status *FlashOrderStatus) error {
+	data, err := json.Marshal(status)
+	if err != nil {
+		return err
+	}
+	return f.redis.SetFlashOrderStatus(ctx, status.Token, data, flashOrderTTL)
+}
+
+// reserveInRedis reserves quantity units of a variant in Redis alone, in the
+// first warehouse by the allocator's preference that still has them, and
+// returns it. Leased-quota products first take the units from this pod's
+// lease. Untracked products reserve nothing, in warehouse 0.
+func (ic *InventoryClient) reserveInRedis(ctx context.Context, variantID int64, quantity int) (int64, bool, error) {
+	strategy := ic.strategyFor(ctx, variantID)
+	util.InventoryReservationsByStrategy.WithLabelValues(strategy).Inc()
+	switch strategy {
+	case StrategyNone:
+		return 0, true, nil
+	case StrategyLeasedQuota:
+		if warehouseID, ok := ic.takeFromLease(variantID, quantity); ok {
+			return warehouseID, true, nil
+		}
+	}
+
+	candidates, err := ic.rankWarehouses(ctx, strategy, variantID, quantity)
+	if err != nil {
+		return 0, false, err
+	}
+	for _, candidate := range candidates {
+		warehouseID := candidate.Warehouse.ID
+		success, err := ic.redis.ReserveUnheldStock(ctx, variantID, warehouseID, quantity, stockHolder(ctx))
+		if err != nil {
+			return 0, false, err
+		}
+		if success {
+			ic.notifyChange(ctx, variantID)
+			return warehouseID, true, nil
+		}
+	}
+	return 0, false, nil
+}
+
+// releaseInRedis gives back units reserved in Redis alone
+func (ic *InventoryClient) releaseInRedis(ctx context.Context, units []FlashUnit) {
+	for _, unit := range units {
+		if unit.WarehouseID == 0 {
+			continue
+		}
+		if err := ic.redis.ReleaseStock(ctx, unit.VariantID, unit.WarehouseID, unit.Quantity); err != nil {
+			ic.logger.Error("Failed to release flash order stock in Redis",
+				zap.Int64("variant_id", unit.VariantID),
+				zap.Int64("warehouse_id", unit.WarehouseID),
+				zap.Int("units", unit.Quantity),
+				zap.Error(err))
+			continue
+		}
+		ic.notifyChange(ctx, unit.VariantID)
+	}
+}
+
+// AdoptReservations records the units of a flash order, reserved in Redis
+// alone, as the reservations of its order and reserves them in the database,
+// all or none
+func (ic *InventoryClient) AdoptReservations(ctx context.Context, orderID int64, units []FlashUnit) error {
+	return ic.inventory.WithTx(ctx, func(ctx context.Context) error {
+		for _, unit := range units {
+			if unit.WarehouseID == 0 {
+				continue
+			}
+			productID, err := ic.productOf(ctx, unit.VariantID)
+			if err != nil {
+				return fmt.Errorf("failed to load variant %d: %w", unit.VariantID, err)
+			}
+			held, err := ic.inventory.HoldReservation(ctx, orderID, productID, unit.VariantID, unit.Quantity)
+			if err != nil {
+				return fmt.Errorf("failed to record reservation: %w", err)
+			}
+			if !held {
+				continue
+			}
+			if err := ic.inventory.AllocateReservation(ctx, orderID, unit.VariantID, unit.WarehouseID); err != nil {
+				return fmt.Errorf("failed to record allocation: %w", err)
+			}
+			if err := ic.inventory.ReserveStockTx(ctx, unit.VariantID, unit.WarehouseID, unit.Quantity); err != nil {
+				return fmt.Errorf("failed to reserve variant %d in warehouse %d: %w", unit.VariantID, unit.WarehouseID, err)
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,136 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"order-service/internal/apperrors"
+	"order-service/internal/models"
+	"order-service/internal/redisclient"
+	"order-service/internal/store/mocks"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// queuedFlashOrders records the flash orders queued instead of publishing them
+type queuedFlashOrders []*FlashOrder
+
+func (q *queuedFlashOrders) PublishEvent(_ context.Context, _ string, event interface{}) error {
+	*q = append(*q, event.(*FlashOrder))
+	return nil
+}
+
+// newTestFlashOrders wires flash orders over a catalog of products 10 and 20,
+// whose default variants 11 and 21 have 3 and 1 units in the default warehouse
+func newTestFlashOrders(t *testing.T) (*FlashOrders, *mocks.OrderRepository, *redisclient.Client, *queuedFlashOrders) {
+	t.Helper()
+	ctx := context.Background()
+
+	inventory := mocks.NewInventoryRepository(t)
+	inventory.On("GetProductsByIDs", mock.Anything, []int64{10}).
+		Return([]models.Product{{ID: 10, Price: 1000}}, nil).Maybe()
+	inventory.On("GetProductsByIDs", mock.Anything, []int64{10, 20}).
+		Return([]models.Product{{ID: 10, Price: 1000}, {ID: 20, Price: 500}}, nil).Maybe()
+	inventory.On("GetVariantsByProductIDs", mock.Anything, mock.Anything).
+		Return([]models.ProductVariant{
+			{ID: 11, ProductID: 10, IsDefault: true},
+			{ID: 21, ProductID: 20, IsDefault: true},
+		}, nil).Maybe()
+	inventory.On("GetProductByID", mock.Anything, mock.Anything).Return(&models.Product{}, nil).Maybe()
+	expectWarehouses(inventory, models.DefaultWarehouseID)
+
+	redis := newTestRedis(t)
+	require.NoError(t, redis.InitInventory(ctx, 11, models.DefaultWarehouseID, 3, 0))
+	require.NoError(t, redis.InitInventory(ctx, 21, models.DefaultWarehouseID, 1, 0))
+
+	orders := mocks.NewOrderRepository(t)
+	ic := NewInventoryClient(inventory, redis)
+	os := &OrderService{
+		orders:          orders,
+		redis:           redis,
+		inventoryClient: ic,
+		productCache:    NewProductCache(inventory, redis, time.Minute),
+		killSwitches:    NewKillSwitches(redis),
+	}
+	queue := &queuedFlashOrders{}
+	return NewFlashOrders(os, ic, redis, queue), orders, redis, queue
+}
+
+func TestFlashOrdersAcceptReservesInRedisAndQueuesTheOrder(t *testing.T) {
+	ctx := context.Background()
+	flash, _, redis, queue := newTestFlashOrders(t)
+
+	req := &CreateOrderRequest{UserID: 1, Items: []OrderItemRequest{{ProductID: 10, Quantity: 2}}, PaymentMethod: "mock", IdempotencyKey: "k1"}
+	resp, err := flash.Accept(ctx, req)
+	require.NoError(t, err)
+	assert.NotEmpty(t, resp.OrderToken)
+	assert.Equal(t, FlashOrderPending, resp.Status)
+
+	available, reserved, err := redis.GetInventory(ctx, 11)
+	require.NoError(t, err)
+	assert.Equal(t, 1, available)
+	assert.Equal(t, 2, reserved)
+
+	require.Len(t, *queue, 1)
+	assert.Equal(t, resp.OrderToken, (*queue)[0].Token)
+	assert.Equal(t, []FlashUnit{{VariantID: 11, WarehouseID: models.DefaultWarehouseID, Quantity: 2}}, (*queue)[0].Units)
+
+	status, err := flash.Status(ctx, resp.OrderToken)
+	require.NoError(t, err)
+	assert.Equal(t, FlashOrderPending, status.Status)
+
+	again, err := flash.Accept(ctx, &CreateOrderRequest{UserID: 1, Items: []OrderItemRequest{{ProductID: 10, Quantity: 2}}, PaymentMethod: "mock", IdempotencyKey: "k1"})
+	require.NoError(t, err)
+	assert.Equal(t, resp.OrderToken, again.OrderToken)
+	assert.Len(t, *queue, 1, "a repeated request is not queued again")
+	available, _, err = redis.GetInventory(ctx, 11)
+	require.NoError(t, err)
+	assert.Equal(t, 1, available, "nor reserved again")
+}
+
+func TestFlashOrdersAcceptGivesStockBackWhenAnItemIsOutOfStock(t *testing.T) {
+	ctx := context.Background()
+	flash, _, redis, queue := newTestFlashOrders(t)
+
+	_, err := flash.Accept(ctx, &CreateOrderRequest{UserID: 1, PaymentMethod: "mock", Items: []OrderItemRequest{
+		{ProductID: 10, Quantity: 2},
+		{ProductID: 20, Quantity: 2},
+	}})
+	assert.ErrorIs(t, err, apperrors.ErrInsufficientStock)
+	assert.Empty(t, *queue)
+
+	available, reserved, err := redis.GetInventory(ctx, 11)
+	require.NoError(t, err)
+	assert.Equal(t, 3, available)
+	assert.Equal(t, 0, reserved)
+}
+
+func TestFlashOrdersPersistGivesStockBackOfRejectedOrders(t *testing.T) {
+	ctx := context.Background()
+	flash, orders, redis, queue := newTestFlashOrders(t)
+
+	resp, err := flash.Accept(ctx, &CreateOrderRequest{UserID: 1, Items: []OrderItemRequest{{ProductID: 10, Quantity: 2}}, PaymentMethod: "mock", IdempotencyKey: "k1"})
+	require.NoError(t, err)
+	require.Len(t, *queue, 1)
+
+	// The payment method is disabled before the order is persisted
+	_, err = flash.orders.killSwitches.Engage(ctx, KillSwitchPaymentMethod, "mock", "provider outage", "admin:operator")
+	require.NoError(t, err)
+	orders.On("GetOrderByIdempotencyKey", mock.Anything, "k1").Return(nil, nil).Once()
+	require.NoError(t, flash.Persist(ctx, (*queue)[0]))
+
+	status, err := flash.Status(ctx, resp.OrderToken)
+	require.NoError(t, err)
+	assert.Equal(t, FlashOrderFailed, status.Status)
+	assert.Contains(t, status.Reason, "provider outage")
+
+	available, reserved, err := redis.GetInventory(ctx, 11)
+	require.NoError(t, err)
+	assert.Equal(t, 3, available)
+	assert.Equal(t, 0, reserved)
+
+	require.NoError(t, flash.Persist(ctx, (*queue)[0]), "a redelivered order that settled is skipped")
+}
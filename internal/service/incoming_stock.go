@@ -0,0 +1,366 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"order-service/internal/apperrors"
+	"order-service/internal/broker"
+	"order-service/internal/models"
+	"order-service/internal/store"
+	"order-service/internal/util"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// IncomingStockService tracks stock expected from suppliers. Backordered items
+// are planned against it so they can be quoted a ship date, and replanned when
+// an arrival slips, shrinks or is cancelled.
+type IncomingStockService struct {
+	incoming        store.IncomingStockRepository
+	orders          store.OrderRepository
+	inventoryClient *InventoryClient
+	eventPublisher  *broker.EventPublisher
+	orderCache      *OrderCache
+	logger          *zap.Logger
+}
+
+// NewIncomingStockService creates a new incoming stock service
+func NewIncomingStockService(
+	incoming store.IncomingStockRepository,
+	orders store.OrderRepository,
+	inventoryClient *InventoryClient,
+	eventPublisher *broker.EventPublisher,
+	orderCache *OrderCache,
+) *IncomingStockService {
+	return &IncomingStockService{
+		incoming:        incoming,
+		orders:          orders,
+		inventoryClient: inventoryClient,
+		eventPublisher:  eventPublisher,
+		orderCache:      orderCache,
+		logger:          util.GetLogger(),
+	}
+}
+
+// CreateIncomingStockRequest records stock expected from a supplier
+type CreateIncomingStockRequest struct {
+	VariantID int64 `json:"variant_id"`
+	// Reference is the supplier's purchase order number
+	Reference string    `json:"reference"`
+	Quantity  int       `json:"quantity"`
+	ETA       time.Time `json:"eta"`
+}
+
+// UpdateIncomingStockRequest changes incoming stock; unset fields are kept
+type UpdateIncomingStockRequest struct {
+	Quantity *int       `json:"quantity,omitempty"`
+	ETA      *time.Time `json:"eta,omitempty"`
+	// Status CANCELLED cancels the incoming stock
+	Status string `json:"status,omitempty"`
+}
+
+// AvailabilityDate is the stock of a variant once an arrival is in
+type AvailabilityDate struct {
+	IncomingStockID int64     `json:"incoming_stock_id"`
+	Reference       string    `json:"reference"`
+	Date            time.Time `json:"date"`
+	Incoming        int       `json:"incoming"`
+	// Allocated is the quantity of backordered items shipping with this arrival
+	Allocated int `json:"allocated"`
+	// Available is how many units a new order could get by Date
+	Available int `json:"available"`
+}
+
+// VariantAvailability is the stock of a variant by date
+type VariantAvailability struct {
+	VariantID    int64 `json:"variant_id"`
+	AvailableNow int   `json:"available_now"`
+	Backordered  int   `json:"backordered"`
+	// Unplanned is the backordered quantity no incoming stock covers yet
+	Unplanned int                `json:"unplanned"`
+	Dates     []AvailabilityDate `json:"dates"`
+	// ConsistencyToken is the inventory version the availability is as of,
+	// to pass back when ordering
+	ConsistencyToken int64 `json:"consistency_token"`
+}
+
+// Create records incoming stock and plans backorders of its variant against it
+func (s *IncomingStockService) Create(ctx context.Context, req CreateIncomingStockRequest) (*models.IncomingStock, error) {
+	switch {
+	case strings.TrimSpace(req.Reference) == "":
+		return nil, apperrors.New(apperrors.ErrInvalidRequest, "reference is required")
+	case req.Quantity <= 0:
+		return nil, apperrors.New(apperrors.ErrInvalidRequest, "quantity must be positive")
+	case req.ETA.IsZero():
+		return nil, apperrors.New(apperrors.ErrInvalidRequest, "eta is required")
+	}
+	if _, err := s.inventoryClient.GetInventory(ctx, req.VariantID); err != nil {
+		return nil, err
+	}
+
+	incoming := &models.IncomingStock{
+		VariantID: req.VariantID,
+		Reference: req.Reference,
+		Quantity:  req.Quantity,
+		ETA:       req.ETA,
+	}
+	if err := s.incoming.CreateIncomingStock(ctx, incoming); err != nil {
+		return nil, fmt.Errorf("failed to create incoming stock: %w", err)
+	}
+
+	util.AdminActionsTotal.WithLabelValues("incoming_stock_create").Inc()
+	s.logger.Info("Incoming stock recorded",
+		zap.Int64("incoming_stock_id", incoming.ID),
+		zap.Int64("variant_id", incoming.VariantID),
+		zap.Int("quantity", incoming.Quantity),
+		zap.Time("eta", incoming.ETA))
+
+	if _, err := s.replan(ctx, incoming.VariantID, 0); err != nil {
+		return nil, err
+	}
+	return s.incoming.GetIncomingStock(ctx, incoming.ID)
+}
+
+// Update changes the quantity, ETA or status of open incoming stock and
+// replans the backorders of its variant
+func (s *IncomingStockService) Update(ctx context.Context, id int64, req UpdateIncomingStockRequest) (*models.IncomingStock, error) {
+	switch {
+	case req.Quantity != nil && *req.Quantity <= 0:
+		return nil, apperrors.New(apperrors.ErrInvalidRequest, "quantity must be positive")
+	case req.ETA != nil && req.ETA.IsZero():
+		return nil, apperrors.New(apperrors.ErrInvalidRequest, "eta must be set")
+	case req.Status != "" && req.Status != models.IncomingStockCancelled:
+		return nil, apperrors.New(apperrors.ErrInvalidRequest, "status can only be set to %s", models.IncomingStockCancelled)
+	}
+
+	incoming, err := s.incoming.GetIncomingStock(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if req.Quantity != nil {
+		incoming.Quantity = *req.Quantity
+	}
+	if req.ETA != nil {
+		incoming.ETA = *req.ETA
+	}
+	if req.Status != "" {
+		incoming.Status = req.Status
+	}
+
+	updated, err := s.incoming.UpdateIncomingStock(ctx, incoming)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update incoming stock: %w", err)
+	}
+	if !updated {
+		return nil, apperrors.New(apperrors.ErrIncomingStockClosed, "incoming stock %d is no longer open", id)
+	}
+
+	util.AdminActionsTotal.WithLabelValues("incoming_stock_update").Inc()
+	s.logger.Info("Incoming stock updated",
+		zap.Int64("incoming_stock_id", id),
+		zap.Int("quantity", incoming.Quantity),
+		zap.Time("eta", incoming.ETA),
+		zap.String("status", incoming.Status))
+
+	if _, err := s.replan(ctx, incoming.VariantID, 0); err != nil {
+		return nil, err
+	}
+	return s.incoming.GetIncomingStock(ctx, id)
+}
+
+// Receive adds open incoming stock to the available stock of its variant.
+// Its backordered items keep their plan until the backorder fulfiller
+// reserves the new stock for them.
+func (s *IncomingStockService) Receive(ctx context.Context, id int64) (*models.IncomingStock, error) {
+	inventory, err := s.incoming.ReceiveIncomingStock(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to receive incoming stock: %w", err)
+	}
+	if inventory == nil {
+		if _, err := s.incoming.GetIncomingStock(ctx, id); err != nil {
+			return nil, err
+		}
+		return nil, apperrors.New(apperrors.ErrIncomingStockClosed, "incoming stock %d is no longer open", id)
+	}
+	s.inventoryClient.LoadImportedStock(ctx, []models.Inventory{*inventory})
+
+	util.AdminActionsTotal.WithLabelValues("incoming_stock_receive").Inc()
+	s.logger.Info("Incoming stock received",
+		zap.Int64("incoming_stock_id", id),
+		zap.Int64("variant_id", inventory.VariantID),
+		zap.Int("available", inventory.Available))
+	return s.incoming.GetIncomingStock(ctx, id)
+}
+
+// ListOpen returns the incoming stock of a variant not yet received
+func (s *IncomingStockService) ListOpen(ctx context.Context, variantID int64) ([]models.IncomingStock, error) {
+	return s.incoming.GetOpenIncomingStock(ctx, variantID)
+}
+
+// Availability returns the stock of a variant now and as each arrival comes
+// in, after the backorders already planned against it
+func (s *IncomingStockService) Availability(ctx context.Context, variantID int64) (*VariantAvailability, error) {
+	// Read before the stock, so the token is never newer than what it covers.
+	// Without Redis there is no token, and orders are not checked against it.
+	token, err := s.inventoryClient.ConsistencyToken(ctx)
+	if err != nil {
+		s.logger.Warn("Failed to read inventory version", zap.Error(err))
+	}
+	inventory, err := s.inventoryClient.GetInventory(ctx, variantID)
+	if err != nil {
+		return nil, err
+	}
+	incoming, err := s.incoming.GetOpenIncomingStock(ctx, variantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get incoming stock: %w", err)
+	}
+	backordered, err := s.incoming.CountBackorderedUnits(ctx, variantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count backorders: %w", err)
+	}
+	availability := availabilityByDate(variantID, inventory.Available, backordered, incoming)
+	availability.ConsistencyToken = token
+	return availability, nil
+}
+
+// availabilityByDate works out availability from the stock available now,
+// the backordered quantity and the open incoming stock, earliest first.
+// Backorders not planned against any arrival take the first units left.
+func availabilityByDate(variantID int64, availableNow, backordered int, incoming []models.IncomingStock) *VariantAvailability {
+	unplanned := backordered
+	for _, in := range incoming {
+		unplanned -= in.Allocated
+	}
+	if unplanned < 0 {
+		unplanned = 0
+	}
+
+	availability := &VariantAvailability{
+		VariantID:    variantID,
+		AvailableNow: availableNow,
+		Backordered:  backordered,
+		Unplanned:    unplanned,
+		Dates:        make([]AvailabilityDate, 0, len(incoming)),
+	}
+
+	free := availableNow - unplanned
+	for _, in := range incoming {
+		free += in.Quantity - in.Allocated
+		availability.Dates = append(availability.Dates, AvailabilityDate{
+			IncomingStockID: in.ID,
+			Reference:       in.Reference,
+			Date:            in.ETA,
+			Incoming:        in.Quantity,
+			Allocated:       in.Allocated,
+			Available:       max(free, 0),
+		})
+	}
+	return availability
+}
+
+// PlanBackorders plans the backordered items of a new order against incoming
+// stock. Returns when incoming stock covers all of them, or nil if it does not.
+func (s *IncomingStockService) PlanBackorders(ctx context.Context, orderID int64, variantIDs []int64) (*time.Time, error) {
+	for _, variantID := range variantIDs {
+		if _, err := s.replan(ctx, variantID, orderID); err != nil {
+			return nil, err
+		}
+	}
+
+	items, err := s.orders.GetOrderItemsByOrderID(ctx, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get order items: %w", err)
+	}
+	return orderShipDate(items), nil
+}
+
+// replan plans the backorders of a variant and tells the orders whose ship
+// date moved, other than skipOrder. Returns the items whose plan changed.
+func (s *IncomingStockService) replan(ctx context.Context, variantID, skipOrder int64) ([]models.OrderItem, error) {
+	changed, err := s.incoming.PlanIncomingStock(ctx, variantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to plan backorders of variant %d: %w", variantID, err)
+	}
+
+	byOrder := make(map[int64][]models.OrderItem)
+	var orderIDs []int64
+	for _, item := range changed {
+		if item.OrderID == skipOrder {
+			continue
+		}
+		if byOrder[item.OrderID] == nil {
+			orderIDs = append(orderIDs, item.OrderID)
+		}
+		byOrder[item.OrderID] = append(byOrder[item.OrderID], item)
+	}
+
+	for _, orderID := range orderIDs {
+		s.orderCache.Invalidate(ctx, orderID)
+		if err := s.publishRescheduled(ctx, orderID, byOrder[orderID]); err != nil {
+			s.logger.Error("Failed to publish BackorderRescheduled event",
+				zap.Int64("order_id", orderID),
+				zap.Error(err))
+		}
+	}
+	if len(orderIDs) > 0 {
+		util.BackordersRescheduledTotal.Add(float64(len(orderIDs)))
+		s.logger.Info("Backorders replanned",
+			zap.Int64("variant_id", variantID),
+			zap.Int("orders", len(orderIDs)))
+	}
+	return changed, nil
+}
+
+// publishRescheduled announces the new plan of an order's backordered items
+func (s *IncomingStockService) publishRescheduled(ctx context.Context, orderID int64, changed []models.OrderItem) error {
+	order, err := s.orders.GetOrderByID(ctx, orderID)
+	if err != nil {
+		return err
+	}
+	items, err := s.orders.GetOrderItemsByOrderID(ctx, orderID)
+	if err != nil {
+		return err
+	}
+
+	event := &models.BackorderRescheduledEvent{
+		BaseEvent: models.BaseEvent{
+			EventID:   uuid.New().String(),
+			EventType: models.EventTypeBackorderRescheduled,
+			Timestamp: time.Now(),
+		},
+		OrderID:          order.ID,
+		UserID:           order.UserID,
+		ExpectedShipDate: orderShipDate(items),
+	}
+	for _, item := range changed {
+		event.Items = append(event.Items, models.OrderItemData{
+			ProductID: item.ProductID,
+			VariantID: item.VariantID,
+			Quantity:  item.Quantity,
+			UnitPrice: item.UnitPrice,
+		})
+	}
+	return s.eventPublisher.PublishBackorderRescheduled(ctx, event)
+}
+
+// orderShipDate returns when every backordered item of an order ships, or nil
+// if one is not covered by incoming stock
+func orderShipDate(items []models.OrderItem) *time.Time {
+	var shipDate *time.Time
+	for _, item := range items {
+		if item.FulfillmentStatus != models.FulfillmentStatusBackordered {
+			continue
+		}
+		if item.ExpectedShipDate == nil {
+			return nil
+		}
+		if shipDate == nil || item.ExpectedShipDate.After(*shipDate) {
+			shipDate = item.ExpectedShipDate
+		}
+	}
+	return shipDate
+}
@@ -0,0 +1,69 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"order-service/internal/apperrors"
+	"order-service/internal/models"
+	"order-service/internal/store/mocks"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAvailabilityByDateAccumulatesArrivals(t *testing.T) {
+	june, july := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC), time.Date(2026, 7, 1, 0, 0, 0, 0, time.UTC)
+	availability := availabilityByDate(7, 2, 5, []models.IncomingStock{
+		{ID: 1, Reference: "PO-1", Quantity: 3, Allocated: 3, ETA: june},
+		{ID: 2, Reference: "PO-2", Quantity: 10, Allocated: 1, ETA: july},
+	})
+
+	assert.Equal(t, int64(7), availability.VariantID)
+	// One backordered unit is not planned and takes a unit available now
+	assert.Equal(t, 1, availability.Unplanned)
+	require.Len(t, availability.Dates, 2)
+	assert.Equal(t, 1, availability.Dates[0].Available)
+	assert.Equal(t, june, availability.Dates[0].Date)
+	assert.Equal(t, 10, availability.Dates[1].Available)
+}
+
+func TestUpdateIncomingStockRejectsReceivedStock(t *testing.T) {
+	incoming := mocks.NewIncomingStockRepository(t)
+	incoming.On("GetIncomingStock", mock.Anything, int64(3)).
+		Return(&models.IncomingStock{ID: 3, VariantID: 7, Quantity: 10, Status: models.IncomingStockOpen}, nil).Once()
+	incoming.On("UpdateIncomingStock", mock.Anything, mock.MatchedBy(func(in *models.IncomingStock) bool {
+		return in.Quantity == 4
+	})).Return(false, nil).Once()
+
+	service := NewIncomingStockService(incoming, nil, nil, nil, nil)
+	quantity := 4
+	_, err := service.Update(context.Background(), 3, UpdateIncomingStockRequest{Quantity: &quantity})
+	assert.ErrorIs(t, err, apperrors.ErrIncomingStockClosed)
+
+	_, err = service.Update(context.Background(), 3, UpdateIncomingStockRequest{Status: models.IncomingStockReceived})
+	assert.ErrorIs(t, err, apperrors.ErrInvalidRequest)
+}
+
+func TestPlanBackordersQuotesWhenEveryItemShips(t *testing.T) {
+	june, july := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC), time.Date(2026, 7, 1, 0, 0, 0, 0, time.UTC)
+	incoming := mocks.NewIncomingStockRepository(t)
+	incoming.On("PlanIncomingStock", mock.Anything, int64(7)).
+		Return([]models.OrderItem{{ID: 1, OrderID: 5, ExpectedShipDate: &june}}, nil).Once()
+	incoming.On("PlanIncomingStock", mock.Anything, int64(8)).
+		Return([]models.OrderItem{{ID: 2, OrderID: 5, ExpectedShipDate: &july}}, nil).Once()
+
+	orders := mocks.NewOrderRepository(t)
+	orders.On("GetOrderItemsByOrderID", mock.Anything, int64(5)).Return([]models.OrderItem{
+		{ID: 1, FulfillmentStatus: models.FulfillmentStatusBackordered, ExpectedShipDate: &june},
+		{ID: 2, FulfillmentStatus: models.FulfillmentStatusBackordered, ExpectedShipDate: &july},
+		{ID: 3, FulfillmentStatus: models.FulfillmentStatusAllocated},
+	}, nil).Once()
+
+	service := NewIncomingStockService(incoming, orders, nil, nil, nil)
+	shipDate, err := service.PlanBackorders(context.Background(), 5, []int64{7, 8})
+	require.NoError(t, err)
+	assert.Equal(t, july, *shipDate)
+}
@@ -2,70 +2,653 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strconv"
+	"sync"
 	"time"
 
+	"order-service/internal/apperrors"
 	"order-service/internal/models"
 	"order-service/internal/redisclient"
 	"order-service/internal/store"
 	"order-service/internal/util"
 
 	"go.uber.org/zap"
+	"golang.org/x/sync/singleflight"
 )
 
-// InventoryClient handles inventory operations
+// Reservation strategies a product can declare in the catalog
+const (
+	// StrategyRedisFast reserves in Redis and syncs the database in the background
+	StrategyRedisFast = "redis-fast"
+	// StrategyDBStrict reserves under a database row lock before touching Redis
+	StrategyDBStrict = "db-strict"
+	// StrategyLeasedQuota reserves out of a per-pod lease of Redis stock
+	StrategyLeasedQuota = "leased-quota"
+	// StrategyNone does not track stock, e.g. for digital goods
+	StrategyNone = "none-for-digital"
+)
+
+// InventoryClient handles inventory operations on product variants,
+// dispatching each to the reservation strategy its product declares in the catalog
 type InventoryClient struct {
-	store  *store.Store
-	redis  *redisclient.Client
-	logger *zap.Logger
+	inventory   store.InventoryRepository
+	redis       *redisclient.Client
+	logger      *zap.Logger
+	hotProducts map[int64]bool
+	metrics     *ProductMetrics
+	lowStock    *LowStockMonitor
+	fair        *FairReservations
+
+	// stockReads coalesces concurrent Redis reads of a variant's warehouse
+	// stock, which every reservation of a flash-sale SKU makes
+	stockReads singleflight.Group
+
+	mu                 sync.Mutex
+	strategies         map[int64]string
+	products           map[int64]int64
+	leases             map[int64]*quotaLease
+	leaseSize          int
+	leaseTTL           time.Duration
+	allocator          WarehouseAllocator
+	warehouses         map[int64]models.Warehouse
+	warehousesLoadedAt time.Time
+}
+
+// warehouseCacheTTL is how long the warehouses are cached for allocation
+const warehouseCacheTTL = time.Minute
+
+// reservationSyncGrace is how long after a Redis reservation's database sync
+// starts or finishes the reconciler leaves the variant alone, so a sync still
+// in flight, or one that landed after the database was read, is not drift
+const reservationSyncGrace = 30 * time.Second
+
+// quotaLease is stock reserved in a warehouse in Redis by this pod and not yet handed out
+type quotaLease struct {
+	warehouseID int64
+	remaining   int
+	expires     time.Time
 }
 
 // NewInventoryClient creates a new inventory client
-func NewInventoryClient(store *store.Store, redis *redisclient.Client) *InventoryClient {
+func NewInventoryClient(inventory store.InventoryRepository, redis *redisclient.Client) *InventoryClient {
 	return &InventoryClient{
-		store:  store,
-		redis:  redis,
-		logger: util.GetLogger(),
+		inventory:   inventory,
+		redis:       redis,
+		logger:      util.GetLogger(),
+		hotProducts: make(map[int64]bool),
+		strategies:  make(map[int64]string),
+		products:    make(map[int64]int64),
+		leases:      make(map[int64]*quotaLease),
+		leaseSize:   50,
+		leaseTTL:    30 * time.Second,
+		allocator:   mostStockAllocator{},
+	}
+}
+
+// SetWarehouseAllocator sets how the warehouse each reservation is taken from
+// is picked; most-stock by default
+func (ic *InventoryClient) SetWarehouseAllocator(allocator WarehouseAllocator) {
+	ic.mu.Lock()
+	defer ic.mu.Unlock()
+	ic.allocator = allocator
+}
+
+// SetProductMetrics enables per-product metrics for allow-listed products
+func (ic *InventoryClient) SetProductMetrics(metrics *ProductMetrics) {
+	ic.metrics = metrics
+}
+
+// SetLowStockMonitor enables low-stock alerts after order commits
+func (ic *InventoryClient) SetLowStockMonitor(monitor *LowStockMonitor) {
+	ic.lowStock = monitor
+}
+
+// SetFairReservations serializes the reservations of the products fair
+// queues, first come first served
+func (ic *InventoryClient) SetFairReservations(fair *FairReservations) {
+	fair.inventory = ic
+	ic.fair = fair
+}
+
+// SetQuotaLease sets how many units a leased-quota product leases at once and
+// how long an unused lease is held
+func (ic *InventoryClient) SetQuotaLease(size int, ttl time.Duration) {
+	ic.mu.Lock()
+	defer ic.mu.Unlock()
+	ic.leaseSize = size
+	ic.leaseTTL = ttl
+}
+
+// rememberStrategies caches the reservation strategies of products
+func (ic *InventoryClient) rememberStrategies(products []models.Product) {
+	ic.mu.Lock()
+	defer ic.mu.Unlock()
+	for _, product := range products {
+		ic.strategies[product.ID] = product.ReservationStrategy
 	}
 }
 
-// ReserveStock reserves stock for a product (fast path via Redis)
-func (ic *InventoryClient) ReserveStock(ctx context.Context, productID int64, quantity int) (bool, error) {
+// rememberVariants caches which product each variant belongs to
+func (ic *InventoryClient) rememberVariants(variants []models.ProductVariant) {
+	ic.mu.Lock()
+	defer ic.mu.Unlock()
+	for _, variant := range variants {
+		ic.products[variant.ID] = variant.ProductID
+	}
+}
+
+// productOf returns the product a variant belongs to, loading it from the
+// catalog on first use
+func (ic *InventoryClient) productOf(ctx context.Context, variantID int64) (int64, error) {
+	ic.mu.Lock()
+	productID, ok := ic.products[variantID]
+	ic.mu.Unlock()
+	if ok {
+		return productID, nil
+	}
+
+	variant, err := ic.inventory.GetVariantByID(ctx, variantID)
+	if err != nil {
+		return 0, err
+	}
+	ic.rememberVariants([]models.ProductVariant{*variant})
+	return variant.ProductID, nil
+}
+
+// strategyFor returns the reservation strategy of a variant's product, loading
+// it from the catalog on first use. Unknown variants use redis-fast.
+func (ic *InventoryClient) strategyFor(ctx context.Context, variantID int64) string {
+	productID, err := ic.productOf(ctx, variantID)
+	if err != nil {
+		ic.logger.Warn("Failed to load variant, using redis-fast",
+			zap.Int64("variant_id", variantID),
+			zap.Error(err))
+		return StrategyRedisFast
+	}
+
+	ic.mu.Lock()
+	strategy, ok := ic.strategies[productID]
+	ic.mu.Unlock()
+
+	if !ok {
+		product, err := ic.inventory.GetProductByID(ctx, productID)
+		if err != nil {
+			ic.logger.Warn("Failed to load reservation strategy, using redis-fast",
+				zap.Int64("product_id", productID),
+				zap.Error(err))
+			return StrategyRedisFast
+		}
+		ic.rememberStrategies([]models.Product{*product})
+		strategy = product.ReservationStrategy
+	}
+
+	switch strategy {
+	case StrategyDBStrict, StrategyLeasedQuota, StrategyNone:
+		return strategy
+	default:
+		return StrategyRedisFast
+	}
+}
+
+// SetHotProducts sets the products whose availability changes are broadcast
+func (ic *InventoryClient) SetHotProducts(productIDs []int64) {
+	hot := make(map[int64]bool, len(productIDs))
+	for _, id := range productIDs {
+		hot[id] = true
+	}
+	ic.hotProducts = hot
+}
+
+// notifyChange advances the inventory version after the availability of a
+// variant changed, and broadcasts the new availability of a variant of a hot
+// product
+func (ic *InventoryClient) notifyChange(ctx context.Context, variantID int64) {
+	version, err := ic.redis.BumpInventoryVersion(ctx)
+	if err != nil {
+		ic.logger.Warn("Failed to advance inventory version",
+			zap.Int64("variant_id", variantID),
+			zap.Error(err))
+	}
+
+	if len(ic.hotProducts) == 0 {
+		return
+	}
+	productID, err := ic.productOf(ctx, variantID)
+	if err != nil || !ic.hotProducts[productID] {
+		return
+	}
+
+	if err := ic.redis.PublishInventoryChange(ctx, productID, variantID, version); err != nil {
+		ic.logger.Warn("Failed to publish inventory change",
+			zap.Int64("product_id", productID),
+			zap.Int64("variant_id", variantID),
+			zap.Error(err))
+	}
+}
+
+// ReserveStock reserves stock of a product variant for an order using the
+// product's reservation strategy, in the warehouse the allocator prefers of
+// those with enough stock. The hold is recorded per order first, so reserving
+// the same order and variant again succeeds without holding more stock.
+// Reservations of products with fair reservations wait their turn in the
+// product's queue.
+func (ic *InventoryClient) ReserveStock(ctx context.Context, orderID, variantID int64, quantity int) (bool, error) {
 	ctx, span := util.StartSpan(ctx, "InventoryClient.ReserveStock")
 	defer span.End()
 
-	success, err := ic.redis.ReserveStock(ctx, productID, quantity)
+	strategy := ic.strategyFor(ctx, variantID)
+	util.InventoryReservationsByStrategy.WithLabelValues(strategy).Inc()
+	if strategy == StrategyNone {
+		return true, nil
+	}
+
+	productID, err := ic.productOf(ctx, variantID)
+	if err != nil {
+		return false, fmt.Errorf("failed to load variant %d: %w", variantID, err)
+	}
+
+	if ic.fair.Queues(productID) {
+		return ic.fair.Reserve(ctx, productID, orderID, variantID, quantity)
+	}
+	return ic.reserveNow(ctx, strategy, productID, orderID, variantID, quantity)
+}
+
+// reserveNow reserves stock of a product variant for an order right away
+func (ic *InventoryClient) reserveNow(ctx context.Context, strategy string, productID, orderID, variantID int64, quantity int) (bool, error) {
+	held, err := ic.inventory.HoldReservation(ctx, orderID, productID, variantID, quantity)
+	if err != nil {
+		return false, fmt.Errorf("failed to record reservation: %w", err)
+	}
+	if !held {
+		util.InventoryReservationReplaysTotal.WithLabelValues("reserve").Inc()
+		return true, nil
+	}
+
+	warehouseID, success, err := ic.allocateStock(ctx, strategy, variantID, quantity)
+	if err == nil && success {
+		if err = ic.inventory.AllocateReservation(ctx, orderID, variantID, warehouseID); err != nil {
+			ic.returnUnits(ctx, variantID, warehouseID, quantity)
+			success, err = false, fmt.Errorf("failed to record allocation: %w", err)
+		}
+	}
+
+	if err != nil || !success {
+		if deleteErr := ic.inventory.DeleteReservation(ctx, orderID, variantID); deleteErr != nil {
+			ic.logger.Error("Failed to forget unreserved hold",
+				zap.Int64("order_id", orderID),
+				zap.Int64("variant_id", variantID),
+				zap.Error(deleteErr))
+		}
+	}
+
+	if ic.metrics.Tracks(variantID) {
+		ic.metrics.RecordReservation(variantID, quantity, success, err)
+		if available, _, redisErr := ic.redis.GetInventory(ctx, variantID); redisErr == nil {
+			ic.metrics.RecordAvailable(variantID, available)
+		}
+	}
+	return success, err
+}
+
+// allocateStock reserves quantity units of a variant in the first warehouse,
+// by the allocator's preference, that still has them, and returns it.
+// Leased-quota products first take the units from this pod's lease, in the
+// warehouse it was leased from.
+func (ic *InventoryClient) allocateStock(ctx context.Context, strategy string, variantID int64, quantity int) (int64, bool, error) {
+	if strategy == StrategyLeasedQuota {
+		if warehouseID, ok := ic.takeFromLease(variantID, quantity); ok {
+			ic.syncReservationToDB(variantID, warehouseID, quantity)
+			return warehouseID, true, nil
+		}
+	}
+
+	candidates, err := ic.rankWarehouses(ctx, strategy, variantID, quantity)
+	if err != nil {
+		return 0, false, err
+	}
+	for _, candidate := range candidates {
+		warehouseID := candidate.Warehouse.ID
+		var success bool
+		switch strategy {
+		case StrategyDBStrict:
+			success, err = ic.reserveStockStrict(ctx, variantID, warehouseID, quantity)
+		case StrategyLeasedQuota:
+			success, err = ic.reserveStockLeased(ctx, variantID, warehouseID, quantity)
+		default:
+			success, err = ic.reserveStockFast(ctx, variantID, warehouseID, quantity)
+		}
+		if err != nil {
+			return 0, false, err
+		}
+		if success {
+			return warehouseID, true, nil
+		}
+	}
+	return 0, false, nil
+}
+
+// rankWarehouses returns the active warehouses with at least quantity
+// available units of a variant, in the allocator's order of preference.
+// Stock is read from Redis, or from the database for db-strict products and
+// when Redis is unavailable.
+func (ic *InventoryClient) rankWarehouses(ctx context.Context, strategy string, variantID int64, quantity int) ([]WarehouseCandidate, error) {
+	var stock []redisclient.WarehouseStock
+	var err error
+	if strategy != StrategyDBStrict {
+		if stock, err = ic.readWarehouseStock(ctx, variantID); err != nil {
+			ic.logger.Warn("Failed to read warehouse stock from Redis, using DB",
+				zap.Int64("variant_id", variantID),
+				zap.Error(err))
+		}
+	}
+	if stock == nil {
+		inventories, err := ic.inventory.GetWarehouseStock(ctx, variantID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get warehouse stock of variant %d: %w", variantID, err)
+		}
+		for _, inv := range inventories {
+			stock = append(stock, redisclient.WarehouseStock{WarehouseID: inv.WarehouseID, Available: inv.Available, Reserved: inv.Reserved})
+		}
+	}
+
+	warehouses := ic.warehousesByID(ctx)
+	var candidates []WarehouseCandidate
+	for _, ws := range stock {
+		warehouse, ok := warehouses[ws.WarehouseID]
+		if !ok || !warehouse.Active || ws.Available < quantity {
+			continue
+		}
+		candidates = append(candidates, WarehouseCandidate{Warehouse: warehouse, Available: ws.Available})
+	}
+
+	ic.mu.Lock()
+	allocator := ic.allocator
+	ic.mu.Unlock()
+	return allocator.Rank(ctx, variantID, candidates), nil
+}
+
+// readWarehouseStock reads the stock of a variant in each warehouse from
+// Redis, sharing the read of a concurrent reservation already in flight. The
+// stock only ranks warehouses: each reservation still takes its units
+// atomically, trying the next warehouse if they are gone.
+func (ic *InventoryClient) readWarehouseStock(ctx context.Context, variantID int64) ([]redisclient.WarehouseStock, error) {
+	stock, err, shared := ic.stockReads.Do(strconv.FormatInt(variantID, 10), func() (interface{}, error) {
+		// Detached from the caller, whose cancellation must not fail the others
+		return ic.redis.GetWarehouseStock(context.WithoutCancel(ctx), variantID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if shared {
+		util.WarehouseStockReadsCoalescedTotal.Inc()
+	}
+	return stock.([]redisclient.WarehouseStock), nil
+}
+
+// warehousesByID returns the warehouses, reloading them once they are older
+// than warehouseCacheTTL. A failed reload keeps the warehouses last loaded.
+func (ic *InventoryClient) warehousesByID(ctx context.Context) map[int64]models.Warehouse {
+	ic.mu.Lock()
+	warehouses, loadedAt := ic.warehouses, ic.warehousesLoadedAt
+	ic.mu.Unlock()
+	if warehouses != nil && time.Since(loadedAt) < warehouseCacheTTL {
+		return warehouses
+	}
+
+	loaded, err := ic.inventory.GetWarehouses(ctx)
+	if err != nil {
+		ic.logger.Warn("Failed to load warehouses", zap.Error(err))
+		return warehouses
+	}
+	warehouses = make(map[int64]models.Warehouse, len(loaded))
+	for _, warehouse := range loaded {
+		warehouses[warehouse.ID] = warehouse
+	}
+
+	ic.mu.Lock()
+	ic.warehouses, ic.warehousesLoadedAt = warehouses, time.Now()
+	ic.mu.Unlock()
+	return warehouses
+}
+
+// SaveWarehouseRequest adds a warehouse or updates the one with its code
+type SaveWarehouseRequest struct {
+	Name     string          `json:"name" binding:"required"`
+	Location models.GeoPoint `json:"location"`
+	// Active is whether stock is allocated from the warehouse, true when unset
+	Active *bool `json:"active,omitempty"`
+}
+
+// ListWarehouses returns every warehouse, active or not
+func (ic *InventoryClient) ListWarehouses(ctx context.Context) ([]models.Warehouse, error) {
+	return ic.inventory.GetWarehouses(ctx)
+}
+
+// SaveWarehouse adds or updates the warehouse with a code. This pod allocates
+// with the change right away; other pods pick it up within warehouseCacheTTL.
+func (ic *InventoryClient) SaveWarehouse(ctx context.Context, code string, req SaveWarehouseRequest) (*models.Warehouse, error) {
+	warehouse := &models.Warehouse{
+		Code:      code,
+		Name:      req.Name,
+		Latitude:  req.Location.Latitude,
+		Longitude: req.Location.Longitude,
+		Active:    req.Active == nil || *req.Active,
+	}
+	if err := ic.inventory.UpsertWarehouse(ctx, warehouse); err != nil {
+		return nil, fmt.Errorf("failed to save warehouse %s: %w", code, err)
+	}
+
+	ic.mu.Lock()
+	ic.warehouses = nil
+	ic.mu.Unlock()
+	return warehouse, nil
+}
+
+// reserveStockFast reserves in Redis, leaving stock soft-held for others, and
+// syncs the database in the background, falling back to the database when
+// Redis is unavailable
+func (ic *InventoryClient) reserveStockFast(ctx context.Context, variantID, warehouseID int64, quantity int) (bool, error) {
+	ic.markReservationSync(ctx, variantID, warehouseID)
+	success, err := ic.redis.ReserveUnheldStock(ctx, variantID, warehouseID, quantity, stockHolder(ctx))
 	if err != nil {
 		ic.logger.Warn("Redis reservation failed, falling back to DB",
-			zap.Int64("product_id", productID),
+			zap.Int64("variant_id", variantID),
+			zap.Int64("warehouse_id", warehouseID),
 			zap.Error(err))
 
-		return ic.reserveStockDB(ctx, productID, quantity)
+		return ic.reserveStockDB(ctx, variantID, warehouseID, quantity)
 	}
 
 	if !success {
 		return false, nil
 	}
 
+	ic.notifyChange(ctx, variantID)
+	ic.syncReservationToDB(variantID, warehouseID, quantity)
+	return true, nil
+}
+
+// syncReservationToDB records a reservation already taken in Redis in the database
+func (ic *InventoryClient) syncReservationToDB(variantID, warehouseID int64, quantity int) {
 	go func() {
 		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 		defer cancel()
 
-		if err := ic.store.ReserveStockTx(ctx, productID, quantity); err != nil {
+		ic.markReservationSync(ctx, variantID, warehouseID)
+		if err := ic.inventory.ReserveStockTx(ctx, variantID, warehouseID, quantity); err != nil {
 			ic.logger.Error("Failed to sync reservation to DB",
-				zap.Int64("product_id", productID),
+				zap.Int64("variant_id", variantID),
+				zap.Int64("warehouse_id", warehouseID),
 				zap.Error(err))
+			return
 		}
+		ic.markReservationSync(ctx, variantID, warehouseID)
 	}()
+}
+
+// markReservationSync keeps the reconciler off a variant in a warehouse for
+// reservationSyncGrace while Redis is ahead of the database
+func (ic *InventoryClient) markReservationSync(ctx context.Context, variantID, warehouseID int64) {
+	if err := ic.redis.MarkInventorySync(ctx, variantID, warehouseID, reservationSyncGrace); err != nil {
+		ic.logger.Warn("Failed to mark reservation sync",
+			zap.Int64("variant_id", variantID),
+			zap.Int64("warehouse_id", warehouseID),
+			zap.Error(err))
+	}
+}
+
+// reserveStockStrict reserves under a database row lock so the database never
+// oversells, then mirrors the reservation into Redis
+func (ic *InventoryClient) reserveStockStrict(ctx context.Context, variantID, warehouseID int64, quantity int) (bool, error) {
+	success, err := ic.reserveStockDB(ctx, variantID, warehouseID, quantity)
+	if err != nil || !success {
+		return success, err
+	}
+
+	if ok, err := ic.redis.ReserveStock(ctx, variantID, warehouseID, quantity); err != nil || !ok {
+		ic.logger.Warn("Failed to mirror strict reservation to Redis",
+			zap.Int64("variant_id", variantID),
+			zap.Int64("warehouse_id", warehouseID),
+			zap.Bool("insufficient", err == nil),
+			zap.Error(err))
+	} else {
+		ic.notifyChange(ctx, variantID)
+	}
+	return true, nil
+}
+
+// reserveStockLeased leases a fresh block of stock from a warehouse in Redis
+// for this pod, along with the requested quantity. When there is not enough
+// stock left there for a full lease it reserves just the requested quantity
+// like redis-fast. A lease of the variant left over from another warehouse is
+// returned, so the pod leases from one warehouse per variant at a time.
+func (ic *InventoryClient) reserveStockLeased(ctx context.Context, variantID, warehouseID int64, quantity int) (bool, error) {
+	ic.mu.Lock()
+	leaseSize, leaseTTL := ic.leaseSize, ic.leaseTTL
+	ic.mu.Unlock()
 
+	ic.markReservationSync(ctx, variantID, warehouseID)
+	leased, err := ic.redis.ReserveUnheldStock(ctx, variantID, warehouseID, quantity+leaseSize, stockHolder(ctx))
+	if err != nil || !leased {
+		return ic.reserveStockFast(ctx, variantID, warehouseID, quantity)
+	}
+
+	ic.mu.Lock()
+	lease := ic.leases[variantID]
+	var stale *quotaLease
+	if lease != nil && lease.warehouseID != warehouseID {
+		stale, lease = lease, nil
+	}
+	if lease == nil {
+		lease = &quotaLease{warehouseID: warehouseID}
+		ic.leases[variantID] = lease
+	}
+	lease.remaining += leaseSize
+	lease.expires = time.Now().Add(leaseTTL)
+	ic.mu.Unlock()
+	util.InventoryQuotaLeasesTotal.Inc()
+
+	if stale != nil && stale.remaining > 0 {
+		ic.returnLease(ctx, variantID, stale)
+	}
+	ic.notifyChange(ctx, variantID)
+	ic.syncReservationToDB(variantID, warehouseID, quantity)
 	return true, nil
 }
 
-// reserveStockDB reserves stock using database transaction (fallback)
-func (ic *InventoryClient) reserveStockDB(ctx context.Context, productID int64, quantity int) (bool, error) {
-	err := ic.store.ReserveStockTx(ctx, productID, quantity)
+// takeFromLease takes quantity out of an unexpired lease, if it covers it,
+// and returns the warehouse it was leased from
+func (ic *InventoryClient) takeFromLease(variantID int64, quantity int) (int64, bool) {
+	ic.mu.Lock()
+	defer ic.mu.Unlock()
+
+	lease := ic.leases[variantID]
+	if lease == nil || lease.remaining < quantity || time.Now().After(lease.expires) {
+		return 0, false
+	}
+	lease.remaining -= quantity
+	return lease.warehouseID, true
+}
+
+// ReturnExpiredLeases gives the unused part of expired leases back to Redis
+func (ic *InventoryClient) ReturnExpiredLeases(ctx context.Context) {
+	ic.returnLeases(ctx, time.Now())
+}
+
+// ReturnLeases gives every unused lease back to Redis, e.g. on shutdown
+func (ic *InventoryClient) ReturnLeases(ctx context.Context) error {
+	if failed := ic.returnLeases(ctx, time.Time{}); failed > 0 {
+		return fmt.Errorf("failed to return %d quota lease(s)", failed)
+	}
+	return nil
+}
+
+// returnLeases returns leases that expired before cutoff, or all of them for a
+// zero cutoff, and reports how many could not be returned
+func (ic *InventoryClient) returnLeases(ctx context.Context, cutoff time.Time) int {
+	ic.mu.Lock()
+	returned := make(map[int64]*quotaLease)
+	for variantID, lease := range ic.leases {
+		if cutoff.IsZero() || cutoff.After(lease.expires) {
+			returned[variantID] = lease
+			delete(ic.leases, variantID)
+		}
+	}
+	ic.mu.Unlock()
+
+	failed := 0
+	for variantID, lease := range returned {
+		if lease.remaining == 0 {
+			continue
+		}
+		if !ic.returnLease(ctx, variantID, lease) {
+			failed++
+		}
+	}
+	return failed
+}
+
+// returnLease gives the unused part of a lease no longer in use back to the
+// warehouse it was leased from
+func (ic *InventoryClient) returnLease(ctx context.Context, variantID int64, lease *quotaLease) bool {
+	if err := ic.redis.ReleaseStock(ctx, variantID, lease.warehouseID, lease.remaining); err != nil {
+		ic.logger.Error("Failed to return quota lease",
+			zap.Int64("variant_id", variantID),
+			zap.Int64("warehouse_id", lease.warehouseID),
+			zap.Int("units", lease.remaining),
+			zap.Error(err))
+		return false
+	}
+	ic.notifyChange(ctx, variantID)
+	return true
+}
+
+// RunLeaseExpiry returns expired leases until ctx is done
+func (ic *InventoryClient) RunLeaseExpiry(ctx context.Context) error {
+	ic.mu.Lock()
+	interval := ic.leaseTTL / 2
+	ic.mu.Unlock()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			ic.ReturnExpiredLeases(ctx)
+		}
+	}
+}
+
+// reserveStockDB reserves stock in a warehouse using database transaction (fallback)
+func (ic *InventoryClient) reserveStockDB(ctx context.Context, variantID, warehouseID int64, quantity int) (bool, error) {
+	err := ic.inventory.ReserveStockTx(ctx, variantID, warehouseID, quantity)
 	if err != nil {
-		if err.Error() == "insufficient stock" {
+		if errors.Is(err, apperrors.ErrInsufficientStock) {
 			return false, nil
 		}
 		return false, err
@@ -73,64 +656,494 @@ func (ic *InventoryClient) reserveStockDB(ctx context.Context, productID int64,
 	return true, nil
 }
 
-// ReleaseStock releases reserved stock (compensation)
-func (ic *InventoryClient) ReleaseStock(ctx context.Context, productID int64, quantity int) error {
+// ReleaseStock gives an order's stock of a variant back (compensation) to the
+// warehouse it was reserved in: held units are released and already committed
+// units restocked. Releasing a reservation that is already released, or was
+// never held, does nothing.
+func (ic *InventoryClient) ReleaseStock(ctx context.Context, orderID, variantID int64) error {
 	ctx, span := util.StartSpan(ctx, "InventoryClient.ReleaseStock")
 	defer span.End()
 
-	if err := ic.redis.ReleaseStock(ctx, productID, quantity); err != nil {
-		ic.logger.Error("Failed to release stock in Redis",
-			zap.Int64("product_id", productID),
+	if ic.strategyFor(ctx, variantID) == StrategyNone {
+		return nil
+	}
+
+	reservation, err := ic.inventory.ReleaseReservation(ctx, orderID, variantID)
+	if err != nil {
+		return err
+	}
+	if reservation == nil {
+		util.InventoryReservationReplaysTotal.WithLabelValues("release").Inc()
+		return nil
+	}
+	if reservation.WarehouseID == nil {
+		return nil
+	}
+	warehouseID := *reservation.WarehouseID
+
+	store.AfterCommit(ctx, func() {
+		var err error
+		if reservation.Status == models.ReservationStatusCommitted {
+			err = ic.redis.RestockInventory(ctx, variantID, warehouseID, reservation.Quantity)
+		} else {
+			err = ic.redis.ReleaseStock(ctx, variantID, warehouseID, reservation.Quantity)
+		}
+		if err != nil {
+			ic.logger.Error("Failed to release stock in Redis",
+				zap.Int64("order_id", orderID),
+				zap.Int64("variant_id", variantID),
+				zap.Int64("warehouse_id", warehouseID),
+				zap.Error(err))
+		} else {
+			ic.notifyChange(ctx, variantID)
+		}
+	})
+	return nil
+}
+
+// ResizeReservation changes the stock an order holds of a variant to quantity:
+// units added are reserved with the product's reservation strategy in the
+// warehouse the reservation holds stock in, units dropped are given back to
+// it. Returns false when there is not enough stock there for the units added.
+// A variant the order holds no allocated reservation of, e.g. a backordered
+// one, is left alone.
+func (ic *InventoryClient) ResizeReservation(ctx context.Context, orderID, variantID int64, quantity int) (bool, error) {
+	ctx, span := util.StartSpan(ctx, "InventoryClient.ResizeReservation")
+	defer span.End()
+
+	strategy := ic.strategyFor(ctx, variantID)
+	if strategy == StrategyNone {
+		return true, nil
+	}
+
+	reservations, err := ic.inventory.GetOrderReservations(ctx, orderID)
+	if err != nil {
+		return false, fmt.Errorf("failed to load reservations: %w", err)
+	}
+	var current *models.Reservation
+	for i, reservation := range reservations {
+		if reservation.VariantID == variantID && reservation.Status == models.ReservationStatusHeld && reservation.WarehouseID != nil {
+			current = &reservations[i]
+		}
+	}
+	if current == nil {
+		return true, nil
+	}
+	warehouseID := *current.WarehouseID
+
+	if added := quantity - current.Quantity; added > 0 {
+		var success bool
+		switch strategy {
+		case StrategyDBStrict:
+			success, err = ic.reserveStockStrict(ctx, variantID, warehouseID, added)
+		default:
+			success, err = ic.reserveStockFast(ctx, variantID, warehouseID, added)
+		}
+		if err != nil || !success {
+			return success, err
+		}
+		if _, err := ic.inventory.ResizeReservation(ctx, orderID, variantID, quantity); err != nil {
+			ic.returnUnits(ctx, variantID, warehouseID, added)
+			return false, fmt.Errorf("failed to resize reservation: %w", err)
+		}
+		return true, nil
+	}
+
+	previous, err := ic.inventory.ResizeReservation(ctx, orderID, variantID, quantity)
+	if err != nil {
+		return false, fmt.Errorf("failed to resize reservation: %w", err)
+	}
+	if previous != nil && previous.Quantity > quantity {
+		if err := ic.redis.ReleaseStock(ctx, variantID, warehouseID, previous.Quantity-quantity); err != nil {
+			ic.logger.Error("Failed to release stock in Redis",
+				zap.Int64("order_id", orderID),
+				zap.Int64("variant_id", variantID),
+				zap.Int64("warehouse_id", warehouseID),
+				zap.Error(err))
+		} else {
+			ic.notifyChange(ctx, variantID)
+		}
+	}
+	return true, nil
+}
+
+// returnUnits gives back units reserved in a warehouse for no reservation,
+// e.g. when recording the reservation failed
+func (ic *InventoryClient) returnUnits(ctx context.Context, variantID, warehouseID int64, quantity int) {
+	if err := ic.inventory.ReleaseStock(ctx, variantID, warehouseID, quantity); err != nil {
+		ic.logger.Error("Failed to return unrecorded stock in DB",
+			zap.Int64("variant_id", variantID),
+			zap.Int64("warehouse_id", warehouseID),
+			zap.Int("units", quantity),
 			zap.Error(err))
 	}
+	if err := ic.redis.ReleaseStock(ctx, variantID, warehouseID, quantity); err != nil {
+		ic.logger.Error("Failed to return unrecorded stock in Redis",
+			zap.Int64("variant_id", variantID),
+			zap.Int64("warehouse_id", warehouseID),
+			zap.Int("units", quantity),
+			zap.Error(err))
+		return
+	}
+	ic.notifyChange(ctx, variantID)
+}
 
-	return ic.store.ReleaseStock(ctx, productID, quantity)
+// GetOrderReservations returns the stock reservations of an order
+func (ic *InventoryClient) GetOrderReservations(ctx context.Context, orderID int64) ([]models.Reservation, error) {
+	return ic.inventory.GetOrderReservations(ctx, orderID)
 }
 
-// CommitStock commits reserved stock (final deduction)
-func (ic *InventoryClient) CommitStock(ctx context.Context, productID int64, quantity int) error {
+// CommitStock commits reserved stock in a warehouse (final deduction)
+func (ic *InventoryClient) CommitStock(ctx context.Context, variantID, warehouseID int64, quantity int) error {
 	ctx, span := util.StartSpan(ctx, "InventoryClient.CommitStock")
 	defer span.End()
 
-	if err := ic.redis.CommitStock(ctx, productID, quantity); err != nil {
+	if ic.strategyFor(ctx, variantID) == StrategyNone {
+		return nil
+	}
+
+	if err := ic.redis.CommitStock(ctx, variantID, warehouseID, quantity); err != nil {
 		ic.logger.Error("Failed to commit stock in Redis",
-			zap.Int64("product_id", productID),
+			zap.Int64("variant_id", variantID),
+			zap.Int64("warehouse_id", warehouseID),
+			zap.Error(err))
+	}
+
+	return ic.inventory.CommitStock(ctx, variantID, warehouseID, quantity)
+}
+
+// CommitOrderStock commits the reserved stock of every order item that has not
+// been committed yet in the warehouse it was allocated from, skipping
+// backordered items which hold no stock. Each item is marked in the database
+// together with its deduction, so a commit interrupted mid-order resumes where
+// it stopped. Committed variants are then checked for low stock.
+func (ic *InventoryClient) CommitOrderStock(ctx context.Context, orderID int64, items []models.OrderItem) error {
+	ctx, span := util.StartSpan(ctx, "InventoryClient.CommitOrderStock")
+	defer span.End()
+
+	for _, item := range items {
+		if item.StockCommittedAt != nil || item.FulfillmentStatus == models.FulfillmentStatusBackordered ||
+			ic.strategyFor(ctx, item.VariantID) == StrategyNone {
+			continue
+		}
+
+		committed, err := ic.inventory.CommitOrderItemStock(ctx, item)
+		if err != nil {
+			return fmt.Errorf("failed to commit stock for variant %d of order %d: %w", item.VariantID, orderID, err)
+		}
+		if !committed || item.WarehouseID == nil {
+			continue
+		}
+
+		// Redis follows the database only once the commit is durable
+		store.AfterCommit(ctx, func() {
+			if err := ic.redis.CommitStock(ctx, item.VariantID, *item.WarehouseID, item.Quantity); err != nil {
+				ic.logger.Error("Failed to commit stock in Redis",
+					zap.Int64("order_id", orderID),
+					zap.Int64("variant_id", item.VariantID),
+					zap.Int64("warehouse_id", *item.WarehouseID),
+					zap.Error(err))
+			}
+			if ic.lowStock != nil {
+				ic.lowStock.Check(ctx, orderID, item.ProductID, item.VariantID)
+			}
+		})
+	}
+
+	return nil
+}
+
+// Restock returns committed units to available stock of a warehouse
+func (ic *InventoryClient) Restock(ctx context.Context, variantID, warehouseID int64, quantity int) error {
+	ctx, span := util.StartSpan(ctx, "InventoryClient.Restock")
+	defer span.End()
+
+	if ic.strategyFor(ctx, variantID) == StrategyNone {
+		return nil
+	}
+
+	if err := ic.redis.RestockInventory(ctx, variantID, warehouseID, quantity); err != nil {
+		ic.logger.Error("Failed to restock in Redis",
+			zap.Int64("variant_id", variantID),
+			zap.Int64("warehouse_id", warehouseID),
 			zap.Error(err))
+	} else {
+		ic.notifyChange(ctx, variantID)
+	}
+
+	return ic.inventory.RestockInventory(ctx, variantID, warehouseID, quantity)
+}
+
+// trackedInventories returns the inventory of every variant whose product
+// tracks stock in every warehouse, with the reservation strategy of each
+// variant's product
+func (ic *InventoryClient) trackedInventories(ctx context.Context) ([]models.Inventory, map[int64]string, error) {
+	products, err := ic.inventory.GetProducts(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get products: %w", err)
+	}
+	ic.rememberStrategies(products)
+
+	strategies := make(map[int64]string, len(products))
+	for _, product := range products {
+		strategies[product.ID] = product.ReservationStrategy
 	}
 
-	return ic.store.CommitStock(ctx, productID, quantity)
+	inventories, err := ic.inventory.GetInventories(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get inventory: %w", err)
+	}
+
+	tracked := make([]models.Inventory, 0, len(inventories))
+	for _, inv := range inventories {
+		ic.mu.Lock()
+		ic.products[inv.VariantID] = inv.ProductID
+		ic.mu.Unlock()
+
+		if strategies[inv.ProductID] != StrategyNone {
+			tracked = append(tracked, inv)
+		}
+	}
+	return tracked, strategies, nil
 }
 
 // SyncInventoryToRedis synchronizes database inventory to Redis
 func (ic *InventoryClient) SyncInventoryToRedis(ctx context.Context) error {
 	ic.logger.Info("Starting inventory sync to Redis")
 
-	products, err := ic.store.GetProducts(ctx)
+	inventories, _, err := ic.trackedInventories(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to get products: %w", err)
+		return err
 	}
 
-	for _, product := range products {
-		inv, err := ic.store.GetInventory(ctx, product.ID)
-		if err != nil {
-			ic.logger.Error("Failed to get inventory",
-				zap.Int64("product_id", product.ID),
+	for _, inv := range inventories {
+		if err := ic.redis.InitInventory(ctx, inv.VariantID, inv.WarehouseID, inv.Available, inv.Reserved); err != nil {
+			ic.logger.Error("Failed to init Redis inventory",
+				zap.Int64("variant_id", inv.VariantID),
+				zap.Int64("warehouse_id", inv.WarehouseID),
 				zap.Error(err))
-			continue
 		}
+	}
 
-		if err := ic.redis.InitInventory(ctx, product.ID, inv.Available, inv.Reserved); err != nil {
-			ic.logger.Error("Failed to init Redis inventory",
-				zap.Int64("product_id", product.ID),
+	ic.logger.Info("Inventory sync completed", zap.Int("count", len(inventories)))
+	return nil
+}
+
+// LoadImportedStock seeds Redis with the stock set by an inventory import,
+// overwriting it as a db-wins resync would
+func (ic *InventoryClient) LoadImportedStock(ctx context.Context, inventories []models.Inventory) {
+	var unknown []int64
+	pending := make(map[int64]bool)
+	ic.mu.Lock()
+	for _, inv := range inventories {
+		ic.products[inv.VariantID] = inv.ProductID
+		if _, ok := ic.strategies[inv.ProductID]; !ok && !pending[inv.ProductID] {
+			pending[inv.ProductID] = true
+			unknown = append(unknown, inv.ProductID)
+		}
+	}
+	ic.mu.Unlock()
+
+	if len(unknown) > 0 {
+		products, err := ic.inventory.GetProductsByIDs(ctx, unknown)
+		if err != nil {
+			ic.logger.Warn("Failed to load reservation strategies of imported products", zap.Error(err))
+		} else {
+			ic.rememberStrategies(products)
+		}
+	}
+
+	for _, inv := range inventories {
+		if ic.strategyFor(ctx, inv.VariantID) == StrategyNone {
+			continue
+		}
+		if err := ic.redis.InitInventory(ctx, inv.VariantID, inv.WarehouseID, inv.Available, inv.Reserved); err != nil {
+			ic.logger.Error("Failed to load imported stock into Redis",
+				zap.Int64("variant_id", inv.VariantID),
+				zap.Int64("warehouse_id", inv.WarehouseID),
 				zap.Error(err))
+			continue
 		}
+		ic.notifyChange(ctx, inv.VariantID)
 	}
+}
 
-	ic.logger.Info("Inventory sync completed", zap.Int("count", len(products)))
-	return nil
+// ConsistencyToken returns the current inventory version. Availability read
+// after it is at least as recent, so it is handed out with availability and
+// checked back when ordering.
+func (ic *InventoryClient) ConsistencyToken(ctx context.Context) (int64, error) {
+	return ic.redis.GetInventoryVersion(ctx)
+}
+
+// GetInventory retrieves the inventory of a product variant across warehouses
+func (ic *InventoryClient) GetInventory(ctx context.Context, variantID int64) (*models.Inventory, error) {
+	return ic.inventory.GetInventory(ctx, variantID)
+}
+
+// GetProductVariants returns the variants of the given products
+func (ic *InventoryClient) GetProductVariants(ctx context.Context, productIDs []int64) ([]models.ProductVariant, error) {
+	variants, err := ic.inventory.GetVariantsByProductIDs(ctx, productIDs)
+	if err != nil {
+		return nil, err
+	}
+	ic.rememberVariants(variants)
+	return variants, nil
+}
+
+// Inventory reconciliation strategies
+const (
+	ReconcileDBWins    = "db-wins"
+	ReconcileRedisWins = "redis-wins"
+	ReconcileAlertOnly = "alert-only"
+)
+
+// InventoryDrift is the stock of a product variant in a warehouse whose Redis
+// counts disagree with the database
+type InventoryDrift struct {
+	ProductID      int64 `json:"product_id"`
+	VariantID      int64 `json:"variant_id"`
+	WarehouseID    int64 `json:"warehouse_id"`
+	Missing        bool  `json:"missing,omitempty"`
+	DBAvailable    int   `json:"db_available"`
+	DBReserved     int   `json:"db_reserved"`
+	RedisAvailable int   `json:"redis_available"`
+	RedisReserved  int   `json:"redis_reserved"`
+}
+
+// FindInventoryDrift compares Redis inventory hashes against the database
+// without changing either. Variants with a reservation sync pending or within
+// reservationSyncGrace are skipped, as Redis is ahead of the database there.
+func (ic *InventoryClient) FindInventoryDrift(ctx context.Context) ([]InventoryDrift, error) {
+	inventories, strategies, err := ic.trackedInventories(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var drifts []InventoryDrift
+	for _, inv := range inventories {
+		drift := InventoryDrift{
+			ProductID:   inv.ProductID,
+			VariantID:   inv.VariantID,
+			WarehouseID: inv.WarehouseID,
+			DBAvailable: inv.Available,
+			DBReserved:  inv.Reserved,
+		}
+		available, reserved, err := ic.redis.GetWarehouseInventory(ctx, inv.VariantID, inv.WarehouseID)
+		if errors.Is(err, redisclient.ErrInventoryNotFound) {
+			drift.Missing = true
+			drifts = append(drifts, drift)
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read Redis inventory: %w", err)
+		}
+
+		if available == inv.Available && reserved == inv.Reserved {
+			continue
+		}
+		// Outstanding leases are reserved in Redis but not yet in the
+		// database, so leased variants only drift when the totals differ
+		if strategies[inv.ProductID] == StrategyLeasedQuota && available+reserved == inv.Available+inv.Reserved {
+			continue
+		}
+		// Checked after Redis was read, so a reservation seen there has marked it
+		syncing, err := ic.redis.InventorySyncPending(ctx, inv.VariantID, inv.WarehouseID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read inventory sync marker: %w", err)
+		}
+		if syncing {
+			continue
+		}
+
+		drift.RedisAvailable, drift.RedisReserved = available, reserved
+		drifts = append(drifts, drift)
+	}
+	return drifts, nil
 }
 
-// GetInventory retrieves inventory for a product
-func (ic *InventoryClient) GetInventory(ctx context.Context, productID int64) (*models.Inventory, error) {
-	return ic.store.GetInventory(ctx, productID)
+// ReconcileInventory compares Redis inventory hashes against the database and
+// heals drifted variants according to strategy. Returns the number of drifted variants.
+func (ic *InventoryClient) ReconcileInventory(ctx context.Context, strategy string) (int, error) {
+	ctx, span := util.StartSpan(ctx, "InventoryClient.ReconcileInventory")
+	defer span.End()
+
+	drifts, err := ic.FindInventoryDrift(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, drift := range drifts {
+		if drift.Missing {
+			ic.logger.Warn("Redis inventory missing, reseeding from DB",
+				zap.Int64("variant_id", drift.VariantID),
+				zap.Int64("warehouse_id", drift.WarehouseID))
+			util.InventoryDriftTotal.WithLabelValues("missing").Inc()
+			if strategy != ReconcileAlertOnly {
+				// Left alone if stock was synced to Redis since it was found missing
+				if _, err := ic.redis.ReseedInventory(ctx, drift.VariantID, drift.WarehouseID, drift.DBAvailable, drift.DBReserved); err != nil {
+					ic.logger.Error("Failed to reseed Redis inventory",
+						zap.Int64("variant_id", drift.VariantID),
+						zap.Int64("warehouse_id", drift.WarehouseID),
+						zap.Error(err))
+				}
+			}
+			continue
+		}
+
+		if drift.RedisAvailable != drift.DBAvailable {
+			util.InventoryDriftTotal.WithLabelValues("available").Inc()
+		}
+		if drift.RedisReserved != drift.DBReserved {
+			util.InventoryDriftTotal.WithLabelValues("reserved").Inc()
+		}
+
+		ic.logger.Warn("Inventory drift detected",
+			zap.Int64("variant_id", drift.VariantID),
+			zap.Int64("warehouse_id", drift.WarehouseID),
+			zap.Int("db_available", drift.DBAvailable),
+			zap.Int("db_reserved", drift.DBReserved),
+			zap.Int("redis_available", drift.RedisAvailable),
+			zap.Int("redis_reserved", drift.RedisReserved),
+			zap.String("strategy", strategy))
+
+		var err error
+		switch strategy {
+		case ReconcileDBWins:
+			var replaced bool
+			replaced, err = ic.redis.ReplaceInventory(ctx, drift.VariantID, drift.WarehouseID,
+				drift.RedisAvailable, drift.RedisReserved, drift.DBAvailable, drift.DBReserved)
+			if err == nil && !replaced {
+				// Stock moved in Redis since it was read, the next run looks again
+				ic.logger.Info("Redis inventory changed while reconciling, left for the next run",
+					zap.Int64("variant_id", drift.VariantID),
+					zap.Int64("warehouse_id", drift.WarehouseID))
+				continue
+			}
+		case ReconcileRedisWins:
+			var replaced bool
+			replaced, err = ic.inventory.ReplaceInventory(ctx, drift.VariantID, drift.WarehouseID,
+				drift.DBAvailable, drift.DBReserved, drift.RedisAvailable, drift.RedisReserved)
+			if err == nil && !replaced {
+				// Stock moved in the database since it was read, the next run looks again
+				ic.logger.Info("Database inventory changed while reconciling, left for the next run",
+					zap.Int64("variant_id", drift.VariantID),
+					zap.Int64("warehouse_id", drift.WarehouseID))
+				continue
+			}
+		}
+		if err != nil {
+			ic.logger.Error("Failed to heal inventory drift",
+				zap.Int64("variant_id", drift.VariantID),
+				zap.Int64("warehouse_id", drift.WarehouseID),
+				zap.String("strategy", strategy),
+				zap.Error(err))
+			continue
+		}
+		if strategy != ReconcileAlertOnly {
+			util.InventoryDriftHealedTotal.WithLabelValues(strategy).Inc()
+		}
+	}
+
+	util.InventoryDriftedProducts.Set(float64(len(drifts)))
+	return len(drifts), nil
 }
@@ -0,0 +1,380 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"order-service/internal/apperrors"
+	"order-service/internal/models"
+	"order-service/internal/redisclient"
+	"order-service/internal/store/mocks"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// expectVariant mocks the catalog lookup of a variant of productID
+func expectVariant(inventory *mocks.InventoryRepository, variantID, productID int64) {
+	inventory.On("GetVariantByID", mock.Anything, variantID).
+		Return(&models.ProductVariant{ID: variantID, ProductID: productID}, nil).Once()
+}
+
+// expectWarehouses mocks the warehouse list with the given active warehouses
+func expectWarehouses(inventory *mocks.InventoryRepository, warehouseIDs ...int64) {
+	warehouses := make([]models.Warehouse, 0, len(warehouseIDs))
+	for _, id := range warehouseIDs {
+		warehouses = append(warehouses, models.Warehouse{ID: id, Code: fmt.Sprintf("wh-%d", id), Active: true})
+	}
+	inventory.On("GetWarehouses", mock.Anything).Return(warehouses, nil).Maybe()
+}
+
+// expectDBStock mocks the database stock of a variant in the default warehouse
+func expectDBStock(inventory *mocks.InventoryRepository, variantID int64, available int) {
+	expectWarehouses(inventory, models.DefaultWarehouseID)
+	inventory.On("GetWarehouseStock", mock.Anything, variantID).
+		Return([]models.Inventory{{VariantID: variantID, WarehouseID: models.DefaultWarehouseID, Available: available}}, nil).Maybe()
+}
+
+func TestCommitOrderStockSkipsCommittedItems(t *testing.T) {
+	committedAt := time.Now()
+	items := []models.OrderItem{
+		{ID: 1, ProductID: 10, VariantID: 11, Quantity: 1, StockCommittedAt: &committedAt},
+		{ID: 2, ProductID: 20, VariantID: 21, Quantity: 2},
+		{ID: 3, ProductID: 30, VariantID: 31, Quantity: 3},
+	}
+
+	inventory := mocks.NewInventoryRepository(t)
+	inventory.On("GetVariantByID", mock.Anything, mock.Anything).Return(&models.ProductVariant{}, nil).Maybe()
+	inventory.On("GetProductByID", mock.Anything, mock.Anything).Return(&models.Product{}, nil).Maybe()
+	inventory.On("CommitOrderItemStock", mock.Anything, items[1]).Return(true, nil).Once()
+	inventory.On("CommitOrderItemStock", mock.Anything, items[2]).Return(false, nil).Once()
+
+	ic := NewInventoryClient(inventory, newTestRedis(t))
+
+	assert.NoError(t, ic.CommitOrderStock(context.Background(), 1, items))
+}
+
+func TestCommitOrderStockStopsAtFirstFailure(t *testing.T) {
+	items := []models.OrderItem{
+		{ID: 1, ProductID: 10, VariantID: 11, Quantity: 1},
+		{ID: 2, ProductID: 20, VariantID: 21, Quantity: 2},
+	}
+
+	inventory := mocks.NewInventoryRepository(t)
+	inventory.On("GetVariantByID", mock.Anything, mock.Anything).Return(&models.ProductVariant{}, nil).Maybe()
+	inventory.On("GetProductByID", mock.Anything, mock.Anything).Return(&models.Product{}, nil).Maybe()
+	inventory.On("CommitOrderItemStock", mock.Anything, items[0]).Return(false, errors.New("connection reset")).Once()
+
+	ic := NewInventoryClient(inventory, newTestRedis(t))
+
+	assert.Error(t, ic.CommitOrderStock(context.Background(), 1, items))
+}
+
+func TestReserveStockSkipsDigitalProducts(t *testing.T) {
+	inventory := mocks.NewInventoryRepository(t)
+	expectVariant(inventory, 11, 10)
+	inventory.On("GetProductByID", mock.Anything, int64(10)).
+		Return(&models.Product{ID: 10, ReservationStrategy: StrategyNone}, nil).Once()
+
+	ic := NewInventoryClient(inventory, newTestRedis(t))
+
+	ok, err := ic.ReserveStock(context.Background(), 1, 11, 3)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.NoError(t, ic.ReleaseStock(context.Background(), 1, 11))
+}
+
+func TestReserveStockStrictReportsInsufficientStockFromDB(t *testing.T) {
+	inventory := mocks.NewInventoryRepository(t)
+	expectVariant(inventory, 11, 10)
+	inventory.On("GetProductByID", mock.Anything, int64(10)).
+		Return(&models.Product{ID: 10, ReservationStrategy: StrategyDBStrict}, nil).Once()
+	inventory.On("HoldReservation", mock.Anything, int64(1), int64(10), int64(11), 3).Return(true, nil).Once()
+	inventory.On("ReserveStockTx", mock.Anything, int64(11), int64(models.DefaultWarehouseID), 3).
+		Return(apperrors.New(apperrors.ErrInsufficientStock, "variant 11")).Once()
+	inventory.On("DeleteReservation", mock.Anything, int64(1), int64(11)).Return(nil).Once()
+	expectDBStock(inventory, 11, 3)
+
+	redis := newTestRedis(t)
+	require.NoError(t, redis.InitInventory(context.Background(), 11, models.DefaultWarehouseID, 100, 0))
+	ic := NewInventoryClient(inventory, redis)
+
+	ok, err := ic.ReserveStock(context.Background(), 1, 11, 3)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	available, _, err := redis.GetInventory(context.Background(), 11)
+	require.NoError(t, err)
+	assert.Equal(t, 100, available)
+}
+
+func TestReserveStockLeasedServesFromLease(t *testing.T) {
+	ctx := context.Background()
+	inventory := mocks.NewInventoryRepository(t)
+	expectVariant(inventory, 11, 10)
+	inventory.On("GetProductByID", mock.Anything, int64(10)).
+		Return(&models.Product{ID: 10, ReservationStrategy: StrategyLeasedQuota}, nil).Once()
+	inventory.On("HoldReservation", mock.Anything, mock.Anything, int64(10), int64(11), mock.Anything).Return(true, nil).Twice()
+	inventory.On("ReserveStockTx", mock.Anything, int64(11), int64(models.DefaultWarehouseID), mock.Anything).Return(nil).Maybe()
+	inventory.On("AllocateReservation", mock.Anything, mock.Anything, int64(11), int64(models.DefaultWarehouseID)).Return(nil).Twice()
+	expectWarehouses(inventory, models.DefaultWarehouseID)
+
+	redis := newTestRedis(t)
+	require.NoError(t, redis.InitInventory(ctx, 11, models.DefaultWarehouseID, 100, 0))
+	ic := NewInventoryClient(inventory, redis)
+	ic.SetQuotaLease(5, time.Minute)
+
+	for i, quantity := range []int{2, 3} {
+		ok, err := ic.ReserveStock(ctx, int64(i+1), 11, quantity)
+		require.NoError(t, err)
+		require.True(t, ok)
+	}
+	available, reserved, err := redis.GetInventory(ctx, 11)
+	require.NoError(t, err)
+	assert.Equal(t, 93, available, "the second reservation comes out of the lease")
+	assert.Equal(t, 7, reserved)
+
+	require.NoError(t, ic.ReturnLeases(ctx))
+	available, reserved, err = redis.GetInventory(ctx, 11)
+	require.NoError(t, err)
+	assert.Equal(t, 95, available)
+	assert.Equal(t, 5, reserved)
+}
+
+func TestReserveStockDoesNotHoldTwiceForAnOrder(t *testing.T) {
+	ctx := context.Background()
+	inventory := mocks.NewInventoryRepository(t)
+	expectVariant(inventory, 11, 10)
+	inventory.On("GetProductByID", mock.Anything, int64(10)).Return(&models.Product{ID: 10}, nil).Once()
+	inventory.On("HoldReservation", mock.Anything, int64(1), int64(10), int64(11), 3).Return(false, nil).Once()
+
+	redis := newTestRedis(t)
+	require.NoError(t, redis.InitInventory(ctx, 11, models.DefaultWarehouseID, 100, 0))
+	ic := NewInventoryClient(inventory, redis)
+
+	ok, err := ic.ReserveStock(ctx, 1, 11, 3)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	available, _, err := redis.GetInventory(ctx, 11)
+	require.NoError(t, err)
+	assert.Equal(t, 100, available)
+}
+
+func TestReleaseStockReturnsEachReservationOnce(t *testing.T) {
+	ctx := context.Background()
+	inventory := mocks.NewInventoryRepository(t)
+	inventory.On("GetVariantByID", mock.Anything, mock.Anything).Return(&models.ProductVariant{}, nil).Maybe()
+	inventory.On("GetProductByID", mock.Anything, mock.Anything).Return(&models.Product{}, nil).Maybe()
+	warehouseID := int64(models.DefaultWarehouseID)
+	inventory.On("ReleaseReservation", mock.Anything, int64(1), int64(11)).
+		Return(&models.Reservation{OrderID: 1, ProductID: 10, VariantID: 11, WarehouseID: &warehouseID, Quantity: 3, Status: models.ReservationStatusHeld}, nil).Once()
+	inventory.On("ReleaseReservation", mock.Anything, int64(1), int64(11)).Return(nil, nil).Once()
+	inventory.On("ReleaseReservation", mock.Anything, int64(1), int64(21)).
+		Return(&models.Reservation{OrderID: 1, ProductID: 20, VariantID: 21, WarehouseID: &warehouseID, Quantity: 2, Status: models.ReservationStatusCommitted}, nil).Once()
+
+	redis := newTestRedis(t)
+	require.NoError(t, redis.InitInventory(ctx, 11, models.DefaultWarehouseID, 97, 3))
+	require.NoError(t, redis.InitInventory(ctx, 21, models.DefaultWarehouseID, 98, 0))
+	ic := NewInventoryClient(inventory, redis)
+
+	require.NoError(t, ic.ReleaseStock(ctx, 1, 11))
+	require.NoError(t, ic.ReleaseStock(ctx, 1, 11))
+	require.NoError(t, ic.ReleaseStock(ctx, 1, 21))
+
+	available, reserved, err := redis.GetInventory(ctx, 11)
+	require.NoError(t, err)
+	assert.Equal(t, 100, available)
+	assert.Equal(t, 0, reserved)
+	available, _, err = redis.GetInventory(ctx, 21)
+	require.NoError(t, err)
+	assert.Equal(t, 100, available)
+}
+
+func TestReserveStockLeavesStockHeldForOthers(t *testing.T) {
+	ctx := context.Background()
+	inventory := mocks.NewInventoryRepository(t)
+	expectVariant(inventory, 11, 10)
+	inventory.On("GetProductByID", mock.Anything, int64(10)).Return(&models.Product{ID: 10}, nil).Once()
+	inventory.On("HoldReservation", mock.Anything, mock.Anything, int64(10), int64(11), mock.Anything).Return(true, nil).Twice()
+	inventory.On("DeleteReservation", mock.Anything, int64(1), int64(11)).Return(nil).Once()
+	inventory.On("ReserveStockTx", mock.Anything, int64(11), int64(models.DefaultWarehouseID), 4).Return(nil).Maybe()
+	inventory.On("AllocateReservation", mock.Anything, int64(2), int64(11), int64(models.DefaultWarehouseID)).Return(nil).Once()
+	expectWarehouses(inventory, models.DefaultWarehouseID)
+
+	redis := newTestRedis(t)
+	require.NoError(t, redis.InitInventory(ctx, 11, models.DefaultWarehouseID, 5, 0))
+	ic := NewInventoryClient(inventory, redis)
+
+	held, err := ic.HoldStock(ctx, "cart-a", []OrderItemRequest{{ProductID: 10, VariantID: 11, Quantity: 4}}, time.Minute)
+	require.NoError(t, err)
+	require.True(t, held)
+
+	ok, err := ic.ReserveStock(ctx, 1, 11, 2)
+	require.NoError(t, err)
+	assert.False(t, ok, "4 of the 5 units are held for cart-a")
+
+	ok, err = ic.ReserveStock(WithStockHolder(ctx, "cart-a"), 2, 11, 4)
+	require.NoError(t, err)
+	assert.True(t, ok)
+}
+
+func TestReserveStockAllocatesFromAWarehouseWithEnoughStock(t *testing.T) {
+	ctx := context.Background()
+	inventory := mocks.NewInventoryRepository(t)
+	expectVariant(inventory, 11, 10)
+	inventory.On("GetProductByID", mock.Anything, int64(10)).Return(&models.Product{ID: 10}, nil).Once()
+	inventory.On("HoldReservation", mock.Anything, int64(1), int64(10), int64(11), 5).Return(true, nil).Once()
+	inventory.On("ReserveStockTx", mock.Anything, int64(11), int64(2), 5).Return(nil).Maybe()
+	inventory.On("AllocateReservation", mock.Anything, int64(1), int64(11), int64(2)).Return(nil).Once()
+	// Warehouse 3 is inactive, so it is not listed
+	expectWarehouses(inventory, 1, 2)
+
+	redis := newTestRedis(t)
+	require.NoError(t, redis.InitInventory(ctx, 11, 1, 2, 0))
+	require.NoError(t, redis.InitInventory(ctx, 11, 2, 8, 0))
+	require.NoError(t, redis.InitInventory(ctx, 11, 3, 50, 0))
+	ic := NewInventoryClient(inventory, redis)
+
+	ok, err := ic.ReserveStock(ctx, 1, 11, 5)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	available, reserved, err := redis.GetWarehouseInventory(ctx, 11, 2)
+	require.NoError(t, err)
+	assert.Equal(t, 3, available)
+	assert.Equal(t, 5, reserved)
+	available, _, err = redis.GetWarehouseInventory(ctx, 11, 1)
+	require.NoError(t, err)
+	assert.Equal(t, 2, available, "warehouse 1 cannot cover the whole item")
+}
+
+// expectTrackedInventory mocks a variant of a redis-fast product with 10 units
+// available in warehouse 1 according to the database
+func expectTrackedInventory(inventory *mocks.InventoryRepository) {
+	inventory.On("GetProducts", mock.Anything).
+		Return([]models.Product{{ID: 10, ReservationStrategy: StrategyRedisFast}}, nil).Once()
+	inventory.On("GetInventories", mock.Anything).
+		Return([]models.Inventory{{ProductID: 10, VariantID: 11, WarehouseID: 1, Available: 10}}, nil).Once()
+}
+
+func TestReconcileInventoryHealsDriftByStrategy(t *testing.T) {
+	tests := []struct {
+		strategy      string
+		wantAvailable int
+		wantReserved  int
+		wantDBUpdate  bool
+	}{
+		{strategy: ReconcileAlertOnly, wantAvailable: 7, wantReserved: 3},
+		{strategy: ReconcileDBWins, wantAvailable: 10, wantReserved: 0},
+		{strategy: ReconcileRedisWins, wantAvailable: 7, wantReserved: 3, wantDBUpdate: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.strategy, func(t *testing.T) {
+			ctx := context.Background()
+			inventory := mocks.NewInventoryRepository(t)
+			expectTrackedInventory(inventory)
+			if tt.wantDBUpdate {
+				inventory.On("ReplaceInventory", mock.Anything, int64(11), int64(1), 10, 0, 7, 3).Return(true, nil).Once()
+			}
+			redis := newTestRedis(t)
+			require.NoError(t, redis.InitInventory(ctx, 11, 1, 7, 3))
+
+			drifted, err := NewInventoryClient(inventory, redis).ReconcileInventory(ctx, tt.strategy)
+			require.NoError(t, err)
+			assert.Equal(t, 1, drifted)
+
+			available, reserved, err := redis.GetWarehouseInventory(ctx, 11, 1)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantAvailable, available)
+			assert.Equal(t, tt.wantReserved, reserved)
+		})
+	}
+}
+
+func TestReconcileInventorySkipsVariantsWithPendingReservationSyncs(t *testing.T) {
+	ctx := context.Background()
+	inventory := mocks.NewInventoryRepository(t)
+	expectTrackedInventory(inventory)
+	redis := newTestRedis(t)
+	require.NoError(t, redis.InitInventory(ctx, 11, 1, 7, 3))
+	require.NoError(t, redis.MarkInventorySync(ctx, 11, 1, reservationSyncGrace))
+
+	drifted, err := NewInventoryClient(inventory, redis).ReconcileInventory(ctx, ReconcileDBWins)
+	require.NoError(t, err)
+	assert.Equal(t, 0, drifted)
+
+	available, reserved, err := redis.GetWarehouseInventory(ctx, 11, 1)
+	require.NoError(t, err)
+	assert.Equal(t, 7, available, "the reservation awaiting its database sync is kept")
+	assert.Equal(t, 3, reserved)
+}
+
+func TestReconcileInventoryRedisWinsLeavesChangedRowsAlone(t *testing.T) {
+	ctx := context.Background()
+	inventory := mocks.NewInventoryRepository(t)
+	expectTrackedInventory(inventory)
+	inventory.On("ReplaceInventory", mock.Anything, int64(11), int64(1), 10, 0, 7, 3).Return(false, nil).Once()
+	redis := newTestRedis(t)
+	require.NoError(t, redis.InitInventory(ctx, 11, 1, 7, 3))
+
+	drifted, err := NewInventoryClient(inventory, redis).ReconcileInventory(ctx, ReconcileRedisWins)
+	require.NoError(t, err)
+	assert.Equal(t, 1, drifted)
+}
+
+func TestReconcileInventoryReseedsMissingInventory(t *testing.T) {
+	tests := []struct {
+		strategy   string
+		wantSeeded bool
+	}{
+		{strategy: ReconcileAlertOnly},
+		{strategy: ReconcileDBWins, wantSeeded: true},
+		{strategy: ReconcileRedisWins, wantSeeded: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.strategy, func(t *testing.T) {
+			ctx := context.Background()
+			inventory := mocks.NewInventoryRepository(t)
+			expectTrackedInventory(inventory)
+			redis := newTestRedis(t)
+
+			drifted, err := NewInventoryClient(inventory, redis).ReconcileInventory(ctx, tt.strategy)
+			require.NoError(t, err)
+			assert.Equal(t, 1, drifted)
+
+			available, _, err := redis.GetWarehouseInventory(ctx, 11, 1)
+			if !tt.wantSeeded {
+				assert.ErrorIs(t, err, redisclient.ErrInventoryNotFound)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, 10, available)
+		})
+	}
+}
+
+func TestReconcileInventoryDoesNotReseedWhenRedisFails(t *testing.T) {
+	ctx := context.Background()
+	inventory := mocks.NewInventoryRepository(t)
+	expectTrackedInventory(inventory)
+	server := miniredis.RunT(t)
+	redis, err := redisclient.NewClient(server.Addr(), "", 0)
+	require.NoError(t, err)
+	require.NoError(t, redis.InitInventory(ctx, 11, 1, 7, 3))
+
+	server.SetError("ERR server unavailable")
+	_, err = NewInventoryClient(inventory, redis).ReconcileInventory(ctx, ReconcileDBWins)
+	assert.Error(t, err)
+
+	server.SetError("")
+	available, reserved, err := redis.GetWarehouseInventory(ctx, 11, 1)
+	require.NoError(t, err)
+	assert.Equal(t, 7, available, "live counters are left alone")
+	assert.Equal(t, 3, reserved)
+}
@@ -0,0 +1,367 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"order-service/internal/apperrors"
+	"order-service/internal/models"
+	"order-service/internal/store"
+	"order-service/internal/util"
+
+	"go.uber.org/zap"
+)
+
+// Inventory import formats
+const (
+	ImportFormatCSV    = "csv"
+	ImportFormatNDJSON = "ndjson"
+)
+
+// maxImportErrors caps the row errors listed in an import report
+const maxImportErrors = 1000
+
+// ImportRowError reports why a row of an inventory import was not imported
+type ImportRowError struct {
+	Line  int    `json:"line"`
+	SKU   string `json:"sku,omitempty"`
+	Error string `json:"error"`
+}
+
+// InventoryImportReport summarizes an inventory import
+type InventoryImportReport struct {
+	Rows     int              `json:"rows"`
+	Imported int              `json:"imported"`
+	Failed   int              `json:"failed"`
+	Errors   []ImportRowError `json:"errors"`
+	// ErrorsTruncated is set when more rows failed than are listed in Errors
+	ErrorsTruncated bool `json:"errors_truncated,omitempty"`
+}
+
+// fail records a row that was not imported
+func (r *InventoryImportReport) fail(line int, sku string, err error) {
+	r.Failed++
+	util.InventoryImportRowsTotal.WithLabelValues("failed").Inc()
+	if len(r.Errors) >= maxImportErrors {
+		r.ErrorsTruncated = true
+		return
+	}
+	r.Errors = append(r.Errors, ImportRowError{Line: line, SKU: sku, Error: err.Error()})
+}
+
+// InventoryImporter bulk loads products, variants and stock from a CSV or
+// NDJSON stream. Rows are read one at a time and upserted in batched
+// transactions, so catalogs of any size are imported in bounded memory.
+type InventoryImporter struct {
+	inventory    store.InventoryRepository
+	client       *InventoryClient
+	productCache *ProductCache
+	batchSize    int
+	logger       *zap.Logger
+}
+
+// NewInventoryImporter creates a new inventory importer
+func NewInventoryImporter(inventory store.InventoryRepository, client *InventoryClient, productCache *ProductCache, batchSize int) *InventoryImporter {
+	return &InventoryImporter{
+		inventory:    inventory,
+		client:       client,
+		productCache: productCache,
+		batchSize:    batchSize,
+		logger:       util.GetLogger(),
+	}
+}
+
+// Import reads rows in format from r, upserts the valid ones and reports the
+// others. Batches imported before a read or database error are kept.
+func (im *InventoryImporter) Import(ctx context.Context, format string, r io.Reader) (*InventoryImportReport, error) {
+	ctx, span := util.StartSpan(ctx, "InventoryImporter.Import")
+	defer span.End()
+
+	var rows importRowReader
+	switch format {
+	case ImportFormatCSV:
+		csvRows, err := newCSVImportReader(r)
+		if err != nil {
+			return nil, err
+		}
+		rows = csvRows
+	case ImportFormatNDJSON:
+		rows = newNDJSONImportReader(r)
+	default:
+		return nil, apperrors.New(apperrors.ErrInvalidRequest, "unknown import format %q", format)
+	}
+
+	report := &InventoryImportReport{Errors: []ImportRowError{}}
+	batch := make([]models.InventoryImportRow, 0, im.batchSize)
+	for {
+		row, err := rows.next()
+		if err == io.EOF {
+			break
+		}
+
+		var invalid *invalidRowError
+		switch {
+		case errors.As(err, &invalid):
+			report.Rows++
+			report.fail(invalid.line, invalid.sku, invalid.err)
+			continue
+		case err != nil:
+			return nil, apperrors.Wrap(apperrors.ErrInvalidRequest, err,
+				"import stopped after %d rows, %d imported", report.Rows, report.Imported)
+		}
+
+		report.Rows++
+		if err := validateImportRow(row); err != nil {
+			report.fail(row.Line, row.SKU, err)
+			continue
+		}
+
+		batch = append(batch, row)
+		if len(batch) == im.batchSize {
+			if err := im.importBatch(ctx, batch, report); err != nil {
+				return nil, err
+			}
+			batch = batch[:0]
+		}
+	}
+
+	if len(batch) > 0 {
+		if err := im.importBatch(ctx, batch, report); err != nil {
+			return nil, err
+		}
+	}
+
+	// Database errors of a batch are reported after the parse errors read
+	// while it filled
+	sort.SliceStable(report.Errors, func(i, j int) bool {
+		return report.Errors[i].Line < report.Errors[j].Line
+	})
+
+	util.AdminActionsTotal.WithLabelValues("inventory_import").Inc()
+	im.logger.Info("Inventory import completed",
+		zap.Int("rows", report.Rows),
+		zap.Int("imported", report.Imported),
+		zap.Int("failed", report.Failed))
+	return report, nil
+}
+
+// importBatch upserts a batch in one transaction and loads its stock into Redis
+func (im *InventoryImporter) importBatch(ctx context.Context, batch []models.InventoryImportRow, report *InventoryImportReport) error {
+	inventories, rowErrs, err := im.inventory.ImportInventoryRows(ctx, batch)
+	if err != nil {
+		return fmt.Errorf("import stopped after %d rows, %d imported: %w", report.Rows, report.Imported, err)
+	}
+
+	for i, rowErr := range rowErrs {
+		if rowErr != nil {
+			report.fail(batch[i].Line, batch[i].SKU, rowErr)
+		}
+	}
+	report.Imported += len(inventories)
+	util.InventoryImportRowsTotal.WithLabelValues("imported").Add(float64(len(inventories)))
+
+	invalidated := make(map[int64]bool)
+	for _, inv := range inventories {
+		if !invalidated[inv.ProductID] {
+			invalidated[inv.ProductID] = true
+			im.productCache.Invalidate(ctx, inv.ProductID)
+		}
+	}
+	im.client.LoadImportedStock(ctx, inventories)
+	return nil
+}
+
+// validateImportRow checks a parsed row before it reaches the database
+func validateImportRow(row models.InventoryImportRow) error {
+	switch {
+	case strings.TrimSpace(row.SKU) == "":
+		return errors.New("sku is required")
+	case strings.TrimSpace(row.Name) == "":
+		return errors.New("name is required")
+	case row.Price < 0:
+		return errors.New("price must not be negative")
+	case row.Available < 0:
+		return errors.New("available must not be negative")
+	}
+
+	if len(row.Attributes) > 0 {
+		var attributes map[string]interface{}
+		if err := json.Unmarshal(row.Attributes, &attributes); err != nil {
+			return errors.New("attributes must be a JSON object")
+		}
+	}
+	return nil
+}
+
+// invalidRowError is a row that could not be parsed; reading continues after it
+type invalidRowError struct {
+	line int
+	sku  string
+	err  error
+}
+
+func (e *invalidRowError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.line, e.err)
+}
+
+// importRowReader reads an import stream one row at a time, returning io.EOF
+// at its end
+type importRowReader interface {
+	next() (models.InventoryImportRow, error)
+}
+
+// csvImportColumns are the columns of a CSV import, with whether each is required
+var csvImportColumns = map[string]bool{
+	"sku":         true,
+	"name":        true,
+	"price":       true,
+	"available":   true,
+	"variant_sku": false,
+	"attributes":  false,
+	"warehouse":   false,
+}
+
+// csvImportReader reads rows from CSV with a header line naming the columns
+type csvImportReader struct {
+	r       *csv.Reader
+	columns map[string]int
+}
+
+func newCSVImportReader(r io.Reader) (*csvImportReader, error) {
+	reader := csv.NewReader(r)
+	reader.ReuseRecord = true
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, apperrors.New(apperrors.ErrInvalidRequest, "CSV import is empty")
+	}
+	if err != nil {
+		return nil, apperrors.Wrap(apperrors.ErrInvalidRequest, err, "invalid CSV header")
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if _, known := csvImportColumns[name]; !known {
+			return nil, apperrors.New(apperrors.ErrInvalidRequest, "unknown CSV column %q", name)
+		}
+		columns[name] = i
+	}
+	for name, required := range csvImportColumns {
+		if _, ok := columns[name]; required && !ok {
+			return nil, apperrors.New(apperrors.ErrInvalidRequest, "CSV column %q is required", name)
+		}
+	}
+
+	return &csvImportReader{r: reader, columns: columns}, nil
+}
+
+func (cr *csvImportReader) next() (models.InventoryImportRow, error) {
+	record, err := cr.r.Read()
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		return models.InventoryImportRow{}, &invalidRowError{line: parseErr.StartLine, err: parseErr.Err}
+	}
+	if err != nil {
+		return models.InventoryImportRow{}, err
+	}
+
+	line, _ := cr.r.FieldPos(0)
+	field := func(name string) string {
+		if i, ok := cr.columns[name]; ok {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	row := models.InventoryImportRow{
+		Line:       line,
+		SKU:        field("sku"),
+		Name:       field("name"),
+		VariantSKU: field("variant_sku"),
+		Warehouse:  field("warehouse"),
+	}
+	if attributes := field("attributes"); attributes != "" {
+		row.Attributes = json.RawMessage(attributes)
+	}
+
+	if row.Price, err = strconv.ParseInt(field("price"), 10, 64); err != nil {
+		return row, &invalidRowError{line: line, sku: row.SKU, err: fmt.Errorf("price: %q is not an integer", field("price"))}
+	}
+	if row.Available, err = strconv.Atoi(field("available")); err != nil {
+		return row, &invalidRowError{line: line, sku: row.SKU, err: fmt.Errorf("available: %q is not an integer", field("available"))}
+	}
+	return row, nil
+}
+
+// ndjsonImportRow is a row of an NDJSON import; numbers are pointers so a
+// missing field is told apart from zero
+type ndjsonImportRow struct {
+	SKU        string          `json:"sku"`
+	Name       string          `json:"name"`
+	Price      *int64          `json:"price"`
+	VariantSKU string          `json:"variant_sku"`
+	Attributes json.RawMessage `json:"attributes"`
+	Warehouse  string          `json:"warehouse"`
+	Available  *int            `json:"available"`
+}
+
+// ndjsonImportReader reads rows from newline-delimited JSON objects
+type ndjsonImportReader struct {
+	scanner *bufio.Scanner
+	line    int
+}
+
+func newNDJSONImportReader(r io.Reader) *ndjsonImportReader {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	return &ndjsonImportReader{scanner: scanner}
+}
+
+func (nr *ndjsonImportReader) next() (models.InventoryImportRow, error) {
+	for nr.scanner.Scan() {
+		nr.line++
+		data := strings.TrimSpace(nr.scanner.Text())
+		if data == "" {
+			continue
+		}
+
+		var raw ndjsonImportRow
+		if err := json.Unmarshal([]byte(data), &raw); err != nil {
+			return models.InventoryImportRow{}, &invalidRowError{line: nr.line, err: fmt.Errorf("invalid JSON: %v", err)}
+		}
+
+		row := models.InventoryImportRow{
+			Line:       nr.line,
+			SKU:        raw.SKU,
+			Name:       raw.Name,
+			VariantSKU: raw.VariantSKU,
+			Warehouse:  raw.Warehouse,
+		}
+		if string(raw.Attributes) != "null" {
+			row.Attributes = raw.Attributes
+		}
+		switch {
+		case raw.Price == nil:
+			return row, &invalidRowError{line: nr.line, sku: raw.SKU, err: errors.New("price is required")}
+		case raw.Available == nil:
+			return row, &invalidRowError{line: nr.line, sku: raw.SKU, err: errors.New("available is required")}
+		}
+		row.Price, row.Available = *raw.Price, *raw.Available
+		return row, nil
+	}
+
+	if err := nr.scanner.Err(); err != nil {
+		return models.InventoryImportRow{}, err
+	}
+	return models.InventoryImportRow{}, io.EOF
+}
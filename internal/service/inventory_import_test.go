@@ -0,0 +1,85 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"order-service/internal/apperrors"
+	"order-service/internal/models"
+	"order-service/internal/store/mocks"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestImporter(t *testing.T, inventory *mocks.InventoryRepository, batchSize int) *InventoryImporter {
+	redis := newTestRedis(t)
+	return NewInventoryImporter(inventory, NewInventoryClient(inventory, redis),
+		NewProductCache(inventory, redis, time.Minute), batchSize)
+}
+
+func TestImportCSVBatchesRowsAndReportsErrors(t *testing.T) {
+	inventory := mocks.NewInventoryRepository(t)
+	inventory.On("ImportInventoryRows", mock.Anything, mock.MatchedBy(func(rows []models.InventoryImportRow) bool {
+		return len(rows) == 2 && rows[0].SKU == "TEE" && rows[1].VariantSKU == "TEE-XL" && string(rows[1].Attributes) == `{"size":"XL"}`
+	})).Return([]models.Inventory{{ProductID: 1, VariantID: 11, WarehouseID: models.DefaultWarehouseID, Available: 10}}, []error{nil, errors.New("SKU TEE-XL belongs to another product")}, nil).Once()
+	inventory.On("ImportInventoryRows", mock.Anything, mock.MatchedBy(func(rows []models.InventoryImportRow) bool {
+		return len(rows) == 1 && rows[0].SKU == "MUG" && rows[0].Line == 6
+	})).Return([]models.Inventory{{ProductID: 2, VariantID: 21, WarehouseID: models.DefaultWarehouseID, Available: 3}}, []error{nil}, nil).Once()
+	inventory.On("GetProductsByIDs", mock.Anything, mock.Anything).
+		Return([]models.Product{{ID: 1}, {ID: 2}}, nil)
+
+	csv := "sku,name,price,available,variant_sku,attributes\n" +
+		"TEE,T-shirt,1500,10,,\n" +
+		"TEE,T-shirt,1500,4,TEE-XL,\"{\"\"size\"\":\"\"XL\"\"}\"\n" +
+		"CAP,Cap,abc,1,,\n" +
+		",Nameless,100,1,,\n" +
+		"MUG,Mug,800,3,,\n"
+
+	report, err := newTestImporter(t, inventory, 2).Import(context.Background(), ImportFormatCSV, strings.NewReader(csv))
+	require.NoError(t, err)
+	assert.Equal(t, 5, report.Rows)
+	assert.Equal(t, 2, report.Imported)
+	assert.Equal(t, 3, report.Failed)
+	require.Len(t, report.Errors, 3)
+	assert.Equal(t, ImportRowError{Line: 3, SKU: "TEE", Error: "SKU TEE-XL belongs to another product"}, report.Errors[0])
+	assert.Equal(t, ImportRowError{Line: 4, SKU: "CAP", Error: `price: "abc" is not an integer`}, report.Errors[1])
+	assert.Equal(t, 5, report.Errors[2].Line)
+}
+
+func TestImportNDJSONRequiresStockAndPrice(t *testing.T) {
+	inventory := mocks.NewInventoryRepository(t)
+	inventory.On("ImportInventoryRows", mock.Anything, []models.InventoryImportRow{
+		{Line: 1, SKU: "TEE", Name: "T-shirt", Price: 1500, Available: 0},
+	}).Return([]models.Inventory{{ProductID: 1, VariantID: 11}}, []error{nil}, nil).Once()
+	inventory.On("GetProductsByIDs", mock.Anything, []int64{1}).
+		Return([]models.Product{{ID: 1}}, nil).Once()
+
+	ndjson := `{"sku":"TEE","name":"T-shirt","price":1500,"available":0}` + "\n\n" +
+		`{"sku":"CAP","name":"Cap","price":900}` + "\n" +
+		`{"sku":` + "\n" +
+		`{"sku":"MUG","name":"Mug","price":800,"available":2,"attributes":["red"]}` + "\n"
+
+	report, err := newTestImporter(t, inventory, 100).Import(context.Background(), ImportFormatNDJSON, strings.NewReader(ndjson))
+	require.NoError(t, err)
+	assert.Equal(t, 4, report.Rows)
+	assert.Equal(t, 1, report.Imported)
+	require.Len(t, report.Errors, 3)
+	assert.Equal(t, ImportRowError{Line: 3, SKU: "CAP", Error: "available is required"}, report.Errors[0])
+	assert.Equal(t, 4, report.Errors[1].Line)
+	assert.Equal(t, "attributes must be a JSON object", report.Errors[2].Error)
+}
+
+func TestImportRejectsUnknownCSVColumns(t *testing.T) {
+	importer := newTestImporter(t, mocks.NewInventoryRepository(t), 100)
+
+	_, err := importer.Import(context.Background(), ImportFormatCSV, strings.NewReader("sku,name,price,stock\n"))
+	assert.ErrorIs(t, err, apperrors.ErrInvalidRequest)
+
+	_, err = importer.Import(context.Background(), ImportFormatCSV, strings.NewReader("sku,name,price\n"))
+	assert.ErrorIs(t, err, apperrors.ErrInvalidRequest)
+}
@@ -0,0 +1,77 @@
+package service
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-pdf/fpdf"
+)
+
+const (
+	// invoiceMargin is the page margin, in mm
+	invoiceMargin = 20.0
+	// invoiceColumnWidth is the width of every column of a tabbed line but
+	// the first, which takes the rest of the page
+	invoiceColumnWidth = 32.0
+	// invoiceLineHeight is the height of a line of body text
+	invoiceLineHeight = 6.0
+)
+
+// renderInvoicePDF typesets the text of a rendered invoice template on A4
+// pages. See DefaultInvoiceTemplate for the markup.
+func renderInvoicePDF(text, title, author string, created time.Time) ([]byte, error) {
+	pdf := fpdf.New("P", "mm", "A4", "")
+	pdf.SetMargins(invoiceMargin, invoiceMargin, invoiceMargin)
+	pdf.SetAutoPageBreak(true, invoiceMargin)
+	pdf.SetTitle(title, true)
+	pdf.SetAuthor(author, true)
+	pdf.SetCreationDate(created)
+	pdf.AddPage()
+
+	// The core fonts are encoded in cp1252
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+	pageWidth, _ := pdf.GetPageSize()
+	width := pageWidth - 2*invoiceMargin
+
+	for _, line := range strings.Split(strings.TrimRight(text, "\n"), "\n") {
+		if line == "---" {
+			y := pdf.GetY() + invoiceLineHeight/2
+			pdf.Line(invoiceMargin, y, invoiceMargin+width, y)
+			pdf.Ln(invoiceLineHeight)
+			continue
+		}
+		if rest, ok := strings.CutPrefix(line, "# "); ok {
+			pdf.SetFont("Helvetica", "B", 18)
+			pdf.CellFormat(0, 12, tr(rest), "", 1, "L", false, 0, "")
+			continue
+		}
+
+		style := ""
+		if rest, ok := strings.CutPrefix(line, "## "); ok {
+			style, line = "B", rest
+		}
+		pdf.SetFont("Helvetica", style, 10)
+
+		columns := strings.Split(line, "\t")
+		if len(columns) == 1 {
+			pdf.MultiCell(0, invoiceLineHeight, tr(line), "", "L", false)
+			continue
+		}
+		for i, column := range columns {
+			w, align := invoiceColumnWidth, "R"
+			if i == 0 {
+				w, align = width-invoiceColumnWidth*float64(len(columns)-1), "L"
+			}
+			pdf.CellFormat(w, invoiceLineHeight, tr(column), "", 0, align, false, 0, "")
+		}
+		pdf.Ln(invoiceLineHeight)
+	}
+
+	var out bytes.Buffer
+	if err := pdf.Output(&out); err != nil {
+		return nil, fmt.Errorf("failed to render invoice PDF: %w", err)
+	}
+	return out.Bytes(), nil
+}
@@ -0,0 +1,321 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"text/template"
+	"time"
+
+	"order-service/internal/apperrors"
+	"order-service/internal/models"
+	"order-service/internal/store"
+	"order-service/internal/tenant"
+	"order-service/internal/util"
+
+	"go.uber.org/zap"
+)
+
+// DefaultInvoiceTemplate lays out an invoice in the markup renderInvoicePDF
+// understands: "# " starts a title, "## " a bold line, "---" draws a rule and
+// tabs split a line into columns, the first left aligned and the rest right
+// aligned.
+const DefaultInvoiceTemplate = `# Invoice {{.Number}}
+{{.Issuer}}
+
+Date: {{date .IssuedAt}}
+Order: #{{.OrderID}}
+Billed to: {{if .CustomerName}}{{.CustomerName}}{{else}}Customer #{{.UserID}}{{end}}{{if .CustomerEmail}} <{{.CustomerEmail}}>{{end}}
+{{if .BillingCompany}}Company: {{.BillingCompany}}{{if .BillingCountry}}, {{.BillingCountry}}{{end}}
+{{end}}{{if .TaxID}}Tax ID: {{.TaxID}}
+{{end}}
+---
+## Item	Quantity	Unit price	Amount
+{{range .Lines}}{{.Description}}	{{.Quantity}}	{{amount .UnitPrice}}	{{amount .Amount}}
+{{end}}---
+## Total ({{.Currency}})			{{amount .Total}}
+{{if .WalletAmount}}Paid from wallet			{{amount .WalletAmount}}
+{{end}}
+Payment method: {{.PaymentMethod}}
+`
+
+// InvoiceData is what invoice templates are rendered with. Amounts are in
+// minor units. Number runs without gaps within the year the invoice is issued.
+type InvoiceData struct {
+	Number         string
+	Issuer         string
+	IssuedAt       time.Time
+	OrderID        int64
+	UserID         int64
+	CustomerName   string
+	CustomerEmail  string
+	BillingCompany string
+	BillingCountry string
+	TaxID          string
+	Currency       string
+	PaymentMethod  string
+	Lines          []InvoiceLine
+	Total          int64
+	WalletAmount   int64
+}
+
+// InvoiceLine is an item of an invoice
+type InvoiceLine struct {
+	Description string
+	Quantity    int
+	UnitPrice   int64
+	Amount      int64
+}
+
+// InvoiceStorage keeps invoices in S3-compatible object storage and signs
+// links downloading them
+type InvoiceStorage interface {
+	Publish(ctx context.Context, key, contentType string, body []byte) error
+	URL(key string) string
+	SignedURL(ctx context.Context, key string, ttl time.Duration) (string, error)
+}
+
+// InvoiceOptions configures the invoices an InvoiceService generates
+type InvoiceOptions struct {
+	// Template overrides DefaultInvoiceTemplate
+	Template string
+	// Issuer is the seller named on invoices
+	Issuer string
+	// Currency is the ISO 4217 code of the amounts
+	Currency string
+	// Prefix is prepended to the object key of every invoice
+	Prefix string
+	// LinkTTL is how long a signed download link stays valid
+	LinkTTL time.Duration
+}
+
+// InvoiceLink is a signed link downloading the invoice of an order
+type InvoiceLink struct {
+	OrderID   int64     `json:"order_id"`
+	URL       string    `json:"url"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// InvoiceService renders a PDF invoice of every confirmed order, stores it
+// and records where on the order
+type InvoiceService struct {
+	orders    store.OrderRepository
+	inventory store.InventoryRepository
+	invoices  store.InvoiceRepository
+	storage   InvoiceStorage
+	template  *template.Template
+	opts      InvoiceOptions
+	logger    *zap.Logger
+}
+
+var invoiceFuncs = template.FuncMap{
+	// amount formats an amount in minor units
+	"amount": func(minor int64) string { return fmt.Sprintf("%d.%02d", minor/100, minor%100) },
+	// date formats a time as a calendar date
+	"date": func(t time.Time) string { return t.UTC().Format("2006-01-02") },
+}
+
+// NewInvoiceService creates a new invoice service, failing on a template that
+// does not parse
+func NewInvoiceService(
+	orders store.OrderRepository,
+	inventory store.InventoryRepository,
+	invoices store.InvoiceRepository,
+	storage InvoiceStorage,
+	opts InvoiceOptions,
+) (*InvoiceService, error) {
+	text := opts.Template
+	if text == "" {
+		text = DefaultInvoiceTemplate
+	}
+	tmpl, err := template.New("invoice").Funcs(invoiceFuncs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse invoice template: %w", err)
+	}
+	return &InvoiceService{
+		orders:    orders,
+		inventory: inventory,
+		invoices:  invoices,
+		storage:   storage,
+		template:  tmpl,
+		opts:      opts,
+		logger:    util.GetLogger(),
+	}, nil
+}
+
+// HandleOrderConfirmed generates the invoice of a confirmed order. Orders that
+// are synthetic, deleted, anonymized or already invoiced are skipped, so a
+// redelivered event generates nothing new.
+func (s *InvoiceService) HandleOrderConfirmed(ctx context.Context, event *models.OrderConfirmedEvent) error {
+	order, err := s.orders.GetOrderByID(ctx, event.OrderID)
+	if errors.Is(err, apperrors.ErrOrderNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to load order: %w", err)
+	}
+	if order.Synthetic || order.AnonymizedAt != nil || order.InvoiceURL != nil {
+		return nil
+	}
+
+	if err := s.Generate(ctx, order); err != nil {
+		util.InvoicesGeneratedTotal.WithLabelValues("failed").Inc()
+		return err
+	}
+	util.InvoicesGeneratedTotal.WithLabelValues("generated").Inc()
+	return nil
+}
+
+// Generate renders the invoice of an order, uploads it and records its URL on
+// the order. An invoice already stored for the order is replaced.
+func (s *InvoiceService) Generate(ctx context.Context, order *models.Order) error {
+	ctx, span := util.StartSpan(ctx, "InvoiceService.Generate")
+	defer span.End()
+
+	data, err := s.invoiceData(ctx, order)
+	if err != nil {
+		return err
+	}
+	// Numbered once per order, so a retried invoice keeps its number
+	data.Number, err = s.invoices.AssignInvoiceNumber(ctx, order.ID, "INV-"+data.IssuedAt.UTC().Format("2006"))
+	if err != nil {
+		return fmt.Errorf("failed to number invoice: %w", err)
+	}
+	pdf, err := s.Render(data)
+	if err != nil {
+		return err
+	}
+
+	key := s.invoiceKey(order)
+	if err := s.storage.Publish(ctx, key, "application/pdf", pdf); err != nil {
+		return err
+	}
+	if err := s.invoices.SetOrderInvoice(ctx, order.ID, s.storage.URL(key)); err != nil {
+		return fmt.Errorf("failed to record invoice: %w", err)
+	}
+
+	s.logger.Info("Invoice generated", zap.Int64("order_id", order.ID), zap.String("key", key))
+	return nil
+}
+
+// Render renders an invoice as a PDF
+func (s *InvoiceService) Render(data *InvoiceData) ([]byte, error) {
+	var text bytes.Buffer
+	if err := s.template.Execute(&text, data); err != nil {
+		return nil, fmt.Errorf("failed to render invoice template: %w", err)
+	}
+	return renderInvoicePDF(text.String(), data.Number, s.opts.Issuer, data.IssuedAt)
+}
+
+// Link returns a signed link downloading the invoice of an order, valid for
+// the configured TTL. Orders not invoiced yet are not found.
+func (s *InvoiceService) Link(ctx context.Context, orderID int64) (*InvoiceLink, error) {
+	order, err := s.orders.GetOrderByID(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order.InvoiceURL == nil {
+		return nil, apperrors.New(apperrors.ErrNotFound, "order %d has no invoice yet", orderID)
+	}
+
+	stored, err := url.Parse(*order.InvoiceURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid invoice URL of order %d: %w", orderID, err)
+	}
+	expiresAt := time.Now().Add(s.opts.LinkTTL)
+	signed, err := s.storage.SignedURL(ctx, strings.TrimPrefix(stored.Path, "/"), s.opts.LinkTTL)
+	if err != nil {
+		return nil, err
+	}
+	return &InvoiceLink{OrderID: orderID, URL: signed, ExpiresAt: expiresAt}, nil
+}
+
+// invoiceKey returns the object key of an order's invoice, under its tenant
+func (s *InvoiceService) invoiceKey(order *models.Order) string {
+	owner := order.TenantID
+	if owner == "" {
+		owner = tenant.Default
+	}
+	return fmt.Sprintf("%s%s/%d.pdf", s.opts.Prefix, owner, order.ID)
+}
+
+// invoiceData gathers what the invoice of an order shows
+func (s *InvoiceService) invoiceData(ctx context.Context, order *models.Order) (*InvoiceData, error) {
+	items, err := s.orders.GetOrderItemsByOrderID(ctx, order.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load order items: %w", err)
+	}
+
+	productIDs := make([]int64, 0, len(items))
+	for _, item := range items {
+		productIDs = append(productIDs, item.ProductID)
+	}
+	products, err := s.inventory.GetProductsByIDs(ctx, productIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load products: %w", err)
+	}
+	variants, err := s.inventory.GetVariantsByProductIDs(ctx, productIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load variants: %w", err)
+	}
+	productByID := make(map[int64]models.Product, len(products))
+	for _, p := range products {
+		productByID[p.ID] = p
+	}
+	skuByVariant := make(map[int64]string, len(variants))
+	for _, v := range variants {
+		skuByVariant[v.ID] = v.SKU
+	}
+
+	issuedAt := time.Now()
+	if order.ConfirmedAt != nil {
+		issuedAt = *order.ConfirmedAt
+	}
+	data := &InvoiceData{
+		Issuer:         s.opts.Issuer,
+		IssuedAt:       issuedAt,
+		OrderID:        order.ID,
+		UserID:         order.UserID,
+		Currency:       s.opts.Currency,
+		CustomerName:   stringValue(order.CustomerName),
+		CustomerEmail:  stringValue(order.CustomerEmail),
+		BillingCompany: stringValue(order.BillingCompany),
+		BillingCountry: stringValue(order.BillingCountry),
+		TaxID:          stringValue(order.TaxID),
+		PaymentMethod:  order.PaymentMethod,
+		Total:          order.TotalAmount,
+		WalletAmount:   order.WalletAmount,
+	}
+
+	for _, item := range items {
+		description := fmt.Sprintf("Product %d", item.ProductID)
+		sku := skuByVariant[item.VariantID]
+		if p, ok := productByID[item.ProductID]; ok {
+			description = p.Name
+			if sku == "" {
+				sku = p.SKU
+			}
+		}
+		if sku != "" {
+			description += " (" + sku + ")"
+		}
+		data.Lines = append(data.Lines, InvoiceLine{
+			Description: description,
+			Quantity:    item.Quantity,
+			UnitPrice:   item.UnitPrice,
+			Amount:      item.UnitPrice * int64(item.Quantity),
+		})
+	}
+	return data, nil
+}
+
+// stringValue returns the string s points to, empty for nil
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
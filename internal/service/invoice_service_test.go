@@ -0,0 +1,123 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"order-service/internal/apperrors"
+	"order-service/internal/models"
+	"order-service/internal/store/mocks"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeInvoiceStorage keeps published invoices in memory
+type fakeInvoiceStorage struct {
+	objects map[string][]byte
+}
+
+func (f *fakeInvoiceStorage) Publish(_ context.Context, key, _ string, body []byte) error {
+	f.objects[key] = body
+	return nil
+}
+
+func (f *fakeInvoiceStorage) URL(key string) string {
+	return "s3://invoices/" + key
+}
+
+func (f *fakeInvoiceStorage) SignedURL(_ context.Context, key string, ttl time.Duration) (string, error) {
+	return "https://storage.example.com/invoices/" + key + "?X-Amz-Expires=" + ttl.String(), nil
+}
+
+func newTestInvoiceService(t *testing.T) (*InvoiceService, *mocks.OrderRepository, *mocks.InvoiceRepository, *fakeInvoiceStorage) {
+	t.Helper()
+	orders := mocks.NewOrderRepository(t)
+	inventory := mocks.NewInventoryRepository(t)
+	invoices := mocks.NewInvoiceRepository(t)
+	storage := &fakeInvoiceStorage{objects: make(map[string][]byte)}
+
+	inventory.On("GetProductsByIDs", mock.Anything, []int64{10}).
+		Return([]models.Product{{ID: 10, SKU: "TEE", Name: "T-shirt"}}, nil).Maybe()
+	inventory.On("GetVariantsByProductIDs", mock.Anything, []int64{10}).
+		Return([]models.ProductVariant{{ID: 11, ProductID: 10, SKU: "TEE-XL"}}, nil).Maybe()
+
+	s, err := NewInvoiceService(orders, inventory, invoices, storage, InvoiceOptions{
+		Issuer:   "Acme Ltd",
+		Currency: "USD",
+		Prefix:   "invoices/",
+		LinkTTL:  15 * time.Minute,
+	})
+	require.NoError(t, err)
+	return s, orders, invoices, storage
+}
+
+func TestInvoiceServiceStoresThePDFOfAConfirmedOrder(t *testing.T) {
+	ctx := context.Background()
+	s, orders, invoices, storage := newTestInvoiceService(t)
+
+	name := "Ada Lovelace"
+	confirmedAt := time.Date(2025, 12, 31, 23, 0, 0, 0, time.UTC)
+	orders.On("GetOrderByID", mock.Anything, int64(7)).
+		Return(&models.Order{ID: 7, UserID: 3, TotalAmount: 4998, PaymentMethod: "card", CustomerName: &name, TenantID: "acme",
+			ConfirmedAt: &confirmedAt}, nil).Once()
+	orders.On("GetOrderItemsByOrderID", mock.Anything, int64(7)).
+		Return([]models.OrderItem{{OrderID: 7, ProductID: 10, VariantID: 11, Quantity: 2, UnitPrice: 2499}}, nil).Once()
+	// Numbered in the series of the year it was confirmed, not by order ID
+	invoices.On("AssignInvoiceNumber", mock.Anything, int64(7), "INV-2025").Return("INV-2025-000001", nil).Once()
+	invoices.On("SetOrderInvoice", mock.Anything, int64(7), "s3://invoices/invoices/acme/7.pdf").Return(nil).Once()
+
+	require.NoError(t, s.HandleOrderConfirmed(ctx, &models.OrderConfirmedEvent{OrderID: 7}))
+
+	pdf := storage.objects["invoices/acme/7.pdf"]
+	require.NotEmpty(t, pdf)
+	assert.True(t, bytes.HasPrefix(pdf, []byte("%PDF-")))
+}
+
+func TestInvoiceServiceSkipsOrdersAlreadyInvoiced(t *testing.T) {
+	s, orders, _, storage := newTestInvoiceService(t)
+
+	url := "s3://invoices/invoices/acme/7.pdf"
+	orders.On("GetOrderByID", mock.Anything, int64(7)).Return(&models.Order{ID: 7, InvoiceURL: &url}, nil).Once()
+
+	require.NoError(t, s.HandleOrderConfirmed(context.Background(), &models.OrderConfirmedEvent{OrderID: 7}))
+	assert.Empty(t, storage.objects, "a redelivered event generates nothing new")
+}
+
+func TestInvoiceServiceRendersTheTemplate(t *testing.T) {
+	s, _, _, _ := newTestInvoiceService(t)
+
+	var text bytes.Buffer
+	require.NoError(t, s.template.Execute(&text, &InvoiceData{
+		Number:   "INV-2026-000007",
+		Issuer:   "Acme Ltd",
+		OrderID:  7,
+		Currency: "USD",
+		Lines:    []InvoiceLine{{Description: "T-shirt (TEE-XL)", Quantity: 2, UnitPrice: 2499, Amount: 4998}},
+		Total:    4998,
+	}))
+	assert.Contains(t, text.String(), "# Invoice INV-2026-000007\n")
+	assert.Contains(t, text.String(), "T-shirt (TEE-XL)\t2\t24.99\t49.98\n")
+	assert.Contains(t, text.String(), "## Total (USD)\t\t\t49.98\n")
+	assert.NotContains(t, text.String(), "wallet", "orders paid without wallet have no wallet line")
+}
+
+func TestInvoiceServiceLink(t *testing.T) {
+	ctx := context.Background()
+	s, orders, _, _ := newTestInvoiceService(t)
+
+	url := "s3://invoices/invoices/acme/7.pdf"
+	orders.On("GetOrderByID", mock.Anything, int64(7)).Return(&models.Order{ID: 7, InvoiceURL: &url}, nil).Once()
+	orders.On("GetOrderByID", mock.Anything, int64(8)).Return(&models.Order{ID: 8}, nil).Once()
+
+	link, err := s.Link(ctx, 7)
+	require.NoError(t, err)
+	assert.Equal(t, "https://storage.example.com/invoices/invoices/acme/7.pdf?X-Amz-Expires=15m0s", link.URL)
+	assert.WithinDuration(t, time.Now().Add(15*time.Minute), link.ExpiresAt, time.Minute)
+
+	_, err = s.Link(ctx, 8)
+	assert.Equal(t, apperrors.ErrNotFound.Code, apperrors.From(err).Code, "orders not invoiced yet have no link")
+}
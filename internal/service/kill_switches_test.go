@@ -0,0 +1,64 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"order-service/internal/apperrors"
+	"order-service/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKillSwitchesBlockSKUsAndPaymentMethods(t *testing.T) {
+	ctx := context.Background()
+	ks := NewKillSwitches(newTestRedis(t))
+
+	require.NoError(t, ks.Check(ctx, "credit_card", []string{"SKU-1"}))
+
+	_, err := ks.Engage(ctx, KillSwitchSKU, "SKU-1", "recall", "admin:operator")
+	require.NoError(t, err)
+	_, err = ks.Engage(ctx, KillSwitchPaymentMethod, "paypal", "provider outage", "admin:operator")
+	require.NoError(t, err)
+
+	err = ks.Check(ctx, "credit_card", []string{"SKU-2", "SKU-1"})
+	assert.ErrorIs(t, err, apperrors.ErrSKUBlocked)
+	assert.Contains(t, err.Error(), "recall")
+	assert.ErrorIs(t, ks.Check(ctx, "paypal", []string{"SKU-2"}), apperrors.ErrPaymentMethodDisabled)
+	assert.NoError(t, ks.Check(ctx, "credit_card", []string{"SKU-2"}))
+
+	switches, err := ks.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, switches, 2)
+	assert.Equal(t, KillSwitchPaymentMethod, switches[0].Kind)
+	assert.Equal(t, "SKU-1", switches[1].Value)
+	assert.Equal(t, "admin:operator", switches[1].Actor)
+
+	require.NoError(t, ks.Release(ctx, KillSwitchSKU, "SKU-1", "admin:operator"))
+	assert.NoError(t, ks.Check(ctx, "credit_card", []string{"SKU-1"}))
+	assert.ErrorIs(t, ks.Release(ctx, KillSwitchSKU, "SKU-1", "admin:operator"), apperrors.ErrNotFound)
+}
+
+func TestKillSwitchesRejectInvalidInput(t *testing.T) {
+	ks := NewKillSwitches(newTestRedis(t))
+
+	_, err := ks.Engage(context.Background(), "user", "42", "fraud", "admin:operator")
+	assert.ErrorIs(t, err, apperrors.ErrInvalidRequest)
+	_, err = ks.Engage(context.Background(), KillSwitchSKU, "SKU-1", " ", "admin:operator")
+	assert.ErrorIs(t, err, apperrors.ErrInvalidRequest)
+}
+
+func TestOrderedSKUsIncludeProductSKUs(t *testing.T) {
+	products := map[int64]*models.Product{1: {ID: 1, SKU: "TEE"}}
+	variants := map[int64]*models.ProductVariant{
+		11: {ID: 11, ProductID: 1, SKU: "TEE"},
+		12: {ID: 12, ProductID: 1, SKU: "TEE-XL"},
+	}
+
+	skus := orderedSKUs([]OrderItemRequest{
+		{ProductID: 1, VariantID: 11, Quantity: 1},
+		{ProductID: 1, VariantID: 12, Quantity: 1},
+	}, products, variants)
+	assert.Equal(t, []string{"TEE", "TEE-XL"}, skus)
+}
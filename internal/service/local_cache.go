@@ -0,0 +1,102 @@
+package service
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// localCache is a bounded in-process LRU cache whose entries expire after a
+// TTL. It sits in front of Redis for reads hot enough that the round trip
+// dominates their latency.
+type localCache[K comparable, V any] struct {
+	ttl      time.Duration
+	capacity int
+
+	mu      sync.Mutex
+	order   *list.List // most recently used first
+	entries map[K]*list.Element
+}
+
+// localEntry is one cached value and when it expires
+type localEntry[K comparable, V any] struct {
+	key       K
+	value     V
+	expiresAt time.Time
+}
+
+// newLocalCache creates a cache of at most capacity entries, each kept for
+// ttl. Returns nil, a cache that never hits, if either is zero.
+func newLocalCache[K comparable, V any](capacity int, ttl time.Duration) *localCache[K, V] {
+	if capacity <= 0 || ttl <= 0 {
+		return nil
+	}
+	return &localCache[K, V]{
+		ttl:      ttl,
+		capacity: capacity,
+		order:    list.New(),
+		entries:  make(map[K]*list.Element),
+	}
+}
+
+// get returns the unexpired value of key
+func (c *localCache[K, V]) get(key K) (V, bool) {
+	var zero V
+	if c == nil {
+		return zero, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.entries[key]
+	if !ok {
+		return zero, false
+	}
+	entry := elem.Value.(*localEntry[K, V])
+	if time.Now().After(entry.expiresAt) {
+		c.order.Remove(elem)
+		delete(c.entries, key)
+		return zero, false
+	}
+	c.order.MoveToFront(elem)
+	return entry.value, true
+}
+
+// set caches value under key, evicting the least recently used entry when
+// the cache is full
+func (c *localCache[K, V]) set(key K, value V) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	expiresAt := time.Now().Add(c.ttl)
+	if elem, ok := c.entries[key]; ok {
+		entry := elem.Value.(*localEntry[K, V])
+		entry.value, entry.expiresAt = value, expiresAt
+		c.order.MoveToFront(elem)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&localEntry[K, V]{key: key, value: value, expiresAt: expiresAt})
+	if c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*localEntry[K, V]).key)
+	}
+}
+
+// evict drops key from the cache
+func (c *localCache[K, V]) evict(key K) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.entries[key]; ok {
+		c.order.Remove(elem)
+		delete(c.entries, key)
+	}
+}
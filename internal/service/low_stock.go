@@ -0,0 +1,136 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"order-service/internal/broker"
+	"order-service/internal/models"
+	"order-service/internal/redisclient"
+	"order-service/internal/store"
+	"order-service/internal/util"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// LowStockMonitor alerts procurement when committing orders leaves the
+// available stock of a variant at or below its product's reorder threshold
+type LowStockMonitor struct {
+	inventory      store.InventoryRepository
+	products       *ProductCache
+	redis          *redisclient.Client
+	eventPublisher *broker.EventPublisher
+	logger         *zap.Logger
+}
+
+// NewLowStockMonitor creates a new low-stock monitor
+func NewLowStockMonitor(
+	inventory store.InventoryRepository,
+	products *ProductCache,
+	redis *redisclient.Client,
+	eventPublisher *broker.EventPublisher,
+) *LowStockMonitor {
+	return &LowStockMonitor{
+		inventory:      inventory,
+		products:       products,
+		redis:          redis,
+		eventPublisher: eventPublisher,
+		logger:         util.GetLogger(),
+	}
+}
+
+// Check publishes an InventoryLow event when the available stock of a variant,
+// across warehouses, is at or below its product's reorder threshold after an
+// order's commit. A variant alerts once until a commit finds its stock back
+// above the threshold.
+func (m *LowStockMonitor) Check(ctx context.Context, orderID, productID, variantID int64) {
+	products, err := m.products.GetProductsByIDs(ctx, []int64{productID})
+	if err != nil || len(products) == 0 || products[0].ReorderThreshold == nil {
+		return
+	}
+	threshold := *products[0].ReorderThreshold
+
+	available, _, err := m.redis.GetInventory(ctx, variantID)
+	if err != nil {
+		inv, dbErr := m.inventory.GetInventory(ctx, variantID)
+		if dbErr != nil {
+			m.logger.Warn("Failed to read stock for low-stock check",
+				zap.Int64("variant_id", variantID),
+				zap.Error(dbErr))
+			return
+		}
+		available = inv.Available
+	}
+
+	low, err := m.crossed(ctx, variantID, available, threshold)
+	if err != nil {
+		m.logger.Warn("Failed to track low stock", zap.Int64("variant_id", variantID), zap.Error(err))
+		return
+	}
+	if !low {
+		return
+	}
+
+	util.InventoryLowStockAlertsTotal.Inc()
+	event := &models.InventoryLowEvent{
+		BaseEvent: models.BaseEvent{
+			EventID:   uuid.New().String(),
+			EventType: models.EventTypeInventoryLow,
+			Timestamp: time.Now(),
+		},
+		ProductID:        productID,
+		VariantID:        variantID,
+		Available:        available,
+		ReorderThreshold: threshold,
+		OrderID:          orderID,
+	}
+	if variant, err := m.inventory.GetVariantByID(ctx, variantID); err == nil {
+		event.SKU = variant.SKU
+	}
+
+	if err := m.eventPublisher.PublishInventoryLow(ctx, event); err != nil {
+		m.logger.Error("Failed to publish InventoryLow event",
+			zap.Int64("variant_id", variantID),
+			zap.Error(err))
+		// Alert again on the next commit rather than never
+		_ = m.redis.ClearLowStock(ctx, variantID)
+	}
+}
+
+// crossed reports whether available stock at or below threshold was not
+// alerted yet, flagging it, and clears the flag of stock above it
+func (m *LowStockMonitor) crossed(ctx context.Context, variantID int64, available, threshold int) (bool, error) {
+	if available > threshold {
+		return false, m.redis.ClearLowStock(ctx, variantID)
+	}
+	return m.redis.MarkLowStock(ctx, variantID)
+}
+
+// LowStock lists the variants at or below their product's reorder threshold
+func (m *LowStockMonitor) LowStock(ctx context.Context) ([]models.LowStockItem, error) {
+	return m.inventory.GetLowStockItems(ctx)
+}
+
+// SetReorderThreshold changes the reorder threshold of a product, nil to stop
+// alerting, and re-arms the alerts of its variants against the new threshold
+func (m *LowStockMonitor) SetReorderThreshold(ctx context.Context, productID int64, threshold *int) error {
+	if err := m.inventory.SetReorderThreshold(ctx, productID, threshold); err != nil {
+		return err
+	}
+	m.products.Invalidate(ctx, productID)
+
+	variants, err := m.inventory.GetVariantsByProductIDs(ctx, []int64{productID})
+	if err != nil {
+		return fmt.Errorf("failed to load variants of product %d: %w", productID, err)
+	}
+	for _, variant := range variants {
+		if err := m.redis.ClearLowStock(ctx, variant.ID); err != nil {
+			m.logger.Warn("Failed to re-arm low-stock alert",
+				zap.Int64("variant_id", variant.ID),
+				zap.Error(err))
+		}
+	}
+	return nil
+}
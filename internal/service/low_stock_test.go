@@ -0,0 +1,29 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLowStockAlertsOncePerCrossing(t *testing.T) {
+	ctx := context.Background()
+	m := NewLowStockMonitor(nil, nil, newTestRedis(t), nil)
+
+	for _, step := range []struct {
+		available int
+		alert     bool
+	}{
+		{12, false},
+		{10, true},
+		{4, false}, // already alerted
+		{25, false},
+		{9, true}, // restocked above the threshold in between
+	} {
+		low, err := m.crossed(ctx, 11, step.available, 10)
+		require.NoError(t, err)
+		assert.Equal(t, step.alert, low, "available %d", step.available)
+	}
+}
@@ -0,0 +1,288 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"order-service/internal/apperrors"
+	"order-service/internal/models"
+	"order-service/internal/util"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// OrderItemChange sets the quantity of a variant ordered; zero removes it
+type OrderItemChange struct {
+	VariantID int64 `json:"variant_id" binding:"required"`
+	Quantity  int   `json:"quantity" binding:"min=0"`
+}
+
+// AmendOrderRequest changes the items of an order for the customer who placed
+// it. UserID comes from the caller's credentials, never from the body.
+type AmendOrderRequest struct {
+	UserID int64             `json:"-"`
+	Items  []OrderItemChange `json:"items" binding:"required,min=1,dive"`
+}
+
+// OrderAmendment is an order after its items were changed
+type OrderAmendment struct {
+	OrderID             int64              `json:"order_id"`
+	Status              string             `json:"status"`
+	PreviousTotalAmount int64              `json:"previous_total_amount"`
+	TotalAmount         int64              `json:"total_amount"`
+	WalletAmount        int64              `json:"wallet_amount,omitempty"`
+	Items               []models.OrderItem `json:"items"`
+}
+
+// AmendOrder changes the quantities of an order's items, or removes them, for
+// the customer who placed it while the order awaits payment. Reservations are
+// adjusted by the difference only: units added are reserved before the order
+// is changed, so an edit that runs out of stock changes nothing, and units
+// dropped are given back once it is. Edits are rejected once payment started.
+// A reserved order is put up for payment again at its new total, as the
+// payment requested for the old one is refused. Orders another user placed
+// are reported as not found.
+func (so *SagaOrchestrator) AmendOrder(ctx context.Context, orderID int64, req AmendOrderRequest) (*OrderAmendment, error) {
+	ctx, span := util.StartSpan(ctx, "SagaOrchestrator.AmendOrder")
+	defer span.End()
+
+	ctx, lock, err := so.lockOrder(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer so.unlockOrder(lock, orderID)
+
+	order, err := so.orders.GetOrderByID(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order.UserID != req.UserID {
+		return nil, apperrors.New(apperrors.ErrOrderNotFound, "order %d not found", orderID)
+	}
+	if order.Status != models.OrderStatusCreated && order.Status != models.OrderStatusReserved {
+		return nil, apperrors.New(apperrors.ErrInvalidOrderState,
+			"order %d in status %s can no longer be edited", orderID, order.Status)
+	}
+
+	items, err := so.orders.GetOrderItemsByOrderID(ctx, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get order items: %w", err)
+	}
+	amended, changed, err := applyItemChanges(orderID, items, req.Items)
+	if err != nil {
+		return nil, err
+	}
+
+	// Reserve the units added first; nothing else has changed if stock runs out
+	var grown []models.OrderItem
+	restore := func() {
+		for _, item := range grown {
+			if _, err := so.inventoryClient.ResizeReservation(ctx, orderID, item.VariantID, item.Quantity); err != nil {
+				so.logger.Error("Failed to restore reservation of unamended order",
+					zap.Int64("order_id", orderID),
+					zap.Int64("variant_id", item.VariantID),
+					zap.Error(err))
+			}
+		}
+	}
+	for _, change := range changed {
+		if change.to <= change.from.Quantity {
+			continue
+		}
+		ok, err := so.inventoryClient.ResizeReservation(ctx, orderID, change.from.VariantID, change.to)
+		if err != nil || !ok {
+			restore()
+			if err != nil {
+				return nil, fmt.Errorf("failed to reserve stock for variant %d: %w", change.from.VariantID, err)
+			}
+			util.OrdersAmendedTotal.WithLabelValues("insufficient_stock").Inc()
+			return nil, apperrors.New(apperrors.ErrInsufficientStock, "insufficient stock for variant %d", change.from.VariantID)
+		}
+		grown = append(grown, change.from)
+	}
+
+	var total int64
+	for _, item := range amended {
+		total += item.UnitPrice * int64(item.Quantity)
+	}
+	walletAmount := min(order.WalletAmount, total)
+	if order.PaymentMethod == models.PaymentMethodWallet {
+		walletAmount = total
+	}
+
+	rows := make([]models.OrderItem, 0, len(changed))
+	for _, change := range changed {
+		row := change.from
+		row.Quantity = change.to
+		rows = append(rows, row)
+	}
+	if err := so.orders.AmendOrderItems(ctx, orderID, lock.Token(), rows, total, walletAmount); err != nil {
+		restore()
+		var appErr *apperrors.Error
+		if errors.As(err, &appErr) {
+			util.OrdersAmendedTotal.WithLabelValues("rejected").Inc()
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to amend order: %w", err)
+	}
+	so.orderCache.Invalidate(ctx, orderID)
+	util.OrdersAmendedTotal.WithLabelValues("amended").Inc()
+
+	// Give back the units dropped now that the order no longer counts them
+	for _, change := range changed {
+		switch {
+		case change.to == 0:
+			if err := so.inventoryClient.ReleaseStock(ctx, orderID, change.from.VariantID); err != nil {
+				so.logger.Error("Failed to release stock of removed item",
+					zap.Int64("order_id", orderID),
+					zap.Int64("variant_id", change.from.VariantID),
+					zap.Error(err))
+				so.compensations.Enqueue(ctx, models.Compensation{
+					OrderID:   orderID,
+					Kind:      models.CompensationReleaseStock,
+					VariantID: change.from.VariantID,
+					Reason:    "order_amended",
+				}, err)
+			}
+		case change.to < change.from.Quantity:
+			if _, err := so.inventoryClient.ResizeReservation(ctx, orderID, change.from.VariantID, change.to); err != nil {
+				so.logger.Error("Failed to release stock of reduced item",
+					zap.Int64("order_id", orderID),
+					zap.Int64("variant_id", change.from.VariantID),
+					zap.Error(err))
+			}
+		}
+	}
+
+	so.publishAmended(ctx, order, amended, changed, total, walletAmount)
+
+	so.logger.Info("Order amended",
+		zap.Int64("order_id", orderID),
+		zap.Int64("previous_total", order.TotalAmount),
+		zap.Int64("total", total),
+		zap.Int("changed_items", len(changed)))
+	return &OrderAmendment{
+		OrderID:             orderID,
+		Status:              order.Status,
+		PreviousTotalAmount: order.TotalAmount,
+		TotalAmount:         total,
+		WalletAmount:        walletAmount,
+		Items:               amended,
+	}, nil
+}
+
+// itemChange is an order item and the quantity it is changed to
+type itemChange struct {
+	from models.OrderItem
+	to   int
+}
+
+// applyItemChanges validates the changes to an order's items and returns the
+// items after them and the items that change
+func applyItemChanges(orderID int64, items []models.OrderItem, changes []OrderItemChange) ([]models.OrderItem, []itemChange, error) {
+	quantities := make(map[int64]int, len(changes))
+	for _, change := range changes {
+		if change.Quantity < 0 {
+			return nil, nil, apperrors.New(apperrors.ErrInvalidRequest, "quantity of variant %d must not be negative", change.VariantID)
+		}
+		if _, dup := quantities[change.VariantID]; dup {
+			return nil, nil, apperrors.New(apperrors.ErrInvalidRequest, "variant %d is changed twice", change.VariantID)
+		}
+		quantities[change.VariantID] = change.Quantity
+	}
+
+	amended := make([]models.OrderItem, 0, len(items))
+	var changed []itemChange
+	for _, item := range items {
+		quantity, ok := quantities[item.VariantID]
+		if !ok {
+			amended = append(amended, item)
+			continue
+		}
+		delete(quantities, item.VariantID)
+		if item.FulfillmentStatus == models.FulfillmentStatusBackordered {
+			return nil, nil, apperrors.New(apperrors.ErrInvalidRequest, "backordered variant %d cannot be edited", item.VariantID)
+		}
+		if quantity != item.Quantity {
+			changed = append(changed, itemChange{from: item, to: quantity})
+		}
+		if quantity > 0 {
+			item.Quantity = quantity
+			amended = append(amended, item)
+		}
+	}
+	for variantID := range quantities {
+		return nil, nil, apperrors.New(apperrors.ErrInvalidRequest, "order %d has no item of variant %d", orderID, variantID)
+	}
+	if len(amended) == 0 {
+		return nil, nil, apperrors.New(apperrors.ErrInvalidRequest, "an order needs at least one item; cancel order %d instead", orderID)
+	}
+	if len(changed) == 0 {
+		return nil, nil, apperrors.New(apperrors.ErrInvalidRequest, "the items of order %d are unchanged", orderID)
+	}
+	return amended, changed, nil
+}
+
+// publishAmended publishes OrderAmended and, for a reserved order, OrderReserved
+// with the new totals, so its payment is requested at them
+func (so *SagaOrchestrator) publishAmended(ctx context.Context, order *models.Order, amended []models.OrderItem, changed []itemChange, total, walletAmount int64) {
+	items := make([]models.OrderItemData, 0, len(amended))
+	for _, item := range amended {
+		items = append(items, models.OrderItemData{
+			ProductID: item.ProductID,
+			VariantID: item.VariantID,
+			Quantity:  item.Quantity,
+			UnitPrice: item.UnitPrice,
+		})
+	}
+	changes := make([]models.OrderItemData, 0, len(changed))
+	for _, change := range changed {
+		changes = append(changes, models.OrderItemData{
+			ProductID: change.from.ProductID,
+			VariantID: change.from.VariantID,
+			Quantity:  change.to,
+			UnitPrice: change.from.UnitPrice,
+		})
+	}
+
+	event := &models.OrderAmendedEvent{
+		BaseEvent: models.BaseEvent{
+			EventID:   uuid.New().String(),
+			EventType: models.EventTypeOrderAmended,
+			Timestamp: time.Now(),
+		},
+		OrderID:             order.ID,
+		UserID:              order.UserID,
+		PreviousTotalAmount: order.TotalAmount,
+		TotalAmount:         total,
+		WalletAmount:        walletAmount,
+		Items:               items,
+		Changes:             changes,
+	}
+	if err := so.eventPublisher.PublishOrderAmended(ctx, event); err != nil {
+		so.logger.Error("Failed to publish OrderAmended event", zap.Error(err))
+	}
+
+	if order.Status != models.OrderStatusReserved {
+		return
+	}
+	reserved := &models.OrderReservedEvent{
+		BaseEvent: models.BaseEvent{
+			EventID:   uuid.New().String(),
+			EventType: models.EventTypeOrderReserved,
+			Timestamp: time.Now(),
+		},
+		OrderID:      order.ID,
+		UserID:       order.UserID,
+		TotalAmount:  total,
+		WalletAmount: walletAmount,
+		Items:        items,
+		Synthetic:    order.Synthetic,
+	}
+	if err := so.eventPublisher.PublishOrderReserved(ctx, reserved); err != nil {
+		so.logger.Error("Failed to publish OrderReserved event", zap.Error(err))
+	}
+}
@@ -0,0 +1,168 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"order-service/internal/models"
+	"order-service/internal/redisclient"
+	"order-service/internal/tenant"
+	"order-service/internal/util"
+
+	"go.uber.org/zap"
+)
+
+// maxLocalOrderCacheEntries triggers a sweep of expired local entries
+const maxLocalOrderCacheEntries = 10000
+
+// cachedOrder is the payload stored in the order read cache. Entries are
+// shared by all tenants and keep their order's, which models.Order does not
+// serialize.
+type cachedOrder struct {
+	Order     *models.Order      `json:"order"`
+	Items     []models.OrderItem `json:"items"`
+	Tenant    string             `json:"tenant,omitempty"`
+	expiresAt time.Time
+}
+
+// OrderCache is a two-level (local + Redis) read cache for orders. Mutations
+// invalidate through Redis pub/sub so every API replica evicts its local copy.
+type OrderCache struct {
+	redis    *redisclient.Client
+	ttl      time.Duration
+	localTTL time.Duration
+	logger   *zap.Logger
+
+	mu    sync.RWMutex
+	local map[int64]*cachedOrder
+}
+
+// NewOrderCache creates a new order cache
+func NewOrderCache(redis *redisclient.Client, ttl, localTTL time.Duration) *OrderCache {
+	return &OrderCache{
+		redis:    redis,
+		ttl:      ttl,
+		localTTL: localTTL,
+		logger:   util.GetLogger(),
+		local:    make(map[int64]*cachedOrder),
+	}
+}
+
+// Get returns a cached order and its items, or ok=false on a miss. Another
+// tenant's order is a miss, so the store turns it away.
+func (oc *OrderCache) Get(ctx context.Context, orderID int64) (*models.Order, []models.OrderItem, bool) {
+	oc.mu.RLock()
+	entry, found := oc.local[orderID]
+	oc.mu.RUnlock()
+	if found && time.Now().Before(entry.expiresAt) {
+		if !tenant.Owns(ctx, entry.Tenant) {
+			return nil, nil, false
+		}
+		return entry.Order, entry.Items, true
+	}
+
+	data, err := oc.redis.GetCachedOrder(ctx, orderID)
+	if err != nil {
+		oc.logger.Warn("Failed to read order cache", zap.Int64("order_id", orderID), zap.Error(err))
+		return nil, nil, false
+	}
+	if data == nil {
+		return nil, nil, false
+	}
+
+	var cached cachedOrder
+	if err := json.Unmarshal(data, &cached); err != nil || cached.Order == nil {
+		return nil, nil, false
+	}
+	cached.Order.TenantID = cached.Tenant
+
+	oc.storeLocal(orderID, &cached)
+	if !tenant.Owns(ctx, cached.Tenant) {
+		return nil, nil, false
+	}
+	return cached.Order, cached.Items, true
+}
+
+// Generation returns the invalidation generation of an order, to be read
+// before the order is loaded and passed to Set. ok is false when Redis cannot
+// tell, and the order should not be cached.
+func (oc *OrderCache) Generation(ctx context.Context, orderID int64) (generation int64, ok bool) {
+	generation, err := oc.redis.OrderCacheGeneration(ctx, orderID)
+	if err != nil {
+		oc.logger.Warn("Failed to read order cache generation", zap.Int64("order_id", orderID), zap.Error(err))
+		return 0, false
+	}
+	return generation, true
+}
+
+// Set populates both cache levels, unless the order has been invalidated since
+// generation was read: the copy being cached may predate that change. The
+// customer's personal data is left out, so it is only ever read from the
+// database, where it is encrypted.
+func (oc *OrderCache) Set(ctx context.Context, order *models.Order, items []models.OrderItem, generation int64) {
+	cached := &cachedOrder{Order: withoutPersonalData(order), Items: items, Tenant: order.TenantID}
+
+	data, err := json.Marshal(cached)
+	if err != nil {
+		return
+	}
+
+	// Stored locally first, so an invalidation broadcast after the write to
+	// Redis always finds the entry to evict
+	oc.storeLocal(order.ID, cached)
+	stored, err := oc.redis.SetCachedOrder(ctx, order.ID, generation, data, oc.ttl)
+	if err != nil {
+		oc.logger.Warn("Failed to write order cache", zap.Int64("order_id", order.ID), zap.Error(err))
+	}
+	if !stored {
+		oc.evictLocal(order.ID)
+	}
+}
+
+// Invalidate evicts an order locally and broadcasts the invalidation to all replicas
+func (oc *OrderCache) Invalidate(ctx context.Context, orderID int64) {
+	oc.evictLocal(orderID)
+
+	if err := oc.redis.InvalidateOrder(ctx, orderID); err != nil {
+		oc.logger.Error("Failed to publish order cache invalidation",
+			zap.Int64("order_id", orderID),
+			zap.Error(err))
+	}
+}
+
+// Run listens for invalidations from other replicas until ctx is cancelled
+func (oc *OrderCache) Run(ctx context.Context) error {
+	return oc.redis.SubscribeOrderInvalidations(ctx, oc.evictLocal)
+}
+
+func (oc *OrderCache) storeLocal(orderID int64, entry *cachedOrder) {
+	now := time.Now()
+	entry.expiresAt = now.Add(oc.localTTL)
+
+	oc.mu.Lock()
+	defer oc.mu.Unlock()
+
+	if len(oc.local) >= maxLocalOrderCacheEntries {
+		for id, e := range oc.local {
+			if now.After(e.expiresAt) {
+				delete(oc.local, id)
+			}
+		}
+	}
+	oc.local[orderID] = entry
+}
+
+// withoutPersonalData returns a copy of order without the customer's personal data
+func withoutPersonalData(order *models.Order) *models.Order {
+	stripped := *order
+	stripped.CustomerEmail, stripped.CustomerName, stripped.TaxID = nil, nil, nil
+	return &stripped
+}
+
+func (oc *OrderCache) evictLocal(orderID int64) {
+	oc.mu.Lock()
+	delete(oc.local, orderID)
+	oc.mu.Unlock()
+}
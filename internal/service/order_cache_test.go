@@ -0,0 +1,122 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"order-service/internal/models"
+	"order-service/internal/redisclient"
+	"order-service/internal/store/mocks"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestOrderCache(t *testing.T) (*OrderCache, *miniredis.Miniredis) {
+	t.Helper()
+
+	server := miniredis.RunT(t)
+	client, err := redisclient.NewClient(server.Addr(), "", 0)
+	require.NoError(t, err)
+	t.Cleanup(func() { client.Close() })
+	return NewOrderCache(client, time.Minute, time.Minute), server
+}
+
+func TestOrderCacheLeavesOutPersonalData(t *testing.T) {
+	cache, server := newTestOrderCache(t)
+	email, name, taxID := "jane@example.com", "Jane Doe", "DE123456789"
+	order := &models.Order{ID: 7, UserID: 3, Status: models.OrderStatusConfirmed,
+		CustomerEmail: &email, CustomerName: &name, TaxID: &taxID}
+
+	cache.Set(context.Background(), order, nil, 0)
+
+	payload, err := server.Get("order-cache:{order:7}")
+	require.NoError(t, err)
+	assert.NotContains(t, payload, email)
+	assert.NotContains(t, payload, name)
+	assert.NotContains(t, payload, taxID)
+	assert.Equal(t, &email, order.CustomerEmail, "the caller's order is left alone")
+
+	cached, _, ok := cache.Get(context.Background(), 7)
+	require.True(t, ok)
+	assert.Nil(t, cached.CustomerEmail)
+	assert.Nil(t, cached.CustomerName)
+	assert.Nil(t, cached.TaxID)
+}
+
+func TestOrderCacheServesOtherReplicasFromRedis(t *testing.T) {
+	writer, _ := newTestOrderCache(t)
+	reader := NewOrderCache(writer.redis, time.Minute, time.Minute)
+
+	_, _, ok := reader.Get(context.Background(), 7)
+	assert.False(t, ok, "an order never cached is a miss")
+
+	writer.Set(context.Background(), &models.Order{ID: 7, Status: models.OrderStatusPaid},
+		[]models.OrderItem{{OrderID: 7, ProductID: 1, Quantity: 2}}, 0)
+	order, items, ok := reader.Get(context.Background(), 7)
+	require.True(t, ok, "another replica hits the copy in Redis")
+	assert.Equal(t, models.OrderStatusPaid, order.Status)
+	assert.Len(t, items, 1)
+}
+
+func TestOrderCacheInvalidationsReachEveryReplica(t *testing.T) {
+	server := miniredis.RunT(t)
+	replica := func() *OrderCache {
+		client, err := redisclient.NewClient(server.Addr(), "", 0)
+		require.NoError(t, err)
+		t.Cleanup(func() { client.Close() })
+		return NewOrderCache(client, time.Minute, time.Minute)
+	}
+	writer, reader := replica(), replica()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go reader.Run(ctx)
+	require.Eventually(t, func() bool {
+		return server.PubSubNumSub(redisclient.OrderInvalidationChannel)[redisclient.OrderInvalidationChannel] == 1
+	}, time.Second, 10*time.Millisecond)
+
+	writer.Set(ctx, &models.Order{ID: 7, Status: models.OrderStatusPaid}, nil, 0)
+	_, _, ok := reader.Get(ctx, 7)
+	require.True(t, ok, "the reader caches the order locally")
+
+	writer.Invalidate(ctx, 7)
+	assert.Eventually(t, func() bool {
+		reader.mu.RLock()
+		defer reader.mu.RUnlock()
+		_, cached := reader.local[7]
+		return !cached
+	}, time.Second, 10*time.Millisecond, "the reader evicts its local copy")
+	_, _, ok = reader.Get(ctx, 7)
+	assert.False(t, ok)
+}
+
+func TestGetOrderDoesNotCacheOrdersInvalidatedWhileLoading(t *testing.T) {
+	cache, server := newTestOrderCache(t)
+	orders := mocks.NewOrderRepository(t)
+	// The order is cancelled and invalidated after the read saw it paid
+	orders.On("GetOrderByID", mock.Anything, int64(7)).
+		Run(func(mock.Arguments) { cache.Invalidate(context.Background(), 7) }).
+		Return(&models.Order{ID: 7, Status: models.OrderStatusPaid}, nil).Once()
+	orders.On("GetOrderByID", mock.Anything, int64(7)).
+		Return(&models.Order{ID: 7, Status: models.OrderStatusCancelled}, nil).Once()
+	orders.On("GetOrderItemsByOrderID", mock.Anything, int64(7)).Return(nil, nil).Twice()
+	service := NewOrderService(orders, nil, nil, nil, cache, nil, nil, nil)
+
+	order, _, err := service.GetOrder(context.Background(), 7)
+	require.NoError(t, err)
+	assert.Equal(t, models.OrderStatusPaid, order.Status)
+	assert.False(t, server.Exists("order-cache:{order:7}"), "the stale copy is not cached in Redis")
+	_, _, ok := cache.Get(context.Background(), 7)
+	assert.False(t, ok, "nor locally")
+
+	order, _, err = service.GetOrder(context.Background(), 7)
+	require.NoError(t, err)
+	assert.Equal(t, models.OrderStatusCancelled, order.Status)
+	cached, _, ok := cache.Get(context.Background(), 7)
+	require.True(t, ok, "a later read is cached again")
+	assert.Equal(t, models.OrderStatusCancelled, cached.Status)
+}
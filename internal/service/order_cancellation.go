@@ -0,0 +1,159 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"order-service/internal/apperrors"
+	"order-service/internal/models"
+	"order-service/internal/util"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// SetCancellationPolicies enables cancellations by customers under the
+// policies of their customer group
+func (so *SagaOrchestrator) SetCancellationPolicies(policies *CancellationPolicies) {
+	so.cancellations = policies
+}
+
+// CancelOrder cancels an order for the customer who placed it. A captured
+// payment is voided within the free-cancel window of the customer's policy and
+// refunded less the late-cancel fee after it; the policy and fee are recorded
+// on the order. Orders another user placed are reported as not found.
+//
+// The order is cancelled before its payment is returned, so no money leaves
+// for an order whose cancellation failed. A refund or void that fails is
+// queued as a compensation and retried in the background.
+func (so *SagaOrchestrator) CancelOrder(ctx context.Context, orderID, userID int64, reason string) (*CancellationQuote, error) {
+	ctx, span := util.StartSpan(ctx, "SagaOrchestrator.CancelOrder")
+	defer span.End()
+
+	ctx, lock, err := so.lockOrder(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer so.unlockOrder(lock, orderID)
+
+	order, err := so.orders.GetOrderByID(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order.UserID != userID {
+		return nil, apperrors.New(apperrors.ErrOrderNotFound, "order %d not found", orderID)
+	}
+
+	var captured, paymentID int64
+	switch order.Status {
+	case models.OrderStatusCreated, models.OrderStatusReserved:
+	case models.OrderStatusPaid, models.OrderStatusOnHold, models.OrderStatusConfirmed:
+		payment, err := so.paymentService.GetPayment(ctx, orderID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get payment: %w", err)
+		}
+		if payment.Status == models.PaymentStatusSuccess {
+			captured, paymentID = payment.Amount, payment.ID
+		}
+	default:
+		return nil, apperrors.New(apperrors.ErrInvalidOrderState,
+			"order %d in status %s cannot be cancelled", orderID, order.Status)
+	}
+
+	policy, err := so.cancellations.For(ctx, order.UserID)
+	if err != nil {
+		return nil, err
+	}
+	quote := policy.Quote(order, captured, time.Now())
+
+	if err := so.orders.SetOrderCancellation(ctx, orderID, quote.Policy, quote.Fee); err != nil {
+		return nil, fmt.Errorf("failed to record cancellation policy: %w", err)
+	}
+	change := models.StatusChange{Reason: "customer_cancelled: " + reason, Actor: models.ActorCustomer}
+	if err := so.cancelOrder(ctx, orderID, lock.Token(), change); err != nil {
+		return nil, err
+	}
+	// The payment of a cancelled order is owed even if the lock or the
+	// request is lost from here on
+	ctx = context.WithoutCancel(ctx)
+
+	if captured > 0 {
+		if err := so.returnPayment(ctx, quote); err != nil {
+			so.logger.Error("Failed to return payment of cancelled order",
+				zap.Int64("order_id", orderID),
+				zap.Error(err))
+			so.compensations.Enqueue(ctx, models.Compensation{
+				OrderID:   orderID,
+				Kind:      models.CompensationCancellationRefund,
+				PaymentID: paymentID,
+				Amount:    quote.Refund,
+				Reason:    "customer_cancellation",
+			}, err)
+		}
+	}
+
+	window := "free"
+	if quote.Late {
+		window = "late"
+	}
+	util.CustomerCancellationsTotal.WithLabelValues(policy.Name, window).Inc()
+
+	event := &models.OrderCancelledEvent{
+		BaseEvent: models.BaseEvent{
+			EventID:   uuid.New().String(),
+			EventType: models.EventTypeOrderCancelled,
+			Timestamp: time.Now(),
+		},
+		OrderID: orderID,
+		Reason:  "customer_cancelled",
+	}
+	if err := so.eventPublisher.PublishOrderCancelled(ctx, event); err != nil {
+		so.logger.Error("Failed to publish OrderCancelled event", zap.Error(err))
+	}
+
+	so.logger.Info("Order cancelled by customer",
+		zap.Int64("order_id", orderID),
+		zap.String("policy", quote.Policy),
+		zap.Bool("late", quote.Late),
+		zap.Int64("fee", quote.Fee))
+	return &quote, nil
+}
+
+// returnPayment returns the captured payment of an order a customer cancelled
+// under quote: it is voided when there is no fee and refunded less the fee
+// otherwise
+func (so *SagaOrchestrator) returnPayment(ctx context.Context, quote CancellationQuote) error {
+	if quote.Fee > 0 {
+		return so.paymentService.RefundPayment(ctx, quote, "customer_cancellation")
+	}
+	return so.paymentService.VoidPayment(ctx, quote.OrderID, "customer_cancellation")
+}
+
+// retryCancellationRefund returns the payment of a cancelled order again after
+// it failed, refunding the compensation's amount. Payments returned in the
+// meantime, e.g. by an operator, are left alone.
+func (so *SagaOrchestrator) retryCancellationRefund(ctx context.Context, c *models.Compensation) error {
+	order, err := so.orders.GetOrderByID(ctx, c.OrderID)
+	if err != nil {
+		return err
+	}
+	payment, err := so.paymentService.GetPayment(ctx, c.OrderID)
+	if err != nil {
+		return fmt.Errorf("failed to get payment: %w", err)
+	}
+	if payment.ID != c.PaymentID || payment.Status != models.PaymentStatusSuccess {
+		return nil
+	}
+
+	quote := CancellationQuote{
+		OrderID:  c.OrderID,
+		Captured: payment.Amount,
+		Fee:      payment.Amount - c.Amount,
+		Refund:   c.Amount,
+	}
+	if order.CancellationPolicy != nil {
+		quote.Policy = *order.CancellationPolicy
+	}
+	return so.returnPayment(ctx, quote)
+}
@@ -0,0 +1,67 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"order-service/internal/models"
+	"order-service/internal/store/mocks"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCancelOrderKeepsPaymentWhenTheCancellationFails(t *testing.T) {
+	orders := mocks.NewOrderRepository(t)
+	orders.On("GetOrderByID", mock.Anything, int64(7)).
+		Return(&models.Order{ID: 7, UserID: 9, Status: models.OrderStatusPaid, CreatedAt: time.Now()}, nil).Once()
+	orders.On("SetOrderCancellation", mock.Anything, int64(7), mock.Anything, int64(0)).Return(nil).Once()
+	orders.On("GetOrderItemsByOrderID", mock.Anything, int64(7)).Return([]models.OrderItem{}, nil).Once()
+	orders.On("UpdateOrderStatusFenced", mock.Anything, int64(7), models.OrderStatusCancelled, mock.Anything, mock.Anything).
+		Return(errors.New("connection reset")).Once()
+	payments := mocks.NewPaymentRepository(t)
+	payments.On("GetPaymentByOrderID", mock.Anything, int64(7)).
+		Return(&models.Payment{ID: 3, OrderID: 7, Status: models.PaymentStatusSuccess, Amount: 1000}, nil).Once()
+	groups := mocks.NewPricingRepository(t)
+	groups.On("GetUserCustomerGroup", mock.Anything, int64(9)).Return("", nil).Once()
+	policies, err := NewCancellationPolicies(groups, "")
+	require.NoError(t, err)
+	compensations := mocks.NewCompensationRepository(t)
+
+	so := NewSagaOrchestrator(orders, newTestRedis(t), nil, NewPaymentService(payments, nil), nil, nil, CommitFailurePolicy{})
+	so.SetCancellationPolicies(policies)
+	so.SetCompensations(NewCompensationQueue(compensations, testCompensationPolicy))
+
+	_, err = so.CancelOrder(context.Background(), 7, 9, "changed my mind")
+	assert.Error(t, err)
+	// The order is still paid, so its payment is neither voided nor queued to be
+	payments.AssertNotCalled(t, "UpdatePaymentStatus", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	compensations.AssertNotCalled(t, "CreateCompensation", mock.Anything, mock.Anything)
+}
+
+func TestRetryDueCompensationsSkipsReturnedCancellationPayments(t *testing.T) {
+	orders := mocks.NewOrderRepository(t)
+	orders.On("GetOrderByID", mock.Anything, int64(7)).
+		Return(&models.Order{ID: 7, Status: models.OrderStatusCancelled}, nil).Once()
+	payments := mocks.NewPaymentRepository(t)
+	payments.On("GetPaymentByOrderID", mock.Anything, int64(7)).
+		Return(&models.Payment{ID: 3, OrderID: 7, Status: models.PaymentStatusVoided, Amount: 1000}, nil).Once()
+	compensations := mocks.NewCompensationRepository(t)
+	compensations.On("ClaimDueCompensations", mock.Anything, mock.Anything, compensationClaimTTL, compensationBatchSize).
+		Return([]models.Compensation{{ID: 1, OrderID: 7, Kind: models.CompensationCancellationRefund, PaymentID: 3, Amount: 1000,
+			Status: models.CompensationPending, Attempts: 1}}, nil).Once()
+	compensations.On("UpdateCompensation", mock.Anything, mock.MatchedBy(func(c *models.Compensation) bool {
+		return c.Status == models.CompensationDone
+	})).Return(nil).Once()
+	compensations.On("CountCompensations", mock.Anything, models.CompensationEscalated).Return(0, nil).Once()
+
+	so := NewSagaOrchestrator(orders, nil, nil, NewPaymentService(payments, nil), nil, nil, CommitFailurePolicy{})
+	so.SetCompensations(NewCompensationQueue(compensations, testCompensationPolicy))
+
+	succeeded, err := so.RetryDueCompensations(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, succeeded)
+}
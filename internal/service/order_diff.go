@@ -0,0 +1,184 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"order-service/internal/apperrors"
+	"order-service/internal/models"
+	"order-service/internal/store"
+	"order-service/internal/util"
+)
+
+// OrderDiffer reconstructs an order at two points in time from its status
+// history and the timestamps on its items and payments, and diffs them field
+// by field, so support can see who changed what when without reading the raw
+// audit trail
+type OrderDiffer struct {
+	orders   store.OrderRepository
+	payments store.PaymentRepository
+}
+
+// NewOrderDiffer creates a new order differ
+func NewOrderDiffer(orders store.OrderRepository, payments store.PaymentRepository) *OrderDiffer {
+	return &OrderDiffer{orders: orders, payments: payments}
+}
+
+// OrderFieldChange is one field of an order that differs between two points
+// in time. From or To is nil where the field did not exist yet, e.g. a payment
+// made in between.
+type OrderFieldChange struct {
+	Field string      `json:"field"`
+	From  interface{} `json:"from"`
+	To    interface{} `json:"to"`
+}
+
+// OrderDiff is the field-level difference of an order between From and To,
+// with the status transitions in between and who made them
+type OrderDiff struct {
+	OrderID       int64                       `json:"order_id"`
+	From          time.Time                   `json:"from"`
+	To            time.Time                   `json:"to"`
+	Changes       []OrderFieldChange          `json:"changes"`
+	StatusChanges []models.OrderStatusHistory `json:"status_changes"`
+}
+
+// Diff returns what changed on an order between from and to
+func (d *OrderDiffer) Diff(ctx context.Context, orderID int64, from, to time.Time) (*OrderDiff, error) {
+	ctx, span := util.StartSpan(ctx, "OrderDiffer.Diff")
+	defer span.End()
+
+	if !from.Before(to) {
+		return nil, apperrors.New(apperrors.ErrInvalidRequest, "from must be before to")
+	}
+
+	order, err := d.orders.GetOrderByID(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	items, err := d.orders.GetOrderItemsByOrderID(ctx, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load order items: %w", err)
+	}
+	history, err := d.orders.GetOrderStatusHistory(ctx, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load status history: %w", err)
+	}
+	payments, err := d.payments.GetPaymentsByOrderID(ctx, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load payments: %w", err)
+	}
+
+	return diffOrder(order, items, history, payments, from, to), nil
+}
+
+// diffOrder diffs the snapshots of an order at from and to
+func diffOrder(order *models.Order, items []models.OrderItem, history []models.OrderStatusHistory, payments []models.Payment, from, to time.Time) *OrderDiff {
+	diff := &OrderDiff{
+		OrderID:       order.ID,
+		From:          from,
+		To:            to,
+		Changes:       []OrderFieldChange{},
+		StatusChanges: []models.OrderStatusHistory{},
+	}
+	for _, entry := range history {
+		if entry.CreatedAt.After(from) && !entry.CreatedAt.After(to) {
+			diff.StatusChanges = append(diff.StatusChanges, entry)
+		}
+	}
+
+	before := orderSnapshot(order, items, history, payments, from)
+	after := orderSnapshot(order, items, history, payments, to)
+
+	fields := make([]string, 0, len(after))
+	for field := range after {
+		fields = append(fields, field)
+	}
+	for field := range before {
+		if _, ok := after[field]; !ok {
+			fields = append(fields, field)
+		}
+	}
+	sort.Strings(fields)
+
+	for _, field := range fields {
+		was, wasSet := before[field]
+		is, isSet := after[field]
+		if wasSet == isSet && was == is {
+			continue
+		}
+		change := OrderFieldChange{Field: field}
+		if wasSet {
+			change.From = was
+		}
+		if isSet {
+			change.To = is
+		}
+		diff.Changes = append(diff.Changes, change)
+	}
+	return diff
+}
+
+// orderSnapshot flattens the order as it stood at t into field paths, e.g.
+// items[3].stock_committed. The order fields only set on creation are taken as
+// is. A payment updated after t is shown as it was created, PENDING and
+// nothing refunded, since only its latest state is kept.
+func orderSnapshot(order *models.Order, items []models.OrderItem, history []models.OrderStatusHistory, payments []models.Payment, t time.Time) map[string]interface{} {
+	snapshot := map[string]interface{}{}
+	if order.CreatedAt.After(t) {
+		return snapshot
+	}
+
+	status := ""
+	for _, entry := range history {
+		if !entry.CreatedAt.After(t) {
+			status = entry.NewStatus
+		}
+	}
+	if status != "" {
+		snapshot["status"] = status
+	}
+	snapshot["total_amount"] = order.TotalAmount
+	snapshot["wallet_amount"] = order.WalletAmount
+	snapshot["payment_method"] = order.PaymentMethod
+	if status == models.OrderStatusCancelled && order.CancellationFee != nil {
+		snapshot["cancellation_fee"] = *order.CancellationFee
+	}
+	for field, at := range map[string]*time.Time{
+		"reserved_at":  order.ReservedAt,
+		"paid_at":      order.PaidAt,
+		"confirmed_at": order.ConfirmedAt,
+	} {
+		if at != nil && !at.After(t) {
+			snapshot[field] = at.UTC().Format(time.RFC3339Nano)
+		}
+	}
+
+	for _, item := range items {
+		prefix := "items[" + strconv.FormatInt(item.ID, 10) + "]."
+		snapshot[prefix+"variant_id"] = item.VariantID
+		snapshot[prefix+"quantity"] = item.Quantity
+		snapshot[prefix+"unit_price"] = item.UnitPrice
+		snapshot[prefix+"stock_committed"] = item.StockCommittedAt != nil && !item.StockCommittedAt.After(t)
+	}
+
+	for _, payment := range payments {
+		if payment.CreatedAt.After(t) {
+			continue
+		}
+		prefix := "payments[" + strconv.FormatInt(payment.ID, 10) + "]."
+		snapshot[prefix+"amount"] = payment.Amount
+		snapshot[prefix+"wallet_amount"] = payment.WalletAmount
+		if payment.UpdatedAt.After(t) {
+			snapshot[prefix+"status"] = models.PaymentStatusPending
+			snapshot[prefix+"refunded_amount"] = int64(0)
+		} else {
+			snapshot[prefix+"status"] = payment.Status
+			snapshot[prefix+"refunded_amount"] = payment.RefundedAmount
+		}
+	}
+	return snapshot
+}
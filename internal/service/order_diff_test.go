@@ -0,0 +1,65 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"order-service/internal/apperrors"
+	"order-service/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffOrderReportsChangesBetweenPoints(t *testing.T) {
+	created := time.Date(2026, 10, 14, 10, 0, 0, 0, time.UTC)
+	paid := created.Add(2 * time.Minute)
+	committed := created.Add(3 * time.Minute)
+	reserved := models.OrderStatusReserved
+
+	order := &models.Order{ID: 7, Status: models.OrderStatusConfirmed, TotalAmount: 2500, PaymentMethod: "card",
+		CreatedAt: created, PaidAt: &paid}
+	items := []models.OrderItem{{ID: 1, VariantID: 10, Quantity: 2, UnitPrice: 1250, StockCommittedAt: &committed}}
+	history := []models.OrderStatusHistory{
+		{ID: 1, NewStatus: models.OrderStatusCreated, CreatedAt: created},
+		{ID: 2, NewStatus: models.OrderStatusReserved, CreatedAt: created.Add(time.Second)},
+		{ID: 3, OldStatus: &reserved, NewStatus: models.OrderStatusPaid, Actor: "saga-orchestrator", CreatedAt: paid},
+		{ID: 4, NewStatus: models.OrderStatusConfirmed, CreatedAt: committed},
+	}
+	payments := []models.Payment{{ID: 5, Status: models.PaymentStatusSuccess, Amount: 2500,
+		CreatedAt: created.Add(time.Minute), UpdatedAt: paid}}
+
+	diff := diffOrder(order, items, history, payments, created.Add(30*time.Second), created.Add(150*time.Second))
+
+	assert.Equal(t, []OrderFieldChange{
+		{Field: "paid_at", To: paid.Format(time.RFC3339Nano)},
+		{Field: "payments[5].amount", To: int64(2500)},
+		{Field: "payments[5].refunded_amount", To: int64(0)},
+		{Field: "payments[5].status", To: models.PaymentStatusSuccess},
+		{Field: "payments[5].wallet_amount", To: int64(0)},
+		{Field: "status", From: models.OrderStatusReserved, To: models.OrderStatusPaid},
+	}, diff.Changes)
+	require.Len(t, diff.StatusChanges, 1)
+	assert.Equal(t, "saga-orchestrator", diff.StatusChanges[0].Actor)
+}
+
+func TestDiffOrderShowsPaymentsUpdatedLaterAsCreated(t *testing.T) {
+	created := time.Date(2026, 10, 14, 10, 0, 0, 0, time.UTC)
+	order := &models.Order{ID: 7, CreatedAt: created}
+	payments := []models.Payment{{ID: 5, Status: models.PaymentStatusRefunded, Amount: 2500, RefundedAmount: 2500,
+		CreatedAt: created, UpdatedAt: created.Add(time.Hour)}}
+
+	diff := diffOrder(order, nil, nil, payments, created.Add(time.Minute), created.Add(2*time.Hour))
+
+	assert.Equal(t, []OrderFieldChange{
+		{Field: "payments[5].refunded_amount", From: int64(0), To: int64(2500)},
+		{Field: "payments[5].status", From: models.PaymentStatusPending, To: models.PaymentStatusRefunded},
+	}, diff.Changes)
+}
+
+func TestOrderDiffRequiresFromBeforeTo(t *testing.T) {
+	now := time.Now()
+	_, err := NewOrderDiffer(nil, nil).Diff(context.Background(), 7, now, now)
+	assert.ErrorIs(t, err, apperrors.ErrInvalidRequest)
+}
@@ -2,10 +2,16 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
+	"unicode/utf8"
 
+	"order-service/internal/apperrors"
 	"order-service/internal/broker"
+	"order-service/internal/fraud"
 	"order-service/internal/models"
 	"order-service/internal/redisclient"
 	"order-service/internal/store"
@@ -17,47 +23,192 @@ import (
 
 // OrderService handles order business logic
 type OrderService struct {
-	store           *store.Store
+	orders          store.OrderRepository
 	redis           *redisclient.Client
 	eventPublisher  *broker.EventPublisher
 	inventoryClient *InventoryClient
+	orderCache      *OrderCache
+	productCache    *ProductCache
+	pricingService  *PricingService
+	timeoutPolicy   *TimeoutPolicy
+	slaTracker      *SLATracker
+	statusFeed      *OrderStatusFeed
+	reminders       ReminderPolicy
+	backorders      bool
+	incomingStock   *IncomingStockService
+	riskScorer      fraud.Scorer
+	riskBands       fraud.Bands
+	killSwitches    *KillSwitches
+	wallets         *WalletService
+	customers       *CustomerService
+	availabilityLag int64
+	flashOrders     *FlashOrders
 	logger          *zap.Logger
 }
 
 // NewOrderService creates a new order service
 func NewOrderService(
-	store *store.Store,
+	orders store.OrderRepository,
 	redis *redisclient.Client,
 	eventPublisher *broker.EventPublisher,
 	inventoryClient *InventoryClient,
+	orderCache *OrderCache,
+	productCache *ProductCache,
+	pricingService *PricingService,
+	timeoutPolicy *TimeoutPolicy,
 ) *OrderService {
 	return &OrderService{
-		store:           store,
+		orders:          orders,
 		redis:           redis,
 		eventPublisher:  eventPublisher,
 		inventoryClient: inventoryClient,
+		orderCache:      orderCache,
+		productCache:    productCache,
+		pricingService:  pricingService,
+		timeoutPolicy:   timeoutPolicy,
 		logger:          util.GetLogger(),
 	}
 }
 
+// SetSLATracker enables lifecycle SLA tracking
+func (s *OrderService) SetSLATracker(tracker *SLATracker) {
+	s.slaTracker = tracker
+}
+
+// SetStatusFeed broadcasts status transitions to order tracking streams
+func (s *OrderService) SetStatusFeed(feed *OrderStatusFeed) {
+	s.statusFeed = feed
+}
+
+// ReminderPolicy controls the reminders scheduled for reserved orders; a zero
+// duration disables a reminder
+type ReminderPolicy struct {
+	// PaymentReminderAfter is how long after reservation an unpaid order gets a PaymentReminder
+	PaymentReminderAfter time.Duration
+	// ExpiryWarningBefore is how long before its deadline an unpaid order gets a ReservationExpiring
+	ExpiryWarningBefore time.Duration
+}
+
+// SetReminders schedules payment reminders and expiry warnings for reserved
+// orders; the event publisher must have a scheduler
+func (s *OrderService) SetReminders(policy ReminderPolicy) {
+	s.reminders = policy
+}
+
+// SetBackorders backorders out-of-stock items instead of failing the order;
+// a BackorderFulfiller must run to reserve them once restocked
+func (s *OrderService) SetBackorders(enabled bool) {
+	s.backorders = enabled
+}
+
+// SetIncomingStock plans backordered items against incoming stock so new
+// orders are quoted an expected ship date
+func (s *OrderService) SetIncomingStock(incoming *IncomingStockService) {
+	s.incomingStock = incoming
+}
+
+// SetRiskScorer scores each new order for fraud risk and stores the score and
+// its band on the order
+func (s *OrderService) SetRiskScorer(scorer fraud.Scorer, bands fraud.Bands) {
+	s.riskScorer = scorer
+	s.riskBands = bands
+}
+
+// SetKillSwitches rejects new orders for SKUs or payment methods blocked by
+// a kill switch
+func (s *OrderService) SetKillSwitches(killSwitches *KillSwitches) {
+	s.killSwitches = killSwitches
+}
+
+// SetWallets lets orders be paid, in part or in full, from the wallets of users
+func (s *OrderService) SetWallets(wallets *WalletService) {
+	s.wallets = wallets
+}
+
+// SetCustomers checks that the customer of each new order is active and
+// records their email and name on the order
+func (s *OrderService) SetCustomers(customers *CustomerService) {
+	s.customers = customers
+}
+
+// SetAvailabilityMaxLag rejects orders whose consistency token is more than
+// maxLag inventory changes behind, so the storefront refreshes availability;
+// 0 disables the check
+func (s *OrderService) SetAvailabilityMaxLag(maxLag int64) {
+	s.availabilityLag = maxLag
+}
+
+// SetFlashOrders accepts orders in flash-sale mode: reserved in Redis alone and
+// persisted asynchronously, answered with an order token
+func (s *OrderService) SetFlashOrders(flashOrders *FlashOrders) {
+	s.flashOrders = flashOrders
+}
+
 // CreateOrderRequest represents a request to create an order
 type CreateOrderRequest struct {
 	UserID         int64              `json:"user_id" binding:"required"`
 	Items          []OrderItemRequest `json:"items" binding:"required,min=1"`
 	PaymentMethod  string             `json:"payment_method" binding:"required"`
 	IdempotencyKey string             `json:"idempotency_key,omitempty"`
+	// Currency is the ISO 4217 code the client priced the order in, checked
+	// against the currencies the store sells in
+	Currency string `json:"currency,omitempty"`
+	// Priority is standard, the default, or express to process the order
+	// ahead of the backlog, e.g. for paid memberships
+	Priority string `json:"priority,omitempty"`
+
+	// AllowMixedPricing permits combining contract and retail prices in one order
+	AllowMixedPricing bool `json:"allow_mixed_pricing,omitempty"`
+
+	// AllowPartial creates the order without items whose product disappeared
+	// mid-request instead of failing it
+	AllowPartial bool `json:"allow_partial,omitempty"`
+
+	// Billing invoices a B2B order to a company under its tax ID
+	Billing *models.BillingDetails `json:"billing,omitempty"`
+
+	// ShipTo is where the order ships, to allocate its stock from the nearest
+	// warehouse under the nearest allocation strategy
+	ShipTo *models.GeoPoint `json:"ship_to,omitempty"`
+
+	// WalletAmount of the total is paid from the user's wallet and the rest
+	// with PaymentMethod. The "wallet" payment method pays it all.
+	WalletAmount int64 `json:"wallet_amount,omitempty"`
+
+	// ConsistencyToken is the token of the availability the order was placed
+	// from, to reject orders placed from stale availability up front
+	ConsistencyToken int64 `json:"consistency_token,omitempty"`
+
+	// Synthetic marks internal probe orders; never bound from client input
+	Synthetic bool `json:"-"`
+
+	// NoBackorder fails the order when stock runs out even with backorders
+	// enabled, e.g. for product drops; never bound from client input
+	NoBackorder bool `json:"-"`
 }
 
 // OrderItemRequest represents an item in an order
 type OrderItemRequest struct {
 	ProductID int64 `json:"product_id" binding:"required"`
+	// VariantID is the variant ordered, the product's default variant when unset
+	VariantID int64 `json:"variant_id,omitempty"`
 	Quantity  int   `json:"quantity" binding:"required,min=1"`
 }
 
 // CreateOrderResponse represents the response after creating an order
 type CreateOrderResponse struct {
-	OrderID int64  `json:"order_id"`
-	Status  string `json:"status"`
+	OrderID int64 `json:"order_id,omitempty"`
+	// OrderToken identifies an order accepted in flash-sale mode until it is
+	// persisted; OrderID is unset until then
+	OrderToken   string                    `json:"order_token,omitempty"`
+	Status       string                    `json:"status"`
+	SkippedItems []models.SkippedOrderItem `json:"skipped_items,omitempty"`
+
+	// BackorderedItems were out of stock and will be reserved once restocked
+	BackorderedItems []OrderItemRequest `json:"backordered_items,omitempty"`
+	// ExpectedShipDate is when incoming stock covers every backordered item,
+	// unset if it does not
+	ExpectedShipDate *time.Time `json:"expected_ship_date,omitempty"`
 }
 
 // CreateOrder creates a new order with saga orchestration
@@ -65,11 +216,18 @@ func (s *OrderService) CreateOrder(ctx context.Context, req *CreateOrderRequest)
 	ctx, span := util.StartSpan(ctx, "OrderService.CreateOrder")
 	defer span.End()
 
+	if s.flashOrders.accepts(ctx, req) {
+		resp, err := s.flashOrders.Accept(ctx, req)
+		if !errors.Is(err, errFlashIneligible) {
+			return resp, err
+		}
+	}
+
 	if req.IdempotencyKey == "" {
 		req.IdempotencyKey = uuid.New().String()
 	}
 
-	existingOrder, err := s.store.GetOrderByIdempotencyKey(ctx, req.IdempotencyKey)
+	existingOrder, err := s.orders.GetOrderByIdempotencyKey(ctx, req.IdempotencyKey)
 	if err != nil {
 		return nil, fmt.Errorf("failed to check idempotency: %w", err)
 	}
@@ -83,48 +241,115 @@ func (s *OrderService) CreateOrder(ctx context.Context, req *CreateOrderRequest)
 		}, nil
 	}
 
-	products, err := s.validateOrderItems(ctx, req.Items)
+	if err := s.checkConsistencyToken(ctx, req.ConsistencyToken); err != nil {
+		util.OrdersFailedTotal.WithLabelValues("stale_availability").Inc()
+		return nil, err
+	}
+
+	billing, err := normalizeBilling(req.Billing)
+	if err != nil {
+		util.OrdersFailedTotal.WithLabelValues("invalid_billing").Inc()
+		return nil, err
+	}
+
+	var customer *models.Customer
+	if s.customers != nil && !req.Synthetic {
+		if customer, err = s.customers.CheckCanOrder(ctx, req.UserID); err != nil {
+			util.OrdersFailedTotal.WithLabelValues("customer").Inc()
+			return nil, err
+		}
+	}
+
+	products, variants, err := s.validateOrderItems(ctx, req.Items)
 	if err != nil {
 		util.OrdersFailedTotal.WithLabelValues("invalid_items").Inc()
 		return nil, err
 	}
 
+	if s.killSwitches != nil {
+		if err := s.killSwitches.Check(ctx, req.PaymentMethod, orderedSKUs(req.Items, products, variants)); err != nil {
+			util.OrdersFailedTotal.WithLabelValues("kill_switch").Inc()
+			return nil, err
+		}
+	}
+
+	priceListID, err := s.pricingService.ApplyPriceList(ctx, req.UserID, products, req.AllowMixedPricing)
+	if err != nil {
+		util.OrdersFailedTotal.WithLabelValues("pricing").Inc()
+		return nil, err
+	}
+
 	totalAmount := s.calculateTotal(req.Items, products)
+	walletAmount, err := s.walletAmount(ctx, req, totalAmount)
+	if err != nil {
+		util.OrdersFailedTotal.WithLabelValues("wallet").Inc()
+		return nil, err
+	}
+	expiresAt := s.timeoutPolicy.Deadline(req.PaymentMethod, time.Now()).UTC()
 
 	order := &models.Order{
 		UserID:         req.UserID,
 		TotalAmount:    totalAmount,
 		Status:         models.OrderStatusCreated,
 		IdempotencyKey: req.IdempotencyKey,
+		PaymentMethod:  req.PaymentMethod,
+		ExpiresAt:      &expiresAt,
+		Synthetic:      req.Synthetic,
+		PriceListID:    priceListID,
+		WalletAmount:   walletAmount,
+		Priority:       models.PriorityStandard,
+	}
+	if req.Priority == models.PriorityExpress {
+		order.Priority = models.PriorityExpress
+		ctx = broker.WithPriority(ctx, order.Priority)
+	}
+	if billing != nil {
+		order.BillingCompany, order.BillingCountry, order.TaxID = &billing.Company, &billing.Country, &billing.TaxID
+	}
+	if customer != nil {
+		order.CustomerEmail, order.CustomerName = &customer.Email, &customer.Name
 	}
 
-	if err := s.store.CreateOrder(ctx, order); err != nil {
+	s.scoreRisk(ctx, order, req.Items)
+
+	items := make([]*models.OrderItem, 0, len(req.Items))
+	for _, item := range req.Items {
+		items = append(items, &models.OrderItem{
+			ProductID: item.ProductID,
+			VariantID: item.VariantID,
+			Quantity:  item.Quantity,
+			UnitPrice: products[item.ProductID].Price,
+		})
+	}
+
+	skipped, err := s.orders.CreateOrderWithItems(ctx, order, items, req.AllowPartial)
+	if err != nil {
 		util.OrdersFailedTotal.WithLabelValues("db_error").Inc()
 		return nil, fmt.Errorf("failed to create order: %w", err)
 	}
+	if len(skipped) > 0 {
+		req.Items = withoutSkippedItems(req.Items, skipped)
+		util.OrderItemsSkippedTotal.Add(float64(len(skipped)))
+		s.logger.Warn("Order created without unavailable items",
+			zap.Int64("order_id", order.ID),
+			zap.Int("skipped", len(skipped)))
+	}
 
 	util.OrdersCreatedTotal.Inc()
+	util.OrdersByPriorityTotal.WithLabelValues(order.Priority).Inc()
+	if order.RiskScore != nil {
+		util.OrderRiskScore.Observe(float64(*order.RiskScore))
+		util.OrdersByRiskBandTotal.WithLabelValues(*order.RiskBand).Inc()
+	}
 	s.logger.Info("Order created", zap.Int64("order_id", order.ID))
 
-	// Create order items
 	orderItems := make([]models.OrderItemData, 0, len(req.Items))
 	for _, item := range req.Items {
-		product := products[item.ProductID]
-		orderItem := &models.OrderItem{
-			OrderID:   order.ID,
-			ProductID: item.ProductID,
-			Quantity:  item.Quantity,
-			UnitPrice: product.Price,
-		}
-
-		if err := s.store.CreateOrderItem(ctx, orderItem); err != nil {
-			return nil, fmt.Errorf("failed to create order item: %w", err)
-		}
-
 		orderItems = append(orderItems, models.OrderItemData{
 			ProductID: item.ProductID,
+			VariantID: item.VariantID,
 			Quantity:  item.Quantity,
-			UnitPrice: product.Price,
+			UnitPrice: products[item.ProductID].Price,
 		})
 	}
 
@@ -138,21 +363,40 @@ func (s *OrderService) CreateOrder(ctx context.Context, req *CreateOrderRequest)
 		UserID:      order.UserID,
 		TotalAmount: order.TotalAmount,
 		Items:       orderItems,
+		Synthetic:   order.Synthetic,
+		RiskScore:   order.RiskScore,
+		Billing:     order.Billing(),
+	}
+	if order.RiskBand != nil {
+		event.RiskBand = *order.RiskBand
 	}
 
 	if err := s.eventPublisher.PublishOrderCreated(ctx, event); err != nil {
 		s.logger.Error("Failed to publish OrderCreated event", zap.Error(err))
 	}
 
-	if err := s.reserveInventory(ctx, order.ID, req.Items); err != nil {
-		_ = s.store.UpdateOrderStatus(ctx, order.ID, models.OrderStatusFailed)
+	if req.ShipTo != nil {
+		ctx = WithShipTo(ctx, *req.ShipTo)
+	}
+	backordered, err := s.reserveInventory(ctx, order.ID, req.Items, s.backorders && !req.NoBackorder)
+	if err != nil {
+		_ = s.orders.UpdateOrderStatus(ctx, order.ID, models.OrderStatusFailed, models.StatusChange{
+			Reason: "reservation_failed: " + err.Error(),
+			Actor:  models.ActorOrderService,
+		})
+		s.orderCache.Invalidate(ctx, order.ID)
+		s.statusFeed.Publish(ctx, order.ID, models.OrderStatusFailed)
 		util.OrdersFailedTotal.WithLabelValues("reservation_failed").Inc()
 		return nil, fmt.Errorf("inventory reservation failed: %w", err)
 	}
 
-	if err := s.store.UpdateOrderStatus(ctx, order.ID, models.OrderStatusReserved); err != nil {
+	reserved := models.StatusChange{Reason: "inventory_reserved", Actor: models.ActorOrderService}
+	if err := s.orders.UpdateOrderStatus(ctx, order.ID, models.OrderStatusReserved, reserved); err != nil {
 		return nil, fmt.Errorf("failed to update order status: %w", err)
 	}
+	s.orderCache.Invalidate(ctx, order.ID)
+	s.statusFeed.Publish(ctx, order.ID, models.OrderStatusReserved)
+	s.slaTracker.Record(ctx, order.ID, StageReservation)
 
 	util.OrdersReservedTotal.Inc()
 
@@ -162,74 +406,261 @@ func (s *OrderService) CreateOrder(ctx context.Context, req *CreateOrderRequest)
 			EventType: models.EventTypeOrderReserved,
 			Timestamp: time.Now(),
 		},
-		OrderID:     order.ID,
-		UserID:      order.UserID,
-		TotalAmount: order.TotalAmount,
-		Items:       orderItems,
+		OrderID:      order.ID,
+		UserID:       order.UserID,
+		TotalAmount:  order.TotalAmount,
+		WalletAmount: order.WalletAmount,
+		Items:        orderItems,
+		Synthetic:    order.Synthetic,
 	}
 
 	if err := s.eventPublisher.PublishOrderReserved(ctx, reservedEvent); err != nil {
 		s.logger.Error("Failed to publish OrderReserved event", zap.Error(err))
 	}
+	var shipDate *time.Time
+	if len(backordered) > 0 {
+		shipDate = s.planBackorders(ctx, order.ID, backordered)
+		s.publishBackordered(ctx, order, backordered, products, shipDate)
+	}
+	if !order.Synthetic {
+		s.scheduleReminders(ctx, order)
+	}
 
 	return &CreateOrderResponse{
-		OrderID: order.ID,
-		Status:  models.OrderStatusReserved,
+		OrderID:          order.ID,
+		Status:           models.OrderStatusReserved,
+		SkippedItems:     skipped,
+		BackorderedItems: backordered,
+		ExpectedShipDate: shipDate,
 	}, nil
 }
 
-// reserveInventory reserves inventory for order items
-func (s *OrderService) reserveInventory(ctx context.Context, orderID int64, items []OrderItemRequest) error {
+// planBackorders quotes when the backordered items of a new order ship. The
+// order goes ahead unquoted if planning fails.
+func (s *OrderService) planBackorders(ctx context.Context, orderID int64, backordered []OrderItemRequest) *time.Time {
+	if s.incomingStock == nil {
+		return nil
+	}
+	variantIDs := make([]int64, len(backordered))
+	for i, item := range backordered {
+		variantIDs[i] = item.VariantID
+	}
+
+	shipDate, err := s.incomingStock.PlanBackorders(ctx, orderID, variantIDs)
+	if err != nil {
+		s.logger.Warn("Failed to plan backordered items", zap.Int64("order_id", orderID), zap.Error(err))
+		return nil
+	}
+	return shipDate
+}
+
+// publishBackordered announces the items of a new order that were backordered
+func (s *OrderService) publishBackordered(ctx context.Context, order *models.Order, backordered []OrderItemRequest, products map[int64]*models.Product, shipDate *time.Time) {
+	items := make([]models.OrderItemData, 0, len(backordered))
+	for _, item := range backordered {
+		items = append(items, models.OrderItemData{
+			ProductID: item.ProductID,
+			VariantID: item.VariantID,
+			Quantity:  item.Quantity,
+			UnitPrice: products[item.ProductID].Price,
+		})
+	}
+
+	event := &models.OrderBackorderedEvent{
+		BaseEvent: models.BaseEvent{
+			EventID:   uuid.New().String(),
+			EventType: models.EventTypeOrderBackordered,
+			Timestamp: time.Now(),
+		},
+		OrderID:          order.ID,
+		UserID:           order.UserID,
+		Items:            items,
+		ExpectedShipDate: shipDate,
+	}
+	if err := s.eventPublisher.PublishOrderBackordered(ctx, event); err != nil {
+		s.logger.Error("Failed to publish OrderBackordered event", zap.Error(err))
+	}
+}
+
+// scheduleReminders schedules the payment reminder and expiry warning of a
+// reserved order. They are dropped when due if the order was paid or cancelled.
+func (s *OrderService) scheduleReminders(ctx context.Context, order *models.Order) {
+	if order.ExpiresAt == nil {
+		return
+	}
+	now := time.Now()
+
+	if after := s.reminders.PaymentReminderAfter; after > 0 && now.Add(after).Before(*order.ExpiresAt) {
+		at := now.Add(after)
+		event := &models.PaymentReminderEvent{
+			BaseEvent: models.BaseEvent{
+				EventID:   uuid.New().String(),
+				EventType: models.EventTypePaymentReminder,
+				Timestamp: at,
+			},
+			OrderID:       order.ID,
+			UserID:        order.UserID,
+			PaymentMethod: order.PaymentMethod,
+			ExpiresAt:     *order.ExpiresAt,
+		}
+		if err := s.eventPublisher.SchedulePaymentReminder(ctx, at, event); err != nil {
+			s.logger.Error("Failed to schedule PaymentReminder event", zap.Int64("order_id", order.ID), zap.Error(err))
+		}
+	}
+
+	if before := s.reminders.ExpiryWarningBefore; before > 0 && order.ExpiresAt.Add(-before).After(now) {
+		at := order.ExpiresAt.Add(-before)
+		event := &models.ReservationExpiringEvent{
+			BaseEvent: models.BaseEvent{
+				EventID:   uuid.New().String(),
+				EventType: models.EventTypeReservationExpiring,
+				Timestamp: at,
+			},
+			OrderID:   order.ID,
+			UserID:    order.UserID,
+			ExpiresAt: *order.ExpiresAt,
+		}
+		if err := s.eventPublisher.ScheduleReservationExpiring(ctx, at, event); err != nil {
+			s.logger.Error("Failed to schedule ReservationExpiring event", zap.Int64("order_id", order.ID), zap.Error(err))
+		}
+	}
+}
+
+// scoreRisk stores the fraud risk score and band of a new order on it. Scoring
+// is best effort: an order the scorer fails on is left unscored, not rejected.
+func (s *OrderService) scoreRisk(ctx context.Context, order *models.Order, items []OrderItemRequest) {
+	if s.riskScorer == nil || order.Synthetic {
+		return
+	}
+
+	units := 0
+	for _, item := range items {
+		units += item.Quantity
+	}
+	score, err := s.riskScorer.Score(ctx, fraud.Order{
+		UserID:        order.UserID,
+		TotalAmount:   order.TotalAmount,
+		PaymentMethod: order.PaymentMethod,
+		Units:         units,
+	})
+	if err != nil {
+		s.logger.Warn("Failed to score order risk", zap.Int64("user_id", order.UserID), zap.Error(err))
+		return
+	}
+
+	band := s.riskBands.Band(score)
+	order.RiskScore = &score
+	order.RiskBand = &band
+}
+
+// withoutSkippedItems drops the items left out of the order
+func withoutSkippedItems(items []OrderItemRequest, skipped []models.SkippedOrderItem) []OrderItemRequest {
+	gone := make(map[int64]bool, len(skipped))
+	for _, item := range skipped {
+		gone[item.VariantID] = true
+	}
+
+	kept := make([]OrderItemRequest, 0, len(items))
+	for _, item := range items {
+		if !gone[item.VariantID] {
+			kept = append(kept, item)
+		}
+	}
+	return kept
+}
+
+// reserveInventory reserves inventory for order items. With backorders allowed
+// out-of-stock items are marked backordered and returned instead of failing the order.
+func (s *OrderService) reserveInventory(ctx context.Context, orderID int64, items []OrderItemRequest, backorders bool) ([]OrderItemRequest, error) {
 	timer := util.InventoryReserveLatency
 	start := time.Now()
 	defer func() {
 		timer.Observe(time.Since(start).Seconds())
 	}()
 
+	if reservation := flashReservationFrom(ctx); reservation != nil {
+		if err := s.inventoryClient.AdoptReservations(ctx, orderID, reservation.units); err != nil {
+			util.InventoryReservationsFailed.WithLabelValues("error").Inc()
+			return nil, fmt.Errorf("failed to adopt flash order reservations: %w", err)
+		}
+		reservation.adopted = true
+		return nil, nil
+	}
+
+	var backordered []OrderItemRequest
 	for _, item := range items {
-		success, err := s.inventoryClient.ReserveStock(ctx, item.ProductID, item.Quantity)
+		success, err := s.inventoryClient.ReserveStock(ctx, orderID, item.VariantID, item.Quantity)
 		if err != nil {
 			util.InventoryReservationsFailed.WithLabelValues("error").Inc()
 			s.compensateReservations(ctx, orderID, items)
-			return fmt.Errorf("failed to reserve stock for product %d: %w", item.ProductID, err)
+			return nil, fmt.Errorf("failed to reserve stock for variant %d of product %d: %w", item.VariantID, item.ProductID, err)
 		}
 
 		if !success {
+			if backorders {
+				backordered = append(backordered, item)
+				continue
+			}
 			util.InventoryReservationsFailed.WithLabelValues("insufficient_stock").Inc()
 			s.compensateReservations(ctx, orderID, items)
-			return fmt.Errorf("insufficient stock for product %d", item.ProductID)
+			return nil, apperrors.New(apperrors.ErrInsufficientStock, "insufficient stock for variant %d of product %d", item.VariantID, item.ProductID)
 		}
 	}
 
-	return nil
+	if len(backordered) > 0 {
+		variantIDs := make([]int64, len(backordered))
+		for i, item := range backordered {
+			variantIDs[i] = item.VariantID
+		}
+		if err := s.orders.MarkOrderItemsBackordered(ctx, orderID, variantIDs); err != nil {
+			s.compensateReservations(ctx, orderID, items)
+			return nil, fmt.Errorf("failed to backorder items: %w", err)
+		}
+		util.OrderItemsBackorderedTotal.Add(float64(len(backordered)))
+	}
+
+	return backordered, nil
 }
 
-// compensateReservations rolls back inventory reservations
+// compensateReservations rolls back the inventory reservations the order holds
 func (s *OrderService) compensateReservations(ctx context.Context, orderID int64, items []OrderItemRequest) {
 	for _, item := range items {
-		if err := s.inventoryClient.ReleaseStock(ctx, item.ProductID, item.Quantity); err != nil {
+		if err := s.inventoryClient.ReleaseStock(ctx, orderID, item.VariantID); err != nil {
 			s.logger.Error("Failed to compensate reservation",
 				zap.Int64("order_id", orderID),
-				zap.Int64("product_id", item.ProductID),
+				zap.Int64("variant_id", item.VariantID),
 				zap.Error(err))
 		}
 	}
 }
 
-// validateOrderItems validates that all products exist
-func (s *OrderService) validateOrderItems(ctx context.Context, items []OrderItemRequest) (map[int64]*models.Product, error) {
-	productIDs := make([]int64, len(items))
-	for i, item := range items {
-		productIDs[i] = item.ProductID
+// validateOrderItems validates that all products exist and resolves every
+// item to a variant of its product, returning the products and variants by ID
+func (s *OrderService) validateOrderItems(ctx context.Context, items []OrderItemRequest) (map[int64]*models.Product, map[int64]*models.ProductVariant, error) {
+	seen := make(map[int64]bool, len(items))
+	productIDs := make([]int64, 0, len(items))
+	for _, item := range items {
+		if !seen[item.ProductID] {
+			seen[item.ProductID] = true
+			productIDs = append(productIDs, item.ProductID)
+		}
 	}
 
-	products, err := s.store.GetProductsByIDs(ctx, productIDs)
+	products, err := s.productCache.GetProductsByIDs(ctx, productIDs)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	if len(products) != len(items) {
-		return nil, fmt.Errorf("some products not found")
+	if len(products) != len(productIDs) {
+		return nil, nil, apperrors.New(apperrors.ErrProductNotFound, "some products not found")
+	}
+
+	variants, err := s.inventoryClient.GetProductVariants(ctx, productIDs)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load product variants: %w", err)
+	}
+	if err := resolveVariants(items, variants); err != nil {
+		return nil, nil, err
 	}
 
 	productMap := make(map[int64]*models.Product)
@@ -237,7 +668,107 @@ func (s *OrderService) validateOrderItems(ctx context.Context, items []OrderItem
 		productMap[products[i].ID] = &products[i]
 	}
 
-	return productMap, nil
+	variantMap := make(map[int64]*models.ProductVariant, len(variants))
+	for i := range variants {
+		variantMap[variants[i].ID] = &variants[i]
+	}
+
+	return productMap, variantMap, nil
+}
+
+// orderedSKUs lists the SKUs of the ordered variants and of their products,
+// so blocking a product's SKU blocks all of its variants
+func orderedSKUs(items []OrderItemRequest, products map[int64]*models.Product, variants map[int64]*models.ProductVariant) []string {
+	seen := make(map[string]bool, 2*len(items))
+	skus := make([]string, 0, 2*len(items))
+	for _, item := range items {
+		for _, sku := range []string{products[item.ProductID].SKU, variants[item.VariantID].SKU} {
+			if sku != "" && !seen[sku] {
+				seen[sku] = true
+				skus = append(skus, sku)
+			}
+		}
+	}
+	return skus
+}
+
+// resolveVariants sets the variant of items ordered without one to their
+// product's default variant. Variants of another product and variants ordered
+// twice are rejected.
+func resolveVariants(items []OrderItemRequest, variants []models.ProductVariant) error {
+	defaults := make(map[int64]int64)
+	owners := make(map[int64]int64, len(variants))
+	for _, variant := range variants {
+		owners[variant.ID] = variant.ProductID
+		if variant.IsDefault {
+			defaults[variant.ProductID] = variant.ID
+		}
+	}
+
+	ordered := make(map[int64]bool, len(items))
+	for i := range items {
+		item := &items[i]
+		switch {
+		case item.VariantID == 0 && defaults[item.ProductID] == 0:
+			return apperrors.New(apperrors.ErrProductNotFound, "product %d has no default variant", item.ProductID)
+		case item.VariantID == 0:
+			item.VariantID = defaults[item.ProductID]
+		case owners[item.VariantID] != item.ProductID:
+			return apperrors.New(apperrors.ErrProductNotFound, "variant %d not found for product %d", item.VariantID, item.ProductID)
+		}
+
+		if ordered[item.VariantID] {
+			return apperrors.New(apperrors.ErrInvalidRequest, "variant %d is ordered more than once", item.VariantID)
+		}
+		ordered[item.VariantID] = true
+	}
+	return nil
+}
+
+// checkConsistencyToken fails with ErrStaleAvailability when token is too far
+// behind the inventory version. Orders without a token, or placed while the
+// version cannot be read, are let through to reservation.
+func (s *OrderService) checkConsistencyToken(ctx context.Context, token int64) error {
+	if token == 0 || s.availabilityLag == 0 {
+		return nil
+	}
+	version, err := s.inventoryClient.ConsistencyToken(ctx)
+	if err != nil {
+		s.logger.Warn("Failed to read inventory version", zap.Error(err))
+		return nil
+	}
+	if lag := version - token; lag > s.availabilityLag {
+		return apperrors.New(apperrors.ErrStaleAvailability,
+			"availability is %d inventory changes behind, refresh it and order again", lag)
+	}
+	return nil
+}
+
+// walletAmount validates the wallet share of an order against its total and
+// the user's balance. The balance is only checked up front to fail early; it
+// is debited when the order is paid.
+func (s *OrderService) walletAmount(ctx context.Context, req *CreateOrderRequest, total int64) (int64, error) {
+	amount := req.WalletAmount
+	if req.PaymentMethod == models.PaymentMethodWallet {
+		amount = total
+	}
+	switch {
+	case amount == 0:
+		return 0, nil
+	case amount < 0 || amount > total:
+		return 0, apperrors.New(apperrors.ErrInvalidRequest, "wallet_amount must be between 0 and the order total %d", total)
+	case s.wallets == nil:
+		return 0, apperrors.New(apperrors.ErrPaymentMethodDisabled, "wallet payments are disabled")
+	}
+
+	wallet, err := s.wallets.Balance(ctx, req.UserID)
+	if err != nil {
+		return 0, err
+	}
+	if wallet.Balance < amount {
+		return 0, apperrors.New(apperrors.ErrInsufficientBalance, "wallet balance %d is less than %d", wallet.Balance, amount)
+	}
+	return amount, nil
 }
 
 // calculateTotal calculates the total amount for an order
@@ -250,17 +781,176 @@ func (s *OrderService) calculateTotal(items []OrderItemRequest, products map[int
 	return total
 }
 
-// GetOrder retrieves an order by ID
+// GetOrder retrieves an order by ID, serving from the read cache when possible
 func (s *OrderService) GetOrder(ctx context.Context, orderID int64) (*models.Order, []models.OrderItem, error) {
-	order, err := s.store.GetOrderByID(ctx, orderID)
+	if order, items, ok := s.orderCache.Get(ctx, orderID); ok {
+		return order, items, nil
+	}
+
+	generation, cacheable := s.orderCache.Generation(ctx, orderID)
+	order, err := s.orders.GetOrderByID(ctx, orderID)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	items, err := s.store.GetOrderItemsByOrderID(ctx, orderID)
+	items, err := s.orders.GetOrderItemsByOrderID(ctx, orderID)
 	if err != nil {
 		return nil, nil, err
 	}
 
+	if cacheable {
+		s.orderCache.Set(ctx, order, items, generation)
+	}
 	return order, items, nil
 }
+
+// GetOrderUncached reads an order from the database, bypassing the order cache,
+// for checks that must not pass on a stale copy
+func (s *OrderService) GetOrderUncached(ctx context.Context, orderID int64) (*models.Order, error) {
+	return s.orders.GetOrderByID(ctx, orderID)
+}
+
+// MaxBulkGetOrders is how many orders BulkGetOrders returns in one call
+const MaxBulkGetOrders = 500
+
+// BulkGetOrdersResponse is the orders found by BulkGetOrders and the IDs not found
+type BulkGetOrdersResponse struct {
+	Orders  []models.OrderWithItems `json:"orders"`
+	Missing []int64                 `json:"missing"`
+}
+
+// BulkGetOrders retrieves up to MaxBulkGetOrders orders with their items in
+// one round trip, for services that would otherwise call GetOrder in a loop
+func (s *OrderService) BulkGetOrders(ctx context.Context, ids []int64) (*BulkGetOrdersResponse, error) {
+	if len(ids) == 0 {
+		return nil, apperrors.New(apperrors.ErrInvalidRequest, "order_ids must not be empty")
+	}
+	if len(ids) > MaxBulkGetOrders {
+		return nil, apperrors.New(apperrors.ErrInvalidRequest, "at most %d order_ids can be fetched at once", MaxBulkGetOrders)
+	}
+
+	unique := make([]int64, 0, len(ids))
+	seen := make(map[int64]bool, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+
+	orders, err := s.orders.GetOrdersWithItems(ctx, unique)
+	if err != nil {
+		return nil, err
+	}
+
+	found := make(map[int64]bool, len(orders))
+	for _, order := range orders {
+		found[order.Order.ID] = true
+	}
+	resp := &BulkGetOrdersResponse{Orders: orders, Missing: []int64{}}
+	for _, id := range unique {
+		if !found[id] {
+			resp.Missing = append(resp.Missing, id)
+		}
+	}
+	return resp, nil
+}
+
+// OrderAdminView is the operator view of an order including SLA stage timings
+type OrderAdminView struct {
+	Order       *models.Order      `json:"order"`
+	Customer    OrderPersonalData  `json:"customer"`
+	Items       []models.OrderItem `json:"items"`
+	SLA         []SLAStage         `json:"sla"`
+	SLABreached bool               `json:"sla_breached"`
+}
+
+// OrderPersonalData is the customer's personal data recorded on an order,
+// which order resources leave out so that only staff see it
+type OrderPersonalData struct {
+	Email *string `json:"email,omitempty"`
+	Name  *string `json:"name,omitempty"`
+	TaxID *string `json:"tax_id,omitempty"`
+}
+
+// GetOrderAdminView loads an order fresh from the database with its SLA timings
+func (s *OrderService) GetOrderAdminView(ctx context.Context, orderID int64) (*OrderAdminView, error) {
+	order, err := s.orders.GetOrderByID(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	items, err := s.orders.GetOrderItemsByOrderID(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	view := &OrderAdminView{
+		Order:       order,
+		Customer:    OrderPersonalData{Email: order.CustomerEmail, Name: order.CustomerName, TaxID: order.TaxID},
+		Items:       items,
+		SLABreached: order.SLABreached,
+	}
+	if s.slaTracker != nil {
+		view.SLA = s.slaTracker.Evaluate(order, time.Now())
+		for _, stage := range view.SLA {
+			view.SLABreached = view.SLABreached || stage.Breached
+		}
+	}
+	return view, nil
+}
+
+// ListOrders returns the summaries of the orders matching filter, riskiest first
+func (s *OrderService) ListOrders(ctx context.Context, filter models.OrderFilter) ([]models.OrderSummary, error) {
+	return s.orders.ListOrders(ctx, filter)
+}
+
+// minSearchQueryLength is the shortest text query the trigram indexes can match
+const minSearchQueryLength = 3
+
+// SearchOrders finds orders for support tooling by idempotency key, payment
+// provider transaction ID, product or variant name or SKU, and by order or
+// user ID for a numeric query, most relevant first
+func (s *OrderService) SearchOrders(ctx context.Context, query string, limit int) ([]models.OrderSearchResult, error) {
+	ctx, span := util.StartSpan(ctx, "OrderService.SearchOrders")
+	defer span.End()
+
+	query = strings.TrimSpace(query)
+	if _, err := strconv.ParseInt(query, 10, 64); err != nil && utf8.RuneCountInString(query) < minSearchQueryLength {
+		return nil, apperrors.New(apperrors.ErrInvalidRequest, "q must be a number or at least %d characters", minSearchQueryLength)
+	}
+
+	results, err := s.orders.SearchOrders(ctx, query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search orders: %w", err)
+	}
+	return results, nil
+}
+
+// GetOrderReservations returns the stock reservations of an order
+func (s *OrderService) GetOrderReservations(ctx context.Context, orderID int64) ([]models.Reservation, error) {
+	if _, err := s.orders.GetOrderByID(ctx, orderID); err != nil {
+		return nil, err
+	}
+	return s.inventoryClient.GetOrderReservations(ctx, orderID)
+}
+
+// GetProductVariants returns the variants a product can be ordered in
+func (s *OrderService) GetProductVariants(ctx context.Context, productID int64) ([]models.ProductVariant, error) {
+	products, err := s.productCache.GetProductsByIDs(ctx, []int64{productID})
+	if err != nil {
+		return nil, err
+	}
+	if len(products) == 0 {
+		return nil, apperrors.New(apperrors.ErrNotFound, "product %d not found", productID)
+	}
+	return s.inventoryClient.GetProductVariants(ctx, []int64{productID})
+}
+
+// GetOrderHistory returns the status transitions of an order, oldest first
+func (s *OrderService) GetOrderHistory(ctx context.Context, orderID int64) ([]models.OrderStatusHistory, error) {
+	if _, err := s.orders.GetOrderByID(ctx, orderID); err != nil {
+		return nil, err
+	}
+	return s.orders.GetOrderStatusHistory(ctx, orderID)
+}
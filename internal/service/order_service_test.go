@@ -1,13 +1,32 @@
 package service
 
 import (
+	"context"
 	"testing"
+	"time"
 
+	"order-service/internal/apperrors"
 	"order-service/internal/models"
+	"order-service/internal/redisclient"
+	"order-service/internal/store/mocks"
 
+	"github.com/alicebob/miniredis/v2"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
+// newTestRedis starts an in-memory Redis for the duration of the test
+func newTestRedis(t *testing.T) *redisclient.Client {
+	t.Helper()
+
+	server := miniredis.RunT(t)
+	client, err := redisclient.NewClient(server.Addr(), "", 0)
+	require.NoError(t, err)
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
 func TestCalculateTotal(t *testing.T) {
 	os := &OrderService{}
 
@@ -28,7 +47,189 @@ func TestCalculateTotal(t *testing.T) {
 }
 
 func TestValidateOrderItems(t *testing.T) {
-	// This would require mocking the store
-	// Placeholder for demonstration
-	t.Skip("Requires mocked store")
+	inventory := mocks.NewInventoryRepository(t)
+	inventory.On("GetProductsByIDs", mock.Anything, []int64{1, 2}).
+		Return([]models.Product{{ID: 1, Price: 1000}, {ID: 2, Price: 500}}, nil).Once()
+	inventory.On("GetVariantsByProductIDs", mock.Anything, []int64{1, 2}).
+		Return([]models.ProductVariant{
+			{ID: 11, ProductID: 1, IsDefault: true},
+			{ID: 12, ProductID: 1},
+			{ID: 21, ProductID: 2, IsDefault: true},
+		}, nil).Once()
+
+	redis := newTestRedis(t)
+	os := &OrderService{
+		productCache:    NewProductCache(inventory, redis, time.Minute),
+		inventoryClient: NewInventoryClient(inventory, redis),
+	}
+
+	items := []OrderItemRequest{
+		{ProductID: 1, Quantity: 2},
+		{ProductID: 1, VariantID: 12, Quantity: 1},
+		{ProductID: 2, Quantity: 1},
+	}
+	products, variants, err := os.validateOrderItems(context.Background(), items)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1000), products[1].Price)
+	assert.Equal(t, int64(500), products[2].Price)
+	assert.Equal(t, []int64{11, 12, 21}, []int64{items[0].VariantID, items[1].VariantID, items[2].VariantID})
+	assert.Equal(t, int64(1), variants[12].ProductID)
+}
+
+func TestResolveVariantsRejectsForeignAndRepeatedVariants(t *testing.T) {
+	variants := []models.ProductVariant{
+		{ID: 11, ProductID: 1, IsDefault: true},
+		{ID: 21, ProductID: 2, IsDefault: true},
+	}
+
+	err := resolveVariants([]OrderItemRequest{{ProductID: 1, VariantID: 21, Quantity: 1}}, variants)
+	assert.ErrorIs(t, err, apperrors.ErrProductNotFound)
+
+	err = resolveVariants([]OrderItemRequest{
+		{ProductID: 1, Quantity: 1},
+		{ProductID: 1, VariantID: 11, Quantity: 2},
+	}, variants)
+	assert.ErrorIs(t, err, apperrors.ErrInvalidRequest)
+}
+
+func TestValidateOrderItemsRejectsUnknownProducts(t *testing.T) {
+	inventory := mocks.NewInventoryRepository(t)
+	inventory.On("GetProductsByIDs", mock.Anything, []int64{1, 99}).
+		Return([]models.Product{{ID: 1, Price: 1000}}, nil).Once()
+
+	os := &OrderService{productCache: NewProductCache(inventory, newTestRedis(t), time.Minute)}
+
+	_, _, err := os.validateOrderItems(context.Background(), []OrderItemRequest{
+		{ProductID: 1, Quantity: 1},
+		{ProductID: 99, Quantity: 1},
+	})
+	assert.ErrorIs(t, err, apperrors.ErrProductNotFound)
+}
+
+func TestWithoutSkippedItemsDropsSkippedProducts(t *testing.T) {
+	items := []OrderItemRequest{
+		{ProductID: 1, VariantID: 11, Quantity: 2},
+		{ProductID: 2, VariantID: 21, Quantity: 1},
+		{ProductID: 3, VariantID: 31, Quantity: 4},
+	}
+	skipped := []models.SkippedOrderItem{{ProductID: 2, VariantID: 21, Quantity: 1, Reason: "product_not_found"}}
+
+	assert.Equal(t, []OrderItemRequest{
+		{ProductID: 1, VariantID: 11, Quantity: 2},
+		{ProductID: 3, VariantID: 31, Quantity: 4},
+	}, withoutSkippedItems(items, skipped))
+}
+
+func TestGetOrderServesRepeatReadsFromCache(t *testing.T) {
+	orders := mocks.NewOrderRepository(t)
+	orders.On("GetOrderByID", mock.Anything, int64(7)).
+		Return(&models.Order{ID: 7, Status: models.OrderStatusReserved}, nil).Once()
+	orders.On("GetOrderItemsByOrderID", mock.Anything, int64(7)).
+		Return([]models.OrderItem{{OrderID: 7, ProductID: 1, Quantity: 2}}, nil).Once()
+
+	os := &OrderService{
+		orders:     orders,
+		orderCache: NewOrderCache(newTestRedis(t), time.Minute, time.Minute),
+	}
+
+	for i := 0; i < 2; i++ {
+		order, items, err := os.GetOrder(context.Background(), 7)
+		require.NoError(t, err)
+		assert.Equal(t, models.OrderStatusReserved, order.Status)
+		assert.Len(t, items, 1)
+	}
+}
+
+func TestGetOrderHistoryReturnsNotFoundForUnknownOrder(t *testing.T) {
+	orders := mocks.NewOrderRepository(t)
+	orders.On("GetOrderByID", mock.Anything, int64(9)).
+		Return(nil, apperrors.New(apperrors.ErrOrderNotFound, "order 9 not found")).Once()
+
+	os := &OrderService{orders: orders}
+
+	_, err := os.GetOrderHistory(context.Background(), 9)
+	assert.ErrorIs(t, err, apperrors.ErrOrderNotFound)
+}
+
+func TestReserveInventoryBackordersOutOfStockItems(t *testing.T) {
+	inventory := mocks.NewInventoryRepository(t)
+	expectVariant(inventory, 11, 10)
+	inventory.On("GetProductByID", mock.Anything, int64(10)).
+		Return(&models.Product{ID: 10, ReservationStrategy: StrategyDBStrict}, nil).Once()
+	inventory.On("HoldReservation", mock.Anything, int64(1), int64(10), int64(11), 2).Return(true, nil).Once()
+	inventory.On("ReserveStockTx", mock.Anything, int64(11), int64(models.DefaultWarehouseID), 2).
+		Return(apperrors.New(apperrors.ErrInsufficientStock, "variant 11")).Once()
+	inventory.On("DeleteReservation", mock.Anything, int64(1), int64(11)).Return(nil).Once()
+	expectDBStock(inventory, 11, 2)
+
+	orders := mocks.NewOrderRepository(t)
+	orders.On("MarkOrderItemsBackordered", mock.Anything, int64(1), []int64{11}).Return(nil).Once()
+
+	os := &OrderService{
+		orders:          orders,
+		inventoryClient: NewInventoryClient(inventory, newTestRedis(t)),
+		backorders:      true,
+	}
+
+	items := []OrderItemRequest{{ProductID: 10, VariantID: 11, Quantity: 2}}
+	backordered, err := os.reserveInventory(context.Background(), 1, items, true)
+	require.NoError(t, err)
+	assert.Equal(t, items, backordered)
+}
+
+func TestSearchOrdersRequiresThreeCharactersOrANumber(t *testing.T) {
+	orders := mocks.NewOrderRepository(t)
+	orders.On("SearchOrders", mock.Anything, "42", 20).
+		Return([]models.OrderSearchResult{{OrderSummary: models.OrderSummary{ID: 42}, Score: 1, MatchedOn: []string{"order_id"}}}, nil).Once()
+	orders.On("SearchOrders", mock.Anything, "TXN-1", 20).Return([]models.OrderSearchResult{}, nil).Once()
+	os := &OrderService{orders: orders}
+
+	_, err := os.SearchOrders(context.Background(), " ab ", 20)
+	assert.ErrorIs(t, err, apperrors.ErrInvalidRequest)
+
+	results, err := os.SearchOrders(context.Background(), "42", 20)
+	require.NoError(t, err)
+	assert.Equal(t, int64(42), results[0].ID)
+
+	_, err = os.SearchOrders(context.Background(), " TXN-1 ", 20)
+	assert.NoError(t, err)
+}
+
+func TestCheckConsistencyTokenRejectsStaleAvailability(t *testing.T) {
+	redis := newTestRedis(t)
+	os := &OrderService{
+		inventoryClient: NewInventoryClient(mocks.NewInventoryRepository(t), redis),
+		availabilityLag: 2,
+	}
+	ctx := context.Background()
+	for i := 0; i < 5; i++ {
+		_, err := redis.BumpInventoryVersion(ctx)
+		require.NoError(t, err)
+	}
+
+	assert.NoError(t, os.checkConsistencyToken(ctx, 0), "orders without a token are not checked")
+	assert.NoError(t, os.checkConsistencyToken(ctx, 3))
+	assert.NoError(t, os.checkConsistencyToken(ctx, 9), "a token ahead of a reset version passes")
+	assert.ErrorIs(t, os.checkConsistencyToken(ctx, 2), apperrors.ErrStaleAvailability)
+}
+
+func TestBulkGetOrdersFetchesEachOrderOnceAndReportsMissing(t *testing.T) {
+	orders := mocks.NewOrderRepository(t)
+	orders.On("GetOrdersWithItems", mock.Anything, []int64{3, 1, 9}).
+		Return([]models.OrderWithItems{
+			{Order: models.Order{ID: 1}, Items: []models.OrderItem{{OrderID: 1, ProductID: 10, Quantity: 1}}},
+			{Order: models.Order{ID: 3}, Items: []models.OrderItem{}},
+		}, nil).Once()
+
+	os := &OrderService{orders: orders}
+
+	resp, err := os.BulkGetOrders(context.Background(), []int64{3, 1, 3, 9})
+	require.NoError(t, err)
+	assert.Len(t, resp.Orders, 2)
+	assert.Equal(t, []int64{9}, resp.Missing)
+
+	_, err = os.BulkGetOrders(context.Background(), make([]int64, MaxBulkGetOrders+1))
+	assert.ErrorIs(t, err, apperrors.ErrInvalidRequest)
+	_, err = os.BulkGetOrders(context.Background(), nil)
+	assert.ErrorIs(t, err, apperrors.ErrInvalidRequest)
 }
@@ -0,0 +1,120 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"order-service/internal/models"
+	"order-service/internal/redisclient"
+	"order-service/internal/util"
+
+	"go.uber.org/zap"
+)
+
+// ErrStatusFeedAtCapacity is returned when the order status feed has reached its connection limit
+var ErrStatusFeedAtCapacity = errors.New("order status feed at capacity")
+
+// statusFeedBuffer is the per-subscriber backlog before status changes are dropped
+const statusFeedBuffer = 8
+
+// OrderStatusFeed broadcasts order status transitions over Redis pub/sub and
+// fans them out to clients tracking a single order
+type OrderStatusFeed struct {
+	redis          *redisclient.Client
+	maxConnections int
+	logger         *zap.Logger
+
+	mu          sync.Mutex
+	subscribers map[int64]map[chan redisclient.OrderStatusChange]bool
+	connections int
+}
+
+// NewOrderStatusFeed creates a feed accepting up to maxConnections streaming clients
+func NewOrderStatusFeed(redis *redisclient.Client, maxConnections int) *OrderStatusFeed {
+	return &OrderStatusFeed{
+		redis:          redis,
+		maxConnections: maxConnections,
+		logger:         util.GetLogger(),
+		subscribers:    make(map[int64]map[chan redisclient.OrderStatusChange]bool),
+	}
+}
+
+// IsFinalStatus reports whether an order can no longer change status on its own
+func IsFinalStatus(status string) bool {
+	switch status {
+	case models.OrderStatusConfirmed, models.OrderStatusCancelled, models.OrderStatusFailed:
+		return true
+	}
+	return false
+}
+
+// Publish broadcasts that an order moved to status. Publishing is best effort:
+// tracking clients are a convenience and never fail a transition.
+func (f *OrderStatusFeed) Publish(ctx context.Context, orderID int64, status string) {
+	if f == nil {
+		return
+	}
+
+	change := redisclient.OrderStatusChange{OrderID: orderID, Status: status, Timestamp: time.Now()}
+	if err := f.redis.PublishOrderStatusChange(ctx, change); err != nil {
+		f.logger.Warn("Failed to publish order status change",
+			zap.Int64("order_id", orderID),
+			zap.String("status", status),
+			zap.Error(err))
+	}
+}
+
+// Subscribe registers a client tracking orderID. The returned cancel func must be called on disconnect.
+func (f *OrderStatusFeed) Subscribe(orderID int64) (<-chan redisclient.OrderStatusChange, func(), error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.connections >= f.maxConnections {
+		return nil, nil, ErrStatusFeedAtCapacity
+	}
+
+	ch := make(chan redisclient.OrderStatusChange, statusFeedBuffer)
+	if f.subscribers[orderID] == nil {
+		f.subscribers[orderID] = make(map[chan redisclient.OrderStatusChange]bool)
+	}
+	f.subscribers[orderID][ch] = true
+	f.connections++
+	util.OrderStatusFeedConnections.Inc()
+
+	cancel := func() {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+
+		if !f.subscribers[orderID][ch] {
+			return
+		}
+		delete(f.subscribers[orderID], ch)
+		if len(f.subscribers[orderID]) == 0 {
+			delete(f.subscribers, orderID)
+		}
+		f.connections--
+		util.OrderStatusFeedConnections.Dec()
+	}
+
+	return ch, cancel, nil
+}
+
+// Run relays order status changes from Redis to subscribers until ctx is cancelled
+func (f *OrderStatusFeed) Run(ctx context.Context) error {
+	return f.redis.SubscribeOrderStatusChanges(ctx, f.broadcast)
+}
+
+func (f *OrderStatusFeed) broadcast(change redisclient.OrderStatusChange) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for ch := range f.subscribers[change.OrderID] {
+		select {
+		case ch <- change:
+		default:
+			util.OrderStatusFeedDroppedTotal.Inc()
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"order-service/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOrderStatusFeedDeliversOnlyTrackedOrder(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	feed := NewOrderStatusFeed(newTestRedis(t), 10)
+	updates, unsubscribe, err := feed.Subscribe(1)
+	require.NoError(t, err)
+	defer unsubscribe()
+
+	go func() { _ = feed.Run(ctx) }()
+
+	// Publish until the relay has subscribed to Redis
+	assert.Eventually(t, func() bool {
+		feed.Publish(ctx, 2, models.OrderStatusPaid)
+		feed.Publish(ctx, 1, models.OrderStatusPaid)
+		select {
+		case change := <-updates:
+			return change.OrderID == 1 && change.Status == models.OrderStatusPaid
+		case <-time.After(20 * time.Millisecond):
+			return false
+		}
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestOrderStatusFeedEnforcesCapacity(t *testing.T) {
+	feed := NewOrderStatusFeed(newTestRedis(t), 1)
+
+	_, unsubscribe, err := feed.Subscribe(1)
+	require.NoError(t, err)
+
+	_, _, err = feed.Subscribe(2)
+	assert.ErrorIs(t, err, ErrStatusFeedAtCapacity)
+
+	unsubscribe()
+	unsubscribe()
+	_, _, err = feed.Subscribe(2)
+	assert.NoError(t, err)
+}
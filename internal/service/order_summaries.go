@@ -0,0 +1,47 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"order-service/internal/models"
+	"order-service/internal/store"
+	"order-service/internal/util"
+)
+
+// OrderSummaryProjector maintains the order_summaries read model from the
+// order and payment events, so the list and search endpoints read one row per
+// order instead of joining orders, items and payments on every request
+type OrderSummaryProjector struct {
+	orders store.OrderRepository
+}
+
+// NewOrderSummaryProjector creates a new order summary projector
+func NewOrderSummaryProjector(orders store.OrderRepository) *OrderSummaryProjector {
+	return &OrderSummaryProjector{orders: orders}
+}
+
+// Project refreshes the summary of the order an event is about. Events about
+// no order are skipped.
+func (p *OrderSummaryProjector) Project(ctx context.Context, data []byte) error {
+	var event struct {
+		models.BaseEvent
+		OrderID int64 `json:"order_id"`
+	}
+	if err := json.Unmarshal(data, &event); err != nil {
+		return fmt.Errorf("failed to decode event: %w", err)
+	}
+	if event.OrderID == 0 {
+		return nil
+	}
+
+	if err := p.orders.RefreshOrderSummary(ctx, event.OrderID, event.EventType, event.Timestamp); err != nil {
+		return fmt.Errorf("failed to project order %d: %w", event.OrderID, err)
+	}
+	if !event.Timestamp.IsZero() {
+		util.OrderSummaryProjectionLag.Observe(time.Since(event.Timestamp).Seconds())
+	}
+	return nil
+}
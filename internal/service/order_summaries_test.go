@@ -0,0 +1,29 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"order-service/internal/store/mocks"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOrderSummaryProjectorRefreshesTheEventsOrder(t *testing.T) {
+	orders := mocks.NewOrderRepository(t)
+	projector := NewOrderSummaryProjector(orders)
+	at := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+
+	orders.On("RefreshOrderSummary", mock.Anything, int64(7), "PAYMENT_SUCCESS", at).Return(nil).Once()
+	require.NoError(t, projector.Project(context.Background(),
+		[]byte(`{"event_id":"e1","event_type":"PAYMENT_SUCCESS","timestamp":"2024-01-15T10:30:00Z","order_id":7,"amount":3000}`)))
+
+	// Events about no order are skipped
+	require.NoError(t, projector.Project(context.Background(),
+		[]byte(`{"event_id":"e2","event_type":"INVENTORY_LOW","timestamp":"2024-01-15T10:30:00Z","variant_id":11}`)))
+
+	assert.Error(t, projector.Project(context.Background(), []byte(`{`)))
+}
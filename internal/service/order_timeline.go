@@ -0,0 +1,131 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"order-service/internal/broker"
+	"order-service/internal/models"
+	"order-service/internal/store"
+	"order-service/internal/util"
+
+	"go.uber.org/zap"
+)
+
+// Directions in which an event passed through the service
+const (
+	TimelinePublished = "published"
+	TimelineConsumed  = "consumed"
+)
+
+// OrderTimeline records a one-line summary of every order event the service
+// publishes or consumes, so support can see what happened without Kafka access
+type OrderTimeline struct {
+	timeline store.TimelineRepository
+	logger   *zap.Logger
+}
+
+// NewOrderTimeline creates a new order timeline
+func NewOrderTimeline(timeline store.TimelineRepository) *OrderTimeline {
+	return &OrderTimeline{
+		timeline: timeline,
+		logger:   util.GetLogger(),
+	}
+}
+
+// timelineEvent holds the fields any order event may carry
+type timelineEvent struct {
+	models.BaseEvent
+	OrderID     int64                  `json:"order_id"`
+	TotalAmount int64                  `json:"total_amount"`
+	Amount      int64                  `json:"amount"`
+	Fee         int64                  `json:"fee"`
+	TxID        string                 `json:"tx_id"`
+	Reason      string                 `json:"reason"`
+	Items       []models.OrderItemData `json:"items"`
+	ShipDate    *time.Time             `json:"expected_ship_date"`
+	NewStatus   string                 `json:"new_status"`
+}
+
+// Observer returns the broker observer recording events seen in direction.
+// Recording is best effort and never fails the publish or the message.
+func (t *OrderTimeline) Observer(direction string) broker.EventObserver {
+	return func(ctx context.Context, payload []byte) {
+		var event timelineEvent
+		if err := json.Unmarshal(payload, &event); err != nil || event.OrderID == 0 || event.EventID == "" {
+			return
+		}
+
+		occurredAt := event.Timestamp
+		if occurredAt.IsZero() {
+			occurredAt = time.Now()
+		}
+		entry := &models.OrderTimelineEntry{
+			OrderID:    event.OrderID,
+			EventID:    event.EventID,
+			EventType:  event.EventType,
+			Direction:  direction,
+			Summary:    summarizeEvent(event),
+			OccurredAt: occurredAt,
+		}
+		if err := t.timeline.AddTimelineEntry(ctx, entry); err != nil {
+			util.OrderTimelineWriteErrorsTotal.Inc()
+			t.logger.Warn("Failed to record order timeline entry",
+				zap.Int64("order_id", event.OrderID),
+				zap.String("event_id", event.EventID),
+				zap.Error(err))
+		}
+	}
+}
+
+// Get returns the timeline of an order, oldest first
+func (t *OrderTimeline) Get(ctx context.Context, orderID int64) ([]models.OrderTimelineEntry, error) {
+	return t.timeline.GetOrderTimeline(ctx, orderID)
+}
+
+// summarizeEvent describes an event in one line for the support UI
+func summarizeEvent(event timelineEvent) string {
+	switch event.EventType {
+	case models.EventTypeOrderCreated:
+		return fmt.Sprintf("Order created with %d item(s), total %d", len(event.Items), event.TotalAmount)
+	case models.EventTypeOrderAmended:
+		return fmt.Sprintf("Order edited to %d item(s), total %d", len(event.Items), event.TotalAmount)
+	case models.EventTypeOrderReserved:
+		return fmt.Sprintf("Stock reserved for %d item(s)", len(event.Items))
+	case models.EventTypePaymentSuccess:
+		return fmt.Sprintf("Payment of %d captured (tx %s)", event.Amount, event.TxID)
+	case models.EventTypePaymentFailed:
+		return "Payment failed: " + event.Reason
+	case models.EventTypeOrderPaid:
+		return fmt.Sprintf("Order paid: %d", event.Amount)
+	case models.EventTypeOrderConfirmed:
+		return "Order confirmed"
+	case models.EventTypePaymentVoided:
+		return fmt.Sprintf("Payment of %d voided: %s", event.Amount, event.Reason)
+	case models.EventTypePaymentRefunded:
+		return fmt.Sprintf("Payment refunded: %d, keeping a fee of %d: %s", event.Amount, event.Fee, event.Reason)
+	case models.EventTypeOrderCancelled:
+		return "Order cancelled: " + event.Reason
+	case models.EventTypeOrderOnHold:
+		return "Order put on hold: " + event.Reason
+	case models.EventTypePaymentReminder:
+		return "Payment reminder sent"
+	case models.EventTypeReservationExpiring:
+		return "Reservation expiry warning sent"
+	case models.EventTypeOrderBackordered:
+		return fmt.Sprintf("%d item(s) backordered", len(event.Items))
+	case models.EventTypeBackorderRescheduled:
+		if event.ShipDate == nil {
+			return fmt.Sprintf("%d backordered item(s) no longer covered by incoming stock", len(event.Items))
+		}
+		return fmt.Sprintf("%d backordered item(s) rescheduled to ship %s", len(event.Items), event.ShipDate.Format(time.DateOnly))
+	case models.EventTypeBackorderFulfilled:
+		return fmt.Sprintf("Stock reserved for %d backordered item(s)", len(event.Items))
+	case models.EventTypeOrderStatusChanged:
+		return fmt.Sprintf("Status changed to %s: %s", event.NewStatus, event.Reason)
+	default:
+		return event.EventType
+	}
+}
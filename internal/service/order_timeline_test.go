@@ -0,0 +1,58 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"order-service/internal/models"
+	"order-service/internal/store/mocks"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTimelineObserverRecordsOrderEvents(t *testing.T) {
+	at := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	payload, err := json.Marshal(&models.PaymentFailedEvent{
+		BaseEvent: models.BaseEvent{EventID: "evt-1", EventType: models.EventTypePaymentFailed, Timestamp: at},
+		OrderID:   42,
+		Reason:    "card declined",
+	})
+	require.NoError(t, err)
+
+	repo := mocks.NewTimelineRepository(t)
+	repo.On("AddTimelineEntry", mock.Anything, &models.OrderTimelineEntry{
+		OrderID:    42,
+		EventID:    "evt-1",
+		EventType:  models.EventTypePaymentFailed,
+		Direction:  TimelineConsumed,
+		Summary:    "Payment failed: card declined",
+		OccurredAt: at,
+	}).Return(nil).Once()
+
+	NewOrderTimeline(repo).Observer(TimelineConsumed)(context.Background(), payload)
+}
+
+func TestTimelineObserverIgnoresNonOrderEventsAndWriteErrors(t *testing.T) {
+	repo := mocks.NewTimelineRepository(t)
+	repo.On("AddTimelineEntry", mock.Anything, mock.Anything).Return(errors.New("connection reset")).Once()
+	observe := NewOrderTimeline(repo).Observer(TimelinePublished)
+
+	userEvent, _ := json.Marshal(&models.UserDeletedEvent{
+		BaseEvent: models.BaseEvent{EventID: "evt-2", EventType: models.EventTypeUserDeleted},
+		UserID:    7,
+	})
+	observe(context.Background(), userEvent)
+	observe(context.Background(), []byte("not json"))
+
+	orderEvent, _ := json.Marshal(&models.OrderCancelledEvent{
+		BaseEvent: models.BaseEvent{EventID: "evt-3", EventType: models.EventTypeOrderCancelled},
+		OrderID:   42,
+		Reason:    "payment timeout",
+	})
+	assert.NotPanics(t, func() { observe(context.Background(), orderEvent) })
+}
@@ -2,12 +2,16 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"math/rand"
+	"sync/atomic"
 	"time"
 
+	"order-service/internal/apperrors"
 	"order-service/internal/broker"
 	"order-service/internal/models"
+	"order-service/internal/resilience/retry"
 	"order-service/internal/store"
 	"order-service/internal/util"
 
@@ -15,29 +19,128 @@ import (
 	"go.uber.org/zap"
 )
 
+const (
+	// paymentRetryClaimTTL is how long a claimed retry is held before another
+	// retrier may take it over
+	paymentRetryClaimTTL  = time.Minute
+	paymentRetryBatchSize = 100
+)
+
+// mockDeclineReasons are the reasons the mock provider declines payments with
+var mockDeclineReasons = []string{
+	"insufficient_funds",
+	"card_declined",
+	"do_not_honor",
+	"provider_timeout",
+	"provider_unavailable",
+}
+
+// softDeclineReasons are the decline reasons of transient provider failures,
+// which may succeed when retried
+var softDeclineReasons = map[string]bool{
+	"provider_timeout":     true,
+	"provider_unavailable": true,
+	"issuer_unavailable":   true,
+	"rate_limited":         true,
+	"wallet_unavailable":   true,
+}
+
+// ClassifyDecline returns whether a decline reason is soft or hard. Reasons not
+// known to be transient are hard, so a payment is never retried by accident.
+func ClassifyDecline(reason string) string {
+	if softDeclineReasons[reason] {
+		return models.DeclineClassSoft
+	}
+	return models.DeclineClassHard
+}
+
+// PaymentRetryPolicy sets how payments declined softly are retried
+type PaymentRetryPolicy struct {
+	// Policy schedules the retries of an order's payment: its MaxAttempts
+	// counts the first attempt, so 1 or less cancels the order on the first
+	// decline, and retry n is due Delay(n) after the decline
+	retry.Policy
+}
+
 // PaymentService handles payment processing (mocked)
 type PaymentService struct {
-	store          *store.Store
+	payments       store.PaymentRepository
 	eventPublisher *broker.EventPublisher
 	logger         *zap.Logger
-	successRate    float64 // Mock success rate (0.0 - 1.0)
+	successRate    atomic.Value // float64, mock success rate (0.0 - 1.0)
+	retryPolicy    PaymentRetryPolicy
+	wallets        *WalletService
+	compensations  *CompensationQueue
+}
+
+// PaymentRequest is the payment of a reserved order
+type PaymentRequest struct {
+	OrderID int64
+	UserID  int64
+	Amount  int64
+	// WalletAmount is settled from the user's wallet, the provider charges the rest
+	WalletAmount int64
 }
 
 // NewPaymentService creates a new payment service
-func NewPaymentService(store *store.Store, eventPublisher *broker.EventPublisher) *PaymentService {
-	return &PaymentService{
-		store:          store,
+func NewPaymentService(payments store.PaymentRepository, eventPublisher *broker.EventPublisher) *PaymentService {
+	ps := &PaymentService{
+		payments:       payments,
 		eventPublisher: eventPublisher,
 		logger:         util.GetLogger(),
-		successRate:    0.9, // 90% success rate for testing
 	}
+	ps.successRate.Store(0.9) // 90% success rate for testing
+	return ps
+}
+
+// SetSuccessRate sets the share of mocked payments that succeed; safe to call while payments run
+func (ps *PaymentService) SetSuccessRate(rate float64) {
+	ps.successRate.Store(rate)
+}
+
+// SetRetryPolicy enables retries of payments declined softly
+func (ps *PaymentService) SetRetryPolicy(policy PaymentRetryPolicy) {
+	ps.retryPolicy = policy
+}
+
+// SetWallets enables settling payments from the wallets of users
+func (ps *PaymentService) SetWallets(wallets *WalletService) {
+	ps.wallets = wallets
+}
+
+// SetCompensations queues wallet credits that fail for background retries
+func (ps *PaymentService) SetCompensations(compensations *CompensationQueue) {
+	ps.compensations = compensations
 }
 
 // ProcessPayment processes payment for an order (mocked)
-func (ps *PaymentService) ProcessPayment(ctx context.Context, orderID int64, amount int64) error {
+func (ps *PaymentService) ProcessPayment(ctx context.Context, req PaymentRequest) error {
+	return ps.processPayment(ctx, req, false)
+}
+
+// ProcessSyntheticPayment processes payment for a synthetic probe order; the mock provider always approves it
+func (ps *PaymentService) ProcessSyntheticPayment(ctx context.Context, req PaymentRequest) error {
+	return ps.processPayment(ctx, req, true)
+}
+
+// processPayment debits the wallet share of an order, then charges the rest
+// through the provider. The wallet share is credited back if the provider
+// declines, so a retry debits it again.
+func (ps *PaymentService) processPayment(ctx context.Context, req PaymentRequest, forceSuccess bool) error {
 	ctx, span := util.StartSpan(ctx, "PaymentService.ProcessPayment")
 	defer span.End()
 
+	orderID, amount := req.OrderID, req.Amount
+
+	// OrderReserved may be redelivered or republished by saga recovery; never charge twice
+	if existing, err := ps.payments.GetPaymentByOrderID(ctx, orderID); err == nil &&
+		(existing.Status == models.PaymentStatusPending || existing.Status == models.PaymentStatusSuccess) {
+		ps.logger.Info("Payment already in progress or captured, skipping",
+			zap.Int64("order_id", orderID),
+			zap.String("status", existing.Status))
+		return nil
+	}
+
 	util.PaymentAttemptsTotal.Inc()
 	start := time.Now()
 	defer func() {
@@ -46,30 +149,59 @@ func (ps *PaymentService) ProcessPayment(ctx context.Context, orderID int64, amo
 
 	ps.logger.Info("Processing payment",
 		zap.Int64("order_id", orderID),
-		zap.Int64("amount", amount))
+		zap.Int64("amount", amount),
+		zap.Int64("wallet_amount", req.WalletAmount))
 
 	payment := &models.Payment{
 		OrderID:      orderID,
 		Status:       models.PaymentStatusPending,
 		Amount:       amount,
+		WalletAmount: req.WalletAmount,
 		ProviderTxID: "",
 	}
 
-	if err := ps.store.CreatePayment(ctx, payment); err != nil {
+	if err := ps.payments.CreatePayment(ctx, payment); err != nil {
+		if errors.Is(err, apperrors.ErrOrderAmended) {
+			// The amendment requested payment of the new totals again
+			ps.logger.Info("Order amended since payment was requested, skipping",
+				zap.Int64("order_id", orderID),
+				zap.Int64("amount", amount))
+			return nil
+		}
+		if errors.Is(err, apperrors.ErrInvalidOrderState) {
+			// Cancelled, e.g. at its payment deadline, or paid meanwhile
+			ps.logger.Info("Order no longer awaiting payment, skipping",
+				zap.Int64("order_id", orderID),
+				zap.Error(err))
+			return nil
+		}
 		return fmt.Errorf("failed to create payment: %w", err)
 	}
 
-	time.Sleep(time.Duration(100+rand.Intn(400)) * time.Millisecond)
+	var success bool
+	var reason, providerTxID string
+	switch reason = ps.debitWallet(ctx, req, payment); {
+	case reason != "":
+	case req.WalletAmount >= amount:
+		// Settled internally without a provider round-trip
+		success, providerTxID = true, fmt.Sprintf("WALLET-%s", uuid.New().String()[:8])
+	default:
+		time.Sleep(time.Duration(100+rand.Intn(400)) * time.Millisecond)
 
-	success := rand.Float64() < ps.successRate
-	providerTxID := fmt.Sprintf("TXN-%s", uuid.New().String()[:8])
+		success = forceSuccess || rand.Float64() < ps.successRate.Load().(float64)
+		providerTxID = fmt.Sprintf("TXN-%s", uuid.New().String()[:8])
+		if !success {
+			reason = mockDeclineReasons[rand.Intn(len(mockDeclineReasons))]
+			ps.creditWallet(ctx, payment, req.WalletAmount, "payment_declined")
+		}
+	}
 
 	if success {
 		ps.logger.Info("Payment succeeded",
 			zap.Int64("order_id", orderID),
 			zap.String("tx_id", providerTxID))
 
-		if err := ps.store.UpdatePaymentStatus(ctx, payment.ID, models.PaymentStatusSuccess, providerTxID); err != nil {
+		if err := ps.payments.UpdatePaymentStatus(ctx, payment.ID, models.PaymentStatusSuccess, providerTxID); err != nil {
 			return fmt.Errorf("failed to update payment status: %w", err)
 		}
 
@@ -93,34 +225,300 @@ func (ps *PaymentService) ProcessPayment(ctx context.Context, orderID int64, amo
 
 	} else {
 		ps.logger.Warn("Payment failed",
-			zap.Int64("order_id", orderID))
+			zap.Int64("order_id", orderID),
+			zap.String("reason", reason))
 
-		if err := ps.store.UpdatePaymentStatus(ctx, payment.ID, models.PaymentStatusFailed, ""); err != nil {
+		if err := ps.payments.UpdatePaymentStatus(ctx, payment.ID, models.PaymentStatusFailed, ""); err != nil {
 			return fmt.Errorf("failed to update payment status: %w", err)
 		}
 
 		util.PaymentFailedTotal.Inc()
 
-		event := &models.PaymentFailedEvent{
-			BaseEvent: models.BaseEvent{
-				EventID:   uuid.New().String(),
-				EventType: models.EventTypePaymentFailed,
-				Timestamp: time.Now(),
-			},
-			OrderID:   orderID,
+		return ps.handleDecline(ctx, payment, reason)
+	}
+
+	return nil
+}
+
+// debitWallet debits the wallet share of a payment. Returns the reason the
+// payment is declined for if the wallet cannot settle it.
+func (ps *PaymentService) debitWallet(ctx context.Context, req PaymentRequest, payment *models.Payment) string {
+	if req.WalletAmount == 0 {
+		return ""
+	}
+	if ps.wallets == nil {
+		return "wallet_unavailable"
+	}
+
+	_, err := ps.wallets.Debit(ctx, req.UserID, req.WalletAmount, WalletEntry{
+		PaymentID: &payment.ID,
+		Reason:    fmt.Sprintf("order %d", req.OrderID),
+		Actor:     models.ActorPaymentService,
+	})
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, apperrors.ErrInsufficientBalance):
+		return "insufficient_wallet_balance"
+	default:
+		ps.logger.Error("Failed to debit wallet",
+			zap.Int64("order_id", req.OrderID),
+			zap.Error(err))
+		return "wallet_unavailable"
+	}
+}
+
+// creditWallet credits back up to amount of what a payment debited from a
+// wallet. A failure is queued for retry rather than returned, as the payment
+// outcome has to be recorded either way; crediting a payment again later is
+// idempotent.
+func (ps *PaymentService) creditWallet(ctx context.Context, payment *models.Payment, amount int64, reason string) int64 {
+	if payment.WalletAmount == 0 || amount <= 0 || ps.wallets == nil {
+		return 0
+	}
+	credited, err := ps.wallets.RefundPayment(ctx, payment.ID, amount, reason)
+	if err != nil {
+		ps.logger.Error("Failed to credit wallet back",
+			zap.Int64("order_id", payment.OrderID),
+			zap.Int64("payment_id", payment.ID),
+			zap.Int64("amount", amount),
+			zap.Error(err))
+		ps.compensations.Enqueue(ctx, models.Compensation{
+			OrderID:   payment.OrderID,
+			Kind:      models.CompensationWalletRefund,
 			PaymentID: payment.ID,
-			Reason:    "mock_payment_declined",
+			Amount:    amount,
+			Reason:    reason,
+		}, err)
+	}
+	return credited
+}
+
+// refundToWallet retries crediting back a wallet payment
+func (ps *PaymentService) refundToWallet(ctx context.Context, paymentID, amount int64, reason string) error {
+	if ps.wallets == nil {
+		return fmt.Errorf("wallet payments are disabled")
+	}
+	_, err := ps.wallets.RefundPayment(ctx, paymentID, amount, reason)
+	return err
+}
+
+// handleDecline records a declined payment and schedules a retry of a soft
+// decline while the order has retries left. Otherwise it publishes
+// PaymentFailed, on which the saga cancels the order.
+func (ps *PaymentService) handleDecline(ctx context.Context, payment *models.Payment, reason string) error {
+	class := ClassifyDecline(reason)
+	util.PaymentDeclinesTotal.WithLabelValues(class).Inc()
+
+	previous, err := ps.payments.CountPaymentAttempts(ctx, payment.OrderID)
+	if err != nil {
+		return fmt.Errorf("failed to count payment attempts: %w", err)
+	}
+
+	attempt := &models.PaymentAttempt{
+		OrderID:       payment.OrderID,
+		PaymentID:     payment.ID,
+		Attempt:       previous + 1,
+		Amount:        payment.Amount,
+		DeclineReason: reason,
+		DeclineClass:  class,
+		Status:        models.PaymentAttemptFinal,
+	}
+	if class == models.DeclineClassSoft && attempt.Attempt < ps.retryPolicy.MaxAttempts {
+		retryAt := time.Now().Add(ps.retryPolicy.Delay(previous + 1)).UTC()
+		attempt.Status, attempt.RetryAt = models.PaymentAttemptScheduled, &retryAt
+	}
+	if err := ps.payments.CreatePaymentAttempt(ctx, attempt); err != nil {
+		return fmt.Errorf("failed to record payment attempt: %w", err)
+	}
+
+	if attempt.Status == models.PaymentAttemptScheduled {
+		util.PaymentRetriesTotal.WithLabelValues("scheduled").Inc()
+		ps.logger.Info("Payment retry scheduled",
+			zap.Int64("order_id", payment.OrderID),
+			zap.Int("attempt", attempt.Attempt),
+			zap.Time("retry_at", *attempt.RetryAt))
+		return nil
+	}
+	if class == models.DeclineClassSoft && ps.retryPolicy.MaxAttempts > 1 {
+		util.PaymentRetriesTotal.WithLabelValues("exhausted").Inc()
+	}
+
+	event := &models.PaymentFailedEvent{
+		BaseEvent: models.BaseEvent{
+			EventID:   uuid.New().String(),
+			EventType: models.EventTypePaymentFailed,
+			Timestamp: time.Now(),
+		},
+		OrderID:      payment.OrderID,
+		PaymentID:    payment.ID,
+		Reason:       reason,
+		DeclineClass: class,
+		Attempts:     attempt.Attempt,
+	}
+
+	if err := ps.eventPublisher.PublishPaymentFailed(ctx, event); err != nil {
+		ps.logger.Error("Failed to publish PaymentFailed event", zap.Error(err))
+	}
+	return nil
+}
+
+// RetryDuePayments re-attempts the payments whose retry is due, after dropping
+// the retries of orders no longer awaiting payment. A retry is marked done
+// before the payment is attempted, so it is made at most once; an order whose
+// retry is lost to a crash is cancelled at its payment deadline. Returns the
+// number of payments retried.
+func (ps *PaymentService) RetryDuePayments(ctx context.Context) (int, error) {
+	ctx, span := util.StartSpan(ctx, "PaymentService.RetryDuePayments")
+	defer span.End()
+
+	dropped, err := ps.payments.DropStalePaymentRetries(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to drop stale payment retries: %w", err)
+	}
+	util.PaymentRetriesTotal.WithLabelValues("dropped").Add(float64(dropped))
+
+	retried := 0
+	for {
+		attempts, err := ps.payments.ClaimDuePaymentRetries(ctx, time.Now(), paymentRetryClaimTTL, paymentRetryBatchSize)
+		if err != nil {
+			return retried, fmt.Errorf("failed to claim payment retries: %w", err)
 		}
 
-		if err := ps.eventPublisher.PublishPaymentFailed(ctx, event); err != nil {
-			ps.logger.Error("Failed to publish PaymentFailed event", zap.Error(err))
+		for _, attempt := range attempts {
+			if err := ps.payments.SetPaymentAttemptStatus(ctx, attempt.ID, models.PaymentAttemptRetried); err != nil {
+				ps.logger.Error("Failed to mark payment retried",
+					zap.Int64("order_id", attempt.OrderID),
+					zap.Error(err))
+				continue
+			}
+			util.PaymentRetriesTotal.WithLabelValues("retried").Inc()
+			retried++
+
+			req := PaymentRequest{
+				OrderID:      attempt.OrderID,
+				UserID:       attempt.UserID,
+				Amount:       attempt.Amount,
+				WalletAmount: attempt.WalletAmount,
+			}
+			if err := ps.processPayment(ctx, req, false); err != nil {
+				ps.logger.Error("Payment retry failed",
+					zap.Int64("order_id", attempt.OrderID),
+					zap.Int("attempt", attempt.Attempt),
+					zap.Error(err))
+			}
+		}
+
+		if len(attempts) < paymentRetryBatchSize {
+			return retried, nil
 		}
 	}
+}
+
+// RetryScheduled reports whether the payment of an order is waiting on a retry
+func (ps *PaymentService) RetryScheduled(ctx context.Context, orderID int64) (bool, error) {
+	return ps.payments.HasScheduledPaymentRetry(ctx, orderID)
+}
+
+// VoidPayment voids the captured payment of an order through the provider and
+// publishes PaymentVoided. Voiding an already voided payment is a no-op.
+func (ps *PaymentService) VoidPayment(ctx context.Context, orderID int64, reason string) error {
+	ctx, span := util.StartSpan(ctx, "PaymentService.VoidPayment")
+	defer span.End()
+
+	payment, err := ps.payments.GetPaymentByOrderID(ctx, orderID)
+	if err != nil {
+		return err
+	}
+	if payment.Status == models.PaymentStatusVoided {
+		return nil
+	}
+	if payment.Status != models.PaymentStatusSuccess {
+		return fmt.Errorf("cannot void payment %d in status %s", payment.ID, payment.Status)
+	}
+
+	// The mock provider always accepts voids
+	if err := ps.payments.UpdatePaymentStatus(ctx, payment.ID, models.PaymentStatusVoided, payment.ProviderTxID); err != nil {
+		return fmt.Errorf("failed to update payment status: %w", err)
+	}
+	ps.creditWallet(ctx, payment, payment.WalletAmount, reason)
+
+	ps.logger.Warn("Payment voided",
+		zap.Int64("order_id", orderID),
+		zap.String("tx_id", payment.ProviderTxID),
+		zap.String("reason", reason))
+
+	event := &models.PaymentVoidedEvent{
+		BaseEvent: models.BaseEvent{
+			EventID:   uuid.New().String(),
+			EventType: models.EventTypePaymentVoided,
+			Timestamp: time.Now(),
+		},
+		OrderID:   orderID,
+		PaymentID: payment.ID,
+		Amount:    payment.Amount,
+		TxID:      payment.ProviderTxID,
+		Reason:    reason,
+	}
+
+	if err := ps.eventPublisher.PublishPaymentVoided(ctx, event); err != nil {
+		ps.logger.Error("Failed to publish PaymentVoided event", zap.Error(err))
+	}
+
+	return nil
+}
+
+// RefundPayment refunds the captured payment of an order less the fee of a
+// late cancellation, as quoted, and publishes PaymentRefunded
+func (ps *PaymentService) RefundPayment(ctx context.Context, quote CancellationQuote, reason string) error {
+	ctx, span := util.StartSpan(ctx, "PaymentService.RefundPayment")
+	defer span.End()
+
+	payment, err := ps.payments.GetPaymentByOrderID(ctx, quote.OrderID)
+	if err != nil {
+		return err
+	}
+	if payment.Status != models.PaymentStatusSuccess {
+		return fmt.Errorf("cannot refund payment %d in status %s", payment.ID, payment.Status)
+	}
+
+	// The mock provider always accepts refunds. The refund goes to the wallet
+	// first, up to the wallet share, and the rest to the provider.
+	if err := ps.payments.RefundPayment(ctx, payment.ID, quote.Refund); err != nil {
+		return fmt.Errorf("failed to update payment status: %w", err)
+	}
+	ps.creditWallet(ctx, payment, quote.Refund, reason)
+
+	ps.logger.Info("Payment refunded",
+		zap.Int64("order_id", quote.OrderID),
+		zap.String("tx_id", payment.ProviderTxID),
+		zap.Int64("refund", quote.Refund),
+		zap.Int64("fee", quote.Fee),
+		zap.String("reason", reason))
+
+	event := &models.PaymentRefundedEvent{
+		BaseEvent: models.BaseEvent{
+			EventID:   uuid.New().String(),
+			EventType: models.EventTypePaymentRefunded,
+			Timestamp: time.Now(),
+		},
+		OrderID:   quote.OrderID,
+		PaymentID: payment.ID,
+		Amount:    quote.Refund,
+		Fee:       quote.Fee,
+		Policy:    quote.Policy,
+		TxID:      payment.ProviderTxID,
+		Reason:    reason,
+	}
+
+	if err := ps.eventPublisher.PublishPaymentRefunded(ctx, event); err != nil {
+		ps.logger.Error("Failed to publish PaymentRefunded event", zap.Error(err))
+	}
 
 	return nil
 }
 
 // GetPayment retrieves payment for an order
 func (ps *PaymentService) GetPayment(ctx context.Context, orderID int64) (*models.Payment, error) {
-	return ps.store.GetPaymentByOrderID(ctx, orderID)
+	return ps.payments.GetPaymentByOrderID(ctx, orderID)
 }
@@ -0,0 +1,94 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"order-service/internal/apperrors"
+	"order-service/internal/models"
+	"order-service/internal/resilience/retry"
+	"order-service/internal/store/mocks"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestVoidPaymentIsIdempotent(t *testing.T) {
+	payments := mocks.NewPaymentRepository(t)
+	payments.On("GetPaymentByOrderID", mock.Anything, int64(1)).
+		Return(&models.Payment{ID: 5, OrderID: 1, Status: models.PaymentStatusVoided}, nil).Once()
+
+	ps := NewPaymentService(payments, nil)
+
+	assert.NoError(t, ps.VoidPayment(context.Background(), 1, "stock_commit_failed"))
+}
+
+func TestVoidPaymentRejectsUncapturedPayments(t *testing.T) {
+	payments := mocks.NewPaymentRepository(t)
+	payments.On("GetPaymentByOrderID", mock.Anything, int64(1)).
+		Return(&models.Payment{ID: 5, OrderID: 1, Status: models.PaymentStatusFailed}, nil).Once()
+
+	ps := NewPaymentService(payments, nil)
+
+	assert.Error(t, ps.VoidPayment(context.Background(), 1, "stock_commit_failed"))
+}
+
+func TestProcessPaymentSkipsOrdersWithPaymentInProgress(t *testing.T) {
+	payments := mocks.NewPaymentRepository(t)
+	payments.On("GetPaymentByOrderID", mock.Anything, int64(1)).
+		Return(&models.Payment{ID: 5, OrderID: 1, Status: models.PaymentStatusPending}, nil).Once()
+
+	ps := NewPaymentService(payments, nil)
+
+	assert.NoError(t, ps.ProcessPayment(context.Background(), PaymentRequest{OrderID: 1, UserID: 7, Amount: 100}))
+	payments.AssertNotCalled(t, "CreatePayment", mock.Anything, mock.Anything)
+}
+
+func TestProcessPaymentSkipsAmendedOrders(t *testing.T) {
+	payments := mocks.NewPaymentRepository(t)
+	payments.On("GetPaymentByOrderID", mock.Anything, int64(1)).
+		Return(nil, apperrors.New(apperrors.ErrNotFound, "payment not found")).Once()
+	payments.On("CreatePayment", mock.Anything, mock.Anything).
+		Return(apperrors.New(apperrors.ErrOrderAmended, "order 1 was amended")).Once()
+
+	ps := NewPaymentService(payments, nil)
+
+	assert.NoError(t, ps.ProcessPayment(context.Background(), PaymentRequest{OrderID: 1, UserID: 7, Amount: 100}))
+}
+
+func TestClassifyDecline(t *testing.T) {
+	assert.Equal(t, models.DeclineClassSoft, ClassifyDecline("provider_timeout"))
+	assert.Equal(t, models.DeclineClassHard, ClassifyDecline("insufficient_funds"))
+	assert.Equal(t, models.DeclineClassHard, ClassifyDecline("unknown_reason"))
+}
+
+func TestHandleDeclineSchedulesRetryOfSoftDecline(t *testing.T) {
+	payments := mocks.NewPaymentRepository(t)
+	payments.On("CountPaymentAttempts", mock.Anything, int64(1)).Return(1, nil).Once()
+	payments.On("CreatePaymentAttempt", mock.Anything, mock.MatchedBy(func(a *models.PaymentAttempt) bool {
+		return a.Attempt == 2 && a.DeclineClass == models.DeclineClassSoft &&
+			a.Status == models.PaymentAttemptScheduled && a.RetryAt != nil &&
+			// The second retry backs off twice the base delay, jittered
+			time.Until(*a.RetryAt) > 7*time.Second && time.Until(*a.RetryAt) <= 12*time.Second
+	})).Return(nil).Once()
+
+	ps := NewPaymentService(payments, nil)
+	ps.SetRetryPolicy(PaymentRetryPolicy{Policy: retry.Policy{MaxAttempts: 3, BaseDelay: 5 * time.Second, MaxDelay: time.Minute, Jitter: 0.2}})
+
+	payment := &models.Payment{ID: 5, OrderID: 1, Amount: 100, Status: models.PaymentStatusFailed}
+	assert.NoError(t, ps.handleDecline(context.Background(), payment, "provider_timeout"))
+}
+
+func TestRetryDuePaymentsDropsStaleRetries(t *testing.T) {
+	payments := mocks.NewPaymentRepository(t)
+	payments.On("DropStalePaymentRetries", mock.Anything).Return(int64(2), nil).Once()
+	payments.On("ClaimDuePaymentRetries", mock.Anything, mock.Anything, paymentRetryClaimTTL, paymentRetryBatchSize).
+		Return([]models.PaymentAttempt{}, nil).Once()
+
+	ps := NewPaymentService(payments, nil)
+
+	retried, err := ps.RetryDuePayments(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 0, retried)
+}
@@ -0,0 +1,78 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"order-service/internal/apperrors"
+	"order-service/internal/models"
+	"order-service/internal/store"
+	"order-service/internal/util"
+)
+
+// PricingService resolves customer-group contract prices for orders
+type PricingService struct {
+	pricing store.PricingRepository
+}
+
+// NewPricingService creates a new pricing service
+func NewPricingService(pricing store.PricingRepository) *PricingService {
+	return &PricingService{pricing: pricing}
+}
+
+// ApplyPriceList replaces retail prices in products with the contract prices of
+// the user's price list. It returns the applied price list ID, or nil for retail.
+// Orders that would mix contract and retail prices are rejected unless allowMixed is set.
+func (ps *PricingService) ApplyPriceList(
+	ctx context.Context,
+	userID int64,
+	products map[int64]*models.Product,
+	allowMixed bool,
+) (*int64, error) {
+	ctx, span := util.StartSpan(ctx, "PricingService.ApplyPriceList")
+	defer span.End()
+
+	group, err := ps.pricing.GetUserCustomerGroup(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve customer group: %w", err)
+	}
+	if group == "" {
+		return nil, nil
+	}
+
+	list, err := ps.pricing.GetActivePriceList(ctx, group, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve price list: %w", err)
+	}
+	if list == nil {
+		return nil, nil
+	}
+
+	productIDs := make([]int64, 0, len(products))
+	for id := range products {
+		productIDs = append(productIDs, id)
+	}
+
+	prices, err := ps.pricing.GetPriceListPrices(ctx, list.ID, productIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load price list prices: %w", err)
+	}
+	if len(prices) == 0 {
+		return nil, nil
+	}
+
+	if len(prices) < len(products) && !allowMixed {
+		return nil, apperrors.New(apperrors.ErrMixedPricing,
+			"price list %d covers %d of %d products; set allow_mixed_pricing to combine with retail prices",
+			list.ID, len(prices), len(products))
+	}
+
+	for id, price := range prices {
+		contract := *products[id]
+		contract.Price = price
+		products[id] = &contract
+	}
+
+	return &list.ID, nil
+}
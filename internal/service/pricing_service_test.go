@@ -0,0 +1,107 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"order-service/internal/apperrors"
+	"order-service/internal/models"
+	"order-service/internal/store/mocks"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplyPriceList(t *testing.T) {
+	contract := &models.PriceList{ID: 5, Name: "Wholesale 2026", CustomerGroup: "wholesale", Active: true}
+
+	tests := []struct {
+		name       string
+		group      string
+		list       *models.PriceList
+		prices     map[int64]int64
+		allowMixed bool
+		wantList   *int64
+		wantPrices map[int64]int64
+		wantErr    error
+	}{
+		{
+			name:       "users outside a customer group pay retail",
+			wantPrices: map[int64]int64{1: 1000, 2: 2000},
+		},
+		{
+			name:       "groups without an active price list pay retail",
+			group:      "wholesale",
+			wantPrices: map[int64]int64{1: 1000, 2: 2000},
+		},
+		{
+			name:       "price lists covering none of the products leave retail prices",
+			group:      "wholesale",
+			list:       contract,
+			prices:     map[int64]int64{},
+			wantPrices: map[int64]int64{1: 1000, 2: 2000},
+		},
+		{
+			name:       "the group's price list replaces every retail price",
+			group:      "wholesale",
+			list:       contract,
+			prices:     map[int64]int64{1: 800, 2: 1500},
+			wantList:   &contract.ID,
+			wantPrices: map[int64]int64{1: 800, 2: 1500},
+		},
+		{
+			name:    "contract and retail prices are not mixed by default",
+			group:   "wholesale",
+			list:    contract,
+			prices:  map[int64]int64{1: 800},
+			wantErr: apperrors.ErrMixedPricing,
+		},
+		{
+			name:       "contract and retail prices are mixed when allowed",
+			group:      "wholesale",
+			list:       contract,
+			prices:     map[int64]int64{1: 800},
+			allowMixed: true,
+			wantList:   &contract.ID,
+			wantPrices: map[int64]int64{1: 800, 2: 2000},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pricing := mocks.NewPricingRepository(t)
+			pricing.On("GetUserCustomerGroup", mock.Anything, int64(9)).Return(tt.group, nil).Once()
+			if tt.group != "" {
+				pricing.On("GetActivePriceList", mock.Anything, tt.group, mock.Anything).Return(tt.list, nil).Once()
+			}
+			if tt.list != nil {
+				pricing.On("GetPriceListPrices", mock.Anything, tt.list.ID, mock.Anything).Return(tt.prices, nil).Once()
+			}
+			products := map[int64]*models.Product{1: {ID: 1, Price: 1000}, 2: {ID: 2, Price: 2000}}
+
+			listID, err := NewPricingService(pricing).ApplyPriceList(context.Background(), 9, products, tt.allowMixed)
+			if tt.wantErr != nil {
+				assert.True(t, errors.Is(err, tt.wantErr))
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantList, listID)
+			for id, price := range tt.wantPrices {
+				assert.Equal(t, price, products[id].Price, "price of product %d", id)
+			}
+		})
+	}
+}
+
+func TestApplyPriceListDoesNotChangeTheCallersProducts(t *testing.T) {
+	pricing := mocks.NewPricingRepository(t)
+	pricing.On("GetUserCustomerGroup", mock.Anything, int64(9)).Return("wholesale", nil).Once()
+	pricing.On("GetActivePriceList", mock.Anything, "wholesale", mock.Anything).Return(&models.PriceList{ID: 5}, nil).Once()
+	pricing.On("GetPriceListPrices", mock.Anything, int64(5), mock.Anything).Return(map[int64]int64{1: 800}, nil).Once()
+	retail := &models.Product{ID: 1, Price: 1000}
+
+	_, err := NewPricingService(pricing).ApplyPriceList(context.Background(), 9, map[int64]*models.Product{1: retail}, false)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1000), retail.Price, "products may be shared with the product cache")
+}
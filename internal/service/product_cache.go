@@ -0,0 +1,219 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"order-service/internal/models"
+	"order-service/internal/redisclient"
+	"order-service/internal/store"
+	"order-service/internal/tenant"
+	"order-service/internal/util"
+
+	"go.uber.org/zap"
+	"golang.org/x/sync/singleflight"
+)
+
+// ProductCache is a read-through Redis cache in front of the product catalog,
+// optionally fronted by a small in-process LRU for the products every
+// checkout of a flash sale reads
+type ProductCache struct {
+	products store.InventoryRepository
+	redis    *redisclient.Client
+	ttl      time.Duration
+	logger   *zap.Logger
+
+	local *localCache[int64, models.Product]
+	// loads coalesces concurrent lookups of the same products missing locally
+	loads singleflight.Group
+}
+
+// NewProductCache creates a new product cache
+func NewProductCache(products store.InventoryRepository, redis *redisclient.Client, ttl time.Duration) *ProductCache {
+	return &ProductCache{
+		products: products,
+		redis:    redis,
+		ttl:      ttl,
+		logger:   util.GetLogger(),
+	}
+}
+
+// SetLocalCache keeps up to size products in process for ttl in front of
+// Redis. Replicas evict a product changed in the catalog while Run listens
+// for invalidations. Zero size or ttl disables the local tier.
+func (pc *ProductCache) SetLocalCache(size int, ttl time.Duration) {
+	pc.local = newLocalCache[int64, models.Product](size, ttl)
+}
+
+// cachedProduct is a product as stored in Redis, with the tenant that
+// models.Product does not serialize
+type cachedProduct struct {
+	models.Product
+	Tenant string `json:"tenant,omitempty"`
+}
+
+// GetProductsByIDs returns products from the local cache, then Redis, loading
+// misses from the database. Concurrent lookups of the same products missing
+// locally share one Redis read. Both tiers are shared by every tenant, so
+// products of tenants other than ctx's are left out afterwards, as the store
+// would.
+func (pc *ProductCache) GetProductsByIDs(ctx context.Context, ids []int64) ([]models.Product, error) {
+	ctx, span := util.StartSpan(ctx, "ProductCache.GetProductsByIDs")
+	defer span.End()
+
+	products, err := pc.lookup(tenant.Detach(ctx), ids)
+	if err != nil {
+		return nil, err
+	}
+	return slices.DeleteFunc(products, func(p models.Product) bool {
+		return !tenant.Owns(ctx, p.TenantID)
+	}), nil
+}
+
+// lookup returns products from the local cache, then Redis and the database
+func (pc *ProductCache) lookup(ctx context.Context, ids []int64) ([]models.Product, error) {
+	if pc.local == nil {
+		return pc.load(ctx, ids)
+	}
+
+	products := make([]models.Product, 0, len(ids))
+	var missing []int64
+	for _, id := range ids {
+		if product, ok := pc.local.get(id); ok {
+			products = append(products, product)
+			continue
+		}
+		missing = append(missing, id)
+	}
+	util.ProductCacheLocalHitsTotal.Add(float64(len(products)))
+	if len(missing) == 0 {
+		return products, nil
+	}
+
+	loaded, err, shared := pc.loads.Do(loadKey(missing), func() (interface{}, error) {
+		// Detached from the caller, whose cancellation must not fail the others
+		return pc.load(context.WithoutCancel(ctx), missing)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if shared {
+		util.ProductCacheCoalescedTotal.Inc()
+	}
+	return append(products, loaded.([]models.Product)...), nil
+}
+
+// loadKey identifies a lookup of a set of products regardless of their order
+func loadKey(ids []int64) string {
+	sorted := slices.Clone(ids)
+	slices.Sort(sorted)
+	parts := make([]string, len(sorted))
+	for i, id := range sorted {
+		parts[i] = strconv.FormatInt(id, 10)
+	}
+	return strings.Join(parts, ",")
+}
+
+// load returns products from Redis, loading misses from the database, and
+// keeps them in the local tier
+func (pc *ProductCache) load(ctx context.Context, ids []int64) ([]models.Product, error) {
+	cached, err := pc.redis.GetCachedProducts(ctx, ids)
+	if err != nil {
+		pc.logger.Warn("Product cache unavailable, falling back to DB", zap.Error(err))
+		util.ProductCacheMissesTotal.Add(float64(len(ids)))
+		products, err := pc.products.GetProductsByIDs(ctx, ids)
+		if err != nil {
+			return nil, err
+		}
+		for _, product := range products {
+			pc.local.set(product.ID, product)
+		}
+		return products, nil
+	}
+
+	products := make([]models.Product, 0, len(ids))
+	var missing []int64
+	for _, id := range ids {
+		data, ok := cached[id]
+		if !ok {
+			missing = append(missing, id)
+			continue
+		}
+
+		var entry cachedProduct
+		if err := json.Unmarshal(data, &entry); err != nil {
+			missing = append(missing, id)
+			continue
+		}
+		entry.Product.TenantID = entry.Tenant
+		products = append(products, entry.Product)
+		pc.local.set(entry.ID, entry.Product)
+	}
+
+	util.ProductCacheHitsTotal.Add(float64(len(products)))
+	util.ProductCacheMissesTotal.Add(float64(len(missing)))
+
+	if len(missing) == 0 {
+		return products, nil
+	}
+
+	// Read before the products are loaded, so that a product changed
+	// meanwhile is not cached as it was before the change
+	generations, err := pc.redis.ProductCacheGenerations(ctx, missing)
+	if err != nil {
+		pc.logger.Warn("Failed to read product cache generations", zap.Error(err))
+	}
+	loaded, loadErr := pc.products.GetProductsByIDs(ctx, missing)
+	if loadErr != nil {
+		return nil, loadErr
+	}
+	if err == nil {
+		pc.fill(ctx, loaded, generations)
+	}
+
+	return append(products, loaded...), nil
+}
+
+// fill caches products loaded from the database in both tiers, unless they
+// have been invalidated since their generation was read
+func (pc *ProductCache) fill(ctx context.Context, products []models.Product, generations map[int64]int64) {
+	entries := make(map[int64][]byte, len(products))
+	for _, product := range products {
+		if data, err := json.Marshal(cachedProduct{Product: product, Tenant: product.TenantID}); err == nil {
+			entries[product.ID] = data
+		}
+		// Stored locally first, so an invalidation broadcast after the write
+		// to Redis always finds the entry to evict
+		pc.local.set(product.ID, product)
+	}
+
+	stored, err := pc.redis.SetCachedProducts(ctx, entries, generations, pc.ttl)
+	if err != nil {
+		pc.logger.Warn("Failed to populate product cache", zap.Error(err))
+	}
+	for _, product := range products {
+		if !stored[product.ID] {
+			pc.local.evict(product.ID)
+		}
+	}
+}
+
+// Invalidate evicts a product after it changes in the catalog and broadcasts
+// the invalidation to all replicas
+func (pc *ProductCache) Invalidate(ctx context.Context, productID int64) {
+	pc.local.evict(productID)
+	if err := pc.redis.InvalidateProduct(ctx, productID); err != nil {
+		pc.logger.Error("Failed to invalidate product cache",
+			zap.Int64("product_id", productID),
+			zap.Error(err))
+	}
+}
+
+// Run listens for invalidations from other replicas until ctx is cancelled
+func (pc *ProductCache) Run(ctx context.Context) error {
+	return pc.redis.SubscribeProductInvalidations(ctx, pc.local.evict)
+}
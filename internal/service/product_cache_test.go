@@ -0,0 +1,143 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"order-service/internal/models"
+	"order-service/internal/redisclient"
+	"order-service/internal/store/mocks"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocalCacheEvictsLeastRecentlyUsedAndExpired(t *testing.T) {
+	cache := newLocalCache[int64, string](2, time.Hour)
+	cache.set(1, "a")
+	cache.set(2, "b")
+	_, _ = cache.get(1)
+	cache.set(3, "c")
+
+	_, ok := cache.get(2)
+	assert.False(t, ok, "the least recently used entry is evicted")
+	value, ok := cache.get(1)
+	assert.True(t, ok)
+	assert.Equal(t, "a", value)
+
+	expiring := newLocalCache[int64, string](2, time.Nanosecond)
+	expiring.set(1, "a")
+	time.Sleep(time.Millisecond)
+	_, ok = expiring.get(1)
+	assert.False(t, ok)
+
+	assert.Nil(t, newLocalCache[int64, string](0, time.Hour), "zero size disables the cache")
+}
+
+func TestProductCacheServesHotProductsLocally(t *testing.T) {
+	inventory := mocks.NewInventoryRepository(t)
+	inventory.On("GetProductsByIDs", mock.Anything, []int64{8}).
+		Return([]models.Product{{ID: 8, Name: "Cap"}}, nil).Once()
+	release := make(chan struct{})
+	inventory.On("GetProductsByIDs", mock.Anything, []int64{7}).
+		Run(func(mock.Arguments) { <-release }).
+		Return([]models.Product{{ID: 7, Name: "Sneaker"}}, nil).Once()
+
+	pc := NewProductCache(inventory, newTestRedis(t), time.Minute)
+	pc.SetLocalCache(10, time.Minute)
+
+	_, err := pc.GetProductsByIDs(context.Background(), []int64{8})
+	require.NoError(t, err)
+
+	// Concurrent misses share one load
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			products, err := pc.GetProductsByIDs(context.Background(), []int64{7})
+			assert.NoError(t, err)
+			assert.Len(t, products, 1)
+		}()
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	// Later reads never leave the process
+	products, err := pc.GetProductsByIDs(context.Background(), []int64{8, 7})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Cap", "Sneaker"}, []string{products[0].Name, products[1].Name})
+
+	// A catalog change evicts the local copy
+	pc.Invalidate(context.Background(), 7)
+	_, ok := pc.local.get(7)
+	assert.False(t, ok)
+}
+
+func TestProductCacheDoesNotCacheProductsInvalidatedWhileLoading(t *testing.T) {
+	server := miniredis.RunT(t)
+	client, err := redisclient.NewClient(server.Addr(), "", 0)
+	require.NoError(t, err)
+	t.Cleanup(func() { client.Close() })
+
+	inventory := mocks.NewInventoryRepository(t)
+	pc := NewProductCache(inventory, client, time.Minute)
+	pc.SetLocalCache(10, time.Minute)
+	// The price changes and is invalidated after the read saw the old one
+	inventory.On("GetProductsByIDs", mock.Anything, []int64{7}).
+		Run(func(mock.Arguments) { pc.Invalidate(context.Background(), 7) }).
+		Return([]models.Product{{ID: 7, Price: 100}}, nil).Once()
+	inventory.On("GetProductsByIDs", mock.Anything, []int64{7}).
+		Return([]models.Product{{ID: 7, Price: 120}}, nil).Once()
+
+	products, err := pc.GetProductsByIDs(context.Background(), []int64{7})
+	require.NoError(t, err)
+	assert.Equal(t, int64(100), products[0].Price)
+	assert.False(t, server.Exists("product:{7}"), "the stale copy is not cached in Redis")
+	_, ok := pc.local.get(7)
+	assert.False(t, ok, "nor locally")
+
+	products, err = pc.GetProductsByIDs(context.Background(), []int64{7})
+	require.NoError(t, err)
+	assert.Equal(t, int64(120), products[0].Price)
+	assert.True(t, server.Exists("product:{7}"), "a later read is cached again")
+}
+
+func TestProductCacheInvalidationsReachEveryReplica(t *testing.T) {
+	server := miniredis.RunT(t)
+	replica := func(inventory *mocks.InventoryRepository) *ProductCache {
+		client, err := redisclient.NewClient(server.Addr(), "", 0)
+		require.NoError(t, err)
+		t.Cleanup(func() { client.Close() })
+		pc := NewProductCache(inventory, client, time.Minute)
+		pc.SetLocalCache(10, time.Minute)
+		return pc
+	}
+	inventory := mocks.NewInventoryRepository(t)
+	inventory.On("GetProductsByIDs", mock.Anything, []int64{7}).
+		Return([]models.Product{{ID: 7, Price: 100}}, nil).Once()
+	writer, reader := replica(mocks.NewInventoryRepository(t)), replica(inventory)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go reader.Run(ctx)
+	require.Eventually(t, func() bool {
+		return server.PubSubNumSub(redisclient.ProductInvalidationChannel)[redisclient.ProductInvalidationChannel] == 1
+	}, time.Second, 10*time.Millisecond)
+
+	_, err := reader.GetProductsByIDs(ctx, []int64{7})
+	require.NoError(t, err)
+	_, ok := reader.local.get(7)
+	require.True(t, ok, "the reader caches the product locally")
+
+	writer.Invalidate(ctx, 7)
+	assert.Eventually(t, func() bool {
+		_, cached := reader.local.get(7)
+		return !cached
+	}, time.Second, 10*time.Millisecond, "the reader evicts its local copy")
+}
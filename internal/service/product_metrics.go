@@ -0,0 +1,134 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"order-service/internal/store"
+	"order-service/internal/util"
+
+	"go.uber.org/zap"
+)
+
+// ProductMetrics records per-SKU reservation metrics for an allow-list of
+// variant SKUs. Only listed variants get a sku label and the list is capped, so
+// the number of series stays bounded however many products are sold.
+type ProductMetrics struct {
+	inventory   store.InventoryRepository
+	maxProducts int
+	logger      *zap.Logger
+
+	mu       sync.Mutex
+	skus     map[int64]string
+	reserved map[int64]bool
+	soldOut  map[int64]bool
+}
+
+// NewProductMetrics creates per-product metrics tracking at most maxProducts SKUs
+func NewProductMetrics(inventory store.InventoryRepository, maxProducts int) *ProductMetrics {
+	return &ProductMetrics{
+		inventory:   inventory,
+		maxProducts: maxProducts,
+		logger:      util.GetLogger(),
+		skus:        make(map[int64]string),
+		reserved:    make(map[int64]bool),
+		soldOut:     make(map[int64]bool),
+	}
+}
+
+// Watch starts tracking the variants with the given SKUs. A product's default
+// variant shares its SKU. SKUs past the cap or not in the catalog are skipped and logged.
+func (m *ProductMetrics) Watch(ctx context.Context, skus []string) {
+	dropped := 0
+	for _, sku := range skus {
+		m.mu.Lock()
+		full := len(m.skus) >= m.maxProducts
+		m.mu.Unlock()
+		if full {
+			dropped++
+			continue
+		}
+
+		variant, err := m.inventory.GetVariantBySKU(ctx, sku)
+		if err != nil {
+			m.logger.Warn("Skipping per-product metrics for unknown SKU", zap.String("sku", sku), zap.Error(err))
+			continue
+		}
+
+		m.mu.Lock()
+		m.skus[variant.ID] = sku
+		m.mu.Unlock()
+	}
+
+	util.ProductMetricsDroppedSKUs.Set(float64(dropped))
+	if dropped > 0 {
+		m.logger.Warn("Per-product metrics allow-list exceeds its cap",
+			zap.Int("max_products", m.maxProducts),
+			zap.Int("dropped", dropped))
+	}
+}
+
+// sku returns the SKU label of a tracked variant
+func (m *ProductMetrics) sku(variantID int64) (string, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	sku, ok := m.skus[variantID]
+	return sku, ok
+}
+
+// Tracks reports whether variantID is on the allow-list
+func (m *ProductMetrics) Tracks(variantID int64) bool {
+	if m == nil {
+		return false
+	}
+	_, ok := m.sku(variantID)
+	return ok
+}
+
+// RecordReservation counts a reservation attempt of a tracked variant
+func (m *ProductMetrics) RecordReservation(variantID int64, quantity int, success bool, err error) {
+	sku, ok := m.sku(variantID)
+	if !ok {
+		return
+	}
+
+	result := "ok"
+	switch {
+	case err != nil:
+		result = "error"
+	case !success:
+		result = "insufficient"
+	}
+	util.ProductReservationsTotal.WithLabelValues(sku, result).Inc()
+	if result != "ok" {
+		return
+	}
+
+	util.ProductReservedUnitsTotal.WithLabelValues(sku).Add(float64(quantity))
+	m.mu.Lock()
+	first := !m.reserved[variantID]
+	m.reserved[variantID] = true
+	m.mu.Unlock()
+	if first {
+		util.ProductFirstReservationTimestamp.WithLabelValues(sku).Set(float64(time.Now().Unix()))
+	}
+}
+
+// RecordAvailable notes a tracked variant's available stock, stamping the
+// moment it sells out. A restock re-arms the sellout stamp.
+func (m *ProductMetrics) RecordAvailable(variantID int64, available int) {
+	sku, ok := m.sku(variantID)
+	if !ok {
+		return
+	}
+
+	m.mu.Lock()
+	wasSoldOut := m.soldOut[variantID]
+	m.soldOut[variantID] = available <= 0
+	m.mu.Unlock()
+
+	if available <= 0 && !wasSoldOut {
+		util.ProductSoldOutTimestamp.WithLabelValues(sku).Set(float64(time.Now().Unix()))
+	}
+}
@@ -0,0 +1,70 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"order-service/internal/apperrors"
+	"order-service/internal/models"
+	"order-service/internal/store/mocks"
+	"order-service/internal/util"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProductMetricsWatchCapsAllowList(t *testing.T) {
+	inventory := mocks.NewInventoryRepository(t)
+	inventory.On("GetVariantBySKU", mock.Anything, "SKU-A").Return(&models.ProductVariant{ID: 1, SKU: "SKU-A"}, nil).Once()
+	inventory.On("GetVariantBySKU", mock.Anything, "SKU-GONE").Return(nil, errors.New("not found")).Once()
+	inventory.On("GetVariantBySKU", mock.Anything, "SKU-B").Return(&models.ProductVariant{ID: 2, SKU: "SKU-B"}, nil).Once()
+
+	pm := NewProductMetrics(inventory, 2)
+	pm.Watch(context.Background(), []string{"SKU-A", "SKU-GONE", "SKU-B", "SKU-C", "SKU-D"})
+
+	assert.True(t, pm.Tracks(1))
+	assert.True(t, pm.Tracks(2))
+	assert.False(t, pm.Tracks(3))
+	assert.Equal(t, 2.0, testutil.ToFloat64(util.ProductMetricsDroppedSKUs))
+}
+
+func TestReserveStockRecordsTrackedProductMetrics(t *testing.T) {
+	ctx := context.Background()
+	inventory := mocks.NewInventoryRepository(t)
+	inventory.On("GetVariantBySKU", mock.Anything, "SKU-HOT").
+		Return(&models.ProductVariant{ID: 71, ProductID: 7, SKU: "SKU-HOT"}, nil).Once()
+	expectVariant(inventory, 71, 7)
+	inventory.On("GetProductByID", mock.Anything, int64(7)).
+		Return(&models.Product{ID: 7, ReservationStrategy: StrategyDBStrict}, nil).Once()
+	inventory.On("HoldReservation", mock.Anything, mock.Anything, int64(7), int64(71), mock.Anything).Return(true, nil).Twice()
+	inventory.On("DeleteReservation", mock.Anything, int64(2), int64(71)).Return(nil).Once()
+	inventory.On("ReserveStockTx", mock.Anything, int64(71), int64(models.DefaultWarehouseID), 2).Return(nil).Once()
+	inventory.On("ReserveStockTx", mock.Anything, int64(71), int64(models.DefaultWarehouseID), 1).
+		Return(apperrors.New(apperrors.ErrInsufficientStock, "out of stock")).Once()
+	inventory.On("AllocateReservation", mock.Anything, int64(1), int64(71), int64(models.DefaultWarehouseID)).Return(nil).Once()
+	expectDBStock(inventory, 71, 2)
+
+	redis := newTestRedis(t)
+	require.NoError(t, redis.InitInventory(ctx, 71, models.DefaultWarehouseID, 2, 0))
+
+	pm := NewProductMetrics(inventory, 5)
+	pm.Watch(ctx, []string{"SKU-HOT"})
+	ic := NewInventoryClient(inventory, redis)
+	ic.SetProductMetrics(pm)
+
+	ok, err := ic.ReserveStock(ctx, 1, 71, 2)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	ok, err = ic.ReserveStock(ctx, 2, 71, 1)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(util.ProductReservationsTotal.WithLabelValues("SKU-HOT", "ok")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(util.ProductReservationsTotal.WithLabelValues("SKU-HOT", "insufficient")))
+	assert.Equal(t, 2.0, testutil.ToFloat64(util.ProductReservedUnitsTotal.WithLabelValues("SKU-HOT")))
+	assert.NotZero(t, testutil.ToFloat64(util.ProductFirstReservationTimestamp.WithLabelValues("SKU-HOT")))
+	assert.NotZero(t, testutil.ToFloat64(util.ProductSoldOutTimestamp.WithLabelValues("SKU-HOT")))
+}
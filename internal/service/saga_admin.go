@@ -0,0 +1,227 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"order-service/internal/apperrors"
+	"order-service/internal/models"
+	"order-service/internal/util"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// Saga steps operators can replay through the admin API
+const (
+	SagaStepCommitStock = "commit_stock"
+	SagaStepCompensate  = "compensate"
+)
+
+// ForceTransition sets an order's status without running saga steps or
+// compensation. It is the escape hatch for orders the saga cannot move.
+func (so *SagaOrchestrator) ForceTransition(ctx context.Context, orderID int64, status, reason, actor string) error {
+	return so.ForceTransitionFrom(ctx, orderID, "", status, reason, actor)
+}
+
+// ForceTransitionFrom is ForceTransition for an order expected to be in
+// status from, as previewed by a dry run. An empty from accepts any status.
+func (so *SagaOrchestrator) ForceTransitionFrom(ctx context.Context, orderID int64, from, status, reason, actor string) error {
+	ctx, span := util.StartSpan(ctx, "SagaOrchestrator.ForceTransition")
+	defer span.End()
+
+	if !models.IsOrderStatus(status) {
+		return apperrors.New(apperrors.ErrInvalidRequest, "unknown order status %q", status)
+	}
+
+	ctx, lock, err := so.lockOrder(ctx, orderID)
+	if err != nil {
+		return err
+	}
+	defer so.unlockOrder(lock, orderID)
+
+	order, err := so.orders.GetOrderByID(ctx, orderID)
+	if err != nil {
+		return err
+	}
+	if from != "" && order.Status != from {
+		return apperrors.New(apperrors.ErrStalePlan, "order %d is %s, not %s as previewed", orderID, order.Status, from)
+	}
+
+	change := models.StatusChange{Reason: "forced: " + reason, Actor: actor, Forced: true}
+	if err := so.orders.UpdateOrderStatusFenced(ctx, orderID, status, lock.Token(), change); err != nil {
+		return fmt.Errorf("failed to update order status: %w", err)
+	}
+	so.orderCache.Invalidate(ctx, orderID)
+	so.statusFeed.Publish(ctx, orderID, status)
+	util.AdminActionsTotal.WithLabelValues("force_transition").Inc()
+
+	so.logger.Warn("Order status forced",
+		zap.Int64("order_id", orderID),
+		zap.String("from", order.Status),
+		zap.String("to", status),
+		zap.String("actor", actor),
+		zap.String("reason", reason))
+	return nil
+}
+
+// TransitionEffect is what forcing an order's status would change
+type TransitionEffect struct {
+	OrderID      int64  `json:"order_id"`
+	FromStatus   string `json:"from_status"`
+	ToStatus     string `json:"to_status"`
+	RowsAffected int    `json:"rows_affected"`
+}
+
+// PreviewForceTransition reports what ForceTransition would change without changing it
+func (so *SagaOrchestrator) PreviewForceTransition(ctx context.Context, orderID int64, status string) (*TransitionEffect, error) {
+	if !models.IsOrderStatus(status) {
+		return nil, apperrors.New(apperrors.ErrInvalidRequest, "unknown order status %q", status)
+	}
+
+	order, err := so.orders.GetOrderByID(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TransitionEffect{OrderID: orderID, FromStatus: order.Status, ToStatus: status, RowsAffected: 1}, nil
+}
+
+// RetriggerPayment runs payment again for a reserved order whose payment
+// failed or never happened. The outcome flows through the usual payment events.
+func (so *SagaOrchestrator) RetriggerPayment(ctx context.Context, orderID int64) error {
+	ctx, span := util.StartSpan(ctx, "SagaOrchestrator.RetriggerPayment")
+	defer span.End()
+
+	order, err := so.orders.GetOrderByID(ctx, orderID)
+	if err != nil {
+		return err
+	}
+	if order.Status != models.OrderStatusReserved {
+		return apperrors.New(apperrors.ErrInvalidOrderState,
+			"payment can only be retriggered for RESERVED orders, order %d is %s", orderID, order.Status)
+	}
+
+	payment, err := so.paymentService.GetPayment(ctx, orderID)
+	if err == nil && (payment.Status == models.PaymentStatusPending || payment.Status == models.PaymentStatusSuccess) {
+		return apperrors.New(apperrors.ErrInvalidOrderState,
+			"order %d already has a %s payment", orderID, payment.Status)
+	}
+
+	util.AdminActionsTotal.WithLabelValues("retrigger_payment").Inc()
+	so.logger.Warn("Retriggering payment", zap.Int64("order_id", orderID))
+
+	req := PaymentRequest{
+		OrderID:      orderID,
+		UserID:       order.UserID,
+		Amount:       order.TotalAmount,
+		WalletAmount: order.WalletAmount,
+	}
+	if order.Synthetic {
+		return so.paymentService.ProcessSyntheticPayment(ctx, req)
+	}
+	return so.paymentService.ProcessPayment(ctx, req)
+}
+
+// ReplayStep re-runs a saga step for an order stuck between steps:
+// commit_stock confirms a PAID or ON_HOLD order, compensate cancels an
+// unconfirmed order, voiding its payment if it was captured
+func (so *SagaOrchestrator) ReplayStep(ctx context.Context, orderID int64, step, actor string) error {
+	ctx, span := util.StartSpan(ctx, "SagaOrchestrator.ReplayStep")
+	defer span.End()
+
+	if step != SagaStepCommitStock && step != SagaStepCompensate {
+		return apperrors.New(apperrors.ErrInvalidRequest, "unknown saga step %q", step)
+	}
+
+	ctx, lock, err := so.lockOrder(ctx, orderID)
+	if err != nil {
+		return err
+	}
+	defer so.unlockOrder(lock, orderID)
+
+	order, err := so.orders.GetOrderByID(ctx, orderID)
+	if err != nil {
+		return err
+	}
+
+	so.logger.Warn("Replaying saga step",
+		zap.Int64("order_id", orderID),
+		zap.String("step", step),
+		zap.String("status", order.Status),
+		zap.String("actor", actor))
+
+	change := models.StatusChange{Reason: "replayed " + step, Actor: actor}
+	if step == SagaStepCommitStock {
+		err = so.replayCommitStock(ctx, order, lock.Token(), change)
+	} else {
+		err = so.replayCompensation(ctx, order, lock.Token(), change, "admin_compensation")
+	}
+	if err != nil {
+		return err
+	}
+
+	util.AdminActionsTotal.WithLabelValues("replay_" + step).Inc()
+	return nil
+}
+
+// replayCommitStock commits the stock of a paid order and confirms it
+func (so *SagaOrchestrator) replayCommitStock(ctx context.Context, order *models.Order, token int64, change models.StatusChange) error {
+	if order.Status != models.OrderStatusPaid && order.Status != models.OrderStatusOnHold {
+		return apperrors.New(apperrors.ErrInvalidOrderState,
+			"stock can only be committed for PAID or ON_HOLD orders, order %d is %s", order.ID, order.Status)
+	}
+
+	items, err := so.orders.GetOrderItemsByOrderID(ctx, order.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get order items: %w", err)
+	}
+	if err := so.commitOrderStock(ctx, order.ID, items); err != nil {
+		return err
+	}
+
+	if err := so.orders.UpdateOrderStatusFenced(ctx, order.ID, models.OrderStatusConfirmed, token, change); err != nil {
+		return fmt.Errorf("failed to confirm order: %w", err)
+	}
+	so.slaTracker.Record(ctx, order.ID, StageConfirmation)
+	so.orderCache.Invalidate(ctx, order.ID)
+	so.statusFeed.Publish(ctx, order.ID, models.OrderStatusConfirmed)
+	return nil
+}
+
+// replayCompensation voids any captured payment and cancels the order for
+// reason
+func (so *SagaOrchestrator) replayCompensation(ctx context.Context, order *models.Order, token int64, change models.StatusChange, reason string) error {
+	switch order.Status {
+	case models.OrderStatusCreated, models.OrderStatusReserved, models.OrderStatusPaid, models.OrderStatusOnHold:
+	default:
+		return apperrors.New(apperrors.ErrInvalidOrderState,
+			"order %d in status %s cannot be compensated", order.ID, order.Status)
+	}
+
+	if order.Status == models.OrderStatusPaid || order.Status == models.OrderStatusOnHold {
+		if err := so.paymentService.VoidPayment(ctx, order.ID, reason); err != nil {
+			return fmt.Errorf("failed to void payment: %w", err)
+		}
+	}
+
+	if err := so.cancelOrder(ctx, order.ID, token, change); err != nil {
+		return err
+	}
+
+	event := &models.OrderCancelledEvent{
+		BaseEvent: models.BaseEvent{
+			EventID:   uuid.New().String(),
+			EventType: models.EventTypeOrderCancelled,
+			Timestamp: time.Now(),
+		},
+		OrderID: order.ID,
+		Reason:  reason,
+	}
+
+	if err := so.eventPublisher.PublishOrderCancelled(ctx, event); err != nil {
+		so.logger.Error("Failed to publish OrderCancelled event", zap.Error(err))
+	}
+	return nil
+}
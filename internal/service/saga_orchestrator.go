@@ -2,47 +2,105 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"time"
 
+	"order-service/internal/apperrors"
 	"order-service/internal/broker"
 	"order-service/internal/models"
+	"order-service/internal/redisclient"
+	"order-service/internal/resilience/retry"
 	"order-service/internal/store"
 	"order-service/internal/util"
 
+	"github.com/google/uuid"
 	"go.uber.org/zap"
 )
 
+// orderLockTTL bounds how long a crashed saga step can block an order
+const orderLockTTL = 30 * time.Second
+
+// Stock commit failure policies for paid orders
+const (
+	CommitFailureVoid = "void"
+	CommitFailureHold = "hold"
+)
+
+// CommitFailurePolicy controls retries and compensation when a paid order's
+// stock cannot be committed
+type CommitFailurePolicy struct {
+	// Action is CommitFailureVoid or CommitFailureHold, applied once the
+	// retries run out
+	Action string
+	// Policy retries the commit; its MaxAttempts counts every attempt
+	retry.Policy
+}
+
 // SagaOrchestrator orchestrates the order saga workflow
 type SagaOrchestrator struct {
-	store           *store.Store
+	orders          store.OrderRepository
+	redis           *redisclient.Client
 	inventoryClient *InventoryClient
 	paymentService  *PaymentService
 	eventPublisher  *broker.EventPublisher
+	orderCache      *OrderCache
+	commitPolicy    CommitFailurePolicy
+	slaTracker      *SLATracker
+	timeline        store.TimelineRepository
+	statusFeed      *OrderStatusFeed
+	cancellations   *CancellationPolicies
+	compensations   *CompensationQueue
 	logger          *zap.Logger
 }
 
 // NewSagaOrchestrator creates a new saga orchestrator
 func NewSagaOrchestrator(
-	store *store.Store,
+	orders store.OrderRepository,
+	redis *redisclient.Client,
 	inventoryClient *InventoryClient,
 	paymentService *PaymentService,
 	eventPublisher *broker.EventPublisher,
+	orderCache *OrderCache,
+	commitPolicy CommitFailurePolicy,
 ) *SagaOrchestrator {
+	if commitPolicy.MaxAttempts <= 0 {
+		commitPolicy.MaxAttempts = 1
+	}
+
 	return &SagaOrchestrator{
-		store:           store,
+		orders:          orders,
+		redis:           redis,
 		inventoryClient: inventoryClient,
 		paymentService:  paymentService,
 		eventPublisher:  eventPublisher,
+		orderCache:      orderCache,
+		commitPolicy:    commitPolicy,
 		logger:          util.GetLogger(),
 	}
 }
 
+// SetSLATracker enables lifecycle SLA tracking
+func (so *SagaOrchestrator) SetSLATracker(tracker *SLATracker) {
+	so.slaTracker = tracker
+}
+
+// SetStatusFeed broadcasts every status transition to order tracking streams
+func (so *SagaOrchestrator) SetStatusFeed(feed *OrderStatusFeed) {
+	so.statusFeed = feed
+}
+
+// SetTimeline enables re-dispatching events missing from order timelines
+func (so *SagaOrchestrator) SetTimeline(timeline store.TimelineRepository) {
+	so.timeline = timeline
+}
+
 // HandlePaymentSuccess handles successful payment event
 func (so *SagaOrchestrator) HandlePaymentSuccess(ctx context.Context, event *models.PaymentSuccessEvent) error {
 	ctx, span := util.StartSpan(ctx, "SagaOrchestrator.HandlePaymentSuccess")
 	defer span.End()
 
-	processed, err := so.store.IsEventProcessed(ctx, event.EventID)
+	processed, err := so.orders.IsEventProcessed(ctx, event.EventID)
 	if err != nil {
 		return fmt.Errorf("failed to check event processed: %w", err)
 	}
@@ -51,39 +109,150 @@ func (so *SagaOrchestrator) HandlePaymentSuccess(ctx context.Context, event *mod
 		return nil
 	}
 
+	ctx, lock, err := so.lockOrder(ctx, event.OrderID)
+	if err != nil {
+		return err
+	}
+	defer so.unlockOrder(lock, event.OrderID)
+
 	so.logger.Info("Handling payment success",
 		zap.Int64("order_id", event.OrderID),
 		zap.String("tx_id", event.TxID))
 
-	if err := so.store.UpdateOrderStatus(ctx, event.OrderID, models.OrderStatusPaid); err != nil {
-		return fmt.Errorf("failed to update order status: %w", err)
+	// The order is paid, its stock committed, the order confirmed and the
+	// event marked processed in one transaction, retried as a whole when the
+	// stock commit fails, so a crash never leaves the step half applied
+	err = so.retryStockCommit(ctx, event.OrderID, func(ctx context.Context) error {
+		err := so.orders.WithTx(ctx, func(ctx context.Context) error {
+			if err := so.markPaid(ctx, event, lock.Token()); err != nil {
+				return err
+			}
+
+			items, err := so.orders.GetOrderItemsByOrderID(ctx, event.OrderID)
+			if err != nil {
+				return fmt.Errorf("failed to get order items: %w", err)
+			}
+			if err := so.inventoryClient.CommitOrderStock(ctx, event.OrderID, items); err != nil {
+				return &stockCommitError{err: err}
+			}
+
+			confirmed := models.StatusChange{Reason: "stock_committed", Actor: models.ActorSaga, EventID: event.EventID}
+			if err := so.orders.UpdateOrderStatusFenced(ctx, event.OrderID, models.OrderStatusConfirmed, lock.Token(), confirmed); err != nil {
+				return fmt.Errorf("failed to confirm order: %w", err)
+			}
+			store.AfterCommit(ctx, func() {
+				so.orderCache.Invalidate(ctx, event.OrderID)
+				so.slaTracker.Record(ctx, event.OrderID, StageConfirmation)
+				so.statusFeed.Publish(ctx, event.OrderID, models.OrderStatusConfirmed)
+			})
+
+			return so.markEventProcessed(ctx, event.EventID, event.EventType)
+		})
+		var commitErr *stockCommitError
+		if err != nil && !errors.As(err, &commitErr) {
+			return retry.Permanent(err)
+		}
+		return err
+	})
+
+	var commitErr *stockCommitError
+	if errors.As(err, &commitErr) {
+		err = so.orders.WithTx(ctx, func(ctx context.Context) error {
+			if err := so.markPaid(ctx, event, lock.Token()); err != nil {
+				return err
+			}
+			failed := models.StatusChange{Reason: "stock_commit_failed", Actor: models.ActorSaga, EventID: event.EventID}
+			if err := so.handleCommitFailure(ctx, event.OrderID, lock.Token(), failed, commitErr.err); err != nil {
+				return err
+			}
+			return so.markEventProcessed(ctx, event.EventID, event.EventType)
+		})
+		return err
+	}
+	if errors.Is(err, apperrors.ErrInvalidOrderState) {
+		return so.returnLatePayment(ctx, event)
+	}
+	if err != nil {
+		return err
 	}
 
-	util.OrdersPaidTotal.Inc()
+	so.logger.Info("Order confirmed", zap.Int64("order_id", event.OrderID))
+	return nil
+}
 
-	items, err := so.store.GetOrderItemsByOrderID(ctx, event.OrderID)
+// returnLatePayment handles a payment that succeeded after its order stopped
+// awaiting payment, e.g. one already in flight when the timeout reaper or the
+// customer cancelled the order. The payment of a cancelled or failed order is
+// voided, as nothing is sold for it; a failed void is queued as a compensation.
+func (so *SagaOrchestrator) returnLatePayment(ctx context.Context, event *models.PaymentSuccessEvent) error {
+	order, err := so.orders.GetOrderByID(ctx, event.OrderID)
 	if err != nil {
-		return fmt.Errorf("failed to get order items: %w", err)
+		return err
+	}
+	if order.Status != models.OrderStatusCancelled && order.Status != models.OrderStatusFailed {
+		so.logger.Warn("Ignoring payment success for an order no longer awaiting payment",
+			zap.Int64("order_id", event.OrderID),
+			zap.Int64("payment_id", event.PaymentID),
+			zap.String("status", order.Status))
+		return so.markEventProcessed(ctx, event.EventID, event.EventType)
 	}
 
-	for _, item := range items {
-		if err := so.inventoryClient.CommitStock(ctx, item.ProductID, item.Quantity); err != nil {
-			so.logger.Error("Failed to commit stock",
-				zap.Int64("product_id", item.ProductID),
+	payment, err := so.paymentService.GetPayment(ctx, event.OrderID)
+	if err != nil {
+		return fmt.Errorf("failed to get payment: %w", err)
+	}
+	if payment.ID == event.PaymentID && payment.Status == models.PaymentStatusSuccess {
+		so.logger.Warn("Payment succeeded after the order was cancelled, voiding it",
+			zap.Int64("order_id", event.OrderID),
+			zap.Int64("payment_id", payment.ID),
+			zap.String("status", order.Status))
+		quote := CancellationQuote{OrderID: event.OrderID, Captured: payment.Amount, Refund: payment.Amount}
+		if err := so.returnPayment(ctx, quote); err != nil {
+			so.logger.Error("Failed to void payment of cancelled order",
+				zap.Int64("order_id", event.OrderID),
 				zap.Error(err))
+			so.compensations.Enqueue(ctx, models.Compensation{
+				OrderID:   event.OrderID,
+				Kind:      models.CompensationCancellationRefund,
+				PaymentID: payment.ID,
+				Amount:    payment.Amount,
+				Reason:    "payment_after_cancellation",
+			}, err)
 		}
 	}
+	return so.markEventProcessed(ctx, event.EventID, event.EventType)
+}
 
-	// Update order to CONFIRMED
-	if err := so.store.UpdateOrderStatus(ctx, event.OrderID, models.OrderStatusConfirmed); err != nil {
-		so.logger.Error("Failed to confirm order", zap.Error(err))
-	}
+// stockCommitError is a failed stock commit of a paid order, which the saga
+// retries and then compensates rather than redelivering the payment event
+type stockCommitError struct {
+	err error
+}
+
+func (e *stockCommitError) Error() string { return e.err.Error() }
+
+func (e *stockCommitError) Unwrap() error { return e.err }
 
-	if err := so.store.MarkEventProcessed(ctx, event.EventID, event.EventType); err != nil {
-		so.logger.Error("Failed to mark event processed", zap.Error(err))
+// markPaid moves an order to PAID for a payment success event
+func (so *SagaOrchestrator) markPaid(ctx context.Context, event *models.PaymentSuccessEvent, token int64) error {
+	paid := models.StatusChange{Reason: "payment_succeeded", Actor: models.ActorSaga, EventID: event.EventID}
+	if err := so.orders.UpdateOrderStatusFenced(ctx, event.OrderID, models.OrderStatusPaid, token, paid); err != nil {
+		return fmt.Errorf("failed to update order status: %w", err)
 	}
+	store.AfterCommit(ctx, func() {
+		so.orderCache.Invalidate(ctx, event.OrderID)
+		so.statusFeed.Publish(ctx, event.OrderID, models.OrderStatusPaid)
+		so.slaTracker.Record(ctx, event.OrderID, StagePayment)
+		util.OrdersPaidTotal.Inc()
+	})
+	return nil
+}
 
-	so.logger.Info("Order confirmed", zap.Int64("order_id", event.OrderID))
+// markEventProcessed records a handled event in the transaction of its step
+func (so *SagaOrchestrator) markEventProcessed(ctx context.Context, eventID, eventType string) error {
+	if err := so.orders.MarkEventProcessed(ctx, eventID, eventType); err != nil {
+		return fmt.Errorf("failed to mark event processed: %w", err)
+	}
 	return nil
 }
 
@@ -92,7 +261,7 @@ func (so *SagaOrchestrator) HandlePaymentFailed(ctx context.Context, event *mode
 	ctx, span := util.StartSpan(ctx, "SagaOrchestrator.HandlePaymentFailed")
 	defer span.End()
 
-	processed, err := so.store.IsEventProcessed(ctx, event.EventID)
+	processed, err := so.orders.IsEventProcessed(ctx, event.EventID)
 	if err != nil {
 		return fmt.Errorf("failed to check event processed: %w", err)
 	}
@@ -101,33 +270,262 @@ func (so *SagaOrchestrator) HandlePaymentFailed(ctx context.Context, event *mode
 		return nil
 	}
 
+	ctx, lock, err := so.lockOrder(ctx, event.OrderID)
+	if err != nil {
+		return err
+	}
+	defer so.unlockOrder(lock, event.OrderID)
+
+	// A payment failing for an order already cancelled, e.g. at its payment
+	// deadline, has nothing left to compensate
+	order, err := so.orders.GetOrderByID(ctx, event.OrderID)
+	if err != nil {
+		return err
+	}
+	if order.Status != models.OrderStatusCreated && order.Status != models.OrderStatusReserved {
+		so.logger.Info("Order no longer awaiting payment, ignoring payment failure",
+			zap.Int64("order_id", event.OrderID),
+			zap.String("status", order.Status))
+		return so.markEventProcessed(ctx, event.EventID, event.EventType)
+	}
+
 	so.logger.Warn("Handling payment failure - starting compensation",
 		zap.Int64("order_id", event.OrderID),
 		zap.String("reason", event.Reason))
 
-	items, err := so.store.GetOrderItemsByOrderID(ctx, event.OrderID)
+	cancelled := models.StatusChange{Reason: "payment_failed: " + event.Reason, Actor: models.ActorSaga, EventID: event.EventID}
+	err = so.orders.WithTx(ctx, func(ctx context.Context) error {
+		if err := so.cancelOrder(ctx, event.OrderID, lock.Token(), cancelled); err != nil {
+			return err
+		}
+		return so.markEventProcessed(ctx, event.EventID, event.EventType)
+	})
+	if err != nil {
+		return err
+	}
+
+	so.logger.Info("Order cancelled and compensated", zap.Int64("order_id", event.OrderID))
+	return nil
+}
+
+// CancelExpiredOrders cancels and compensates orders whose payment deadline has
+// passed. Returns the number of orders cancelled.
+func (so *SagaOrchestrator) CancelExpiredOrders(ctx context.Context, limit int) (int, error) {
+	ctx, span := util.StartSpan(ctx, "SagaOrchestrator.CancelExpiredOrders")
+	defer span.End()
+
+	orders, err := so.orders.GetExpiredOrders(ctx, time.Now(), limit)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get expired orders: %w", err)
+	}
+
+	cancelled := 0
+	for _, order := range orders {
+		if err := so.expireOrder(ctx, &order); err != nil {
+			so.logger.Error("Failed to expire order",
+				zap.Int64("order_id", order.ID),
+				zap.Error(err))
+			continue
+		}
+		cancelled++
+	}
+
+	return cancelled, nil
+}
+
+// expireOrder cancels a single order that missed its payment deadline
+func (so *SagaOrchestrator) expireOrder(ctx context.Context, order *models.Order) error {
+	ctx, lock, err := so.lockOrder(ctx, order.ID)
+	if err != nil {
+		return err
+	}
+	defer so.unlockOrder(lock, order.ID)
+
+	// Re-read under the lock: a payment may have landed since the scan
+	current, err := so.orders.GetOrderByID(ctx, order.ID)
+	if err != nil {
+		return err
+	}
+	if current.Status != models.OrderStatusCreated && current.Status != models.OrderStatusReserved {
+		return nil
+	}
+
+	so.logger.Warn("Order payment deadline passed - cancelling",
+		zap.Int64("order_id", order.ID),
+		zap.String("payment_method", order.PaymentMethod))
+
+	expired := models.StatusChange{Reason: "payment_timeout", Actor: models.ActorTimeoutReaper}
+	if err := so.cancelOrder(ctx, order.ID, lock.Token(), expired); err != nil {
+		return err
+	}
+
+	util.OrdersExpiredTotal.Inc()
+
+	event := &models.OrderCancelledEvent{
+		BaseEvent: models.BaseEvent{
+			EventID:   uuid.New().String(),
+			EventType: models.EventTypeOrderCancelled,
+			Timestamp: time.Now(),
+		},
+		OrderID: order.ID,
+		Reason:  "payment_timeout",
+	}
+
+	if err := so.eventPublisher.PublishOrderCancelled(ctx, event); err != nil {
+		so.logger.Error("Failed to publish OrderCancelled event", zap.Error(err))
+	}
+
+	return nil
+}
+
+// commitOrderStock commits the order's stock, retrying with jittered exponential backoff
+func (so *SagaOrchestrator) commitOrderStock(ctx context.Context, orderID int64, items []models.OrderItem) error {
+	return so.retryStockCommit(ctx, orderID, func(ctx context.Context) error {
+		return so.inventoryClient.CommitOrderStock(ctx, orderID, items)
+	})
+}
+
+// retryStockCommit runs commit with the stock commit retry policy
+func (so *SagaOrchestrator) retryStockCommit(ctx context.Context, orderID int64, commit func(ctx context.Context) error) error {
+	policy := so.commitPolicy.Policy
+	policy.OnRetry = func(attempt int, err error) {
+		so.logger.Warn("Stock commit failed, retrying",
+			zap.Int64("order_id", orderID),
+			zap.Int("attempt", attempt),
+			zap.Error(err))
+	}
+
+	return retry.Do(ctx, policy, commit)
+}
+
+// handleCommitFailure compensates a paid order whose stock could not be
+// committed: it voids the payment and cancels the order, or puts the order on
+// hold for manual review. A failed void falls back to hold.
+func (so *SagaOrchestrator) handleCommitFailure(ctx context.Context, orderID int64, token int64, change models.StatusChange, commitErr error) error {
+	if so.commitPolicy.Action == CommitFailureVoid {
+		err := so.paymentService.VoidPayment(ctx, orderID, "stock_commit_failed")
+		if err == nil {
+			if err := so.cancelOrder(ctx, orderID, token, change); err != nil {
+				return err
+			}
+			store.AfterCommit(ctx, func() {
+				util.StockCommitFailuresTotal.WithLabelValues("voided").Inc()
+
+				event := &models.OrderCancelledEvent{
+					BaseEvent: models.BaseEvent{
+						EventID:   uuid.New().String(),
+						EventType: models.EventTypeOrderCancelled,
+						Timestamp: time.Now(),
+					},
+					OrderID: orderID,
+					Reason:  "stock_commit_failed",
+				}
+				if err := so.eventPublisher.PublishOrderCancelled(ctx, event); err != nil {
+					so.logger.Error("Failed to publish OrderCancelled event", zap.Error(err))
+				}
+			})
+			return nil
+		}
+
+		so.logger.Error("Failed to void payment, holding order",
+			zap.Int64("order_id", orderID),
+			zap.Error(err))
+	}
+
+	if err := so.orders.UpdateOrderStatusFenced(ctx, orderID, models.OrderStatusOnHold, token, change); err != nil {
+		return fmt.Errorf("failed to hold order: %w", err)
+	}
+
+	// Retry settling the held order in the background: voiding it again when
+	// the void failed, committing its stock otherwise
+	kind := models.CompensationCommitStock
+	if so.commitPolicy.Action == CommitFailureVoid {
+		kind = models.CompensationVoidPayment
+	}
+	so.compensations.Enqueue(ctx, models.Compensation{
+		OrderID: orderID,
+		Kind:    kind,
+		Reason:  "stock_commit_failed",
+	}, commitErr)
+
+	store.AfterCommit(ctx, func() {
+		so.orderCache.Invalidate(ctx, orderID)
+		so.statusFeed.Publish(ctx, orderID, models.OrderStatusOnHold)
+		util.StockCommitFailuresTotal.WithLabelValues("held").Inc()
+
+		so.logger.Error("Stock commit failed for paid order - held for manual intervention",
+			zap.Int64("order_id", orderID),
+			zap.Error(commitErr))
+
+		event := &models.OrderOnHoldEvent{
+			BaseEvent: models.BaseEvent{
+				EventID:   uuid.New().String(),
+				EventType: models.EventTypeOrderOnHold,
+				Timestamp: time.Now(),
+			},
+			OrderID: orderID,
+			Reason:  "stock_commit_failed",
+			Error:   commitErr.Error(),
+		}
+		if err := so.eventPublisher.PublishOrderOnHold(ctx, event); err != nil {
+			so.logger.Error("Failed to publish OrderOnHold event", zap.Error(err))
+		}
+	})
+	return nil
+}
+
+// cancelOrder releases reserved stock, restocks already committed items and
+// marks the order cancelled
+func (so *SagaOrchestrator) cancelOrder(ctx context.Context, orderID int64, token int64, change models.StatusChange) error {
+	items, err := so.orders.GetOrderItemsByOrderID(ctx, orderID)
 	if err != nil {
 		return fmt.Errorf("failed to get order items: %w", err)
 	}
 
 	for _, item := range items {
-		if err := so.inventoryClient.ReleaseStock(ctx, item.ProductID, item.Quantity); err != nil {
+		if err := so.inventoryClient.ReleaseStock(ctx, orderID, item.VariantID); err != nil {
 			so.logger.Error("Failed to release stock during compensation",
-				zap.Int64("product_id", item.ProductID),
+				zap.Int64("variant_id", item.VariantID),
 				zap.Error(err))
+			so.compensations.Enqueue(ctx, models.Compensation{
+				OrderID:   orderID,
+				Kind:      models.CompensationReleaseStock,
+				VariantID: item.VariantID,
+				Reason:    change.Reason,
+			}, err)
 		}
 	}
 
-	if err := so.store.UpdateOrderStatus(ctx, event.OrderID, models.OrderStatusCancelled); err != nil {
+	if err := so.orders.UpdateOrderStatusFenced(ctx, orderID, models.OrderStatusCancelled, token, change); err != nil {
 		return fmt.Errorf("failed to update order status: %w", err)
 	}
+	store.AfterCommit(ctx, func() {
+		so.orderCache.Invalidate(ctx, orderID)
+		so.statusFeed.Publish(ctx, orderID, models.OrderStatusCancelled)
+		util.OrdersCancelledTotal.Inc()
+	})
+	return nil
+}
 
-	util.OrdersCancelledTotal.Inc()
-
-	if err := so.store.MarkEventProcessed(ctx, event.EventID, event.EventType); err != nil {
-		so.logger.Error("Failed to mark event processed", zap.Error(err))
+// lockOrder acquires the per-order saga lock. The returned context is
+// cancelled if the lock is lost, so a step still running stops instead of
+// racing the next holder.
+func (so *SagaOrchestrator) lockOrder(ctx context.Context, orderID int64) (context.Context, *redisclient.Lock, error) {
+	lock, err := so.redis.ObtainLock(ctx, fmt.Sprintf("order:%d", orderID), orderLockTTL)
+	if err != nil {
+		return ctx, nil, fmt.Errorf("failed to lock order %d: %w", orderID, err)
 	}
+	return lock.Context(ctx), lock, nil
+}
 
-	so.logger.Info("Order cancelled and compensated", zap.Int64("order_id", event.OrderID))
-	return nil
+// unlockOrder releases the per-order saga lock
+func (so *SagaOrchestrator) unlockOrder(lock *redisclient.Lock, orderID int64) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := lock.Release(ctx); err != nil {
+		so.logger.Warn("Failed to release order lock",
+			zap.Int64("order_id", orderID),
+			zap.Error(err))
+	}
 }
@@ -0,0 +1,208 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"order-service/internal/apperrors"
+	"order-service/internal/models"
+	"order-service/internal/resilience/retry"
+	"order-service/internal/store/mocks"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestHandlePaymentSuccessSkipsProcessedEvents(t *testing.T) {
+	orders := mocks.NewOrderRepository(t)
+	orders.On("IsEventProcessed", mock.Anything, "evt-1").Return(true, nil).Once()
+
+	so := NewSagaOrchestrator(orders, nil, nil, nil, nil, nil, CommitFailurePolicy{})
+
+	err := so.HandlePaymentSuccess(context.Background(), &models.PaymentSuccessEvent{
+		BaseEvent: models.BaseEvent{EventID: "evt-1", EventType: models.EventTypePaymentSuccess},
+		OrderID:   1,
+	})
+	assert.NoError(t, err)
+}
+
+func TestHandlePaymentFailedSkipsProcessedEvents(t *testing.T) {
+	orders := mocks.NewOrderRepository(t)
+	orders.On("IsEventProcessed", mock.Anything, "evt-2").Return(true, nil).Once()
+
+	so := NewSagaOrchestrator(orders, nil, nil, nil, nil, nil, CommitFailurePolicy{})
+
+	err := so.HandlePaymentFailed(context.Background(), &models.PaymentFailedEvent{
+		BaseEvent: models.BaseEvent{EventID: "evt-2", EventType: models.EventTypePaymentFailed},
+		OrderID:   1,
+	})
+	assert.NoError(t, err)
+}
+
+func TestRetryStockCommitRetriesUnderThePolicy(t *testing.T) {
+	so := NewSagaOrchestrator(mocks.NewOrderRepository(t), nil, nil, nil, nil, nil, CommitFailurePolicy{
+		Policy: retry.Policy{MaxAttempts: 3, BaseDelay: time.Millisecond, Jitter: 0.2},
+	})
+
+	calls := 0
+	err := so.retryStockCommit(context.Background(), 1, func(context.Context) error {
+		calls++
+		if calls < 3 {
+			return errors.New("redis unavailable")
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, calls)
+
+	calls = 0
+	err = so.retryStockCommit(context.Background(), 1, func(context.Context) error {
+		calls++
+		return errors.New("redis unavailable")
+	})
+	assert.Error(t, err)
+	assert.Equal(t, 3, calls, "MaxAttempts counts every attempt")
+}
+
+func TestForceTransitionRejectsUnknownStatus(t *testing.T) {
+	so := NewSagaOrchestrator(mocks.NewOrderRepository(t), nil, nil, nil, nil, nil, CommitFailurePolicy{})
+
+	err := so.ForceTransition(context.Background(), 1, "SHIPPED", "typo", "admin:operator")
+	assert.ErrorIs(t, err, apperrors.ErrInvalidRequest)
+}
+
+func TestRetriggerPaymentRequiresReservedOrder(t *testing.T) {
+	orders := mocks.NewOrderRepository(t)
+	orders.On("GetOrderByID", mock.Anything, int64(3)).
+		Return(&models.Order{ID: 3, Status: models.OrderStatusConfirmed}, nil).Once()
+
+	so := NewSagaOrchestrator(orders, nil, nil, nil, nil, nil, CommitFailurePolicy{})
+
+	err := so.RetriggerPayment(context.Background(), 3)
+	assert.ErrorIs(t, err, apperrors.ErrInvalidOrderState)
+}
+
+func TestRecoverStalledOrdersSkipsOrdersThatMovedOn(t *testing.T) {
+	orders := mocks.NewOrderRepository(t)
+	orders.On("GetStalledOrders", mock.Anything, mock.Anything, 10).
+		Return([]models.Order{{ID: 4, Status: models.OrderStatusReserved}}, nil).Once()
+	orders.On("GetOrderByID", mock.Anything, int64(4)).
+		Return(&models.Order{ID: 4, Status: models.OrderStatusConfirmed}, nil).Once()
+
+	so := NewSagaOrchestrator(orders, newTestRedis(t), nil, nil, nil, nil, CommitFailurePolicy{})
+
+	recovered, err := so.RecoverStalledOrders(context.Background(), RecoveryPolicy{MaxAttempts: 3, BatchSize: 10})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, recovered)
+	orders.AssertNotCalled(t, "MarkOrderRecoveryAttempt", mock.Anything, mock.Anything)
+}
+
+func TestRedispatchMissingEventsSkipsOrdersThatMovedOrPublished(t *testing.T) {
+	orders := mocks.NewOrderRepository(t)
+	orders.On("GetOrderByID", mock.Anything, int64(4)).
+		Return(&models.Order{ID: 4, Status: models.OrderStatusPaid}, nil).Once()
+	orders.On("GetOrderByID", mock.Anything, int64(5)).
+		Return(&models.Order{ID: 5, Status: models.OrderStatusCancelled}, nil).Once()
+
+	timeline := mocks.NewTimelineRepository(t)
+	timeline.On("GetOrdersMissingEvents", mock.Anything, mock.Anything, mock.Anything, int64(0), 10).
+		Return([]models.Order{
+			{ID: 4, Status: models.OrderStatusReserved},
+			{ID: 5, Status: models.OrderStatusCancelled},
+		}, nil).Once()
+	timeline.On("GetOrderTimeline", mock.Anything, int64(5)).
+		Return([]models.OrderTimelineEntry{{EventType: models.EventTypeOrderCancelled}}, nil).Once()
+
+	so := NewSagaOrchestrator(orders, newTestRedis(t), nil, nil, nil, nil, CommitFailurePolicy{})
+	so.SetTimeline(timeline)
+
+	redispatched, err := so.RedispatchMissingEvents(context.Background(), RedispatchPolicy{BatchSize: 10})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, redispatched)
+}
+
+func TestHandlePaymentFailedMarksEventProcessedInTheCancellation(t *testing.T) {
+	orders := mocks.NewOrderRepository(t)
+	orders.On("IsEventProcessed", mock.Anything, "evt-3").Return(false, nil).Once()
+	orders.On("GetOrderByID", mock.Anything, int64(7)).
+		Return(&models.Order{ID: 7, Status: models.OrderStatusReserved}, nil).Once()
+	orders.On("WithTx", mock.Anything, mock.Anything).
+		Return(func(ctx context.Context, fn func(context.Context) error) error { return fn(ctx) }).Once()
+	orders.On("GetOrderItemsByOrderID", mock.Anything, int64(7)).Return([]models.OrderItem{}, nil).Once()
+	orders.On("UpdateOrderStatusFenced", mock.Anything, int64(7), models.OrderStatusCancelled, mock.Anything, mock.Anything).
+		Return(errors.New("connection reset")).Once()
+
+	so := NewSagaOrchestrator(orders, newTestRedis(t), nil, nil, nil, nil, CommitFailurePolicy{})
+
+	err := so.HandlePaymentFailed(context.Background(), &models.PaymentFailedEvent{
+		BaseEvent: models.BaseEvent{EventID: "evt-3", EventType: models.EventTypePaymentFailed},
+		OrderID:   7,
+	})
+	assert.Error(t, err)
+	orders.AssertNotCalled(t, "MarkEventProcessed", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestHandlePaymentFailedSkipsOrdersNoLongerAwaitingPayment(t *testing.T) {
+	orders := mocks.NewOrderRepository(t)
+	orders.On("IsEventProcessed", mock.Anything, "evt-4").Return(false, nil).Once()
+	orders.On("GetOrderByID", mock.Anything, int64(8)).
+		Return(&models.Order{ID: 8, Status: models.OrderStatusCancelled}, nil).Once()
+	orders.On("MarkEventProcessed", mock.Anything, "evt-4", models.EventTypePaymentFailed).Return(nil).Once()
+
+	so := NewSagaOrchestrator(orders, newTestRedis(t), nil, nil, nil, nil, CommitFailurePolicy{})
+
+	err := so.HandlePaymentFailed(context.Background(), &models.PaymentFailedEvent{
+		BaseEvent: models.BaseEvent{EventID: "evt-4", EventType: models.EventTypePaymentFailed},
+		OrderID:   8,
+	})
+	assert.NoError(t, err)
+	orders.AssertNotCalled(t, "UpdateOrderStatusFenced", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestAmendOrderRejectsOrdersPastPayment(t *testing.T) {
+	orders := mocks.NewOrderRepository(t)
+	orders.On("GetOrderByID", mock.Anything, int64(6)).
+		Return(&models.Order{ID: 6, UserID: 9, Status: models.OrderStatusPaid}, nil).Once()
+
+	so := NewSagaOrchestrator(orders, newTestRedis(t), nil, nil, nil, nil, CommitFailurePolicy{})
+
+	_, err := so.AmendOrder(context.Background(), 6, AmendOrderRequest{
+		UserID: 9,
+		Items:  []OrderItemChange{{VariantID: 1, Quantity: 1}},
+	})
+	assert.ErrorIs(t, err, apperrors.ErrInvalidOrderState)
+}
+
+func TestApplyItemChanges(t *testing.T) {
+	items := []models.OrderItem{
+		{VariantID: 1, Quantity: 2, UnitPrice: 100},
+		{VariantID: 2, Quantity: 1, UnitPrice: 50},
+		{VariantID: 3, Quantity: 1, UnitPrice: 70, FulfillmentStatus: models.FulfillmentStatusBackordered},
+	}
+
+	amended, changed, err := applyItemChanges(1, items, []OrderItemChange{
+		{VariantID: 1, Quantity: 3},
+		{VariantID: 2, Quantity: 0},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []models.OrderItem{
+		{VariantID: 1, Quantity: 3, UnitPrice: 100},
+		items[2],
+	}, amended)
+	assert.Equal(t, []itemChange{{from: items[0], to: 3}, {from: items[1], to: 0}}, changed)
+
+	for name, changes := range map[string][]OrderItemChange{
+		"unknown variant":   {{VariantID: 4, Quantity: 1}},
+		"duplicate variant": {{VariantID: 1, Quantity: 1}, {VariantID: 1, Quantity: 3}},
+		"backordered item":  {{VariantID: 3, Quantity: 2}},
+		"unchanged":         {{VariantID: 1, Quantity: 2}},
+	} {
+		_, _, err := applyItemChanges(1, items, changes)
+		assert.ErrorIs(t, err, apperrors.ErrInvalidRequest, name)
+	}
+
+	_, _, err = applyItemChanges(1, items[:2], []OrderItemChange{{VariantID: 1, Quantity: 0}, {VariantID: 2, Quantity: 0}})
+	assert.ErrorIs(t, err, apperrors.ErrInvalidRequest, "all removed")
+}
@@ -0,0 +1,249 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"order-service/internal/models"
+	"order-service/internal/util"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// RecoveryPolicy controls which stalled orders saga recovery picks up and when it gives up
+type RecoveryPolicy struct {
+	StallThreshold time.Duration
+	MaxAttempts    int
+	BatchSize      int
+}
+
+// RecoverStalledOrders resumes or compensates orders whose saga stopped
+// moving, e.g. because the process crashed between steps. Returns the number
+// of orders acted on.
+func (so *SagaOrchestrator) RecoverStalledOrders(ctx context.Context, policy RecoveryPolicy) (int, error) {
+	ctx, span := util.StartSpan(ctx, "SagaOrchestrator.RecoverStalledOrders")
+	defer span.End()
+
+	orders, err := so.orders.GetStalledOrders(ctx, time.Now().Add(-policy.StallThreshold), policy.BatchSize)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get stalled orders: %w", err)
+	}
+
+	recovered := 0
+	for _, order := range orders {
+		acted, err := so.recoverOrder(ctx, order.ID, policy)
+		if err != nil {
+			so.logger.Error("Failed to recover order",
+				zap.Int64("order_id", order.ID),
+				zap.Error(err))
+			continue
+		}
+		if acted {
+			recovered++
+		}
+	}
+
+	return recovered, nil
+}
+
+// recoverOrder picks the recovery action for one stalled order from its
+// persisted saga state. Returns false if the order no longer needs recovery.
+func (so *SagaOrchestrator) recoverOrder(ctx context.Context, orderID int64, policy RecoveryPolicy) (bool, error) {
+	ctx, lock, err := so.lockOrder(ctx, orderID)
+	if err != nil {
+		return false, err
+	}
+	defer so.unlockOrder(lock, orderID)
+
+	// Re-read under the lock: the saga may have moved since the scan
+	order, err := so.orders.GetOrderByID(ctx, orderID)
+	if err != nil {
+		return false, err
+	}
+	if order.Status != models.OrderStatusCreated && order.Status != models.OrderStatusReserved &&
+		order.Status != models.OrderStatusPaid {
+		return false, nil
+	}
+
+	so.logger.Warn("Recovering stalled order",
+		zap.Int64("order_id", order.ID),
+		zap.String("status", order.Status),
+		zap.Int("attempt", order.RecoveryAttempts+1))
+
+	var action string
+	switch {
+	case order.Status == models.OrderStatusPaid:
+		// Payment captured but stock never committed: finish the step
+		action, err = so.resumeStockCommit(ctx, order, lock.Token())
+
+	case order.Status == models.OrderStatusCreated || order.RecoveryAttempts >= policy.MaxAttempts:
+		// Reservation was interrupted, or nudging did not help: give the stock back
+		action, err = so.compensateStalledOrder(ctx, order, lock.Token())
+
+	default:
+		action, err = so.republishPaymentStep(ctx, order)
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if err := so.orders.MarkOrderRecoveryAttempt(ctx, order.ID); err != nil {
+		so.logger.Error("Failed to record recovery attempt", zap.Int64("order_id", order.ID), zap.Error(err))
+	}
+	util.SagaRecoveryActionsTotal.WithLabelValues(action).Inc()
+	return true, nil
+}
+
+// resumeStockCommit commits the stock of a paid order and confirms it,
+// compensating per the commit failure policy if the commit keeps failing
+func (so *SagaOrchestrator) resumeStockCommit(ctx context.Context, order *models.Order, token int64) (string, error) {
+	items, err := so.orders.GetOrderItemsByOrderID(ctx, order.ID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get order items: %w", err)
+	}
+
+	if err := so.commitOrderStock(ctx, order.ID, items); err != nil {
+		failed := models.StatusChange{Reason: "stock_commit_failed", Actor: models.ActorSagaRecovery}
+		if err := so.handleCommitFailure(ctx, order.ID, token, failed, err); err != nil {
+			return "", err
+		}
+		return "commit_failed", nil
+	}
+
+	confirmed := models.StatusChange{Reason: "stock_committed", Actor: models.ActorSagaRecovery}
+	if err := so.orders.UpdateOrderStatusFenced(ctx, order.ID, models.OrderStatusConfirmed, token, confirmed); err != nil {
+		return "", fmt.Errorf("failed to confirm order: %w", err)
+	}
+	so.slaTracker.Record(ctx, order.ID, StageConfirmation)
+	so.orderCache.Invalidate(ctx, order.ID)
+	so.statusFeed.Publish(ctx, order.ID, models.OrderStatusConfirmed)
+	return "resumed_commit", nil
+}
+
+// compensateStalledOrder voids a payment captured for the order, releases its
+// stock and cancels it
+func (so *SagaOrchestrator) compensateStalledOrder(ctx context.Context, order *models.Order, token int64) (string, error) {
+	reason := "saga_stalled"
+	if order.Status == models.OrderStatusCreated {
+		reason = "reservation_interrupted"
+	}
+
+	if payment, err := so.paymentService.GetPayment(ctx, order.ID); err == nil && payment.Status == models.PaymentStatusSuccess {
+		if err := so.paymentService.VoidPayment(ctx, order.ID, reason); err != nil {
+			return "", fmt.Errorf("failed to void payment: %w", err)
+		}
+	}
+
+	change := models.StatusChange{Reason: reason, Actor: models.ActorSagaRecovery}
+	if err := so.cancelOrder(ctx, order.ID, token, change); err != nil {
+		return "", err
+	}
+
+	event := &models.OrderCancelledEvent{
+		BaseEvent: models.BaseEvent{
+			EventID:   uuid.New().String(),
+			EventType: models.EventTypeOrderCancelled,
+			Timestamp: time.Now(),
+		},
+		OrderID: order.ID,
+		Reason:  reason,
+	}
+
+	if err := so.eventPublisher.PublishOrderCancelled(ctx, event); err != nil {
+		so.logger.Error("Failed to publish OrderCancelled event", zap.Error(err))
+	}
+	return "compensated", nil
+}
+
+// republishPaymentStep re-publishes the event a reserved order is waiting on:
+// the payment outcome if one was recorded, otherwise OrderReserved to start payment
+func (so *SagaOrchestrator) republishPaymentStep(ctx context.Context, order *models.Order) (string, error) {
+	base := models.BaseEvent{EventID: uuid.New().String(), Timestamp: time.Now()}
+
+	payment, err := so.paymentService.GetPayment(ctx, order.ID)
+	if err != nil {
+		event, err := so.orderReservedEvent(ctx, order)
+		if err != nil {
+			return "", err
+		}
+		if err := so.eventPublisher.PublishOrderReserved(ctx, event); err != nil {
+			return "", fmt.Errorf("failed to republish OrderReserved: %w", err)
+		}
+		return "republished_order_reserved", nil
+	}
+
+	switch payment.Status {
+	case models.PaymentStatusSuccess:
+		base.EventType = models.EventTypePaymentSuccess
+		event := &models.PaymentSuccessEvent{
+			BaseEvent: base,
+			OrderID:   order.ID,
+			PaymentID: payment.ID,
+			Amount:    payment.Amount,
+			TxID:      payment.ProviderTxID,
+		}
+		if err := so.eventPublisher.PublishPaymentSuccess(ctx, event); err != nil {
+			return "", fmt.Errorf("failed to republish PaymentSuccess: %w", err)
+		}
+		return "republished_payment_success", nil
+
+	case models.PaymentStatusFailed:
+		scheduled, err := so.paymentService.RetryScheduled(ctx, order.ID)
+		if err != nil {
+			return "", fmt.Errorf("failed to check payment retry: %w", err)
+		}
+		if scheduled {
+			// The payment retrier owns it until the retry is made
+			return "awaiting_payment_retry", nil
+		}
+
+		base.EventType = models.EventTypePaymentFailed
+		event := &models.PaymentFailedEvent{
+			BaseEvent: base,
+			OrderID:   order.ID,
+			PaymentID: payment.ID,
+			Reason:    "recovered_payment_failure",
+		}
+		if err := so.eventPublisher.PublishPaymentFailed(ctx, event); err != nil {
+			return "", fmt.Errorf("failed to republish PaymentFailed: %w", err)
+		}
+		return "republished_payment_failed", nil
+	}
+
+	// Payment still in flight; the payment deadline reaper owns it from here
+	return "awaiting_payment", nil
+}
+
+// orderReservedEvent rebuilds the OrderReserved event of an order from its items
+func (so *SagaOrchestrator) orderReservedEvent(ctx context.Context, order *models.Order) (*models.OrderReservedEvent, error) {
+	items, err := so.orders.GetOrderItemsByOrderID(ctx, order.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get order items: %w", err)
+	}
+
+	itemData := make([]models.OrderItemData, 0, len(items))
+	for _, item := range items {
+		itemData = append(itemData, models.OrderItemData{
+			ProductID: item.ProductID,
+			VariantID: item.VariantID,
+			Quantity:  item.Quantity,
+			UnitPrice: item.UnitPrice,
+		})
+	}
+
+	return &models.OrderReservedEvent{
+		BaseEvent: models.BaseEvent{
+			EventID:   uuid.New().String(),
+			EventType: models.EventTypeOrderReserved,
+			Timestamp: time.Now(),
+		},
+		OrderID:      order.ID,
+		UserID:       order.UserID,
+		TotalAmount:  order.TotalAmount,
+		WalletAmount: order.WalletAmount,
+		Items:        itemData,
+		Synthetic:    order.Synthetic,
+	}, nil
+}
@@ -0,0 +1,81 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"order-service/internal/models"
+	"order-service/internal/store"
+	"order-service/internal/util"
+)
+
+// inFlightStatuses are the statuses of orders whose saga is still running
+var inFlightStatuses = []string{
+	models.OrderStatusCreated,
+	models.OrderStatusReserved,
+	models.OrderStatusPaid,
+}
+
+// BacklogSource reports how far a consumer is behind its topic
+type BacklogSource interface {
+	Lag() int64
+	Topic() string
+}
+
+// ConsumerBacklog is the backlog of one worker's consumer
+type ConsumerBacklog struct {
+	Topic    string `json:"topic"`
+	Messages int64  `json:"messages"`
+}
+
+// ScalingSnapshot holds the autoscaling signals of the order pipeline
+type ScalingSnapshot struct {
+	Backlog               map[string]ConsumerBacklog `json:"backlog"`
+	TotalBacklog          int64                      `json:"total_backlog"`
+	SagasInFlight         int                        `json:"sagas_in_flight"`
+	ReservationQueueDepth int                        `json:"reservation_queue_depth"`
+}
+
+// ScalingMonitor derives autoscaling signals from consumer lag and order
+// states, so workers can scale on real backlog instead of CPU
+type ScalingMonitor struct {
+	orders  store.OrderRepository
+	sources map[string]BacklogSource
+}
+
+// NewScalingMonitor creates a new scaling monitor
+func NewScalingMonitor(orders store.OrderRepository) *ScalingMonitor {
+	return &ScalingMonitor{
+		orders:  orders,
+		sources: make(map[string]BacklogSource),
+	}
+}
+
+// Watch adds a worker's consumer to the reported backlog
+func (m *ScalingMonitor) Watch(worker string, source BacklogSource) {
+	m.sources[worker] = source
+}
+
+// Snapshot collects the current signals and mirrors them to the Prometheus gauges
+func (m *ScalingMonitor) Snapshot(ctx context.Context) (*ScalingSnapshot, error) {
+	snapshot := &ScalingSnapshot{Backlog: make(map[string]ConsumerBacklog, len(m.sources))}
+	for worker, source := range m.sources {
+		backlog := ConsumerBacklog{Topic: source.Topic(), Messages: source.Lag()}
+		snapshot.Backlog[worker] = backlog
+		snapshot.TotalBacklog += backlog.Messages
+		util.ConsumerBacklog.WithLabelValues(worker, backlog.Topic).Set(float64(backlog.Messages))
+	}
+
+	counts, err := m.orders.CountOrdersByStatus(ctx, inFlightStatuses)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count in-flight orders: %w", err)
+	}
+	for _, count := range counts {
+		snapshot.SagasInFlight += count
+	}
+	snapshot.ReservationQueueDepth = counts[models.OrderStatusReserved]
+
+	util.SagasInFlight.Set(float64(snapshot.SagasInFlight))
+	util.ReservationQueueDepth.Set(float64(snapshot.ReservationQueueDepth))
+	return snapshot, nil
+}
@@ -0,0 +1,41 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"order-service/internal/models"
+	"order-service/internal/store/mocks"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeBacklog struct {
+	topic string
+	lag   int64
+}
+
+func (f fakeBacklog) Lag() int64    { return f.lag }
+func (f fakeBacklog) Topic() string { return f.topic }
+
+func TestScalingMonitorSnapshot(t *testing.T) {
+	orders := mocks.NewOrderRepository(t)
+	orders.On("CountOrdersByStatus", mock.Anything, inFlightStatuses).Return(map[string]int{
+		models.OrderStatusCreated:  1,
+		models.OrderStatusReserved: 4,
+		models.OrderStatusPaid:     2,
+	}, nil).Once()
+
+	monitor := NewScalingMonitor(orders)
+	monitor.Watch("order-worker", fakeBacklog{topic: "order-events", lag: 10})
+	monitor.Watch("payment-worker", fakeBacklog{topic: "order-events", lag: 5})
+
+	snapshot, err := monitor.Snapshot(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, ConsumerBacklog{Topic: "order-events", Messages: 10}, snapshot.Backlog["order-worker"])
+	assert.Equal(t, int64(15), snapshot.TotalBacklog)
+	assert.Equal(t, 7, snapshot.SagasInFlight)
+	assert.Equal(t, 4, snapshot.ReservationQueueDepth)
+}
@@ -0,0 +1,137 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"order-service/internal/apperrors"
+	"order-service/internal/broker"
+	"order-service/internal/models"
+	"order-service/internal/redisclient"
+	"order-service/internal/store"
+	"order-service/internal/util"
+
+	"go.uber.org/zap"
+)
+
+// scheduledClaimTTL is how long a claimed event is held before another
+// dispatcher may take it over
+const scheduledClaimTTL = time.Minute
+
+// ScheduledEventDispatcher publishes the events scheduled with
+// EventPublisher.PublishAt once they are due
+type ScheduledEventDispatcher struct {
+	redis     *redisclient.Client
+	producer  *broker.Producer
+	orders    store.OrderRepository
+	batchSize int
+	logger    *zap.Logger
+}
+
+// NewScheduledEventDispatcher creates a new scheduled event dispatcher
+func NewScheduledEventDispatcher(redis *redisclient.Client, producer *broker.Producer, orders store.OrderRepository) *ScheduledEventDispatcher {
+	return &ScheduledEventDispatcher{
+		redis:     redis,
+		producer:  producer,
+		orders:    orders,
+		batchSize: 100,
+		logger:    util.GetLogger(),
+	}
+}
+
+// DispatchDue publishes the scheduled events that are due. Reminders for
+// orders no longer awaiting payment are dropped. An event that fails to publish
+// stays claimed and is retried once its claim expires. Returns the number of
+// events published.
+func (d *ScheduledEventDispatcher) DispatchDue(ctx context.Context) (int, error) {
+	ctx, span := util.StartSpan(ctx, "ScheduledEventDispatcher.DispatchDue")
+	defer span.End()
+
+	if pending, err := d.redis.CountScheduledEvents(ctx); err == nil {
+		util.ScheduledEventsPending.Set(float64(pending))
+	}
+
+	published := 0
+	for {
+		events, err := d.redis.ClaimDueEvents(ctx, time.Now(), scheduledClaimTTL, d.batchSize)
+		if err != nil {
+			return published, fmt.Errorf("failed to claim scheduled events: %w", err)
+		}
+
+		for _, event := range events {
+			if d.dispatch(ctx, event) {
+				published++
+			}
+		}
+
+		if len(events) < d.batchSize {
+			return published, nil
+		}
+	}
+}
+
+// dispatch publishes one claimed event, reporting whether it was published
+func (d *ScheduledEventDispatcher) dispatch(ctx context.Context, event redisclient.ScheduledEvent) bool {
+	var base struct {
+		EventType string `json:"event_type"`
+		OrderID   int64  `json:"order_id"`
+	}
+	_ = json.Unmarshal(event.Payload, &base)
+
+	result := "published"
+	defer func() {
+		util.ScheduledEventsDispatchedTotal.WithLabelValues(base.EventType, result).Inc()
+	}()
+
+	relevant, err := d.stillRelevant(ctx, base.EventType, base.OrderID)
+	if err != nil {
+		result = "failed"
+		d.logger.Warn("Failed to check scheduled event, will retry",
+			zap.String("event_id", event.ID), zap.Error(err))
+		return false
+	}
+
+	if relevant {
+		err = d.producer.PublishEvent(ctx, event.Key, event.Payload)
+		switch {
+		case errors.Is(err, broker.ErrInvalidEvent):
+			result = "invalid"
+			d.logger.Error("Dropping invalid scheduled event",
+				zap.String("event_id", event.ID), zap.Error(err))
+		case err != nil:
+			result = "failed"
+			d.logger.Warn("Failed to publish scheduled event, will retry",
+				zap.String("event_id", event.ID), zap.Error(err))
+			return false
+		}
+	} else {
+		result = "skipped"
+	}
+
+	if err := d.redis.AckScheduledEvent(ctx, event.ID); err != nil {
+		// The event becomes due again when the claim expires, and may be published twice
+		d.logger.Warn("Failed to acknowledge scheduled event",
+			zap.String("event_id", event.ID), zap.Error(err))
+	}
+	return result == "published"
+}
+
+// stillRelevant reports whether a due event should still be published.
+// Reminders only make sense while the order waits for payment.
+func (d *ScheduledEventDispatcher) stillRelevant(ctx context.Context, eventType string, orderID int64) (bool, error) {
+	switch eventType {
+	case models.EventTypePaymentReminder, models.EventTypeReservationExpiring:
+		order, err := d.orders.GetOrderByID(ctx, orderID)
+		if errors.Is(err, apperrors.ErrOrderNotFound) {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		return order.Status == models.OrderStatusReserved, nil
+	}
+	return true, nil
+}
@@ -0,0 +1,35 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"order-service/internal/models"
+	"order-service/internal/store/mocks"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDispatchDueDropsRemindersForPaidOrders(t *testing.T) {
+	redis := newTestRedis(t)
+	orders := mocks.NewOrderRepository(t)
+	orders.On("GetOrderByID", mock.Anything, int64(7)).
+		Return(&models.Order{ID: 7, Status: models.OrderStatusPaid}, nil).Once()
+
+	ctx := context.Background()
+	payload := []byte(`{"event_type":"PAYMENT_REMINDER","event_id":"evt-1","order_id":7}`)
+	require.NoError(t, redis.ScheduleEvent(ctx, "evt-1", time.Now().Add(-time.Second), "order-7", payload))
+
+	// A nil producer would panic if the stale reminder were published
+	dispatcher := NewScheduledEventDispatcher(redis, nil, orders)
+	published, err := dispatcher.DispatchDue(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, published)
+
+	pending, err := redis.CountScheduledEvents(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, pending)
+}
@@ -0,0 +1,137 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"order-service/internal/models"
+	"order-service/internal/store"
+	"order-service/internal/util"
+
+	"go.uber.org/zap"
+)
+
+// Order lifecycle stages tracked against SLA targets
+const (
+	StageReservation  = "reservation"  // created → reserved
+	StagePayment      = "payment"      // reserved → paid
+	StageConfirmation = "confirmation" // paid → confirmed
+)
+
+// SLATargets are the maximum durations of each lifecycle stage; zero disables a stage
+type SLATargets struct {
+	Reservation  time.Duration
+	Payment      time.Duration
+	Confirmation time.Duration
+}
+
+// SLAStage is the timing of one lifecycle stage of an order
+type SLAStage struct {
+	Stage           string     `json:"stage"`
+	StartedAt       *time.Time `json:"started_at,omitempty"`
+	CompletedAt     *time.Time `json:"completed_at,omitempty"`
+	DurationSeconds float64    `json:"duration_seconds"`
+	TargetSeconds   float64    `json:"target_seconds"`
+	Breached        bool       `json:"breached"`
+}
+
+// SLATracker measures order lifecycle stages against their SLA targets
+type SLATracker struct {
+	orders  store.OrderRepository
+	targets SLATargets
+	logger  *zap.Logger
+}
+
+// NewSLATracker creates a new SLA tracker
+func NewSLATracker(orders store.OrderRepository, targets SLATargets) *SLATracker {
+	return &SLATracker{
+		orders:  orders,
+		targets: targets,
+		logger:  util.GetLogger(),
+	}
+}
+
+// Evaluate returns the timing of every lifecycle stage the order has entered.
+// Stages still in progress are measured up to now; stages of cancelled or
+// failed orders stop at the terminal state and are never breached.
+func (t *SLATracker) Evaluate(order *models.Order, now time.Time) []SLAStage {
+	createdAt := order.CreatedAt
+	stages := []struct {
+		name   string
+		start  *time.Time
+		end    *time.Time
+		target time.Duration
+	}{
+		{StageReservation, &createdAt, order.ReservedAt, t.targets.Reservation},
+		{StagePayment, order.ReservedAt, order.PaidAt, t.targets.Payment},
+		{StageConfirmation, order.PaidAt, order.ConfirmedAt, t.targets.Confirmation},
+	}
+
+	terminal := order.Status == models.OrderStatusCancelled || order.Status == models.OrderStatusFailed
+
+	result := make([]SLAStage, 0, len(stages))
+	for _, s := range stages {
+		if s.start == nil {
+			break
+		}
+
+		stage := SLAStage{
+			Stage:         s.name,
+			StartedAt:     s.start,
+			CompletedAt:   s.end,
+			TargetSeconds: s.target.Seconds(),
+		}
+
+		var elapsed time.Duration
+		switch {
+		case s.end != nil:
+			elapsed = s.end.Sub(*s.start)
+		case terminal:
+			elapsed = order.UpdatedAt.Sub(*s.start)
+		default:
+			elapsed = now.Sub(*s.start)
+		}
+		stage.DurationSeconds = elapsed.Seconds()
+		stage.Breached = s.target > 0 && elapsed > s.target && (s.end != nil || !terminal)
+
+		result = append(result, stage)
+	}
+	return result
+}
+
+// Record observes a just-completed stage of an order and flags the order if
+// the stage breached its SLA. Failures are logged; SLA tracking never fails the saga.
+func (t *SLATracker) Record(ctx context.Context, orderID int64, stage string) {
+	if t == nil {
+		return
+	}
+
+	order, err := t.orders.GetOrderByID(ctx, orderID)
+	if err != nil {
+		t.logger.Warn("Failed to load order for SLA tracking", zap.Int64("order_id", orderID), zap.Error(err))
+		return
+	}
+
+	for _, s := range t.Evaluate(order, time.Now()) {
+		if s.Stage != stage || s.CompletedAt == nil {
+			continue
+		}
+
+		util.OrderStageDuration.WithLabelValues(stage).Observe(s.DurationSeconds)
+		if !s.Breached {
+			return
+		}
+
+		util.OrderSLABreachesTotal.WithLabelValues(stage).Inc()
+		t.logger.Warn("Order SLA breached",
+			zap.Int64("order_id", orderID),
+			zap.String("stage", stage),
+			zap.Float64("duration_seconds", s.DurationSeconds),
+			zap.Float64("target_seconds", s.TargetSeconds))
+
+		if err := t.orders.MarkOrderSLABreached(ctx, orderID); err != nil {
+			t.logger.Error("Failed to flag SLA breach", zap.Int64("order_id", orderID), zap.Error(err))
+		}
+		return
+	}
+}
@@ -0,0 +1,56 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"order-service/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSLATrackerEvaluate(t *testing.T) {
+	tracker := NewSLATracker(nil, SLATargets{
+		Reservation:  5 * time.Second,
+		Payment:      time.Minute,
+		Confirmation: 10 * time.Second,
+	})
+
+	created := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	reserved := created.Add(2 * time.Second)
+	order := &models.Order{
+		Status:     models.OrderStatusReserved,
+		CreatedAt:  created,
+		ReservedAt: &reserved,
+	}
+
+	stages := tracker.Evaluate(order, reserved.Add(2*time.Minute))
+	require.Len(t, stages, 2)
+
+	assert.Equal(t, StageReservation, stages[0].Stage)
+	assert.Equal(t, 2.0, stages[0].DurationSeconds)
+	assert.False(t, stages[0].Breached)
+
+	// Payment is still open and past its target
+	assert.Equal(t, StagePayment, stages[1].Stage)
+	assert.Nil(t, stages[1].CompletedAt)
+	assert.True(t, stages[1].Breached)
+}
+
+func TestSLATrackerDoesNotFlagCancelledOrders(t *testing.T) {
+	tracker := NewSLATracker(nil, SLATargets{Payment: time.Minute})
+
+	created := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	reserved := created.Add(time.Second)
+	order := &models.Order{
+		Status:     models.OrderStatusCancelled,
+		CreatedAt:  created,
+		ReservedAt: &reserved,
+		UpdatedAt:  reserved.Add(time.Hour),
+	}
+
+	stages := tracker.Evaluate(order, time.Now())
+	require.Len(t, stages, 2)
+	assert.False(t, stages[1].Breached)
+}
@@ -0,0 +1,93 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"order-service/internal/broker"
+	"order-service/internal/models"
+	"order-service/internal/store"
+	"order-service/internal/util"
+
+	"github.com/google/uuid"
+)
+
+// statusChangeConsumer names the bridge's cursor over the status changes
+const statusChangeConsumer = "kafka-order-status-changed"
+
+// StatusChangeBridge publishes every committed order status change as an
+// OrderStatusChanged event. The database notifies the bridge as changes
+// commit, so changes are captured from the database rather than from the code
+// paths that make them, and forwarded at least once; an event forwarded twice
+// keeps its ID for consumers to deduplicate.
+type StatusChangeBridge struct {
+	changes   store.StatusChangeRepository
+	events    *broker.EventPublisher
+	batchSize int
+}
+
+// NewStatusChangeBridge creates a new status change bridge forwarding up to
+// batchSize changes per transaction
+func NewStatusChangeBridge(changes store.StatusChangeRepository, events *broker.EventPublisher, batchSize int) *StatusChangeBridge {
+	return &StatusChangeBridge{
+		changes:   changes,
+		events:    events,
+		batchSize: batchSize,
+	}
+}
+
+// Listen returns a channel receiving a value when status changes commit
+func (b *StatusChangeBridge) Listen(ctx context.Context) (<-chan struct{}, error) {
+	return b.changes.ListenStatusChanges(ctx)
+}
+
+// Forward publishes the status changes committed since the last forward.
+// Returns the number of changes published.
+func (b *StatusChangeBridge) Forward(ctx context.Context) (int, error) {
+	ctx, span := util.StartSpan(ctx, "StatusChangeBridge.Forward")
+	defer span.End()
+
+	forwarded := 0
+	for {
+		n, err := b.changes.ForwardStatusChanges(ctx, statusChangeConsumer, b.batchSize, b.publish)
+		forwarded += n
+		if err != nil {
+			return forwarded, err
+		}
+		if n < b.batchSize {
+			return forwarded, nil
+		}
+	}
+}
+
+// publish publishes a batch of status changes in order
+func (b *StatusChangeBridge) publish(ctx context.Context, changes []models.OrderStatusHistory) error {
+	for _, change := range changes {
+		if err := b.events.PublishOrderStatusChanged(ctx, statusChangedEvent(change)); err != nil {
+			return fmt.Errorf("failed to publish status change %d: %w", change.ID, err)
+		}
+		util.StatusChangesForwardedTotal.Inc()
+		util.StatusChangeForwardLag.Observe(time.Since(change.CreatedAt).Seconds())
+	}
+	return nil
+}
+
+// statusChangedEvent converts a status change into its event, whose ID is
+// derived from the change
+func statusChangedEvent(change models.OrderStatusHistory) *models.OrderStatusChangedEvent {
+	return &models.OrderStatusChangedEvent{
+		BaseEvent: models.BaseEvent{
+			EventID:   uuid.NewSHA1(uuid.NameSpaceOID, []byte(fmt.Sprintf("order_status_history/%d", change.ID))).String(),
+			EventType: models.EventTypeOrderStatusChanged,
+			Timestamp: time.Now(),
+		},
+		OrderID:   change.OrderID,
+		HistoryID: change.ID,
+		OldStatus: change.OldStatus,
+		NewStatus: change.NewStatus,
+		Reason:    change.Reason,
+		Actor:     change.Actor,
+		ChangedAt: change.CreatedAt,
+	}
+}
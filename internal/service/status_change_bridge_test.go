@@ -0,0 +1,46 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"order-service/internal/models"
+	"order-service/internal/schema"
+	"order-service/internal/store/mocks"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatusChangedEventIDIsStablePerChange(t *testing.T) {
+	old := models.OrderStatusReserved
+	change := models.OrderStatusHistory{
+		ID: 42, OrderID: 7, OldStatus: &old, NewStatus: models.OrderStatusPaid,
+		Reason: "payment_success", Actor: models.ActorOrderService, CreatedAt: time.Now(),
+	}
+
+	event := statusChangedEvent(change)
+	assert.Equal(t, event.EventID, statusChangedEvent(change).EventID)
+	assert.NotEqual(t, event.EventID, statusChangedEvent(models.OrderStatusHistory{ID: 43, OrderID: 7}).EventID)
+	assert.Equal(t, int64(42), event.HistoryID)
+	assert.Equal(t, models.OrderStatusPaid, event.NewStatus)
+
+	schemas, err := schema.NewRegistry()
+	require.NoError(t, err)
+	payload, err := json.Marshal(event)
+	require.NoError(t, err)
+	assert.NoError(t, schemas.ValidateEvent(payload))
+}
+
+func TestForwardDrainsFullBatches(t *testing.T) {
+	changes := mocks.NewStatusChangeRepository(t)
+	changes.On("ForwardStatusChanges", mock.Anything, statusChangeConsumer, 2, mock.Anything).Return(2, nil).Twice()
+	changes.On("ForwardStatusChanges", mock.Anything, statusChangeConsumer, 2, mock.Anything).Return(1, nil).Once()
+
+	forwarded, err := NewStatusChangeBridge(changes, nil, 2).Forward(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 5, forwarded)
+}
@@ -0,0 +1,73 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"order-service/internal/util"
+
+	"go.uber.org/zap"
+)
+
+type stockHolderKey struct{}
+
+// WithStockHolder marks ctx as acting for holder, e.g. a cart being checked
+// out, so reservations made with it may take the stock soft-held for holder
+func WithStockHolder(ctx context.Context, holder string) context.Context {
+	return context.WithValue(ctx, stockHolderKey{}, holder)
+}
+
+// stockHolder returns the holder ctx acts for, or "" for none
+func stockHolder(ctx context.Context) string {
+	holder, _ := ctx.Value(stockHolderKey{}).(string)
+	return holder
+}
+
+// holdsStock reports whether a strategy's reservations respect soft holds.
+// Only stock reserved in Redis is held: db-strict reserves under the database
+// row lock and untracked products have nothing to hold.
+func holdsStock(strategy string) bool {
+	return strategy == StrategyRedisFast || strategy == StrategyLeasedQuota
+}
+
+// HoldStock soft-holds the items for holder for ttl: the units stay available
+// but other reservations cannot take them until the hold is consumed by a
+// reservation made WithStockHolder, released, or expires. When any item lacks
+// unheld stock no hold is left in place and false is returned.
+func (ic *InventoryClient) HoldStock(ctx context.Context, holder string, items []OrderItemRequest, ttl time.Duration) (bool, error) {
+	ctx, span := util.StartSpan(ctx, "InventoryClient.HoldStock")
+	defer span.End()
+
+	held := make([]int64, 0, len(items))
+	for _, item := range items {
+		if !holdsStock(ic.strategyFor(ctx, item.VariantID)) {
+			continue
+		}
+		ok, err := ic.redis.HoldStock(ctx, item.VariantID, holder, item.Quantity, ttl)
+		if err != nil || !ok {
+			ic.ReleaseHolds(ctx, holder, held)
+			if err != nil {
+				util.StockHoldsTotal.WithLabelValues("error").Inc()
+				return false, err
+			}
+			util.StockHoldsTotal.WithLabelValues("insufficient").Inc()
+			return false, nil
+		}
+		held = append(held, item.VariantID)
+	}
+	util.StockHoldsTotal.WithLabelValues("held").Inc()
+	return true, nil
+}
+
+// ReleaseHolds drops holder's soft holds on the variants, returning the units
+// to other reservations before the holds expire
+func (ic *InventoryClient) ReleaseHolds(ctx context.Context, holder string, variantIDs []int64) {
+	for _, variantID := range variantIDs {
+		if err := ic.redis.ReleaseHold(ctx, variantID, holder); err != nil {
+			ic.logger.Warn("Failed to release stock hold, leaving it to expire",
+				zap.String("holder", holder),
+				zap.Int64("variant_id", variantID),
+				zap.Error(err))
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"order-service/internal/models"
+	"order-service/internal/store"
+	"order-service/internal/util"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// probePollInterval is how often the probe checks its order status
+const probePollInterval = 250 * time.Millisecond
+
+// SyntheticProbe places a flagged order for a dedicated SKU and verifies it is
+// confirmed end to end within the SLO
+type SyntheticProbe struct {
+	store           *store.Store
+	orderService    *OrderService
+	inventoryClient *InventoryClient
+	sku             string
+	userID          int64
+	slo             time.Duration
+	logger          *zap.Logger
+}
+
+// NewSyntheticProbe creates a new synthetic probe
+func NewSyntheticProbe(
+	store *store.Store,
+	orderService *OrderService,
+	inventoryClient *InventoryClient,
+	sku string,
+	userID int64,
+	slo time.Duration,
+) *SyntheticProbe {
+	return &SyntheticProbe{
+		store:           store,
+		orderService:    orderService,
+		inventoryClient: inventoryClient,
+		sku:             sku,
+		userID:          userID,
+		slo:             slo,
+		logger:          util.GetLogger(),
+	}
+}
+
+// Run executes one probe and records the outcome in metrics
+func (p *SyntheticProbe) Run(ctx context.Context) error {
+	ctx, span := util.StartSpan(ctx, "SyntheticProbe.Run")
+	defer span.End()
+
+	start := time.Now()
+	err := p.run(ctx)
+	util.SyntheticProbeDuration.Observe(time.Since(start).Seconds())
+
+	if err != nil {
+		util.SyntheticProbeRunsTotal.WithLabelValues("failure").Inc()
+		util.SyntheticProbeSuccess.Set(0)
+		return err
+	}
+
+	util.SyntheticProbeRunsTotal.WithLabelValues("success").Inc()
+	util.SyntheticProbeSuccess.Set(1)
+	return nil
+}
+
+func (p *SyntheticProbe) run(ctx context.Context) error {
+	variant, err := p.store.GetVariantBySKU(ctx, p.sku)
+	if err != nil {
+		return fmt.Errorf("probe product unavailable: %w", err)
+	}
+
+	resp, err := p.orderService.CreateOrder(ctx, &CreateOrderRequest{
+		UserID:         p.userID,
+		Items:          []OrderItemRequest{{ProductID: variant.ProductID, VariantID: variant.ID, Quantity: 1}},
+		PaymentMethod:  "mock",
+		IdempotencyKey: "probe-" + uuid.New().String(),
+		Synthetic:      true,
+	})
+	if err != nil {
+		return fmt.Errorf("probe order creation failed: %w", err)
+	}
+
+	status, err := p.awaitTerminalStatus(ctx, resp.OrderID)
+	if err != nil {
+		// Leave the order in place so the stuck saga can be investigated
+		return fmt.Errorf("probe order %d: %w", resp.OrderID, err)
+	}
+
+	p.cleanup(ctx, resp.OrderID, variant.ID, status)
+
+	if status != models.OrderStatusConfirmed {
+		return fmt.Errorf("probe order %d ended in status %s", resp.OrderID, status)
+	}
+	return nil
+}
+
+// awaitTerminalStatus polls the order until it is confirmed, cancelled or failed, or the SLO elapses
+func (p *SyntheticProbe) awaitTerminalStatus(ctx context.Context, orderID int64) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, p.slo)
+	defer cancel()
+
+	ticker := time.NewTicker(probePollInterval)
+	defer ticker.Stop()
+
+	for {
+		order, err := p.store.GetOrderByID(ctx, orderID)
+		if err == nil {
+			switch order.Status {
+			case models.OrderStatusConfirmed, models.OrderStatusCancelled, models.OrderStatusFailed:
+				return order.Status, nil
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return "", fmt.Errorf("not confirmed within SLO of %s", p.slo)
+		case <-ticker.C:
+		}
+	}
+}
+
+// cleanup removes the probe order and returns committed stock to the warehouse
+// the probe SKU was allocated from
+func (p *SyntheticProbe) cleanup(ctx context.Context, orderID, variantID int64, status string) {
+	if status == models.OrderStatusConfirmed {
+		warehouseID := int64(models.DefaultWarehouseID)
+		if items, err := p.store.GetOrderItemsByOrderID(ctx, orderID); err == nil && len(items) > 0 && items[0].WarehouseID != nil {
+			warehouseID = *items[0].WarehouseID
+		}
+		if err := p.inventoryClient.Restock(ctx, variantID, warehouseID, 1); err != nil {
+			p.logger.Warn("Failed to restock probe product", zap.Error(err))
+		}
+	}
+
+	if err := p.store.DeleteOrder(ctx, orderID); err != nil {
+		p.logger.Warn("Failed to delete probe order", zap.Int64("order_id", orderID), zap.Error(err))
+	}
+}
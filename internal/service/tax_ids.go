@@ -0,0 +1,74 @@
+package service
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"order-service/internal/apperrors"
+	"order-service/internal/models"
+)
+
+// taxIDFormats are the tax identifiers B2B orders can be invoiced to, by
+// country, once normalized to upper case without spaces, dots or dashes.
+// VAT numbers carry their country prefix.
+var taxIDFormats = map[string]*regexp.Regexp{
+	// NPWP: 15 digits, or 16 in the NIK-based format introduced in 2024
+	"ID": regexp.MustCompile(`^(\d{15}|\d{16})$`),
+	// UEN of the business, which GST registration uses
+	"SG": regexp.MustCompile(`^(\d{8}[A-Z]|\d{9}[A-Z]|[TSR]\d{2}[A-Z]{2}\d{4}[A-Z])$`),
+	// ABN
+	"AU": regexp.MustCompile(`^\d{11}$`),
+	"DE": regexp.MustCompile(`^DE\d{9}$`),
+	"FR": regexp.MustCompile(`^FR[0-9A-HJ-NP-Z]{2}\d{9}$`),
+	"NL": regexp.MustCompile(`^NL\d{9}B\d{2}$`),
+	"IT": regexp.MustCompile(`^IT\d{11}$`),
+	"ES": regexp.MustCompile(`^ES[0-9A-Z]\d{7}[0-9A-Z]$`),
+	"GB": regexp.MustCompile(`^GB(\d{9}|\d{12})$`),
+}
+
+// vatPrefixed are the countries whose tax IDs are VAT numbers prefixed with
+// the country code, which customers often leave out
+var vatPrefixed = map[string]bool{"DE": true, "FR": true, "NL": true, "IT": true, "ES": true, "GB": true}
+
+var taxIDSeparators = strings.NewReplacer(" ", "", ".", "", "-", "", "/", "")
+
+// normalizeBilling validates the billing details of an order against the tax
+// ID format of their country and returns them normalized
+func normalizeBilling(billing *models.BillingDetails) (*models.BillingDetails, error) {
+	if billing == nil {
+		return nil, nil
+	}
+
+	normalized := &models.BillingDetails{
+		Company: strings.TrimSpace(billing.Company),
+		Country: strings.ToUpper(strings.TrimSpace(billing.Country)),
+		TaxID:   taxIDSeparators.Replace(strings.ToUpper(billing.TaxID)),
+	}
+	if normalized.Company == "" {
+		return nil, apperrors.New(apperrors.ErrInvalidRequest, "billing company is required")
+	}
+
+	format, ok := taxIDFormats[normalized.Country]
+	if !ok {
+		return nil, apperrors.New(apperrors.ErrInvalidRequest,
+			"tax IDs of country %q are not supported, want one of %s", normalized.Country, strings.Join(taxIDCountries(), ", "))
+	}
+	if vatPrefixed[normalized.Country] && !strings.HasPrefix(normalized.TaxID, normalized.Country) {
+		normalized.TaxID = normalized.Country + normalized.TaxID
+	}
+	if !format.MatchString(normalized.TaxID) {
+		return nil, apperrors.New(apperrors.ErrInvalidRequest, "%q is not a valid tax ID in %s", billing.TaxID, normalized.Country)
+	}
+	return normalized, nil
+}
+
+// taxIDCountries lists the countries with a known tax ID format
+func taxIDCountries() []string {
+	countries := make([]string, 0, len(taxIDFormats))
+	for country := range taxIDFormats {
+		countries = append(countries, country)
+	}
+	sort.Strings(countries)
+	return countries
+}
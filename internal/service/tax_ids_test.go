@@ -0,0 +1,50 @@
+package service
+
+import (
+	"testing"
+
+	"order-service/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeBilling(t *testing.T) {
+	cases := []struct {
+		country, taxID, want string
+	}{
+		{"id", "01.234.567.8-901.000", "012345678901000"},
+		{"ID", "3201234567890001", "3201234567890001"},
+		{"DE", "de 123 456 789", "DE123456789"},
+		{"DE", "123456789", "DE123456789"},
+		{"NL", "NL123456789B01", "NL123456789B01"},
+		{"SG", "201912345K", "201912345K"},
+		{"GB", "GB 123 4567 89", "GB123456789"},
+	}
+	for _, c := range cases {
+		billing, err := normalizeBilling(&models.BillingDetails{Company: " Acme ", Country: c.country, TaxID: c.taxID})
+		require.NoError(t, err, c.taxID)
+		assert.Equal(t, c.want, billing.TaxID)
+		assert.Equal(t, "Acme", billing.Company)
+	}
+}
+
+func TestNormalizeBillingRejectsInvalidTaxIDs(t *testing.T) {
+	cases := []models.BillingDetails{
+		{Company: "Acme", Country: "ID", TaxID: "01.234.567.8-901"},
+		{Company: "Acme", Country: "DE", TaxID: "FR12345678901"},
+		{Company: "Acme", Country: "NL", TaxID: "NL123456789"},
+		{Company: "Acme", Country: "US", TaxID: "12-3456789"},
+		{Company: " ", Country: "DE", TaxID: "DE123456789"},
+	}
+	for _, c := range cases {
+		_, err := normalizeBilling(&c)
+		assert.Error(t, err, c)
+	}
+}
+
+func TestNormalizeBillingWithoutBilling(t *testing.T) {
+	billing, err := normalizeBilling(nil)
+	require.NoError(t, err)
+	assert.Nil(t, billing)
+}
@@ -0,0 +1,90 @@
+package service
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"order-service/internal/calendar"
+)
+
+// Timeout rule kinds accepted in PAYMENT_TIMEOUT_RULES
+const (
+	TimeoutRuleDefault         = "default"
+	TimeoutRuleNextBusinessDay = "next_business_day"
+	TimeoutRuleBusinessDays    = "business_days" // business_days:N
+)
+
+// TimeoutPolicy computes the payment deadline of an order from its payment method
+type TimeoutPolicy struct {
+	calendar     *calendar.Calendar
+	orderTimeout atomic.Int64 // time.Duration
+	rules        map[string]string
+}
+
+// NewTimeoutPolicy creates a timeout policy. rules maps payment methods to a
+// rule string, e.g. "bank_transfer=next_business_day,invoice=business_days:3".
+func NewTimeoutPolicy(cal *calendar.Calendar, orderTimeout time.Duration, rules string) (*TimeoutPolicy, error) {
+	p := &TimeoutPolicy{
+		calendar: cal,
+		rules:    make(map[string]string),
+	}
+	p.SetOrderTimeout(orderTimeout)
+
+	for _, entry := range strings.Split(rules, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		method, rule, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid timeout rule %q", entry)
+		}
+		if _, err := p.apply(rule, time.Now()); err != nil {
+			return nil, err
+		}
+		p.rules[strings.TrimSpace(method)] = strings.TrimSpace(rule)
+	}
+
+	return p, nil
+}
+
+// SetOrderTimeout sets the default payment deadline for orders placed from now on
+func (p *TimeoutPolicy) SetOrderTimeout(orderTimeout time.Duration) {
+	p.orderTimeout.Store(int64(orderTimeout))
+}
+
+// Deadline returns when an order placed at createdAt with paymentMethod expires
+func (p *TimeoutPolicy) Deadline(paymentMethod string, createdAt time.Time) time.Time {
+	rule, ok := p.rules[paymentMethod]
+	if !ok {
+		return createdAt.Add(time.Duration(p.orderTimeout.Load()))
+	}
+
+	deadline, err := p.apply(rule, createdAt)
+	if err != nil {
+		return createdAt.Add(time.Duration(p.orderTimeout.Load()))
+	}
+	return deadline
+}
+
+func (p *TimeoutPolicy) apply(rule string, createdAt time.Time) (time.Time, error) {
+	rule = strings.TrimSpace(rule)
+
+	switch {
+	case rule == TimeoutRuleDefault:
+		return createdAt.Add(time.Duration(p.orderTimeout.Load())), nil
+	case rule == TimeoutRuleNextBusinessDay:
+		return p.calendar.EndOfNextBusinessDay(createdAt), nil
+	case strings.HasPrefix(rule, TimeoutRuleBusinessDays+":"):
+		n, err := strconv.Atoi(strings.TrimPrefix(rule, TimeoutRuleBusinessDays+":"))
+		if err != nil || n <= 0 {
+			return time.Time{}, fmt.Errorf("invalid business_days rule %q", rule)
+		}
+		return p.calendar.AddBusinessDays(createdAt, n), nil
+	}
+
+	return time.Time{}, fmt.Errorf("unknown timeout rule %q", rule)
+}
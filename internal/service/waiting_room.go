@@ -0,0 +1,213 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"order-service/internal/apperrors"
+	"order-service/internal/redisclient"
+	"order-service/internal/util"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// Statuses of an order request in the waiting room, polled by its queue token
+const (
+	QueueWaiting = "WAITING"
+	QueueOrdered = "ORDERED"
+	QueueFailed  = "FAILED"
+)
+
+// waitingRoomLockTTL bounds how long an instance that died while admitting
+// keeps the others from admitting
+const waitingRoomLockTTL = 10 * time.Second
+
+// WaitingRoomPolicy controls when order requests wait in line and how fast
+// they are let in
+type WaitingRoomPolicy struct {
+	// MaxConcurrent orders may be created at once on this instance before new
+	// requests wait in line
+	MaxConcurrent int
+	// AdmitPerSecond waiting requests are admitted a second, across instances
+	AdmitPerSecond int
+	// TicketTTL is how long a queue token can be polled
+	TicketTTL time.Duration
+}
+
+// QueueTicket is what a queue token reports
+type QueueTicket struct {
+	Token  string `json:"queue_token"`
+	Status string `json:"status"`
+	// Position in line while waiting, 1 for the request admitted next
+	Position int64 `json:"position,omitempty"`
+	// Order is the created order once admitted
+	Order *CreateOrderResponse `json:"order,omitempty"`
+	// Reason the order failed once admitted
+	Reason string `json:"reason,omitempty"`
+}
+
+// WaitingRoom is a virtual waiting room for order creation: past a number of
+// orders in flight, requests wait in line in a Redis stream and are admitted
+// at a controlled rate, and clients poll their position with a queue token
+type WaitingRoom struct {
+	orders   *OrderService
+	redis    *redisclient.Client
+	policy   WaitingRoomPolicy
+	inFlight atomic.Int64
+	logger   *zap.Logger
+}
+
+// NewWaitingRoom creates a new waiting room
+func NewWaitingRoom(orders *OrderService, redis *redisclient.Client, policy WaitingRoomPolicy) *WaitingRoom {
+	return &WaitingRoom{
+		orders: orders,
+		redis:  redis,
+		policy: policy,
+		logger: util.GetLogger(),
+	}
+}
+
+// Enter lets an order request in unless MaxConcurrent orders are in flight on
+// this instance or requests are already waiting in line, which it must join.
+// A request let in must Leave once its order is created. Requests are let in
+// while the line cannot be read.
+func (w *WaitingRoom) Enter(ctx context.Context) bool {
+	if w.inFlight.Add(1) > int64(w.policy.MaxConcurrent) {
+		w.inFlight.Add(-1)
+		return false
+	}
+
+	waiting, err := w.redis.WaitingLength(ctx)
+	if err != nil {
+		w.logger.Warn("Failed to read waiting room length, letting request in", zap.Error(err))
+		return true
+	}
+	if waiting > 0 {
+		w.inFlight.Add(-1)
+		return false
+	}
+	return true
+}
+
+// Leave frees the slot of a request let in by Enter
+func (w *WaitingRoom) Leave() {
+	w.inFlight.Add(-1)
+}
+
+// Enqueue puts an order request in line and returns its queue token
+func (w *WaitingRoom) Enqueue(ctx context.Context, req *CreateOrderRequest) (*QueueTicket, error) {
+	// The order is created once however often its admission is retried
+	if req.IdempotencyKey == "" {
+		req.IdempotencyKey = uuid.New().String()
+	}
+	payload, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode order request: %w", err)
+	}
+
+	token := uuid.New().String()
+	ticket, admitted, err := w.redis.EnqueueWaiting(ctx, token, payload, QueueWaiting, w.policy.TicketTTL)
+	if err != nil {
+		return nil, apperrors.Wrap(apperrors.ErrUnavailable, err, "failed to join the waiting room")
+	}
+	util.WaitingRoomEnqueuedTotal.Inc()
+
+	return &QueueTicket{Token: token, Status: QueueWaiting, Position: ticket - admitted}, nil
+}
+
+// Ticket returns the status of a queue token: the position in line while
+// waiting, then the order or the reason it failed
+func (w *WaitingRoom) Ticket(ctx context.Context, token string) (*QueueTicket, error) {
+	state, err := w.redis.GetWaitingTicket(ctx, token)
+	if err != nil {
+		return nil, apperrors.Wrap(apperrors.ErrUnavailable, err, "failed to read queue token %s", token)
+	}
+	if state == nil {
+		return nil, apperrors.New(apperrors.ErrNotFound, "queue token %s not found", token)
+	}
+
+	ticket := &QueueTicket{Token: token, Status: state.Status}
+	if state.Status == QueueWaiting {
+		ticket.Position = max(state.Ticket-state.Admitted, 1)
+		return ticket, nil
+	}
+	if err := json.Unmarshal(state.Result, ticket); err != nil {
+		return nil, fmt.Errorf("failed to decode result of queue token %s: %w", token, err)
+	}
+	return ticket, nil
+}
+
+// Admit creates the orders of up to AdmitPerSecond requests at the front of
+// the line, once a second across instances, and returns how many it admitted.
+// A request whose order fails on infrastructure stays in line and stops the
+// batch, so it is retried first.
+func (w *WaitingRoom) Admit(ctx context.Context) (int, error) {
+	claimed, err := w.redis.ClaimWaitingAdmission(ctx, time.Second)
+	if err != nil || !claimed {
+		return 0, err
+	}
+	lock, err := w.redis.ObtainLock(ctx, "waiting-room", waitingRoomLockTTL)
+	if errors.Is(err, redisclient.ErrLockNotObtained) {
+		// The previous batch is still being admitted
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	defer lock.Release(ctx)
+
+	entries, err := w.redis.PeekWaiting(ctx, w.policy.AdmitPerSecond)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read waiting room: %w", err)
+	}
+
+	admitted := 0
+	for _, entry := range entries {
+		ticket, err := w.admit(ctx, entry)
+		if err != nil {
+			return admitted, err
+		}
+		payload, err := json.Marshal(ticket)
+		if err != nil {
+			return admitted, err
+		}
+		if err := w.redis.AdmitWaiting(ctx, entry, ticket.Status, payload, w.policy.TicketTTL); err != nil {
+			return admitted, fmt.Errorf("failed to admit queue token %s: %w", entry.Token, err)
+		}
+		result := "ordered"
+		if ticket.Status == QueueFailed {
+			result = "failed"
+		}
+		util.WaitingRoomAdmittedTotal.WithLabelValues(result).Inc()
+		admitted++
+	}
+
+	if waiting, err := w.redis.WaitingLength(ctx); err == nil {
+		util.WaitingRoomLength.Set(float64(waiting))
+	}
+	return admitted, nil
+}
+
+// admit creates the order of a waiting request. Business errors fail the
+// request; infrastructure errors are returned.
+func (w *WaitingRoom) admit(ctx context.Context, entry redisclient.WaitingEntry) (*QueueTicket, error) {
+	var req CreateOrderRequest
+	if err := json.Unmarshal(entry.Request, &req); err != nil {
+		w.logger.Error("Dropping malformed waiting order request", zap.String("queue_token", entry.Token), zap.Error(err))
+		return &QueueTicket{Status: QueueFailed, Reason: "malformed request"}, nil
+	}
+
+	resp, err := w.orders.CreateOrder(ctx, &req)
+	if err != nil {
+		if apperrors.From(err).Status >= 500 {
+			return nil, fmt.Errorf("failed to create order of queue token %s: %w", entry.Token, err)
+		}
+		return &QueueTicket{Status: QueueFailed, Reason: err.Error()}, nil
+	}
+	return &QueueTicket{Status: QueueOrdered, Order: resp}, nil
+}
@@ -0,0 +1,57 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWaitingRoomQueuesPastTheThresholdAndAdmitsInOrder(t *testing.T) {
+	ctx := context.Background()
+	flash, orders, redis, _ := newTestFlashOrders(t)
+	os := flash.orders
+	room := NewWaitingRoom(os, redis, WaitingRoomPolicy{MaxConcurrent: 1, AdmitPerSecond: 10, TicketTTL: time.Hour})
+
+	require.True(t, room.Enter(ctx))
+	assert.False(t, room.Enter(ctx), "past the threshold")
+
+	first, err := room.Enqueue(ctx, &CreateOrderRequest{UserID: 1, Items: []OrderItemRequest{{ProductID: 10, Quantity: 1}}, PaymentMethod: "mock"})
+	require.NoError(t, err)
+	second, err := room.Enqueue(ctx, &CreateOrderRequest{UserID: 2, Items: []OrderItemRequest{{ProductID: 10, Quantity: 1}}, PaymentMethod: "mock"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), first.Position)
+	assert.Equal(t, int64(2), second.Position)
+
+	room.Leave()
+	assert.False(t, room.Enter(ctx), "no one jumps the line")
+
+	ticket, err := room.Ticket(ctx, second.Token)
+	require.NoError(t, err)
+	assert.Equal(t, QueueWaiting, ticket.Status)
+	assert.Equal(t, int64(2), ticket.Position)
+
+	// The orders are rejected once admitted
+	_, err = os.killSwitches.Engage(ctx, KillSwitchPaymentMethod, "mock", "provider outage", "admin:operator")
+	require.NoError(t, err)
+	orders.On("GetOrderByIdempotencyKey", mock.Anything, mock.Anything).Return(nil, nil)
+
+	admitted, err := room.Admit(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, admitted)
+
+	ticket, err = room.Ticket(ctx, second.Token)
+	require.NoError(t, err)
+	assert.Equal(t, QueueFailed, ticket.Status)
+	assert.Contains(t, ticket.Reason, "provider outage")
+	assert.Zero(t, ticket.Position)
+
+	admitted, err = room.Admit(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, admitted, "admission is claimed once a second")
+
+	assert.True(t, room.Enter(ctx), "the line is empty")
+}
@@ -0,0 +1,147 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"order-service/internal/apperrors"
+	"order-service/internal/models"
+	"order-service/internal/store"
+	"order-service/internal/tenant"
+	"order-service/internal/util"
+
+	"go.uber.org/zap"
+)
+
+// walletMaxAttempts bounds how often a wallet update is retried after losing
+// a race to a concurrent one
+const walletMaxAttempts = 5
+
+// WalletService debits and credits the store credit of users. Balances are
+// updated with optimistic locking: a transaction applies only to the wallet
+// version it was checked against, and is retried on a newer one.
+type WalletService struct {
+	wallets store.WalletRepository
+	logger  *zap.Logger
+}
+
+// NewWalletService creates a new wallet service
+func NewWalletService(wallets store.WalletRepository) *WalletService {
+	return &WalletService{
+		wallets: wallets,
+		logger:  util.GetLogger(),
+	}
+}
+
+// WalletEntry describes why a wallet is debited or credited
+type WalletEntry struct {
+	// PaymentID is the payment settled or refunded; each payment is debited
+	// and credited at most once
+	PaymentID *int64
+	Reason    string
+	Actor     string
+}
+
+// Balance returns the wallet of a user
+func (ws *WalletService) Balance(ctx context.Context, userID int64) (*models.Wallet, error) {
+	return ws.wallets.GetWallet(ctx, userID)
+}
+
+// Transactions returns the latest transactions of a user's wallet, newest first
+func (ws *WalletService) Transactions(ctx context.Context, userID int64, limit int) ([]models.WalletTransaction, error) {
+	txns, err := ws.wallets.GetWalletTransactions(ctx, userID, limit)
+	if txns == nil {
+		txns = []models.WalletTransaction{}
+	}
+	return txns, err
+}
+
+// Debit takes amount from a user's wallet. Fails with ErrInsufficientBalance
+// if the balance does not cover it.
+func (ws *WalletService) Debit(ctx context.Context, userID, amount int64, entry WalletEntry) (*models.WalletTransaction, error) {
+	return ws.apply(ctx, userID, models.WalletDebit, amount, entry)
+}
+
+// Credit adds amount to a user's wallet, creating it on first credit
+func (ws *WalletService) Credit(ctx context.Context, userID, amount int64, entry WalletEntry) (*models.WalletTransaction, error) {
+	return ws.apply(ctx, userID, models.WalletCredit, amount, entry)
+}
+
+// RefundPayment credits back up to amount of what a payment debited from a
+// wallet. Returns the amount credited, 0 if the payment debited no wallet.
+func (ws *WalletService) RefundPayment(ctx context.Context, paymentID, amount int64, reason string) (int64, error) {
+	debit, err := ws.wallets.GetPaymentWalletDebit(ctx, paymentID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get wallet debit: %w", err)
+	}
+	if debit == nil || amount <= 0 {
+		return 0, nil
+	}
+
+	credited := min(amount, debit.Amount)
+	_, err = ws.Credit(tenant.WithID(ctx, debit.TenantID), debit.UserID, credited, WalletEntry{PaymentID: &paymentID, Reason: reason, Actor: models.ActorPaymentService})
+	if err != nil {
+		return 0, err
+	}
+	return credited, nil
+}
+
+// apply debits or credits a wallet, retrying against the latest version when
+// a concurrent update got there first. Wallets belong to the tenant of ctx,
+// or for work done for no tenant, e.g. payment retries, to the tenant of the
+// order paid.
+func (ws *WalletService) apply(ctx context.Context, userID int64, kind string, amount int64, entry WalletEntry) (*models.WalletTransaction, error) {
+	ctx, span := util.StartSpan(ctx, "WalletService.apply")
+	defer span.End()
+
+	if amount <= 0 {
+		return nil, apperrors.New(apperrors.ErrInvalidRequest, "wallet amount must be positive")
+	}
+	if _, ok := tenant.ID(ctx); !ok && entry.PaymentID != nil {
+		id, err := ws.wallets.GetPaymentTenant(ctx, *entry.PaymentID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get payment tenant: %w", err)
+		}
+		ctx = tenant.WithID(ctx, id)
+	}
+
+	for attempt := 1; ; attempt++ {
+		wallet, err := ws.wallets.GetWallet(ctx, userID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get wallet: %w", err)
+		}
+		if kind == models.WalletDebit && wallet.Balance < amount {
+			return nil, apperrors.New(apperrors.ErrInsufficientBalance,
+				"wallet balance %d does not cover %d", wallet.Balance, amount)
+		}
+
+		txn := &models.WalletTransaction{
+			UserID:    userID,
+			Kind:      kind,
+			Amount:    amount,
+			PaymentID: entry.PaymentID,
+			Reason:    entry.Reason,
+			Actor:     entry.Actor,
+		}
+		applied, err := ws.wallets.ApplyWalletTransaction(ctx, txn, wallet.Version)
+		if errors.Is(err, store.ErrStaleWallet) && attempt < walletMaxAttempts {
+			util.WalletConflictsTotal.Inc()
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to update wallet: %w", err)
+		}
+
+		if applied {
+			util.WalletTransactionsTotal.WithLabelValues(kind).Inc()
+			ws.logger.Info("Wallet updated",
+				zap.Int64("user_id", userID),
+				zap.String("kind", kind),
+				zap.Int64("amount", amount),
+				zap.Int64("balance", txn.BalanceAfter),
+				zap.String("reason", entry.Reason))
+		}
+		return txn, nil
+	}
+}
@@ -0,0 +1,94 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"order-service/internal/apperrors"
+	"order-service/internal/models"
+	"order-service/internal/store"
+	"order-service/internal/store/mocks"
+	"order-service/internal/tenant"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWalletDebitRetriesAgainstLatestVersion(t *testing.T) {
+	wallets := mocks.NewWalletRepository(t)
+	wallets.On("GetWallet", mock.Anything, int64(7)).
+		Return(&models.Wallet{UserID: 7, Balance: 500, Version: 3}, nil).Once()
+	wallets.On("ApplyWalletTransaction", mock.Anything, mock.Anything, int64(3)).
+		Return(false, store.ErrStaleWallet).Once()
+	wallets.On("GetWallet", mock.Anything, int64(7)).
+		Return(&models.Wallet{UserID: 7, Balance: 400, Version: 4}, nil).Once()
+	wallets.On("ApplyWalletTransaction", mock.Anything, mock.MatchedBy(func(txn *models.WalletTransaction) bool {
+		return txn.Kind == models.WalletDebit && txn.Amount == 300
+	}), int64(4)).Return(true, nil).Once()
+
+	ws := NewWalletService(wallets)
+
+	_, err := ws.Debit(context.Background(), 7, 300, WalletEntry{Reason: "order 1"})
+	assert.NoError(t, err)
+}
+
+func TestWalletDebitActsForThePaymentsTenant(t *testing.T) {
+	paymentID := int64(5)
+	acme := mock.MatchedBy(func(ctx context.Context) bool {
+		id, _ := tenant.ID(ctx)
+		return id == "acme"
+	})
+	wallets := mocks.NewWalletRepository(t)
+	wallets.On("GetPaymentTenant", mock.Anything, paymentID).Return("acme", nil).Once()
+	wallets.On("GetWallet", acme, int64(7)).
+		Return(&models.Wallet{TenantID: "acme", UserID: 7, Balance: 500, Version: 1}, nil).Once()
+	wallets.On("ApplyWalletTransaction", acme, mock.Anything, int64(1)).Return(true, nil).Once()
+
+	ws := NewWalletService(wallets)
+
+	_, err := ws.Debit(context.Background(), 7, 300, WalletEntry{Reason: "order 1", PaymentID: &paymentID})
+	assert.NoError(t, err)
+}
+
+func TestWalletDebitFailsOnInsufficientBalance(t *testing.T) {
+	wallets := mocks.NewWalletRepository(t)
+	wallets.On("GetWallet", mock.Anything, int64(7)).
+		Return(&models.Wallet{UserID: 7, Balance: 200, Version: 3}, nil).Once()
+
+	ws := NewWalletService(wallets)
+
+	_, err := ws.Debit(context.Background(), 7, 300, WalletEntry{Reason: "order 1"})
+	assert.True(t, errors.Is(err, apperrors.ErrInsufficientBalance))
+	wallets.AssertNotCalled(t, "ApplyWalletTransaction", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestWalletRefundCreditsAtMostTheDebit(t *testing.T) {
+	paymentID := int64(5)
+	wallets := mocks.NewWalletRepository(t)
+	wallets.On("GetPaymentWalletDebit", mock.Anything, paymentID).
+		Return(&models.WalletTransaction{TenantID: "acme", UserID: 7, Kind: models.WalletDebit, Amount: 300, PaymentID: &paymentID}, nil).Once()
+	wallets.On("GetWallet", mock.Anything, int64(7)).
+		Return(&models.Wallet{UserID: 7, Balance: 0, Version: 2}, nil).Once()
+	wallets.On("ApplyWalletTransaction", mock.Anything, mock.MatchedBy(func(txn *models.WalletTransaction) bool {
+		return txn.Kind == models.WalletCredit && txn.Amount == 300 && *txn.PaymentID == paymentID
+	}), int64(2)).Return(true, nil).Once()
+
+	ws := NewWalletService(wallets)
+
+	credited, err := ws.RefundPayment(context.Background(), paymentID, 1000, "customer_cancelled")
+	require.NoError(t, err)
+	assert.Equal(t, int64(300), credited)
+}
+
+func TestWalletRefundSkipsPaymentsWithoutWalletDebit(t *testing.T) {
+	wallets := mocks.NewWalletRepository(t)
+	wallets.On("GetPaymentWalletDebit", mock.Anything, int64(5)).Return(nil, nil).Once()
+
+	ws := NewWalletService(wallets)
+
+	credited, err := ws.RefundPayment(context.Background(), 5, 1000, "customer_cancelled")
+	require.NoError(t, err)
+	assert.Zero(t, credited)
+}
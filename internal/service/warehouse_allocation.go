@@ -0,0 +1,128 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"sync/atomic"
+
+	"order-service/internal/models"
+)
+
+// Warehouse allocation strategies, set with WAREHOUSE_ALLOCATION
+const (
+	// AllocationNearest prefers the warehouse closest to where the order ships
+	AllocationNearest = "nearest"
+	// AllocationMostStock prefers the warehouse with the most available units
+	AllocationMostStock = "most-stock"
+	// AllocationRoundRobin spreads reservations over the warehouses in turn
+	AllocationRoundRobin = "round-robin"
+)
+
+// WarehouseCandidate is an active warehouse with enough available units of a
+// variant for an order item
+type WarehouseCandidate struct {
+	Warehouse models.Warehouse
+	Available int
+}
+
+// WarehouseAllocator orders the warehouses an order item's stock may be
+// reserved in by preference. Reservation tries them in that order, as stock
+// may run out between reading it and reserving it.
+type WarehouseAllocator interface {
+	Rank(ctx context.Context, variantID int64, candidates []WarehouseCandidate) []WarehouseCandidate
+}
+
+// NewWarehouseAllocator returns the allocator of a WAREHOUSE_ALLOCATION strategy
+func NewWarehouseAllocator(strategy string) (WarehouseAllocator, error) {
+	switch strategy {
+	case AllocationNearest:
+		return nearestAllocator{}, nil
+	case AllocationMostStock:
+		return mostStockAllocator{}, nil
+	case AllocationRoundRobin:
+		return &roundRobinAllocator{}, nil
+	default:
+		return nil, fmt.Errorf("unknown warehouse allocation %q", strategy)
+	}
+}
+
+type shipToKey struct{}
+
+// WithShipTo returns a context whose reservations allocate stock from the
+// warehouses nearest to where the order ships
+func WithShipTo(ctx context.Context, point models.GeoPoint) context.Context {
+	return context.WithValue(ctx, shipToKey{}, point)
+}
+
+// shipTo returns where the order reserving in ctx ships, if known
+func shipTo(ctx context.Context) (models.GeoPoint, bool) {
+	point, ok := ctx.Value(shipToKey{}).(models.GeoPoint)
+	return point, ok
+}
+
+// mostStockAllocator prefers warehouses with more available units, keeping
+// small warehouses for orders only they can cover
+type mostStockAllocator struct{}
+
+func (mostStockAllocator) Rank(_ context.Context, _ int64, candidates []WarehouseCandidate) []WarehouseCandidate {
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].Available != candidates[j].Available {
+			return candidates[i].Available > candidates[j].Available
+		}
+		return candidates[i].Warehouse.ID < candidates[j].Warehouse.ID
+	})
+	return candidates
+}
+
+// nearestAllocator prefers warehouses closer to where the order ships. Orders
+// without a destination, e.g. backorders reserved later, rank by most stock.
+type nearestAllocator struct{}
+
+func (nearestAllocator) Rank(ctx context.Context, variantID int64, candidates []WarehouseCandidate) []WarehouseCandidate {
+	point, ok := shipTo(ctx)
+	if !ok {
+		return mostStockAllocator{}.Rank(ctx, variantID, candidates)
+	}
+
+	distances := make(map[int64]float64, len(candidates))
+	for _, c := range candidates {
+		distances[c.Warehouse.ID] = distanceKm(point, models.GeoPoint{Latitude: c.Warehouse.Latitude, Longitude: c.Warehouse.Longitude})
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		di, dj := distances[candidates[i].Warehouse.ID], distances[candidates[j].Warehouse.ID]
+		if di != dj {
+			return di < dj
+		}
+		return candidates[i].Warehouse.ID < candidates[j].Warehouse.ID
+	})
+	return candidates
+}
+
+// distanceKm is the great-circle distance between two points
+func distanceKm(a, b models.GeoPoint) float64 {
+	const earthRadiusKm = 6371
+	lat1, lat2 := a.Latitude*math.Pi/180, b.Latitude*math.Pi/180
+	dLat, dLon := lat2-lat1, (b.Longitude-a.Longitude)*math.Pi/180
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(h))
+}
+
+// roundRobinAllocator starts each reservation at the next warehouse in ID
+// order, so reservations spread evenly over the warehouses of this pod
+type roundRobinAllocator struct {
+	next atomic.Uint64
+}
+
+func (a *roundRobinAllocator) Rank(_ context.Context, _ int64, candidates []WarehouseCandidate) []WarehouseCandidate {
+	if len(candidates) == 0 {
+		return candidates
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].Warehouse.ID < candidates[j].Warehouse.ID
+	})
+	start := int(a.next.Add(1)-1) % len(candidates)
+	ranked := make([]WarehouseCandidate, 0, len(candidates))
+	return append(append(ranked, candidates[start:]...), candidates[:start]...)
+}
@@ -0,0 +1,48 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"order-service/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// rankedIDs ranks candidates with the allocator and returns their warehouse IDs
+func rankedIDs(ctx context.Context, allocator WarehouseAllocator, candidates []WarehouseCandidate) []int64 {
+	var ids []int64
+	for _, c := range allocator.Rank(ctx, 11, append([]WarehouseCandidate(nil), candidates...)) {
+		ids = append(ids, c.Warehouse.ID)
+	}
+	return ids
+}
+
+func TestWarehouseAllocators(t *testing.T) {
+	candidates := []WarehouseCandidate{
+		{Warehouse: models.Warehouse{ID: 1, Code: "berlin", Latitude: 52.52, Longitude: 13.40}, Available: 5},
+		{Warehouse: models.Warehouse{ID: 2, Code: "madrid", Latitude: 40.42, Longitude: -3.70}, Available: 40},
+		{Warehouse: models.Warehouse{ID: 3, Code: "warsaw", Latitude: 52.23, Longitude: 21.01}, Available: 40},
+	}
+	ctx := context.Background()
+
+	mostStock, err := NewWarehouseAllocator(AllocationMostStock)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{2, 3, 1}, rankedIDs(ctx, mostStock, candidates))
+
+	nearest, err := NewWarehouseAllocator(AllocationNearest)
+	require.NoError(t, err)
+	lisbon := WithShipTo(ctx, models.GeoPoint{Latitude: 38.72, Longitude: -9.14})
+	assert.Equal(t, []int64{2, 1, 3}, rankedIDs(lisbon, nearest, candidates))
+	assert.Equal(t, []int64{2, 3, 1}, rankedIDs(ctx, nearest, candidates), "without a destination it ranks by most stock")
+
+	roundRobin, err := NewWarehouseAllocator(AllocationRoundRobin)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{1, 2, 3}, rankedIDs(ctx, roundRobin, candidates))
+	assert.Equal(t, []int64{2, 3, 1}, rankedIDs(ctx, roundRobin, candidates))
+	assert.Equal(t, []int64{3, 1, 2}, rankedIDs(ctx, roundRobin, candidates))
+
+	_, err = NewWarehouseAllocator("cheapest")
+	assert.Error(t, err)
+}
@@ -0,0 +1,536 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"order-service/internal/apperrors"
+	"order-service/internal/models"
+	"order-service/internal/resilience/retry"
+	"order-service/internal/store"
+	"order-service/internal/tenant"
+	"order-service/internal/util"
+
+	"go.uber.org/zap"
+)
+
+// Headers of webhook deliveries. The signature is the hex HMAC-SHA256, keyed
+// with the subscription secret, of the timestamp header, a dot and the body,
+// prefixed "sha256=", so receivers can verify the sender and reject replays.
+const (
+	WebhookIDHeader        = "X-Webhook-ID"
+	WebhookEventHeader     = "X-Webhook-Event"
+	WebhookTimestampHeader = "X-Webhook-Timestamp"
+	WebhookSignatureHeader = "X-Webhook-Signature"
+)
+
+const (
+	// webhookClaimTTL is how long a claimed delivery is hidden from other
+	// dispatchers while it is posted
+	webhookClaimTTL = 2 * time.Minute
+	// webhookBatchSize caps the deliveries posted per poll
+	webhookBatchSize = 50
+	// webhookSubscriberTTL is how long the active subscriptions are cached
+	// between events
+	webhookSubscriberTTL = 30 * time.Second
+)
+
+// WebhookEventTypes are the order events third parties can subscribe to
+var WebhookEventTypes = []string{
+	models.EventTypeOrderCreated,
+	models.EventTypeOrderReserved,
+	models.EventTypeOrderAmended,
+	models.EventTypeOrderPaid,
+	models.EventTypeOrderConfirmed,
+	models.EventTypeOrderCancelled,
+	models.EventTypeOrderOnHold,
+	models.EventTypeOrderStatusChanged,
+	models.EventTypePaymentSuccess,
+	models.EventTypePaymentFailed,
+	models.EventTypePaymentVoided,
+	models.EventTypePaymentRefunded,
+	models.EventTypeOrderBackordered,
+	models.EventTypeBackorderRescheduled,
+	models.EventTypeBackorderFulfilled,
+}
+
+// WebhookPolicy is the retry budget of webhook deliveries and when a
+// failing endpoint is disabled
+type WebhookPolicy struct {
+	// Policy retries deliveries; its MaxAttempts counts every attempt, and a
+	// delivery is marked FAILED once they run out
+	retry.Policy
+	// DisableAfterFailures is the number of consecutive failed attempts,
+	// across deliveries, after which a subscription is disabled
+	DisableAfterFailures int
+	// AllowInsecureURLs accepts plain http endpoints, for local development
+	AllowInsecureURLs bool
+	// AllowPrivateAddresses accepts endpoints on loopback, private and
+	// link-local addresses, for local development; otherwise third parties
+	// could make the service post to internal hosts
+	AllowPrivateAddresses bool
+}
+
+// ipResolver resolves the hosts of webhook URLs; *net.Resolver implements it
+type ipResolver interface {
+	LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error)
+}
+
+// WebhookService manages the webhook subscriptions of third parties, queues
+// the order events they subscribed to and delivers them signed, retrying
+// with backoff and disabling endpoints that keep failing
+type WebhookService struct {
+	webhooks store.WebhookRepository
+	policy   WebhookPolicy
+	client   *http.Client
+	resolver ipResolver
+	logger   *zap.Logger
+
+	mu           sync.Mutex
+	active       []models.WebhookSubscription
+	activeLoaded time.Time
+}
+
+// NewWebhookService creates a new webhook service
+func NewWebhookService(webhooks store.WebhookRepository, policy WebhookPolicy) *WebhookService {
+	if policy.MaxAttempts < 1 {
+		policy.MaxAttempts = 1
+	}
+	if policy.DisableAfterFailures < 1 {
+		policy.DisableAfterFailures = 1
+	}
+	return &WebhookService{
+		webhooks: webhooks,
+		policy:   policy,
+		client:   newWebhookClient(policy.AllowPrivateAddresses),
+		resolver: net.DefaultResolver,
+		logger:   util.GetLogger(),
+	}
+}
+
+// newWebhookClient returns the client deliveries are posted with. It does not
+// follow redirects, which could point at another host, nor use a proxy, and
+// unless private addresses are allowed it refuses to connect to them, so that
+// a host resolving to a public address when subscribed cannot be repointed
+// at an internal one.
+func newWebhookClient(allowPrivate bool) *http.Client {
+	dialer := &net.Dialer{Timeout: 5 * time.Second}
+	if !allowPrivate {
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || privateAddress(ip) {
+				return fmt.Errorf("webhook endpoint address %s is not public", host)
+			}
+			return nil
+		}
+	}
+	return &http.Client{
+		Timeout: 10 * time.Second,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			ForceAttemptHTTP2:   true,
+			TLSHandshakeTimeout: 5 * time.Second,
+			MaxIdleConnsPerHost: 4,
+			IdleConnTimeout:     90 * time.Second,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// privateAddress reports whether ip is not a public unicast address
+func privateAddress(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast()
+}
+
+// WebhookSubscriptionRequest registers an endpoint for event types
+type WebhookSubscriptionRequest struct {
+	URL         string   `json:"url" binding:"required"`
+	EventTypes  []string `json:"event_types" binding:"required"`
+	Description string   `json:"description"`
+}
+
+// Subscribe registers an endpoint for event types. The returned subscription
+// carries the signing secret, which is not shown again.
+func (s *WebhookService) Subscribe(ctx context.Context, req WebhookSubscriptionRequest, actor string) (*models.WebhookSubscription, error) {
+	if err := s.validateURL(ctx, req.URL); err != nil {
+		return nil, err
+	}
+	eventTypes, err := validateWebhookEventTypes(req.EventTypes)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := newWebhookSecret()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate webhook secret: %w", err)
+	}
+	sub := &models.WebhookSubscription{
+		URL:         req.URL,
+		EventTypes:  eventTypes,
+		Secret:      secret,
+		Description: req.Description,
+		Status:      models.WebhookActive,
+		CreatedBy:   actor,
+	}
+	if err := s.webhooks.CreateWebhookSubscription(ctx, sub); err != nil {
+		return nil, fmt.Errorf("failed to create webhook subscription: %w", err)
+	}
+	s.invalidate()
+
+	util.AdminActionsTotal.WithLabelValues("create_webhook_subscription").Inc()
+	s.logger.Info("Webhook subscription created",
+		zap.Int64("subscription_id", sub.ID),
+		zap.String("url", sub.URL),
+		zap.Strings("event_types", sub.EventTypes),
+		zap.String("actor", actor))
+	return sub, nil
+}
+
+// validateURL accepts absolute https URLs, and http ones if allowed, whose
+// host only resolves to public addresses unless private ones are allowed
+func (s *WebhookService) validateURL(ctx context.Context, raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return apperrors.New(apperrors.ErrInvalidRequest, "url must be an absolute URL")
+	}
+	if u.Scheme != "https" && !(u.Scheme == "http" && s.policy.AllowInsecureURLs) {
+		return apperrors.New(apperrors.ErrInvalidRequest, "url must use https")
+	}
+	if s.policy.AllowPrivateAddresses {
+		return nil
+	}
+
+	addrs, err := s.resolver.LookupIPAddr(ctx, u.Hostname())
+	if err != nil || len(addrs) == 0 {
+		return apperrors.New(apperrors.ErrInvalidRequest, "url host %s does not resolve", u.Hostname())
+	}
+	for _, addr := range addrs {
+		if privateAddress(addr.IP) {
+			return apperrors.New(apperrors.ErrInvalidRequest, "url host %s must not resolve to a private address", u.Hostname())
+		}
+	}
+	return nil
+}
+
+// validateWebhookEventTypes checks the event types are subscribable,
+// dropping duplicates
+func validateWebhookEventTypes(eventTypes []string) ([]string, error) {
+	if len(eventTypes) == 0 {
+		return nil, apperrors.New(apperrors.ErrInvalidRequest, "event_types must not be empty")
+	}
+	seen := make(map[string]bool, len(eventTypes))
+	valid := make([]string, 0, len(eventTypes))
+	for _, eventType := range eventTypes {
+		if !isWebhookEventType(eventType) {
+			return nil, apperrors.New(apperrors.ErrInvalidRequest, "unknown event type %q", eventType)
+		}
+		if !seen[eventType] {
+			seen[eventType] = true
+			valid = append(valid, eventType)
+		}
+	}
+	return valid, nil
+}
+
+// isWebhookEventType reports whether an event type can be subscribed to
+func isWebhookEventType(eventType string) bool {
+	for _, t := range WebhookEventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// newWebhookSecret returns a random 32-byte secret, hex encoded
+func newWebhookSecret() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(secret), nil
+}
+
+// List returns the subscriptions, without their secrets
+func (s *WebhookService) List(ctx context.Context, status string) ([]models.WebhookSubscription, error) {
+	subs, err := s.webhooks.ListWebhookSubscriptions(ctx, status)
+	if err != nil {
+		return nil, err
+	}
+	for i := range subs {
+		subs[i].Secret = ""
+	}
+	return subs, nil
+}
+
+// Deliveries returns the latest deliveries of a subscription
+func (s *WebhookService) Deliveries(ctx context.Context, id int64, limit int) ([]models.WebhookDelivery, error) {
+	if _, err := s.webhooks.GetWebhookSubscription(ctx, id); err != nil {
+		return nil, err
+	}
+	return s.webhooks.ListWebhookDeliveries(ctx, id, limit)
+}
+
+// Enable re-enables a subscription, e.g. once its endpoint is fixed. The
+// deliveries still pending resume.
+func (s *WebhookService) Enable(ctx context.Context, id int64, actor string) error {
+	if err := s.webhooks.EnableWebhookSubscription(ctx, id); err != nil {
+		return err
+	}
+	s.invalidate()
+
+	util.AdminActionsTotal.WithLabelValues("enable_webhook_subscription").Inc()
+	s.logger.Info("Webhook subscription enabled", zap.Int64("subscription_id", id), zap.String("actor", actor))
+	return nil
+}
+
+// Delete removes a subscription with its deliveries
+func (s *WebhookService) Delete(ctx context.Context, id int64, actor string) error {
+	if err := s.webhooks.DeleteWebhookSubscription(ctx, id); err != nil {
+		return err
+	}
+	s.invalidate()
+
+	util.AdminActionsTotal.WithLabelValues("delete_webhook_subscription").Inc()
+	s.logger.Info("Webhook subscription deleted", zap.Int64("subscription_id", id), zap.String("actor", actor))
+	return nil
+}
+
+// Fanout queues an order event for the active subscriptions of its type
+// owned by the event's tenant. Events of synthetic orders are not sent to
+// third parties.
+func (s *WebhookService) Fanout(ctx context.Context, event models.BaseEvent, payload []byte) error {
+	if !isWebhookEventType(event.EventType) {
+		return nil
+	}
+	var marker struct {
+		Synthetic bool  `json:"synthetic"`
+		OrderID   int64 `json:"order_id"`
+	}
+	if json.Unmarshal(payload, &marker) == nil && marker.Synthetic {
+		return nil
+	}
+
+	ctx, err := s.eventTenant(ctx, marker.OrderID)
+	if errors.Is(err, apperrors.ErrOrderNotFound) {
+		s.logger.Warn("Skipping webhooks of an event for an unknown order",
+			zap.String("event_id", event.EventID), zap.Int64("order_id", marker.OrderID))
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to resolve event tenant: %w", err)
+	}
+
+	subs, err := s.activeSubscriptions(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load webhook subscriptions: %w", err)
+	}
+	for i := range subs {
+		if !subs[i].Subscribes(event.EventType) || !tenant.Owns(ctx, subs[i].TenantID) {
+			continue
+		}
+		d := &models.WebhookDelivery{
+			SubscriptionID: subs[i].ID,
+			EventID:        event.EventID,
+			EventType:      event.EventType,
+			Payload:        string(payload),
+			Status:         models.WebhookDeliveryPending,
+			NextAttemptAt:  time.Now(),
+		}
+		created, err := s.webhooks.CreateWebhookDelivery(ctx, d)
+		if err != nil {
+			return fmt.Errorf("failed to queue webhook delivery: %w", err)
+		}
+		if created {
+			util.WebhookDeliveriesTotal.WithLabelValues("queued").Inc()
+		}
+	}
+	return nil
+}
+
+// eventTenant returns ctx acting for the tenant of an event. Events published
+// for no tenant, e.g. by sweepers, belong to the tenant of their order, and
+// events of no order to the default tenant, so that they never reach every
+// tenant's subscribers.
+func (s *WebhookService) eventTenant(ctx context.Context, orderID int64) (context.Context, error) {
+	if _, ok := tenant.ID(ctx); ok {
+		return ctx, nil
+	}
+	if orderID == 0 {
+		return tenant.WithID(ctx, tenant.Default), nil
+	}
+	id, err := s.webhooks.GetOrderTenant(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	return tenant.WithID(ctx, id), nil
+}
+
+// activeSubscriptions returns the active subscriptions of every tenant,
+// cached for a while so that every event does not query them
+func (s *WebhookService) activeSubscriptions(ctx context.Context) ([]models.WebhookSubscription, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.active != nil && time.Since(s.activeLoaded) < webhookSubscriberTTL {
+		return s.active, nil
+	}
+	subs, err := s.webhooks.ListWebhookSubscriptions(tenant.Detach(ctx), models.WebhookActive)
+	if err != nil {
+		return nil, err
+	}
+	s.active, s.activeLoaded = subs, time.Now()
+	return subs, nil
+}
+
+// invalidate drops the cached subscriptions after a change on this instance
+func (s *WebhookService) invalidate() {
+	s.mu.Lock()
+	s.active = nil
+	s.mu.Unlock()
+}
+
+// DeliverDue posts the deliveries that are due. Returns the number delivered.
+func (s *WebhookService) DeliverDue(ctx context.Context) (int, error) {
+	ctx, span := util.StartSpan(ctx, "WebhookService.DeliverDue")
+	defer span.End()
+
+	due, err := s.webhooks.ClaimDueWebhookDeliveries(ctx, time.Now(), webhookClaimTTL, webhookBatchSize)
+	if err != nil {
+		return 0, fmt.Errorf("failed to claim due webhook deliveries: %w", err)
+	}
+
+	subs := make(map[int64]*models.WebhookSubscription)
+	delivered := 0
+	for i := range due {
+		d := &due[i]
+		sub, ok := subs[d.SubscriptionID]
+		if !ok {
+			if sub, err = s.webhooks.GetWebhookSubscription(ctx, d.SubscriptionID); err != nil {
+				s.logger.Error("Failed to load webhook subscription",
+					zap.Int64("subscription_id", d.SubscriptionID),
+					zap.Error(err))
+				continue
+			}
+			subs[d.SubscriptionID] = sub
+		}
+		if sub.Status != models.WebhookActive {
+			continue
+		}
+
+		statusCode, postErr := s.post(ctx, sub, d)
+		disabled, err := s.recordAttempt(ctx, d, statusCode, postErr)
+		if err != nil {
+			s.logger.Error("Failed to record webhook attempt",
+				zap.Int64("delivery_id", d.ID),
+				zap.Error(err))
+			continue
+		}
+		if disabled {
+			sub.Status = models.WebhookDisabled
+			s.invalidate()
+			util.WebhookSubscriptionsDisabledTotal.Inc()
+			s.logger.Error("Webhook subscription disabled after repeated failures",
+				zap.Int64("subscription_id", sub.ID),
+				zap.String("url", sub.URL),
+				zap.Error(postErr))
+		}
+		if postErr == nil {
+			delivered++
+		}
+	}
+	return delivered, nil
+}
+
+// post sends a delivery to its subscription, signed. Returns the status code
+// answered, zero if there was no answer.
+func (s *WebhookService) post(ctx context.Context, sub *models.WebhookSubscription, d *models.WebhookDelivery) (int, error) {
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.URL, bytes.NewReader([]byte(d.Payload)))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookIDHeader, d.EventID)
+	req.Header.Set(WebhookEventHeader, d.EventType)
+	req.Header.Set(WebhookTimestampHeader, timestamp)
+	req.Header.Set(WebhookSignatureHeader, SignWebhook(sub.Secret, timestamp, []byte(d.Payload)))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp.StatusCode, nil
+	}
+	detail, _ := io.ReadAll(io.LimitReader(resp.Body, 256))
+	return resp.StatusCode, fmt.Errorf("endpoint answered %d: %s", resp.StatusCode, detail)
+}
+
+// SignWebhook returns the signature header value of a delivery body sent at
+// timestamp
+func SignWebhook(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// recordAttempt records the outcome of a delivery attempt: delivered,
+// rescheduled with backoff, or failed once the budget is spent. Returns
+// whether the attempt disabled the subscription.
+func (s *WebhookService) recordAttempt(ctx context.Context, d *models.WebhookDelivery, statusCode int, attemptErr error) (bool, error) {
+	now := time.Now()
+	d.Attempts++
+	d.LastAttemptAt = &now
+	d.LastStatusCode = nil
+	if statusCode != 0 {
+		d.LastStatusCode = &statusCode
+	}
+
+	switch {
+	case attemptErr == nil:
+		d.Status = models.WebhookDeliveryDelivered
+		d.DeliveredAt = &now
+		d.LastError = ""
+		util.WebhookDeliveriesTotal.WithLabelValues("delivered").Inc()
+	case d.Attempts >= s.policy.MaxAttempts:
+		d.Status = models.WebhookDeliveryFailed
+		d.LastError = attemptErr.Error()
+		util.WebhookDeliveriesTotal.WithLabelValues("failed").Inc()
+		s.logger.Warn("Webhook delivery out of retries",
+			zap.Int64("delivery_id", d.ID),
+			zap.Int64("subscription_id", d.SubscriptionID),
+			zap.String("event_id", d.EventID),
+			zap.Int("attempts", d.Attempts),
+			zap.Error(attemptErr))
+	default:
+		d.LastError = attemptErr.Error()
+		d.NextAttemptAt = now.Add(s.policy.Delay(d.Attempts))
+		util.WebhookDeliveriesTotal.WithLabelValues("rescheduled").Inc()
+	}
+	return s.webhooks.RecordWebhookAttempt(ctx, d, attemptErr == nil, s.policy.DisableAfterFailures)
+}
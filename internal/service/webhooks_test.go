@@ -0,0 +1,203 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"order-service/internal/apperrors"
+	"order-service/internal/models"
+	"order-service/internal/resilience/retry"
+	"order-service/internal/store/mocks"
+	"order-service/internal/tenant"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// staticResolver resolves every host to its addresses
+type staticResolver map[string][]string
+
+func (r staticResolver) LookupIPAddr(_ context.Context, host string) ([]net.IPAddr, error) {
+	var addrs []net.IPAddr
+	for _, a := range r[host] {
+		addrs = append(addrs, net.IPAddr{IP: net.ParseIP(a)})
+	}
+	if addrs == nil {
+		return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	}
+	return addrs, nil
+}
+
+func TestWebhookSubscribeRejectsInvalidRequests(t *testing.T) {
+	ws := NewWebhookService(mocks.NewWebhookRepository(t), WebhookPolicy{})
+	ws.resolver = staticResolver{
+		"example.com":          {"93.184.216.34"},
+		"internal.example.com": {"93.184.216.34", "10.0.0.5"},
+		"169.254.169.254":      {"169.254.169.254"},
+		"127.0.0.1":            {"127.0.0.1"},
+	}
+
+	for name, req := range map[string]WebhookSubscriptionRequest{
+		"insecure url":       {URL: "http://example.com/hook", EventTypes: []string{models.EventTypeOrderPaid}},
+		"relative url":       {URL: "/hook", EventTypes: []string{models.EventTypeOrderPaid}},
+		"unknown event type": {URL: "https://example.com/hook", EventTypes: []string{models.EventTypeUserDeleted}},
+		"no event types":     {URL: "https://example.com/hook"},
+		"unresolvable host":  {URL: "https://nowhere.example.com/hook", EventTypes: []string{models.EventTypeOrderPaid}},
+		"private address":    {URL: "https://internal.example.com/hook", EventTypes: []string{models.EventTypeOrderPaid}},
+		"link-local address": {URL: "https://169.254.169.254/latest/meta-data", EventTypes: []string{models.EventTypeOrderPaid}},
+		"loopback address":   {URL: "https://127.0.0.1/hook", EventTypes: []string{models.EventTypeOrderPaid}},
+	} {
+		_, err := ws.Subscribe(context.Background(), req, "ops")
+		assert.True(t, errors.Is(err, apperrors.ErrInvalidRequest), name)
+	}
+}
+
+func TestWebhookSubscribeReturnsSecret(t *testing.T) {
+	webhooks := mocks.NewWebhookRepository(t)
+	webhooks.On("CreateWebhookSubscription", mock.Anything, mock.MatchedBy(func(sub *models.WebhookSubscription) bool {
+		return len(sub.Secret) == 64 && sub.Status == models.WebhookActive &&
+			assert.ObjectsAreEqual([]string{models.EventTypeOrderPaid}, sub.EventTypes)
+	})).Return(nil).Once()
+
+	ws := NewWebhookService(webhooks, WebhookPolicy{})
+	ws.resolver = staticResolver{"example.com": {"93.184.216.34"}}
+
+	sub, err := ws.Subscribe(context.Background(), WebhookSubscriptionRequest{
+		URL:        "https://example.com/hook",
+		EventTypes: []string{models.EventTypeOrderPaid, models.EventTypeOrderPaid},
+	}, "ops")
+	require.NoError(t, err)
+	assert.NotEmpty(t, sub.Secret)
+}
+
+func TestWebhookFanoutSkipsSyntheticOrders(t *testing.T) {
+	ws := NewWebhookService(mocks.NewWebhookRepository(t), WebhookPolicy{})
+
+	event := models.BaseEvent{EventID: "e1", EventType: models.EventTypeOrderCreated}
+	err := ws.Fanout(context.Background(), event, []byte(`{"event_type":"ORDER_CREATED","synthetic":true}`))
+	assert.NoError(t, err)
+}
+
+func TestWebhookFanoutQueuesSubscribedEvents(t *testing.T) {
+	webhooks := mocks.NewWebhookRepository(t)
+	webhooks.On("ListWebhookSubscriptions", mock.Anything, models.WebhookActive).Return([]models.WebhookSubscription{
+		{ID: 1, EventTypes: []string{models.EventTypeOrderPaid}},
+		{ID: 2, EventTypes: []string{models.EventTypeOrderCancelled}},
+	}, nil).Once()
+	webhooks.On("CreateWebhookDelivery", mock.Anything, mock.MatchedBy(func(d *models.WebhookDelivery) bool {
+		return d.SubscriptionID == 1 && d.EventID == "e1" && d.Status == models.WebhookDeliveryPending
+	})).Return(true, nil).Twice()
+
+	ws := NewWebhookService(webhooks, WebhookPolicy{})
+
+	event := models.BaseEvent{EventID: "e1", EventType: models.EventTypeOrderPaid}
+	require.NoError(t, ws.Fanout(context.Background(), event, []byte(`{}`)))
+	// The subscriptions are cached between events
+	require.NoError(t, ws.Fanout(context.Background(), event, []byte(`{}`)))
+}
+
+func TestWebhookFanoutOnlyQueuesTheEventTenantsSubscriptions(t *testing.T) {
+	webhooks := mocks.NewWebhookRepository(t)
+	webhooks.On("ListWebhookSubscriptions", mock.Anything, models.WebhookActive).Return([]models.WebhookSubscription{
+		{ID: 1, TenantID: "acme", EventTypes: []string{models.EventTypeOrderPaid}},
+		{ID: 2, TenantID: "globex", EventTypes: []string{models.EventTypeOrderPaid}},
+	}, nil).Once()
+	webhooks.On("CreateWebhookDelivery", mock.Anything, mock.MatchedBy(func(d *models.WebhookDelivery) bool {
+		return d.SubscriptionID == 1
+	})).Return(true, nil).Once()
+	webhooks.On("CreateWebhookDelivery", mock.Anything, mock.MatchedBy(func(d *models.WebhookDelivery) bool {
+		return d.SubscriptionID == 2
+	})).Return(true, nil).Once()
+	// Events published for no tenant go to the subscribers of their order's
+	webhooks.On("GetOrderTenant", mock.Anything, int64(9)).Return("globex", nil).Once()
+
+	ws := NewWebhookService(webhooks, WebhookPolicy{})
+
+	event := models.BaseEvent{EventID: "e1", EventType: models.EventTypeOrderPaid}
+	require.NoError(t, ws.Fanout(tenant.WithID(context.Background(), "acme"), event, []byte(`{"order_id":8}`)))
+	event.EventID = "e2"
+	require.NoError(t, ws.Fanout(context.Background(), event, []byte(`{"order_id":9}`)))
+}
+
+func TestWebhookDeliverySignsTimestampAndBody(t *testing.T) {
+	var got http.Header
+	endpoint := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+	}))
+	defer endpoint.Close()
+
+	sub := &models.WebhookSubscription{ID: 1, URL: endpoint.URL, Secret: "s3cret", Status: models.WebhookActive}
+	webhooks := mocks.NewWebhookRepository(t)
+	webhooks.On("ClaimDueWebhookDeliveries", mock.Anything, mock.Anything, webhookClaimTTL, webhookBatchSize).
+		Return([]models.WebhookDelivery{{ID: 9, SubscriptionID: 1, EventID: "e1", EventType: models.EventTypeOrderPaid, Payload: `{"order_id":1}`}}, nil).Once()
+	webhooks.On("GetWebhookSubscription", mock.Anything, int64(1)).Return(sub, nil).Once()
+	webhooks.On("RecordWebhookAttempt", mock.Anything, mock.MatchedBy(func(d *models.WebhookDelivery) bool {
+		return d.Status == models.WebhookDeliveryDelivered && d.Attempts == 1 && *d.LastStatusCode == http.StatusOK
+	}), true, 3).Return(false, nil).Once()
+
+	ws := NewWebhookService(webhooks, WebhookPolicy{Policy: retry.Policy{MaxAttempts: 5}, DisableAfterFailures: 3, AllowInsecureURLs: true, AllowPrivateAddresses: true})
+
+	delivered, err := ws.DeliverDue(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, delivered)
+	assert.Equal(t, "e1", got.Get(WebhookIDHeader))
+	assert.Equal(t, models.EventTypeOrderPaid, got.Get(WebhookEventHeader))
+	assert.Equal(t, SignWebhook("s3cret", got.Get(WebhookTimestampHeader), []byte(`{"order_id":1}`)), got.Get(WebhookSignatureHeader))
+}
+
+func TestWebhookDeliveryRefusesPrivateAddressesAndRedirects(t *testing.T) {
+	var posted int
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		posted++
+	}))
+	defer target.Close()
+	redirect := httptest.NewServer(http.RedirectHandler(target.URL, http.StatusTemporaryRedirect))
+	defer redirect.Close()
+	d := &models.WebhookDelivery{EventID: "e1", Payload: `{}`}
+
+	// A host that resolved to a public address when subscribed now resolves
+	// to a private one
+	ws := NewWebhookService(mocks.NewWebhookRepository(t), WebhookPolicy{AllowInsecureURLs: true})
+	_, err := ws.post(context.Background(), &models.WebhookSubscription{URL: target.URL}, d)
+	assert.ErrorContains(t, err, "is not public")
+
+	ws = NewWebhookService(mocks.NewWebhookRepository(t), WebhookPolicy{AllowInsecureURLs: true, AllowPrivateAddresses: true})
+	status, err := ws.post(context.Background(), &models.WebhookSubscription{URL: redirect.URL}, d)
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusTemporaryRedirect, status)
+	assert.Zero(t, posted, "redirects are not followed")
+}
+
+func TestWebhookFailedDeliveryBacksOffAndDisables(t *testing.T) {
+	endpoint := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer endpoint.Close()
+
+	sub := &models.WebhookSubscription{ID: 1, URL: endpoint.URL, Secret: "s3cret", Status: models.WebhookActive}
+	webhooks := mocks.NewWebhookRepository(t)
+	webhooks.On("ClaimDueWebhookDeliveries", mock.Anything, mock.Anything, webhookClaimTTL, webhookBatchSize).
+		Return([]models.WebhookDelivery{
+			{ID: 9, SubscriptionID: 1, EventID: "e1", Payload: `{}`, Status: models.WebhookDeliveryPending, Attempts: 1},
+			{ID: 10, SubscriptionID: 1, EventID: "e2", Payload: `{}`, Status: models.WebhookDeliveryPending},
+		}, nil).Once()
+	webhooks.On("GetWebhookSubscription", mock.Anything, int64(1)).Return(sub, nil).Once()
+	webhooks.On("RecordWebhookAttempt", mock.Anything, mock.MatchedBy(func(d *models.WebhookDelivery) bool {
+		return d.ID == 9 && d.Status == models.WebhookDeliveryPending && d.Attempts == 2 && *d.LastStatusCode == http.StatusServiceUnavailable &&
+			d.NextAttemptAt.After(time.Now())
+	}), false, 3).Return(true, nil).Once()
+
+	ws := NewWebhookService(webhooks, WebhookPolicy{Policy: retry.Policy{MaxAttempts: 5, BaseDelay: time.Minute, MaxDelay: time.Hour}, DisableAfterFailures: 3, AllowInsecureURLs: true, AllowPrivateAddresses: true})
+
+	delivered, err := ws.DeliverDue(context.Background())
+	require.NoError(t, err)
+	assert.Zero(t, delivered)
+	// The second delivery waits for the disabled subscription to be re-enabled
+	assert.Equal(t, models.WebhookDisabled, sub.Status)
+}
@@ -0,0 +1,211 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"order-service/internal/health"
+	"order-service/internal/redisclient"
+	"order-service/internal/util"
+
+	"go.uber.org/zap"
+)
+
+// heartbeatRetention is how long the heartbeat of a worker instance that
+// stopped reporting, e.g. of a pod scaled down, is still listed
+const heartbeatRetention = 24 * time.Hour
+
+// WorkerHeartbeatPolicy sets when a worker counts as alive
+type WorkerHeartbeatPolicy struct {
+	// Timeout is how long a worker may go without a heartbeat
+	Timeout time.Duration
+	// StuckThreshold is how long a worker may spend on one message and still
+	// heartbeat
+	StuckThreshold time.Duration
+	// Critical workers fail readiness once they miss Timeout
+	Critical []string
+}
+
+// WorkerHeartbeat is the latest report of a worker on one instance
+type WorkerHeartbeat struct {
+	Worker   string `json:"worker"`
+	Instance string `json:"instance"`
+	Critical bool   `json:"critical"`
+	// HeartbeatAt is when the worker was last seen running and not stuck
+	HeartbeatAt *time.Time `json:"heartbeat_at,omitempty"`
+	ReportedAt  time.Time  `json:"reported_at"`
+	// Lag is how many messages the worker's consumer is behind, for Kafka consumers
+	Lag *int64 `json:"lag,omitempty"`
+	health.WorkerStatus
+}
+
+// WorkerLiveness is a stored heartbeat judged against the timeout
+type WorkerLiveness struct {
+	WorkerHeartbeat
+	Alive bool `json:"alive"`
+}
+
+// WorkerHeartbeats periodically writes a heartbeat of each worker of this
+// instance to Redis, so the liveness of the workers of every instance can be
+// listed in one place, and fails readiness when a critical worker stops
+type WorkerHeartbeats struct {
+	redis    *redisclient.Client
+	checker  *health.Checker
+	instance string
+	policy   WorkerHeartbeatPolicy
+	sources  map[string]BacklogSource
+	created  time.Time
+	logger   *zap.Logger
+
+	mu        sync.Mutex
+	lastBeats map[string]time.Time
+}
+
+// NewWorkerHeartbeats creates heartbeats of the workers recorded in checker,
+// reported as instance
+func NewWorkerHeartbeats(redis *redisclient.Client, checker *health.Checker, instance string, policy WorkerHeartbeatPolicy) *WorkerHeartbeats {
+	return &WorkerHeartbeats{
+		redis:     redis,
+		checker:   checker,
+		instance:  instance,
+		policy:    policy,
+		sources:   make(map[string]BacklogSource),
+		created:   time.Now(),
+		logger:    util.GetLogger(),
+		lastBeats: make(map[string]time.Time),
+	}
+}
+
+// Watch adds the consumer lag of a worker to its heartbeats
+func (wh *WorkerHeartbeats) Watch(worker string, source BacklogSource) {
+	wh.sources[worker] = source
+}
+
+// Beat records a heartbeat of every running worker that is not stuck on a
+// message, and writes each worker's status to Redis
+func (wh *WorkerHeartbeats) Beat(ctx context.Context) error {
+	now := time.Now().UTC()
+
+	var failed []string
+	for worker, status := range wh.checker.Workers() {
+		stuck := status.InFlightSince != nil && now.Sub(*status.InFlightSince) > wh.policy.StuckThreshold
+
+		wh.mu.Lock()
+		if status.Running && !stuck {
+			wh.lastBeats[worker] = now
+		}
+		lastBeat, beaten := wh.lastBeats[worker]
+		wh.mu.Unlock()
+
+		heartbeat := WorkerHeartbeat{
+			Worker:       worker,
+			Instance:     wh.instance,
+			Critical:     wh.isCritical(worker),
+			ReportedAt:   now,
+			WorkerStatus: status,
+		}
+		if beaten {
+			heartbeat.HeartbeatAt = &lastBeat
+			util.WorkerHeartbeatAge.WithLabelValues(worker).Set(now.Sub(lastBeat).Seconds())
+		}
+		if source, ok := wh.sources[worker]; ok {
+			lag := source.Lag()
+			heartbeat.Lag = &lag
+		}
+
+		payload, err := json.Marshal(heartbeat)
+		if err != nil {
+			return fmt.Errorf("failed to marshal heartbeat: %w", err)
+		}
+		if err := wh.redis.SaveWorkerHeartbeat(ctx, worker+"@"+wh.instance, payload); err != nil {
+			failed = append(failed, worker)
+		}
+	}
+	if len(failed) > 0 {
+		sort.Strings(failed)
+		return fmt.Errorf("failed to write heartbeats of %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// List returns the latest heartbeat of each worker on each instance, critical
+// workers first, and drops those not reported for a day
+func (wh *WorkerHeartbeats) List(ctx context.Context) ([]WorkerLiveness, error) {
+	entries, err := wh.redis.GetWorkerHeartbeats(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read heartbeats: %w", err)
+	}
+
+	now := time.Now()
+	workers := make([]WorkerLiveness, 0, len(entries))
+	var expired []string
+	for field, payload := range entries {
+		var heartbeat WorkerHeartbeat
+		if err := json.Unmarshal(payload, &heartbeat); err != nil {
+			wh.logger.Warn("Skipping unreadable worker heartbeat", zap.String("field", field), zap.Error(err))
+			continue
+		}
+		if now.Sub(heartbeat.ReportedAt) > heartbeatRetention {
+			expired = append(expired, field)
+			continue
+		}
+		workers = append(workers, WorkerLiveness{
+			WorkerHeartbeat: heartbeat,
+			Alive:           heartbeat.HeartbeatAt != nil && now.Sub(*heartbeat.HeartbeatAt) <= wh.policy.Timeout,
+		})
+	}
+	if err := wh.redis.DeleteWorkerHeartbeats(ctx, expired...); err != nil {
+		wh.logger.Warn("Failed to drop expired worker heartbeats", zap.Error(err))
+	}
+
+	sort.Slice(workers, func(i, j int) bool {
+		a, b := workers[i], workers[j]
+		if a.Critical != b.Critical {
+			return a.Critical
+		}
+		if a.Worker != b.Worker {
+			return a.Worker < b.Worker
+		}
+		return a.Instance < b.Instance
+	})
+	return workers, nil
+}
+
+// Check fails when a critical worker of this instance has not had a
+// heartbeat within the timeout, counting from startup for workers that never
+// had one. It is a readiness check, so the instance stops taking traffic.
+func (wh *WorkerHeartbeats) Check(ctx context.Context) error {
+	now := time.Now()
+
+	wh.mu.Lock()
+	defer wh.mu.Unlock()
+
+	var missing []string
+	for _, worker := range wh.policy.Critical {
+		lastBeat, ok := wh.lastBeats[worker]
+		if !ok {
+			lastBeat = wh.created
+		}
+		if now.Sub(lastBeat) > wh.policy.Timeout {
+			missing = append(missing, worker)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("no heartbeat within %s from %s", wh.policy.Timeout, strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+func (wh *WorkerHeartbeats) isCritical(worker string) bool {
+	for _, critical := range wh.policy.Critical {
+		if critical == worker {
+			return true
+		}
+	}
+	return false
+}
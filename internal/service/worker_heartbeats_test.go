@@ -0,0 +1,70 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"order-service/internal/health"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWorkerHeartbeatsListLivenessAndLag(t *testing.T) {
+	ctx := context.Background()
+	checker := health.NewChecker()
+	heartbeats := NewWorkerHeartbeats(newTestRedis(t), checker, "pod-1", WorkerHeartbeatPolicy{
+		Timeout:        time.Minute,
+		StuckThreshold: time.Minute,
+		Critical:       []string{"order-worker"},
+	})
+	heartbeats.Watch("order-worker", fakeBacklog{topic: "order-events", lag: 7})
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go checker.RunWorker("order-worker", func() error { <-stop; return nil })
+	require.Eventually(t, func() bool { return checker.Workers()["order-worker"].Running }, time.Second, time.Millisecond)
+	checker.RecordWork("order-worker", 1, 99, nil)
+	// A worker that exited keeps reporting its status, but has no heartbeat
+	_ = checker.RunWorker("drops", func() error { return assert.AnError })
+
+	require.NoError(t, heartbeats.Beat(ctx))
+	workers, err := heartbeats.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, workers, 2)
+
+	assert.Equal(t, "order-worker", workers[0].Worker)
+	assert.Equal(t, "pod-1", workers[0].Instance)
+	assert.True(t, workers[0].Critical)
+	assert.True(t, workers[0].Alive)
+	assert.Equal(t, int64(99), workers[0].LastOffset)
+	assert.Equal(t, int64(7), *workers[0].Lag)
+
+	assert.Equal(t, "drops", workers[1].Worker)
+	assert.False(t, workers[1].Alive)
+	assert.Equal(t, assert.AnError.Error(), workers[1].LastError)
+}
+
+func TestWorkerHeartbeatsCheckFailsForStuckCriticalWorkers(t *testing.T) {
+	checker := health.NewChecker()
+	heartbeats := NewWorkerHeartbeats(newTestRedis(t), checker, "pod-1", WorkerHeartbeatPolicy{
+		Timeout:        time.Minute,
+		StuckThreshold: time.Millisecond,
+		Critical:       []string{"order-worker"},
+	})
+
+	// Critical workers get the timeout from startup to have a first heartbeat
+	assert.NoError(t, heartbeats.Check(context.Background()))
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go checker.RunWorker("order-worker", func() error { <-stop; return nil })
+	require.Eventually(t, func() bool { return checker.Workers()["order-worker"].Running }, time.Second, time.Millisecond)
+	checker.BeginWork("order-worker")
+	time.Sleep(5 * time.Millisecond)
+	require.NoError(t, heartbeats.Beat(context.Background()))
+
+	heartbeats.created = time.Now().Add(-2 * time.Minute)
+	assert.ErrorContains(t, heartbeats.Check(context.Background()), "order-worker")
+}
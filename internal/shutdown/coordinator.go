@@ -0,0 +1,88 @@
+package shutdown
+
+import (
+	"context"
+	"time"
+
+	"order-service/internal/util"
+
+	"go.uber.org/zap"
+)
+
+// closeTimeout bounds each close step, which runs even if draining timed out
+const closeTimeout = 5 * time.Second
+
+type step struct {
+	name string
+	fn   func(ctx context.Context) error
+}
+
+// Coordinator runs shutdown in two phases: drain steps share one drain
+// timeout and run in order, then close steps release connections. Steps are
+// run in registration order within each phase.
+type Coordinator struct {
+	drainTimeout time.Duration
+	drain        []step
+	close        []step
+	logger       *zap.Logger
+}
+
+// NewCoordinator creates a new shutdown coordinator
+func NewCoordinator(drainTimeout time.Duration) *Coordinator {
+	return &Coordinator{
+		drainTimeout: drainTimeout,
+		logger:       util.GetLogger(),
+	}
+}
+
+// OnDrain registers a step that finishes in-flight work, e.g. stopping the
+// HTTP server or waiting for consumer handlers
+func (c *Coordinator) OnDrain(name string, fn func(ctx context.Context) error) {
+	c.drain = append(c.drain, step{name: name, fn: fn})
+}
+
+// OnClose registers a step that releases a connection once draining is done
+func (c *Coordinator) OnClose(name string, fn func(ctx context.Context) error) {
+	c.close = append(c.close, step{name: name, fn: fn})
+}
+
+// Shutdown runs all drain steps, then all close steps. A failing or timed out
+// step is logged and does not stop the steps after it. Returns false if any
+// drain step did not finish cleanly.
+func (c *Coordinator) Shutdown() bool {
+	drained := true
+
+	drainCtx, cancel := context.WithTimeout(context.Background(), c.drainTimeout)
+	defer cancel()
+	for _, s := range c.drain {
+		if !c.run(drainCtx, s, "drain") {
+			drained = false
+		}
+	}
+
+	for _, s := range c.close {
+		ctx, cancel := context.WithTimeout(context.Background(), closeTimeout)
+		c.run(ctx, s, "close")
+		cancel()
+	}
+
+	return drained
+}
+
+// run executes one step and reports whether it succeeded
+func (c *Coordinator) run(ctx context.Context, s step, phase string) bool {
+	start := time.Now()
+	if err := s.fn(ctx); err != nil {
+		c.logger.Error("Shutdown step failed",
+			zap.String("phase", phase),
+			zap.String("step", s.name),
+			zap.Error(err))
+		return false
+	}
+
+	c.logger.Info("Shutdown step done",
+		zap.String("phase", phase),
+		zap.String("step", s.name),
+		zap.Duration("took", time.Since(start)))
+	return true
+}
@@ -0,0 +1,59 @@
+package shutdown
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShutdownRunsDrainStepsBeforeCloseSteps(t *testing.T) {
+	var order []string
+	record := func(name string) func(context.Context) error {
+		return func(context.Context) error {
+			order = append(order, name)
+			return nil
+		}
+	}
+
+	c := NewCoordinator(time.Second)
+	c.OnClose("postgres", record("postgres"))
+	c.OnDrain("http", record("http"))
+	c.OnDrain("consumers", record("consumers"))
+
+	assert.True(t, c.Shutdown())
+	assert.Equal(t, []string{"http", "consumers", "postgres"}, order)
+}
+
+func TestShutdownClosesConnectionsWhenDrainTimesOut(t *testing.T) {
+	closed := false
+
+	c := NewCoordinator(10 * time.Millisecond)
+	c.OnDrain("consumers", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	c.OnClose("postgres", func(ctx context.Context) error {
+		closed = ctx.Err() == nil
+		return nil
+	})
+
+	assert.False(t, c.Shutdown())
+	assert.True(t, closed)
+}
+
+func TestShutdownContinuesPastFailedStep(t *testing.T) {
+	ran := false
+
+	c := NewCoordinator(time.Second)
+	c.OnDrain("http", func(context.Context) error { return errors.New("boom") })
+	c.OnDrain("consumers", func(context.Context) error {
+		ran = true
+		return nil
+	})
+
+	assert.False(t, c.Shutdown())
+	assert.True(t, ran)
+}
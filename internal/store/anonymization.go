@@ -0,0 +1,96 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"order-service/internal/models"
+
+	"github.com/lib/pq"
+)
+
+// CreateAnonymizationJob starts a job for a UserDeleted event, or returns the
+// existing job when the event is redelivered
+func (s *Store) CreateAnonymizationJob(ctx context.Context, eventID string, userID int64) (*models.AnonymizationJob, error) {
+	query := `
+		INSERT INTO anonymization_jobs (event_id, user_id, status, orders_total)
+		VALUES ($1, $2, $3, (SELECT COUNT(*) FROM orders WHERE user_id = $2 AND anonymized_at IS NULL))
+		ON CONFLICT (event_id) DO UPDATE SET event_id = EXCLUDED.event_id
+		RETURNING *`
+
+	var job models.AnonymizationJob
+	if err := s.get(ctx, "create_anonymization_job", &job, query, eventID, userID, models.AnonymizationStatusRunning); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// AnonymizeUserOrders processes up to limit of the user's remaining orders:
+// orders created before retainAfter are deleted with their items and payments,
+// newer ones are kept for accounting but detached from the user.
+func (s *Store) AnonymizeUserOrders(ctx context.Context, userID int64, retainAfter time.Time, limit int) (anonymized, deleted int, err error) {
+	err = s.withRetry(ctx, "anonymize_user_orders", func() error {
+		tx, err := s.db.BeginTxx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+
+		var ids []int64
+		err = s.selectTx(ctx, tx, "lock_user_orders", &ids,
+			"SELECT id FROM orders WHERE user_id = $1 AND anonymized_at IS NULL ORDER BY id LIMIT $2 FOR UPDATE",
+			userID, limit)
+		if err != nil || len(ids) == 0 {
+			return err
+		}
+
+		res, err := s.execTx(ctx, tx, "delete_user_orders",
+			"DELETE FROM orders WHERE id = ANY($1) AND created_at < $2",
+			pq.Array(ids), retainAfter)
+		if err != nil {
+			return err
+		}
+		removed, _ := res.RowsAffected()
+
+		res, err = s.execTx(ctx, tx, "anonymize_user_orders",
+			`UPDATE orders
+			 SET user_id = $2, idempotency_key = NULL, customer_email = NULL, customer_name = NULL,
+			     anonymized_at = NOW(), updated_at = NOW()
+			 WHERE id = ANY($1)`,
+			pq.Array(ids), models.AnonymousUserID)
+		if err != nil {
+			return err
+		}
+		kept, _ := res.RowsAffected()
+
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+		anonymized, deleted = int(kept), int(removed)
+		return nil
+	})
+	return anonymized, deleted, err
+}
+
+// UpdateAnonymizationJobProgress adds processed order counts to a job
+func (s *Store) UpdateAnonymizationJobProgress(ctx context.Context, jobID int64, anonymized, deleted int) (*models.AnonymizationJob, error) {
+	var job models.AnonymizationJob
+	err := s.get(ctx, "update_anonymization_job_progress", &job,
+		`UPDATE anonymization_jobs
+		 SET orders_anonymized = orders_anonymized + $2, orders_deleted = orders_deleted + $3
+		 WHERE id = $1
+		 RETURNING *`,
+		jobID, anonymized, deleted)
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// CompleteAnonymizationJob marks a job completed
+func (s *Store) CompleteAnonymizationJob(ctx context.Context, jobID int64) error {
+	_, err := s.exec(ctx, "complete_anonymization_job",
+		"UPDATE anonymization_jobs SET status = $2, completed_at = NOW() WHERE id = $1",
+		jobID, models.AnonymizationStatusCompleted)
+	return err
+}
@@ -0,0 +1,36 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"order-service/internal/models"
+)
+
+// CreateAuditEntry records an admin mutation
+func (s *Store) CreateAuditEntry(ctx context.Context, entry *models.AuditEntry) error {
+	return s.get(ctx, "create_audit_entry", entry,
+		`INSERT INTO audit_log (actor, action, target, request_id, status, before, after, diff)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, created_at`,
+		entry.Actor, entry.Action, entry.Target, entry.RequestID, entry.Status,
+		entry.Before, entry.After, entry.Diff)
+}
+
+// ListAuditEntries returns the audit log entries matching filter, newest first
+func (s *Store) ListAuditEntries(ctx context.Context, filter models.AuditFilter) ([]models.AuditEntry, error) {
+	until := filter.Until
+	if until.IsZero() {
+		until = time.Now()
+	}
+
+	entries := []models.AuditEntry{}
+	err := s.selectWithFailover(ctx, "list_audit_entries", &entries,
+		`SELECT * FROM audit_log
+		WHERE ($1 = '' OR actor = $1) AND ($2 = '' OR action = $2)
+		AND created_at >= $3 AND created_at < $4
+		ORDER BY created_at DESC, id DESC
+		LIMIT $5`,
+		filter.Actor, filter.Action, filter.From.UTC(), until.UTC(), filter.Limit)
+	return entries, err
+}
@@ -0,0 +1,48 @@
+package store
+
+import (
+	"context"
+
+	"order-service/internal/models"
+
+	"github.com/lib/pq"
+)
+
+// MarkOrderItemsBackordered backorders the items of an order for the given variants
+func (s *Store) MarkOrderItemsBackordered(ctx context.Context, orderID int64, variantIDs []int64) error {
+	return s.withRetry(ctx, "mark_order_items_backordered", func() error {
+		_, err := s.exec(ctx, "mark_order_items_backordered",
+			"UPDATE order_items SET fulfillment_status = $1 WHERE order_id = $2 AND variant_id = ANY($3)",
+			models.FulfillmentStatusBackordered, orderID, pq.Array(variantIDs))
+		return err
+	})
+}
+
+// GetBackorderedItems retrieves backordered items of orders still going ahead
+// (reserved, paid or confirmed), oldest first after afterID
+func (s *Store) GetBackorderedItems(ctx context.Context, afterID int64, limit int) ([]models.OrderItem, error) {
+	var items []models.OrderItem
+	err := s.selectWithFailover(ctx, "get_backordered_items", &items,
+		`SELECT oi.* FROM order_items oi
+		JOIN orders o ON o.id = oi.order_id
+		WHERE oi.fulfillment_status = $1 AND oi.id > $2 AND o.status IN ($3, $4, $5)
+		ORDER BY oi.id
+		LIMIT $6`,
+		models.FulfillmentStatusBackordered, afterID,
+		models.OrderStatusReserved, models.OrderStatusPaid, models.OrderStatusConfirmed,
+		limit)
+	return items, err
+}
+
+// MarkOrderItemAllocated marks a backordered item as having its stock reserved.
+// Returns false if the item was not backordered.
+func (s *Store) MarkOrderItemAllocated(ctx context.Context, itemID int64) (bool, error) {
+	res, err := s.exec(ctx, "mark_order_item_allocated",
+		"UPDATE order_items SET fulfillment_status = $1 WHERE id = $2 AND fulfillment_status = $3",
+		models.FulfillmentStatusAllocated, itemID, models.FulfillmentStatusBackordered)
+	if err != nil {
+		return false, err
+	}
+	rows, err := res.RowsAffected()
+	return rows > 0, err
+}
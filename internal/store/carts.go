@@ -0,0 +1,141 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"order-service/internal/apperrors"
+	"order-service/internal/models"
+	"order-service/internal/tenant"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// CreateCart stores a new, empty cart of the tenant of ctx
+func (s *Store) CreateCart(ctx context.Context, cart *models.Cart) error {
+	return s.get(ctx, "create_cart", cart,
+		`INSERT INTO carts (id, user_id, status, expires_at, tenant_id)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING *`,
+		cart.ID, cart.UserID, cart.Status, cart.ExpiresAt.UTC(), tenant.Owner(ctx))
+}
+
+// GetCart retrieves a cart with its items, oldest first; another tenant's
+// cart is not found
+func (s *Store) GetCart(ctx context.Context, id string) (*models.Cart, error) {
+	var cart models.Cart
+	err := s.get(ctx, "get_cart", &cart,
+		"SELECT * FROM carts WHERE id = $1 AND ($2 = '' OR tenant_id = $2)", id, tenantArg(ctx))
+	if err == sql.ErrNoRows {
+		return nil, apperrors.New(apperrors.ErrNotFound, "cart %s not found", id)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	cart.Items = []models.CartItem{}
+	if err := s.selectAll(ctx, "get_cart_items", &cart.Items,
+		"SELECT * FROM cart_items WHERE cart_id = $1 ORDER BY created_at, variant_id", id); err != nil {
+		return nil, err
+	}
+	return &cart, nil
+}
+
+// AddCartItem adds the quantity of an item to an open cart, adding the item
+// if the cart does not hold its variant yet, and extends the cart to expiresAt
+func (s *Store) AddCartItem(ctx context.Context, item *models.CartItem, expiresAt time.Time) error {
+	return s.updateOpenCart(ctx, item.CartID, expiresAt, func(tx *sqlx.Tx) error {
+		_, err := s.execTx(ctx, tx, "add_cart_item",
+			`INSERT INTO cart_items (cart_id, product_id, variant_id, quantity)
+			VALUES ($1, $2, $3, $4)
+			ON CONFLICT (cart_id, variant_id) DO UPDATE
+			SET quantity = cart_items.quantity + EXCLUDED.quantity, updated_at = NOW()`,
+			item.CartID, item.ProductID, item.VariantID, item.Quantity)
+		return err
+	})
+}
+
+// SetCartItemQuantity sets the quantity of an item in an open cart, removing
+// it at zero, and extends the cart to expiresAt
+func (s *Store) SetCartItemQuantity(ctx context.Context, cartID string, variantID int64, quantity int, expiresAt time.Time) error {
+	return s.updateOpenCart(ctx, cartID, expiresAt, func(tx *sqlx.Tx) error {
+		var res sql.Result
+		var err error
+		if quantity > 0 {
+			res, err = s.execTx(ctx, tx, "set_cart_item_quantity",
+				"UPDATE cart_items SET quantity = $3, updated_at = NOW() WHERE cart_id = $1 AND variant_id = $2",
+				cartID, variantID, quantity)
+		} else {
+			res, err = s.execTx(ctx, tx, "remove_cart_item",
+				"DELETE FROM cart_items WHERE cart_id = $1 AND variant_id = $2", cartID, variantID)
+		}
+		if err != nil {
+			return err
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return apperrors.New(apperrors.ErrNotFound, "variant %d is not in cart %s", variantID, cartID)
+		}
+		return nil
+	})
+}
+
+// updateOpenCart runs fn on a cart locked for update and extends the cart to
+// expiresAt. Carts that expired or were checked out are not changed.
+func (s *Store) updateOpenCart(ctx context.Context, cartID string, expiresAt time.Time, fn func(tx *sqlx.Tx) error) error {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var cart models.Cart
+	err = s.getTx(ctx, tx, "lock_cart", &cart,
+		"SELECT * FROM carts WHERE id = $1 AND ($2 = '' OR tenant_id = $2) FOR UPDATE", cartID, tenantArg(ctx))
+	if err == sql.ErrNoRows || (err == nil && !cart.ExpiresAt.After(time.Now())) {
+		return apperrors.New(apperrors.ErrNotFound, "cart %s not found", cartID)
+	}
+	if err != nil {
+		return err
+	}
+	if cart.Status != models.CartOpen {
+		return apperrors.New(apperrors.ErrCartClosed, "cart %s is already checked out", cartID)
+	}
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	if _, err := s.execTx(ctx, tx, "extend_cart",
+		"UPDATE carts SET expires_at = $2, updated_at = NOW() WHERE id = $1", cartID, expiresAt.UTC()); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// CheckOutCart marks an open cart checked out into an order. Returns false if
+// the cart was no longer open.
+func (s *Store) CheckOutCart(ctx context.Context, cartID string, orderID int64) (bool, error) {
+	res, err := s.exec(ctx, "check_out_cart",
+		`UPDATE carts SET status = $3, order_id = $2, updated_at = NOW()
+		WHERE id = $1 AND status = $4 AND ($5 = '' OR tenant_id = $5)`,
+		cartID, orderID, models.CartCheckedOut, models.CartOpen, tenantArg(ctx))
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+// DeleteExpiredCarts deletes up to limit carts that expired before the given
+// time, with their items. Returns the number deleted.
+func (s *Store) DeleteExpiredCarts(ctx context.Context, before time.Time, limit int) (int64, error) {
+	res, err := s.exec(ctx, "delete_expired_carts",
+		`DELETE FROM carts WHERE id IN (
+			SELECT id FROM carts WHERE expires_at < $1 LIMIT $2
+		)`,
+		before.UTC(), limit)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
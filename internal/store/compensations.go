@@ -0,0 +1,89 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"order-service/internal/apperrors"
+	"order-service/internal/models"
+)
+
+// CreateCompensation queues a failed compensation step. Returns false if the
+// same step is already queued or escalated.
+func (s *Store) CreateCompensation(ctx context.Context, c *models.Compensation) (bool, error) {
+	err := s.get(ctx, "create_compensation", c,
+		`INSERT INTO compensations (order_id, kind, variant_id, payment_id, amount, reason, priority, status, attempts, next_attempt_at, last_error)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		ON CONFLICT (order_id, kind, variant_id, payment_id) WHERE status IN ('PENDING', 'ESCALATED') DO NOTHING
+		RETURNING id, created_at, updated_at`,
+		c.OrderID, c.Kind, c.VariantID, c.PaymentID, c.Amount, c.Reason, c.Priority,
+		c.Status, c.Attempts, c.NextAttemptAt.UTC(), c.LastError)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// ClaimDueCompensations claims up to limit pending compensations that are
+// due, highest priority first, by moving them claimTTL into the future, so
+// that a retrier that dies holding a claim hands it over once it expires
+func (s *Store) ClaimDueCompensations(ctx context.Context, now time.Time, claimTTL time.Duration, limit int) ([]models.Compensation, error) {
+	var compensations []models.Compensation
+	err := s.selectAll(ctx, "claim_due_compensations", &compensations,
+		`UPDATE compensations SET next_attempt_at = $1, updated_at = NOW()
+		WHERE id IN (
+			SELECT id FROM compensations
+			WHERE status = $2 AND next_attempt_at <= $3
+			ORDER BY priority, next_attempt_at
+			LIMIT $4
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *`,
+		now.Add(claimTTL).UTC(), models.CompensationPending, now.UTC(), limit)
+	return compensations, err
+}
+
+// UpdateCompensation records the outcome of an attempt or a resolution of a
+// compensation
+func (s *Store) UpdateCompensation(ctx context.Context, c *models.Compensation) error {
+	_, err := s.exec(ctx, "update_compensation",
+		`UPDATE compensations
+		SET status = $2, attempts = $3, next_attempt_at = $4, last_error = $5, resolved_by = $6, resolution = $7, updated_at = NOW()
+		WHERE id = $1`,
+		c.ID, c.Status, c.Attempts, c.NextAttemptAt.UTC(), c.LastError, c.ResolvedBy, c.Resolution)
+	return err
+}
+
+// GetCompensation retrieves a compensation by ID
+func (s *Store) GetCompensation(ctx context.Context, id int64) (*models.Compensation, error) {
+	var c models.Compensation
+	err := s.get(ctx, "get_compensation", &c, "SELECT * FROM compensations WHERE id = $1", id)
+	if err == sql.ErrNoRows {
+		return nil, apperrors.New(apperrors.ErrNotFound, "compensation %d not found", id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// ListCompensations returns the compensations matching filter, most urgent first
+func (s *Store) ListCompensations(ctx context.Context, filter models.CompensationFilter) ([]models.Compensation, error) {
+	compensations := []models.Compensation{}
+	err := s.selectWithFailover(ctx, "list_compensations", &compensations,
+		`SELECT * FROM compensations
+		WHERE ($1 = '' OR status = $1) AND ($2 = 0 OR order_id = $2)
+		ORDER BY priority, created_at
+		LIMIT $3`,
+		filter.Status, filter.OrderID, filter.Limit)
+	return compensations, err
+}
+
+// CountCompensations counts the compensations in a status
+func (s *Store) CountCompensations(ctx context.Context, status string) (int, error) {
+	var count int
+	err := s.get(ctx, "count_compensations", &count,
+		"SELECT COUNT(*) FROM compensations WHERE status = $1", status)
+	return count, err
+}
@@ -0,0 +1,71 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"order-service/internal/models"
+	"order-service/internal/tenant"
+)
+
+// UpsertCustomer creates or updates a customer of the tenant of ctx from an
+// identity event.
+// Returns false without changing it if the customer was updated from a newer
+// event, or was closed, since closed accounts are not reopened.
+func (s *Store) UpsertCustomer(ctx context.Context, c *models.Customer) (bool, error) {
+	contact := []string{c.Email, c.Name, c.Phone, c.WebhookURL}
+	for i := range contact {
+		sealed, err := s.seal(ctx, contact[i])
+		if err != nil {
+			return false, err
+		}
+		contact[i] = sealed
+	}
+
+	res, err := s.exec(ctx, "upsert_customer",
+		`INSERT INTO customers (user_id, email, name, status, phone, webhook_url, source_updated_at, tenant_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (tenant_id, user_id) DO UPDATE
+		SET email = EXCLUDED.email, name = EXCLUDED.name, status = EXCLUDED.status,
+			phone = EXCLUDED.phone, webhook_url = EXCLUDED.webhook_url,
+			source_updated_at = EXCLUDED.source_updated_at, updated_at = NOW()
+		WHERE customers.source_updated_at < EXCLUDED.source_updated_at AND customers.status <> 'CLOSED'`,
+		c.UserID, contact[0], contact[1], c.Status, contact[2], contact[3], c.SourceUpdatedAt, tenant.Owner(ctx))
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+// GetCustomer retrieves a customer of the tenant of ctx, or nil if the user
+// was never seen
+func (s *Store) GetCustomer(ctx context.Context, userID int64) (*models.Customer, error) {
+	var customer models.Customer
+	err := s.getWithFailover(ctx, "get_customer", &customer,
+		"SELECT * FROM customers WHERE tenant_id = $1 AND user_id = $2", tenant.Owner(ctx), userID)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := s.open(ctx, &customer.Email, &customer.Name, &customer.Phone, &customer.WebhookURL); err != nil {
+		return nil, err
+	}
+	return &customer, nil
+}
+
+// CloseCustomer marks a customer closed as of at and erases their contact
+// details. The row is kept so later events of the account cannot reopen it.
+func (s *Store) CloseCustomer(ctx context.Context, userID int64, at time.Time) error {
+	_, err := s.exec(ctx, "close_customer",
+		`INSERT INTO customers (user_id, email, name, status, source_updated_at, tenant_id)
+		VALUES ($1, '', '', $2, $3, $4)
+		ON CONFLICT (tenant_id, user_id) DO UPDATE
+		SET email = '', name = '', phone = '', webhook_url = '', status = EXCLUDED.status,
+			source_updated_at = GREATEST(customers.source_updated_at, EXCLUDED.source_updated_at), updated_at = NOW()`,
+		userID, models.CustomerClosed, at, tenant.Owner(ctx))
+	return err
+}
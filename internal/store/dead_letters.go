@@ -0,0 +1,129 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"order-service/internal/apperrors"
+	"order-service/internal/models"
+
+	"github.com/lib/pq"
+)
+
+// CreateDeadLetter parks a message whose handler failed
+func (s *Store) CreateDeadLetter(ctx context.Context, letter *models.DeadLetter) error {
+	query := `
+		INSERT INTO dead_letters (consumer_group, topic, message_partition, message_offset, message_key, payload, error,
+			redrive_of, redrive_attempt)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, (SELECT id FROM dead_letters WHERE id = $8), $9)
+		RETURNING id, created_at, redrive_of`
+
+	return s.get(ctx, "create_dead_letter", letter, query,
+		letter.ConsumerGroup, letter.Topic, letter.Partition, letter.Offset,
+		letter.Key, letter.Payload, letter.Error, letter.RedriveOf, letter.RedriveAttempt)
+}
+
+// ListDeadLetters returns the parked messages matching filter, newest first
+func (s *Store) ListDeadLetters(ctx context.Context, filter models.DeadLetterFilter) ([]models.DeadLetter, error) {
+	until := filter.Until
+	if until.IsZero() {
+		until = time.Now()
+	}
+
+	var ids interface{}
+	if len(filter.IDs) > 0 {
+		ids = pq.Array(filter.IDs)
+	}
+
+	letters := []models.DeadLetter{}
+	err := s.selectWithFailover(ctx, "list_dead_letters", &letters,
+		`SELECT * FROM dead_letters
+		WHERE ($1::bigint[] IS NULL OR id = ANY($1))
+			AND ($2 = '' OR consumer_group = $2)
+			AND ($3 = '' OR topic = $3)
+			AND ($4 = '' OR strpos(error, $4) > 0)
+			AND created_at >= $5 AND created_at < $6
+			AND (NOT $7 OR redriven_at IS NULL)
+		ORDER BY id DESC
+		LIMIT $8`,
+		ids, filter.ConsumerGroup, filter.Topic, filter.Error,
+		filter.Since.UTC(), until.UTC(), filter.Pending, filter.Limit)
+	return letters, err
+}
+
+// ClaimDeadLetterRedrive marks a dead letter re-driven and records the
+// re-drive. Returns false if the letter was already re-driven.
+func (s *Store) ClaimDeadLetterRedrive(ctx context.Context, redrive *models.DeadLetterRedrive) (bool, error) {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	res, err := s.execTx(ctx, tx, "claim_dead_letter",
+		"UPDATE dead_letters SET redriven_at = NOW() WHERE id = $1 AND redriven_at IS NULL",
+		redrive.DeadLetterID)
+	if err != nil {
+		return false, fmt.Errorf("failed to claim dead letter: %w", err)
+	}
+	if rows, err := res.RowsAffected(); err != nil || rows == 0 {
+		return false, err
+	}
+
+	err = s.getTx(ctx, tx, "insert_dead_letter_redrive", redrive,
+		`INSERT INTO dead_letter_redrives (dead_letter_id, attempt, actor, transformed, payload)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING *`,
+		redrive.DeadLetterID, redrive.Attempt, redrive.Actor, redrive.Transformed, redrive.Payload)
+	if err != nil {
+		return false, fmt.Errorf("failed to record re-drive: %w", err)
+	}
+
+	return true, tx.Commit()
+}
+
+// ReleaseDeadLetterRedrive undoes a claimed re-drive whose message could not
+// be published, so the letter can be re-driven again
+func (s *Store) ReleaseDeadLetterRedrive(ctx context.Context, redrive *models.DeadLetterRedrive) error {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := s.execTx(ctx, tx, "delete_dead_letter_redrive", "DELETE FROM dead_letter_redrives WHERE id = $1", redrive.ID); err != nil {
+		return err
+	}
+	if _, err := s.execTx(ctx, tx, "release_dead_letter",
+		"UPDATE dead_letters SET redriven_at = NULL WHERE id = $1", redrive.DeadLetterID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetDeadLetterRedrives returns the re-drives of a dead letter and of the
+// letters it was re-driven from, oldest first
+func (s *Store) GetDeadLetterRedrives(ctx context.Context, deadLetterID int64) ([]models.DeadLetterRedrive, error) {
+	var exists bool
+	err := s.getWithFailover(ctx, "get_dead_letter", &exists,
+		"SELECT EXISTS (SELECT 1 FROM dead_letters WHERE id = $1)", deadLetterID)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, apperrors.New(apperrors.ErrNotFound, "dead letter %d not found", deadLetterID)
+	}
+
+	redrives := []models.DeadLetterRedrive{}
+	err = s.selectWithFailover(ctx, "get_dead_letter_redrives", &redrives,
+		`WITH RECURSIVE chain AS (
+			SELECT id, redrive_of FROM dead_letters WHERE id = $1
+			UNION
+			SELECT d.id, d.redrive_of FROM dead_letters d JOIN chain c ON d.id = c.redrive_of
+		)
+		SELECT r.* FROM dead_letter_redrives r JOIN chain c ON r.dead_letter_id = c.id
+		ORDER BY r.id`,
+		deadLetterID)
+	return redrives, err
+}
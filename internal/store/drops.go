@@ -0,0 +1,160 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"order-service/internal/apperrors"
+	"order-service/internal/models"
+	"order-service/internal/tenant"
+)
+
+// CreateDrop schedules a product drop of the tenant of ctx
+func (s *Store) CreateDrop(ctx context.Context, drop *models.Drop) error {
+	return s.get(ctx, "create_drop", drop,
+		`INSERT INTO drops (product_id, variant_id, name, starts_at, policy, max_quantity_per_user, tenant_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING *`,
+		drop.ProductID, drop.VariantID, drop.Name, drop.StartsAt.UTC(), drop.Policy, drop.MaxQuantityPerUser, tenant.Owner(ctx))
+}
+
+// GetDrop retrieves a product drop by ID; another tenant's drop is not found
+func (s *Store) GetDrop(ctx context.Context, id int64) (*models.Drop, error) {
+	var drop models.Drop
+	err := s.getWithFailover(ctx, "get_drop", &drop,
+		"SELECT * FROM drops WHERE id = $1 AND ($2 = '' OR tenant_id = $2)", id, tenantArg(ctx))
+	if err == sql.ErrNoRows {
+		return nil, apperrors.New(apperrors.ErrNotFound, "drop %d not found", id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &drop, nil
+}
+
+// ClaimDueDrop claims the earliest drop that has started, or whose conversion
+// was claimed longer than claimTTL ago, and marks it running. seed is kept as
+// the drop's random seed unless it already has one. Returns nil if no drop is due.
+func (s *Store) ClaimDueDrop(ctx context.Context, now time.Time, claimTTL time.Duration, seed int64) (*models.Drop, error) {
+	var drop models.Drop
+	err := s.get(ctx, "claim_due_drop", &drop,
+		`UPDATE drops SET status = $1, claimed_at = $2, seed = COALESCE(seed, $3)
+		WHERE id = (
+			SELECT id FROM drops
+			WHERE (status = $4 AND starts_at <= $2) OR (status = $1 AND claimed_at < $5)
+			ORDER BY starts_at
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *`,
+		models.DropStatusRunning, now.UTC(), seed, models.DropStatusScheduled, now.Add(-claimTTL).UTC())
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &drop, nil
+}
+
+// RenewDropClaim extends the claim on a running drop
+func (s *Store) RenewDropClaim(ctx context.Context, id int64, now time.Time) error {
+	_, err := s.exec(ctx, "renew_drop_claim",
+		"UPDATE drops SET claimed_at = $1 WHERE id = $2 AND status = $3",
+		now.UTC(), id, models.DropStatusRunning)
+	return err
+}
+
+// CompleteDrop marks a drop whose registrations were all converted completed
+func (s *Store) CompleteDrop(ctx context.Context, id int64) error {
+	return s.withRetry(ctx, "complete_drop", func() error {
+		_, err := s.exec(ctx, "complete_drop",
+			"UPDATE drops SET status = $1, completed_at = NOW() WHERE id = $2",
+			models.DropStatusCompleted, id)
+		return err
+	})
+}
+
+// RegisterForDrop registers a user for a drop, or updates the user's
+// registration. Returns false if the drop is no longer open for registration
+// or is another tenant's.
+func (s *Store) RegisterForDrop(ctx context.Context, reg *models.DropRegistration) (bool, error) {
+	err := s.get(ctx, "register_for_drop", reg,
+		`INSERT INTO drop_registrations (drop_id, user_id, quantity, payment_method, tenant_id)
+		SELECT id, $2, $3, $4, tenant_id FROM drops
+		WHERE id = $1 AND status = $5 AND starts_at > NOW() AND ($6 = '' OR tenant_id = $6)
+		ON CONFLICT (drop_id, user_id) DO UPDATE
+			SET quantity = EXCLUDED.quantity, payment_method = EXCLUDED.payment_method
+		RETURNING *`,
+		reg.DropID, reg.UserID, reg.Quantity, reg.PaymentMethod, models.DropStatusScheduled, tenantArg(ctx))
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// GetDropRegistration retrieves a user's registration for a drop of the
+// tenant of ctx
+func (s *Store) GetDropRegistration(ctx context.Context, dropID, userID int64) (*models.DropRegistration, error) {
+	var reg models.DropRegistration
+	err := s.getWithFailover(ctx, "get_drop_registration", &reg,
+		"SELECT * FROM drop_registrations WHERE drop_id = $1 AND user_id = $2 AND ($3 = '' OR tenant_id = $3)",
+		dropID, userID, tenantArg(ctx))
+	if err == sql.ErrNoRows {
+		return nil, apperrors.New(apperrors.ErrNotFound, "user %d is not registered for drop %d", userID, dropID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &reg, nil
+}
+
+// GetPendingDropRegistrations retrieves the registrations of a drop not yet
+// converted, in registration order
+func (s *Store) GetPendingDropRegistrations(ctx context.Context, dropID int64) ([]models.DropRegistration, error) {
+	regs := []models.DropRegistration{}
+	err := s.selectWithFailover(ctx, "get_pending_drop_registrations", &regs,
+		"SELECT * FROM drop_registrations WHERE drop_id = $1 AND status = $2 ORDER BY id",
+		dropID, models.DropRegistrationRegistered)
+	return regs, err
+}
+
+// CountDropRegistrations counts the registrations of a drop by status
+func (s *Store) CountDropRegistrations(ctx context.Context, dropID int64) (map[string]int, error) {
+	var rows []struct {
+		Status string `db:"status"`
+		Count  int    `db:"count"`
+	}
+	err := s.selectWithFailover(ctx, "count_drop_registrations", &rows,
+		"SELECT status, COUNT(*) AS count FROM drop_registrations WHERE drop_id = $1 GROUP BY status",
+		dropID)
+	if err != nil {
+		return nil, err
+	}
+
+	counts := map[string]int{
+		models.DropRegistrationRegistered: 0,
+		models.DropRegistrationOrdered:    0,
+		models.DropRegistrationSoldOut:    0,
+		models.DropRegistrationFailed:     0,
+	}
+	for _, row := range rows {
+		counts[row.Status] = row.Count
+	}
+	return counts, nil
+}
+
+// FinishDropRegistration records the outcome of converting a registration
+func (s *Store) FinishDropRegistration(ctx context.Context, reg *models.DropRegistration) error {
+	return s.withRetry(ctx, "finish_drop_registration", func() error {
+		_, err := s.exec(ctx, "finish_drop_registration",
+			`UPDATE drop_registrations SET status = $1, order_id = $2, error = $3, processed_at = NOW()
+			WHERE id = $4 AND status = $5`,
+			reg.Status, reg.OrderID, reg.Error, reg.ID, models.DropRegistrationRegistered)
+		return err
+	})
+}
@@ -0,0 +1,158 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"reflect"
+	"time"
+
+	"order-service/internal/resilience/retry"
+	"order-service/internal/util"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// Postgres errors returned while a primary is shut down or promoted
+const (
+	pgConnectionExceptionClass = pq.ErrorClass("08")
+	pgAdminShutdown            = "57P01"
+	pgCrashShutdown            = "57P02"
+	pgCannotConnectNow         = "57P03"
+	pgReadOnlyTransaction      = "25006"
+)
+
+const (
+	defaultReadAttempts       = 4
+	defaultPoolResetThreshold = 5
+	readRetryBaseDelay        = 100 * time.Millisecond
+	readRetryMaxDelay         = 2 * time.Second
+	poolResetCooldown         = 10 * time.Second
+)
+
+// isConnectionError reports whether err means the connection, not the query,
+// is broken, e.g. it still points at a demoted or restarted primary
+func isConnectionError(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, sql.ErrConnDone) ||
+		errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code {
+		case pgAdminShutdown, pgCrashShutdown, pgCannotConnectNow, pgReadOnlyTransaction:
+			return true
+		}
+		return pqErr.Code.Class() == pgConnectionExceptionClass
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// SetFailoverPolicy sets how often idempotent reads are attempted on
+// connection errors and how many consecutive connection errors reset the pool
+func (s *Store) SetFailoverPolicy(readAttempts, poolResetThreshold int) {
+	if readAttempts > 0 {
+		s.readAttempts = readAttempts
+	}
+	if poolResetThreshold > 0 {
+		s.poolResetThreshold = poolResetThreshold
+	}
+}
+
+// withReadRetry runs an idempotent read, retrying on connection errors so
+// reads ride out a failover instead of failing on stale connections
+func (s *Store) withReadRetry(ctx context.Context, operation string, fn func() error) error {
+	if txFrom(ctx) != nil {
+		return fn()
+	}
+	policy := retry.Policy{
+		MaxAttempts: s.readAttempts,
+		BaseDelay:   readRetryBaseDelay,
+		MaxDelay:    readRetryMaxDelay,
+		Jitter:      0.5,
+		Retryable:   isConnectionError,
+		OnRetry: func(int, error) {
+			util.StoreReadRetriesTotal.WithLabelValues(operation).Inc()
+		},
+	}
+
+	return retry.Do(ctx, policy, func(context.Context) error {
+		err := fn()
+		s.observe(err)
+		return err
+	})
+}
+
+// observe tracks consecutive connection errors and resets the pool once they
+// reach the threshold. Any answer from the server clears the count.
+func (s *Store) observe(err error) {
+	if !isConnectionError(err) {
+		s.connErrors.Store(0)
+		return
+	}
+
+	util.DBConnectionErrorsTotal.Inc()
+	if s.poolResetThreshold > 0 && int(s.connErrors.Add(1)) >= s.poolResetThreshold {
+		s.resetPool()
+	}
+}
+
+// resetPool drops idle connections so the next queries dial the current
+// primary. Connections in use are discarded by database/sql when they fail.
+func (s *Store) resetPool() {
+	now := time.Now().UnixNano()
+	last := s.lastPoolReset.Load()
+	if now-last < int64(poolResetCooldown) || !s.lastPoolReset.CompareAndSwap(last, now) {
+		return
+	}
+
+	s.connErrors.Store(0)
+	util.DBPoolResetsTotal.Inc()
+	log.Printf("Resetting database connection pool after repeated connection errors")
+
+	if s.db != nil {
+		s.db.SetMaxIdleConns(0)
+		s.db.SetMaxIdleConns(maxIdleConns)
+	}
+}
+
+// getWithFailover is GetContext for idempotent reads, retried on connection
+// errors. Reads made with WithReplicaReads try a replica first.
+func (s *Store) getWithFailover(ctx context.Context, operation string, dest interface{}, query string, args ...interface{}) error {
+	if ok, err := s.readFromReplica(ctx, operation, func(db *sqlx.DB) error {
+		return s.getOn(ctx, db, operation, dest, query, args...)
+	}); ok || err != nil {
+		return err
+	}
+	return s.withReadRetry(ctx, operation, func() error {
+		return s.get(ctx, operation, dest, query, args...)
+	})
+}
+
+// selectWithFailover is SelectContext for idempotent reads, retried on
+// connection errors. Reads made with WithReplicaReads try a replica first.
+// Rows scanned by a failed attempt are discarded.
+func (s *Store) selectWithFailover(ctx context.Context, operation string, dest interface{}, query string, args ...interface{}) error {
+	rows := reflect.ValueOf(dest).Elem()
+	if ok, err := s.readFromReplica(ctx, operation, func(db *sqlx.DB) error {
+		rows.SetLen(0)
+		return s.selectOn(ctx, db, operation, dest, query, args...)
+	}); ok || err != nil {
+		return err
+	}
+	return s.withReadRetry(ctx, operation, func() error {
+		rows.SetLen(0)
+		return s.selectAll(ctx, operation, dest, query, args...)
+	})
+}
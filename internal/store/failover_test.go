@@ -0,0 +1,113 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsConnectionError(t *testing.T) {
+	assert.True(t, isConnectionError(driver.ErrBadConn))
+	assert.True(t, isConnectionError(&pq.Error{Code: pgAdminShutdown}))
+	assert.True(t, isConnectionError(&pq.Error{Code: pgReadOnlyTransaction}))
+	assert.True(t, isConnectionError(&pq.Error{Code: "08006"}))
+
+	assert.False(t, isConnectionError(nil))
+	assert.False(t, isConnectionError(sql.ErrNoRows))
+	assert.False(t, isConnectionError(context.DeadlineExceeded))
+	assert.False(t, isConnectionError(&pq.Error{Code: pgSerializationFailure}))
+}
+
+func TestWithReadRetryRetriesConnectionErrors(t *testing.T) {
+	s := &Store{readAttempts: 3}
+
+	calls := 0
+	err := s.withReadRetry(context.Background(), "test", func() error {
+		calls++
+		if calls < 2 {
+			return driver.ErrBadConn
+		}
+		return nil
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, calls)
+}
+
+func TestWithReadRetryDoesNotRetryQueryErrors(t *testing.T) {
+	s := &Store{readAttempts: 3}
+
+	calls := 0
+	err := s.withReadRetry(context.Background(), "test", func() error {
+		calls++
+		return sql.ErrNoRows
+	})
+
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+	assert.Equal(t, 1, calls)
+}
+
+func TestRepeatedConnectionErrorsResetPool(t *testing.T) {
+	s := &Store{poolResetThreshold: 3}
+
+	s.observe(driver.ErrBadConn)
+	s.observe(driver.ErrBadConn)
+	assert.Zero(t, s.lastPoolReset.Load())
+
+	s.observe(driver.ErrBadConn)
+	assert.NotZero(t, s.lastPoolReset.Load())
+	assert.Zero(t, s.connErrors.Load())
+}
+
+func TestServerResponseClearsConnectionErrorCount(t *testing.T) {
+	s := &Store{poolResetThreshold: 3}
+
+	s.observe(driver.ErrBadConn)
+	s.observe(driver.ErrBadConn)
+	s.observe(errors.New("syntax error"))
+	s.observe(driver.ErrBadConn)
+
+	assert.Zero(t, s.lastPoolReset.Load())
+	assert.Equal(t, int32(1), s.connErrors.Load())
+}
+
+func TestReadFromReplicaFallsBackForTheRestOfTheRequest(t *testing.T) {
+	replicas := []*sqlx.DB{sqlx.NewDb(nil, "postgres"), sqlx.NewDb(nil, "postgres")}
+	s := &Store{replicas: replicas}
+
+	served, err := s.readFromReplica(context.Background(), "test", func(*sqlx.DB) error {
+		t.Fatal("read without WithReplicaReads went to a replica")
+		return nil
+	})
+	assert.False(t, served)
+	assert.NoError(t, err)
+
+	ctx := WithReplicaReads(context.Background())
+	var used []*sqlx.DB
+	for i := 0; i < 2; i++ {
+		served, err = s.readFromReplica(ctx, "test", func(db *sqlx.DB) error {
+			used = append(used, db)
+			return nil
+		})
+		assert.True(t, served)
+		assert.NoError(t, err)
+	}
+	assert.ElementsMatch(t, replicas, used)
+
+	// A row missing on the replica may not have replicated yet
+	served, err = s.readFromReplica(ctx, "test", func(*sqlx.DB) error { return sql.ErrNoRows })
+	assert.False(t, served)
+	assert.NoError(t, err)
+
+	served, _ = s.readFromReplica(ctx, "test", func(*sqlx.DB) error {
+		t.Fatal("read after a fallback went to a replica")
+		return nil
+	})
+	assert.False(t, served)
+}
@@ -0,0 +1,203 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"order-service/internal/apperrors"
+	"order-service/internal/models"
+)
+
+// selectIncomingStock selects incoming stock with the quantity of backordered
+// items of live orders planned against it
+const selectIncomingStock = `SELECT inc.*, COALESCE((
+		SELECT SUM(oi.quantity) FROM order_items oi
+		JOIN orders o ON o.id = oi.order_id
+		WHERE oi.incoming_stock_id = inc.id AND oi.fulfillment_status = 'BACKORDERED'
+			AND o.status IN ('RESERVED', 'PAID', 'CONFIRMED')
+	), 0) AS allocated
+	FROM incoming_stock inc`
+
+// CreateIncomingStock records stock expected from a supplier
+func (s *Store) CreateIncomingStock(ctx context.Context, incoming *models.IncomingStock) error {
+	return s.get(ctx, "create_incoming_stock", incoming,
+		`INSERT INTO incoming_stock (variant_id, reference, quantity, eta)
+		VALUES ($1, $2, $3, $4)
+		RETURNING *, 0 AS allocated`,
+		incoming.VariantID, incoming.Reference, incoming.Quantity, incoming.ETA.UTC())
+}
+
+// GetIncomingStock retrieves incoming stock by ID
+func (s *Store) GetIncomingStock(ctx context.Context, id int64) (*models.IncomingStock, error) {
+	var incoming models.IncomingStock
+	err := s.getWithFailover(ctx, "get_incoming_stock", &incoming, selectIncomingStock+" WHERE inc.id = $1", id)
+	if err == sql.ErrNoRows {
+		return nil, apperrors.New(apperrors.ErrNotFound, "incoming stock %d not found", id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &incoming, nil
+}
+
+// GetOpenIncomingStock retrieves the incoming stock of a variant not yet
+// received, earliest ETA first
+func (s *Store) GetOpenIncomingStock(ctx context.Context, variantID int64) ([]models.IncomingStock, error) {
+	incoming := []models.IncomingStock{}
+	err := s.selectWithFailover(ctx, "get_open_incoming_stock", &incoming,
+		selectIncomingStock+" WHERE inc.variant_id = $1 AND inc.status = $2 ORDER BY inc.eta, inc.id",
+		variantID, models.IncomingStockOpen)
+	return incoming, err
+}
+
+// CountBackorderedUnits counts the backordered units of a variant in live orders
+func (s *Store) CountBackorderedUnits(ctx context.Context, variantID int64) (int, error) {
+	var units int
+	err := s.getWithFailover(ctx, "count_backordered_units", &units,
+		`SELECT COALESCE(SUM(oi.quantity), 0) FROM order_items oi
+		JOIN orders o ON o.id = oi.order_id
+		WHERE oi.variant_id = $1 AND oi.fulfillment_status = $2 AND o.status IN ($3, $4, $5)`,
+		variantID, models.FulfillmentStatusBackordered,
+		models.OrderStatusReserved, models.OrderStatusPaid, models.OrderStatusConfirmed)
+	return units, err
+}
+
+// UpdateIncomingStock changes the quantity, ETA and status of incoming stock
+// not yet received. Returns false if it is no longer open.
+func (s *Store) UpdateIncomingStock(ctx context.Context, incoming *models.IncomingStock) (bool, error) {
+	res, err := s.exec(ctx, "update_incoming_stock",
+		`UPDATE incoming_stock SET quantity = $1, eta = $2, status = $3, updated_at = NOW()
+		WHERE id = $4 AND status = $5`,
+		incoming.Quantity, incoming.ETA.UTC(), incoming.Status, incoming.ID, models.IncomingStockOpen)
+	if err != nil {
+		return false, err
+	}
+	rows, err := res.RowsAffected()
+	return rows > 0, err
+}
+
+// ReceiveIncomingStock marks open incoming stock received and adds it to the
+// variant's available stock in the default warehouse, returning that stock.
+// Returns nil if it is no longer open.
+func (s *Store) ReceiveIncomingStock(ctx context.Context, id int64) (*models.Inventory, error) {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var incoming models.IncomingStock
+	err = s.getTx(ctx, tx, "receive_incoming_stock", &incoming,
+		`UPDATE incoming_stock SET status = $1, updated_at = NOW()
+		WHERE id = $2 AND status = $3
+		RETURNING *, 0 AS allocated`,
+		models.IncomingStockReceived, id, models.IncomingStockOpen)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var inventory models.Inventory
+	err = s.getTx(ctx, tx, "restock_received_inventory", &inventory,
+		`INSERT INTO inventory (product_id, variant_id, warehouse_id, available)
+		SELECT product_id, id, $3, $1 FROM product_variants WHERE id = $2
+		ON CONFLICT (variant_id, warehouse_id) DO UPDATE
+		SET available = inventory.available + EXCLUDED.available, updated_at = NOW()
+		RETURNING *`,
+		incoming.Quantity, incoming.VariantID, models.DefaultWarehouseID)
+	if err != nil {
+		return nil, err
+	}
+	return &inventory, tx.Commit()
+}
+
+// PlanIncomingStock plans the backordered items of a variant against its
+// open incoming stock and returns the items whose plan changed. Items planned
+// against stock already received keep their plan.
+func (s *Store) PlanIncomingStock(ctx context.Context, variantID int64) ([]models.OrderItem, error) {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Locking the open stock serializes plans of the variant
+	var incoming []models.IncomingStock
+	err = s.selectTx(ctx, tx, "lock_open_incoming_stock", &incoming,
+		`SELECT *, 0 AS allocated FROM incoming_stock
+		WHERE variant_id = $1 AND status = $2
+		ORDER BY eta, id
+		FOR UPDATE`,
+		variantID, models.IncomingStockOpen)
+	if err != nil {
+		return nil, err
+	}
+
+	var items []models.OrderItem
+	err = s.selectTx(ctx, tx, "select_backordered_items", &items,
+		`SELECT oi.* FROM order_items oi
+		JOIN orders o ON o.id = oi.order_id
+		WHERE oi.variant_id = $1 AND oi.fulfillment_status = $2 AND o.status IN ($3, $4, $5)
+			AND NOT EXISTS (
+				SELECT 1 FROM incoming_stock r WHERE r.id = oi.incoming_stock_id AND r.status = $6
+			)
+		ORDER BY oi.id`,
+		variantID, models.FulfillmentStatusBackordered,
+		models.OrderStatusReserved, models.OrderStatusPaid, models.OrderStatusConfirmed,
+		models.IncomingStockReceived)
+	if err != nil {
+		return nil, err
+	}
+
+	changed := planBackorders(incoming, items)
+	for _, item := range changed {
+		_, err := s.execTx(ctx, tx, "plan_backordered_item",
+			`UPDATE order_items SET incoming_stock_id = $1, expected_ship_date = $2
+			WHERE id = $3 AND fulfillment_status = $4`,
+			item.IncomingStockID, item.ExpectedShipDate, item.ID, models.FulfillmentStatusBackordered)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return changed, tx.Commit()
+}
+
+// planBackorders plans items, oldest first, against incoming stock, earliest
+// first. Stock accumulates as it arrives, and an item ships with the arrival
+// that covers it together with every older item, the order the backorder
+// fulfiller reserves stock in. Items the stock does not cover are unplanned.
+// Returns the items whose plan changed.
+func planBackorders(incoming []models.IncomingStock, items []models.OrderItem) []models.OrderItem {
+	var changed []models.OrderItem
+	supply, demand, next := 0, 0, 0
+	for _, item := range items {
+		demand += item.Quantity
+		for supply < demand && next < len(incoming) {
+			supply += incoming[next].Quantity
+			next++
+		}
+
+		var stockID *int64
+		var shipDate *time.Time
+		if supply >= demand {
+			id, eta := incoming[next-1].ID, incoming[next-1].ETA
+			stockID, shipDate = &id, &eta
+		}
+		if !sameInt64(item.IncomingStockID, stockID) || !sameDate(item.ExpectedShipDate, shipDate) {
+			item.IncomingStockID, item.ExpectedShipDate = stockID, shipDate
+			changed = append(changed, item)
+		}
+	}
+	return changed
+}
+
+func sameInt64(a, b *int64) bool {
+	return a == b || (a != nil && b != nil && *a == *b)
+}
+
+func sameDate(a, b *time.Time) bool {
+	return a == b || (a != nil && b != nil && a.Equal(*b))
+}
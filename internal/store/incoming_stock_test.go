@@ -0,0 +1,50 @@
+package store
+
+import (
+	"testing"
+	"time"
+
+	"order-service/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlanBackordersShipsItemsWithTheArrivalCoveringThem(t *testing.T) {
+	june, july := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC), time.Date(2026, 7, 1, 0, 0, 0, 0, time.UTC)
+	incoming := []models.IncomingStock{
+		{ID: 1, Quantity: 5, ETA: june},
+		{ID: 2, Quantity: 10, ETA: july},
+	}
+	stale := int64(9)
+	items := []models.OrderItem{
+		{ID: 10, Quantity: 3},
+		// Too big for what is left in June, so it waits for July...
+		{ID: 11, Quantity: 4, IncomingStockID: &stale, ExpectedShipDate: &june},
+		// ...and so does every later item, as stock is reserved oldest first
+		{ID: 12, Quantity: 1},
+		// Nothing covers it, as before
+		{ID: 13, Quantity: 10},
+	}
+
+	changed := planBackorders(incoming, items)
+	require.Len(t, changed, 3)
+	assert.Equal(t, int64(1), *changed[0].IncomingStockID)
+	assert.Equal(t, june, *changed[0].ExpectedShipDate)
+	assert.Equal(t, int64(2), *changed[1].IncomingStockID)
+	assert.Equal(t, july, *changed[1].ExpectedShipDate)
+	assert.Equal(t, july, *changed[2].ExpectedShipDate)
+
+	// Planning again changes nothing
+	copy(items, changed)
+	assert.Empty(t, planBackorders(incoming, items))
+
+	// A slipped arrival moves the items shipping with it to the next one
+	incoming[0].ETA = july.AddDate(0, 0, 7)
+	incoming[0], incoming[1] = incoming[1], incoming[0]
+	changed = planBackorders(incoming, items)
+	require.Len(t, changed, 1)
+	assert.Equal(t, int64(10), changed[0].ID)
+	assert.Equal(t, int64(2), *changed[0].IncomingStockID)
+	assert.Equal(t, july, *changed[0].ExpectedShipDate)
+}
@@ -0,0 +1,133 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"order-service/internal/models"
+	"order-service/internal/tenant"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// ImportInventoryRows upserts the products, variants and stock of rows in one
+// transaction. A row that fails is rolled back on its own and its error is
+// returned at its index in rowErrs; the other rows are kept.
+func (s *Store) ImportInventoryRows(ctx context.Context, rows []models.InventoryImportRow) ([]models.Inventory, []error, error) {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback()
+
+	inventories := make([]models.Inventory, 0, len(rows))
+	rowErrs := make([]error, len(rows))
+	for i, row := range rows {
+		if _, err := tx.ExecContext(ctx, "SAVEPOINT import_row"); err != nil {
+			return nil, nil, err
+		}
+
+		inv, err := s.importInventoryRow(ctx, tx, row)
+		if err != nil {
+			if _, rbErr := tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT import_row"); rbErr != nil {
+				return nil, nil, rbErr
+			}
+			rowErrs[i] = err
+			continue
+		}
+
+		if _, err := tx.ExecContext(ctx, "RELEASE SAVEPOINT import_row"); err != nil {
+			return nil, nil, err
+		}
+		inventories = append(inventories, *inv)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, nil, err
+	}
+	return inventories, rowErrs, nil
+}
+
+// importInventoryRow upserts one row's product, its default variant, the
+// row's variant and the variant's available stock in the row's warehouse
+func (s *Store) importInventoryRow(ctx context.Context, tx *sqlx.Tx, row models.InventoryImportRow) (*models.Inventory, error) {
+	var productID int64
+	err := s.getTx(ctx, tx, "upsert_product", &productID,
+		`INSERT INTO products (sku, name, price, tenant_id) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (sku) DO UPDATE SET name = EXCLUDED.name, price = EXCLUDED.price
+		WHERE products.tenant_id = EXCLUDED.tenant_id
+		RETURNING id`,
+		row.SKU, row.Name, row.Price, tenant.Owner(ctx))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("SKU %s belongs to another tenant", row.SKU)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to upsert product: %w", err)
+	}
+
+	// The default variant shares the product's SKU; the no-op update returns
+	// it when it already exists
+	variantID, err := s.upsertVariant(ctx, tx,
+		`INSERT INTO product_variants (product_id, sku, is_default) VALUES ($1, $2, TRUE)
+		ON CONFLICT (sku) DO UPDATE SET is_default = product_variants.is_default
+		WHERE product_variants.product_id = EXCLUDED.product_id
+		RETURNING id`,
+		row.SKU, productID, row.SKU)
+	if err != nil {
+		return nil, err
+	}
+
+	if row.VariantSKU != "" && row.VariantSKU != row.SKU {
+		var attributes interface{}
+		if len(row.Attributes) > 0 {
+			attributes = string(row.Attributes)
+		}
+		variantID, err = s.upsertVariant(ctx, tx,
+			`INSERT INTO product_variants (product_id, sku, attributes) VALUES ($1, $2, COALESCE($3::jsonb, '{}'))
+			ON CONFLICT (sku) DO UPDATE SET attributes = COALESCE($3::jsonb, product_variants.attributes)
+			WHERE product_variants.product_id = EXCLUDED.product_id
+			RETURNING id`,
+			row.VariantSKU, productID, row.VariantSKU, attributes)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	warehouseID := int64(models.DefaultWarehouseID)
+	if row.Warehouse != "" {
+		err = s.getTx(ctx, tx, "get_warehouse_id", &warehouseID, "SELECT id FROM warehouses WHERE code = $1", row.Warehouse)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("unknown warehouse %s", row.Warehouse)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get warehouse %s: %w", row.Warehouse, err)
+		}
+	}
+
+	var inv models.Inventory
+	err = s.getTx(ctx, tx, "upsert_inventory", &inv,
+		`INSERT INTO inventory (product_id, variant_id, warehouse_id, available) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (variant_id, warehouse_id) DO UPDATE SET available = EXCLUDED.available, updated_at = NOW()
+		RETURNING *`,
+		productID, variantID, warehouseID, row.Available)
+	if err != nil {
+		return nil, fmt.Errorf("failed to upsert inventory: %w", err)
+	}
+	return &inv, nil
+}
+
+// upsertVariant runs a variant upsert returning its ID; no row comes back
+// when sku already belongs to a variant of another product
+func (s *Store) upsertVariant(ctx context.Context, tx *sqlx.Tx, query, sku string, args ...interface{}) (int64, error) {
+	var variantID int64
+	err := s.getTx(ctx, tx, "upsert_variant", &variantID, query, args...)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, fmt.Errorf("SKU %s belongs to another product", sku)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to upsert variant %s: %w", sku, err)
+	}
+	return variantID, nil
+}
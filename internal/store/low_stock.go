@@ -0,0 +1,40 @@
+package store
+
+import (
+	"context"
+
+	"order-service/internal/apperrors"
+	"order-service/internal/models"
+)
+
+// GetLowStockItems returns the product variants whose available stock across
+// warehouses is at or below their product's reorder threshold, emptiest first
+func (s *Store) GetLowStockItems(ctx context.Context) ([]models.LowStockItem, error) {
+	items := []models.LowStockItem{}
+	err := s.selectWithFailover(ctx, "get_low_stock_items", &items,
+		`SELECT p.id AS product_id, v.id AS variant_id, p.name, v.sku,
+			COALESCE(SUM(i.available), 0) AS available, COALESCE(SUM(i.reserved), 0) AS reserved,
+			p.reorder_threshold
+		FROM products p
+		JOIN product_variants v ON v.product_id = p.id
+		LEFT JOIN inventory i ON i.variant_id = v.id
+		WHERE p.reorder_threshold IS NOT NULL
+		GROUP BY p.id, v.id
+		HAVING COALESCE(SUM(i.available), 0) <= p.reorder_threshold
+		ORDER BY available, v.id`)
+	return items, err
+}
+
+// SetReorderThreshold sets the reorder threshold of a product, or disables
+// low-stock alerts for it when threshold is nil
+func (s *Store) SetReorderThreshold(ctx context.Context, productID int64, threshold *int) error {
+	res, err := s.exec(ctx, "set_reorder_threshold",
+		"UPDATE products SET reorder_threshold = $2 WHERE id = $1", productID, threshold)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return apperrors.New(apperrors.ErrNotFound, "product %d not found", productID)
+	}
+	return nil
+}
@@ -0,0 +1,190 @@
+// Code generated by mockery v2.53.4. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+	models "order-service/internal/models"
+
+	mock "github.com/stretchr/testify/mock"
+
+	time "time"
+)
+
+// AnonymizationRepository is an autogenerated mock type for the AnonymizationRepository type
+type AnonymizationRepository struct {
+	mock.Mock
+}
+
+// AnonymizeUserOrders provides a mock function with given fields: ctx, userID, retainAfter, limit
+func (_m *AnonymizationRepository) AnonymizeUserOrders(ctx context.Context, userID int64, retainAfter time.Time, limit int) (int, int, error) {
+	ret := _m.Called(ctx, userID, retainAfter, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for AnonymizeUserOrders")
+	}
+
+	var r0 int
+	var r1 int
+	var r2 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, time.Time, int) (int, int, error)); ok {
+		return rf(ctx, userID, retainAfter, limit)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64, time.Time, int) int); ok {
+		r0 = rf(ctx, userID, retainAfter, limit)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64, time.Time, int) int); ok {
+		r1 = rf(ctx, userID, retainAfter, limit)
+	} else {
+		r1 = ret.Get(1).(int)
+	}
+
+	if rf, ok := ret.Get(2).(func(context.Context, int64, time.Time, int) error); ok {
+		r2 = rf(ctx, userID, retainAfter, limit)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// CompleteAnonymizationJob provides a mock function with given fields: ctx, jobID
+func (_m *AnonymizationRepository) CompleteAnonymizationJob(ctx context.Context, jobID int64) error {
+	ret := _m.Called(ctx, jobID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CompleteAnonymizationJob")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) error); ok {
+		r0 = rf(ctx, jobID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// CreateAnonymizationJob provides a mock function with given fields: ctx, eventID, userID
+func (_m *AnonymizationRepository) CreateAnonymizationJob(ctx context.Context, eventID string, userID int64) (*models.AnonymizationJob, error) {
+	ret := _m.Called(ctx, eventID, userID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreateAnonymizationJob")
+	}
+
+	var r0 *models.AnonymizationJob
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, int64) (*models.AnonymizationJob, error)); ok {
+		return rf(ctx, eventID, userID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, int64) *models.AnonymizationJob); ok {
+		r0 = rf(ctx, eventID, userID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.AnonymizationJob)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, int64) error); ok {
+		r1 = rf(ctx, eventID, userID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// IsEventProcessed provides a mock function with given fields: ctx, eventID
+func (_m *AnonymizationRepository) IsEventProcessed(ctx context.Context, eventID string) (bool, error) {
+	ret := _m.Called(ctx, eventID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for IsEventProcessed")
+	}
+
+	var r0 bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (bool, error)); ok {
+		return rf(ctx, eventID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) bool); ok {
+		r0 = rf(ctx, eventID)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, eventID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MarkEventProcessed provides a mock function with given fields: ctx, eventID, eventType
+func (_m *AnonymizationRepository) MarkEventProcessed(ctx context.Context, eventID string, eventType string) error {
+	ret := _m.Called(ctx, eventID, eventType)
+
+	if len(ret) == 0 {
+		panic("no return value specified for MarkEventProcessed")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
+		r0 = rf(ctx, eventID, eventType)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UpdateAnonymizationJobProgress provides a mock function with given fields: ctx, jobID, anonymized, deleted
+func (_m *AnonymizationRepository) UpdateAnonymizationJobProgress(ctx context.Context, jobID int64, anonymized int, deleted int) (*models.AnonymizationJob, error) {
+	ret := _m.Called(ctx, jobID, anonymized, deleted)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateAnonymizationJobProgress")
+	}
+
+	var r0 *models.AnonymizationJob
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int, int) (*models.AnonymizationJob, error)); ok {
+		return rf(ctx, jobID, anonymized, deleted)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int, int) *models.AnonymizationJob); ok {
+		r0 = rf(ctx, jobID, anonymized, deleted)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.AnonymizationJob)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64, int, int) error); ok {
+		r1 = rf(ctx, jobID, anonymized, deleted)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// NewAnonymizationRepository creates a new instance of AnonymizationRepository. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewAnonymizationRepository(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *AnonymizationRepository {
+	mock := &AnonymizationRepository{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,171 @@
+// Code generated by mockery v2.53.4. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+	models "order-service/internal/models"
+
+	mock "github.com/stretchr/testify/mock"
+
+	time "time"
+)
+
+// CartRepository is an autogenerated mock type for the CartRepository type
+type CartRepository struct {
+	mock.Mock
+}
+
+// AddCartItem provides a mock function with given fields: ctx, item, expiresAt
+func (_m *CartRepository) AddCartItem(ctx context.Context, item *models.CartItem, expiresAt time.Time) error {
+	ret := _m.Called(ctx, item, expiresAt)
+
+	if len(ret) == 0 {
+		panic("no return value specified for AddCartItem")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.CartItem, time.Time) error); ok {
+		r0 = rf(ctx, item, expiresAt)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// CheckOutCart provides a mock function with given fields: ctx, cartID, orderID
+func (_m *CartRepository) CheckOutCart(ctx context.Context, cartID string, orderID int64) (bool, error) {
+	ret := _m.Called(ctx, cartID, orderID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CheckOutCart")
+	}
+
+	var r0 bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, int64) (bool, error)); ok {
+		return rf(ctx, cartID, orderID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, int64) bool); ok {
+		r0 = rf(ctx, cartID, orderID)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, int64) error); ok {
+		r1 = rf(ctx, cartID, orderID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CreateCart provides a mock function with given fields: ctx, cart
+func (_m *CartRepository) CreateCart(ctx context.Context, cart *models.Cart) error {
+	ret := _m.Called(ctx, cart)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreateCart")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.Cart) error); ok {
+		r0 = rf(ctx, cart)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DeleteExpiredCarts provides a mock function with given fields: ctx, before, limit
+func (_m *CartRepository) DeleteExpiredCarts(ctx context.Context, before time.Time, limit int) (int64, error) {
+	ret := _m.Called(ctx, before, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteExpiredCarts")
+	}
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time, int) (int64, error)); ok {
+		return rf(ctx, before, limit)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time, int) int64); ok {
+		r0 = rf(ctx, before, limit)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, time.Time, int) error); ok {
+		r1 = rf(ctx, before, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetCart provides a mock function with given fields: ctx, id
+func (_m *CartRepository) GetCart(ctx context.Context, id string) (*models.Cart, error) {
+	ret := _m.Called(ctx, id)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetCart")
+	}
+
+	var r0 *models.Cart
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (*models.Cart, error)); ok {
+		return rf(ctx, id)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) *models.Cart); ok {
+		r0 = rf(ctx, id)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Cart)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SetCartItemQuantity provides a mock function with given fields: ctx, cartID, variantID, quantity, expiresAt
+func (_m *CartRepository) SetCartItemQuantity(ctx context.Context, cartID string, variantID int64, quantity int, expiresAt time.Time) error {
+	ret := _m.Called(ctx, cartID, variantID, quantity, expiresAt)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetCartItemQuantity")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, int64, int, time.Time) error); ok {
+		r0 = rf(ctx, cartID, variantID, quantity, expiresAt)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// NewCartRepository creates a new instance of CartRepository. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewCartRepository(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *CartRepository {
+	mock := &CartRepository{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,195 @@
+// Code generated by mockery v2.53.4. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+	models "order-service/internal/models"
+
+	mock "github.com/stretchr/testify/mock"
+
+	time "time"
+)
+
+// CompensationRepository is an autogenerated mock type for the CompensationRepository type
+type CompensationRepository struct {
+	mock.Mock
+}
+
+// ClaimDueCompensations provides a mock function with given fields: ctx, now, claimTTL, limit
+func (_m *CompensationRepository) ClaimDueCompensations(ctx context.Context, now time.Time, claimTTL time.Duration, limit int) ([]models.Compensation, error) {
+	ret := _m.Called(ctx, now, claimTTL, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ClaimDueCompensations")
+	}
+
+	var r0 []models.Compensation
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time, time.Duration, int) ([]models.Compensation, error)); ok {
+		return rf(ctx, now, claimTTL, limit)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time, time.Duration, int) []models.Compensation); ok {
+		r0 = rf(ctx, now, claimTTL, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.Compensation)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, time.Time, time.Duration, int) error); ok {
+		r1 = rf(ctx, now, claimTTL, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CountCompensations provides a mock function with given fields: ctx, status
+func (_m *CompensationRepository) CountCompensations(ctx context.Context, status string) (int, error) {
+	ret := _m.Called(ctx, status)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CountCompensations")
+	}
+
+	var r0 int
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (int, error)); ok {
+		return rf(ctx, status)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) int); ok {
+		r0 = rf(ctx, status)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, status)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CreateCompensation provides a mock function with given fields: ctx, c
+func (_m *CompensationRepository) CreateCompensation(ctx context.Context, c *models.Compensation) (bool, error) {
+	ret := _m.Called(ctx, c)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreateCompensation")
+	}
+
+	var r0 bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.Compensation) (bool, error)); ok {
+		return rf(ctx, c)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *models.Compensation) bool); ok {
+		r0 = rf(ctx, c)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *models.Compensation) error); ok {
+		r1 = rf(ctx, c)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetCompensation provides a mock function with given fields: ctx, id
+func (_m *CompensationRepository) GetCompensation(ctx context.Context, id int64) (*models.Compensation, error) {
+	ret := _m.Called(ctx, id)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetCompensation")
+	}
+
+	var r0 *models.Compensation
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) (*models.Compensation, error)); ok {
+		return rf(ctx, id)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64) *models.Compensation); ok {
+		r0 = rf(ctx, id)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Compensation)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListCompensations provides a mock function with given fields: ctx, filter
+func (_m *CompensationRepository) ListCompensations(ctx context.Context, filter models.CompensationFilter) ([]models.Compensation, error) {
+	ret := _m.Called(ctx, filter)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListCompensations")
+	}
+
+	var r0 []models.Compensation
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, models.CompensationFilter) ([]models.Compensation, error)); ok {
+		return rf(ctx, filter)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, models.CompensationFilter) []models.Compensation); ok {
+		r0 = rf(ctx, filter)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.Compensation)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, models.CompensationFilter) error); ok {
+		r1 = rf(ctx, filter)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// UpdateCompensation provides a mock function with given fields: ctx, c
+func (_m *CompensationRepository) UpdateCompensation(ctx context.Context, c *models.Compensation) error {
+	ret := _m.Called(ctx, c)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateCompensation")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.Compensation) error); ok {
+		r0 = rf(ctx, c)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// NewCompensationRepository creates a new instance of CompensationRepository. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewCompensationRepository(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *CompensationRepository {
+	mock := &CompensationRepository{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,153 @@
+// Code generated by mockery v2.53.4. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+	models "order-service/internal/models"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+// DeadLetterRepository is an autogenerated mock type for the DeadLetterRepository type
+type DeadLetterRepository struct {
+	mock.Mock
+}
+
+// ClaimDeadLetterRedrive provides a mock function with given fields: ctx, redrive
+func (_m *DeadLetterRepository) ClaimDeadLetterRedrive(ctx context.Context, redrive *models.DeadLetterRedrive) (bool, error) {
+	ret := _m.Called(ctx, redrive)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ClaimDeadLetterRedrive")
+	}
+
+	var r0 bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.DeadLetterRedrive) (bool, error)); ok {
+		return rf(ctx, redrive)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *models.DeadLetterRedrive) bool); ok {
+		r0 = rf(ctx, redrive)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *models.DeadLetterRedrive) error); ok {
+		r1 = rf(ctx, redrive)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CreateDeadLetter provides a mock function with given fields: ctx, letter
+func (_m *DeadLetterRepository) CreateDeadLetter(ctx context.Context, letter *models.DeadLetter) error {
+	ret := _m.Called(ctx, letter)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreateDeadLetter")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.DeadLetter) error); ok {
+		r0 = rf(ctx, letter)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// GetDeadLetterRedrives provides a mock function with given fields: ctx, deadLetterID
+func (_m *DeadLetterRepository) GetDeadLetterRedrives(ctx context.Context, deadLetterID int64) ([]models.DeadLetterRedrive, error) {
+	ret := _m.Called(ctx, deadLetterID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetDeadLetterRedrives")
+	}
+
+	var r0 []models.DeadLetterRedrive
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) ([]models.DeadLetterRedrive, error)); ok {
+		return rf(ctx, deadLetterID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64) []models.DeadLetterRedrive); ok {
+		r0 = rf(ctx, deadLetterID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.DeadLetterRedrive)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, deadLetterID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListDeadLetters provides a mock function with given fields: ctx, filter
+func (_m *DeadLetterRepository) ListDeadLetters(ctx context.Context, filter models.DeadLetterFilter) ([]models.DeadLetter, error) {
+	ret := _m.Called(ctx, filter)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListDeadLetters")
+	}
+
+	var r0 []models.DeadLetter
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, models.DeadLetterFilter) ([]models.DeadLetter, error)); ok {
+		return rf(ctx, filter)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, models.DeadLetterFilter) []models.DeadLetter); ok {
+		r0 = rf(ctx, filter)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.DeadLetter)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, models.DeadLetterFilter) error); ok {
+		r1 = rf(ctx, filter)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ReleaseDeadLetterRedrive provides a mock function with given fields: ctx, redrive
+func (_m *DeadLetterRepository) ReleaseDeadLetterRedrive(ctx context.Context, redrive *models.DeadLetterRedrive) error {
+	ret := _m.Called(ctx, redrive)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ReleaseDeadLetterRedrive")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.DeadLetterRedrive) error); ok {
+		r0 = rf(ctx, redrive)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// NewDeadLetterRepository creates a new instance of DeadLetterRepository. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewDeadLetterRepository(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *DeadLetterRepository {
+	mock := &DeadLetterRepository{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,281 @@
+// Code generated by mockery v2.53.4. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+	models "order-service/internal/models"
+
+	mock "github.com/stretchr/testify/mock"
+
+	time "time"
+)
+
+// DropRepository is an autogenerated mock type for the DropRepository type
+type DropRepository struct {
+	mock.Mock
+}
+
+// ClaimDueDrop provides a mock function with given fields: ctx, now, claimTTL, seed
+func (_m *DropRepository) ClaimDueDrop(ctx context.Context, now time.Time, claimTTL time.Duration, seed int64) (*models.Drop, error) {
+	ret := _m.Called(ctx, now, claimTTL, seed)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ClaimDueDrop")
+	}
+
+	var r0 *models.Drop
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time, time.Duration, int64) (*models.Drop, error)); ok {
+		return rf(ctx, now, claimTTL, seed)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time, time.Duration, int64) *models.Drop); ok {
+		r0 = rf(ctx, now, claimTTL, seed)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Drop)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, time.Time, time.Duration, int64) error); ok {
+		r1 = rf(ctx, now, claimTTL, seed)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CompleteDrop provides a mock function with given fields: ctx, id
+func (_m *DropRepository) CompleteDrop(ctx context.Context, id int64) error {
+	ret := _m.Called(ctx, id)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CompleteDrop")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) error); ok {
+		r0 = rf(ctx, id)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// CountDropRegistrations provides a mock function with given fields: ctx, dropID
+func (_m *DropRepository) CountDropRegistrations(ctx context.Context, dropID int64) (map[string]int, error) {
+	ret := _m.Called(ctx, dropID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CountDropRegistrations")
+	}
+
+	var r0 map[string]int
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) (map[string]int, error)); ok {
+		return rf(ctx, dropID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64) map[string]int); ok {
+		r0 = rf(ctx, dropID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(map[string]int)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, dropID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CreateDrop provides a mock function with given fields: ctx, drop
+func (_m *DropRepository) CreateDrop(ctx context.Context, drop *models.Drop) error {
+	ret := _m.Called(ctx, drop)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreateDrop")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.Drop) error); ok {
+		r0 = rf(ctx, drop)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// FinishDropRegistration provides a mock function with given fields: ctx, reg
+func (_m *DropRepository) FinishDropRegistration(ctx context.Context, reg *models.DropRegistration) error {
+	ret := _m.Called(ctx, reg)
+
+	if len(ret) == 0 {
+		panic("no return value specified for FinishDropRegistration")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.DropRegistration) error); ok {
+		r0 = rf(ctx, reg)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// GetDrop provides a mock function with given fields: ctx, id
+func (_m *DropRepository) GetDrop(ctx context.Context, id int64) (*models.Drop, error) {
+	ret := _m.Called(ctx, id)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetDrop")
+	}
+
+	var r0 *models.Drop
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) (*models.Drop, error)); ok {
+		return rf(ctx, id)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64) *models.Drop); ok {
+		r0 = rf(ctx, id)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Drop)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetDropRegistration provides a mock function with given fields: ctx, dropID, userID
+func (_m *DropRepository) GetDropRegistration(ctx context.Context, dropID int64, userID int64) (*models.DropRegistration, error) {
+	ret := _m.Called(ctx, dropID, userID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetDropRegistration")
+	}
+
+	var r0 *models.DropRegistration
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64) (*models.DropRegistration, error)); ok {
+		return rf(ctx, dropID, userID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64) *models.DropRegistration); ok {
+		r0 = rf(ctx, dropID, userID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.DropRegistration)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64, int64) error); ok {
+		r1 = rf(ctx, dropID, userID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetPendingDropRegistrations provides a mock function with given fields: ctx, dropID
+func (_m *DropRepository) GetPendingDropRegistrations(ctx context.Context, dropID int64) ([]models.DropRegistration, error) {
+	ret := _m.Called(ctx, dropID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetPendingDropRegistrations")
+	}
+
+	var r0 []models.DropRegistration
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) ([]models.DropRegistration, error)); ok {
+		return rf(ctx, dropID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64) []models.DropRegistration); ok {
+		r0 = rf(ctx, dropID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.DropRegistration)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, dropID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// RegisterForDrop provides a mock function with given fields: ctx, reg
+func (_m *DropRepository) RegisterForDrop(ctx context.Context, reg *models.DropRegistration) (bool, error) {
+	ret := _m.Called(ctx, reg)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RegisterForDrop")
+	}
+
+	var r0 bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.DropRegistration) (bool, error)); ok {
+		return rf(ctx, reg)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *models.DropRegistration) bool); ok {
+		r0 = rf(ctx, reg)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *models.DropRegistration) error); ok {
+		r1 = rf(ctx, reg)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// RenewDropClaim provides a mock function with given fields: ctx, id, now
+func (_m *DropRepository) RenewDropClaim(ctx context.Context, id int64, now time.Time) error {
+	ret := _m.Called(ctx, id, now)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RenewDropClaim")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, time.Time) error); ok {
+		r0 = rf(ctx, id, now)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// NewDropRepository creates a new instance of DropRepository. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewDropRepository(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *DropRepository {
+	mock := &DropRepository{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,223 @@
+// Code generated by mockery v2.53.4. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+	models "order-service/internal/models"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+// IncomingStockRepository is an autogenerated mock type for the IncomingStockRepository type
+type IncomingStockRepository struct {
+	mock.Mock
+}
+
+// CountBackorderedUnits provides a mock function with given fields: ctx, variantID
+func (_m *IncomingStockRepository) CountBackorderedUnits(ctx context.Context, variantID int64) (int, error) {
+	ret := _m.Called(ctx, variantID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CountBackorderedUnits")
+	}
+
+	var r0 int
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) (int, error)); ok {
+		return rf(ctx, variantID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64) int); ok {
+		r0 = rf(ctx, variantID)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, variantID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CreateIncomingStock provides a mock function with given fields: ctx, incoming
+func (_m *IncomingStockRepository) CreateIncomingStock(ctx context.Context, incoming *models.IncomingStock) error {
+	ret := _m.Called(ctx, incoming)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreateIncomingStock")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.IncomingStock) error); ok {
+		r0 = rf(ctx, incoming)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// GetIncomingStock provides a mock function with given fields: ctx, id
+func (_m *IncomingStockRepository) GetIncomingStock(ctx context.Context, id int64) (*models.IncomingStock, error) {
+	ret := _m.Called(ctx, id)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetIncomingStock")
+	}
+
+	var r0 *models.IncomingStock
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) (*models.IncomingStock, error)); ok {
+		return rf(ctx, id)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64) *models.IncomingStock); ok {
+		r0 = rf(ctx, id)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.IncomingStock)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetOpenIncomingStock provides a mock function with given fields: ctx, variantID
+func (_m *IncomingStockRepository) GetOpenIncomingStock(ctx context.Context, variantID int64) ([]models.IncomingStock, error) {
+	ret := _m.Called(ctx, variantID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetOpenIncomingStock")
+	}
+
+	var r0 []models.IncomingStock
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) ([]models.IncomingStock, error)); ok {
+		return rf(ctx, variantID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64) []models.IncomingStock); ok {
+		r0 = rf(ctx, variantID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.IncomingStock)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, variantID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// PlanIncomingStock provides a mock function with given fields: ctx, variantID
+func (_m *IncomingStockRepository) PlanIncomingStock(ctx context.Context, variantID int64) ([]models.OrderItem, error) {
+	ret := _m.Called(ctx, variantID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for PlanIncomingStock")
+	}
+
+	var r0 []models.OrderItem
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) ([]models.OrderItem, error)); ok {
+		return rf(ctx, variantID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64) []models.OrderItem); ok {
+		r0 = rf(ctx, variantID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.OrderItem)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, variantID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ReceiveIncomingStock provides a mock function with given fields: ctx, id
+func (_m *IncomingStockRepository) ReceiveIncomingStock(ctx context.Context, id int64) (*models.Inventory, error) {
+	ret := _m.Called(ctx, id)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ReceiveIncomingStock")
+	}
+
+	var r0 *models.Inventory
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) (*models.Inventory, error)); ok {
+		return rf(ctx, id)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64) *models.Inventory); ok {
+		r0 = rf(ctx, id)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Inventory)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// UpdateIncomingStock provides a mock function with given fields: ctx, incoming
+func (_m *IncomingStockRepository) UpdateIncomingStock(ctx context.Context, incoming *models.IncomingStock) (bool, error) {
+	ret := _m.Called(ctx, incoming)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateIncomingStock")
+	}
+
+	var r0 bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.IncomingStock) (bool, error)); ok {
+		return rf(ctx, incoming)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *models.IncomingStock) bool); ok {
+		r0 = rf(ctx, incoming)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *models.IncomingStock) error); ok {
+		r1 = rf(ctx, incoming)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// NewIncomingStockRepository creates a new instance of IncomingStockRepository. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewIncomingStockRepository(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *IncomingStockRepository {
+	mock := &IncomingStockRepository{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
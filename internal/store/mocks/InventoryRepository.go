@@ -0,0 +1,764 @@
+// Code generated by mockery v2.53.4. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+	models "order-service/internal/models"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+// InventoryRepository is an autogenerated mock type for the InventoryRepository type
+type InventoryRepository struct {
+	mock.Mock
+}
+
+// AllocateReservation provides a mock function with given fields: ctx, orderID, variantID, warehouseID
+func (_m *InventoryRepository) AllocateReservation(ctx context.Context, orderID int64, variantID int64, warehouseID int64) error {
+	ret := _m.Called(ctx, orderID, variantID, warehouseID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for AllocateReservation")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64, int64) error); ok {
+		r0 = rf(ctx, orderID, variantID, warehouseID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// CommitOrderItemStock provides a mock function with given fields: ctx, item
+func (_m *InventoryRepository) CommitOrderItemStock(ctx context.Context, item models.OrderItem) (bool, error) {
+	ret := _m.Called(ctx, item)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CommitOrderItemStock")
+	}
+
+	var r0 bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, models.OrderItem) (bool, error)); ok {
+		return rf(ctx, item)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, models.OrderItem) bool); ok {
+		r0 = rf(ctx, item)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, models.OrderItem) error); ok {
+		r1 = rf(ctx, item)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CommitStock provides a mock function with given fields: ctx, variantID, warehouseID, quantity
+func (_m *InventoryRepository) CommitStock(ctx context.Context, variantID int64, warehouseID int64, quantity int) error {
+	ret := _m.Called(ctx, variantID, warehouseID, quantity)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CommitStock")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64, int) error); ok {
+		r0 = rf(ctx, variantID, warehouseID, quantity)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DeleteReservation provides a mock function with given fields: ctx, orderID, variantID
+func (_m *InventoryRepository) DeleteReservation(ctx context.Context, orderID int64, variantID int64) error {
+	ret := _m.Called(ctx, orderID, variantID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteReservation")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64) error); ok {
+		r0 = rf(ctx, orderID, variantID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// GetInventories provides a mock function with given fields: ctx
+func (_m *InventoryRepository) GetInventories(ctx context.Context) ([]models.Inventory, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetInventories")
+	}
+
+	var r0 []models.Inventory
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) ([]models.Inventory, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) []models.Inventory); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.Inventory)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetInventory provides a mock function with given fields: ctx, variantID
+func (_m *InventoryRepository) GetInventory(ctx context.Context, variantID int64) (*models.Inventory, error) {
+	ret := _m.Called(ctx, variantID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetInventory")
+	}
+
+	var r0 *models.Inventory
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) (*models.Inventory, error)); ok {
+		return rf(ctx, variantID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64) *models.Inventory); ok {
+		r0 = rf(ctx, variantID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Inventory)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, variantID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetLowStockItems provides a mock function with given fields: ctx
+func (_m *InventoryRepository) GetLowStockItems(ctx context.Context) ([]models.LowStockItem, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetLowStockItems")
+	}
+
+	var r0 []models.LowStockItem
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) ([]models.LowStockItem, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) []models.LowStockItem); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.LowStockItem)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetOrderReservations provides a mock function with given fields: ctx, orderID
+func (_m *InventoryRepository) GetOrderReservations(ctx context.Context, orderID int64) ([]models.Reservation, error) {
+	ret := _m.Called(ctx, orderID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetOrderReservations")
+	}
+
+	var r0 []models.Reservation
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) ([]models.Reservation, error)); ok {
+		return rf(ctx, orderID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64) []models.Reservation); ok {
+		r0 = rf(ctx, orderID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.Reservation)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, orderID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetProductByID provides a mock function with given fields: ctx, id
+func (_m *InventoryRepository) GetProductByID(ctx context.Context, id int64) (*models.Product, error) {
+	ret := _m.Called(ctx, id)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetProductByID")
+	}
+
+	var r0 *models.Product
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) (*models.Product, error)); ok {
+		return rf(ctx, id)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64) *models.Product); ok {
+		r0 = rf(ctx, id)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Product)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetProductBySKU provides a mock function with given fields: ctx, sku
+func (_m *InventoryRepository) GetProductBySKU(ctx context.Context, sku string) (*models.Product, error) {
+	ret := _m.Called(ctx, sku)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetProductBySKU")
+	}
+
+	var r0 *models.Product
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (*models.Product, error)); ok {
+		return rf(ctx, sku)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) *models.Product); ok {
+		r0 = rf(ctx, sku)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Product)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, sku)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetProducts provides a mock function with given fields: ctx
+func (_m *InventoryRepository) GetProducts(ctx context.Context) ([]models.Product, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetProducts")
+	}
+
+	var r0 []models.Product
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) ([]models.Product, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) []models.Product); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.Product)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetProductsByIDs provides a mock function with given fields: ctx, ids
+func (_m *InventoryRepository) GetProductsByIDs(ctx context.Context, ids []int64) ([]models.Product, error) {
+	ret := _m.Called(ctx, ids)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetProductsByIDs")
+	}
+
+	var r0 []models.Product
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, []int64) ([]models.Product, error)); ok {
+		return rf(ctx, ids)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, []int64) []models.Product); ok {
+		r0 = rf(ctx, ids)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.Product)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, []int64) error); ok {
+		r1 = rf(ctx, ids)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetVariantByID provides a mock function with given fields: ctx, id
+func (_m *InventoryRepository) GetVariantByID(ctx context.Context, id int64) (*models.ProductVariant, error) {
+	ret := _m.Called(ctx, id)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetVariantByID")
+	}
+
+	var r0 *models.ProductVariant
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) (*models.ProductVariant, error)); ok {
+		return rf(ctx, id)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64) *models.ProductVariant); ok {
+		r0 = rf(ctx, id)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.ProductVariant)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetVariantBySKU provides a mock function with given fields: ctx, sku
+func (_m *InventoryRepository) GetVariantBySKU(ctx context.Context, sku string) (*models.ProductVariant, error) {
+	ret := _m.Called(ctx, sku)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetVariantBySKU")
+	}
+
+	var r0 *models.ProductVariant
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (*models.ProductVariant, error)); ok {
+		return rf(ctx, sku)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) *models.ProductVariant); ok {
+		r0 = rf(ctx, sku)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.ProductVariant)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, sku)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetVariantsByProductIDs provides a mock function with given fields: ctx, productIDs
+func (_m *InventoryRepository) GetVariantsByProductIDs(ctx context.Context, productIDs []int64) ([]models.ProductVariant, error) {
+	ret := _m.Called(ctx, productIDs)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetVariantsByProductIDs")
+	}
+
+	var r0 []models.ProductVariant
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, []int64) ([]models.ProductVariant, error)); ok {
+		return rf(ctx, productIDs)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, []int64) []models.ProductVariant); ok {
+		r0 = rf(ctx, productIDs)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.ProductVariant)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, []int64) error); ok {
+		r1 = rf(ctx, productIDs)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetWarehouseStock provides a mock function with given fields: ctx, variantID
+func (_m *InventoryRepository) GetWarehouseStock(ctx context.Context, variantID int64) ([]models.Inventory, error) {
+	ret := _m.Called(ctx, variantID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetWarehouseStock")
+	}
+
+	var r0 []models.Inventory
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) ([]models.Inventory, error)); ok {
+		return rf(ctx, variantID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64) []models.Inventory); ok {
+		r0 = rf(ctx, variantID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.Inventory)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, variantID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetWarehouses provides a mock function with given fields: ctx
+func (_m *InventoryRepository) GetWarehouses(ctx context.Context) ([]models.Warehouse, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetWarehouses")
+	}
+
+	var r0 []models.Warehouse
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) ([]models.Warehouse, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) []models.Warehouse); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.Warehouse)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// HoldReservation provides a mock function with given fields: ctx, orderID, productID, variantID, quantity
+func (_m *InventoryRepository) HoldReservation(ctx context.Context, orderID int64, productID int64, variantID int64, quantity int) (bool, error) {
+	ret := _m.Called(ctx, orderID, productID, variantID, quantity)
+
+	if len(ret) == 0 {
+		panic("no return value specified for HoldReservation")
+	}
+
+	var r0 bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64, int64, int) (bool, error)); ok {
+		return rf(ctx, orderID, productID, variantID, quantity)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64, int64, int) bool); ok {
+		r0 = rf(ctx, orderID, productID, variantID, quantity)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64, int64, int64, int) error); ok {
+		r1 = rf(ctx, orderID, productID, variantID, quantity)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ImportInventoryRows provides a mock function with given fields: ctx, rows
+func (_m *InventoryRepository) ImportInventoryRows(ctx context.Context, rows []models.InventoryImportRow) ([]models.Inventory, []error, error) {
+	ret := _m.Called(ctx, rows)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ImportInventoryRows")
+	}
+
+	var r0 []models.Inventory
+	var r1 []error
+	var r2 error
+	if rf, ok := ret.Get(0).(func(context.Context, []models.InventoryImportRow) ([]models.Inventory, []error, error)); ok {
+		return rf(ctx, rows)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, []models.InventoryImportRow) []models.Inventory); ok {
+		r0 = rf(ctx, rows)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.Inventory)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, []models.InventoryImportRow) []error); ok {
+		r1 = rf(ctx, rows)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).([]error)
+		}
+	}
+
+	if rf, ok := ret.Get(2).(func(context.Context, []models.InventoryImportRow) error); ok {
+		r2 = rf(ctx, rows)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// ReleaseReservation provides a mock function with given fields: ctx, orderID, variantID
+func (_m *InventoryRepository) ReleaseReservation(ctx context.Context, orderID int64, variantID int64) (*models.Reservation, error) {
+	ret := _m.Called(ctx, orderID, variantID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ReleaseReservation")
+	}
+
+	var r0 *models.Reservation
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64) (*models.Reservation, error)); ok {
+		return rf(ctx, orderID, variantID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64) *models.Reservation); ok {
+		r0 = rf(ctx, orderID, variantID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Reservation)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64, int64) error); ok {
+		r1 = rf(ctx, orderID, variantID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ReleaseStock provides a mock function with given fields: ctx, variantID, warehouseID, quantity
+func (_m *InventoryRepository) ReleaseStock(ctx context.Context, variantID int64, warehouseID int64, quantity int) error {
+	ret := _m.Called(ctx, variantID, warehouseID, quantity)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ReleaseStock")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64, int) error); ok {
+		r0 = rf(ctx, variantID, warehouseID, quantity)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ReplaceInventory provides a mock function with given fields: ctx, variantID, warehouseID, seenAvailable, seenReserved, available, reserved
+func (_m *InventoryRepository) ReplaceInventory(ctx context.Context, variantID int64, warehouseID int64, seenAvailable int, seenReserved int, available int, reserved int) (bool, error) {
+	ret := _m.Called(ctx, variantID, warehouseID, seenAvailable, seenReserved, available, reserved)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ReplaceInventory")
+	}
+
+	var r0 bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64, int, int, int, int) (bool, error)); ok {
+		return rf(ctx, variantID, warehouseID, seenAvailable, seenReserved, available, reserved)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64, int, int, int, int) bool); ok {
+		r0 = rf(ctx, variantID, warehouseID, seenAvailable, seenReserved, available, reserved)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64, int64, int, int, int, int) error); ok {
+		r1 = rf(ctx, variantID, warehouseID, seenAvailable, seenReserved, available, reserved)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ReserveStockTx provides a mock function with given fields: ctx, variantID, warehouseID, quantity
+func (_m *InventoryRepository) ReserveStockTx(ctx context.Context, variantID int64, warehouseID int64, quantity int) error {
+	ret := _m.Called(ctx, variantID, warehouseID, quantity)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ReserveStockTx")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64, int) error); ok {
+		r0 = rf(ctx, variantID, warehouseID, quantity)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ResizeReservation provides a mock function with given fields: ctx, orderID, variantID, quantity
+func (_m *InventoryRepository) ResizeReservation(ctx context.Context, orderID int64, variantID int64, quantity int) (*models.Reservation, error) {
+	ret := _m.Called(ctx, orderID, variantID, quantity)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ResizeReservation")
+	}
+
+	var r0 *models.Reservation
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64, int) (*models.Reservation, error)); ok {
+		return rf(ctx, orderID, variantID, quantity)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64, int) *models.Reservation); ok {
+		r0 = rf(ctx, orderID, variantID, quantity)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Reservation)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64, int64, int) error); ok {
+		r1 = rf(ctx, orderID, variantID, quantity)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// RestockInventory provides a mock function with given fields: ctx, variantID, warehouseID, quantity
+func (_m *InventoryRepository) RestockInventory(ctx context.Context, variantID int64, warehouseID int64, quantity int) error {
+	ret := _m.Called(ctx, variantID, warehouseID, quantity)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RestockInventory")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64, int) error); ok {
+		r0 = rf(ctx, variantID, warehouseID, quantity)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SetReorderThreshold provides a mock function with given fields: ctx, productID, threshold
+func (_m *InventoryRepository) SetReorderThreshold(ctx context.Context, productID int64, threshold *int) error {
+	ret := _m.Called(ctx, productID, threshold)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetReorderThreshold")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, *int) error); ok {
+		r0 = rf(ctx, productID, threshold)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UpsertWarehouse provides a mock function with given fields: ctx, w
+func (_m *InventoryRepository) UpsertWarehouse(ctx context.Context, w *models.Warehouse) error {
+	ret := _m.Called(ctx, w)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpsertWarehouse")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.Warehouse) error); ok {
+		r0 = rf(ctx, w)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// WithTx provides a mock function with given fields: ctx, fn
+func (_m *InventoryRepository) WithTx(ctx context.Context, fn func(context.Context) error) error {
+	ret := _m.Called(ctx, fn)
+
+	if len(ret) == 0 {
+		panic("no return value specified for WithTx")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, func(context.Context) error) error); ok {
+		r0 = rf(ctx, fn)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// NewInventoryRepository creates a new instance of InventoryRepository. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewInventoryRepository(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *InventoryRepository {
+	mock := &InventoryRepository{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,74 @@
+// Code generated by mockery v2.53.4. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+// InvoiceRepository is an autogenerated mock type for the InvoiceRepository type
+type InvoiceRepository struct {
+	mock.Mock
+}
+
+// AssignInvoiceNumber provides a mock function with given fields: ctx, orderID, series
+func (_m *InvoiceRepository) AssignInvoiceNumber(ctx context.Context, orderID int64, series string) (string, error) {
+	ret := _m.Called(ctx, orderID, series)
+
+	if len(ret) == 0 {
+		panic("no return value specified for AssignInvoiceNumber")
+	}
+
+	var r0 string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, string) (string, error)); ok {
+		return rf(ctx, orderID, series)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64, string) string); ok {
+		r0 = rf(ctx, orderID, series)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64, string) error); ok {
+		r1 = rf(ctx, orderID, series)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SetOrderInvoice provides a mock function with given fields: ctx, orderID, url
+func (_m *InvoiceRepository) SetOrderInvoice(ctx context.Context, orderID int64, url string) error {
+	ret := _m.Called(ctx, orderID, url)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetOrderInvoice")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, string) error); ok {
+		r0 = rf(ctx, orderID, url)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// NewInvoiceRepository creates a new instance of InvoiceRepository. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewInvoiceRepository(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *InvoiceRepository {
+	mock := &InvoiceRepository{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
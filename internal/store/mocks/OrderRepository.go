@@ -0,0 +1,819 @@
+// Code generated by mockery v2.53.4. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+	models "order-service/internal/models"
+
+	mock "github.com/stretchr/testify/mock"
+
+	time "time"
+)
+
+// OrderRepository is an autogenerated mock type for the OrderRepository type
+type OrderRepository struct {
+	mock.Mock
+}
+
+// AmendOrderItems provides a mock function with given fields: ctx, orderID, token, items, totalAmount, walletAmount
+func (_m *OrderRepository) AmendOrderItems(ctx context.Context, orderID int64, token int64, items []models.OrderItem, totalAmount int64, walletAmount int64) error {
+	ret := _m.Called(ctx, orderID, token, items, totalAmount, walletAmount)
+
+	if len(ret) == 0 {
+		panic("no return value specified for AmendOrderItems")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64, []models.OrderItem, int64, int64) error); ok {
+		r0 = rf(ctx, orderID, token, items, totalAmount, walletAmount)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// CountOrdersByStatus provides a mock function with given fields: ctx, statuses
+func (_m *OrderRepository) CountOrdersByStatus(ctx context.Context, statuses []string) (map[string]int, error) {
+	ret := _m.Called(ctx, statuses)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CountOrdersByStatus")
+	}
+
+	var r0 map[string]int
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, []string) (map[string]int, error)); ok {
+		return rf(ctx, statuses)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, []string) map[string]int); ok {
+		r0 = rf(ctx, statuses)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(map[string]int)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, []string) error); ok {
+		r1 = rf(ctx, statuses)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CreateOrder provides a mock function with given fields: ctx, order
+func (_m *OrderRepository) CreateOrder(ctx context.Context, order *models.Order) error {
+	ret := _m.Called(ctx, order)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreateOrder")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.Order) error); ok {
+		r0 = rf(ctx, order)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// CreateOrderItem provides a mock function with given fields: ctx, item
+func (_m *OrderRepository) CreateOrderItem(ctx context.Context, item *models.OrderItem) error {
+	ret := _m.Called(ctx, item)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreateOrderItem")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.OrderItem) error); ok {
+		r0 = rf(ctx, item)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// CreateOrderWithItems provides a mock function with given fields: ctx, order, items, allowPartial
+func (_m *OrderRepository) CreateOrderWithItems(ctx context.Context, order *models.Order, items []*models.OrderItem, allowPartial bool) ([]models.SkippedOrderItem, error) {
+	ret := _m.Called(ctx, order, items, allowPartial)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreateOrderWithItems")
+	}
+
+	var r0 []models.SkippedOrderItem
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.Order, []*models.OrderItem, bool) ([]models.SkippedOrderItem, error)); ok {
+		return rf(ctx, order, items, allowPartial)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *models.Order, []*models.OrderItem, bool) []models.SkippedOrderItem); ok {
+		r0 = rf(ctx, order, items, allowPartial)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.SkippedOrderItem)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *models.Order, []*models.OrderItem, bool) error); ok {
+		r1 = rf(ctx, order, items, allowPartial)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DeleteOrder provides a mock function with given fields: ctx, orderID
+func (_m *OrderRepository) DeleteOrder(ctx context.Context, orderID int64) error {
+	ret := _m.Called(ctx, orderID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteOrder")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) error); ok {
+		r0 = rf(ctx, orderID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// GetBackorderedItems provides a mock function with given fields: ctx, afterID, limit
+func (_m *OrderRepository) GetBackorderedItems(ctx context.Context, afterID int64, limit int) ([]models.OrderItem, error) {
+	ret := _m.Called(ctx, afterID, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetBackorderedItems")
+	}
+
+	var r0 []models.OrderItem
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int) ([]models.OrderItem, error)); ok {
+		return rf(ctx, afterID, limit)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int) []models.OrderItem); ok {
+		r0 = rf(ctx, afterID, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.OrderItem)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64, int) error); ok {
+		r1 = rf(ctx, afterID, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetExpiredOrders provides a mock function with given fields: ctx, now, limit
+func (_m *OrderRepository) GetExpiredOrders(ctx context.Context, now time.Time, limit int) ([]models.Order, error) {
+	ret := _m.Called(ctx, now, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetExpiredOrders")
+	}
+
+	var r0 []models.Order
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time, int) ([]models.Order, error)); ok {
+		return rf(ctx, now, limit)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time, int) []models.Order); ok {
+		r0 = rf(ctx, now, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.Order)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, time.Time, int) error); ok {
+		r1 = rf(ctx, now, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetOrderByID provides a mock function with given fields: ctx, id
+func (_m *OrderRepository) GetOrderByID(ctx context.Context, id int64) (*models.Order, error) {
+	ret := _m.Called(ctx, id)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetOrderByID")
+	}
+
+	var r0 *models.Order
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) (*models.Order, error)); ok {
+		return rf(ctx, id)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64) *models.Order); ok {
+		r0 = rf(ctx, id)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Order)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetOrderByIdempotencyKey provides a mock function with given fields: ctx, key
+func (_m *OrderRepository) GetOrderByIdempotencyKey(ctx context.Context, key string) (*models.Order, error) {
+	ret := _m.Called(ctx, key)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetOrderByIdempotencyKey")
+	}
+
+	var r0 *models.Order
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (*models.Order, error)); ok {
+		return rf(ctx, key)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) *models.Order); ok {
+		r0 = rf(ctx, key)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Order)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, key)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetOrderItemsByOrderID provides a mock function with given fields: ctx, orderID
+func (_m *OrderRepository) GetOrderItemsByOrderID(ctx context.Context, orderID int64) ([]models.OrderItem, error) {
+	ret := _m.Called(ctx, orderID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetOrderItemsByOrderID")
+	}
+
+	var r0 []models.OrderItem
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) ([]models.OrderItem, error)); ok {
+		return rf(ctx, orderID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64) []models.OrderItem); ok {
+		r0 = rf(ctx, orderID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.OrderItem)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, orderID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetOrderItemsByOrderIDs provides a mock function with given fields: ctx, orderIDs
+func (_m *OrderRepository) GetOrderItemsByOrderIDs(ctx context.Context, orderIDs []int64) ([]models.OrderItem, error) {
+	ret := _m.Called(ctx, orderIDs)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetOrderItemsByOrderIDs")
+	}
+
+	var r0 []models.OrderItem
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, []int64) ([]models.OrderItem, error)); ok {
+		return rf(ctx, orderIDs)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, []int64) []models.OrderItem); ok {
+		r0 = rf(ctx, orderIDs)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.OrderItem)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, []int64) error); ok {
+		r1 = rf(ctx, orderIDs)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetOrderStatusHistory provides a mock function with given fields: ctx, orderID
+func (_m *OrderRepository) GetOrderStatusHistory(ctx context.Context, orderID int64) ([]models.OrderStatusHistory, error) {
+	ret := _m.Called(ctx, orderID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetOrderStatusHistory")
+	}
+
+	var r0 []models.OrderStatusHistory
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) ([]models.OrderStatusHistory, error)); ok {
+		return rf(ctx, orderID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64) []models.OrderStatusHistory); ok {
+		r0 = rf(ctx, orderID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.OrderStatusHistory)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, orderID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetOrdersByIDs provides a mock function with given fields: ctx, ids
+func (_m *OrderRepository) GetOrdersByIDs(ctx context.Context, ids []int64) ([]models.Order, error) {
+	ret := _m.Called(ctx, ids)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetOrdersByIDs")
+	}
+
+	var r0 []models.Order
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, []int64) ([]models.Order, error)); ok {
+		return rf(ctx, ids)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, []int64) []models.Order); ok {
+		r0 = rf(ctx, ids)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.Order)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, []int64) error); ok {
+		r1 = rf(ctx, ids)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetOrdersByUserID provides a mock function with given fields: ctx, userID
+func (_m *OrderRepository) GetOrdersByUserID(ctx context.Context, userID int64) ([]models.Order, error) {
+	ret := _m.Called(ctx, userID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetOrdersByUserID")
+	}
+
+	var r0 []models.Order
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) ([]models.Order, error)); ok {
+		return rf(ctx, userID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64) []models.Order); ok {
+		r0 = rf(ctx, userID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.Order)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, userID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetOrdersWithItems provides a mock function with given fields: ctx, ids
+func (_m *OrderRepository) GetOrdersWithItems(ctx context.Context, ids []int64) ([]models.OrderWithItems, error) {
+	ret := _m.Called(ctx, ids)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetOrdersWithItems")
+	}
+
+	var r0 []models.OrderWithItems
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, []int64) ([]models.OrderWithItems, error)); ok {
+		return rf(ctx, ids)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, []int64) []models.OrderWithItems); ok {
+		r0 = rf(ctx, ids)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.OrderWithItems)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, []int64) error); ok {
+		r1 = rf(ctx, ids)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetSkippedOrderItems provides a mock function with given fields: ctx, orderID
+func (_m *OrderRepository) GetSkippedOrderItems(ctx context.Context, orderID int64) ([]models.SkippedOrderItem, error) {
+	ret := _m.Called(ctx, orderID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetSkippedOrderItems")
+	}
+
+	var r0 []models.SkippedOrderItem
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) ([]models.SkippedOrderItem, error)); ok {
+		return rf(ctx, orderID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64) []models.SkippedOrderItem); ok {
+		r0 = rf(ctx, orderID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.SkippedOrderItem)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, orderID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetStalledOrders provides a mock function with given fields: ctx, before, limit
+func (_m *OrderRepository) GetStalledOrders(ctx context.Context, before time.Time, limit int) ([]models.Order, error) {
+	ret := _m.Called(ctx, before, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetStalledOrders")
+	}
+
+	var r0 []models.Order
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time, int) ([]models.Order, error)); ok {
+		return rf(ctx, before, limit)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time, int) []models.Order); ok {
+		r0 = rf(ctx, before, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.Order)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, time.Time, int) error); ok {
+		r1 = rf(ctx, before, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// IsEventProcessed provides a mock function with given fields: ctx, eventID
+func (_m *OrderRepository) IsEventProcessed(ctx context.Context, eventID string) (bool, error) {
+	ret := _m.Called(ctx, eventID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for IsEventProcessed")
+	}
+
+	var r0 bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (bool, error)); ok {
+		return rf(ctx, eventID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) bool); ok {
+		r0 = rf(ctx, eventID)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, eventID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListOrders provides a mock function with given fields: ctx, filter
+func (_m *OrderRepository) ListOrders(ctx context.Context, filter models.OrderFilter) ([]models.OrderSummary, error) {
+	ret := _m.Called(ctx, filter)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListOrders")
+	}
+
+	var r0 []models.OrderSummary
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, models.OrderFilter) ([]models.OrderSummary, error)); ok {
+		return rf(ctx, filter)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, models.OrderFilter) []models.OrderSummary); ok {
+		r0 = rf(ctx, filter)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.OrderSummary)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, models.OrderFilter) error); ok {
+		r1 = rf(ctx, filter)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MarkEventProcessed provides a mock function with given fields: ctx, eventID, eventType
+func (_m *OrderRepository) MarkEventProcessed(ctx context.Context, eventID string, eventType string) error {
+	ret := _m.Called(ctx, eventID, eventType)
+
+	if len(ret) == 0 {
+		panic("no return value specified for MarkEventProcessed")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
+		r0 = rf(ctx, eventID, eventType)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MarkOrderItemAllocated provides a mock function with given fields: ctx, itemID
+func (_m *OrderRepository) MarkOrderItemAllocated(ctx context.Context, itemID int64) (bool, error) {
+	ret := _m.Called(ctx, itemID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for MarkOrderItemAllocated")
+	}
+
+	var r0 bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) (bool, error)); ok {
+		return rf(ctx, itemID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64) bool); ok {
+		r0 = rf(ctx, itemID)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, itemID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MarkOrderItemsBackordered provides a mock function with given fields: ctx, orderID, variantIDs
+func (_m *OrderRepository) MarkOrderItemsBackordered(ctx context.Context, orderID int64, variantIDs []int64) error {
+	ret := _m.Called(ctx, orderID, variantIDs)
+
+	if len(ret) == 0 {
+		panic("no return value specified for MarkOrderItemsBackordered")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, []int64) error); ok {
+		r0 = rf(ctx, orderID, variantIDs)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MarkOrderRecoveryAttempt provides a mock function with given fields: ctx, orderID
+func (_m *OrderRepository) MarkOrderRecoveryAttempt(ctx context.Context, orderID int64) error {
+	ret := _m.Called(ctx, orderID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for MarkOrderRecoveryAttempt")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) error); ok {
+		r0 = rf(ctx, orderID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MarkOrderSLABreached provides a mock function with given fields: ctx, orderID
+func (_m *OrderRepository) MarkOrderSLABreached(ctx context.Context, orderID int64) error {
+	ret := _m.Called(ctx, orderID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for MarkOrderSLABreached")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) error); ok {
+		r0 = rf(ctx, orderID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// RefreshOrderSummary provides a mock function with given fields: ctx, orderID, eventType, eventAt
+func (_m *OrderRepository) RefreshOrderSummary(ctx context.Context, orderID int64, eventType string, eventAt time.Time) error {
+	ret := _m.Called(ctx, orderID, eventType, eventAt)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RefreshOrderSummary")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, string, time.Time) error); ok {
+		r0 = rf(ctx, orderID, eventType, eventAt)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SearchOrders provides a mock function with given fields: ctx, query, limit
+func (_m *OrderRepository) SearchOrders(ctx context.Context, query string, limit int) ([]models.OrderSearchResult, error) {
+	ret := _m.Called(ctx, query, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SearchOrders")
+	}
+
+	var r0 []models.OrderSearchResult
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, int) ([]models.OrderSearchResult, error)); ok {
+		return rf(ctx, query, limit)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, int) []models.OrderSearchResult); ok {
+		r0 = rf(ctx, query, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.OrderSearchResult)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, int) error); ok {
+		r1 = rf(ctx, query, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SetOrderCancellation provides a mock function with given fields: ctx, orderID, policy, fee
+func (_m *OrderRepository) SetOrderCancellation(ctx context.Context, orderID int64, policy string, fee int64) error {
+	ret := _m.Called(ctx, orderID, policy, fee)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetOrderCancellation")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, string, int64) error); ok {
+		r0 = rf(ctx, orderID, policy, fee)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UnmarkEventProcessed provides a mock function with given fields: ctx, eventID
+func (_m *OrderRepository) UnmarkEventProcessed(ctx context.Context, eventID string) error {
+	ret := _m.Called(ctx, eventID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UnmarkEventProcessed")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, eventID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UpdateOrderStatus provides a mock function with given fields: ctx, orderID, status, change
+func (_m *OrderRepository) UpdateOrderStatus(ctx context.Context, orderID int64, status string, change models.StatusChange) error {
+	ret := _m.Called(ctx, orderID, status, change)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateOrderStatus")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, string, models.StatusChange) error); ok {
+		r0 = rf(ctx, orderID, status, change)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UpdateOrderStatusFenced provides a mock function with given fields: ctx, orderID, status, token, change
+func (_m *OrderRepository) UpdateOrderStatusFenced(ctx context.Context, orderID int64, status string, token int64, change models.StatusChange) error {
+	ret := _m.Called(ctx, orderID, status, token, change)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateOrderStatusFenced")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, string, int64, models.StatusChange) error); ok {
+		r0 = rf(ctx, orderID, status, token, change)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// WithTx provides a mock function with given fields: ctx, fn
+func (_m *OrderRepository) WithTx(ctx context.Context, fn func(context.Context) error) error {
+	ret := _m.Called(ctx, fn)
+
+	if len(ret) == 0 {
+		panic("no return value specified for WithTx")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, func(context.Context) error) error); ok {
+		r0 = rf(ctx, fn)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// NewOrderRepository creates a new instance of OrderRepository. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewOrderRepository(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *OrderRepository {
+	mock := &OrderRepository{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,325 @@
+// Code generated by mockery v2.53.4. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+	models "order-service/internal/models"
+
+	mock "github.com/stretchr/testify/mock"
+
+	time "time"
+)
+
+// PaymentRepository is an autogenerated mock type for the PaymentRepository type
+type PaymentRepository struct {
+	mock.Mock
+}
+
+// ClaimDuePaymentRetries provides a mock function with given fields: ctx, now, claimTTL, limit
+func (_m *PaymentRepository) ClaimDuePaymentRetries(ctx context.Context, now time.Time, claimTTL time.Duration, limit int) ([]models.PaymentAttempt, error) {
+	ret := _m.Called(ctx, now, claimTTL, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ClaimDuePaymentRetries")
+	}
+
+	var r0 []models.PaymentAttempt
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time, time.Duration, int) ([]models.PaymentAttempt, error)); ok {
+		return rf(ctx, now, claimTTL, limit)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time, time.Duration, int) []models.PaymentAttempt); ok {
+		r0 = rf(ctx, now, claimTTL, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.PaymentAttempt)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, time.Time, time.Duration, int) error); ok {
+		r1 = rf(ctx, now, claimTTL, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CountPaymentAttempts provides a mock function with given fields: ctx, orderID
+func (_m *PaymentRepository) CountPaymentAttempts(ctx context.Context, orderID int64) (int, error) {
+	ret := _m.Called(ctx, orderID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CountPaymentAttempts")
+	}
+
+	var r0 int
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) (int, error)); ok {
+		return rf(ctx, orderID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64) int); ok {
+		r0 = rf(ctx, orderID)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, orderID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CreatePayment provides a mock function with given fields: ctx, payment
+func (_m *PaymentRepository) CreatePayment(ctx context.Context, payment *models.Payment) error {
+	ret := _m.Called(ctx, payment)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreatePayment")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.Payment) error); ok {
+		r0 = rf(ctx, payment)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// CreatePaymentAttempt provides a mock function with given fields: ctx, attempt
+func (_m *PaymentRepository) CreatePaymentAttempt(ctx context.Context, attempt *models.PaymentAttempt) error {
+	ret := _m.Called(ctx, attempt)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreatePaymentAttempt")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.PaymentAttempt) error); ok {
+		r0 = rf(ctx, attempt)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DropStalePaymentRetries provides a mock function with given fields: ctx
+func (_m *PaymentRepository) DropStalePaymentRetries(ctx context.Context) (int64, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DropStalePaymentRetries")
+	}
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) (int64, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) int64); ok {
+		r0 = rf(ctx)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetPaymentByOrderID provides a mock function with given fields: ctx, orderID
+func (_m *PaymentRepository) GetPaymentByOrderID(ctx context.Context, orderID int64) (*models.Payment, error) {
+	ret := _m.Called(ctx, orderID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetPaymentByOrderID")
+	}
+
+	var r0 *models.Payment
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) (*models.Payment, error)); ok {
+		return rf(ctx, orderID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64) *models.Payment); ok {
+		r0 = rf(ctx, orderID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Payment)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, orderID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetPaymentsByOrderID provides a mock function with given fields: ctx, orderID
+func (_m *PaymentRepository) GetPaymentsByOrderID(ctx context.Context, orderID int64) ([]models.Payment, error) {
+	ret := _m.Called(ctx, orderID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetPaymentsByOrderID")
+	}
+
+	var r0 []models.Payment
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) ([]models.Payment, error)); ok {
+		return rf(ctx, orderID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64) []models.Payment); ok {
+		r0 = rf(ctx, orderID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.Payment)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, orderID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetPaymentsByOrderIDs provides a mock function with given fields: ctx, orderIDs
+func (_m *PaymentRepository) GetPaymentsByOrderIDs(ctx context.Context, orderIDs []int64) ([]models.Payment, error) {
+	ret := _m.Called(ctx, orderIDs)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetPaymentsByOrderIDs")
+	}
+
+	var r0 []models.Payment
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, []int64) ([]models.Payment, error)); ok {
+		return rf(ctx, orderIDs)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, []int64) []models.Payment); ok {
+		r0 = rf(ctx, orderIDs)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.Payment)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, []int64) error); ok {
+		r1 = rf(ctx, orderIDs)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// HasScheduledPaymentRetry provides a mock function with given fields: ctx, orderID
+func (_m *PaymentRepository) HasScheduledPaymentRetry(ctx context.Context, orderID int64) (bool, error) {
+	ret := _m.Called(ctx, orderID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for HasScheduledPaymentRetry")
+	}
+
+	var r0 bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) (bool, error)); ok {
+		return rf(ctx, orderID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64) bool); ok {
+		r0 = rf(ctx, orderID)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, orderID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// RefundPayment provides a mock function with given fields: ctx, paymentID, refunded
+func (_m *PaymentRepository) RefundPayment(ctx context.Context, paymentID int64, refunded int64) error {
+	ret := _m.Called(ctx, paymentID, refunded)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RefundPayment")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64) error); ok {
+		r0 = rf(ctx, paymentID, refunded)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SetPaymentAttemptStatus provides a mock function with given fields: ctx, attemptID, status
+func (_m *PaymentRepository) SetPaymentAttemptStatus(ctx context.Context, attemptID int64, status string) error {
+	ret := _m.Called(ctx, attemptID, status)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetPaymentAttemptStatus")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, string) error); ok {
+		r0 = rf(ctx, attemptID, status)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UpdatePaymentStatus provides a mock function with given fields: ctx, paymentID, status, providerTxID
+func (_m *PaymentRepository) UpdatePaymentStatus(ctx context.Context, paymentID int64, status string, providerTxID string) error {
+	ret := _m.Called(ctx, paymentID, status, providerTxID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdatePaymentStatus")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, string, string) error); ok {
+		r0 = rf(ctx, paymentID, status, providerTxID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// NewPaymentRepository creates a new instance of PaymentRepository. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewPaymentRepository(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *PaymentRepository {
+	mock := &PaymentRepository{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,119 @@
+// Code generated by mockery v2.53.4. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+	models "order-service/internal/models"
+
+	mock "github.com/stretchr/testify/mock"
+
+	time "time"
+)
+
+// PricingRepository is an autogenerated mock type for the PricingRepository type
+type PricingRepository struct {
+	mock.Mock
+}
+
+// GetActivePriceList provides a mock function with given fields: ctx, customerGroup, at
+func (_m *PricingRepository) GetActivePriceList(ctx context.Context, customerGroup string, at time.Time) (*models.PriceList, error) {
+	ret := _m.Called(ctx, customerGroup, at)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetActivePriceList")
+	}
+
+	var r0 *models.PriceList
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, time.Time) (*models.PriceList, error)); ok {
+		return rf(ctx, customerGroup, at)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, time.Time) *models.PriceList); ok {
+		r0 = rf(ctx, customerGroup, at)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.PriceList)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, time.Time) error); ok {
+		r1 = rf(ctx, customerGroup, at)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetPriceListPrices provides a mock function with given fields: ctx, priceListID, productIDs
+func (_m *PricingRepository) GetPriceListPrices(ctx context.Context, priceListID int64, productIDs []int64) (map[int64]int64, error) {
+	ret := _m.Called(ctx, priceListID, productIDs)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetPriceListPrices")
+	}
+
+	var r0 map[int64]int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, []int64) (map[int64]int64, error)); ok {
+		return rf(ctx, priceListID, productIDs)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64, []int64) map[int64]int64); ok {
+		r0 = rf(ctx, priceListID, productIDs)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(map[int64]int64)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64, []int64) error); ok {
+		r1 = rf(ctx, priceListID, productIDs)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetUserCustomerGroup provides a mock function with given fields: ctx, userID
+func (_m *PricingRepository) GetUserCustomerGroup(ctx context.Context, userID int64) (string, error) {
+	ret := _m.Called(ctx, userID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetUserCustomerGroup")
+	}
+
+	var r0 string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) (string, error)); ok {
+		return rf(ctx, userID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64) string); ok {
+		r0 = rf(ctx, userID)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, userID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// NewPricingRepository creates a new instance of PricingRepository. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewPricingRepository(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *PricingRepository {
+	mock := &PricingRepository{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
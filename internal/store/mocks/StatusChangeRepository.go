@@ -0,0 +1,87 @@
+// Code generated by mockery v2.53.4. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+	models "order-service/internal/models"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+// StatusChangeRepository is an autogenerated mock type for the StatusChangeRepository type
+type StatusChangeRepository struct {
+	mock.Mock
+}
+
+// ForwardStatusChanges provides a mock function with given fields: ctx, consumer, limit, forward
+func (_m *StatusChangeRepository) ForwardStatusChanges(ctx context.Context, consumer string, limit int, forward func(context.Context, []models.OrderStatusHistory) error) (int, error) {
+	ret := _m.Called(ctx, consumer, limit, forward)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ForwardStatusChanges")
+	}
+
+	var r0 int
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, int, func(context.Context, []models.OrderStatusHistory) error) (int, error)); ok {
+		return rf(ctx, consumer, limit, forward)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, int, func(context.Context, []models.OrderStatusHistory) error) int); ok {
+		r0 = rf(ctx, consumer, limit, forward)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, int, func(context.Context, []models.OrderStatusHistory) error) error); ok {
+		r1 = rf(ctx, consumer, limit, forward)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListenStatusChanges provides a mock function with given fields: ctx
+func (_m *StatusChangeRepository) ListenStatusChanges(ctx context.Context) (<-chan struct{}, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListenStatusChanges")
+	}
+
+	var r0 <-chan struct{}
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) (<-chan struct{}, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) <-chan struct{}); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(<-chan struct{})
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// NewStatusChangeRepository creates a new instance of StatusChangeRepository. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewStatusChangeRepository(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *StatusChangeRepository {
+	mock := &StatusChangeRepository{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
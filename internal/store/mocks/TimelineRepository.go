@@ -0,0 +1,109 @@
+// Code generated by mockery v2.53.4. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+	models "order-service/internal/models"
+
+	mock "github.com/stretchr/testify/mock"
+
+	time "time"
+)
+
+// TimelineRepository is an autogenerated mock type for the TimelineRepository type
+type TimelineRepository struct {
+	mock.Mock
+}
+
+// AddTimelineEntry provides a mock function with given fields: ctx, entry
+func (_m *TimelineRepository) AddTimelineEntry(ctx context.Context, entry *models.OrderTimelineEntry) error {
+	ret := _m.Called(ctx, entry)
+
+	if len(ret) == 0 {
+		panic("no return value specified for AddTimelineEntry")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.OrderTimelineEntry) error); ok {
+		r0 = rf(ctx, entry)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// GetOrderTimeline provides a mock function with given fields: ctx, orderID
+func (_m *TimelineRepository) GetOrderTimeline(ctx context.Context, orderID int64) ([]models.OrderTimelineEntry, error) {
+	ret := _m.Called(ctx, orderID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetOrderTimeline")
+	}
+
+	var r0 []models.OrderTimelineEntry
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) ([]models.OrderTimelineEntry, error)); ok {
+		return rf(ctx, orderID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64) []models.OrderTimelineEntry); ok {
+		r0 = rf(ctx, orderID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.OrderTimelineEntry)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, orderID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetOrdersMissingEvents provides a mock function with given fields: ctx, since, before, afterID, limit
+func (_m *TimelineRepository) GetOrdersMissingEvents(ctx context.Context, since time.Time, before time.Time, afterID int64, limit int) ([]models.Order, error) {
+	ret := _m.Called(ctx, since, before, afterID, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetOrdersMissingEvents")
+	}
+
+	var r0 []models.Order
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time, time.Time, int64, int) ([]models.Order, error)); ok {
+		return rf(ctx, since, before, afterID, limit)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time, time.Time, int64, int) []models.Order); ok {
+		r0 = rf(ctx, since, before, afterID, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.Order)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, time.Time, time.Time, int64, int) error); ok {
+		r1 = rf(ctx, since, before, afterID, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// NewTimelineRepository creates a new instance of TimelineRepository. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewTimelineRepository(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *TimelineRepository {
+	mock := &TimelineRepository{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,175 @@
+// Code generated by mockery v2.53.4. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+	models "order-service/internal/models"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+// WalletRepository is an autogenerated mock type for the WalletRepository type
+type WalletRepository struct {
+	mock.Mock
+}
+
+// ApplyWalletTransaction provides a mock function with given fields: ctx, txn, version
+func (_m *WalletRepository) ApplyWalletTransaction(ctx context.Context, txn *models.WalletTransaction, version int64) (bool, error) {
+	ret := _m.Called(ctx, txn, version)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ApplyWalletTransaction")
+	}
+
+	var r0 bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.WalletTransaction, int64) (bool, error)); ok {
+		return rf(ctx, txn, version)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *models.WalletTransaction, int64) bool); ok {
+		r0 = rf(ctx, txn, version)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *models.WalletTransaction, int64) error); ok {
+		r1 = rf(ctx, txn, version)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetPaymentTenant provides a mock function with given fields: ctx, paymentID
+func (_m *WalletRepository) GetPaymentTenant(ctx context.Context, paymentID int64) (string, error) {
+	ret := _m.Called(ctx, paymentID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetPaymentTenant")
+	}
+
+	var r0 string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) (string, error)); ok {
+		return rf(ctx, paymentID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64) string); ok {
+		r0 = rf(ctx, paymentID)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, paymentID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetPaymentWalletDebit provides a mock function with given fields: ctx, paymentID
+func (_m *WalletRepository) GetPaymentWalletDebit(ctx context.Context, paymentID int64) (*models.WalletTransaction, error) {
+	ret := _m.Called(ctx, paymentID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetPaymentWalletDebit")
+	}
+
+	var r0 *models.WalletTransaction
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) (*models.WalletTransaction, error)); ok {
+		return rf(ctx, paymentID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64) *models.WalletTransaction); ok {
+		r0 = rf(ctx, paymentID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.WalletTransaction)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, paymentID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetWallet provides a mock function with given fields: ctx, userID
+func (_m *WalletRepository) GetWallet(ctx context.Context, userID int64) (*models.Wallet, error) {
+	ret := _m.Called(ctx, userID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetWallet")
+	}
+
+	var r0 *models.Wallet
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) (*models.Wallet, error)); ok {
+		return rf(ctx, userID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64) *models.Wallet); ok {
+		r0 = rf(ctx, userID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Wallet)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, userID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetWalletTransactions provides a mock function with given fields: ctx, userID, limit
+func (_m *WalletRepository) GetWalletTransactions(ctx context.Context, userID int64, limit int) ([]models.WalletTransaction, error) {
+	ret := _m.Called(ctx, userID, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetWalletTransactions")
+	}
+
+	var r0 []models.WalletTransaction
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int) ([]models.WalletTransaction, error)); ok {
+		return rf(ctx, userID, limit)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int) []models.WalletTransaction); ok {
+		r0 = rf(ctx, userID, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.WalletTransaction)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64, int) error); ok {
+		r1 = rf(ctx, userID, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// NewWalletRepository creates a new instance of WalletRepository. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewWalletRepository(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *WalletRepository {
+	mock := &WalletRepository{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,77 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"order-service/internal/models"
+)
+
+// CreateNotification queues a notification. Returns false if the event was
+// already notified on the channel.
+func (s *Store) CreateNotification(ctx context.Context, n *models.Notification) (bool, error) {
+	recipient, err := s.seal(ctx, n.Recipient)
+	if err != nil {
+		return false, err
+	}
+	body, err := s.seal(ctx, n.Body)
+	if err != nil {
+		return false, err
+	}
+
+	err = s.get(ctx, "create_notification", n,
+		`INSERT INTO notifications (order_id, event_id, kind, channel, recipient, subject, body, status, next_attempt_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (event_id, channel) DO NOTHING
+		RETURNING id, created_at, updated_at`,
+		n.OrderID, n.EventID, n.Kind, n.Channel, recipient, n.Subject, body,
+		n.Status, n.NextAttemptAt.UTC())
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// ClaimDueNotifications claims up to limit pending notifications that are
+// due, oldest first, by moving them claimTTL into the future, so that a
+// dispatcher that dies holding a claim hands it over once it expires
+func (s *Store) ClaimDueNotifications(ctx context.Context, now time.Time, claimTTL time.Duration, limit int) ([]models.Notification, error) {
+	var notifications []models.Notification
+	err := s.selectAll(ctx, "claim_due_notifications", &notifications,
+		`UPDATE notifications SET next_attempt_at = $1, updated_at = NOW()
+		WHERE id IN (
+			SELECT id FROM notifications
+			WHERE status = $2 AND next_attempt_at <= $3
+			ORDER BY next_attempt_at
+			LIMIT $4
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *`,
+		now.Add(claimTTL).UTC(), models.NotificationPending, now.UTC(), limit)
+	if err != nil {
+		return nil, err
+	}
+	return notifications, s.openNotifications(ctx, notifications)
+}
+
+// UpdateNotification records the outcome of a delivery attempt
+func (s *Store) UpdateNotification(ctx context.Context, n *models.Notification) error {
+	_, err := s.exec(ctx, "update_notification",
+		`UPDATE notifications
+		SET status = $2, attempts = $3, next_attempt_at = $4, last_error = $5, sent_at = $6, updated_at = NOW()
+		WHERE id = $1`,
+		n.ID, n.Status, n.Attempts, n.NextAttemptAt.UTC(), n.LastError, n.SentAt)
+	return err
+}
+
+// GetOrderNotifications returns the notifications of an order, oldest first
+func (s *Store) GetOrderNotifications(ctx context.Context, orderID int64) ([]models.Notification, error) {
+	notifications := []models.Notification{}
+	err := s.selectWithFailover(ctx, "get_order_notifications", &notifications,
+		"SELECT * FROM notifications WHERE order_id = $1 ORDER BY created_at, id", orderID)
+	if err != nil {
+		return nil, err
+	}
+	return notifications, s.openNotifications(ctx, notifications)
+}
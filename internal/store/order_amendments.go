@@ -0,0 +1,71 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+
+	"order-service/internal/apperrors"
+	"order-service/internal/models"
+)
+
+// AmendOrderItems sets the quantities of an order's items, deleting those set
+// to zero, and its new totals, fenced by the saga lock token like
+// UpdateOrderStatusFenced. The order row is locked first, so the amendment
+// and the order's first payment, which CreatePayment takes only at the
+// order's current total, never overlap: an order that is past RESERVED or
+// already has a payment is not amended.
+func (s *Store) AmendOrderItems(ctx context.Context, orderID, token int64, items []models.OrderItem, totalAmount, walletAmount int64) error {
+	return s.withRetry(ctx, "amend_order_items", func() error {
+		tx, err := s.db.BeginTxx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+
+		var current struct {
+			Status     string `db:"status"`
+			FenceToken int64  `db:"fence_token"`
+			Paid       bool   `db:"paid"`
+		}
+		err = s.getTx(ctx, tx, "lock_order_amendment", &current,
+			`SELECT status, fence_token, EXISTS(SELECT 1 FROM payments WHERE order_id = $1) AS paid
+			FROM orders WHERE id = $1 FOR UPDATE`, orderID)
+		if err == sql.ErrNoRows {
+			return apperrors.New(apperrors.ErrOrderNotFound, "order %d not found", orderID)
+		}
+		if err != nil {
+			return err
+		}
+		if current.FenceToken > token {
+			return ErrStaleFenceToken
+		}
+		if current.Status != models.OrderStatusCreated && current.Status != models.OrderStatusReserved {
+			return apperrors.New(apperrors.ErrInvalidOrderState, "order %d in status %s can no longer be edited", orderID, current.Status)
+		}
+		if current.Paid {
+			return apperrors.New(apperrors.ErrInvalidOrderState, "order %d can no longer be edited once payment started", orderID)
+		}
+
+		for _, item := range items {
+			if item.Quantity == 0 {
+				_, err = s.execTx(ctx, tx, "delete_order_item",
+					"DELETE FROM order_items WHERE id = $1 AND order_id = $2", item.ID, orderID)
+			} else {
+				_, err = s.execTx(ctx, tx, "amend_order_item",
+					"UPDATE order_items SET quantity = $1 WHERE id = $2 AND order_id = $3", item.Quantity, item.ID, orderID)
+			}
+			if err != nil {
+				return err
+			}
+		}
+
+		_, err = s.execTx(ctx, tx, "amend_order_totals",
+			`UPDATE orders SET total_amount = $1, wallet_amount = $2, fence_token = $3, updated_at = NOW()
+			WHERE id = $4`,
+			totalAmount, walletAmount, token, orderID)
+		if err != nil {
+			return err
+		}
+		return tx.Commit()
+	})
+}
@@ -0,0 +1,45 @@
+package store
+
+import (
+	"context"
+	"time"
+)
+
+// RefreshOrderSummary projects the current state of an order, its items and
+// latest payment into its summary, as of an event of eventType at eventAt.
+// The state is read whole rather than applied from the event, so redelivered
+// and reordered events leave the summary current; only the newest event is
+// recorded. An order that no longer exists is skipped.
+func (s *Store) RefreshOrderSummary(ctx context.Context, orderID int64, eventType string, eventAt time.Time) error {
+	_, err := s.exec(ctx, "refresh_order_summary",
+		`INSERT INTO order_summaries (order_id, user_id, status, item_count, total_quantity, total_amount,
+			wallet_amount, payment_method, payment_status, refunded_amount, risk_score, risk_band,
+			last_event_type, last_event_at, created_at, updated_at, tenant_id)
+		SELECT o.id, o.user_id, o.status, COALESCE(i.item_count, 0), COALESCE(i.total_quantity, 0), o.total_amount,
+			o.wallet_amount, o.payment_method, p.status, COALESCE(p.refunded_amount, 0), o.risk_score, o.risk_band,
+			$2, $3, o.created_at, NOW(), o.tenant_id
+		FROM orders o
+		LEFT JOIN LATERAL (
+			SELECT COUNT(*) AS item_count, SUM(quantity) AS total_quantity FROM order_items WHERE order_id = o.id
+		) i ON TRUE
+		LEFT JOIN LATERAL (
+			SELECT status, refunded_amount FROM payments WHERE order_id = o.id ORDER BY created_at DESC, id DESC LIMIT 1
+		) p ON TRUE
+		WHERE o.id = $1
+		ON CONFLICT (order_id) DO UPDATE SET
+			status = EXCLUDED.status,
+			item_count = EXCLUDED.item_count,
+			total_quantity = EXCLUDED.total_quantity,
+			total_amount = EXCLUDED.total_amount,
+			wallet_amount = EXCLUDED.wallet_amount,
+			payment_status = EXCLUDED.payment_status,
+			refunded_amount = EXCLUDED.refunded_amount,
+			risk_score = EXCLUDED.risk_score,
+			risk_band = EXCLUDED.risk_band,
+			last_event_type = CASE WHEN EXCLUDED.last_event_at >= order_summaries.last_event_at
+				THEN EXCLUDED.last_event_type ELSE order_summaries.last_event_type END,
+			last_event_at = GREATEST(EXCLUDED.last_event_at, order_summaries.last_event_at),
+			updated_at = NOW()`,
+		orderID, eventType, eventAt.UTC())
+	return err
+}
@@ -3,101 +3,616 @@ package store
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"time"
 
+	"order-service/internal/apperrors"
 	"order-service/internal/models"
+	"order-service/internal/tenant"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 )
 
-// CreateOrder creates a new order
+// pgForeignKeyViolation is raised when an item references a product deleted mid-request
+const pgForeignKeyViolation = "23503"
+
+// CreateOrder creates a new order and records its initial status in the history
 func (s *Store) CreateOrder(ctx context.Context, order *models.Order) error {
-	query := `
-		INSERT INTO orders (user_id, total_amount, status, idempotency_key)
-		VALUES ($1, $2, $3, $4)
-		RETURNING id, created_at, updated_at`
+	err := s.withRetry(ctx, "create_order", func() error {
+		tx, err := s.db.BeginTxx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+
+		if err := s.insertOrder(ctx, tx, order); err != nil {
+			return err
+		}
+		return tx.Commit()
+	})
+	if isUniqueViolation(err) {
+		return apperrors.Wrap(apperrors.ErrDuplicateOrder, err, "order with idempotency key %q already exists", order.IdempotencyKey)
+	}
+	return err
+}
+
+// CreateOrderWithItems creates an order with its items in one transaction.
+// With allowPartial each item is inserted under a savepoint, so an item whose
+// product no longer exists is rolled back alone, recorded as skipped and taken
+// off the order total; otherwise any failing item rolls back the whole order.
+func (s *Store) CreateOrderWithItems(ctx context.Context, order *models.Order, items []*models.OrderItem, allowPartial bool) ([]models.SkippedOrderItem, error) {
+	total, walletAmount := order.TotalAmount, order.WalletAmount
+	var skipped []models.SkippedOrderItem
+
+	err := s.withRetry(ctx, "create_order_with_items", func() error {
+		order.TotalAmount, order.WalletAmount = total, walletAmount
+		skipped = nil
+
+		tx, err := s.db.BeginTxx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+
+		if err := s.insertOrder(ctx, tx, order); err != nil {
+			return err
+		}
+
+		for i, item := range items {
+			item.OrderID = order.ID
+			savepoint := fmt.Sprintf("order_item_%d", i)
+			if allowPartial {
+				if _, err := tx.ExecContext(ctx, "SAVEPOINT "+savepoint); err != nil {
+					return err
+				}
+			}
+
+			err := s.insertOrderItem(ctx, tx, item)
+			if err == nil {
+				continue
+			}
+			if !isForeignKeyViolation(err) {
+				return fmt.Errorf("failed to create order item: %w", err)
+			}
+			if !allowPartial {
+				return apperrors.Wrap(apperrors.ErrProductNotFound, err, "product %d no longer exists", item.ProductID)
+			}
+
+			if _, err := tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+savepoint); err != nil {
+				return err
+			}
+			skipped = append(skipped, models.SkippedOrderItem{
+				OrderID:   order.ID,
+				ProductID: item.ProductID,
+				VariantID: item.VariantID,
+				Quantity:  item.Quantity,
+				Reason:    "product_not_found",
+			})
+			order.TotalAmount -= item.UnitPrice * int64(item.Quantity)
+		}
+
+		if len(skipped) == 0 {
+			return tx.Commit()
+		}
+		if len(skipped) == len(items) {
+			return apperrors.New(apperrors.ErrProductNotFound, "none of the ordered products exist any more")
+		}
+
+		for _, item := range skipped {
+			_, err := s.execTx(ctx, tx, "insert_skipped_item",
+				"INSERT INTO order_skipped_items (order_id, product_id, variant_id, quantity, reason) VALUES ($1, $2, $3, $4, $5)",
+				item.OrderID, item.ProductID, item.VariantID, item.Quantity, item.Reason)
+			if err != nil {
+				return fmt.Errorf("failed to record skipped item: %w", err)
+			}
+		}
+		// The wallet cannot pay more than what is left of the order
+		order.WalletAmount = min(order.WalletAmount, order.TotalAmount)
+		if _, err := s.execTx(ctx, tx, "update_order_total",
+			"UPDATE orders SET total_amount = $1, wallet_amount = $2 WHERE id = $3", order.TotalAmount, order.WalletAmount, order.ID); err != nil {
+			return fmt.Errorf("failed to update order total: %w", err)
+		}
+		return tx.Commit()
+	})
+	if isUniqueViolation(err) {
+		return nil, apperrors.Wrap(apperrors.ErrDuplicateOrder, err, "order with idempotency key %q already exists", order.IdempotencyKey)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return skipped, nil
+}
 
-	return s.db.GetContext(ctx, order, query,
-		order.UserID, order.TotalAmount, order.Status, order.IdempotencyKey)
+// insertOrder inserts an order and its initial status history entry
+func (s *Store) insertOrder(ctx context.Context, tx *sqlx.Tx, order *models.Order) error {
+	order.TenantID = tenant.Owner(ctx)
+	if order.Priority == "" {
+		order.Priority = models.PriorityStandard
+	}
+	email, err := s.sealOptional(ctx, order.CustomerEmail)
+	if err != nil {
+		return err
+	}
+	name, err := s.sealOptional(ctx, order.CustomerName)
+	if err != nil {
+		return err
+	}
+	err = s.getTx(ctx, tx, "insert_order", order, `
+		INSERT INTO orders (user_id, total_amount, status, idempotency_key, payment_method, expires_at, synthetic, price_list_id, risk_score, risk_band,
+			billing_company, billing_country, tax_id, wallet_amount, customer_email, customer_name, tenant_id, priority)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18)
+		RETURNING id, created_at, updated_at`,
+		order.UserID, order.TotalAmount, order.Status, order.IdempotencyKey,
+		order.PaymentMethod, order.ExpiresAt, order.Synthetic, order.PriceListID,
+		order.RiskScore, order.RiskBand,
+		order.BillingCompany, order.BillingCountry, order.TaxID, order.WalletAmount,
+		email, name, order.TenantID, order.Priority)
+	if err != nil {
+		return err
+	}
+
+	change := models.StatusChange{Reason: "order_created", Actor: models.ActorOrderService}
+	return s.insertStatusHistory(ctx, tx, order.ID, nil, order.Status, change)
+}
+
+// insertOrderItem inserts an order item, setting its ID
+func (s *Store) insertOrderItem(ctx context.Context, tx *sqlx.Tx, item *models.OrderItem) error {
+	return s.getTx(ctx, tx, "insert_order_item", &item.ID, insertOrderItemQuery,
+		item.OrderID, item.ProductID, item.VariantID, item.Quantity, item.UnitPrice)
+}
+
+// isForeignKeyViolation reports whether err is a foreign key violation
+func isForeignKeyViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == pgForeignKeyViolation
 }
 
-// GetOrderByID retrieves an order by ID
+// GetOrderByID retrieves an order by ID; another tenant's order is not found
 func (s *Store) GetOrderByID(ctx context.Context, id int64) (*models.Order, error) {
 	var order models.Order
-	err := s.db.GetContext(ctx, &order, "SELECT * FROM orders WHERE id = $1", id)
+	err := s.getWithFailover(ctx, "get_order", &order,
+		"SELECT * FROM orders WHERE id = $1 AND ($2 = '' OR tenant_id = $2)", id, tenantArg(ctx))
 	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("order not found: %d", id)
+		return nil, apperrors.New(apperrors.ErrOrderNotFound, "order %d not found", id)
 	}
 	if err != nil {
 		return nil, err
 	}
+	if err := s.open(ctx, order.CustomerEmail, order.CustomerName); err != nil {
+		return nil, err
+	}
 	return &order, nil
 }
 
-// GetOrderByIdempotencyKey retrieves an order by idempotency key
+// GetOrderTenant returns the tenant an order belongs to
+func (s *Store) GetOrderTenant(ctx context.Context, id int64) (string, error) {
+	var tenantID string
+	err := s.getWithFailover(ctx, "get_order_tenant", &tenantID,
+		"SELECT tenant_id FROM orders WHERE id = $1", id)
+	if err == sql.ErrNoRows {
+		return "", apperrors.New(apperrors.ErrOrderNotFound, "order %d not found", id)
+	}
+	return tenantID, err
+}
+
+// GetOrderByIdempotencyKey retrieves an order by idempotency key, which is
+// unique per tenant
 func (s *Store) GetOrderByIdempotencyKey(ctx context.Context, key string) (*models.Order, error) {
 	var order models.Order
-	err := s.db.GetContext(ctx, &order, "SELECT * FROM orders WHERE idempotency_key = $1", key)
+	err := s.getWithFailover(ctx, "get_order_by_idempotency_key", &order,
+		"SELECT * FROM orders WHERE idempotency_key = $1 AND tenant_id = $2", key, tenant.Owner(ctx))
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
 	if err != nil {
 		return nil, err
 	}
+	if err := s.open(ctx, order.CustomerEmail, order.CustomerName); err != nil {
+		return nil, err
+	}
 	return &order, nil
 }
 
-// UpdateOrderStatus updates order status
-func (s *Store) UpdateOrderStatus(ctx context.Context, orderID int64, status string) error {
-	_, err := s.db.ExecContext(ctx,
-		"UPDATE orders SET status = $1, updated_at = NOW() WHERE id = $2",
-		status, orderID)
+// stageTimestamps stamps the first entry into each SLA-tracked status ($1)
+const stageTimestamps = `
+	reserved_at = CASE WHEN $1 = 'RESERVED' THEN COALESCE(reserved_at, NOW()) ELSE reserved_at END,
+	paid_at = CASE WHEN $1 = 'PAID' THEN COALESCE(paid_at, NOW()) ELSE paid_at END,
+	confirmed_at = CASE WHEN $1 = 'CONFIRMED' THEN COALESCE(confirmed_at, NOW()) ELSE confirmed_at END`
+
+// UpdateOrderStatus updates order status and appends the transition to the history
+func (s *Store) UpdateOrderStatus(ctx context.Context, orderID int64, status string, change models.StatusChange) error {
+	return s.withRetry(ctx, "update_order_status", func() error {
+		return s.inTx(ctx, func(tx *sqlx.Tx) error {
+			var oldStatus string
+			err := s.getTx(ctx, tx, "lock_order_status", &oldStatus, "SELECT status FROM orders WHERE id = $1 FOR UPDATE", orderID)
+			if err == sql.ErrNoRows {
+				return apperrors.New(apperrors.ErrOrderNotFound, "order %d not found", orderID)
+			}
+			if err != nil {
+				return err
+			}
+			if err := checkTransition(orderID, oldStatus, status, change); err != nil {
+				return err
+			}
+
+			_, err = s.execTx(ctx, tx, "update_order_status",
+				"UPDATE orders SET status = $1, updated_at = NOW(),"+stageTimestamps+" WHERE id = $2",
+				status, orderID)
+			if err != nil {
+				return err
+			}
+
+			return s.insertStatusHistory(ctx, tx, orderID, &oldStatus, status, change)
+		})
+	})
+}
+
+// ErrStaleFenceToken is returned when a fenced write carries an older token than the last writer
+var ErrStaleFenceToken = errors.New("stale fencing token")
+
+// UpdateOrderStatusFenced updates order status only if token is not older than
+// the fencing token recorded by the previous lock holder, and appends the
+// transition to the history
+func (s *Store) UpdateOrderStatusFenced(ctx context.Context, orderID int64, status string, token int64, change models.StatusChange) error {
+	return s.withRetry(ctx, "update_order_status", func() error {
+		return s.inTx(ctx, func(tx *sqlx.Tx) error {
+			var current struct {
+				Status     string `db:"status"`
+				FenceToken int64  `db:"fence_token"`
+			}
+			err := s.getTx(ctx, tx, "lock_order_status_fenced", &current, "SELECT status, fence_token FROM orders WHERE id = $1 FOR UPDATE", orderID)
+			if err == sql.ErrNoRows {
+				return apperrors.New(apperrors.ErrOrderNotFound, "order %d not found", orderID)
+			}
+			if err != nil {
+				return err
+			}
+			if current.FenceToken > token {
+				return ErrStaleFenceToken
+			}
+			if err := checkTransition(orderID, current.Status, status, change); err != nil {
+				return err
+			}
+
+			_, err = s.execTx(ctx, tx, "update_order_status_fenced",
+				"UPDATE orders SET status = $1, fence_token = $2, updated_at = NOW(),"+stageTimestamps+" WHERE id = $3",
+				status, token, orderID)
+			if err != nil {
+				return err
+			}
+
+			return s.insertStatusHistory(ctx, tx, orderID, &current.Status, status, change)
+		})
+	})
+}
+
+// checkTransition rejects moving an order from one status to another the
+// order lifecycle does not allow, unless the change is forced
+func checkTransition(orderID int64, from, to string, change models.StatusChange) error {
+	if change.Forced || models.CanTransition(from, to) {
+		return nil
+	}
+	return apperrors.New(apperrors.ErrInvalidOrderState, "order %d cannot move from %s to %s", orderID, from, to)
+}
+
+// insertStatusHistory appends one transition to the order status history
+func (s *Store) insertStatusHistory(ctx context.Context, tx *sqlx.Tx, orderID int64, oldStatus *string, newStatus string, change models.StatusChange) error {
+	var eventID *string
+	if change.EventID != "" {
+		eventID = &change.EventID
+	}
+
+	_, err := s.execTx(ctx, tx, "insert_status_history",
+		`INSERT INTO order_status_history (order_id, old_status, new_status, reason, actor, event_id)
+		 VALUES ($1, $2, $3, $4, $5, $6)`,
+		orderID, oldStatus, newStatus, change.Reason, change.Actor, eventID)
+	if err != nil {
+		return fmt.Errorf("failed to record status history: %w", err)
+	}
+	return nil
+}
+
+// GetOrderStatusHistory returns the status transitions of an order, oldest first
+func (s *Store) GetOrderStatusHistory(ctx context.Context, orderID int64) ([]models.OrderStatusHistory, error) {
+	history := []models.OrderStatusHistory{}
+	err := s.selectWithFailover(ctx, "get_order_status_history", &history,
+		"SELECT * FROM order_status_history WHERE order_id = $1 ORDER BY id", orderID)
+	return history, err
+}
+
+// ListOrders retrieves the summaries of orders created in [filter.Since,
+// filter.Until), riskiest first, optionally restricted to one risk band. A
+// zero Until means now.
+func (s *Store) ListOrders(ctx context.Context, filter models.OrderFilter) ([]models.OrderSummary, error) {
+	until := filter.Until
+	if until.IsZero() {
+		until = time.Now()
+	}
+
+	orders := []models.OrderSummary{}
+	err := s.selectWithFailover(ctx, "list_orders", &orders,
+		`SELECT * FROM order_summaries
+		WHERE created_at >= $1 AND created_at < $2 AND ($3 = '' OR risk_band = $3)
+		AND ($5 = '' OR tenant_id = $5)
+		ORDER BY risk_score DESC NULLS LAST, order_id DESC
+		LIMIT $4`,
+		filter.Since.UTC(), until.UTC(), filter.RiskBand, filter.Limit, tenantArg(ctx))
+	return orders, err
+}
+
+// MarkOrderSLABreached flags an order as having breached a lifecycle SLA
+func (s *Store) MarkOrderSLABreached(ctx context.Context, orderID int64) error {
+	_, err := s.exec(ctx, "mark_order_sla_breached", "UPDATE orders SET sla_breached = TRUE WHERE id = $1", orderID)
+	return err
+}
+
+// SetOrderInvoice records where the invoice of an order is stored
+func (s *Store) SetOrderInvoice(ctx context.Context, orderID int64, url string) error {
+	_, err := s.exec(ctx, "set_order_invoice",
+		"UPDATE orders SET invoice_url = $1, invoiced_at = NOW() WHERE id = $2", url, orderID)
+	return err
+}
+
+// AssignInvoiceNumber numbers the invoice of an order with the next number of
+// series, e.g. INV-2026-000042, unless it was numbered before, and returns the
+// number. The counter of the series moves in the transaction that records
+// the number on the order, so the numbers of a series have no gaps.
+func (s *Store) AssignInvoiceNumber(ctx context.Context, orderID int64, series string) (string, error) {
+	var number string
+	err := s.inTx(ctx, func(tx *sqlx.Tx) error {
+		var assigned sql.NullString
+		err := s.getTx(ctx, tx, "lock_order_invoice_number", &assigned,
+			"SELECT invoice_number FROM orders WHERE id = $1 FOR UPDATE", orderID)
+		if err == sql.ErrNoRows {
+			return apperrors.New(apperrors.ErrOrderNotFound, "order %d not found", orderID)
+		}
+		if err != nil {
+			return err
+		}
+		if assigned.Valid {
+			number = assigned.String
+			return nil
+		}
+
+		var next int64
+		if err := s.getTx(ctx, tx, "next_invoice_number", &next,
+			`INSERT INTO invoice_counters (series, last_number) VALUES ($1, 1)
+			ON CONFLICT (series) DO UPDATE SET last_number = invoice_counters.last_number + 1
+			RETURNING last_number`, series); err != nil {
+			return err
+		}
+		number = fmt.Sprintf("%s-%06d", series, next)
+		_, err = s.execTx(ctx, tx, "set_order_invoice_number",
+			"UPDATE orders SET invoice_number = $1 WHERE id = $2", number, orderID)
+		return err
+	})
+	return number, err
+}
+
+// GetExpiredOrders retrieves unpaid orders whose payment deadline has passed
+func (s *Store) GetExpiredOrders(ctx context.Context, now time.Time, limit int) ([]models.Order, error) {
+	var orders []models.Order
+	err := s.selectWithFailover(ctx, "get_expired_orders", &orders,
+		`SELECT * FROM orders
+		WHERE status IN ($1, $2) AND expires_at IS NOT NULL AND expires_at < $3
+		ORDER BY expires_at
+		LIMIT $4`,
+		models.OrderStatusCreated, models.OrderStatusReserved, now.UTC(), limit)
+	if err != nil {
+		return nil, err
+	}
+	return orders, s.openOrders(ctx, orders)
+}
+
+// GetStalledOrders retrieves in-flight orders that have not moved, nor been
+// nudged by saga recovery, since before
+func (s *Store) GetStalledOrders(ctx context.Context, before time.Time, limit int) ([]models.Order, error) {
+	var orders []models.Order
+	err := s.selectWithFailover(ctx, "get_stalled_orders", &orders,
+		`SELECT * FROM orders
+		WHERE status IN ($1, $2, $3) AND COALESCE(last_recovery_at, updated_at) < $4
+		ORDER BY updated_at
+		LIMIT $5`,
+		models.OrderStatusCreated, models.OrderStatusReserved, models.OrderStatusPaid, before.UTC(), limit)
+	if err != nil {
+		return nil, err
+	}
+	return orders, s.openOrders(ctx, orders)
+}
+
+// MarkOrderRecoveryAttempt records that saga recovery acted on an order
+func (s *Store) MarkOrderRecoveryAttempt(ctx context.Context, orderID int64) error {
+	_, err := s.exec(ctx, "mark_order_recovery_attempt",
+		"UPDATE orders SET recovery_attempts = recovery_attempts + 1, last_recovery_at = NOW() WHERE id = $1",
+		orderID)
+	return err
+}
+
+// CountOrdersByStatus counts orders in each of the given statuses
+func (s *Store) CountOrdersByStatus(ctx context.Context, statuses []string) (map[string]int, error) {
+	var rows []struct {
+		Status string `db:"status"`
+		Count  int    `db:"count"`
+	}
+	err := s.selectWithFailover(ctx, "count_orders_by_status", &rows,
+		"SELECT status, COUNT(*) AS count FROM orders WHERE status = ANY($1) GROUP BY status",
+		pq.Array(statuses))
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int, len(statuses))
+	for _, status := range statuses {
+		counts[status] = 0
+	}
+	for _, row := range rows {
+		counts[row.Status] = row.Count
+	}
+	return counts, nil
+}
+
+// DeleteOrder deletes an order together with its items and payments
+func (s *Store) DeleteOrder(ctx context.Context, orderID int64) error {
+	_, err := s.exec(ctx, "delete_order", "DELETE FROM orders WHERE id = $1", orderID)
 	return err
 }
 
 // GetOrdersByUserID retrieves orders for a user
 func (s *Store) GetOrdersByUserID(ctx context.Context, userID int64) ([]models.Order, error) {
 	var orders []models.Order
-	err := s.db.SelectContext(ctx, &orders,
-		"SELECT * FROM orders WHERE user_id = $1 ORDER BY created_at DESC", userID)
-	return orders, err
+	err := s.selectWithFailover(ctx, "get_orders_by_user", &orders,
+		"SELECT * FROM orders WHERE user_id = $1 AND ($2 = '' OR tenant_id = $2) ORDER BY created_at DESC",
+		userID, tenantArg(ctx))
+	if err != nil {
+		return nil, err
+	}
+	return orders, s.openOrders(ctx, orders)
+}
+
+// GetOrdersByIDs retrieves the orders with the given IDs in ID order. Unknown
+// IDs and other tenants' orders are left out.
+func (s *Store) GetOrdersByIDs(ctx context.Context, ids []int64) ([]models.Order, error) {
+	if len(ids) == 0 {
+		return []models.Order{}, nil
+	}
+
+	query, args, err := sqlx.In("SELECT * FROM orders WHERE id IN (?) AND (? = '' OR tenant_id = ?) ORDER BY id",
+		ids, tenantArg(ctx), tenantArg(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	var orders []models.Order
+	err = s.selectWithFailover(ctx, "get_orders_by_ids", &orders, s.db.Rebind(query), args...)
+	if err != nil {
+		return nil, err
+	}
+	return orders, s.openOrders(ctx, orders)
 }
 
+// GetOrdersWithItems retrieves the orders with the given IDs and their items
+// in two queries, in ID order. Unknown IDs are left out.
+func (s *Store) GetOrdersWithItems(ctx context.Context, ids []int64) ([]models.OrderWithItems, error) {
+	if len(ids) == 0 {
+		return []models.OrderWithItems{}, nil
+	}
+
+	orders, err := s.GetOrdersByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	if len(orders) == 0 {
+		return []models.OrderWithItems{}, nil
+	}
+
+	orderIDs := make([]int64, len(orders))
+	for i, order := range orders {
+		orderIDs[i] = order.ID
+	}
+	items, err := s.GetOrderItemsByOrderIDs(ctx, orderIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	itemsByOrder := make(map[int64][]models.OrderItem, len(orders))
+	for _, item := range items {
+		itemsByOrder[item.OrderID] = append(itemsByOrder[item.OrderID], item)
+	}
+	result := make([]models.OrderWithItems, len(orders))
+	for i, order := range orders {
+		result[i] = models.OrderWithItems{Order: order, Items: itemsByOrder[order.ID]}
+		if result[i].Items == nil {
+			result[i].Items = []models.OrderItem{}
+		}
+	}
+	return result, nil
+}
+
+const insertOrderItemQuery = `
+	INSERT INTO order_items (order_id, product_id, variant_id, quantity, unit_price)
+	VALUES ($1, $2, $3, $4, $5)
+	RETURNING id`
+
 // CreateOrderItem creates a new order item
 func (s *Store) CreateOrderItem(ctx context.Context, item *models.OrderItem) error {
-	query := `
-		INSERT INTO order_items (order_id, product_id, quantity, unit_price)
-		VALUES ($1, $2, $3, $4)
-		RETURNING id`
-
-	return s.db.GetContext(ctx, &item.ID, query,
-		item.OrderID, item.ProductID, item.Quantity, item.UnitPrice)
+	return s.get(ctx, "create_order_item", &item.ID, insertOrderItemQuery,
+		item.OrderID, item.ProductID, item.VariantID, item.Quantity, item.UnitPrice)
 }
 
 // GetOrderItemsByOrderID retrieves all items for an order
 func (s *Store) GetOrderItemsByOrderID(ctx context.Context, orderID int64) ([]models.OrderItem, error) {
 	var items []models.OrderItem
-	err := s.db.SelectContext(ctx, &items,
+	err := s.selectWithFailover(ctx, "get_order_items", &items,
 		"SELECT * FROM order_items WHERE order_id = $1", orderID)
 	return items, err
 }
 
-// CreatePayment creates a new payment record
+// GetOrderItemsByOrderIDs retrieves the items of several orders, by order
+func (s *Store) GetOrderItemsByOrderIDs(ctx context.Context, orderIDs []int64) ([]models.OrderItem, error) {
+	if len(orderIDs) == 0 {
+		return []models.OrderItem{}, nil
+	}
+
+	query, args, err := sqlx.In("SELECT * FROM order_items WHERE order_id IN (?) ORDER BY order_id, id", orderIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	var items []models.OrderItem
+	err = s.selectWithFailover(ctx, "get_order_items_by_order_ids", &items, s.db.Rebind(query), args...)
+	return items, err
+}
+
+// GetSkippedOrderItems retrieves the items left out of an order created with allow_partial
+func (s *Store) GetSkippedOrderItems(ctx context.Context, orderID int64) ([]models.SkippedOrderItem, error) {
+	var items []models.SkippedOrderItem
+	err := s.selectWithFailover(ctx, "get_skipped_order_items", &items,
+		"SELECT * FROM order_skipped_items WHERE order_id = $1 ORDER BY id", orderID)
+	return items, err
+}
+
+// CreatePayment creates a new payment record. It is only created while the
+// order is RESERVED and still totals the payment's amounts, under a share
+// lock of the order row: a payment requested before an amendment changed them
+// is refused with ErrOrderAmended, and one for an order cancelled or paid
+// meanwhile with ErrInvalidOrderState, rather than charged.
 func (s *Store) CreatePayment(ctx context.Context, payment *models.Payment) error {
 	query := `
-		INSERT INTO payments (order_id, status, provider_tx_id, amount)
-		VALUES ($1, $2, $3, $4)
+		INSERT INTO payments (order_id, status, provider_tx_id, amount, wallet_amount)
+		SELECT id, $2, $3, $4, $5 FROM orders
+		WHERE id = $1 AND status = $6 AND total_amount = $4 AND wallet_amount = $5
+		FOR SHARE
 		RETURNING id, created_at, updated_at`
 
-	return s.db.GetContext(ctx, payment, query,
-		payment.OrderID, payment.Status, payment.ProviderTxID, payment.Amount)
+	err := s.get(ctx, "create_payment", payment, query,
+		payment.OrderID, payment.Status, payment.ProviderTxID, payment.Amount, payment.WalletAmount, models.OrderStatusReserved)
+	if err != sql.ErrNoRows {
+		return err
+	}
+
+	var status string
+	err = s.get(ctx, "get_order_status", &status, "SELECT status FROM orders WHERE id = $1", payment.OrderID)
+	if err == sql.ErrNoRows {
+		return apperrors.New(apperrors.ErrOrderNotFound, "order %d not found", payment.OrderID)
+	}
+	if err != nil {
+		return err
+	}
+	if status != models.OrderStatusReserved {
+		return apperrors.New(apperrors.ErrInvalidOrderState, "order %d is %s, not awaiting payment", payment.OrderID, status)
+	}
+	return apperrors.New(apperrors.ErrOrderAmended, "order %d no longer totals %d", payment.OrderID, payment.Amount)
 }
 
 // GetPaymentByOrderID retrieves payment for an order
 func (s *Store) GetPaymentByOrderID(ctx context.Context, orderID int64) (*models.Payment, error) {
 	var payment models.Payment
-	err := s.db.GetContext(ctx, &payment,
+	err := s.getWithFailover(ctx, "get_payment", &payment,
 		"SELECT * FROM payments WHERE order_id = $1 ORDER BY created_at DESC LIMIT 1", orderID)
 	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("payment not found for order: %d", orderID)
+		return nil, apperrors.New(apperrors.ErrNotFound, "payment not found for order %d", orderID)
 	}
 	if err != nil {
 		return nil, err
@@ -105,26 +620,75 @@ func (s *Store) GetPaymentByOrderID(ctx context.Context, orderID int64) (*models
 	return &payment, nil
 }
 
+// GetPaymentsByOrderID retrieves every payment attempt of an order, oldest first
+func (s *Store) GetPaymentsByOrderID(ctx context.Context, orderID int64) ([]models.Payment, error) {
+	var payments []models.Payment
+	err := s.selectWithFailover(ctx, "get_payments", &payments,
+		"SELECT * FROM payments WHERE order_id = $1 ORDER BY created_at, id", orderID)
+	return payments, err
+}
+
+// GetPaymentsByOrderIDs retrieves the payments of several orders, by order
+func (s *Store) GetPaymentsByOrderIDs(ctx context.Context, orderIDs []int64) ([]models.Payment, error) {
+	if len(orderIDs) == 0 {
+		return []models.Payment{}, nil
+	}
+
+	query, args, err := sqlx.In("SELECT * FROM payments WHERE order_id IN (?) ORDER BY order_id, created_at, id", orderIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	var payments []models.Payment
+	err = s.selectWithFailover(ctx, "get_payments_by_order_ids", &payments, s.db.Rebind(query), args...)
+	return payments, err
+}
+
 // UpdatePaymentStatus updates payment status
 func (s *Store) UpdatePaymentStatus(ctx context.Context, paymentID int64, status, providerTxID string) error {
-	_, err := s.db.ExecContext(ctx,
+	_, err := s.exec(ctx, "update_payment_status",
 		"UPDATE payments SET status = $1, provider_tx_id = $2, updated_at = NOW() WHERE id = $3",
 		status, providerTxID, paymentID)
 	return err
 }
 
+// RefundPayment marks a captured payment refunded with the amount given back
+func (s *Store) RefundPayment(ctx context.Context, paymentID, refunded int64) error {
+	_, err := s.exec(ctx, "refund_payment",
+		"UPDATE payments SET status = $1, refunded_amount = $2, updated_at = NOW() WHERE id = $3",
+		models.PaymentStatusRefunded, refunded, paymentID)
+	return err
+}
+
+// SetOrderCancellation records the cancellation policy applied to an order
+// and the fee it kept
+func (s *Store) SetOrderCancellation(ctx context.Context, orderID int64, policy string, fee int64) error {
+	_, err := s.exec(ctx, "set_order_cancellation",
+		"UPDATE orders SET cancellation_policy = $1, cancellation_fee = $2, updated_at = NOW() WHERE id = $3",
+		policy, fee, orderID)
+	return err
+}
+
 // IsEventProcessed checks if an event has been processed
 func (s *Store) IsEventProcessed(ctx context.Context, eventID string) (bool, error) {
 	var exists bool
-	err := s.db.GetContext(ctx, &exists,
+	err := s.getWithFailover(ctx, "is_event_processed", &exists,
 		"SELECT EXISTS(SELECT 1 FROM processed_events WHERE event_id = $1)", eventID)
 	return exists, err
 }
 
 // MarkEventProcessed marks an event as processed
 func (s *Store) MarkEventProcessed(ctx context.Context, eventID, eventType string) error {
-	_, err := s.db.ExecContext(ctx,
+	_, err := s.exec(ctx, "mark_event_processed",
 		"INSERT INTO processed_events (event_id, event_type) VALUES ($1, $2) ON CONFLICT (event_id) DO NOTHING",
 		eventID, eventType)
 	return err
 }
+
+// UnmarkEventProcessed forgets that an event was processed, so it is handled
+// again when redelivered
+func (s *Store) UnmarkEventProcessed(ctx context.Context, eventID string) error {
+	_, err := s.exec(ctx, "unmark_event_processed",
+		"DELETE FROM processed_events WHERE event_id = $1", eventID)
+	return err
+}
@@ -0,0 +1,81 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"order-service/internal/models"
+)
+
+// CountPaymentAttempts counts the declined payment attempts of an order
+func (s *Store) CountPaymentAttempts(ctx context.Context, orderID int64) (int, error) {
+	var count int
+	err := s.get(ctx, "count_payment_attempts", &count,
+		"SELECT COUNT(*) FROM payment_attempts WHERE order_id = $1", orderID)
+	return count, err
+}
+
+// CreatePaymentAttempt records a declined payment attempt
+func (s *Store) CreatePaymentAttempt(ctx context.Context, attempt *models.PaymentAttempt) error {
+	return s.get(ctx, "create_payment_attempt", attempt,
+		`INSERT INTO payment_attempts (order_id, payment_id, attempt, amount, decline_reason, decline_class, status, retry_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, created_at, updated_at`,
+		attempt.OrderID, attempt.PaymentID, attempt.Attempt, attempt.Amount,
+		attempt.DeclineReason, attempt.DeclineClass, attempt.Status, attempt.RetryAt)
+}
+
+// DropStalePaymentRetries drops the scheduled retries of orders no longer
+// awaiting payment, e.g. cancelled at their payment deadline. Returns the
+// number of retries dropped.
+func (s *Store) DropStalePaymentRetries(ctx context.Context) (int64, error) {
+	res, err := s.exec(ctx, "drop_stale_payment_retries",
+		`UPDATE payment_attempts pa SET status = $1, updated_at = NOW()
+		FROM orders o
+		WHERE o.id = pa.order_id AND pa.status = $2 AND o.status <> $3`,
+		models.PaymentAttemptDropped, models.PaymentAttemptScheduled, models.OrderStatusReserved)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// HasScheduledPaymentRetry reports whether the payment of an order is waiting
+// on a scheduled retry
+func (s *Store) HasScheduledPaymentRetry(ctx context.Context, orderID int64) (bool, error) {
+	var scheduled bool
+	err := s.get(ctx, "has_scheduled_payment_retry", &scheduled,
+		"SELECT EXISTS (SELECT 1 FROM payment_attempts WHERE order_id = $1 AND status = $2)",
+		orderID, models.PaymentAttemptScheduled)
+	return scheduled, err
+}
+
+// ClaimDuePaymentRetries claims up to limit scheduled retries that are due, of
+// orders still awaiting payment, by moving them claimTTL into the future, so
+// that a retrier that dies holding a claim hands it over once it expires.
+// Claimed retries carry the user and wallet amount of their order.
+func (s *Store) ClaimDuePaymentRetries(ctx context.Context, now time.Time, claimTTL time.Duration, limit int) ([]models.PaymentAttempt, error) {
+	var attempts []models.PaymentAttempt
+	err := s.selectAll(ctx, "claim_due_payment_retries", &attempts,
+		`UPDATE payment_attempts pa SET retry_at = $1, updated_at = NOW()
+		FROM orders o
+		WHERE o.id = pa.order_id AND pa.id IN (
+			SELECT id FROM payment_attempts
+			WHERE status = $2 AND retry_at <= $3
+				AND EXISTS (SELECT 1 FROM orders o WHERE o.id = payment_attempts.order_id AND o.status = $5)
+			ORDER BY retry_at
+			LIMIT $4
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING pa.*, o.user_id, o.wallet_amount`,
+		now.Add(claimTTL).UTC(), models.PaymentAttemptScheduled, now.UTC(), limit, models.OrderStatusReserved)
+	return attempts, err
+}
+
+// SetPaymentAttemptStatus sets the status of a payment attempt
+func (s *Store) SetPaymentAttemptStatus(ctx context.Context, attemptID int64, status string) error {
+	_, err := s.exec(ctx, "set_payment_attempt_status",
+		"UPDATE payment_attempts SET status = $1, updated_at = NOW() WHERE id = $2",
+		status, attemptID)
+	return err
+}
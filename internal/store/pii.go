@@ -0,0 +1,184 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"order-service/internal/models"
+	"order-service/internal/pii"
+)
+
+// SetPIICipher encrypts personal data columns (customer contact details, the
+// customer on orders, notification recipients and bodies) with c. Values
+// stored before are read as they are until re-encrypted.
+func (s *Store) SetPIICipher(c *pii.Cipher) {
+	s.pii = c
+}
+
+// seal encrypts a personal data value for storage
+func (s *Store) seal(ctx context.Context, value string) (string, error) {
+	if s.pii == nil {
+		return value, nil
+	}
+	sealed, err := s.pii.Seal(ctx, value)
+	if err != nil {
+		return "", fmt.Errorf("failed to encrypt personal data: %w", err)
+	}
+	return sealed, nil
+}
+
+// sealOptional encrypts a nullable personal data value for storage
+func (s *Store) sealOptional(ctx context.Context, value *string) (*string, error) {
+	if value == nil {
+		return nil, nil
+	}
+	sealed, err := s.seal(ctx, *value)
+	return &sealed, err
+}
+
+// open decrypts personal data values read from the database in place
+func (s *Store) open(ctx context.Context, values ...*string) error {
+	if s.pii == nil {
+		return nil
+	}
+	for _, value := range values {
+		if value == nil {
+			continue
+		}
+		opened, err := s.pii.Open(ctx, *value)
+		if err != nil {
+			return fmt.Errorf("failed to decrypt personal data: %w", err)
+		}
+		*value = opened
+	}
+	return nil
+}
+
+func (s *Store) openOrders(ctx context.Context, orders []models.Order) error {
+	for i := range orders {
+		if err := s.open(ctx, orders[i].CustomerEmail, orders[i].CustomerName); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Store) openNotifications(ctx context.Context, notifications []models.Notification) error {
+	for i := range notifications {
+		if err := s.open(ctx, &notifications[i].Recipient, &notifications[i].Body); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// piiTables lists the personal data columns of each table, at most four
+var piiTables = []struct {
+	table   string
+	key     string
+	columns []string
+}{
+	{"customers", "user_id", []string{"email", "name", "phone", "webhook_url"}},
+	{"orders", "id", []string{"customer_email", "customer_name"}},
+	{"notifications", "id", []string{"recipient", "body"}},
+}
+
+// piiRow is a row of a table in piiTables, its missing columns NULL
+type piiRow struct {
+	Key    int64          `db:"key"`
+	Value0 sql.NullString `db:"value0"`
+	Value1 sql.NullString `db:"value1"`
+	Value2 sql.NullString `db:"value2"`
+	Value3 sql.NullString `db:"value3"`
+}
+
+func (r *piiRow) values() []*sql.NullString {
+	return []*sql.NullString{&r.Value0, &r.Value1, &r.Value2, &r.Value3}
+}
+
+// ReencryptPII encrypts again every personal data value stored in plain text
+// or under a key other than the active one, batchSize rows at a time, and
+// returns how many rows of each table it rewrote. A row changed meanwhile is
+// skipped; it was written under the active key.
+func (s *Store) ReencryptPII(ctx context.Context, batchSize int) (map[string]int, error) {
+	if s.pii == nil {
+		return nil, errors.New("personal data encryption is not configured")
+	}
+
+	rewritten := make(map[string]int, len(piiTables))
+	for _, t := range piiTables {
+		selects := make([]string, 4)
+		sets := make([]string, len(t.columns))
+		guards := make([]string, len(t.columns))
+		for i := range selects {
+			selects[i] = fmt.Sprintf("NULL::text AS value%d", i)
+			if i < len(t.columns) {
+				selects[i] = fmt.Sprintf("%s AS value%d", t.columns[i], i)
+				sets[i] = fmt.Sprintf("%s = $%d", t.columns[i], i+2)
+				guards[i] = fmt.Sprintf("%s IS NOT DISTINCT FROM $%d", t.columns[i], len(t.columns)+i+2)
+			}
+		}
+		selectQuery := fmt.Sprintf("SELECT %s AS key, %s FROM %s WHERE %s > $1 ORDER BY %s LIMIT $2",
+			t.key, strings.Join(selects, ", "), t.table, t.key, t.key)
+		updateQuery := fmt.Sprintf("UPDATE %s SET %s WHERE %s = $1 AND %s",
+			t.table, strings.Join(sets, ", "), t.key, strings.Join(guards, " AND "))
+
+		var after int64
+		for {
+			var rows []piiRow
+			if err := s.selectAll(ctx, "select_pii_"+t.table, &rows, selectQuery, after, batchSize); err != nil {
+				return rewritten, err
+			}
+			if len(rows) == 0 {
+				break
+			}
+			after = rows[len(rows)-1].Key
+
+			for _, row := range rows {
+				args, stale, err := s.resealRow(ctx, row, len(t.columns))
+				if err != nil {
+					return rewritten, fmt.Errorf("%s %d: %w", t.table, row.Key, err)
+				}
+				if !stale {
+					continue
+				}
+				res, err := s.exec(ctx, "reencrypt_pii_"+t.table, updateQuery, args...)
+				if err != nil {
+					return rewritten, err
+				}
+				if n, _ := res.RowsAffected(); n > 0 {
+					rewritten[t.table]++
+				}
+			}
+		}
+	}
+	return rewritten, nil
+}
+
+// resealRow returns the update arguments of a row (key, new values, old
+// values) and whether any of its first n values is stale
+func (s *Store) resealRow(ctx context.Context, row piiRow, n int) ([]interface{}, bool, error) {
+	args := make([]interface{}, 1+2*n)
+	args[0] = row.Key
+	stale := false
+	for i, value := range row.values()[:n] {
+		args[1+i], args[1+n+i] = *value, *value
+		if !value.Valid || !s.pii.Stale(value.String) {
+			continue
+		}
+		stale = true
+		opened, err := s.pii.Open(ctx, value.String)
+		if err != nil {
+			return nil, false, err
+		}
+		sealed, err := s.pii.Seal(ctx, opened)
+		if err != nil {
+			return nil, false, err
+		}
+		args[1+i] = sealed
+	}
+	return args, stale, nil
+}
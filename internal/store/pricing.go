@@ -0,0 +1,67 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"order-service/internal/models"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// GetUserCustomerGroup returns the customer group of a user, or "" for retail customers
+func (s *Store) GetUserCustomerGroup(ctx context.Context, userID int64) (string, error) {
+	var group string
+	err := s.getWithFailover(ctx, "get_user_customer_group", &group,
+		"SELECT customer_group FROM user_customer_groups WHERE user_id = $1", userID)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return group, err
+}
+
+// GetActivePriceList returns the price list in effect for a customer group at a point in time
+func (s *Store) GetActivePriceList(ctx context.Context, customerGroup string, at time.Time) (*models.PriceList, error) {
+	var list models.PriceList
+	err := s.getWithFailover(ctx, "get_active_price_list", &list, `
+		SELECT * FROM price_lists
+		WHERE customer_group = $1 AND active
+		  AND (valid_from IS NULL OR valid_from <= $2)
+		  AND (valid_to IS NULL OR valid_to > $2)
+		ORDER BY valid_from DESC NULLS LAST, id DESC
+		LIMIT 1`, customerGroup, at.UTC())
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &list, nil
+}
+
+// GetPriceListPrices returns contract prices for the given products, keyed by product ID
+func (s *Store) GetPriceListPrices(ctx context.Context, priceListID int64, productIDs []int64) (map[int64]int64, error) {
+	prices := make(map[int64]int64)
+	if len(productIDs) == 0 {
+		return prices, nil
+	}
+
+	query, args, err := sqlx.In(
+		"SELECT * FROM price_list_items WHERE price_list_id = ? AND product_id IN (?)",
+		priceListID, productIDs)
+	if err != nil {
+		return nil, err
+	}
+	query = s.db.Rebind(query)
+
+	var items []models.PriceListItem
+	if err := s.selectWithFailover(ctx, "get_price_list_prices", &items, query, args...); err != nil {
+		return nil, err
+	}
+
+	for _, item := range items {
+		prices[item.ProductID] = item.Price
+	}
+	return prices, nil
+}
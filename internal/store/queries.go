@@ -0,0 +1,186 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"order-service/internal/util"
+
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	// maxPreparedStatements bounds the statement cache of each pool; queries
+	// built at runtime, e.g. IN lists, run unprepared once it is full
+	maxPreparedStatements = 256
+	defaultSlowQuery      = 200 * time.Millisecond
+)
+
+// statementCache holds the prepared statements of a connection pool by query.
+// database/sql prepares a statement again on each connection it runs on.
+type statementCache struct {
+	mu    sync.Mutex
+	stmts map[*sqlx.DB]map[string]*sqlx.Stmt
+}
+
+// SetQueryOptions sets whether queries run as prepared statements and the
+// latency above which a query is logged as slow; zero disables the log.
+// Prepared statements must be off behind a transaction-pooling PgBouncer.
+func (s *Store) SetQueryOptions(prepare bool, slowQuery time.Duration) {
+	s.prepare = prepare
+	s.slowQuery = slowQuery
+}
+
+// statement returns the cached prepared statement of query on db, preparing
+// it on first use. Returns nil if the query should run unprepared.
+func (s *Store) statement(ctx context.Context, db *sqlx.DB, query string) *sqlx.Stmt {
+	if !s.prepare || db == nil {
+		return nil
+	}
+
+	s.stmts.mu.Lock()
+	defer s.stmts.mu.Unlock()
+	if s.stmts.stmts == nil {
+		s.stmts.stmts = make(map[*sqlx.DB]map[string]*sqlx.Stmt)
+	}
+	cached := s.stmts.stmts[db]
+	if stmt, ok := cached[query]; ok {
+		return stmt
+	}
+	if len(cached) >= maxPreparedStatements {
+		return nil
+	}
+
+	stmt, err := db.PreparexContext(ctx, query)
+	if err != nil {
+		// The query reports the error when it runs unprepared
+		return nil
+	}
+	if cached == nil {
+		cached = make(map[string]*sqlx.Stmt)
+		s.stmts.stmts[db] = cached
+	}
+	cached[query] = stmt
+	return stmt
+}
+
+// closeStatements closes the prepared statements of every pool
+func (s *Store) closeStatements() {
+	s.stmts.mu.Lock()
+	defer s.stmts.mu.Unlock()
+	for _, cached := range s.stmts.stmts {
+		for _, stmt := range cached {
+			stmt.Close()
+		}
+	}
+	s.stmts.stmts = nil
+}
+
+// observeQuery records the latency and outcome of the query called name. A
+// missing row is an answer, not an error.
+func (s *Store) observeQuery(name string, start time.Time, err error) {
+	elapsed := time.Since(start)
+	util.DBQueryDuration.WithLabelValues(name).Observe(elapsed.Seconds())
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		util.DBQueryErrorsTotal.WithLabelValues(name).Inc()
+	}
+	if s.slowQuery > 0 && elapsed >= s.slowQuery {
+		util.DBSlowQueriesTotal.WithLabelValues(name).Inc()
+		log.Printf("Slow query %s took %s", name, elapsed.Round(time.Millisecond))
+	}
+}
+
+// getOn is GetContext on db for the query called name
+func (s *Store) getOn(ctx context.Context, db *sqlx.DB, name string, dest interface{}, query string, args ...interface{}) (err error) {
+	start := time.Now()
+	defer func() { s.observeQuery(name, start, err) }()
+	if stmt := s.statement(ctx, db, query); stmt != nil {
+		return stmt.GetContext(ctx, dest, args...)
+	}
+	return db.GetContext(ctx, dest, query, args...)
+}
+
+// selectOn is SelectContext on db for the query called name
+func (s *Store) selectOn(ctx context.Context, db *sqlx.DB, name string, dest interface{}, query string, args ...interface{}) (err error) {
+	start := time.Now()
+	defer func() { s.observeQuery(name, start, err) }()
+	if stmt := s.statement(ctx, db, query); stmt != nil {
+		return stmt.SelectContext(ctx, dest, args...)
+	}
+	return db.SelectContext(ctx, dest, query, args...)
+}
+
+// get is GetContext on the primary for the query called name, in the
+// transaction of WithTx if ctx carries one
+func (s *Store) get(ctx context.Context, name string, dest interface{}, query string, args ...interface{}) error {
+	if state := txFrom(ctx); state != nil {
+		return s.getTx(ctx, state.tx, name, dest, query, args...)
+	}
+	return s.getOn(ctx, s.db, name, dest, query, args...)
+}
+
+// selectAll is SelectContext on the primary for the query called name, in
+// the transaction of WithTx if ctx carries one
+func (s *Store) selectAll(ctx context.Context, name string, dest interface{}, query string, args ...interface{}) error {
+	if state := txFrom(ctx); state != nil {
+		return s.selectTx(ctx, state.tx, name, dest, query, args...)
+	}
+	return s.selectOn(ctx, s.db, name, dest, query, args...)
+}
+
+// exec is ExecContext on the primary for the statement called name, in the
+// transaction of WithTx if ctx carries one
+func (s *Store) exec(ctx context.Context, name string, query string, args ...interface{}) (res sql.Result, err error) {
+	if state := txFrom(ctx); state != nil {
+		return s.execTx(ctx, state.tx, name, query, args...)
+	}
+	start := time.Now()
+	defer func() { s.observeQuery(name, start, err) }()
+	if stmt := s.statement(ctx, s.db, query); stmt != nil {
+		return stmt.ExecContext(ctx, args...)
+	}
+	return s.db.ExecContext(ctx, query, args...)
+}
+
+// txStatement binds the cached prepared statement of query to tx, or returns
+// nil if the query should run unprepared
+func (s *Store) txStatement(ctx context.Context, tx *sqlx.Tx, query string) *sqlx.Stmt {
+	if stmt := s.statement(ctx, s.db, query); stmt != nil {
+		return tx.StmtxContext(ctx, stmt)
+	}
+	return nil
+}
+
+// getTx is GetContext in tx for the query called name
+func (s *Store) getTx(ctx context.Context, tx *sqlx.Tx, name string, dest interface{}, query string, args ...interface{}) (err error) {
+	start := time.Now()
+	defer func() { s.observeQuery(name, start, err) }()
+	if stmt := s.txStatement(ctx, tx, query); stmt != nil {
+		return stmt.GetContext(ctx, dest, args...)
+	}
+	return tx.GetContext(ctx, dest, query, args...)
+}
+
+// selectTx is SelectContext in tx for the query called name
+func (s *Store) selectTx(ctx context.Context, tx *sqlx.Tx, name string, dest interface{}, query string, args ...interface{}) (err error) {
+	start := time.Now()
+	defer func() { s.observeQuery(name, start, err) }()
+	if stmt := s.txStatement(ctx, tx, query); stmt != nil {
+		return stmt.SelectContext(ctx, dest, args...)
+	}
+	return tx.SelectContext(ctx, dest, query, args...)
+}
+
+// execTx is ExecContext in tx for the statement called name
+func (s *Store) execTx(ctx context.Context, tx *sqlx.Tx, name string, query string, args ...interface{}) (res sql.Result, err error) {
+	start := time.Now()
+	defer func() { s.observeQuery(name, start, err) }()
+	if stmt := s.txStatement(ctx, tx, query); stmt != nil {
+		return stmt.ExecContext(ctx, args...)
+	}
+	return tx.ExecContext(ctx, query, args...)
+}
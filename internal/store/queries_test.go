@@ -0,0 +1,44 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"order-service/internal/util"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestObserveQueryCountsErrorsButNotMissingRows(t *testing.T) {
+	s := &Store{slowQuery: time.Hour}
+	errors0 := testutil.ToFloat64(util.DBQueryErrorsTotal.WithLabelValues("test_observe"))
+
+	s.observeQuery("test_observe", time.Now(), sql.ErrNoRows)
+	s.observeQuery("test_observe", time.Now(), nil)
+	s.observeQuery("test_observe", time.Now(), errors.New("connection refused"))
+
+	assert.Equal(t, errors0+1, testutil.ToFloat64(util.DBQueryErrorsTotal.WithLabelValues("test_observe")))
+	assert.Zero(t, testutil.ToFloat64(util.DBSlowQueriesTotal.WithLabelValues("test_observe")))
+}
+
+func TestObserveQueryCountsSlowQueries(t *testing.T) {
+	s := &Store{slowQuery: time.Millisecond}
+	s.observeQuery("test_slow", time.Now().Add(-time.Second), nil)
+	assert.Equal(t, float64(1), testutil.ToFloat64(util.DBSlowQueriesTotal.WithLabelValues("test_slow")))
+
+	// Zero disables the slow query log
+	s.SetQueryOptions(false, 0)
+	s.observeQuery("test_slow", time.Now().Add(-time.Second), nil)
+	assert.Equal(t, float64(1), testutil.ToFloat64(util.DBSlowQueriesTotal.WithLabelValues("test_slow")))
+}
+
+func TestStatementIsNilWhenPreparingIsOff(t *testing.T) {
+	s := &Store{}
+	s.SetQueryOptions(false, defaultSlowQuery)
+	assert.Nil(t, s.statement(context.Background(), &sqlx.DB{}, "SELECT 1"))
+}
@@ -0,0 +1,85 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"sync/atomic"
+
+	"order-service/internal/util"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+type replicaReadsKey struct{}
+
+// replicaSession tracks the replica reads of one request. After a read falls
+// back to the primary, the rest of the request reads from the primary so it
+// does not see older data than it already has.
+type replicaSession struct {
+	fellBack atomic.Bool
+}
+
+// WithReplicaReads marks ctx as a read-only request whose reads may be served
+// by a replica, which can lag the primary by the replication delay
+func WithReplicaReads(ctx context.Context) context.Context {
+	return context.WithValue(ctx, replicaReadsKey{}, &replicaSession{})
+}
+
+// replicaFor returns the replica to serve a read made with ctx, or nil when it
+// must go to the primary
+func (s *Store) replicaFor(ctx context.Context) (*sqlx.DB, *replicaSession) {
+	session, _ := ctx.Value(replicaReadsKey{}).(*replicaSession)
+	if session == nil || session.fellBack.Load() || len(s.replicas) == 0 {
+		return nil, nil
+	}
+	n := s.nextReplica.Add(1)
+	return s.replicas[int(n)%len(s.replicas)], session
+}
+
+// readFromReplica runs read on a replica when ctx allows it. It reports
+// whether the replica served the read; any replica error, including a missing
+// row that may not have replicated yet, leaves the read to the primary.
+func (s *Store) readFromReplica(ctx context.Context, operation string, read func(db *sqlx.DB) error) (bool, error) {
+	if txFrom(ctx) != nil {
+		// Reads in a transaction see its own writes
+		return false, nil
+	}
+	replica, session := s.replicaFor(ctx)
+	if replica == nil {
+		return false, nil
+	}
+
+	err := read(replica)
+	if err == nil {
+		util.StoreReplicaReadsTotal.WithLabelValues(operation, "replica").Inc()
+		return true, nil
+	}
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return false, ctxErr
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		log.Printf("Read replica failed %s, reading from the primary: %v", operation, err)
+	}
+	session.fellBack.Store(true)
+	util.StoreReplicaReadsTotal.WithLabelValues(operation, "fallback").Inc()
+	return false, nil
+}
+
+// RegisterPoolMetrics registers connection pool metrics for the primary and
+// each replica, labeled db_name primary and replica-N
+func (s *Store) RegisterPoolMetrics(reg prometheus.Registerer) error {
+	if err := reg.Register(collectors.NewDBStatsCollector(s.db.DB, "primary")); err != nil {
+		return err
+	}
+	for i, replica := range s.replicas {
+		if err := reg.Register(collectors.NewDBStatsCollector(replica.DB, fmt.Sprintf("replica-%d", i+1))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"order-service/internal/models"
+)
+
+// GetOrderReport aggregates the UTC days in [from, to) of orders: created,
+// paid (sales), cancelled and declined payment attempts. Every day of the
+// range has a row, oldest first; from and to are truncated to the day. Only
+// the orders of ctx's tenant are counted, or every tenant's without one.
+func (s *Store) GetOrderReport(ctx context.Context, from, to time.Time) ([]models.OrderReportRow, error) {
+	from = from.UTC().Truncate(24 * time.Hour)
+	to = to.UTC().Truncate(24 * time.Hour)
+
+	rows := []models.OrderReportRow{}
+	err := s.selectWithFailover(ctx, "get_order_report", &rows,
+		`WITH days AS (
+			SELECT d::date AS day FROM generate_series($1::date, $2::date - 1, interval '1 day') d
+		), created AS (
+			SELECT created_at::date AS day, COUNT(*) AS n
+			FROM orders
+			WHERE created_at >= $1 AND created_at < $2 AND NOT synthetic AND ($4 = '' OR tenant_id = $4)
+			GROUP BY 1
+		), paid AS (
+			SELECT o.paid_at::date AS day, COUNT(*) AS n, SUM(o.total_amount) AS amount, SUM(i.units) AS units
+			FROM orders o
+			LEFT JOIN LATERAL (SELECT SUM(quantity) AS units FROM order_items WHERE order_id = o.id) i ON TRUE
+			WHERE o.paid_at >= $1 AND o.paid_at < $2 AND NOT o.synthetic AND ($4 = '' OR o.tenant_id = $4)
+			GROUP BY 1
+		), cancelled AS (
+			SELECT h.created_at::date AS day, COUNT(*) AS n, SUM(COALESCE(o.cancellation_fee, 0)) AS fees
+			FROM order_status_history h JOIN orders o ON o.id = h.order_id
+			WHERE h.new_status = $3 AND h.created_at >= $1 AND h.created_at < $2 AND NOT o.synthetic
+				AND ($4 = '' OR o.tenant_id = $4)
+			GROUP BY 1
+		), declines AS (
+			SELECT a.created_at::date AS day, COUNT(*) AS n, COUNT(*) FILTER (WHERE a.status = 'FINAL') AS final,
+				SUM(a.amount) AS amount
+			FROM payment_attempts a JOIN orders o ON o.id = a.order_id
+			WHERE a.created_at >= $1 AND a.created_at < $2 AND NOT o.synthetic AND ($4 = '' OR o.tenant_id = $4)
+			GROUP BY 1
+		)
+		SELECT to_char(d.day, 'YYYY-MM-DD') AS date,
+			COALESCE(c.n, 0) AS orders_created,
+			COALESCE(p.n, 0) AS orders_paid,
+			COALESCE(p.amount, 0) AS gross_sales,
+			COALESCE(p.units, 0) AS units_sold,
+			COALESCE(x.n, 0) AS orders_cancelled,
+			COALESCE(x.fees, 0) AS cancellation_fees,
+			COALESCE(f.n, 0) AS payment_declines,
+			COALESCE(f.final, 0) AS payment_declines_final,
+			COALESCE(f.amount, 0) AS declined_amount
+		FROM days d
+		LEFT JOIN created c ON c.day = d.day
+		LEFT JOIN paid p ON p.day = d.day
+		LEFT JOIN cancelled x ON x.day = d.day
+		LEFT JOIN declines f ON f.day = d.day
+		ORDER BY d.day`,
+		from, to, models.OrderStatusCancelled, tenantArg(ctx))
+	return rows, err
+}
@@ -0,0 +1,282 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"order-service/internal/models"
+)
+
+//go:generate mockery --name=OrderRepository --output=mocks --outpkg=mocks
+//go:generate mockery --name=InventoryRepository --output=mocks --outpkg=mocks
+//go:generate mockery --name=PaymentRepository --output=mocks --outpkg=mocks
+//go:generate mockery --name=PricingRepository --output=mocks --outpkg=mocks
+//go:generate mockery --name=AnonymizationRepository --output=mocks --outpkg=mocks
+//go:generate mockery --name=DeadLetterRepository --output=mocks --outpkg=mocks
+//go:generate mockery --name=TimelineRepository --output=mocks --outpkg=mocks
+//go:generate mockery --name=DropRepository --output=mocks --outpkg=mocks
+//go:generate mockery --name=IncomingStockRepository --output=mocks --outpkg=mocks
+//go:generate mockery --name=StatusChangeRepository --output=mocks --outpkg=mocks
+//go:generate mockery --name=WalletRepository --output=mocks --outpkg=mocks
+//go:generate mockery --name=CompensationRepository --output=mocks --outpkg=mocks
+//go:generate mockery --name=CustomerRepository --output=mocks --outpkg=mocks
+//go:generate mockery --name=NotificationRepository --output=mocks --outpkg=mocks
+//go:generate mockery --name=WebhookRepository --output=mocks --outpkg=mocks
+//go:generate mockery --name=CartRepository --output=mocks --outpkg=mocks
+//go:generate mockery --name=ReportRepository --output=mocks --outpkg=mocks
+//go:generate mockery --name=AuditRepository --output=mocks --outpkg=mocks
+//go:generate mockery --name=InvoiceRepository --output=mocks --outpkg=mocks
+
+// OrderRepository persists orders, order items and processed saga events
+type OrderRepository interface {
+	WithTx(ctx context.Context, fn func(ctx context.Context) error) error
+	CreateOrder(ctx context.Context, order *models.Order) error
+	GetOrderByID(ctx context.Context, id int64) (*models.Order, error)
+	GetOrderByIdempotencyKey(ctx context.Context, key string) (*models.Order, error)
+	UpdateOrderStatus(ctx context.Context, orderID int64, status string, change models.StatusChange) error
+	UpdateOrderStatusFenced(ctx context.Context, orderID int64, status string, token int64, change models.StatusChange) error
+	GetOrderStatusHistory(ctx context.Context, orderID int64) ([]models.OrderStatusHistory, error)
+	MarkOrderSLABreached(ctx context.Context, orderID int64) error
+	GetExpiredOrders(ctx context.Context, now time.Time, limit int) ([]models.Order, error)
+	CountOrdersByStatus(ctx context.Context, statuses []string) (map[string]int, error)
+	GetStalledOrders(ctx context.Context, before time.Time, limit int) ([]models.Order, error)
+	MarkOrderRecoveryAttempt(ctx context.Context, orderID int64) error
+	DeleteOrder(ctx context.Context, orderID int64) error
+	GetOrdersByUserID(ctx context.Context, userID int64) ([]models.Order, error)
+	GetOrdersByIDs(ctx context.Context, ids []int64) ([]models.Order, error)
+	GetOrdersWithItems(ctx context.Context, ids []int64) ([]models.OrderWithItems, error)
+	ListOrders(ctx context.Context, filter models.OrderFilter) ([]models.OrderSummary, error)
+	SearchOrders(ctx context.Context, query string, limit int) ([]models.OrderSearchResult, error)
+	RefreshOrderSummary(ctx context.Context, orderID int64, eventType string, eventAt time.Time) error
+	CreateOrderItem(ctx context.Context, item *models.OrderItem) error
+	CreateOrderWithItems(ctx context.Context, order *models.Order, items []*models.OrderItem, allowPartial bool) ([]models.SkippedOrderItem, error)
+	GetOrderItemsByOrderID(ctx context.Context, orderID int64) ([]models.OrderItem, error)
+	GetOrderItemsByOrderIDs(ctx context.Context, orderIDs []int64) ([]models.OrderItem, error)
+	GetSkippedOrderItems(ctx context.Context, orderID int64) ([]models.SkippedOrderItem, error)
+	MarkOrderItemsBackordered(ctx context.Context, orderID int64, variantIDs []int64) error
+	GetBackorderedItems(ctx context.Context, afterID int64, limit int) ([]models.OrderItem, error)
+	MarkOrderItemAllocated(ctx context.Context, itemID int64) (bool, error)
+	IsEventProcessed(ctx context.Context, eventID string) (bool, error)
+	MarkEventProcessed(ctx context.Context, eventID, eventType string) error
+	UnmarkEventProcessed(ctx context.Context, eventID string) error
+	SetOrderCancellation(ctx context.Context, orderID int64, policy string, fee int64) error
+	AmendOrderItems(ctx context.Context, orderID, token int64, items []models.OrderItem, totalAmount, walletAmount int64) error
+}
+
+// InventoryRepository reads the product catalog and moves stock of product variants
+type InventoryRepository interface {
+	WithTx(ctx context.Context, fn func(ctx context.Context) error) error
+	GetProductByID(ctx context.Context, id int64) (*models.Product, error)
+	GetProductBySKU(ctx context.Context, sku string) (*models.Product, error)
+	GetProducts(ctx context.Context) ([]models.Product, error)
+	GetProductsByIDs(ctx context.Context, ids []int64) ([]models.Product, error)
+	GetVariantByID(ctx context.Context, id int64) (*models.ProductVariant, error)
+	GetVariantBySKU(ctx context.Context, sku string) (*models.ProductVariant, error)
+	GetVariantsByProductIDs(ctx context.Context, productIDs []int64) ([]models.ProductVariant, error)
+	GetInventory(ctx context.Context, variantID int64) (*models.Inventory, error)
+	GetInventories(ctx context.Context) ([]models.Inventory, error)
+	GetWarehouseStock(ctx context.Context, variantID int64) ([]models.Inventory, error)
+	GetWarehouses(ctx context.Context) ([]models.Warehouse, error)
+	GetLowStockItems(ctx context.Context) ([]models.LowStockItem, error)
+	SetReorderThreshold(ctx context.Context, productID int64, threshold *int) error
+	UpsertWarehouse(ctx context.Context, w *models.Warehouse) error
+	ReserveStockTx(ctx context.Context, variantID, warehouseID int64, quantity int) error
+	ReleaseStock(ctx context.Context, variantID, warehouseID int64, quantity int) error
+	CommitStock(ctx context.Context, variantID, warehouseID int64, quantity int) error
+	CommitOrderItemStock(ctx context.Context, item models.OrderItem) (bool, error)
+	HoldReservation(ctx context.Context, orderID, productID, variantID int64, quantity int) (bool, error)
+	AllocateReservation(ctx context.Context, orderID, variantID, warehouseID int64) error
+	DeleteReservation(ctx context.Context, orderID, variantID int64) error
+	ReleaseReservation(ctx context.Context, orderID, variantID int64) (*models.Reservation, error)
+	ResizeReservation(ctx context.Context, orderID, variantID int64, quantity int) (*models.Reservation, error)
+	GetOrderReservations(ctx context.Context, orderID int64) ([]models.Reservation, error)
+	RestockInventory(ctx context.Context, variantID, warehouseID int64, quantity int) error
+	ReplaceInventory(ctx context.Context, variantID, warehouseID int64, seenAvailable, seenReserved, available, reserved int) (bool, error)
+	ImportInventoryRows(ctx context.Context, rows []models.InventoryImportRow) ([]models.Inventory, []error, error)
+}
+
+// PaymentRepository persists payment attempts
+type PaymentRepository interface {
+	CreatePayment(ctx context.Context, payment *models.Payment) error
+	GetPaymentByOrderID(ctx context.Context, orderID int64) (*models.Payment, error)
+	GetPaymentsByOrderID(ctx context.Context, orderID int64) ([]models.Payment, error)
+	GetPaymentsByOrderIDs(ctx context.Context, orderIDs []int64) ([]models.Payment, error)
+	UpdatePaymentStatus(ctx context.Context, paymentID int64, status, providerTxID string) error
+	RefundPayment(ctx context.Context, paymentID, refunded int64) error
+	CountPaymentAttempts(ctx context.Context, orderID int64) (int, error)
+	CreatePaymentAttempt(ctx context.Context, attempt *models.PaymentAttempt) error
+	HasScheduledPaymentRetry(ctx context.Context, orderID int64) (bool, error)
+	DropStalePaymentRetries(ctx context.Context) (int64, error)
+	ClaimDuePaymentRetries(ctx context.Context, now time.Time, claimTTL time.Duration, limit int) ([]models.PaymentAttempt, error)
+	SetPaymentAttemptStatus(ctx context.Context, attemptID int64, status string) error
+}
+
+// PricingRepository reads customer groups and contract price lists
+type PricingRepository interface {
+	GetUserCustomerGroup(ctx context.Context, userID int64) (string, error)
+	GetActivePriceList(ctx context.Context, customerGroup string, at time.Time) (*models.PriceList, error)
+	GetPriceListPrices(ctx context.Context, priceListID int64, productIDs []int64) (map[int64]int64, error)
+}
+
+// AnonymizationRepository anonymizes the order history of deleted users
+type AnonymizationRepository interface {
+	IsEventProcessed(ctx context.Context, eventID string) (bool, error)
+	MarkEventProcessed(ctx context.Context, eventID, eventType string) error
+	CreateAnonymizationJob(ctx context.Context, eventID string, userID int64) (*models.AnonymizationJob, error)
+	AnonymizeUserOrders(ctx context.Context, userID int64, retainAfter time.Time, limit int) (anonymized, deleted int, err error)
+	UpdateAnonymizationJobProgress(ctx context.Context, jobID int64, anonymized, deleted int) (*models.AnonymizationJob, error)
+	CompleteAnonymizationJob(ctx context.Context, jobID int64) error
+}
+
+// DeadLetterRepository parks messages whose consumer handler failed and
+// records their re-drives
+type DeadLetterRepository interface {
+	CreateDeadLetter(ctx context.Context, letter *models.DeadLetter) error
+	ListDeadLetters(ctx context.Context, filter models.DeadLetterFilter) ([]models.DeadLetter, error)
+	ClaimDeadLetterRedrive(ctx context.Context, redrive *models.DeadLetterRedrive) (bool, error)
+	ReleaseDeadLetterRedrive(ctx context.Context, redrive *models.DeadLetterRedrive) error
+	GetDeadLetterRedrives(ctx context.Context, deadLetterID int64) ([]models.DeadLetterRedrive, error)
+}
+
+// TimelineRepository stores the per-order event timeline
+type TimelineRepository interface {
+	AddTimelineEntry(ctx context.Context, entry *models.OrderTimelineEntry) error
+	GetOrderTimeline(ctx context.Context, orderID int64) ([]models.OrderTimelineEntry, error)
+	GetOrdersMissingEvents(ctx context.Context, since, before time.Time, afterID int64, limit int) ([]models.Order, error)
+}
+
+// DropRepository stores scheduled product drops and their registrations
+type DropRepository interface {
+	CreateDrop(ctx context.Context, drop *models.Drop) error
+	GetDrop(ctx context.Context, id int64) (*models.Drop, error)
+	ClaimDueDrop(ctx context.Context, now time.Time, claimTTL time.Duration, seed int64) (*models.Drop, error)
+	RenewDropClaim(ctx context.Context, id int64, now time.Time) error
+	CompleteDrop(ctx context.Context, id int64) error
+	RegisterForDrop(ctx context.Context, reg *models.DropRegistration) (bool, error)
+	GetDropRegistration(ctx context.Context, dropID, userID int64) (*models.DropRegistration, error)
+	GetPendingDropRegistrations(ctx context.Context, dropID int64) ([]models.DropRegistration, error)
+	CountDropRegistrations(ctx context.Context, dropID int64) (map[string]int, error)
+	FinishDropRegistration(ctx context.Context, reg *models.DropRegistration) error
+}
+
+// IncomingStockRepository stores stock expected from suppliers and plans
+// backordered items against it
+type IncomingStockRepository interface {
+	CreateIncomingStock(ctx context.Context, incoming *models.IncomingStock) error
+	GetIncomingStock(ctx context.Context, id int64) (*models.IncomingStock, error)
+	GetOpenIncomingStock(ctx context.Context, variantID int64) ([]models.IncomingStock, error)
+	CountBackorderedUnits(ctx context.Context, variantID int64) (int, error)
+	UpdateIncomingStock(ctx context.Context, incoming *models.IncomingStock) (bool, error)
+	ReceiveIncomingStock(ctx context.Context, id int64) (*models.Inventory, error)
+	PlanIncomingStock(ctx context.Context, variantID int64) ([]models.OrderItem, error)
+}
+
+// StatusChangeRepository captures committed order status changes
+type StatusChangeRepository interface {
+	ListenStatusChanges(ctx context.Context) (<-chan struct{}, error)
+	ForwardStatusChanges(
+		ctx context.Context,
+		consumer string,
+		limit int,
+		forward func(ctx context.Context, changes []models.OrderStatusHistory) error,
+	) (int, error)
+}
+
+// WalletRepository persists the store credit of users
+type WalletRepository interface {
+	GetWallet(ctx context.Context, userID int64) (*models.Wallet, error)
+	ApplyWalletTransaction(ctx context.Context, txn *models.WalletTransaction, version int64) (bool, error)
+	GetPaymentWalletDebit(ctx context.Context, paymentID int64) (*models.WalletTransaction, error)
+	GetWalletTransactions(ctx context.Context, userID int64, limit int) ([]models.WalletTransaction, error)
+	GetPaymentTenant(ctx context.Context, paymentID int64) (string, error)
+}
+
+// CompensationRepository persists failed compensation steps awaiting a retry
+// or manual resolution
+type CompensationRepository interface {
+	CreateCompensation(ctx context.Context, c *models.Compensation) (bool, error)
+	ClaimDueCompensations(ctx context.Context, now time.Time, claimTTL time.Duration, limit int) ([]models.Compensation, error)
+	UpdateCompensation(ctx context.Context, c *models.Compensation) error
+	GetCompensation(ctx context.Context, id int64) (*models.Compensation, error)
+	ListCompensations(ctx context.Context, filter models.CompensationFilter) ([]models.Compensation, error)
+	CountCompensations(ctx context.Context, status string) (int, error)
+}
+
+// CustomerRepository persists the projection of identity service users
+type CustomerRepository interface {
+	UpsertCustomer(ctx context.Context, c *models.Customer) (bool, error)
+	GetCustomer(ctx context.Context, userID int64) (*models.Customer, error)
+	CloseCustomer(ctx context.Context, userID int64, at time.Time) error
+}
+
+// NotificationRepository persists the notifications sent to customers about
+// their orders
+type NotificationRepository interface {
+	CreateNotification(ctx context.Context, n *models.Notification) (bool, error)
+	ClaimDueNotifications(ctx context.Context, now time.Time, claimTTL time.Duration, limit int) ([]models.Notification, error)
+	UpdateNotification(ctx context.Context, n *models.Notification) error
+	GetOrderNotifications(ctx context.Context, orderID int64) ([]models.Notification, error)
+}
+
+// WebhookRepository persists the webhook subscriptions of third parties and
+// the deliveries of events to them
+type WebhookRepository interface {
+	CreateWebhookSubscription(ctx context.Context, sub *models.WebhookSubscription) error
+	GetWebhookSubscription(ctx context.Context, id int64) (*models.WebhookSubscription, error)
+	ListWebhookSubscriptions(ctx context.Context, status string) ([]models.WebhookSubscription, error)
+	EnableWebhookSubscription(ctx context.Context, id int64) error
+	DeleteWebhookSubscription(ctx context.Context, id int64) error
+	CreateWebhookDelivery(ctx context.Context, d *models.WebhookDelivery) (bool, error)
+	ClaimDueWebhookDeliveries(ctx context.Context, now time.Time, claimTTL time.Duration, limit int) ([]models.WebhookDelivery, error)
+	RecordWebhookAttempt(ctx context.Context, d *models.WebhookDelivery, succeeded bool, disableAfter int) (bool, error)
+	ListWebhookDeliveries(ctx context.Context, subscriptionID int64, limit int) ([]models.WebhookDelivery, error)
+	GetOrderTenant(ctx context.Context, orderID int64) (string, error)
+}
+
+// CartRepository persists shopping carts and their items
+type CartRepository interface {
+	CreateCart(ctx context.Context, cart *models.Cart) error
+	GetCart(ctx context.Context, id string) (*models.Cart, error)
+	AddCartItem(ctx context.Context, item *models.CartItem, expiresAt time.Time) error
+	SetCartItemQuantity(ctx context.Context, cartID string, variantID int64, quantity int, expiresAt time.Time) error
+	CheckOutCart(ctx context.Context, cartID string, orderID int64) (bool, error)
+	DeleteExpiredCarts(ctx context.Context, before time.Time, limit int) (int64, error)
+}
+
+// ReportRepository aggregates orders for reporting
+type ReportRepository interface {
+	GetOrderReport(ctx context.Context, from, to time.Time) ([]models.OrderReportRow, error)
+}
+
+// AuditRepository stores the audit log of admin mutations
+type AuditRepository interface {
+	CreateAuditEntry(ctx context.Context, entry *models.AuditEntry) error
+	ListAuditEntries(ctx context.Context, filter models.AuditFilter) ([]models.AuditEntry, error)
+}
+
+// InvoiceRepository records the invoices of orders
+type InvoiceRepository interface {
+	AssignInvoiceNumber(ctx context.Context, orderID int64, series string) (string, error)
+	SetOrderInvoice(ctx context.Context, orderID int64, url string) error
+}
+
+var (
+	_ OrderRepository         = (*Store)(nil)
+	_ InventoryRepository     = (*Store)(nil)
+	_ PaymentRepository       = (*Store)(nil)
+	_ PricingRepository       = (*Store)(nil)
+	_ AnonymizationRepository = (*Store)(nil)
+	_ DeadLetterRepository    = (*Store)(nil)
+	_ TimelineRepository      = (*Store)(nil)
+	_ DropRepository          = (*Store)(nil)
+	_ IncomingStockRepository = (*Store)(nil)
+	_ StatusChangeRepository  = (*Store)(nil)
+	_ WalletRepository        = (*Store)(nil)
+	_ CompensationRepository  = (*Store)(nil)
+	_ CustomerRepository      = (*Store)(nil)
+	_ NotificationRepository  = (*Store)(nil)
+	_ WebhookRepository       = (*Store)(nil)
+	_ CartRepository          = (*Store)(nil)
+	_ ReportRepository        = (*Store)(nil)
+	_ AuditRepository         = (*Store)(nil)
+)
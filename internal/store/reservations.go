@@ -0,0 +1,169 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"order-service/internal/models"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// HoldReservation records that quantity units of a product variant are held
+// for an order. Returns false if the order already has a reservation for the
+// variant, so a replayed reserve never holds stock twice.
+func (s *Store) HoldReservation(ctx context.Context, orderID, productID, variantID int64, quantity int) (bool, error) {
+	var held bool
+	err := s.withRetry(ctx, "hold_reservation", func() error {
+		res, err := s.exec(ctx, "hold_reservation",
+			`INSERT INTO reservations (order_id, product_id, variant_id, quantity, status)
+			VALUES ($1, $2, $3, $4, $5)
+			ON CONFLICT (order_id, variant_id) DO NOTHING`,
+			orderID, productID, variantID, quantity, models.ReservationStatusHeld)
+		if err != nil {
+			return err
+		}
+		rows, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		held = rows > 0
+		return nil
+	})
+	return held, err
+}
+
+// AllocateReservation records the warehouse a held reservation's stock was
+// reserved in, on the reservation and on the order item it ships
+func (s *Store) AllocateReservation(ctx context.Context, orderID, variantID, warehouseID int64) error {
+	return s.withRetry(ctx, "allocate_reservation", func() error {
+		_, err := s.exec(ctx, "allocate_reservation",
+			`WITH allocated AS (
+				UPDATE reservations SET warehouse_id = $3, updated_at = NOW()
+				WHERE order_id = $1 AND variant_id = $2 AND status = $4
+				RETURNING order_id, variant_id
+			)
+			UPDATE order_items oi SET warehouse_id = $3
+			FROM allocated a
+			WHERE oi.order_id = a.order_id AND oi.variant_id = a.variant_id`,
+			orderID, variantID, warehouseID, models.ReservationStatusHeld)
+		return err
+	})
+}
+
+// DeleteReservation forgets a hold whose stock could not be reserved
+func (s *Store) DeleteReservation(ctx context.Context, orderID, variantID int64) error {
+	_, err := s.exec(ctx, "delete_reservation",
+		"DELETE FROM reservations WHERE order_id = $1 AND variant_id = $2 AND status = $3",
+		orderID, variantID, models.ReservationStatusHeld)
+	return err
+}
+
+// ReleaseReservation marks a held or committed reservation released and gives
+// its units back to available stock of its warehouse in the same transaction:
+// held units leave reserved, committed units are restocked. A reservation
+// never allocated to a warehouse holds no stock to give back. Returns the
+// reservation as it was before the release, or nil if there was nothing left
+// to release, so running a compensation twice never returns stock twice.
+func (s *Store) ReleaseReservation(ctx context.Context, orderID, variantID int64) (*models.Reservation, error) {
+	var released *models.Reservation
+	err := s.withRetry(ctx, "release_reservation", func() error {
+		released = nil
+		return s.inTx(ctx, func(tx *sqlx.Tx) error {
+			var reservation models.Reservation
+			err := s.getTx(ctx, tx, "lock_reservation", &reservation,
+				`SELECT * FROM reservations
+			WHERE order_id = $1 AND variant_id = $2 AND status IN ($3, $4)
+			FOR UPDATE`,
+				orderID, variantID, models.ReservationStatusHeld, models.ReservationStatusCommitted)
+			if errors.Is(err, sql.ErrNoRows) {
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("failed to lock reservation: %w", err)
+			}
+
+			_, err = s.execTx(ctx, tx, "release_reservation",
+				"UPDATE reservations SET status = $1, updated_at = NOW() WHERE order_id = $2 AND variant_id = $3",
+				models.ReservationStatusReleased, orderID, variantID)
+			if err != nil {
+				return fmt.Errorf("failed to release reservation: %w", err)
+			}
+
+			if reservation.WarehouseID != nil {
+				inventoryUpdate := `UPDATE inventory SET available = available + $1, reserved = reserved - $1, updated_at = NOW()
+				WHERE variant_id = $2 AND warehouse_id = $3`
+				if reservation.Status == models.ReservationStatusCommitted {
+					inventoryUpdate = `UPDATE inventory SET available = available + $1, updated_at = NOW()
+					WHERE variant_id = $2 AND warehouse_id = $3`
+				}
+				_, err := s.execTx(ctx, tx, "release_reserved_stock", inventoryUpdate,
+					reservation.Quantity, variantID, *reservation.WarehouseID)
+				if err != nil {
+					return fmt.Errorf("failed to release stock: %w", err)
+				}
+			}
+
+			released = &reservation
+			return nil
+		})
+	})
+	return released, err
+}
+
+// GetOrderReservations returns the stock reservations of an order
+func (s *Store) GetOrderReservations(ctx context.Context, orderID int64) ([]models.Reservation, error) {
+	reservations := []models.Reservation{}
+	err := s.selectWithFailover(ctx, "get_order_reservations", &reservations,
+		"SELECT * FROM reservations WHERE order_id = $1 ORDER BY product_id, variant_id", orderID)
+	return reservations, err
+}
+
+// ResizeReservation sets the quantity of a held reservation. Units it no
+// longer holds leave reserved for available stock of its warehouse in the
+// same transaction; units it gains must already have been reserved in it.
+// Returns the reservation as it was before, or nil if the order holds no
+// reservation of the variant.
+func (s *Store) ResizeReservation(ctx context.Context, orderID, variantID int64, quantity int) (*models.Reservation, error) {
+	var resized *models.Reservation
+	err := s.withRetry(ctx, "resize_reservation", func() error {
+		resized = nil
+		return s.inTx(ctx, func(tx *sqlx.Tx) error {
+			var reservation models.Reservation
+			err := s.getTx(ctx, tx, "lock_reservation", &reservation,
+				`SELECT * FROM reservations
+			WHERE order_id = $1 AND variant_id = $2 AND status = $3
+			FOR UPDATE`,
+				orderID, variantID, models.ReservationStatusHeld)
+			if errors.Is(err, sql.ErrNoRows) {
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("failed to lock reservation: %w", err)
+			}
+
+			_, err = s.execTx(ctx, tx, "resize_reservation",
+				"UPDATE reservations SET quantity = $1, updated_at = NOW() WHERE order_id = $2 AND variant_id = $3",
+				quantity, orderID, variantID)
+			if err != nil {
+				return fmt.Errorf("failed to resize reservation: %w", err)
+			}
+
+			if released := reservation.Quantity - quantity; released > 0 && reservation.WarehouseID != nil {
+				_, err = s.execTx(ctx, tx, "release_reserved_stock",
+					`UPDATE inventory SET available = available + $1, reserved = reserved - $1, updated_at = NOW()
+				WHERE variant_id = $2 AND warehouse_id = $3`,
+					released, variantID, *reservation.WarehouseID)
+				if err != nil {
+					return fmt.Errorf("failed to release stock: %w", err)
+				}
+			}
+
+			resized = &reservation
+			return nil
+		})
+	})
+	return resized, err
+}
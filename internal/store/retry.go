@@ -0,0 +1,73 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"order-service/internal/resilience/retry"
+	"order-service/internal/util"
+
+	"github.com/lib/pq"
+)
+
+// Postgres error codes that are safe to retry
+const (
+	pgSerializationFailure = "40001"
+	pgDeadlockDetected     = "40P01"
+	pgUniqueViolation      = "23505"
+)
+
+const (
+	defaultMaxAttempts = 3
+	retryBaseDelay     = 10 * time.Millisecond
+)
+
+// retryableCode returns the SQLSTATE of err if it is a transient conflict
+func retryableCode(err error) (string, bool) {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return "", false
+	}
+
+	switch pqErr.Code {
+	case pgSerializationFailure, pgDeadlockDetected:
+		return string(pqErr.Code), true
+	}
+	return "", false
+}
+
+// withRetry runs fn, retrying with jittered exponential backoff on
+// serialization failures and deadlocks. In the transaction of WithTx fn runs
+// once, as the failure aborts the whole transaction.
+func (s *Store) withRetry(ctx context.Context, operation string, fn func() error) error {
+	if txFrom(ctx) != nil {
+		return fn()
+	}
+	policy := retry.Policy{
+		MaxAttempts: s.maxAttempts,
+		BaseDelay:   retryBaseDelay,
+		Jitter:      0.5,
+		Retryable: func(err error) bool {
+			_, ok := retryableCode(err)
+			return ok
+		},
+		OnRetry: func(attempt int, err error) {
+			code, _ := retryableCode(err)
+			util.StoreRetriesTotal.WithLabelValues(operation, code).Inc()
+		},
+	}
+
+	err := retry.Do(ctx, policy, func(context.Context) error { return fn() })
+	s.observe(err)
+	if code, ok := retryableCode(err); ok {
+		util.StoreRetryExhaustedTotal.WithLabelValues(operation, code).Inc()
+	}
+	return err
+}
+
+// isUniqueViolation reports whether err is a unique constraint violation
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == pgUniqueViolation
+}
@@ -0,0 +1,52 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithRetryRetriesSerializationFailures(t *testing.T) {
+	s := &Store{maxAttempts: 3}
+
+	calls := 0
+	err := s.withRetry(context.Background(), "test", func() error {
+		calls++
+		if calls < 3 {
+			return &pq.Error{Code: pgSerializationFailure}
+		}
+		return nil
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, calls)
+}
+
+func TestWithRetryGivesUpAfterMaxAttempts(t *testing.T) {
+	s := &Store{maxAttempts: 2}
+
+	calls := 0
+	err := s.withRetry(context.Background(), "test", func() error {
+		calls++
+		return &pq.Error{Code: pgDeadlockDetected}
+	})
+
+	assert.Error(t, err)
+	assert.Equal(t, 2, calls)
+}
+
+func TestWithRetryDoesNotRetryOtherErrors(t *testing.T) {
+	s := &Store{maxAttempts: 3}
+
+	calls := 0
+	err := s.withRetry(context.Background(), "test", func() error {
+		calls++
+		return errors.New("insufficient stock")
+	})
+
+	assert.Error(t, err)
+	assert.Equal(t, 1, calls)
+}
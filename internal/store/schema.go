@@ -0,0 +1,27 @@
+package store
+
+import (
+	"context"
+	"time"
+)
+
+// SchemaVersion is the version of the newest migration this build needs,
+// the number prefix of its file in migrations/. Every migration records its
+// version in schema_migrations.
+const SchemaVersion = 44
+
+// AppliedSchemaVersion returns the version of the newest migration applied
+// to the database
+func (s *Store) AppliedSchemaVersion(ctx context.Context) (int, error) {
+	var version int
+	err := s.get(ctx, "applied_schema_version", &version,
+		"SELECT COALESCE(MAX(version), 0) FROM schema_migrations")
+	return version, err
+}
+
+// DatabaseTime returns the clock of the primary
+func (s *Store) DatabaseTime(ctx context.Context) (time.Time, error) {
+	var now time.Time
+	err := s.get(ctx, "database_time", &now, "SELECT NOW()")
+	return now, err
+}
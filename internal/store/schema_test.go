@@ -0,0 +1,36 @@
+package store
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var migrationName = regexp.MustCompile(`^(\d+)_.*\.sql$`)
+
+func TestSchemaVersionMatchesNewestMigration(t *testing.T) {
+	files, err := filepath.Glob("../../migrations/*.sql")
+	require.NoError(t, err)
+
+	newest := 0
+	for _, file := range files {
+		m := migrationName.FindStringSubmatch(filepath.Base(file))
+		require.NotNil(t, m, "migration %s is not named NNN_name.sql", file)
+		version, _ := strconv.Atoi(m[1])
+		newest = max(newest, version)
+
+		if version > 30 {
+			sql, err := os.ReadFile(file)
+			require.NoError(t, err)
+			assert.Contains(t, string(sql), fmt.Sprintf("INSERT INTO schema_migrations (version) VALUES (%d)", version),
+				"migration %s does not record its version", file)
+		}
+	}
+	assert.Equal(t, newest, SchemaVersion, "bump SchemaVersion with every migration")
+}
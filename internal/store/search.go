@@ -0,0 +1,83 @@
+package store
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"order-service/internal/models"
+)
+
+// likeEscaper escapes the LIKE wildcards in a search query
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// SearchOrders finds orders by idempotency key, payment provider transaction
+// ID, or the name or SKU of a product or variant they contain, and by order
+// or user ID for a numeric query. Results are ordered by relevance, then
+// newest first, as order summaries. Matching uses the trigram indexes of
+// migration 022.
+func (s *Store) SearchOrders(ctx context.Context, query string, limit int) ([]models.OrderSearchResult, error) {
+	var id interface{}
+	if n, err := strconv.ParseInt(query, 10, 64); err == nil {
+		id = n
+	}
+
+	var rows []struct {
+		models.OrderSummary
+		Score     float64 `db:"score"`
+		MatchedOn string  `db:"matched_on"`
+	}
+	err := s.selectWithFailover(ctx, "search_orders", &rows,
+		`WITH product_hits AS (
+			SELECT id, GREATEST(word_similarity($1, name), similarity(sku, $1)) AS score
+			FROM products
+			WHERE name ILIKE $2 OR sku ILIKE $2 OR $1 <% name
+		), variant_hits AS (
+			SELECT id, similarity(sku, $1) AS score
+			FROM product_variants
+			WHERE sku ILIKE $2
+		), matches AS (
+			SELECT id AS order_id, 'order_id' AS field, 1::real AS score FROM orders WHERE id = $3
+			UNION ALL
+			SELECT id, 'user_id', 1::real FROM orders WHERE user_id = $3
+			UNION ALL
+			SELECT id, 'idempotency_key', CASE WHEN idempotency_key = $1 THEN 1 ELSE similarity(idempotency_key, $1) END
+			FROM orders WHERE idempotency_key ILIKE $2
+			UNION ALL
+			SELECT order_id, 'provider_tx_id', CASE WHEN provider_tx_id = $1 THEN 1 ELSE similarity(provider_tx_id, $1) END
+			FROM payments WHERE provider_tx_id ILIKE $2
+			UNION ALL
+			SELECT oi.order_id, 'product', h.score FROM product_hits h
+			CROSS JOIN LATERAL (
+				SELECT order_id FROM order_items WHERE product_id = h.id ORDER BY order_id DESC LIMIT $4
+			) oi
+			UNION ALL
+			SELECT oi.order_id, 'variant_sku', h.score FROM variant_hits h
+			CROSS JOIN LATERAL (
+				SELECT order_id FROM order_items WHERE variant_id = h.id ORDER BY order_id DESC LIMIT $4
+			) oi
+		), ranked AS (
+			SELECT order_id, MAX(score) AS score, string_agg(DISTINCT field, ',') AS matched_on
+			FROM matches
+			GROUP BY order_id
+		)
+		SELECT o.*, r.score, r.matched_on
+		FROM ranked r JOIN order_summaries o ON o.order_id = r.order_id
+		WHERE $5 = '' OR o.tenant_id = $5
+		ORDER BY r.score DESC, o.order_id DESC
+		LIMIT $4`,
+		query, "%"+likeEscaper.Replace(query)+"%", id, limit, tenantArg(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]models.OrderSearchResult, 0, len(rows))
+	for _, row := range rows {
+		results = append(results, models.OrderSearchResult{
+			OrderSummary: row.OrderSummary,
+			Score:        row.Score,
+			MatchedOn:    strings.Split(row.MatchedOn, ","),
+		})
+	}
+	return results, nil
+}
@@ -0,0 +1,133 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	"order-service/internal/models"
+
+	"github.com/lib/pq"
+)
+
+// StatusChangeChannel is the channel a committed order status change is
+// notified on, with the order ID as payload
+const StatusChangeChannel = "order_status_changes"
+
+// listenerPingInterval checks an idle listener connection is still alive
+const listenerPingInterval = 90 * time.Second
+
+// ErrNoDatabaseURL is returned when listening on a store made from a pool
+var ErrNoDatabaseURL = errors.New("store has no database URL to listen on")
+
+// ListenStatusChanges listens for committed order status changes until ctx is
+// cancelled. The returned channel receives a value when changes were notified;
+// notifications are coalesced, and one is sent after a reconnect since
+// changes may have been missed meanwhile.
+func (s *Store) ListenStatusChanges(ctx context.Context) (<-chan struct{}, error) {
+	if s.url == "" {
+		return nil, ErrNoDatabaseURL
+	}
+
+	listener := pq.NewListener(s.url, time.Second, time.Minute, func(event pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Printf("Status change listener: %v", err)
+		}
+	})
+	if err := listener.Listen(StatusChangeChannel); err != nil {
+		listener.Close()
+		return nil, err
+	}
+
+	changes := make(chan struct{}, 1)
+	go func() {
+		defer listener.Close()
+		ping := time.NewTicker(listenerPingInterval)
+		defer ping.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-listener.Notify:
+				// A nil notification follows a reconnect
+				select {
+				case changes <- struct{}{}:
+				default:
+				}
+			case <-ping.C:
+				go listener.Ping()
+			}
+		}
+	}()
+	return changes, nil
+}
+
+// ForwardStatusChanges passes up to limit order status changes not yet
+// forwarded by consumer to forward, oldest first, and moves the consumer's
+// cursor past them once forward succeeds. Only changes of transactions older
+// than every transaction still in progress are passed, so a change that
+// commits later can never sort before the cursor. A consumer starts at the
+// changes committed after its first call. Returns the number of changes
+// forwarded, 0 if another instance is forwarding for consumer.
+func (s *Store) ForwardStatusChanges(
+	ctx context.Context,
+	consumer string,
+	limit int,
+	forward func(ctx context.Context, changes []models.OrderStatusHistory) error,
+) (int, error) {
+	_, err := s.exec(ctx, "init_change_capture_cursor",
+		`INSERT INTO change_capture_cursors (name, txid)
+		VALUES ($1, txid_snapshot_xmin(txid_current_snapshot()))
+		ON CONFLICT (name) DO NOTHING`,
+		consumer)
+	if err != nil {
+		return 0, err
+	}
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var cursor struct {
+		TxID      int64 `db:"txid"`
+		HistoryID int64 `db:"history_id"`
+	}
+	err = s.getTx(ctx, tx, "lock_change_capture_cursor", &cursor,
+		"SELECT txid, history_id FROM change_capture_cursors WHERE name = $1 FOR UPDATE SKIP LOCKED",
+		consumer)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	var changes []models.OrderStatusHistory
+	err = s.selectTx(ctx, tx, "select_status_changes", &changes,
+		`SELECT * FROM order_status_history
+		WHERE (txid, id) > ($1, $2) AND txid < txid_snapshot_xmin(txid_current_snapshot())
+		ORDER BY txid, id
+		LIMIT $3`,
+		cursor.TxID, cursor.HistoryID, limit)
+	if err != nil || len(changes) == 0 {
+		return 0, err
+	}
+
+	if err := forward(ctx, changes); err != nil {
+		return 0, err
+	}
+
+	last := changes[len(changes)-1]
+	_, err = s.execTx(ctx, tx, "advance_change_capture_cursor",
+		"UPDATE change_capture_cursors SET txid = $1, history_id = $2, updated_at = NOW() WHERE name = $3",
+		*last.TxID, last.ID, consumer)
+	if err != nil {
+		return 0, err
+	}
+	return len(changes), tx.Commit()
+}
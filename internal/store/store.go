@@ -3,42 +3,136 @@ package store
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"log"
+	"sync/atomic"
 	"time"
 
+	"order-service/internal/apperrors"
 	"order-service/internal/models"
+	"order-service/internal/pii"
 
 	"github.com/jmoiron/sqlx"
 	_ "github.com/lib/pq"
 )
 
+// maxIdleConns is the idle pool size, restored after a pool reset
+const maxIdleConns = 5
+
 type Store struct {
-	db *sqlx.DB
+	db          *sqlx.DB
+	maxAttempts int
+
+	// url is the primary's connection string, unset for a store made from a
+	// pool; listening for notifications needs a dedicated connection
+	url string
+
+	// replicas serve reads made with WithReplicaReads, round-robin
+	replicas    []*sqlx.DB
+	nextReplica atomic.Uint32
+
+	// prepare runs queries as cached prepared statements; queries slower
+	// than slowQuery are logged
+	prepare   bool
+	slowQuery time.Duration
+	stmts     statementCache
+
+	// pii encrypts personal data columns; nil stores them in plain text
+	pii *pii.Cipher
+
+	readAttempts       int
+	poolResetThreshold int
+	connErrors         atomic.Int32
+	lastPoolReset      atomic.Int64
 }
 
 // NewStore creates a new database store
 func NewStore(databaseURL string) (*Store, error) {
-	db, err := sqlx.Connect("postgres", databaseURL)
+	db, err := openPool(databaseURL)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
 
-	db.SetMaxOpenConns(25)
-	db.SetMaxIdleConns(5)
-	db.SetConnMaxLifetime(5 * time.Minute)
-
 	if err := db.Ping(); err != nil {
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
-	return &Store{db: db}, nil
+	s := NewStoreFromDB(db)
+	s.url = databaseURL
+	return s, nil
+}
+
+// NewStoreWithReplicas creates a store on the primary at databaseURL whose
+// reads made with WithReplicaReads are served by the replicas at replicaURLs.
+// A replica that cannot be reached at startup is kept; reads fall back to the
+// primary until it is.
+func NewStoreWithReplicas(databaseURL string, replicaURLs []string) (*Store, error) {
+	s, err := NewStore(databaseURL)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, replicaURL := range replicaURLs {
+		db, err := openPool(replicaURL)
+		if err != nil {
+			s.Close()
+			return nil, fmt.Errorf("failed to open read replica %d: %w", i+1, err)
+		}
+		if err := db.Ping(); err != nil {
+			log.Printf("Read replica %d is unreachable, reading from the primary until it is: %v", i+1, err)
+		}
+		s.replicas = append(s.replicas, db)
+	}
+	return s, nil
+}
+
+// openPool opens a connection pool without connecting
+func openPool(databaseURL string) (*sqlx.DB, error) {
+	db, err := sqlx.Open("postgres", databaseURL)
+	if err != nil {
+		return nil, err
+	}
+
+	db.SetMaxOpenConns(25)
+	db.SetMaxIdleConns(maxIdleConns)
+	db.SetConnMaxLifetime(5 * time.Minute)
+	// Idle connections to a failed-over primary are dropped before they are reused
+	db.SetConnMaxIdleTime(time.Minute)
+	return db, nil
 }
 
-// Close closes the database connection
+// NewStoreFromDB creates a store on top of an existing connection pool, e.g.
+// one shared with the process embedding the order service. The pool settings
+// are left to the caller.
+func NewStoreFromDB(db *sqlx.DB) *Store {
+	return &Store{
+		db:                 db,
+		maxAttempts:        defaultMaxAttempts,
+		prepare:            true,
+		slowQuery:          defaultSlowQuery,
+		readAttempts:       defaultReadAttempts,
+		poolResetThreshold: defaultPoolResetThreshold,
+	}
+}
+
+// Close closes the database connections
 func (s *Store) Close() error {
+	s.closeStatements()
+	for _, replica := range s.replicas {
+		replica.Close()
+	}
 	return s.db.Close()
 }
 
+// Ping verifies the database connection is alive. Failed pings count
+// towards resetting the pool.
+func (s *Store) Ping(ctx context.Context) error {
+	err := s.db.PingContext(ctx)
+	s.observe(err)
+	return err
+}
+
 // GetDB returns the underlying database connection
 func (s *Store) GetDB() *sqlx.DB {
 	return s.db
@@ -47,7 +141,8 @@ func (s *Store) GetDB() *sqlx.DB {
 // GetProductByID retrieves a product by ID
 func (s *Store) GetProductByID(ctx context.Context, id int64) (*models.Product, error) {
 	var product models.Product
-	err := s.db.GetContext(ctx, &product, "SELECT * FROM products WHERE id = $1", id)
+	err := s.getWithFailover(ctx, "get_product", &product,
+		"SELECT * FROM products WHERE id = $1 AND ($2 = '' OR tenant_id = $2)", id, tenantArg(ctx))
 	if err == sql.ErrNoRows {
 		return nil, fmt.Errorf("product not found: %d", id)
 	}
@@ -60,7 +155,8 @@ func (s *Store) GetProductByID(ctx context.Context, id int64) (*models.Product,
 // GetProductBySKU retrieves a product by SKU
 func (s *Store) GetProductBySKU(ctx context.Context, sku string) (*models.Product, error) {
 	var product models.Product
-	err := s.db.GetContext(ctx, &product, "SELECT * FROM products WHERE sku = $1", sku)
+	err := s.getWithFailover(ctx, "get_product_by_sku", &product,
+		"SELECT * FROM products WHERE sku = $1 AND ($2 = '' OR tenant_id = $2)", sku, tenantArg(ctx))
 	if err == sql.ErrNoRows {
 		return nil, fmt.Errorf("product not found: %s", sku)
 	}
@@ -73,7 +169,8 @@ func (s *Store) GetProductBySKU(ctx context.Context, sku string) (*models.Produc
 // GetProducts retrieves all products
 func (s *Store) GetProducts(ctx context.Context) ([]models.Product, error) {
 	var products []models.Product
-	err := s.db.SelectContext(ctx, &products, "SELECT * FROM products ORDER BY id")
+	err := s.selectWithFailover(ctx, "get_products", &products,
+		"SELECT * FROM products WHERE $1 = '' OR tenant_id = $1 ORDER BY id", tenantArg(ctx))
 	return products, err
 }
 
@@ -83,23 +180,30 @@ func (s *Store) GetProductsByIDs(ctx context.Context, ids []int64) ([]models.Pro
 		return []models.Product{}, nil
 	}
 
-	query, args, err := sqlx.In("SELECT * FROM products WHERE id IN (?)", ids)
+	query, args, err := sqlx.In("SELECT * FROM products WHERE id IN (?) AND (? = '' OR tenant_id = ?)",
+		ids, tenantArg(ctx), tenantArg(ctx))
 	if err != nil {
 		return nil, err
 	}
 	query = s.db.Rebind(query)
 
 	var products []models.Product
-	err = s.db.SelectContext(ctx, &products, query, args...)
+	err = s.selectWithFailover(ctx, "get_products_by_ids", &products, query, args...)
 	return products, err
 }
 
-// GetInventory retrieves inventory for a product
-func (s *Store) GetInventory(ctx context.Context, productID int64) (*models.Inventory, error) {
+// GetInventory retrieves the stock of a product variant across warehouses
+func (s *Store) GetInventory(ctx context.Context, variantID int64) (*models.Inventory, error) {
 	var inv models.Inventory
-	err := s.db.GetContext(ctx, &inv, "SELECT * FROM inventory WHERE product_id = $1", productID)
+	err := s.getWithFailover(ctx, "get_inventory", &inv,
+		`SELECT product_id, variant_id, 0 AS warehouse_id, SUM(available) AS available,
+			SUM(reserved) AS reserved, MAX(updated_at) AS updated_at
+		FROM inventory WHERE variant_id = $1
+		AND ($2 = '' OR product_id IN (SELECT id FROM products WHERE tenant_id = $2))
+		GROUP BY product_id, variant_id`,
+		variantID, tenantArg(ctx))
 	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("inventory not found for product: %d", productID)
+		return nil, apperrors.New(apperrors.ErrNotFound, "inventory not found for variant %d", variantID)
 	}
 	if err != nil {
 		return nil, err
@@ -107,55 +211,146 @@ func (s *Store) GetInventory(ctx context.Context, productID int64) (*models.Inve
 	return &inv, nil
 }
 
-// ReserveStockTx reserves stock within a transaction (FOR UPDATE lock)
-func (s *Store) ReserveStockTx(ctx context.Context, productID int64, quantity int) error {
-	tx, err := s.db.BeginTxx(ctx, nil)
-	if err != nil {
-		return err
+// SetMaxAttempts sets how many times transient conflicts are attempted
+func (s *Store) SetMaxAttempts(n int) {
+	if n > 0 {
+		s.maxAttempts = n
 	}
-	defer tx.Rollback()
+}
 
-	var available int
-	err = tx.GetContext(ctx, &available,
-		"SELECT available FROM inventory WHERE product_id = $1 FOR UPDATE", productID)
-	if err != nil {
-		return fmt.Errorf("failed to lock inventory: %w", err)
-	}
+// GetWarehouseStock returns the stock of a product variant in each warehouse
+// holding any
+func (s *Store) GetWarehouseStock(ctx context.Context, variantID int64) ([]models.Inventory, error) {
+	inventories := []models.Inventory{}
+	err := s.selectWithFailover(ctx, "get_warehouse_stock", &inventories,
+		`SELECT * FROM inventory WHERE variant_id = $1
+		AND ($2 = '' OR product_id IN (SELECT id FROM products WHERE tenant_id = $2))
+		ORDER BY warehouse_id`,
+		variantID, tenantArg(ctx))
+	return inventories, err
+}
 
-	if available < quantity {
-		return fmt.Errorf("insufficient stock: available=%d, requested=%d", available, quantity)
-	}
+// ReserveStockTx reserves stock in a warehouse within a transaction (FOR UPDATE lock)
+func (s *Store) ReserveStockTx(ctx context.Context, variantID, warehouseID int64, quantity int) error {
+	return s.withRetry(ctx, "reserve_stock", func() error {
+		return s.reserveStockTx(ctx, variantID, warehouseID, quantity)
+	})
+}
 
-	_, err = tx.ExecContext(ctx,
-		"UPDATE inventory SET available = available - $1, reserved = reserved + $1, updated_at = NOW() WHERE product_id = $2",
-		quantity, productID)
-	if err != nil {
-		return fmt.Errorf("failed to reserve stock: %w", err)
-	}
+func (s *Store) reserveStockTx(ctx context.Context, variantID, warehouseID int64, quantity int) error {
+	return s.inTx(ctx, func(tx *sqlx.Tx) error {
+		var available int
+		err := s.getTx(ctx, tx, "lock_inventory", &available,
+			"SELECT available FROM inventory WHERE variant_id = $1 AND warehouse_id = $2 FOR UPDATE",
+			variantID, warehouseID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return apperrors.New(apperrors.ErrInsufficientStock, "variant %d has no stock in warehouse %d", variantID, warehouseID)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to lock inventory: %w", err)
+		}
+
+		if available < quantity {
+			return apperrors.New(apperrors.ErrInsufficientStock, "variant %d in warehouse %d: available=%d, requested=%d",
+				variantID, warehouseID, available, quantity)
+		}
 
-	return tx.Commit()
+		_, err = s.execTx(ctx, tx, "reserve_stock",
+			`UPDATE inventory SET available = available - $1, reserved = reserved + $1, updated_at = NOW()
+			WHERE variant_id = $2 AND warehouse_id = $3`,
+			quantity, variantID, warehouseID)
+		if err != nil {
+			return fmt.Errorf("failed to reserve stock: %w", err)
+		}
+		return nil
+	})
 }
 
-// ReleaseStock releases reserved stock (compensation)
-func (s *Store) ReleaseStock(ctx context.Context, productID int64, quantity int) error {
-	_, err := s.db.ExecContext(ctx,
-		"UPDATE inventory SET available = available + $1, reserved = reserved - $1, updated_at = NOW() WHERE product_id = $2",
-		quantity, productID)
-	return err
+// ReleaseStock releases reserved stock in a warehouse (compensation)
+func (s *Store) ReleaseStock(ctx context.Context, variantID, warehouseID int64, quantity int) error {
+	return s.withRetry(ctx, "release_stock", func() error {
+		_, err := s.exec(ctx, "release_stock",
+			`UPDATE inventory SET available = available + $1, reserved = reserved - $1, updated_at = NOW()
+			WHERE variant_id = $2 AND warehouse_id = $3`,
+			quantity, variantID, warehouseID)
+		return err
+	})
 }
 
-// CommitStock commits reserved stock (final deduction)
-func (s *Store) CommitStock(ctx context.Context, productID int64, quantity int) error {
-	_, err := s.db.ExecContext(ctx,
-		"UPDATE inventory SET reserved = reserved - $1, updated_at = NOW() WHERE product_id = $2",
-		quantity, productID)
-	return err
+// CommitStock commits reserved stock in a warehouse (final deduction)
+func (s *Store) CommitStock(ctx context.Context, variantID, warehouseID int64, quantity int) error {
+	return s.withRetry(ctx, "commit_stock", func() error {
+		_, err := s.exec(ctx, "commit_stock",
+			"UPDATE inventory SET reserved = reserved - $1, updated_at = NOW() WHERE variant_id = $2 AND warehouse_id = $3",
+			quantity, variantID, warehouseID)
+		return err
+	})
 }
 
-// UpdateInventory updates inventory counts
-func (s *Store) UpdateInventory(ctx context.Context, productID int64, available, reserved int) error {
-	_, err := s.db.ExecContext(ctx,
-		"UPDATE inventory SET available = $1, reserved = $2, updated_at = NOW() WHERE product_id = $3",
-		available, reserved, productID)
+// CommitOrderItemStock deducts the reserved stock of one order item and marks
+// the item and its reservation committed in the same transaction. Returns false
+// if the item was already committed, so replaying an order-level commit never
+// deducts twice.
+func (s *Store) CommitOrderItemStock(ctx context.Context, item models.OrderItem) (bool, error) {
+	var committed bool
+	err := s.withRetry(ctx, "commit_order_item_stock", func() error {
+		committed = false
+		return s.inTx(ctx, func(tx *sqlx.Tx) error {
+
+			res, err := s.execTx(ctx, tx, "mark_item_stock_committed",
+				"UPDATE order_items SET stock_committed_at = NOW() WHERE id = $1 AND stock_committed_at IS NULL",
+				item.ID)
+			if err != nil {
+				return fmt.Errorf("failed to mark item committed: %w", err)
+			}
+			rows, err := res.RowsAffected()
+			if err != nil {
+				return err
+			}
+			if rows == 0 {
+				return nil
+			}
+
+			_, err = s.execTx(ctx, tx, "commit_reserved_stock",
+				"UPDATE inventory SET reserved = reserved - $1, updated_at = NOW() WHERE variant_id = $2 AND warehouse_id = $3",
+				item.Quantity, item.VariantID, item.WarehouseID)
+			if err != nil {
+				return fmt.Errorf("failed to commit stock: %w", err)
+			}
+
+			_, err = s.execTx(ctx, tx, "commit_reservation",
+				"UPDATE reservations SET status = $1, updated_at = NOW() WHERE order_id = $2 AND variant_id = $3 AND status = $4",
+				models.ReservationStatusCommitted, item.OrderID, item.VariantID, models.ReservationStatusHeld)
+			if err != nil {
+				return fmt.Errorf("failed to commit reservation: %w", err)
+			}
+			committed = true
+			return nil
+		})
+	})
+	return committed, err
+}
+
+// RestockInventory adds units back to available stock in a warehouse
+func (s *Store) RestockInventory(ctx context.Context, variantID, warehouseID int64, quantity int) error {
+	_, err := s.exec(ctx, "restock_inventory",
+		"UPDATE inventory SET available = available + $1, updated_at = NOW() WHERE variant_id = $2 AND warehouse_id = $3",
+		quantity, variantID, warehouseID)
 	return err
 }
+
+// ReplaceInventory overwrites the inventory counts of a variant in a
+// warehouse unless they have changed from seenAvailable and seenReserved, so
+// stock reserved or released since they were read is not lost. Reports
+// whether the row was written.
+func (s *Store) ReplaceInventory(ctx context.Context, variantID, warehouseID int64, seenAvailable, seenReserved, available, reserved int) (bool, error) {
+	res, err := s.exec(ctx, "replace_inventory",
+		`UPDATE inventory SET available = $1, reserved = $2, updated_at = NOW()
+		 WHERE variant_id = $3 AND warehouse_id = $4 AND available = $5 AND reserved = $6`,
+		available, reserved, variantID, warehouseID, seenAvailable, seenReserved)
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n == 1, nil
+}
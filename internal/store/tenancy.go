@@ -0,0 +1,15 @@
+package store
+
+import (
+	"context"
+
+	"order-service/internal/tenant"
+)
+
+// tenantArg binds the tenant reads under ctx are restricted to: queries keep
+// the rows whose tenant_id is the argument, or every row when it is empty, as
+// it is for work done for no tenant.
+func tenantArg(ctx context.Context) string {
+	id, _ := tenant.ID(ctx)
+	return id
+}
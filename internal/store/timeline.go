@@ -0,0 +1,57 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"order-service/internal/models"
+)
+
+// AddTimelineEntry records an event in an order's timeline. Events already
+// recorded and events for unknown orders are ignored.
+func (s *Store) AddTimelineEntry(ctx context.Context, entry *models.OrderTimelineEntry) error {
+	query := `
+		INSERT INTO order_timeline (order_id, event_id, event_type, direction, summary, occurred_at)
+		SELECT $1, $2, $3, $4, $5, $6
+		WHERE EXISTS (SELECT 1 FROM orders WHERE id = $1)
+		ON CONFLICT (order_id, event_id) DO NOTHING`
+
+	_, err := s.exec(ctx, "add_timeline_entry", query,
+		entry.OrderID, entry.EventID, entry.EventType, entry.Direction, entry.Summary, entry.OccurredAt)
+	return err
+}
+
+// GetOrderTimeline returns the events of an order, oldest first
+func (s *Store) GetOrderTimeline(ctx context.Context, orderID int64) ([]models.OrderTimelineEntry, error) {
+	entries := []models.OrderTimelineEntry{}
+	err := s.selectWithFailover(ctx, "get_order_timeline", &entries,
+		"SELECT * FROM order_timeline WHERE order_id = $1 ORDER BY occurred_at, id", orderID)
+	return entries, err
+}
+
+// GetOrdersMissingEvents retrieves orders updated in [since, before) whose
+// current status implies an event (RESERVED, CANCELLED, ON_HOLD) that never
+// made it into their timeline, in ID order after afterID
+func (s *Store) GetOrdersMissingEvents(ctx context.Context, since, before time.Time, afterID int64, limit int) ([]models.Order, error) {
+	var orders []models.Order
+	err := s.selectWithFailover(ctx, "get_orders_missing_events", &orders,
+		`SELECT o.* FROM orders o
+		WHERE o.id > $1 AND o.updated_at >= $2 AND o.updated_at < $3
+		AND o.status IN ($4, $6, $8)
+		AND NOT EXISTS (
+			SELECT 1 FROM order_timeline t
+			WHERE t.order_id = o.id
+			AND t.event_type = CASE o.status WHEN $4 THEN $5 WHEN $6 THEN $7 WHEN $8 THEN $9 END
+		)
+		ORDER BY o.id
+		LIMIT $10`,
+		afterID, since.UTC(), before.UTC(),
+		models.OrderStatusReserved, models.EventTypeOrderReserved,
+		models.OrderStatusCancelled, models.EventTypeOrderCancelled,
+		models.OrderStatusOnHold, models.EventTypeOrderOnHold,
+		limit)
+	if err != nil {
+		return nil, err
+	}
+	return orders, s.openOrders(ctx, orders)
+}
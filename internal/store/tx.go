@@ -0,0 +1,82 @@
+package store
+
+import (
+	"context"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// txKey carries the transaction of a WithTx call in a context
+type txKey struct{}
+
+// txState is a transaction joined by the store calls made under WithTx and
+// the side effects to run once it commits
+type txState struct {
+	tx          *sqlx.Tx
+	afterCommit []func()
+}
+
+// txFrom returns the transaction the context carries, or nil
+func txFrom(ctx context.Context) *txState {
+	state, _ := ctx.Value(txKey{}).(*txState)
+	return state
+}
+
+// WithTx runs fn in a transaction on the primary: store calls made with the
+// context fn is given join it, and it commits only if fn returns nil. A
+// WithTx nested in another joins the outer transaction. Calls in the
+// transaction are not retried on their own; a serialization failure or
+// deadlock fails the whole transaction.
+func (s *Store) WithTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if txFrom(ctx) != nil {
+		return fn(ctx)
+	}
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		s.observe(err)
+		return err
+	}
+	defer tx.Rollback()
+
+	state := &txState{tx: tx}
+	if err := fn(context.WithValue(ctx, txKey{}, state)); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	for _, f := range state.afterCommit {
+		f()
+	}
+	return nil
+}
+
+// AfterCommit runs fn once the transaction ctx carries commits, and never if
+// it rolls back. Outside a transaction fn runs right away.
+func AfterCommit(ctx context.Context, fn func()) {
+	if state := txFrom(ctx); state != nil {
+		state.afterCommit = append(state.afterCommit, fn)
+		return
+	}
+	fn()
+}
+
+// inTx runs fn in the transaction ctx carries, or else in one of its own
+// that commits if fn returns nil
+func (s *Store) inTx(ctx context.Context, fn func(tx *sqlx.Tx) error) error {
+	if state := txFrom(ctx); state != nil {
+		return fn(state.tx)
+	}
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,39 @@
+package store
+
+import (
+	"context"
+	"testing"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAfterCommitRunsRightAwayOutsideATransaction(t *testing.T) {
+	ran := false
+	AfterCommit(context.Background(), func() { ran = true })
+	assert.True(t, ran)
+}
+
+func TestAfterCommitWaitsForTheTransaction(t *testing.T) {
+	state := &txState{}
+	ctx := context.WithValue(context.Background(), txKey{}, state)
+
+	ran := false
+	AfterCommit(ctx, func() { ran = true })
+	assert.False(t, ran)
+	assert.Len(t, state.afterCommit, 1)
+}
+
+func TestWithRetryRunsOnceInATransaction(t *testing.T) {
+	s := &Store{maxAttempts: 3}
+	ctx := context.WithValue(context.Background(), txKey{}, &txState{})
+
+	calls := 0
+	err := s.withRetry(ctx, "test", func() error {
+		calls++
+		return &pq.Error{Code: pgSerializationFailure}
+	})
+
+	assert.Error(t, err)
+	assert.Equal(t, 1, calls)
+}
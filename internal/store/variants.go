@@ -0,0 +1,75 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"order-service/internal/models"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// GetVariantByID retrieves a product variant by ID
+func (s *Store) GetVariantByID(ctx context.Context, id int64) (*models.ProductVariant, error) {
+	var variant models.ProductVariant
+	err := s.getWithFailover(ctx, "get_variant", &variant,
+		`SELECT * FROM product_variants WHERE id = $1
+		AND ($2 = '' OR product_id IN (SELECT id FROM products WHERE tenant_id = $2))`,
+		id, tenantArg(ctx))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("variant not found: %d", id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &variant, nil
+}
+
+// GetVariantBySKU retrieves a product variant by SKU
+func (s *Store) GetVariantBySKU(ctx context.Context, sku string) (*models.ProductVariant, error) {
+	var variant models.ProductVariant
+	err := s.getWithFailover(ctx, "get_variant_by_sku", &variant,
+		`SELECT * FROM product_variants WHERE sku = $1
+		AND ($2 = '' OR product_id IN (SELECT id FROM products WHERE tenant_id = $2))`,
+		sku, tenantArg(ctx))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("variant not found: %s", sku)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &variant, nil
+}
+
+// GetVariantsByProductIDs retrieves the variants of the given products
+func (s *Store) GetVariantsByProductIDs(ctx context.Context, productIDs []int64) ([]models.ProductVariant, error) {
+	if len(productIDs) == 0 {
+		return []models.ProductVariant{}, nil
+	}
+
+	query, args, err := sqlx.In(
+		`SELECT * FROM product_variants WHERE product_id IN (?)
+		AND (? = '' OR product_id IN (SELECT id FROM products WHERE tenant_id = ?))
+		ORDER BY product_id, id`,
+		productIDs, tenantArg(ctx), tenantArg(ctx))
+	if err != nil {
+		return nil, err
+	}
+	query = s.db.Rebind(query)
+
+	variants := []models.ProductVariant{}
+	err = s.selectWithFailover(ctx, "get_variants_by_product_ids", &variants, query, args...)
+	return variants, err
+}
+
+// GetInventories retrieves the inventory of every product variant in every warehouse
+func (s *Store) GetInventories(ctx context.Context) ([]models.Inventory, error) {
+	var inventories []models.Inventory
+	err := s.selectWithFailover(ctx, "get_inventories", &inventories,
+		`SELECT * FROM inventory
+		WHERE $1 = '' OR product_id IN (SELECT id FROM products WHERE tenant_id = $1)
+		ORDER BY product_id, variant_id, warehouse_id`,
+		tenantArg(ctx))
+	return inventories, err
+}
@@ -0,0 +1,123 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"order-service/internal/apperrors"
+	"order-service/internal/models"
+	"order-service/internal/tenant"
+)
+
+// ErrStaleWallet is returned when a wallet changed since the version a
+// transaction was applied against
+var ErrStaleWallet = errors.New("wallet changed concurrently")
+
+// GetWallet retrieves the wallet of a user of the tenant of ctx, an empty
+// wallet at version 0 if the user has none yet
+func (s *Store) GetWallet(ctx context.Context, userID int64) (*models.Wallet, error) {
+	var wallet models.Wallet
+	err := s.get(ctx, "get_wallet", &wallet,
+		"SELECT * FROM wallets WHERE tenant_id = $1 AND user_id = $2", tenant.Owner(ctx), userID)
+	if err == sql.ErrNoRows {
+		return &models.Wallet{UserID: userID, TenantID: tenant.Owner(ctx)}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &wallet, nil
+}
+
+// ApplyWalletTransaction debits or credits a wallet of the tenant of ctx if it is still at version
+// and records the transaction, setting its ID and the balance after it.
+// Returns ErrStaleWallet if the wallet changed or a debit would overdraw it,
+// and false without changing the wallet if the payment of txn was already
+// debited or credited.
+func (s *Store) ApplyWalletTransaction(ctx context.Context, txn *models.WalletTransaction, version int64) (bool, error) {
+	delta := txn.Amount
+	if txn.Kind == models.WalletDebit {
+		delta = -delta
+	}
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	var wallet models.Wallet
+	if version == 0 {
+		err = s.getTx(ctx, tx, "create_wallet", &wallet,
+			`INSERT INTO wallets (user_id, balance, version, tenant_id) VALUES ($1, $2, 1, $3)
+			ON CONFLICT (tenant_id, user_id) DO NOTHING
+			RETURNING *`,
+			txn.UserID, delta, tenant.Owner(ctx))
+	} else {
+		err = s.getTx(ctx, tx, "update_wallet_balance", &wallet,
+			`UPDATE wallets SET balance = balance + $2, version = version + 1, updated_at = NOW()
+			WHERE tenant_id = $4 AND user_id = $1 AND version = $3 AND balance + $2 >= 0
+			RETURNING *`,
+			txn.UserID, delta, version, tenant.Owner(ctx))
+	}
+	if err == sql.ErrNoRows {
+		return false, ErrStaleWallet
+	}
+	if err != nil {
+		return false, err
+	}
+
+	txn.BalanceAfter = wallet.Balance
+	txn.TenantID = wallet.TenantID
+	err = s.getTx(ctx, tx, "insert_wallet_transaction", txn,
+		`INSERT INTO wallet_transactions (user_id, kind, amount, balance_after, payment_id, reason, actor, tenant_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (payment_id, kind) DO NOTHING
+		RETURNING id, created_at`,
+		txn.UserID, txn.Kind, txn.Amount, txn.BalanceAfter, txn.PaymentID, txn.Reason, txn.Actor, txn.TenantID)
+	if err == sql.ErrNoRows {
+		// Rolls back the balance change
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// GetPaymentWalletDebit retrieves the wallet debit settling a payment, with
+// the tenant of its wallet, or nil if the payment debited no wallet
+func (s *Store) GetPaymentWalletDebit(ctx context.Context, paymentID int64) (*models.WalletTransaction, error) {
+	var txn models.WalletTransaction
+	err := s.get(ctx, "get_payment_wallet_debit", &txn,
+		"SELECT * FROM wallet_transactions WHERE payment_id = $1 AND kind = $2",
+		paymentID, models.WalletDebit)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &txn, nil
+}
+
+// GetWalletTransactions retrieves the latest transactions of a wallet of the
+// tenant of ctx, newest first
+func (s *Store) GetWalletTransactions(ctx context.Context, userID int64, limit int) ([]models.WalletTransaction, error) {
+	var txns []models.WalletTransaction
+	err := s.selectAll(ctx, "get_wallet_transactions", &txns,
+		"SELECT * FROM wallet_transactions WHERE tenant_id = $1 AND user_id = $2 ORDER BY id DESC LIMIT $3",
+		tenant.Owner(ctx), userID, limit)
+	return txns, err
+}
+
+// GetPaymentTenant returns the tenant of the order a payment is for
+func (s *Store) GetPaymentTenant(ctx context.Context, paymentID int64) (string, error) {
+	var id string
+	err := s.get(ctx, "get_payment_tenant", &id,
+		"SELECT o.tenant_id FROM payments p JOIN orders o ON o.id = p.order_id WHERE p.id = $1", paymentID)
+	if err == sql.ErrNoRows {
+		return "", apperrors.New(apperrors.ErrNotFound, "payment %d not found", paymentID)
+	}
+	return id, err
+}
@@ -0,0 +1,25 @@
+package store
+
+import (
+	"context"
+
+	"order-service/internal/models"
+)
+
+// GetWarehouses returns every warehouse, active or not
+func (s *Store) GetWarehouses(ctx context.Context) ([]models.Warehouse, error) {
+	warehouses := []models.Warehouse{}
+	err := s.selectWithFailover(ctx, "get_warehouses", &warehouses, "SELECT * FROM warehouses ORDER BY id")
+	return warehouses, err
+}
+
+// UpsertWarehouse creates the warehouse with w's code, or updates its name,
+// location and whether it is active, and fills in the rest of w
+func (s *Store) UpsertWarehouse(ctx context.Context, w *models.Warehouse) error {
+	return s.get(ctx, "upsert_warehouse", w,
+		`INSERT INTO warehouses (code, name, latitude, longitude, active) VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (code) DO UPDATE SET name = EXCLUDED.name, latitude = EXCLUDED.latitude,
+			longitude = EXCLUDED.longitude, active = EXCLUDED.active
+		RETURNING *`,
+		w.Code, w.Name, w.Latitude, w.Longitude, w.Active)
+}
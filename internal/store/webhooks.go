@@ -0,0 +1,195 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"order-service/internal/apperrors"
+	"order-service/internal/models"
+	"order-service/internal/tenant"
+
+	"github.com/lib/pq"
+)
+
+// webhookSubscriptionRow scans a subscription with its event types array
+type webhookSubscriptionRow struct {
+	models.WebhookSubscription
+	EventTypes pq.StringArray `db:"event_types"`
+}
+
+func (r webhookSubscriptionRow) subscription() models.WebhookSubscription {
+	sub := r.WebhookSubscription
+	sub.EventTypes = []string(r.EventTypes)
+	return sub
+}
+
+func webhookSubscriptions(rows []webhookSubscriptionRow) []models.WebhookSubscription {
+	subs := make([]models.WebhookSubscription, 0, len(rows))
+	for _, row := range rows {
+		subs = append(subs, row.subscription())
+	}
+	return subs
+}
+
+// CreateWebhookSubscription stores a subscription of the tenant of ctx,
+// setting its ID
+func (s *Store) CreateWebhookSubscription(ctx context.Context, sub *models.WebhookSubscription) error {
+	sub.TenantID = tenant.Owner(ctx)
+	return s.get(ctx, "create_webhook_subscription", sub,
+		`INSERT INTO webhook_subscriptions (url, event_types, secret, description, status, created_by, tenant_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at, updated_at`,
+		sub.URL, pq.Array(sub.EventTypes), sub.Secret, sub.Description, sub.Status, sub.CreatedBy, sub.TenantID)
+}
+
+// GetWebhookSubscription retrieves a subscription by ID; another tenant's
+// subscription is not found
+func (s *Store) GetWebhookSubscription(ctx context.Context, id int64) (*models.WebhookSubscription, error) {
+	var row webhookSubscriptionRow
+	err := s.get(ctx, "get_webhook_subscription", &row,
+		"SELECT * FROM webhook_subscriptions WHERE id = $1 AND ($2 = '' OR tenant_id = $2)", id, tenantArg(ctx))
+	if err == sql.ErrNoRows {
+		return nil, apperrors.New(apperrors.ErrNotFound, "webhook subscription %d not found", id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	sub := row.subscription()
+	return &sub, nil
+}
+
+// ListWebhookSubscriptions returns the subscriptions of the tenant of ctx in a
+// status, every subscription if status is empty, oldest first
+func (s *Store) ListWebhookSubscriptions(ctx context.Context, status string) ([]models.WebhookSubscription, error) {
+	var rows []webhookSubscriptionRow
+	err := s.selectWithFailover(ctx, "list_webhook_subscriptions", &rows,
+		"SELECT * FROM webhook_subscriptions WHERE ($1 = '' OR status = $1) AND ($2 = '' OR tenant_id = $2) ORDER BY id",
+		status, tenantArg(ctx))
+	if err != nil {
+		return nil, err
+	}
+	return webhookSubscriptions(rows), nil
+}
+
+// EnableWebhookSubscription re-enables a subscription with a clean failure count
+func (s *Store) EnableWebhookSubscription(ctx context.Context, id int64) error {
+	res, err := s.exec(ctx, "enable_webhook_subscription",
+		`UPDATE webhook_subscriptions
+		SET status = $2, consecutive_failures = 0, disabled_reason = NULL, disabled_at = NULL, updated_at = NOW()
+		WHERE id = $1 AND ($3 = '' OR tenant_id = $3)`,
+		id, models.WebhookActive, tenantArg(ctx))
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return apperrors.New(apperrors.ErrNotFound, "webhook subscription %d not found", id)
+	}
+	return nil
+}
+
+// DeleteWebhookSubscription deletes a subscription with its deliveries
+func (s *Store) DeleteWebhookSubscription(ctx context.Context, id int64) error {
+	res, err := s.exec(ctx, "delete_webhook_subscription",
+		"DELETE FROM webhook_subscriptions WHERE id = $1 AND ($2 = '' OR tenant_id = $2)", id, tenantArg(ctx))
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return apperrors.New(apperrors.ErrNotFound, "webhook subscription %d not found", id)
+	}
+	return nil
+}
+
+// CreateWebhookDelivery queues an event for a subscription. Returns false if
+// the event was already queued for it.
+func (s *Store) CreateWebhookDelivery(ctx context.Context, d *models.WebhookDelivery) (bool, error) {
+	err := s.get(ctx, "create_webhook_delivery", d,
+		`INSERT INTO webhook_deliveries (subscription_id, event_id, event_type, payload, status, next_attempt_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (subscription_id, event_id) DO NOTHING
+		RETURNING id, created_at, updated_at`,
+		d.SubscriptionID, d.EventID, d.EventType, d.Payload, d.Status, d.NextAttemptAt.UTC())
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// ClaimDueWebhookDeliveries claims up to limit pending deliveries of active
+// subscriptions that are due, oldest first, by moving them claimTTL into the
+// future, so that a worker that dies holding a claim hands it over once it
+// expires. Deliveries of disabled subscriptions wait until they are re-enabled.
+func (s *Store) ClaimDueWebhookDeliveries(ctx context.Context, now time.Time, claimTTL time.Duration, limit int) ([]models.WebhookDelivery, error) {
+	var deliveries []models.WebhookDelivery
+	err := s.selectAll(ctx, "claim_due_webhook_deliveries", &deliveries,
+		`UPDATE webhook_deliveries SET next_attempt_at = $1, updated_at = NOW()
+		WHERE id IN (
+			SELECT d.id FROM webhook_deliveries d
+			JOIN webhook_subscriptions s ON s.id = d.subscription_id
+			WHERE d.status = $2 AND d.next_attempt_at <= $3 AND s.status = $4
+			ORDER BY d.next_attempt_at
+			LIMIT $5
+			FOR UPDATE OF d SKIP LOCKED
+		)
+		RETURNING *`,
+		now.Add(claimTTL).UTC(), models.WebhookDeliveryPending, now.UTC(), models.WebhookActive, limit)
+	return deliveries, err
+}
+
+// RecordWebhookAttempt records the outcome of a delivery attempt and counts
+// it against the subscription: a success resets its consecutive failures, a
+// failure disables it once they reach disableAfter. Returns whether this
+// attempt disabled the subscription.
+func (s *Store) RecordWebhookAttempt(ctx context.Context, d *models.WebhookDelivery, succeeded bool, disableAfter int) (bool, error) {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	if _, err := s.execTx(ctx, tx, "update_webhook_delivery",
+		`UPDATE webhook_deliveries
+		SET status = $2, attempts = $3, next_attempt_at = $4, last_status_code = $5, last_error = $6,
+			last_attempt_at = $7, delivered_at = $8, updated_at = NOW()
+		WHERE id = $1`,
+		d.ID, d.Status, d.Attempts, d.NextAttemptAt.UTC(), d.LastStatusCode, d.LastError,
+		d.LastAttemptAt, d.DeliveredAt); err != nil {
+		return false, err
+	}
+
+	disabled := false
+	if succeeded {
+		_, err = s.execTx(ctx, tx, "reset_webhook_failures",
+			"UPDATE webhook_subscriptions SET consecutive_failures = 0, updated_at = NOW() WHERE id = $1 AND consecutive_failures > 0",
+			d.SubscriptionID)
+	} else {
+		err = s.getTx(ctx, tx, "count_webhook_failure", &disabled,
+			`UPDATE webhook_subscriptions
+			SET consecutive_failures = consecutive_failures + 1,
+				status = CASE WHEN consecutive_failures + 1 >= $2 THEN $3 ELSE status END,
+				disabled_reason = CASE WHEN consecutive_failures + 1 >= $2 THEN $4 ELSE disabled_reason END,
+				disabled_at = CASE WHEN consecutive_failures + 1 >= $2 THEN NOW() ELSE disabled_at END,
+				updated_at = NOW()
+			WHERE id = $1 AND status = $5
+			RETURNING status = $3`,
+			d.SubscriptionID, disableAfter, models.WebhookDisabled, "too many failed deliveries: "+d.LastError, models.WebhookActive)
+		if err == sql.ErrNoRows {
+			err = nil
+		}
+	}
+	if err != nil {
+		return false, err
+	}
+	return disabled, tx.Commit()
+}
+
+// ListWebhookDeliveries returns the latest deliveries of a subscription,
+// newest first
+func (s *Store) ListWebhookDeliveries(ctx context.Context, subscriptionID int64, limit int) ([]models.WebhookDelivery, error) {
+	deliveries := []models.WebhookDelivery{}
+	err := s.selectWithFailover(ctx, "list_webhook_deliveries", &deliveries,
+		"SELECT * FROM webhook_deliveries WHERE subscription_id = $1 ORDER BY created_at DESC, id DESC LIMIT $2",
+		subscriptionID, limit)
+	return deliveries, err
+}
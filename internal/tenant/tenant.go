@@ -0,0 +1,76 @@
+// Package tenant carries the storefront a request or event is handled for.
+//
+// Requests and events act for one tenant, which the store restricts reads to
+// and stamps on the rows it creates. Background work not started for a tenant
+// (sweepers, schedulers, admin requests naming none) has no tenant and sees
+// every tenant's data.
+package tenant
+
+import (
+	"context"
+	"regexp"
+)
+
+// Default owns every row created before tenancy and by requests that carry
+// no tenant credentials
+const Default = "default"
+
+// Header is the Kafka message header carrying the tenant of an event
+const Header = "tenant-id"
+
+// validID keeps tenant IDs short and safe in Redis keys and headers
+var validID = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,62}$`)
+
+type contextKey struct{}
+
+// Valid reports whether id can name a tenant
+func Valid(id string) bool {
+	return validID.MatchString(id)
+}
+
+// WithID returns ctx acting for tenant id
+func WithID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// Detach returns ctx acting for no tenant, e.g. to fill a cache shared by all
+func Detach(ctx context.Context) context.Context {
+	return context.WithValue(ctx, contextKey{}, "")
+}
+
+// ID returns the tenant ctx acts for, if any
+func ID(ctx context.Context) (string, bool) {
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id, id != ""
+}
+
+// Owner returns the tenant rows created under ctx belong to
+func Owner(ctx context.Context) string {
+	if id, ok := ID(ctx); ok {
+		return id
+	}
+	return Default
+}
+
+// Owns reports whether ctx may see a row owned by owner; an empty owner is
+// the default tenant, as in data cached before tenancy
+func Owns(ctx context.Context, owner string) bool {
+	id, ok := ID(ctx)
+	if !ok {
+		return true
+	}
+	if owner == "" {
+		owner = Default
+	}
+	return id == owner
+}
+
+// Key prefixes a Redis key with the tenant of ctx, so tenants choosing the
+// same name (e.g. an idempotency key) do not collide. Keys of the default
+// tenant and of work for no tenant are left as they were before tenancy.
+func Key(ctx context.Context, key string) string {
+	if id, ok := ID(ctx); ok && id != Default {
+		return "tenant:" + id + ":" + key
+	}
+	return key
+}
@@ -0,0 +1,43 @@
+package tenant
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOwnership(t *testing.T) {
+	ctx := context.Background()
+	acme := WithID(ctx, "acme")
+
+	_, ok := ID(ctx)
+	assert.False(t, ok)
+	assert.Equal(t, Default, Owner(ctx))
+	assert.Equal(t, "acme", Owner(acme))
+
+	assert.True(t, Owns(ctx, "acme"), "work for no tenant sees every tenant")
+	assert.True(t, Owns(acme, "acme"))
+	assert.False(t, Owns(acme, "globex"))
+	assert.False(t, Owns(acme, ""), "unstamped data is the default tenant's")
+	assert.True(t, Owns(WithID(ctx, Default), ""))
+
+	assert.True(t, Owns(Detach(acme), "globex"))
+	assert.Equal(t, Default, Owner(Detach(acme)))
+}
+
+func TestKey(t *testing.T) {
+	ctx := context.Background()
+	assert.Equal(t, "idempotency:k", Key(ctx, "idempotency:k"))
+	assert.Equal(t, "idempotency:k", Key(WithID(ctx, Default), "idempotency:k"))
+	assert.Equal(t, "tenant:acme:idempotency:k", Key(WithID(ctx, "acme"), "idempotency:k"))
+}
+
+func TestValid(t *testing.T) {
+	assert.True(t, Valid("acme"))
+	assert.True(t, Valid("acme-eu_2"))
+	assert.False(t, Valid(""))
+	assert.False(t, Valid("Acme"))
+	assert.False(t, Valid("acme:eu"))
+	assert.False(t, Valid("-acme"))
+}
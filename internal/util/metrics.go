@@ -26,11 +26,82 @@ var (
 		Help: "Total number of failed orders",
 	}, []string{"reason"})
 
+	OrderItemsSkippedTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "order_items_skipped_total",
+		Help: "Total number of items left out of allow_partial orders because their product disappeared",
+	})
+
 	OrdersCancelledTotal = promauto.NewCounter(prometheus.CounterOpts{
 		Name: "orders_cancelled_total",
 		Help: "Total number of cancelled orders",
 	})
 
+	OrdersAmendedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "orders_amended_total",
+		Help: "Total number of order item edits before payment, by result",
+	}, []string{"result"})
+
+	CustomerCancellationsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "customer_cancellations_total",
+		Help: "Total number of orders cancelled by customers, by cancellation policy and whether within the free window",
+	}, []string{"policy", "window"})
+
+	StockCommitFailuresTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "stock_commit_failures_total",
+		Help: "Total number of paid orders whose stock commit failed, by compensation outcome",
+	}, []string{"outcome"})
+
+	CompensationsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "compensations_total",
+		Help: "Total number of failed compensation steps queued and retried, by kind and result",
+	}, []string{"kind", "result"})
+
+	CompensationsAwaitingResolution = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "compensations_awaiting_resolution",
+		Help: "Compensations that ran out of retries and await manual resolution",
+	})
+
+	NotificationsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "notifications_total",
+		Help: "Total number of order notifications queued and delivered, by channel and result",
+	}, []string{"channel", "result"})
+
+	WebhookDeliveriesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "webhook_deliveries_total",
+		Help: "Total number of events queued and delivered to webhook subscriptions, by result",
+	}, []string{"result"})
+
+	WebhookSubscriptionsDisabledTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "webhook_subscriptions_disabled_total",
+		Help: "Total number of webhook subscriptions disabled after repeated delivery failures",
+	})
+
+	OrderStageDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "order_stage_duration_seconds",
+		Help:    "Duration of order lifecycle stages",
+		Buckets: []float64{0.1, 0.5, 1, 5, 15, 60, 300, 900, 3600, 86400},
+	}, []string{"stage"})
+
+	OrderSLABreachesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "order_sla_breaches_total",
+		Help: "Total number of order lifecycle stages that exceeded their SLA target",
+	}, []string{"stage"})
+
+	UsersAnonymizedTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "users_anonymized_total",
+		Help: "Total number of deleted users whose order history was anonymized",
+	})
+
+	OrdersAnonymizedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "orders_anonymized_total",
+		Help: "Total number of orders processed for account closures, by action",
+	}, []string{"action"})
+
+	OrdersExpiredTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "orders_expired_total",
+		Help: "Total number of orders cancelled after missing their payment deadline",
+	})
+
 	InventoryReserveLatency = promauto.NewHistogram(prometheus.HistogramOpts{
 		Name:    "inventory_reserve_latency_seconds",
 		Help:    "Latency of inventory reservation operations",
@@ -42,6 +113,173 @@ var (
 		Help: "Total number of failed inventory reservations",
 	}, []string{"reason"})
 
+	InventoryReservationsByStrategy = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "inventory_reservations_by_strategy_total",
+		Help: "Total number of inventory reservation attempts by the product's reservation strategy",
+	}, []string{"strategy"})
+
+	InventoryQuotaLeasesTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "inventory_quota_leases_total",
+		Help: "Total number of stock blocks leased from Redis for leased-quota products",
+	})
+
+	OrderRiskScore = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "order_risk_score",
+		Help:    "Fraud risk scores of created orders",
+		Buckets: prometheus.LinearBuckets(10, 10, 10),
+	})
+
+	OrdersByRiskBandTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "orders_by_risk_band_total",
+		Help: "Total number of created orders by fraud risk band",
+	}, []string{"band"})
+
+	OrdersByPriorityTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "orders_by_priority_total",
+		Help: "Total number of created orders by priority",
+	}, []string{"priority"})
+
+	OrderItemsBackorderedTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "order_items_backordered_total",
+		Help: "Total number of order items backordered because they were out of stock",
+	})
+
+	BackordersRescheduledTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "backorders_rescheduled_total",
+		Help: "Total number of orders whose backordered items were replanned after incoming stock changed",
+	})
+
+	BackordersFulfilledTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "backorders_fulfilled_total",
+		Help: "Total number of backordered items processed by the fulfillment job by result",
+	}, []string{"result"})
+
+	InventoryReservationReplaysTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "inventory_reservation_replays_total",
+		Help: "Total number of reserve and release calls skipped because the order's reservation was already in that state",
+	}, []string{"operation"})
+
+	ProductReservationsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "product_reservations_total",
+		Help: "Reservation attempts of allow-listed products, by SKU and result",
+	}, []string{"sku", "result"})
+
+	ProductReservedUnitsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "product_reserved_units_total",
+		Help: "Units reserved of allow-listed products, by SKU",
+	}, []string{"sku"})
+
+	ProductFirstReservationTimestamp = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "product_first_reservation_timestamp_seconds",
+		Help: "When this pod first reserved an allow-listed product",
+	}, []string{"sku"})
+
+	ProductSoldOutTimestamp = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "product_sold_out_timestamp_seconds",
+		Help: "When an allow-listed product was last seen selling out",
+	}, []string{"sku"})
+
+	ProductMetricsDroppedSKUs = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "product_metrics_dropped_skus",
+		Help: "SKUs left out of per-product metrics because the allow-list exceeds its cap",
+	})
+
+	InventoryDriftTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "inventory_drift_total",
+		Help: "Total number of Redis/DB inventory discrepancies detected",
+	}, []string{"field"})
+
+	InventoryDriftHealedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "inventory_drift_healed_total",
+		Help: "Total number of inventory discrepancies auto-healed",
+	}, []string{"strategy"})
+
+	InventoryDriftedProducts = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "inventory_drifted_products",
+		Help: "Number of products with Redis/DB drift in the last reconciliation run",
+	})
+
+	InventoryLowStockAlertsTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "inventory_low_stock_alerts_total",
+		Help: "Total number of variants whose available stock fell to or below their reorder threshold",
+	})
+
+	AvailabilityFeedConnections = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "availability_feed_connections",
+		Help: "Number of open availability stream connections",
+	})
+
+	AvailabilityFeedDroppedTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "availability_feed_dropped_total",
+		Help: "Total number of availability updates dropped for slow subscribers",
+	})
+
+	OrderStatusFeedConnections = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "order_status_feed_connections",
+		Help: "Number of open order tracking stream connections",
+	})
+
+	OrderStatusFeedDroppedTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "order_status_feed_dropped_total",
+		Help: "Total number of order status changes dropped for slow subscribers",
+	})
+
+	EventsScheduledTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "events_scheduled_total",
+		Help: "Total number of events scheduled for later publishing",
+	}, []string{"event_type"})
+
+	AnalyticsEventsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "analytics_events_total",
+		Help: "Total number of events fanned out to the analytics topic by event and result",
+	}, []string{"event", "result"})
+
+	AuditEntriesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "audit_entries_total",
+		Help: "Total number of admin mutations audited, by result (recorded, failed)",
+	}, []string{"result"})
+
+	ScheduledEventsDispatchedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "scheduled_events_dispatched_total",
+		Help: "Total number of due scheduled events handled, by result (published, skipped, failed, invalid)",
+	}, []string{"event_type", "result"})
+
+	ScheduledEventsPending = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "scheduled_events_pending",
+		Help: "Number of scheduled events not yet due",
+	})
+
+	StatusChangesForwardedTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "status_changes_forwarded_total",
+		Help: "Total number of order status changes published by the notify bridge",
+	})
+
+	StatusChangeForwardLag = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "status_change_forward_lag_seconds",
+		Help:    "Time from an order status change being recorded to the notify bridge publishing it",
+		Buckets: []float64{.01, .05, .1, .5, 1, 5, 10, 30, 60, 300},
+	})
+
+	RealtimeConnections = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "realtime_connections",
+		Help: "Number of open order status WebSocket connections",
+	})
+
+	RealtimeRejectedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "realtime_rejected_total",
+		Help: "Total number of order status WebSocket connections refused",
+	}, []string{"reason"})
+
+	RealtimeNotificationsTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "realtime_notifications_total",
+		Help: "Total number of order status notifications written to WebSockets",
+	})
+
+	RealtimeSlowConsumersTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "realtime_slow_consumers_total",
+		Help: "Total number of WebSockets closed because their send buffer was full",
+	})
+
 	PaymentAttemptsTotal = promauto.NewCounter(prometheus.CounterOpts{
 		Name: "payment_attempts_total",
 		Help: "Total number of payment attempts",
@@ -57,12 +295,308 @@ var (
 		Help: "Total number of failed payments",
 	})
 
+	WalletTransactionsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "wallet_transactions_total",
+		Help: "Total number of wallet debits and credits, by kind",
+	}, []string{"kind"})
+
+	WalletConflictsTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "wallet_conflicts_total",
+		Help: "Total number of wallet updates retried after a concurrent change",
+	})
+
+	PaymentDeclinesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "payment_declines_total",
+		Help: "Total number of declined payments, by decline class",
+	}, []string{"class"})
+
+	PaymentRetriesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "payment_retries_total",
+		Help: "Total number of payment retries after soft declines, by result (scheduled, retried, dropped or exhausted)",
+	}, []string{"result"})
+
 	PaymentProcessingLatency = promauto.NewHistogram(prometheus.HistogramOpts{
 		Name:    "payment_processing_latency_seconds",
 		Help:    "Latency of payment processing",
 		Buckets: prometheus.DefBuckets,
 	})
 
+	ProductCacheHitsTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "product_cache_hits_total",
+		Help: "Total number of product lookups served from Redis",
+	})
+
+	ProductCacheMissesTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "product_cache_misses_total",
+		Help: "Total number of product lookups that fell through to the database",
+	})
+
+	ProductCacheLocalHitsTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "product_cache_local_hits_total",
+		Help: "Total number of product lookups served from the in-process cache",
+	})
+
+	ProductCacheCoalescedTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "product_cache_coalesced_total",
+		Help: "Total number of product cache loads that shared another caller's in-flight read",
+	})
+
+	WarehouseStockReadsCoalescedTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "warehouse_stock_reads_coalesced_total",
+		Help: "Total number of warehouse stock reads that shared another reservation's in-flight Redis read",
+	})
+
+	FlashOrdersTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "flash_orders_total",
+		Help: "Total number of orders accepted in flash-sale mode, and of those placed or failed when persisted",
+	}, []string{"outcome"})
+
+	WaitingRoomEnqueuedTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "waiting_room_enqueued_total",
+		Help: "Total number of order requests put in line in the waiting room",
+	})
+
+	WaitingRoomAdmittedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "waiting_room_admitted_total",
+		Help: "Total number of order requests admitted from the waiting room by result",
+	}, []string{"result"})
+
+	WaitingRoomLength = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "waiting_room_length",
+		Help: "Order requests waiting in line in the waiting room",
+	})
+
+	ExpressLaneFallbacksTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "express_lane_fallbacks_total",
+		Help: "Total number of express order events the express lane could not take, handled by the standard lane",
+	})
+
+	FairReservationsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "fair_reservations_total",
+		Help: "Total number of reservations served from a fair reservation queue, or given up on, by result",
+	}, []string{"result"})
+
+	FairReservationQueueWait = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "fair_reservation_queue_wait_seconds",
+		Help:    "Time a reservation waited in a fair reservation queue before it was served",
+		Buckets: []float64{0.001, 0.005, 0.01, 0.05, 0.1, 0.5, 1, 5},
+	})
+
+	OrderSummaryProjectionLag = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "order_summary_projection_lag_seconds",
+		Help:    "Time from an order or payment event to its projection into the order summaries",
+		Buckets: []float64{0.05, 0.1, 0.5, 1, 5, 15, 60, 300},
+	})
+
+	ReportsPublishedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "reports_published_total",
+		Help: "Total number of scheduled reports published to storage by format and result",
+	}, []string{"format", "result"})
+
+	InvoicesGeneratedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "invoices_generated_total",
+		Help: "Total number of PDF invoices of confirmed orders generated and stored, by result",
+	}, []string{"result"})
+
+	StoreRetriesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "store_retries_total",
+		Help: "Total number of store operations retried after a transient Postgres conflict",
+	}, []string{"operation", "code"})
+
+	StoreRetryExhaustedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "store_retry_exhausted_total",
+		Help: "Total number of store operations that failed after exhausting retries",
+	}, []string{"operation", "code"})
+
+	StoreReadRetriesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "store_read_retries_total",
+		Help: "Total number of idempotent reads retried after a database connection error",
+	}, []string{"operation"})
+
+	DBConnectionErrorsTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "db_connection_errors_total",
+		Help: "Total number of database calls that failed on a broken connection",
+	})
+
+	DBPoolResetsTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "db_pool_resets_total",
+		Help: "Total number of database connection pool resets after repeated connection errors",
+	})
+
+	DBQueryDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "db_query_duration_seconds",
+		Help:    "Latency of database queries by query name",
+		Buckets: []float64{0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5},
+	}, []string{"query"})
+
+	DBQueryErrorsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "db_query_errors_total",
+		Help: "Total number of database queries that failed by query name",
+	}, []string{"query"})
+
+	DBSlowQueriesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "db_slow_queries_total",
+		Help: "Total number of database queries slower than DB_SLOW_QUERY_MS by query name",
+	}, []string{"query"})
+
+	StoreReplicaReadsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "store_replica_reads_total",
+		Help: "Total number of reads sent to a read replica, by whether the replica served them or they fell back to the primary",
+	}, []string{"operation", "result"})
+
+	IdempotencyRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_idempotency_requests_total",
+		Help: "Total number of requests carrying an Idempotency-Key, by outcome",
+	}, []string{"outcome"})
+
+	SyntheticProbeSuccess = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "synthetic_probe_success",
+		Help: "1 if the last synthetic end-to-end probe order was confirmed within SLO, 0 otherwise",
+	})
+
+	SyntheticProbeRunsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "synthetic_probe_runs_total",
+		Help: "Total number of synthetic probe runs",
+	}, []string{"result"})
+
+	SyntheticProbeDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "synthetic_probe_duration_seconds",
+		Help:    "End-to-end latency of synthetic probe orders",
+		Buckets: prometheus.DefBuckets,
+	})
+
+	EventsRedispatchedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "events_redispatched_total",
+		Help: "Total number of order events re-published on startup because they were never published",
+	}, []string{"event_type"})
+
+	SagaRecoveryActionsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "saga_recovery_actions_total",
+		Help: "Total number of stalled orders acted on by saga recovery, by action",
+	}, []string{"action"})
+
+	ConsumerBacklog = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "consumer_backlog_messages",
+		Help: "Messages each worker's consumer is behind the end of its topic",
+	}, []string{"worker", "topic"})
+
+	WorkerHeartbeatAge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "worker_heartbeat_age_seconds",
+		Help: "Seconds since each worker of this instance last had a heartbeat",
+	}, []string{"worker"})
+
+	KafkaConsumerLag = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "kafka_consumer_lag_messages",
+		Help: "Messages behind the partition's high water mark as of the last message fetched",
+	}, []string{"consumer_group", "topic", "partition"})
+
+	KafkaFetchErrorsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "kafka_fetch_errors_total",
+		Help: "Total number of failed Kafka fetches",
+	}, []string{"topic"})
+
+	KafkaCommitErrorsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "kafka_commit_errors_total",
+		Help: "Total number of failed Kafka offset commits",
+	}, []string{"topic"})
+
+	KafkaPublishDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "kafka_publish_duration_seconds",
+		Help:    "Latency of publishing an event to Kafka, including retries",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"topic", "result"})
+
+	KafkaMessageHandleDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "kafka_message_handle_duration_seconds",
+		Help:    "Time spent handling a consumed message, by event type",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"topic", "event_type", "result"})
+
+	KafkaBrokerUp = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "kafka_broker_up",
+		Help: "Whether the broker answered the last health check that tried it (1) or not (0)",
+	}, []string{"broker"})
+
+	SagasInFlight = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "sagas_in_flight",
+		Help: "Orders whose saga has started but not reached a terminal status",
+	})
+
+	ReservationQueueDepth = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "reservation_queue_depth",
+		Help: "Orders holding reserved stock while waiting for payment",
+	})
+
+	EventsIngestedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "events_ingested_total",
+		Help: "Total number of CloudEvents received over HTTP, by outcome",
+	}, []string{"outcome"})
+
+	DeadLettersTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "dead_letters_total",
+		Help: "Total number of consumed messages parked after their handler failed",
+	}, []string{"consumer_group"})
+
+	DeadLetterRedrivesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "dead_letter_redrives_total",
+		Help: "Total number of dead letters selected for re-drive, by result",
+	}, []string{"result"})
+
+	EventsReplayedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "events_replayed_total",
+		Help: "Total number of historical events matched by a replay, by mode and result",
+	}, []string{"mode", "result"})
+
+	OrderTimelineWriteErrorsTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "order_timeline_write_errors_total",
+		Help: "Total number of events that could not be recorded in an order timeline",
+	})
+
+	OrderValidationFailuresTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "order_validation_failures_total",
+		Help: "Total number of CreateOrder request fields rejected, by the rule they broke",
+	}, []string{"rule"})
+
+	AdminActionsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "admin_actions_total",
+		Help: "Total number of operator actions taken through the admin API",
+	}, []string{"action"})
+
+	InventoryImportRowsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "inventory_import_rows_total",
+		Help: "Total number of rows processed by bulk inventory imports, by result",
+	}, []string{"result"})
+
+	KillSwitchRejectionsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "kill_switch_rejections_total",
+		Help: "Total number of orders rejected by a kill switch, by kill switch kind",
+	}, []string{"kind"})
+
+	DropRegistrationsTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "drop_registrations_total",
+		Help: "Total number of registrations for product drops",
+	})
+
+	DropConversionsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "drop_conversions_total",
+		Help: "Total number of drop registrations converted at drop time, by result",
+	}, []string{"result"})
+
+	CartCheckoutsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "cart_checkouts_total",
+		Help: "Total number of cart checkouts, by result",
+	}, []string{"result"})
+
+	StockHoldsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "stock_holds_total",
+		Help: "Total number of soft stock holds placed at checkout start, by result",
+	}, []string{"result"})
+
+	EventSchemaViolationsTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "event_schema_violations_total",
+		Help: "Total number of outbound events rejected by JSON Schema validation",
+	})
+
 	HTTPRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
 		Name:    "http_request_duration_seconds",
 		Help:    "HTTP request latency",
@@ -0,0 +1,47 @@
+package worker
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"order-service/internal/service"
+)
+
+// BackorderWorker periodically reserves replenished stock for backordered items
+type BackorderWorker struct {
+	fulfiller *service.BackorderFulfiller
+	interval  time.Duration
+}
+
+// NewBackorderWorker creates a new backorder worker
+func NewBackorderWorker(fulfiller *service.BackorderFulfiller, interval time.Duration) *BackorderWorker {
+	return &BackorderWorker{
+		fulfiller: fulfiller,
+		interval:  interval,
+	}
+}
+
+// Start fulfills backorders on every tick until ctx is cancelled
+func (w *BackorderWorker) Start(ctx context.Context) error {
+	log.Printf("Starting backorder worker: interval=%s", w.interval)
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			fulfilled, err := w.fulfiller.FulfillBackorders(ctx)
+			if err != nil {
+				log.Printf("Backorder fulfillment failed: %v", err)
+				continue
+			}
+			if fulfilled > 0 {
+				log.Printf("Fulfilled %d backordered item(s)", fulfilled)
+			}
+		}
+	}
+}
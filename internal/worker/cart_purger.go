@@ -0,0 +1,47 @@
+package worker
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"order-service/internal/service"
+)
+
+// CartPurgeWorker periodically deletes expired carts
+type CartPurgeWorker struct {
+	carts    *service.CartService
+	interval time.Duration
+}
+
+// NewCartPurgeWorker creates a new cart purge worker
+func NewCartPurgeWorker(carts *service.CartService, interval time.Duration) *CartPurgeWorker {
+	return &CartPurgeWorker{
+		carts:    carts,
+		interval: interval,
+	}
+}
+
+// Start deletes expired carts on every tick until ctx is cancelled
+func (w *CartPurgeWorker) Start(ctx context.Context) error {
+	log.Printf("Starting cart purge worker: interval=%s", w.interval)
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			purged, err := w.carts.PurgeExpired(ctx)
+			if err != nil {
+				log.Printf("Cart purge failed: %v", err)
+				continue
+			}
+			if purged > 0 {
+				log.Printf("Deleted %d expired cart(s)", purged)
+			}
+		}
+	}
+}
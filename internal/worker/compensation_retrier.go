@@ -0,0 +1,48 @@
+package worker
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"order-service/internal/service"
+)
+
+// CompensationRetryWorker retries failed saga compensation steps once their
+// retry falls due, money-related steps first
+type CompensationRetryWorker struct {
+	saga     *service.SagaOrchestrator
+	interval time.Duration
+}
+
+// NewCompensationRetryWorker creates a new compensation retry worker
+func NewCompensationRetryWorker(saga *service.SagaOrchestrator, interval time.Duration) *CompensationRetryWorker {
+	return &CompensationRetryWorker{
+		saga:     saga,
+		interval: interval,
+	}
+}
+
+// Start retries due compensations on every tick until ctx is cancelled
+func (w *CompensationRetryWorker) Start(ctx context.Context) error {
+	log.Printf("Starting compensation retry worker: interval=%s", w.interval)
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			retried, err := w.saga.RetryDueCompensations(ctx)
+			if err != nil {
+				log.Printf("Compensation retry failed: %v", err)
+				continue
+			}
+			if retried > 0 {
+				log.Printf("Retried %d compensation(s)", retried)
+			}
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package worker
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"order-service/internal/service"
+)
+
+// DropWorker periodically converts the registrations of started product drops into orders
+type DropWorker struct {
+	drops    *service.DropService
+	interval time.Duration
+}
+
+// NewDropWorker creates a new drop worker
+func NewDropWorker(drops *service.DropService, interval time.Duration) *DropWorker {
+	return &DropWorker{
+		drops:    drops,
+		interval: interval,
+	}
+}
+
+// Start converts due drops on every tick until ctx is cancelled
+func (w *DropWorker) Start(ctx context.Context) error {
+	log.Printf("Starting drop worker: interval=%s", w.interval)
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			ordered, err := w.drops.ConvertDueDrops(ctx)
+			if err != nil {
+				log.Printf("Drop conversion failed: %v", err)
+			}
+			if ordered > 0 {
+				log.Printf("Created %d order(s) from drop registrations", ordered)
+			}
+		}
+	}
+}
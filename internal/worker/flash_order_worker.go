@@ -0,0 +1,55 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+
+	"order-service/internal/broker"
+	"order-service/internal/health"
+	"order-service/internal/service"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// FlashOrderWorker persists the orders accepted in flash-sale mode to Postgres
+type FlashOrderWorker struct {
+	consumer    *broker.Consumer
+	flashOrders *service.FlashOrders
+	health      *health.Checker
+}
+
+// NewFlashOrderWorker creates a new flash order worker
+func NewFlashOrderWorker(consumer *broker.Consumer, flashOrders *service.FlashOrders, health *health.Checker) *FlashOrderWorker {
+	return &FlashOrderWorker{
+		consumer:    consumer,
+		flashOrders: flashOrders,
+		health:      health,
+	}
+}
+
+// Start starts the flash order worker
+func (fw *FlashOrderWorker) Start(ctx context.Context) error {
+	log.Println("Starting flash order worker...")
+
+	return fw.consumer.StartConsuming(ctx, trackWork(fw.health, "flash-order-worker", func(ctx context.Context, msg kafka.Message) error {
+		var order service.FlashOrder
+		if err := json.Unmarshal(msg.Value, &order); err != nil {
+			log.Printf("Failed to unmarshal flash order: %v", err)
+			return err
+		}
+
+		return fw.flashOrders.Persist(ctx, &order)
+	}))
+}
+
+// Stop stops the flash order worker
+func (fw *FlashOrderWorker) Stop() error {
+	log.Println("Stopping flash order worker...")
+	return fw.consumer.Close()
+}
+
+// Drain waits for the message being handled when the worker's context was cancelled
+func (fw *FlashOrderWorker) Drain(ctx context.Context) error {
+	return fw.consumer.Drain(ctx)
+}
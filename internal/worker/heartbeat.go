@@ -0,0 +1,42 @@
+package worker
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"order-service/internal/service"
+)
+
+// HeartbeatWorker periodically writes the heartbeats of this instance's workers
+type HeartbeatWorker struct {
+	heartbeats *service.WorkerHeartbeats
+	interval   time.Duration
+}
+
+// NewHeartbeatWorker creates a new heartbeat worker
+func NewHeartbeatWorker(heartbeats *service.WorkerHeartbeats, interval time.Duration) *HeartbeatWorker {
+	return &HeartbeatWorker{
+		heartbeats: heartbeats,
+		interval:   interval,
+	}
+}
+
+// Start writes heartbeats on every tick until ctx is cancelled
+func (w *HeartbeatWorker) Start(ctx context.Context) error {
+	log.Printf("Starting heartbeat worker: interval=%s", w.interval)
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			if err := w.heartbeats.Beat(ctx); err != nil {
+				log.Printf("Worker heartbeat failed: %v", err)
+			}
+		}
+	}
+}
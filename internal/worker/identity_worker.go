@@ -0,0 +1,88 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+
+	"order-service/internal/broker"
+	"order-service/internal/health"
+	"order-service/internal/models"
+	"order-service/internal/service"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// IdentityWorker consumes identity service events: user updates keep the
+// customers current, account closures close the customer and anonymize their
+// orders
+type IdentityWorker struct {
+	consumer      *broker.Consumer
+	customers     *service.CustomerService
+	anonymization *service.AnonymizationService
+	health        *health.Checker
+}
+
+// NewIdentityWorker creates a new identity worker
+func NewIdentityWorker(
+	consumer *broker.Consumer,
+	customers *service.CustomerService,
+	anonymization *service.AnonymizationService,
+	health *health.Checker,
+) *IdentityWorker {
+	return &IdentityWorker{
+		consumer:      consumer,
+		customers:     customers,
+		anonymization: anonymization,
+		health:        health,
+	}
+}
+
+// Start starts the identity worker
+func (iw *IdentityWorker) Start(ctx context.Context) error {
+	log.Println("Starting identity worker...")
+
+	return iw.consumer.StartConsuming(ctx, trackWork(iw.health, "identity-worker", func(ctx context.Context, msg kafka.Message) error {
+		var baseEvent models.BaseEvent
+		if err := json.Unmarshal(msg.Value, &baseEvent); err != nil {
+			log.Printf("Failed to unmarshal event: %v", err)
+			return err
+		}
+
+		switch baseEvent.EventType {
+		case models.EventTypeUserUpdated:
+			var event models.UserUpdatedEvent
+			if err := json.Unmarshal(msg.Value, &event); err != nil {
+				log.Printf("Failed to unmarshal UserUpdated event: %v", err)
+				return err
+			}
+
+			return iw.customers.HandleUserUpdated(ctx, &event)
+
+		case models.EventTypeUserDeleted:
+			var event models.UserDeletedEvent
+			if err := json.Unmarshal(msg.Value, &event); err != nil {
+				log.Printf("Failed to unmarshal UserDeleted event: %v", err)
+				return err
+			}
+
+			if err := iw.customers.HandleUserDeleted(ctx, &event); err != nil {
+				return err
+			}
+			return iw.anonymization.HandleUserDeleted(ctx, &event)
+		}
+
+		return nil
+	}))
+}
+
+// Stop stops the identity worker
+func (iw *IdentityWorker) Stop() error {
+	log.Println("Stopping identity worker...")
+	return iw.consumer.Close()
+}
+
+// Drain waits for the message being handled when the worker's context was cancelled
+func (iw *IdentityWorker) Drain(ctx context.Context) error {
+	return iw.consumer.Drain(ctx)
+}
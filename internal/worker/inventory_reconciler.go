@@ -0,0 +1,53 @@
+package worker
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"order-service/internal/service"
+)
+
+// InventoryReconciler periodically compares Redis inventory against the database
+type InventoryReconciler struct {
+	inventoryClient *service.InventoryClient
+	interval        time.Duration
+	strategy        string
+}
+
+// NewInventoryReconciler creates a new inventory reconciler
+func NewInventoryReconciler(
+	inventoryClient *service.InventoryClient,
+	interval time.Duration,
+	strategy string,
+) *InventoryReconciler {
+	return &InventoryReconciler{
+		inventoryClient: inventoryClient,
+		interval:        interval,
+		strategy:        strategy,
+	}
+}
+
+// Start runs reconciliation on every tick until ctx is cancelled
+func (r *InventoryReconciler) Start(ctx context.Context) error {
+	log.Printf("Starting inventory reconciler: interval=%s, strategy=%s", r.interval, r.strategy)
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			drifted, err := r.inventoryClient.ReconcileInventory(ctx, r.strategy)
+			if err != nil {
+				log.Printf("Inventory reconciliation failed: %v", err)
+				continue
+			}
+			if drifted > 0 {
+				log.Printf("Inventory reconciliation found %d drifted products", drifted)
+			}
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+
+	"order-service/internal/broker"
+	"order-service/internal/health"
+	"order-service/internal/models"
+	"order-service/internal/service"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// InvoiceWorker generates the invoices of confirmed orders from the order
+// events
+type InvoiceWorker struct {
+	consumer *broker.Consumer
+	invoices *service.InvoiceService
+	health   *health.Checker
+}
+
+// NewInvoiceWorker creates a new invoice worker
+func NewInvoiceWorker(
+	consumer *broker.Consumer,
+	invoices *service.InvoiceService,
+	health *health.Checker,
+) *InvoiceWorker {
+	return &InvoiceWorker{
+		consumer: consumer,
+		invoices: invoices,
+		health:   health,
+	}
+}
+
+// Start starts the invoice worker
+func (iw *InvoiceWorker) Start(ctx context.Context) error {
+	log.Println("Starting invoice worker...")
+
+	return iw.consumer.StartConsuming(ctx, trackWork(iw.health, "invoice-worker", func(ctx context.Context, msg kafka.Message) error {
+		var baseEvent models.BaseEvent
+		if err := json.Unmarshal(msg.Value, &baseEvent); err != nil {
+			log.Printf("Failed to unmarshal event: %v", err)
+			return err
+		}
+		if baseEvent.EventType != models.EventTypeOrderConfirmed {
+			return nil
+		}
+
+		var event models.OrderConfirmedEvent
+		if err := json.Unmarshal(msg.Value, &event); err != nil {
+			log.Printf("Failed to unmarshal OrderConfirmed event: %v", err)
+			return err
+		}
+		return iw.invoices.HandleOrderConfirmed(ctx, &event)
+	}))
+}
+
+// Stop stops the invoice worker
+func (iw *InvoiceWorker) Stop() error {
+	log.Println("Stopping invoice worker...")
+	return iw.consumer.Close()
+}
+
+// Drain waits for the message being handled when the worker's context was cancelled
+func (iw *InvoiceWorker) Drain(ctx context.Context) error {
+	return iw.consumer.Drain(ctx)
+}
@@ -0,0 +1,48 @@
+package worker
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"order-service/internal/notification"
+)
+
+// NotificationDispatchWorker sends queued customer notifications once they
+// are due, retrying failed deliveries with backoff
+type NotificationDispatchWorker struct {
+	notifications *notification.Service
+	interval      time.Duration
+}
+
+// NewNotificationDispatchWorker creates a new notification dispatch worker
+func NewNotificationDispatchWorker(notifications *notification.Service, interval time.Duration) *NotificationDispatchWorker {
+	return &NotificationDispatchWorker{
+		notifications: notifications,
+		interval:      interval,
+	}
+}
+
+// Start sends due notifications on every tick until ctx is cancelled
+func (w *NotificationDispatchWorker) Start(ctx context.Context) error {
+	log.Printf("Starting notification dispatch worker: interval=%s", w.interval)
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			sent, err := w.notifications.DispatchDue(ctx)
+			if err != nil {
+				log.Printf("Notification dispatch failed: %v", err)
+				continue
+			}
+			if sent > 0 {
+				log.Printf("Sent %d notification(s)", sent)
+			}
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+
+	"order-service/internal/broker"
+	"order-service/internal/health"
+	"order-service/internal/models"
+	"order-service/internal/notification"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// NotificationWorker queues customer notifications of confirmed and cancelled
+// orders from the order events
+type NotificationWorker struct {
+	consumer      *broker.Consumer
+	notifications *notification.Service
+	health        *health.Checker
+}
+
+// NewNotificationWorker creates a new notification worker
+func NewNotificationWorker(
+	consumer *broker.Consumer,
+	notifications *notification.Service,
+	health *health.Checker,
+) *NotificationWorker {
+	return &NotificationWorker{
+		consumer:      consumer,
+		notifications: notifications,
+		health:        health,
+	}
+}
+
+// Start starts the notification worker
+func (nw *NotificationWorker) Start(ctx context.Context) error {
+	log.Println("Starting notification worker...")
+
+	return nw.consumer.StartConsuming(ctx, trackWork(nw.health, "notification-worker", func(ctx context.Context, msg kafka.Message) error {
+		var baseEvent models.BaseEvent
+		if err := json.Unmarshal(msg.Value, &baseEvent); err != nil {
+			log.Printf("Failed to unmarshal event: %v", err)
+			return err
+		}
+
+		switch baseEvent.EventType {
+		case models.EventTypeOrderConfirmed:
+			var event models.OrderConfirmedEvent
+			if err := json.Unmarshal(msg.Value, &event); err != nil {
+				log.Printf("Failed to unmarshal OrderConfirmed event: %v", err)
+				return err
+			}
+			return nw.notifications.HandleOrderConfirmed(ctx, &event)
+
+		case models.EventTypeOrderCancelled:
+			var event models.OrderCancelledEvent
+			if err := json.Unmarshal(msg.Value, &event); err != nil {
+				log.Printf("Failed to unmarshal OrderCancelled event: %v", err)
+				return err
+			}
+			return nw.notifications.HandleOrderCancelled(ctx, &event)
+		}
+
+		return nil
+	}))
+}
+
+// Stop stops the notification worker
+func (nw *NotificationWorker) Stop() error {
+	log.Println("Stopping notification worker...")
+	return nw.consumer.Close()
+}
+
+// Drain waits for the message being handled when the worker's context was cancelled
+func (nw *NotificationWorker) Drain(ctx context.Context) error {
+	return nw.consumer.Drain(ctx)
+}
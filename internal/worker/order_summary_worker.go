@@ -0,0 +1,53 @@
+package worker
+
+import (
+	"context"
+	"log"
+
+	"order-service/internal/broker"
+	"order-service/internal/health"
+	"order-service/internal/service"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// OrderSummaryWorker projects the order events into the order summaries read
+// model
+type OrderSummaryWorker struct {
+	consumer  *broker.Consumer
+	projector *service.OrderSummaryProjector
+	health    *health.Checker
+}
+
+// NewOrderSummaryWorker creates a new order summary worker
+func NewOrderSummaryWorker(
+	consumer *broker.Consumer,
+	projector *service.OrderSummaryProjector,
+	health *health.Checker,
+) *OrderSummaryWorker {
+	return &OrderSummaryWorker{
+		consumer:  consumer,
+		projector: projector,
+		health:    health,
+	}
+}
+
+// Start starts the order summary worker
+func (sw *OrderSummaryWorker) Start(ctx context.Context) error {
+	log.Println("Starting order summary worker...")
+
+	return sw.consumer.StartConsuming(ctx, trackWork(sw.health, "order-summary-worker", func(ctx context.Context, msg kafka.Message) error {
+		return sw.projector.Project(ctx, msg.Value)
+	}))
+}
+
+// Stop stops the order summary worker
+func (sw *OrderSummaryWorker) Stop() error {
+	log.Println("Stopping order summary worker...")
+	return sw.consumer.Close()
+}
+
+// Drain waits for the message being handled when the worker's context was cancelled
+func (sw *OrderSummaryWorker) Drain(ctx context.Context) error {
+	return sw.consumer.Drain(ctx)
+}
@@ -0,0 +1,50 @@
+package worker
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"order-service/internal/service"
+)
+
+// reapBatchSize caps how many expired orders are cancelled per tick
+const reapBatchSize = 100
+
+// OrderTimeoutReaper cancels orders that missed their payment deadline
+type OrderTimeoutReaper struct {
+	sagaOrchestrator *service.SagaOrchestrator
+	interval         time.Duration
+}
+
+// NewOrderTimeoutReaper creates a new order timeout reaper
+func NewOrderTimeoutReaper(sagaOrchestrator *service.SagaOrchestrator, interval time.Duration) *OrderTimeoutReaper {
+	return &OrderTimeoutReaper{
+		sagaOrchestrator: sagaOrchestrator,
+		interval:         interval,
+	}
+}
+
+// Start runs the reaper on every tick until ctx is cancelled
+func (r *OrderTimeoutReaper) Start(ctx context.Context) error {
+	log.Printf("Starting order timeout reaper: interval=%s", r.interval)
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			cancelled, err := r.sagaOrchestrator.CancelExpiredOrders(ctx, reapBatchSize)
+			if err != nil {
+				log.Printf("Order timeout reaper failed: %v", err)
+				continue
+			}
+			if cancelled > 0 {
+				log.Printf("Cancelled %d expired orders", cancelled)
+			}
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package worker
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"order-service/internal/service"
+)
+
+// PaymentRetryWorker re-attempts payments declined softly once their retry
+// falls due
+type PaymentRetryWorker struct {
+	payments *service.PaymentService
+	interval time.Duration
+}
+
+// NewPaymentRetryWorker creates a new payment retry worker
+func NewPaymentRetryWorker(payments *service.PaymentService, interval time.Duration) *PaymentRetryWorker {
+	return &PaymentRetryWorker{
+		payments: payments,
+		interval: interval,
+	}
+}
+
+// Start retries due payments on every tick until ctx is cancelled
+func (w *PaymentRetryWorker) Start(ctx context.Context) error {
+	log.Printf("Starting payment retry worker: interval=%s", w.interval)
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			retried, err := w.payments.RetryDuePayments(ctx)
+			if err != nil {
+				log.Printf("Payment retry failed: %v", err)
+				continue
+			}
+			if retried > 0 {
+				log.Printf("Retried %d payment(s)", retried)
+			}
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package worker
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"order-service/internal/reports"
+)
+
+// ReportSchedulerWorker generates the scheduled reports that are due
+type ReportSchedulerWorker struct {
+	scheduler *reports.Scheduler
+}
+
+// NewReportSchedulerWorker creates a new report scheduler worker
+func NewReportSchedulerWorker(scheduler *reports.Scheduler) *ReportSchedulerWorker {
+	return &ReportSchedulerWorker{scheduler: scheduler}
+}
+
+// Start checks for a due report every minute until ctx is cancelled
+func (w *ReportSchedulerWorker) Start(ctx context.Context) error {
+	log.Println("Starting report scheduler worker...")
+
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case now := <-ticker.C:
+			if _, err := w.scheduler.RunDue(ctx, now); err != nil {
+				log.Printf("Scheduled report failed: %v", err)
+			}
+		}
+	}
+}
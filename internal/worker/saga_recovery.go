@@ -0,0 +1,67 @@
+package worker
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"order-service/internal/service"
+)
+
+// SagaRecovery resumes or compensates orders whose saga stalled, once on
+// startup and then periodically
+type SagaRecovery struct {
+	sagaOrchestrator *service.SagaOrchestrator
+	policy           service.RecoveryPolicy
+	interval         time.Duration
+}
+
+// NewSagaRecovery creates a new saga recovery worker; interval 0 runs the startup pass only
+func NewSagaRecovery(
+	sagaOrchestrator *service.SagaOrchestrator,
+	policy service.RecoveryPolicy,
+	interval time.Duration,
+) *SagaRecovery {
+	if policy.BatchSize <= 0 {
+		policy.BatchSize = reapBatchSize
+	}
+
+	return &SagaRecovery{
+		sagaOrchestrator: sagaOrchestrator,
+		policy:           policy,
+		interval:         interval,
+	}
+}
+
+// Start runs a recovery pass immediately and then on every tick until ctx is cancelled
+func (r *SagaRecovery) Start(ctx context.Context) error {
+	log.Printf("Starting saga recovery: interval=%s, stall_threshold=%s", r.interval, r.policy.StallThreshold)
+
+	r.sweep(ctx)
+	if r.interval <= 0 {
+		return nil
+	}
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			r.sweep(ctx)
+		}
+	}
+}
+
+func (r *SagaRecovery) sweep(ctx context.Context) {
+	recovered, err := r.sagaOrchestrator.RecoverStalledOrders(ctx, r.policy)
+	if err != nil {
+		log.Printf("Saga recovery failed: %v", err)
+		return
+	}
+	if recovered > 0 {
+		log.Printf("Saga recovery acted on %d stalled orders", recovered)
+	}
+}
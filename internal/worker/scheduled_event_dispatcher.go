@@ -0,0 +1,42 @@
+package worker
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"order-service/internal/service"
+)
+
+// ScheduledEventWorker publishes scheduled events as they fall due
+type ScheduledEventWorker struct {
+	dispatcher *service.ScheduledEventDispatcher
+	interval   time.Duration
+}
+
+// NewScheduledEventWorker creates a new scheduled event worker
+func NewScheduledEventWorker(dispatcher *service.ScheduledEventDispatcher, interval time.Duration) *ScheduledEventWorker {
+	return &ScheduledEventWorker{
+		dispatcher: dispatcher,
+		interval:   interval,
+	}
+}
+
+// Start dispatches due events on every tick until ctx is cancelled
+func (w *ScheduledEventWorker) Start(ctx context.Context) error {
+	log.Printf("Starting scheduled event worker: interval=%s", w.interval)
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			if _, err := w.dispatcher.DispatchDue(ctx); err != nil {
+				log.Printf("Scheduled event dispatch failed: %v", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package worker
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"order-service/internal/service"
+)
+
+// StatusBridgeWorker forwards order status changes as the database notifies
+// them, and on every tick in case a notification was lost
+type StatusBridgeWorker struct {
+	bridge   *service.StatusChangeBridge
+	interval time.Duration
+}
+
+// NewStatusBridgeWorker creates a new status bridge worker
+func NewStatusBridgeWorker(bridge *service.StatusChangeBridge, interval time.Duration) *StatusBridgeWorker {
+	return &StatusBridgeWorker{
+		bridge:   bridge,
+		interval: interval,
+	}
+}
+
+// Start forwards status changes until ctx is cancelled. Without a listener
+// connection changes are only forwarded on every tick.
+func (w *StatusBridgeWorker) Start(ctx context.Context) error {
+	log.Printf("Starting status notify bridge: interval=%s", w.interval)
+
+	notified, err := w.bridge.Listen(ctx)
+	if err != nil {
+		log.Printf("Status notify bridge is polling only, failed to listen: %v", err)
+	}
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		w.forward(ctx)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-notified:
+		case <-ticker.C:
+		}
+	}
+}
+
+func (w *StatusBridgeWorker) forward(ctx context.Context) {
+	if _, err := w.bridge.Forward(ctx); err != nil && ctx.Err() == nil {
+		log.Printf("Status notify bridge failed to forward changes: %v", err)
+	}
+}
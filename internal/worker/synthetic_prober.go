@@ -0,0 +1,42 @@
+package worker
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"order-service/internal/service"
+)
+
+// SyntheticProber runs the end-to-end synthetic probe on a schedule
+type SyntheticProber struct {
+	probe    *service.SyntheticProbe
+	interval time.Duration
+}
+
+// NewSyntheticProber creates a new synthetic prober
+func NewSyntheticProber(probe *service.SyntheticProbe, interval time.Duration) *SyntheticProber {
+	return &SyntheticProber{
+		probe:    probe,
+		interval: interval,
+	}
+}
+
+// Start runs the probe on every tick until ctx is cancelled
+func (sp *SyntheticProber) Start(ctx context.Context) error {
+	log.Printf("Starting synthetic prober: interval=%s", sp.interval)
+
+	ticker := time.NewTicker(sp.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			if err := sp.probe.Run(ctx); err != nil {
+				log.Printf("Synthetic probe failed: %v", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package worker
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"order-service/internal/service"
+)
+
+// WaitingRoomDispatcher admits order requests waiting in the waiting room
+type WaitingRoomDispatcher struct {
+	room     *service.WaitingRoom
+	interval time.Duration
+}
+
+// NewWaitingRoomDispatcher creates a new waiting room dispatcher
+func NewWaitingRoomDispatcher(room *service.WaitingRoom, interval time.Duration) *WaitingRoomDispatcher {
+	return &WaitingRoomDispatcher{
+		room:     room,
+		interval: interval,
+	}
+}
+
+// Start admits waiting requests on every tick until ctx is cancelled
+func (w *WaitingRoomDispatcher) Start(ctx context.Context) error {
+	log.Printf("Starting waiting room dispatcher: interval=%s", w.interval)
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			if _, err := w.room.Admit(ctx); err != nil {
+				log.Printf("Waiting room admission failed: %v", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package worker
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"order-service/internal/service"
+)
+
+// WebhookDispatchWorker posts queued webhook deliveries once they are due,
+// retrying failed ones with backoff
+type WebhookDispatchWorker struct {
+	webhooks *service.WebhookService
+	interval time.Duration
+}
+
+// NewWebhookDispatchWorker creates a new webhook dispatch worker
+func NewWebhookDispatchWorker(webhooks *service.WebhookService, interval time.Duration) *WebhookDispatchWorker {
+	return &WebhookDispatchWorker{
+		webhooks: webhooks,
+		interval: interval,
+	}
+}
+
+// Start posts due deliveries on every tick until ctx is cancelled
+func (w *WebhookDispatchWorker) Start(ctx context.Context) error {
+	log.Printf("Starting webhook dispatch worker: interval=%s", w.interval)
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			delivered, err := w.webhooks.DeliverDue(ctx)
+			if err != nil {
+				log.Printf("Webhook dispatch failed: %v", err)
+				continue
+			}
+			if delivered > 0 {
+				log.Printf("Delivered %d webhook(s)", delivered)
+			}
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+
+	"order-service/internal/broker"
+	"order-service/internal/health"
+	"order-service/internal/models"
+	"order-service/internal/service"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// WebhookWorker queues the order events third parties subscribed to for
+// delivery to their webhooks
+type WebhookWorker struct {
+	consumer *broker.Consumer
+	webhooks *service.WebhookService
+	health   *health.Checker
+}
+
+// NewWebhookWorker creates a new webhook worker
+func NewWebhookWorker(
+	consumer *broker.Consumer,
+	webhooks *service.WebhookService,
+	health *health.Checker,
+) *WebhookWorker {
+	return &WebhookWorker{
+		consumer: consumer,
+		webhooks: webhooks,
+		health:   health,
+	}
+}
+
+// Start starts the webhook worker
+func (ww *WebhookWorker) Start(ctx context.Context) error {
+	log.Println("Starting webhook worker...")
+
+	return ww.consumer.StartConsuming(ctx, trackWork(ww.health, "webhook-worker", func(ctx context.Context, msg kafka.Message) error {
+		var baseEvent models.BaseEvent
+		if err := json.Unmarshal(msg.Value, &baseEvent); err != nil {
+			log.Printf("Failed to unmarshal event: %v", err)
+			return err
+		}
+
+		return ww.webhooks.Fanout(ctx, baseEvent, msg.Value)
+	}))
+}
+
+// Stop stops the webhook worker
+func (ww *WebhookWorker) Stop() error {
+	log.Println("Stopping webhook worker...")
+	return ww.consumer.Close()
+}
+
+// Drain waits for the message being handled when the worker's context was cancelled
+func (ww *WebhookWorker) Drain(ctx context.Context) error {
+	return ww.consumer.Drain(ctx)
+}
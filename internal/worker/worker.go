@@ -6,6 +6,7 @@ import (
 	"log"
 
 	"order-service/internal/broker"
+	"order-service/internal/health"
 	"order-service/internal/models"
 	"order-service/internal/service"
 
@@ -17,29 +18,60 @@ type OrderWorker struct {
 	consumer         *broker.Consumer
 	eventHandler     *broker.EventHandler
 	sagaOrchestrator *service.SagaOrchestrator
+	health           *health.Checker
+	name             string
+	skipExpress      bool
 }
 
 // NewOrderWorker creates a new order worker
 func NewOrderWorker(
 	consumer *broker.Consumer,
 	sagaOrchestrator *service.SagaOrchestrator,
+	health *health.Checker,
 ) *OrderWorker {
-	eventHandler := broker.NewEventHandler()
-
-	eventHandler.OnPaymentSuccess(sagaOrchestrator.HandlePaymentSuccess)
-	eventHandler.OnPaymentFailed(sagaOrchestrator.HandlePaymentFailed)
-
 	return &OrderWorker{
 		consumer:         consumer,
-		eventHandler:     eventHandler,
+		eventHandler:     sagaEventHandler(sagaOrchestrator),
 		sagaOrchestrator: sagaOrchestrator,
+		health:           health,
+		name:             "order-worker",
 	}
 }
 
+// SetLane makes the worker the worker of a lane once express orders have
+// their own: the express lane's worker is order-worker-express, and the
+// standard lane's leaves express orders to it
+func (w *OrderWorker) SetLane(priority string) {
+	w.name, w.skipExpress = laneWorker("order-worker", priority)
+}
+
+// SagaHandler returns a handler routing payment events to the saga, for
+// delivering events outside the order worker such as replays
+func SagaHandler(sagaOrchestrator *service.SagaOrchestrator) broker.MessageHandler {
+	return sagaEventHandler(sagaOrchestrator).HandleMessage
+}
+
+func sagaEventHandler(sagaOrchestrator *service.SagaOrchestrator) *broker.EventHandler {
+	eventHandler := broker.NewEventHandler()
+	eventHandler.OnPaymentSuccess(sagaOrchestrator.HandlePaymentSuccess)
+	eventHandler.OnPaymentFailed(sagaOrchestrator.HandlePaymentFailed)
+	return eventHandler
+}
+
 // Start starts the worker
 func (w *OrderWorker) Start(ctx context.Context) error {
 	log.Println("Starting order worker...")
-	return w.consumer.StartConsuming(ctx, w.eventHandler.HandleMessage)
+	return w.consumer.StartConsuming(ctx, w.Handler())
+}
+
+// Handler returns the worker's message pipeline, so events from other
+// transports are handled exactly like Kafka messages
+func (w *OrderWorker) Handler() broker.MessageHandler {
+	handler := w.eventHandler.HandleMessage
+	if w.skipExpress {
+		handler = broker.SkipExpress(handler)
+	}
+	return trackWork(w.health, w.name, handler)
 }
 
 // Stop stops the worker
@@ -48,28 +80,46 @@ func (w *OrderWorker) Stop() error {
 	return w.consumer.Close()
 }
 
+// Drain waits for the message being handled when the worker's context was cancelled
+func (w *OrderWorker) Drain(ctx context.Context) error {
+	return w.consumer.Drain(ctx)
+}
+
 // PaymentWorker handles payment processing
 type PaymentWorker struct {
 	consumer       *broker.Consumer
 	paymentService *service.PaymentService
+	health         *health.Checker
+	name           string
+	skipExpress    bool
 }
 
 // NewPaymentWorker creates a new payment worker
 func NewPaymentWorker(
 	consumer *broker.Consumer,
 	paymentService *service.PaymentService,
+	health *health.Checker,
 ) *PaymentWorker {
 	return &PaymentWorker{
 		consumer:       consumer,
 		paymentService: paymentService,
+		health:         health,
+		name:           "payment-worker",
 	}
 }
 
+// SetLane makes the worker the worker of a lane once express orders have
+// their own: the express lane's worker is payment-worker-express, and the
+// standard lane's leaves express orders to it
+func (pw *PaymentWorker) SetLane(priority string) {
+	pw.name, pw.skipExpress = laneWorker("payment-worker", priority)
+}
+
 // Start starts the payment worker
 func (pw *PaymentWorker) Start(ctx context.Context) error {
 	log.Println("Starting payment worker...")
 
-	return pw.consumer.StartConsuming(ctx, func(ctx context.Context, msg kafka.Message) error {
+	handler := func(ctx context.Context, msg kafka.Message) error {
 		var baseEvent models.BaseEvent
 		if err := json.Unmarshal(msg.Value, &baseEvent); err != nil {
 			log.Printf("Failed to unmarshal event: %v", err)
@@ -85,11 +135,24 @@ func (pw *PaymentWorker) Start(ctx context.Context) error {
 
 			log.Printf("Processing payment for order: %d", event.OrderID)
 
-			return pw.paymentService.ProcessPayment(ctx, event.OrderID, event.TotalAmount)
+			req := service.PaymentRequest{
+				OrderID:      event.OrderID,
+				UserID:       event.UserID,
+				Amount:       event.TotalAmount,
+				WalletAmount: event.WalletAmount,
+			}
+			if event.Synthetic {
+				return pw.paymentService.ProcessSyntheticPayment(ctx, req)
+			}
+			return pw.paymentService.ProcessPayment(ctx, req)
 		}
 
 		return nil
-	})
+	}
+	if pw.skipExpress {
+		handler = broker.SkipExpress(handler)
+	}
+	return pw.consumer.StartConsuming(ctx, trackWork(pw.health, pw.name, handler))
 }
 
 // Stop stops the payment worker
@@ -97,3 +160,29 @@ func (pw *PaymentWorker) Stop() error {
 	log.Println("Stopping payment worker...")
 	return pw.consumer.Close()
 }
+
+// Drain waits for the message being handled when the worker's context was cancelled
+func (pw *PaymentWorker) Drain(ctx context.Context) error {
+	return pw.consumer.Drain(ctx)
+}
+
+// laneWorker returns the name of the worker of a lane and whether it leaves
+// express orders to the express lane
+func laneWorker(name, priority string) (string, bool) {
+	if priority == models.PriorityExpress {
+		return name + "-express", false
+	}
+	return name, true
+}
+
+// trackWork records handler start/end so liveness can detect wedged workers,
+// and the last message handled for the worker heartbeats
+func trackWork(checker *health.Checker, name string, handler broker.MessageHandler) broker.MessageHandler {
+	return func(ctx context.Context, msg kafka.Message) error {
+		checker.BeginWork(name)
+		defer checker.EndWork(name)
+		err := handler(ctx, msg)
+		checker.RecordWork(name, msg.Partition, msg.Offset, err)
+		return err
+	}
+}
@@ -0,0 +1,2 @@
+-- fencing token of the last lock holder that mutated the order
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS fence_token BIGINT NOT NULL DEFAULT 0;
@@ -0,0 +1,5 @@
+-- payment method and calendar-aware payment deadline per order
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS payment_method TEXT NOT NULL DEFAULT '';
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS expires_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_orders_expires_at ON orders(expires_at) WHERE status IN ('CREATED', 'RESERVED');
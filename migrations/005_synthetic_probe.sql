@@ -0,0 +1,13 @@
+-- flag synthetic probe orders so they can be excluded from business reporting
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS synthetic BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE INDEX IF NOT EXISTS idx_orders_synthetic ON orders(synthetic) WHERE synthetic;
+
+-- dedicated probe product, never sold to customers
+INSERT INTO products (sku, name, price) VALUES
+    ('PROBE-001', 'Synthetic Probe Item', 1)
+ON CONFLICT (sku) DO NOTHING;
+
+INSERT INTO inventory (product_id, available, reserved)
+SELECT id, 1000000, 0 FROM products WHERE sku = 'PROBE-001'
+ON CONFLICT (product_id) DO NOTHING;
@@ -0,0 +1,29 @@
+-- customer group membership (B2B contracts)
+CREATE TABLE IF NOT EXISTS user_customer_groups (
+    user_id BIGINT PRIMARY KEY,
+    customer_group TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+-- price lists per customer group
+CREATE TABLE IF NOT EXISTS price_lists (
+    id BIGSERIAL PRIMARY KEY,
+    name TEXT NOT NULL,
+    customer_group TEXT NOT NULL,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    valid_from TIMESTAMP,
+    valid_to TIMESTAMP,
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_price_lists_customer_group ON price_lists(customer_group) WHERE active;
+
+CREATE TABLE IF NOT EXISTS price_list_items (
+    price_list_id BIGINT NOT NULL REFERENCES price_lists(id) ON DELETE CASCADE,
+    product_id BIGINT NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    price BIGINT NOT NULL, -- contract price in cents
+    PRIMARY KEY (price_list_id, product_id)
+);
+
+-- price list applied to the order, NULL for retail pricing
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS price_list_id BIGINT REFERENCES price_lists(id);
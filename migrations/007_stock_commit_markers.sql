@@ -0,0 +1,2 @@
+-- per-item stock commit markers so order-level commits are resumable and idempotent
+ALTER TABLE order_items ADD COLUMN IF NOT EXISTS stock_committed_at TIMESTAMP;
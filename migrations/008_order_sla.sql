@@ -0,0 +1,7 @@
+-- lifecycle stage timestamps and SLA breach flag per order
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS reserved_at TIMESTAMP;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS paid_at TIMESTAMP;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS confirmed_at TIMESTAMP;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS sla_breached BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE INDEX IF NOT EXISTS idx_orders_sla_breached ON orders(created_at) WHERE sla_breached;
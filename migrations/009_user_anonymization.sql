@@ -0,0 +1,16 @@
+-- account-closure anonymization of order history
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS anonymized_at TIMESTAMP;
+
+CREATE TABLE IF NOT EXISTS anonymization_jobs (
+    id BIGSERIAL PRIMARY KEY,
+    event_id TEXT NOT NULL UNIQUE, -- UserDeleted event that requested the job
+    user_id BIGINT NOT NULL,
+    status TEXT NOT NULL, -- RUNNING, COMPLETED
+    orders_total INT NOT NULL DEFAULT 0,
+    orders_anonymized INT NOT NULL DEFAULT 0,
+    orders_deleted INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP DEFAULT NOW(),
+    completed_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_anonymization_jobs_user_id ON anonymization_jobs(user_id);
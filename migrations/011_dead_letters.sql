@@ -0,0 +1,14 @@
+-- messages whose handler failed, parked for inspection instead of blocking the partition
+CREATE TABLE IF NOT EXISTS dead_letters (
+    id BIGSERIAL PRIMARY KEY,
+    consumer_group TEXT NOT NULL,
+    topic TEXT NOT NULL,
+    message_partition INT NOT NULL,
+    message_offset BIGINT NOT NULL,
+    message_key TEXT NOT NULL DEFAULT '',
+    payload TEXT NOT NULL,
+    error TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_dead_letters_created_at ON dead_letters(created_at);
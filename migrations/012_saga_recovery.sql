@@ -0,0 +1,6 @@
+-- saga recovery bookkeeping: how often a stalled order has been nudged and when
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS recovery_attempts INT NOT NULL DEFAULT 0;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS last_recovery_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_orders_in_flight ON orders(updated_at)
+    WHERE status IN ('CREATED', 'RESERVED', 'PAID');
@@ -0,0 +1,11 @@
+-- items left out of orders created with allow_partial, e.g. because the product was deleted mid-request
+CREATE TABLE IF NOT EXISTS order_skipped_items (
+    id BIGSERIAL PRIMARY KEY,
+    order_id BIGINT NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    product_id BIGINT NOT NULL,
+    quantity INT NOT NULL,
+    reason TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_order_skipped_items_order_id ON order_skipped_items(order_id);
@@ -0,0 +1,14 @@
+-- compact per-order record of published and consumed events for the support UI
+CREATE TABLE IF NOT EXISTS order_timeline (
+    id BIGSERIAL PRIMARY KEY,
+    order_id BIGINT NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    event_id TEXT NOT NULL,
+    event_type TEXT NOT NULL,
+    direction TEXT NOT NULL, -- published | consumed, whichever saw the event first
+    summary TEXT NOT NULL,
+    occurred_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP DEFAULT NOW(),
+    UNIQUE (order_id, event_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_order_timeline_order_id ON order_timeline(order_id, occurred_at);
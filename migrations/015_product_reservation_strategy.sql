@@ -0,0 +1,3 @@
+-- how each product's stock is reserved; see InventoryClient
+ALTER TABLE products ADD COLUMN IF NOT EXISTS reservation_strategy TEXT NOT NULL DEFAULT 'redis-fast'
+    CHECK (reservation_strategy IN ('redis-fast', 'db-strict', 'leased-quota', 'none-for-digital'));
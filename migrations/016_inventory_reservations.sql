@@ -0,0 +1,21 @@
+-- per-order stock holds so reserve, release and commit are idempotent
+CREATE TABLE IF NOT EXISTS reservations (
+    order_id BIGINT NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    product_id BIGINT NOT NULL REFERENCES products(id),
+    quantity INT NOT NULL CHECK (quantity > 0),
+    status TEXT NOT NULL CHECK (status IN ('HELD', 'RELEASED', 'COMMITTED')),
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW(),
+    PRIMARY KEY (order_id, product_id)
+);
+
+-- holds of orders still in flight when the table was introduced
+INSERT INTO reservations (order_id, product_id, quantity, status)
+SELECT oi.order_id, oi.product_id, oi.quantity,
+       CASE WHEN oi.stock_committed_at IS NULL THEN 'HELD' ELSE 'COMMITTED' END
+FROM order_items oi
+JOIN orders o ON o.id = oi.order_id
+JOIN products p ON p.id = oi.product_id
+WHERE o.status IN ('RESERVED', 'PAID', 'ON_HOLD', 'CONFIRMED')
+  AND p.reservation_strategy <> 'none-for-digital'
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,5 @@
+-- items ordered while out of stock wait as BACKORDERED until stock is reserved for them
+ALTER TABLE order_items ADD COLUMN IF NOT EXISTS fulfillment_status TEXT NOT NULL DEFAULT 'ALLOCATED'
+    CHECK (fulfillment_status IN ('ALLOCATED', 'BACKORDERED'));
+
+CREATE INDEX IF NOT EXISTS idx_order_items_backordered ON order_items(id) WHERE fulfillment_status = 'BACKORDERED';
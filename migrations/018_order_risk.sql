@@ -0,0 +1,5 @@
+-- fraud risk score (0-100) and band of each order, NULL for orders placed before scoring
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS risk_score SMALLINT CHECK (risk_score BETWEEN 0 AND 100);
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS risk_band TEXT CHECK (risk_band IN ('low', 'medium', 'high'));
+
+CREATE INDEX IF NOT EXISTS idx_orders_risk_band ON orders(risk_band, created_at) WHERE risk_band IS NOT NULL;
@@ -0,0 +1,46 @@
+-- sellable variants of a product (size, color, ...), each with its own SKU and stock
+CREATE TABLE IF NOT EXISTS product_variants (
+    id BIGSERIAL PRIMARY KEY,
+    product_id BIGINT NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    sku TEXT UNIQUE NOT NULL,
+    attributes JSONB NOT NULL DEFAULT '{}',
+    is_default BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_product_variants_product_id ON product_variants(product_id);
+-- items ordered without a variant get the product's default variant
+CREATE UNIQUE INDEX IF NOT EXISTS idx_product_variants_default ON product_variants(product_id) WHERE is_default;
+
+-- every existing product becomes a single default variant sharing its SKU
+INSERT INTO product_variants (product_id, sku, is_default)
+SELECT id, sku, TRUE FROM products
+ON CONFLICT DO NOTHING;
+
+-- stock is kept per variant
+ALTER TABLE inventory ADD COLUMN IF NOT EXISTS variant_id BIGINT REFERENCES product_variants(id) ON DELETE CASCADE;
+UPDATE inventory i SET variant_id = v.id
+FROM product_variants v
+WHERE v.product_id = i.product_id AND v.is_default AND i.variant_id IS NULL;
+ALTER TABLE inventory ALTER COLUMN variant_id SET NOT NULL;
+ALTER TABLE inventory DROP CONSTRAINT IF EXISTS inventory_pkey;
+ALTER TABLE inventory ADD PRIMARY KEY (variant_id);
+
+-- order items and reservations refer to the variant they hold stock of
+ALTER TABLE order_items ADD COLUMN IF NOT EXISTS variant_id BIGINT REFERENCES product_variants(id);
+UPDATE order_items oi SET variant_id = v.id
+FROM product_variants v
+WHERE v.product_id = oi.product_id AND v.is_default AND oi.variant_id IS NULL;
+ALTER TABLE order_items ALTER COLUMN variant_id SET NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_order_items_variant_id ON order_items(variant_id);
+
+ALTER TABLE reservations ADD COLUMN IF NOT EXISTS variant_id BIGINT REFERENCES product_variants(id);
+UPDATE reservations r SET variant_id = v.id
+FROM product_variants v
+WHERE v.product_id = r.product_id AND v.is_default AND r.variant_id IS NULL;
+ALTER TABLE reservations ALTER COLUMN variant_id SET NOT NULL;
+ALTER TABLE reservations DROP CONSTRAINT IF EXISTS reservations_pkey;
+ALTER TABLE reservations ADD PRIMARY KEY (order_id, variant_id);
+
+-- skipped items may name a variant that no longer exists, so there is no foreign key
+ALTER TABLE order_skipped_items ADD COLUMN IF NOT EXISTS variant_id BIGINT;
@@ -0,0 +1,19 @@
+-- dead letters re-published to their source topic by operators
+ALTER TABLE dead_letters ADD COLUMN IF NOT EXISTS redriven_at TIMESTAMP;
+-- a message that fails again after a re-drive is parked with the letter it came from
+ALTER TABLE dead_letters ADD COLUMN IF NOT EXISTS redrive_of BIGINT REFERENCES dead_letters(id) ON DELETE SET NULL;
+ALTER TABLE dead_letters ADD COLUMN IF NOT EXISTS redrive_attempt INT NOT NULL DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS idx_dead_letters_pending ON dead_letters(id) WHERE redriven_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS dead_letter_redrives (
+    id BIGSERIAL PRIMARY KEY,
+    dead_letter_id BIGINT NOT NULL REFERENCES dead_letters(id) ON DELETE CASCADE,
+    attempt INT NOT NULL,
+    actor TEXT NOT NULL,
+    transformed BOOLEAN NOT NULL DEFAULT FALSE,
+    payload TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_dead_letter_redrives_dead_letter_id ON dead_letter_redrives(dead_letter_id);
@@ -0,0 +1,35 @@
+-- scheduled product drops: users register before starts_at and are converted
+-- into orders at drop time under the drop's fairness policy
+CREATE TABLE IF NOT EXISTS drops (
+    id BIGSERIAL PRIMARY KEY,
+    product_id BIGINT NOT NULL REFERENCES products(id),
+    variant_id BIGINT NOT NULL REFERENCES product_variants(id),
+    name TEXT NOT NULL,
+    starts_at TIMESTAMP NOT NULL,
+    policy TEXT NOT NULL DEFAULT 'random' CHECK (policy IN ('fifo', 'random')),
+    max_quantity_per_user INT NOT NULL DEFAULT 1 CHECK (max_quantity_per_user > 0),
+    status TEXT NOT NULL DEFAULT 'SCHEDULED' CHECK (status IN ('SCHEDULED', 'RUNNING', 'COMPLETED')),
+    -- seed of the random order, drawn when the drop starts so it cannot be known in advance
+    seed BIGINT,
+    claimed_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT NOW(),
+    completed_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_drops_due ON drops(starts_at) WHERE status <> 'COMPLETED';
+
+CREATE TABLE IF NOT EXISTS drop_registrations (
+    id BIGSERIAL PRIMARY KEY,
+    drop_id BIGINT NOT NULL REFERENCES drops(id) ON DELETE CASCADE,
+    user_id BIGINT NOT NULL,
+    quantity INT NOT NULL CHECK (quantity > 0),
+    payment_method TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'REGISTERED' CHECK (status IN ('REGISTERED', 'ORDERED', 'SOLD_OUT', 'FAILED')),
+    order_id BIGINT REFERENCES orders(id) ON DELETE SET NULL,
+    error TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT NOW(),
+    processed_at TIMESTAMP,
+    UNIQUE (drop_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_drop_registrations_pending ON drop_registrations(drop_id, id) WHERE status = 'REGISTERED';
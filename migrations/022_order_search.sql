@@ -0,0 +1,12 @@
+-- trigram indexes behind the support order search (GET /api/v1/orders/search)
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX IF NOT EXISTS idx_orders_idempotency_key_trgm ON orders USING GIN (idempotency_key gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_payments_provider_tx_id_trgm ON payments USING GIN (provider_tx_id gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_products_name_trgm ON products USING GIN (name gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_products_sku_trgm ON products USING GIN (sku gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_product_variants_sku_trgm ON product_variants USING GIN (sku gin_trgm_ops);
+
+-- newest orders of a matched product or variant first
+CREATE INDEX IF NOT EXISTS idx_order_items_product_order ON order_items(product_id, order_id DESC);
+CREATE INDEX IF NOT EXISTS idx_order_items_variant_order ON order_items(variant_id, order_id DESC);
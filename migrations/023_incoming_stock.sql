@@ -0,0 +1,21 @@
+-- stock on its way from suppliers (purchase orders), planned against
+-- backordered items so they can be quoted an expected ship date
+CREATE TABLE IF NOT EXISTS incoming_stock (
+    id BIGSERIAL PRIMARY KEY,
+    variant_id BIGINT NOT NULL REFERENCES product_variants(id) ON DELETE CASCADE,
+    -- purchase order number of the supplier
+    reference TEXT NOT NULL,
+    quantity INT NOT NULL CHECK (quantity > 0),
+    eta DATE NOT NULL,
+    status TEXT NOT NULL DEFAULT 'OPEN' CHECK (status IN ('OPEN', 'RECEIVED', 'CANCELLED')),
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_incoming_stock_open ON incoming_stock(variant_id, eta, id) WHERE status = 'OPEN';
+
+-- the incoming stock a backordered item is planned against, and the date it ships
+ALTER TABLE order_items ADD COLUMN IF NOT EXISTS incoming_stock_id BIGINT REFERENCES incoming_stock(id) ON DELETE SET NULL;
+ALTER TABLE order_items ADD COLUMN IF NOT EXISTS expected_ship_date DATE;
+
+CREATE INDEX IF NOT EXISTS idx_order_items_backordered_variant ON order_items(variant_id, id) WHERE fulfillment_status = 'BACKORDERED';
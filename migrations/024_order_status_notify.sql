@@ -0,0 +1,29 @@
+-- the transaction that recorded each status change, so the notify bridge can
+-- forward only changes no in-flight transaction can precede; rows recorded
+-- before this migration keep NULL and are not forwarded
+ALTER TABLE order_status_history ADD COLUMN IF NOT EXISTS txid BIGINT;
+ALTER TABLE order_status_history ALTER COLUMN txid SET DEFAULT txid_current();
+
+CREATE INDEX IF NOT EXISTS idx_order_status_history_txid ON order_status_history(txid, id) WHERE txid IS NOT NULL;
+
+-- wake the notify bridge when a status change commits
+CREATE OR REPLACE FUNCTION notify_order_status_change() RETURNS trigger AS $$
+BEGIN
+    PERFORM pg_notify('order_status_changes', NEW.order_id::text);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS order_status_change_notify ON order_status_history;
+CREATE TRIGGER order_status_change_notify
+    AFTER INSERT ON order_status_history
+    FOR EACH ROW EXECUTE FUNCTION notify_order_status_change();
+
+-- how far each change capture consumer has forwarded order_status_history,
+-- by (txid, history_id)
+CREATE TABLE IF NOT EXISTS change_capture_cursors (
+    name TEXT PRIMARY KEY,
+    txid BIGINT NOT NULL,
+    history_id BIGINT NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP DEFAULT NOW()
+);
@@ -0,0 +1,7 @@
+-- the cancellation policy applied when a customer cancelled the order, and
+-- the late-cancel fee kept from the refund
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS cancellation_policy TEXT;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS cancellation_fee BIGINT;
+
+-- the amount given back when a captured payment is refunded less a fee
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS refunded_amount BIGINT NOT NULL DEFAULT 0;
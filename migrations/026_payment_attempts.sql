@@ -0,0 +1,20 @@
+-- declined payment attempts of an order; soft declines (provider timeouts and
+-- outages) are retried with backoff before the order is cancelled
+CREATE TABLE IF NOT EXISTS payment_attempts (
+    id BIGSERIAL PRIMARY KEY,
+    order_id BIGINT NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    payment_id BIGINT NOT NULL REFERENCES payments(id) ON DELETE CASCADE,
+    attempt INT NOT NULL CHECK (attempt > 0),
+    amount BIGINT NOT NULL,
+    decline_reason TEXT NOT NULL,
+    decline_class TEXT NOT NULL CHECK (decline_class IN ('SOFT', 'HARD')),
+    -- SCHEDULED until retried at retry_at; FINAL when the decline cancels the
+    -- order; DROPPED when the order stopped awaiting payment before the retry
+    status TEXT NOT NULL CHECK (status IN ('SCHEDULED', 'RETRIED', 'FINAL', 'DROPPED')),
+    retry_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW(),
+    UNIQUE (order_id, attempt)
+);
+
+CREATE INDEX IF NOT EXISTS idx_payment_attempts_due ON payment_attempts(retry_at) WHERE status = 'SCHEDULED';
@@ -0,0 +1,4 @@
+-- company and tax identifier (VAT number, NPWP, ...) a B2B order is invoiced to
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS billing_company TEXT;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS billing_country CHAR(2);
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS tax_id TEXT;
@@ -0,0 +1,29 @@
+-- store credit per user; version guards balance updates (optimistic locking)
+CREATE TABLE IF NOT EXISTS wallets (
+    user_id BIGINT PRIMARY KEY,
+    balance BIGINT NOT NULL DEFAULT 0 CHECK (balance >= 0),
+    version BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW()
+);
+
+-- every debit and credit of a wallet; a payment is debited and credited back
+-- at most once
+CREATE TABLE IF NOT EXISTS wallet_transactions (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES wallets(user_id) ON DELETE CASCADE,
+    kind TEXT NOT NULL CHECK (kind IN ('DEBIT', 'CREDIT')),
+    amount BIGINT NOT NULL CHECK (amount > 0),
+    balance_after BIGINT NOT NULL,
+    payment_id BIGINT REFERENCES payments(id) ON DELETE SET NULL,
+    reason TEXT NOT NULL,
+    actor TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT NOW(),
+    UNIQUE (payment_id, kind)
+);
+
+CREATE INDEX IF NOT EXISTS idx_wallet_transactions_user ON wallet_transactions(user_id, id);
+
+-- the part of an order settled from the wallet; the provider charges the rest
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS wallet_amount BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS wallet_amount BIGINT NOT NULL DEFAULT 0;
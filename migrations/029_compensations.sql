@@ -0,0 +1,30 @@
+-- compensation steps that failed during the saga, retried in the background
+-- most urgent first until their retry budget runs out, then escalated for
+-- manual resolution
+CREATE TABLE IF NOT EXISTS compensations (
+    id BIGSERIAL PRIMARY KEY,
+    order_id BIGINT NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    kind TEXT NOT NULL CHECK (kind IN ('WALLET_REFUND', 'VOID_PAYMENT', 'COMMIT_STOCK', 'RELEASE_STOCK')),
+    -- variant of stock compensations and payment of refunds, 0 otherwise
+    variant_id BIGINT NOT NULL DEFAULT 0,
+    payment_id BIGINT NOT NULL DEFAULT 0,
+    amount BIGINT NOT NULL DEFAULT 0,
+    reason TEXT NOT NULL,
+    -- lower runs first; money is handled before stock
+    priority INT NOT NULL,
+    -- PENDING until it succeeds (DONE) or runs out of attempts (ESCALATED);
+    -- an operator closes escalated compensations (RESOLVED)
+    status TEXT NOT NULL CHECK (status IN ('PENDING', 'DONE', 'ESCALATED', 'RESOLVED')),
+    attempts INT NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP NOT NULL,
+    last_error TEXT NOT NULL DEFAULT '',
+    resolved_by TEXT,
+    resolution TEXT,
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW()
+);
+
+-- one open compensation per step, so a step failing again is not queued twice
+CREATE UNIQUE INDEX IF NOT EXISTS idx_compensations_open
+    ON compensations(order_id, kind, variant_id, payment_id) WHERE status IN ('PENDING', 'ESCALATED');
+CREATE INDEX IF NOT EXISTS idx_compensations_due ON compensations(priority, next_attempt_at) WHERE status = 'PENDING';
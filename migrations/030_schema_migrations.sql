@@ -0,0 +1,11 @@
+-- versions of the migrations applied, checked by `server doctor` before rollout.
+-- Every migration records its own version at its end.
+CREATE TABLE IF NOT EXISTS schema_migrations (
+    version INT PRIMARY KEY,
+    applied_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- the migrations up to this one did not record themselves
+INSERT INTO schema_migrations (version)
+SELECT generate_series(1, 30)
+ON CONFLICT (version) DO NOTHING;
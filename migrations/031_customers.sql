@@ -0,0 +1,19 @@
+-- the order service's projection of identity service users, kept current from
+-- USER_UPDATED and USER_DELETED events, to check customers at order time
+CREATE TABLE IF NOT EXISTS customers (
+    user_id BIGINT PRIMARY KEY,
+    email TEXT NOT NULL,
+    name TEXT NOT NULL,
+    status TEXT NOT NULL CHECK (status IN ('ACTIVE', 'SUSPENDED', 'CLOSED')),
+    -- timestamp of the event the row was last updated from, so events
+    -- delivered out of order do not overwrite newer ones
+    source_updated_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW()
+);
+
+-- the customer as they were when the order was placed, for reporting
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS customer_email TEXT;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS customer_name TEXT;
+
+INSERT INTO schema_migrations (version) VALUES (31) ON CONFLICT (version) DO NOTHING;
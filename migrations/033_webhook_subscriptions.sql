@@ -0,0 +1,43 @@
+-- endpoints of third parties subscribed to order events
+CREATE TABLE IF NOT EXISTS webhook_subscriptions (
+    id BIGSERIAL PRIMARY KEY,
+    url TEXT NOT NULL,
+    event_types TEXT[] NOT NULL,
+    -- signs the payloads posted to the endpoint
+    secret TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    -- DISABLED after too many failed deliveries in a row, until re-enabled
+    status TEXT NOT NULL CHECK (status IN ('ACTIVE', 'DISABLED')),
+    consecutive_failures INT NOT NULL DEFAULT 0,
+    disabled_reason TEXT,
+    disabled_at TIMESTAMP,
+    created_by TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW()
+);
+
+-- one delivery of an event to a subscription, retried with backoff until
+-- DELIVERED or out of attempts (FAILED)
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id BIGSERIAL PRIMARY KEY,
+    subscription_id BIGINT NOT NULL REFERENCES webhook_subscriptions(id) ON DELETE CASCADE,
+    event_id TEXT NOT NULL,
+    event_type TEXT NOT NULL,
+    payload TEXT NOT NULL,
+    status TEXT NOT NULL CHECK (status IN ('PENDING', 'DELIVERED', 'FAILED')),
+    attempts INT NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP NOT NULL,
+    last_status_code INT,
+    last_error TEXT NOT NULL DEFAULT '',
+    last_attempt_at TIMESTAMP,
+    delivered_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW(),
+    -- a redelivered event is not posted twice
+    UNIQUE (subscription_id, event_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due ON webhook_deliveries(next_attempt_at) WHERE status = 'PENDING';
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_subscription ON webhook_deliveries(subscription_id, created_at);
+
+INSERT INTO schema_migrations (version) VALUES (33) ON CONFLICT (version) DO NOTHING;
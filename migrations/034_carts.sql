@@ -0,0 +1,29 @@
+-- shopping carts, kept until they expire CART_TTL_HOURS after their last
+-- change; Redis holds a copy of open carts for reads. The ID is random so a
+-- cart cannot be guessed by another client.
+CREATE TABLE IF NOT EXISTS carts (
+    id UUID PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    -- OPEN until it is checked out into order_id
+    status TEXT NOT NULL CHECK (status IN ('OPEN', 'CHECKED_OUT')),
+    order_id BIGINT REFERENCES orders(id) ON DELETE SET NULL,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_carts_user ON carts(user_id);
+CREATE INDEX IF NOT EXISTS idx_carts_expires ON carts(expires_at);
+
+CREATE TABLE IF NOT EXISTS cart_items (
+    cart_id UUID NOT NULL REFERENCES carts(id) ON DELETE CASCADE,
+    product_id BIGINT NOT NULL,
+    -- resolved to the product's default variant when added without one
+    variant_id BIGINT NOT NULL,
+    quantity INT NOT NULL CHECK (quantity > 0),
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW(),
+    PRIMARY KEY (cart_id, variant_id)
+);
+
+INSERT INTO schema_migrations (version) VALUES (34) ON CONFLICT (version) DO NOTHING;
@@ -0,0 +1,30 @@
+-- warehouses stock is kept in. Existing stock moves to the default warehouse,
+-- which incoming stock is received into.
+CREATE TABLE IF NOT EXISTS warehouses (
+    id BIGSERIAL PRIMARY KEY,
+    code VARCHAR(50) NOT NULL UNIQUE,
+    name VARCHAR(255) NOT NULL,
+    -- location the nearest allocation measures distance from
+    latitude DOUBLE PRECISION NOT NULL DEFAULT 0,
+    longitude DOUBLE PRECISION NOT NULL DEFAULT 0,
+    -- inactive warehouses keep their stock but are never allocated from
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+INSERT INTO warehouses (id, code, name) VALUES (1, 'default', 'Default warehouse') ON CONFLICT (id) DO NOTHING;
+SELECT setval('warehouses_id_seq', GREATEST((SELECT MAX(id) FROM warehouses), 1));
+
+-- inventory is kept per variant and warehouse
+ALTER TABLE inventory ADD COLUMN IF NOT EXISTS warehouse_id BIGINT NOT NULL DEFAULT 1 REFERENCES warehouses(id);
+ALTER TABLE inventory DROP CONSTRAINT IF EXISTS inventory_pkey;
+ALTER TABLE inventory ADD PRIMARY KEY (variant_id, warehouse_id);
+
+-- the warehouse a reservation holds stock in, and an order item ships from;
+-- NULL until stock is allocated, e.g. for backordered items
+ALTER TABLE reservations ADD COLUMN IF NOT EXISTS warehouse_id BIGINT REFERENCES warehouses(id);
+UPDATE reservations SET warehouse_id = 1 WHERE warehouse_id IS NULL;
+ALTER TABLE order_items ADD COLUMN IF NOT EXISTS warehouse_id BIGINT REFERENCES warehouses(id);
+UPDATE order_items SET warehouse_id = 1 WHERE warehouse_id IS NULL AND fulfillment_status = 'ALLOCATED';
+
+INSERT INTO schema_migrations (version) VALUES (35) ON CONFLICT (version) DO NOTHING;
@@ -0,0 +1,5 @@
+-- available units of a variant at or below which procurement is alerted to
+-- reorder the product; NULL disables low-stock alerts for the product
+ALTER TABLE products ADD COLUMN IF NOT EXISTS reorder_threshold INT CHECK (reorder_threshold >= 0);
+
+INSERT INTO schema_migrations (version) VALUES (36) ON CONFLICT (version) DO NOTHING;
@@ -0,0 +1,45 @@
+-- denormalized read model of orders for list and search endpoints, kept
+-- current from the order and payment events by the order summary projector so
+-- reads do not join orders, items and payments. Every event refreshes the
+-- whole row from the current state, so redelivered or reordered events are
+-- harmless.
+CREATE TABLE IF NOT EXISTS order_summaries (
+    order_id BIGINT PRIMARY KEY REFERENCES orders(id) ON DELETE CASCADE,
+    user_id BIGINT NOT NULL,
+    status TEXT NOT NULL,
+    -- order lines, and units across them
+    item_count INT NOT NULL DEFAULT 0,
+    total_quantity INT NOT NULL DEFAULT 0,
+    total_amount BIGINT NOT NULL,
+    wallet_amount BIGINT NOT NULL DEFAULT 0,
+    payment_method TEXT NOT NULL,
+    -- of the latest payment, NULL before one is made
+    payment_status TEXT,
+    refunded_amount BIGINT NOT NULL DEFAULT 0,
+    risk_score INT,
+    risk_band TEXT,
+    -- the newest event projected; NULL for rows backfilled below
+    last_event_type TEXT,
+    last_event_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_order_summaries_created ON order_summaries(created_at);
+
+INSERT INTO order_summaries (order_id, user_id, status, item_count, total_quantity, total_amount,
+    wallet_amount, payment_method, payment_status, refunded_amount, risk_score, risk_band,
+    last_event_at, created_at)
+SELECT o.id, o.user_id, o.status, COALESCE(i.item_count, 0), COALESCE(i.total_quantity, 0), o.total_amount,
+    o.wallet_amount, o.payment_method, p.status, COALESCE(p.refunded_amount, 0), o.risk_score, o.risk_band,
+    o.updated_at, o.created_at
+FROM orders o
+LEFT JOIN LATERAL (
+    SELECT COUNT(*) AS item_count, SUM(quantity) AS total_quantity FROM order_items WHERE order_id = o.id
+) i ON TRUE
+LEFT JOIN LATERAL (
+    SELECT status, refunded_amount FROM payments WHERE order_id = o.id ORDER BY created_at DESC, id DESC LIMIT 1
+) p ON TRUE
+ON CONFLICT (order_id) DO NOTHING;
+
+INSERT INTO schema_migrations (version) VALUES (37) ON CONFLICT (version) DO NOTHING;
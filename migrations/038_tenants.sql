@@ -0,0 +1,19 @@
+-- storefronts hosted by the service. Orders, products and order summaries
+-- belong to a tenant; variants and stock belong to their product's. Rows from
+-- before tenancy, and from requests without tenant credentials, belong to the
+-- default tenant.
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS tenant_id TEXT NOT NULL DEFAULT 'default';
+ALTER TABLE products ADD COLUMN IF NOT EXISTS tenant_id TEXT NOT NULL DEFAULT 'default';
+ALTER TABLE order_summaries ADD COLUMN IF NOT EXISTS tenant_id TEXT NOT NULL DEFAULT 'default';
+
+CREATE INDEX IF NOT EXISTS idx_orders_tenant_created ON orders(tenant_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_products_tenant ON products(tenant_id);
+CREATE INDEX IF NOT EXISTS idx_order_summaries_tenant_created ON order_summaries(tenant_id, created_at);
+
+-- clients choose idempotency keys, so two storefronts may pick the same one.
+-- SKUs stay unique across tenants since default variants share them.
+ALTER TABLE orders DROP CONSTRAINT IF EXISTS orders_idempotency_key_key;
+DROP INDEX IF EXISTS idx_orders_idempotency_key;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_orders_tenant_idempotency_key ON orders(tenant_id, idempotency_key);
+
+INSERT INTO schema_migrations (version) VALUES (38) ON CONFLICT (version) DO NOTHING;
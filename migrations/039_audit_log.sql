@@ -0,0 +1,22 @@
+-- every admin mutation: who made it, on what, in which request and what it
+-- changed, for compliance audits. The service only ever inserts rows.
+CREATE TABLE IF NOT EXISTS audit_log (
+    id BIGSERIAL PRIMARY KEY,
+    actor TEXT NOT NULL,
+    action TEXT NOT NULL,
+    target TEXT NOT NULL DEFAULT '',
+    request_id TEXT NOT NULL DEFAULT '',
+    -- HTTP status of the response, so failed attempts show too
+    status INT NOT NULL,
+    before JSONB,
+    after JSONB,
+    -- the top-level fields that changed between before and after
+    diff JSONB,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_created ON audit_log(created_at);
+CREATE INDEX IF NOT EXISTS idx_audit_log_actor ON audit_log(actor, created_at);
+CREATE INDEX IF NOT EXISTS idx_audit_log_action ON audit_log(action, created_at);
+
+INSERT INTO schema_migrations (version) VALUES (39) ON CONFLICT (version) DO NOTHING;
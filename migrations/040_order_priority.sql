@@ -0,0 +1,6 @@
+-- how urgently an order is processed. Express orders, e.g. of paid
+-- memberships, publish their events to the express lane, which workers
+-- consume apart from the backlog of standard orders.
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS priority TEXT NOT NULL DEFAULT 'standard';
+
+INSERT INTO schema_migrations (version) VALUES (40) ON CONFLICT (version) DO NOTHING;
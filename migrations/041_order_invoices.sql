@@ -0,0 +1,7 @@
+-- the PDF invoice of a confirmed order, as the s3://bucket/key URL of the
+-- object it is stored in. Customers download it through short-lived signed
+-- links.
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS invoice_url TEXT;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS invoiced_at TIMESTAMP;
+
+INSERT INTO schema_migrations (version) VALUES (41) ON CONFLICT (version) DO NOTHING;
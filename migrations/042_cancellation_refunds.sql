@@ -0,0 +1,7 @@
+-- customer cancellations change the order's status before returning its
+-- payment; a refund or void that fails then is retried as a compensation
+ALTER TABLE compensations DROP CONSTRAINT IF EXISTS compensations_kind_check;
+ALTER TABLE compensations ADD CONSTRAINT compensations_kind_check
+    CHECK (kind IN ('WALLET_REFUND', 'VOID_PAYMENT', 'CANCELLATION_REFUND', 'COMMIT_STOCK', 'RELEASE_STOCK'));
+
+INSERT INTO schema_migrations (version) VALUES (42) ON CONFLICT (version) DO NOTHING;
@@ -0,0 +1,9 @@
+-- invoice numbers, e.g. INV-2026-000042, run without gaps per series: the
+-- counter of a series moves in the transaction that numbers an invoice
+CREATE TABLE IF NOT EXISTS invoice_counters (
+    series TEXT PRIMARY KEY,
+    last_number BIGINT NOT NULL
+);
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS invoice_number TEXT UNIQUE;
+
+INSERT INTO schema_migrations (version) VALUES (43) ON CONFLICT (version) DO NOTHING;
@@ -0,0 +1,29 @@
+-- carts, customers, wallets, drops and webhook subscriptions belong to a
+-- tenant like orders do. Customers and wallets are keyed by tenant and user,
+-- as storefronts number their users independently. Existing rows belong to
+-- the default tenant.
+ALTER TABLE carts ADD COLUMN IF NOT EXISTS tenant_id TEXT NOT NULL DEFAULT 'default';
+ALTER TABLE drops ADD COLUMN IF NOT EXISTS tenant_id TEXT NOT NULL DEFAULT 'default';
+ALTER TABLE drop_registrations ADD COLUMN IF NOT EXISTS tenant_id TEXT NOT NULL DEFAULT 'default';
+ALTER TABLE webhook_subscriptions ADD COLUMN IF NOT EXISTS tenant_id TEXT NOT NULL DEFAULT 'default';
+ALTER TABLE customers ADD COLUMN IF NOT EXISTS tenant_id TEXT NOT NULL DEFAULT 'default';
+ALTER TABLE wallets ADD COLUMN IF NOT EXISTS tenant_id TEXT NOT NULL DEFAULT 'default';
+ALTER TABLE wallet_transactions ADD COLUMN IF NOT EXISTS tenant_id TEXT NOT NULL DEFAULT 'default';
+
+ALTER TABLE customers DROP CONSTRAINT IF EXISTS customers_pkey;
+ALTER TABLE customers ADD PRIMARY KEY (tenant_id, user_id);
+
+ALTER TABLE wallet_transactions DROP CONSTRAINT IF EXISTS wallet_transactions_user_id_fkey;
+ALTER TABLE wallet_transactions DROP CONSTRAINT IF EXISTS wallet_transactions_wallet_fkey;
+ALTER TABLE wallets DROP CONSTRAINT IF EXISTS wallets_pkey;
+ALTER TABLE wallets ADD PRIMARY KEY (tenant_id, user_id);
+ALTER TABLE wallet_transactions ADD CONSTRAINT wallet_transactions_wallet_fkey
+    FOREIGN KEY (tenant_id, user_id) REFERENCES wallets(tenant_id, user_id) ON DELETE CASCADE;
+
+DROP INDEX IF EXISTS idx_wallet_transactions_user;
+CREATE INDEX IF NOT EXISTS idx_wallet_transactions_tenant_user ON wallet_transactions(tenant_id, user_id, id);
+CREATE INDEX IF NOT EXISTS idx_carts_tenant_user ON carts(tenant_id, user_id);
+CREATE INDEX IF NOT EXISTS idx_drops_tenant ON drops(tenant_id);
+CREATE INDEX IF NOT EXISTS idx_webhook_subscriptions_tenant ON webhook_subscriptions(tenant_id);
+
+INSERT INTO schema_migrations (version) VALUES (44) ON CONFLICT (version) DO NOTHING;
@@ -0,0 +1,31 @@
+// Package orderservice embeds order creation in another Go service, for
+// callers such as kiosk gateways that must take orders in-process rather than
+// over HTTP.
+//
+// The embedded service shares the database, Redis stock counters and Kafka
+// topic of the order service deployment: orders it creates are reserved and
+// published exactly as POST /api/v1/orders would, and the deployment's
+// consumers drive them through payment and confirmation.
+//
+//	cfg, err := config.Load()
+//	if err != nil {
+//		return err
+//	}
+//	orders, err := orderservice.New(cfg, orderservice.Deps{DB: db})
+//	if err != nil {
+//		return err
+//	}
+//	defer orders.Close(context.Background())
+//	go orders.Run(ctx)
+//
+//	resp, err := orders.CreateOrder(ctx, &orderservice.CreateOrderRequest{
+//		UserID:        42,
+//		Items:         []orderservice.OrderItemRequest{{ProductID: 1, Quantity: 1}},
+//		PaymentMethod: "card",
+//	})
+//	if errors.Is(err, orderservice.ErrInsufficientStock) {
+//		// sold out
+//	}
+//
+// The HTTP server builds its core the same way, so both paths stay in step.
+package orderservice
@@ -0,0 +1,213 @@
+package orderservice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"order-service/config"
+	"order-service/internal/apperrors"
+	"order-service/internal/core"
+	"order-service/internal/models"
+	"order-service/internal/redisclient"
+	"order-service/internal/service"
+	"order-service/internal/store"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/jmoiron/sqlx"
+)
+
+// Request and result types of the embedded API
+type (
+	CreateOrderRequest  = service.CreateOrderRequest
+	CreateOrderResponse = service.CreateOrderResponse
+	OrderItemRequest    = service.OrderItemRequest
+	BillingDetails      = models.BillingDetails
+	Order               = models.Order
+	OrderItem           = models.OrderItem
+	OrderStatusHistory  = models.OrderStatusHistory
+	SkippedOrderItem    = models.SkippedOrderItem
+	ProductVariant      = models.ProductVariant
+	OrderStatusChange   = redisclient.OrderStatusChange
+)
+
+// Order statuses
+const (
+	StatusCreated   = models.OrderStatusCreated
+	StatusReserved  = models.OrderStatusReserved
+	StatusPaid      = models.OrderStatusPaid
+	StatusConfirmed = models.OrderStatusConfirmed
+	StatusCancelled = models.OrderStatusCancelled
+	StatusFailed    = models.OrderStatusFailed
+	StatusOnHold    = models.OrderStatusOnHold
+)
+
+// Error is a domain error with a stable code; match it with errors.Is against
+// the sentinels below
+type Error = apperrors.Error
+
+// Domain errors returned by the embedded API
+var (
+	ErrProductNotFound      = apperrors.ErrProductNotFound
+	ErrMixedPricing         = apperrors.ErrMixedPricing
+	ErrInsufficientStock    = apperrors.ErrInsufficientStock
+	ErrOrderNotFound        = apperrors.ErrOrderNotFound
+	ErrDuplicateOrder       = apperrors.ErrDuplicateOrder
+	ErrIdempotencyMismatch  = apperrors.ErrIdempotencyMismatch
+	ErrStatusFeedAtCapacity = service.ErrStatusFeedAtCapacity
+)
+
+// Deps are connections the embedding process already holds. A nil field is
+// opened from the configuration instead; Close only closes what New opened.
+type Deps struct {
+	// DB is a PostgreSQL pool on the order service schema
+	DB *sqlx.DB
+	// Redis is a client of the Redis deployment holding the stock counters
+	Redis redis.UniversalClient
+}
+
+// Service is an in-process order service. It creates orders and publishes
+// their events exactly like the HTTP server; the saga itself is driven by the
+// consumers of a running order service deployment.
+type Service struct {
+	core     *core.Core
+	closeDB  bool
+	closeRDB bool
+}
+
+// New builds an embedded order service from cfg, reusing the connections in deps
+func New(cfg *config.Config, deps Deps) (*Service, error) {
+	if cfg == nil {
+		return nil, errors.New("orderservice: configuration is required")
+	}
+	s := &Service{}
+
+	var db *store.Store
+	if deps.DB != nil {
+		db = store.NewStoreFromDB(deps.DB)
+	} else {
+		var err error
+		if db, err = store.NewStoreWithReplicas(cfg.Database.URL, cfg.Database.ReplicaURLs); err != nil {
+			return nil, err
+		}
+		s.closeDB = true
+	}
+	db.SetMaxAttempts(cfg.Database.MaxRetryAttempts)
+	db.SetFailoverPolicy(cfg.Database.ReadRetryAttempts, cfg.Database.PoolResetThreshold)
+	db.SetQueryOptions(cfg.Database.PreparedStatements, time.Duration(cfg.Database.SlowQueryMs)*time.Millisecond)
+
+	var rdb *redisclient.Client
+	if deps.Redis != nil {
+		rdb = redisclient.Wrap(deps.Redis, cfg.Redis.MaxRetries)
+	} else {
+		var err error
+		rdb, err = redisclient.NewClientWithOptions(redisclient.Options{
+			Mode:             cfg.Redis.Mode,
+			Addrs:            cfg.Redis.Addrs,
+			Password:         cfg.Redis.Password,
+			DB:               cfg.Redis.DB,
+			MasterName:       cfg.Redis.MasterName,
+			SentinelPassword: cfg.Redis.SentinelPassword,
+			MaxRetries:       cfg.Redis.MaxRetries,
+		})
+		if err != nil {
+			s.closeConnections(db, nil)
+			return nil, fmt.Errorf("failed to connect to Redis: %w", err)
+		}
+		s.closeRDB = true
+	}
+
+	c, err := core.New(cfg, db, rdb)
+	if err != nil {
+		s.closeConnections(db, rdb)
+		return nil, err
+	}
+	s.core = c
+	return s, nil
+}
+
+// CreateOrder reserves stock and creates an order, like POST /api/v1/orders
+func (s *Service) CreateOrder(ctx context.Context, req *CreateOrderRequest) (*CreateOrderResponse, error) {
+	return s.core.Orders.CreateOrder(ctx, req)
+}
+
+// GetOrder returns an order and its items
+func (s *Service) GetOrder(ctx context.Context, orderID int64) (*Order, []OrderItem, error) {
+	return s.core.Orders.GetOrder(ctx, orderID)
+}
+
+// GetOrderHistory returns the status transitions of an order
+func (s *Service) GetOrderHistory(ctx context.Context, orderID int64) ([]OrderStatusHistory, error) {
+	return s.core.Orders.GetOrderHistory(ctx, orderID)
+}
+
+// GetProductVariants returns the variants a product can be ordered in
+func (s *Service) GetProductVariants(ctx context.Context, productID int64) ([]ProductVariant, error) {
+	return s.core.Orders.GetProductVariants(ctx, productID)
+}
+
+// WatchOrder streams the status changes of an order until cancel is called.
+// Changes are only delivered while Run is running.
+func (s *Service) WatchOrder(orderID int64) (<-chan OrderStatusChange, func(), error) {
+	return s.core.StatusFeed.Subscribe(orderID)
+}
+
+// Run keeps the local caches, order status feed and stock quota leases up to
+// date until ctx is cancelled or one of them fails
+func (s *Service) Run(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	loops := []func(context.Context) error{
+		s.core.OrderCache.Run,
+		s.core.ProductCache.Run,
+		s.core.StatusFeed.Run,
+		s.core.Inventory.RunLeaseExpiry,
+	}
+	errs := make(chan error, len(loops))
+	for _, loop := range loops {
+		go func(loop func(context.Context) error) {
+			errs <- loop(ctx)
+		}(loop)
+	}
+
+	err := <-errs
+	cancel()
+	for i := 1; i < len(loops); i++ {
+		<-errs
+	}
+	return err
+}
+
+// Close returns unused stock quota, flushes pending events and closes the
+// connections New opened
+func (s *Service) Close(ctx context.Context) error {
+	var errs []error
+	if err := s.core.Inventory.ReturnLeases(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("failed to return quota leases: %w", err))
+	}
+	if err := s.core.Producer.Close(); err != nil {
+		errs = append(errs, fmt.Errorf("failed to close producer: %w", err))
+	}
+	if err := s.closeConnections(s.core.Store, s.core.Redis); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// closeConnections closes the database and Redis connections New opened
+func (s *Service) closeConnections(db *store.Store, rdb *redisclient.Client) error {
+	var errs []error
+	if s.closeRDB && rdb != nil {
+		if err := rdb.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("failed to close Redis: %w", err))
+		}
+	}
+	if s.closeDB && db != nil {
+		if err := db.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("failed to close database: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
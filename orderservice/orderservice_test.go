@@ -0,0 +1,49 @@
+package orderservice
+
+import (
+	"context"
+	"testing"
+
+	"order-service/config"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/jmoiron/sqlx"
+	_ "github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testDeps(t *testing.T) Deps {
+	t.Helper()
+
+	// sqlx.Open does not connect, so nothing below touches the database
+	db, err := sqlx.Open("postgres", "postgres://localhost/orders?sslmode=disable")
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	rdb := redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})
+	t.Cleanup(func() { rdb.Close() })
+	return Deps{DB: db, Redis: rdb}
+}
+
+func TestCloseLeavesCallerConnectionsOpen(t *testing.T) {
+	cfg, err := config.Load()
+	require.NoError(t, err)
+	deps := testDeps(t)
+
+	orders, err := New(cfg, deps)
+	require.NoError(t, err)
+	require.NoError(t, orders.Close(context.Background()))
+
+	assert.NoError(t, deps.Redis.Ping(context.Background()).Err())
+}
+
+func TestNewRejectsInvalidConfiguration(t *testing.T) {
+	cfg, err := config.Load()
+	require.NoError(t, err)
+	cfg.Business.CalendarTimezone = "Not/AZone"
+
+	_, err = New(cfg, testDeps(t))
+	assert.Error(t, err)
+}